@@ -0,0 +1,146 @@
+// Command admin runs maintenance tasks against the database configured for
+// the API, using the same environment.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"bailanysta/api/internal/config"
+	"bailanysta/api/internal/pkg/database"
+	"bailanysta/api/internal/pkg/logger"
+	"bailanysta/api/internal/pkg/s3"
+	"bailanysta/api/internal/services"
+)
+
+const usage = `Usage: admin <command>
+
+Commands:
+  backup              back up user content to the backup bucket now
+  backups             list backups, newest first
+  restore <id|latest> insert rows missing from the database from a backup
+  superadmin <email>  let a user run the deployment: log levels, maintenance,
+                      organizations, IP blocks
+  unsuperadmin <email>
+                      make a superadmin an ordinary admin of their organization
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	switch command := os.Args[1]; command {
+	case "backup", "backups", "restore":
+		if err := runBackupCommand(ctx, cfg, command, os.Args[2:]); err != nil {
+			log.Fatalf("%s failed: %v", command, err)
+		}
+	case "superadmin", "unsuperadmin":
+		if err := runSuperadminCommand(ctx, cfg, command, os.Args[2:]); err != nil {
+			log.Fatalf("%s failed: %v", command, err)
+		}
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func runBackupCommand(ctx context.Context, cfg *config.Config, command string, args []string) error {
+	if cfg.BackupS3Bucket == "" {
+		return fmt.Errorf("BACKUP_S3_BUCKET is not set")
+	}
+	store, err := s3.New(cfg.BackupS3())
+	if err != nil {
+		return err
+	}
+
+	pool, err := pgxpool.New(ctx, cfg.DatabaseURL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer pool.Close()
+	db := database.New(pool, nil, logger.New("warn", io.Discard))
+
+	backups := services.NewBackupService(db, store, cfg.BackupS3Prefix, cfg.BackupInterval, cfg.BackupRetention)
+
+	switch command {
+	case "backup":
+		backup, err := backups.Backup(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Backup %s (%d bytes)\n", backup.ID, backup.Size)
+		for _, table := range backup.Tables {
+			fmt.Printf("  %-10s %d rows\n", table.Name, table.Rows)
+		}
+
+	case "backups":
+		list, err := backups.List(ctx)
+		if err != nil {
+			return err
+		}
+		for _, backup := range list {
+			fmt.Printf("%s  %s  %d bytes\n", backup.ID, backup.CreatedAt.Format("2006-01-02 15:04 MST"), backup.Size)
+		}
+
+	case "restore":
+		if len(args) != 1 {
+			return fmt.Errorf("restore needs a backup ID or latest")
+		}
+		restored, err := backups.Restore(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		tables := make([]string, 0, len(restored))
+		for table := range restored {
+			tables = append(tables, table)
+		}
+		sort.Strings(tables)
+		fmt.Println("Restored rows missing from the database:")
+		for _, table := range tables {
+			fmt.Printf("  %-10s %d\n", table, restored[table])
+		}
+	}
+	return nil
+}
+
+func runSuperadminCommand(ctx context.Context, cfg *config.Config, command string, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%s needs the user's email", command)
+	}
+
+	pool, err := pgxpool.New(ctx, cfg.DatabaseURL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer pool.Close()
+	db := database.New(pool, nil, logger.New("warn", io.Discard))
+
+	superadmin := command == "superadmin"
+	if err := services.NewAuthService(db, nil, nil).SetSuperadmin(ctx, args[0], superadmin); err != nil {
+		return err
+	}
+	if superadmin {
+		fmt.Printf("%s is now a superadmin\n", args[0])
+	} else {
+		fmt.Printf("%s is now an admin of their organization\n", args[0])
+	}
+	return nil
+}
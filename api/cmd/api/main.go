@@ -3,24 +3,42 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
+	_ "time/tzdata" // user time zones must not depend on the host's zoneinfo
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"bailanysta/api/internal/config"
+	"bailanysta/api/internal/db/migrations"
+	"bailanysta/api/internal/graph"
 	httpRouter "bailanysta/api/internal/http"
 	"bailanysta/api/internal/http/handlers"
 	"bailanysta/api/internal/pkg/ai"
 	"bailanysta/api/internal/pkg/auth"
+	"bailanysta/api/internal/pkg/captcha"
+	"bailanysta/api/internal/pkg/database"
+	"bailanysta/api/internal/pkg/dbtrace"
+	"bailanysta/api/internal/pkg/geoip"
+	"bailanysta/api/internal/pkg/gifs"
+	"bailanysta/api/internal/pkg/lifecycle"
+	"bailanysta/api/internal/pkg/linkpreview"
 	"bailanysta/api/internal/pkg/logger"
+	"bailanysta/api/internal/pkg/mailer"
+	"bailanysta/api/internal/pkg/s3"
+	"bailanysta/api/internal/pkg/sentry"
+	"bailanysta/api/internal/pkg/webpush"
 	"bailanysta/api/internal/services"
 )
 
@@ -33,19 +51,53 @@ func main() {
 	cfg.PrintConfig()
 
 	// Initialize logger
-	appLogger := logger.New(cfg.LogLevel, os.Stdout)
+	var logOutput io.Writer = os.Stdout
+	var asyncLogOutput *logger.AsyncWriter
+	if cfg.LogAsync {
+		asyncLogOutput = logger.NewAsyncWriter(os.Stdout, cfg.LogFlushInterval)
+		logOutput = asyncLogOutput
+	}
+	appLogger := logger.New(cfg.LogLevel, logOutput)
+	appLogger.SetSampling(cfg.LogSampleInitial, cfg.LogSampleThereafter)
 
 	// Connect to database
-	dbpool, err := connectDB(cfg.DatabaseURL)
+	dbTracer := dbtrace.New(appLogger.Named("db"), cfg.DBSlowQueryThreshold)
+	dbpool, err := connectDB(cfg.DatabaseURL, dbTracer, cfg.DBConnectMaxWait, appLogger.Named("db"))
 	if err != nil {
 		appLogger.Fatal("Failed to connect to database", map[string]interface{}{
 			"error": err.Error(),
 		})
 	}
-	defer dbpool.Close()
-
 	appLogger.Info("Connected to database")
 
+	// The replica isn't pinged here: reads stay on the primary until the
+	// health check reaches it
+	var replicaPool *pgxpool.Pool
+	if cfg.DatabaseReplicaURL != "" {
+		replicaPool, err = newPool(cfg.DatabaseReplicaURL, dbTracer)
+		if err != nil {
+			appLogger.Fatal("Failed to configure read replica", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+	}
+	db := database.New(dbpool, replicaPool, appLogger.Named("db"))
+
+	// pgvector is a hard requirement: migration 0004 and semantic search
+	// need it, so a server without it fails here rather than part way
+	// through the migrations
+	missing, err := migrations.MissingExtensions(context.Background(), dbpool)
+	if err != nil {
+		appLogger.Fatal("Failed to check PostgreSQL extensions", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	if len(missing) > 0 {
+		appLogger.Fatal("PostgreSQL is missing required extensions; for vector, run the pgvector/pgvector image or install pgvector", map[string]interface{}{
+			"missing": strings.Join(missing, ", "),
+		})
+	}
+
 	// Run migrations if enabled
 	if cfg.MigrateOnStart {
 		if err := runMigrations(cfg.DatabaseURL); err != nil {
@@ -56,56 +108,361 @@ func main() {
 		appLogger.Info("Migrations completed")
 	}
 
+	// Drift between the schema and this build is reported, not fatal, so a
+	// rollout can run ahead of its migrations
+	schemaStatus, err := migrations.Inspect(context.Background(), dbpool, cfg.SchemaCheckIndexes)
+	if err != nil {
+		appLogger.Error("Failed to check database schema", map[string]interface{}{
+			"error": err.Error(),
+		})
+	} else {
+		for _, problem := range schemaStatus.Problems() {
+			appLogger.Warn("Database schema drift", map[string]interface{}{
+				"problem":         problem,
+				"applied_version": schemaStatus.Applied,
+				"latest_version":  schemaStatus.Latest,
+			})
+		}
+	}
+
 	// Initialize JWT manager
-	jwtManager := auth.NewJWTManager(cfg.JwtSecret, cfg.JwtExpiry, cfg.RefreshExpiry)
+	jwtManager, err := auth.NewJWTManagerFromConfig(cfg.JWT())
+	if err != nil {
+		appLogger.Fatal("Failed to configure JWT signing", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
 
 	// Initialize AI client
 	aiClient := ai.NewClient(cfg.OpenAIBaseURL, cfg.OpenAIApiKey)
 
+	// Initialize Web Push client
+	var pushClient *webpush.Client
+	if cfg.VAPIDPrivateKey != "" {
+		pushClient, err = webpush.NewClient(cfg.VAPIDPrivateKey, cfg.VAPIDSubject)
+		if err != nil {
+			appLogger.Fatal("Failed to initialize Web Push", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+	}
+
 	// Initialize services
-	notificationsService := services.NewNotificationService(dbpool)
-	authService := services.NewAuthService(dbpool, jwtManager)
-	postsService := services.NewPostsService(dbpool, notificationsService)
-	socialService := services.NewSocialService(dbpool, notificationsService)
-	aiService := services.NewAIService(aiClient)
+	notificationsService := services.NewNotificationService(db, pushClient, cfg.UnreadCountCacheTTL)
+	var smtpMailer *mailer.Mailer
+	if cfg.SMTPHost != "" {
+		smtpMailer = mailer.New(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom)
+	}
+	var geoDB *geoip.DB
+	if cfg.GeoIPDBPath != "" {
+		geoDB, err = geoip.Open(cfg.GeoIPDBPath)
+		if err != nil {
+			log.Fatalf("Failed to open GeoIP database: %v", err)
+		}
+	}
+	var asnDB *geoip.DB
+	if cfg.GeoIPASNDBPath != "" {
+		asnDB, err = geoip.Open(cfg.GeoIPASNDBPath)
+		if err != nil {
+			log.Fatalf("Failed to open GeoIP ASN database: %v", err)
+		}
+	}
+	ipBlocklist := services.NewIPBlocklistService(db, asnDB)
+	if err := ipBlocklist.Refresh(context.Background()); err != nil {
+		log.Fatalf("Failed to load IP blocklist: %v", err)
+	}
+	deviceService := services.NewDeviceService(db, geoDB, notificationsService, smtpMailer, cfg.AppURL)
+	authService := services.NewAuthService(db, jwtManager, deviceService)
+	contentFilterService := services.NewContentFilterService(dbpool, services.ContentFilterConfig{
+		Words:           cfg.ContentFilterWordList(),
+		WordsAction:     services.FilterAction(cfg.ContentFilterWordsAction),
+		MaxLinks:        cfg.ContentFilterMaxLinks,
+		LinksAction:     services.FilterAction(cfg.ContentFilterLinksAction),
+		DuplicateWindow: cfg.ContentFilterDuplicateWindow,
+		DuplicateAction: services.FilterAction(cfg.ContentFilterDuplicateAction),
+	})
+	postsContentFilter := contentFilterService
+	if !cfg.ContentFilterEnabled {
+		postsContentFilter = nil
+	}
+	var gifClient *gifs.Client
+	if cfg.GIFProvider != "" {
+		gifClient, err = gifs.NewClient(cfg.GIFProvider, cfg.GIFAPIKey, cfg.GIFTimeout)
+		if err != nil {
+			appLogger.Fatal("Failed to configure GIF search", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+	}
+	gifService := services.NewGIFService(gifClient, cfg.GIFCacheTTL)
+	abuseService := services.NewAbuseService(db, services.AbuseLimits{
+		FollowsPerMinute: cfg.AbuseFollowsPerMinute,
+		LikesPerMinute:   cfg.AbuseLikesPerMinute,
+		Throttle:         cfg.AbuseThrottle,
+		BlockIPFor:       cfg.AbuseBlockIPFor,
+	}, ipBlocklist)
+	duplicateService := services.NewDuplicateService(dbpool, services.DuplicateConfig{
+		Window:      cfg.DuplicateWindow,
+		MaxDistance: cfg.DuplicateMaxDistance,
+	})
+	postLimitsService := services.NewPostLimitsService(dbpool, services.PostLimits{
+		MaxChars: cfg.PostMaxChars,
+		MaxWords: cfg.PostMaxWords,
+	})
+	postsService := services.NewPostsService(dbpool, notificationsService, postsContentFilter, gifService, abuseService, duplicateService, postLimitsService, cfg.PostDetailComments, cfg.FeedFanoutEnabled, cfg.PostDetailCacheTTL)
+	socialService := services.NewSocialService(db, notificationsService, services.FeedRankingWeights{
+		Recency:    cfg.FeedRankRecencyWeight,
+		Engagement: cfg.FeedRankEngagementWeight,
+		Affinity:   cfg.FeedRankAffinityWeight,
+		Course:     cfg.FeedRankCourseWeight,
+		HalfLife:   cfg.FeedRankHalfLife,
+		Window:     cfg.FeedRankWindow,
+		AIPenalty:  cfg.FeedRankAIPenalty,
+	}, cfg.FeedFanoutEnabled, abuseService)
+	// AI output is held to the same wordlist as posts
+	var aiBlockedWords []string
+	if cfg.ContentFilterEnabled {
+		aiBlockedWords = cfg.ContentFilterWordList()
+	}
+	aiService := services.NewAIService(aiClient, dbpool, cfg.AICacheTTL, aiLimits(cfg), aiBlockedWords)
+	aiQuotaService := services.NewAIQuotaService(dbpool, cfg.AIDailyRequestQuota, cfg.AIDailyTokenQuota)
+	impersonationService := services.NewImpersonationService(db, jwtManager, cfg.ImpersonationTTL)
+	presenceService := services.NewPresenceService(db, cfg.PresenceWriteInterval)
+	profileViewService := services.NewProfileViewService(db, cfg.ProfileViewRetentionDays)
+	analyticsService := services.NewAnalyticsService(dbpool)
+	eventService := services.NewEventService(db, cfg.AppURL, cfg.EventReminderLead, cfg.EventReminderInterval)
+	leaderboardService := services.NewLeaderboardService(db, cfg.LeaderboardRefreshInterval)
+	activityService := services.NewActivityService(db, notificationsService, cfg.StreakReminderHour)
+	exportService := services.NewExportService(db)
+	assignmentService := services.NewAssignmentService(db, notificationsService, cfg.AssignmentReminderLead)
+
+	emailDigestService := services.NewEmailDigestService(dbpool, notificationsService, smtpMailer,
+		cfg.JwtSecret, cfg.AppURL, cfg.APIURL, cfg.EmailDigestInterval, cfg.EmailDigestHour)
+
+	var waitlistService *services.WaitlistService
+	if cfg.RegistrationMode == services.RegistrationWaitlist {
+		waitlistService = services.NewWaitlistService(db, smtpMailer, cfg.AppURL, cfg.WaitlistInviteTTL)
+	}
+
+	var embeddingService *services.EmbeddingService
+	if cfg.EmbeddingsEnabled {
+		embeddingService = services.NewEmbeddingService(dbpool, aiClient, cfg.EmbeddingModel, cfg.EmbeddingInterval)
+	}
+
+	// Background workers are drained on shutdown before the pool is closed
+	workers := lifecycle.New(appLogger.Named("workers"))
+	if cfg.SentryDSN != "" {
+		reporter, err := sentry.New(sentry.Config{
+			DSN:         cfg.SentryDSN,
+			Environment: cfg.SentryEnvironment,
+			Release:     cfg.SentryRelease,
+		})
+		if err != nil {
+			log.Fatalf("Failed to configure error reporting: %v", err)
+		}
+		sentry.Default = reporter
+		workers.Go("error-reporting", reporter.Run)
+	}
+	if embeddingService != nil {
+		workers.Go("embeddings", embeddingService.Run)
+	}
+	if db.HasReplica() {
+		workers.Go("db-replica-health", db.RunHealthCheck)
+	}
+	workers.Go("push-dispatcher", notificationsService.RunPushDispatcher)
+	workers.Go("notification-outbox", notificationsService.RunOutboxDispatcher)
+	workers.Go("notification-metrics", notificationsService.RunBacklogMetrics)
+	workers.Go("email-digest", emailDigestService.Run)
+	workers.Go("event-reminders", eventService.Run)
+	workers.Go("leaderboard-refresh", leaderboardService.Run)
+	workers.Go("streak-reminders", activityService.Run)
+	workers.Go("abuse-cleanup", abuseService.Run)
+	workers.Go("ip-blocklist", ipBlocklist.Run)
+	workers.Go("assignment-reminders", assignmentService.Run)
+	workers.Go("presence", presenceService.Run)
+	workers.Go("profile-view-cleanup", profileViewService.Run)
+	workers.Go("ai-cache-cleanup", aiService.RunCachePurge)
+	if cfg.LinkPreviewsEnabled {
+		linkPreviewService := services.NewLinkPreviewService(dbpool, linkpreview.NewFetcher(cfg.LinkPreviewTimeout), cfg.LinkPreviewTTL, cfg.LinkPreviewInterval)
+		workers.Go("link-previews", linkPreviewService.Run)
+	}
+	if cfg.NotificationRetentionDays > 0 {
+		notificationCleanupService := services.NewNotificationCleanupService(dbpool,
+			time.Duration(cfg.NotificationRetentionDays)*24*time.Hour, cfg.NotificationRetentionAction, cfg.NotificationCleanupInterval)
+		workers.Go("notification-cleanup", notificationCleanupService.Run)
+	}
+	if cfg.BackupS3Bucket != "" {
+		store, err := s3.New(cfg.BackupS3())
+		if err != nil {
+			log.Fatalf("Failed to configure backups: %v", err)
+		}
+		backupService := services.NewBackupService(db, store, cfg.BackupS3Prefix, cfg.BackupInterval, cfg.BackupRetention)
+		workers.Go("backups", backupService.Run)
+	}
+	if cfg.FeedFanoutEnabled {
+		workers.Go("feed-backfill", socialService.RunFeedBackfill)
+	} else if err := socialService.ResetMaterializedFeeds(context.Background()); err != nil {
+		appLogger.Error("Failed to reset materialized feeds", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
 
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(authService, appLogger)
-	postsHandler := handlers.NewPostsHandler(postsService, appLogger, jwtManager)
-	socialHandler := handlers.NewSocialHandler(socialService, appLogger, jwtManager)
-	usersHandler := handlers.NewUsersHandler(authService, socialService, appLogger, jwtManager)
-	searchHandler := handlers.NewSearchHandler(dbpool, appLogger, jwtManager)
-	notificationsHandler := handlers.NewNotificationsHandler(notificationsService, appLogger, jwtManager)
-	aiHandler := handlers.NewAIHandler(aiService, appLogger)
+	var botProtection *handlers.BotProtection
+	if cfg.BotProtection != "" {
+		var verifier captcha.Verifier
+		if cfg.BotProtection == captcha.ProviderPoW {
+			verifier = captcha.NewProofOfWork(cfg.JwtSecret, cfg.BotProtectionPoWMaxNumber, cfg.BotProtectionPoWTTL)
+		} else {
+			verifier, err = captcha.NewSiteVerifier(cfg.BotProtection, cfg.BotProtectionSecret, cfg.BotProtectionTimeout)
+			if err != nil {
+				log.Fatalf("Failed to configure bot protection: %v", err)
+			}
+		}
+		botProtection = handlers.NewBotProtection(cfg.BotProtection, cfg.BotProtectionSiteKey, verifier,
+			cfg.BotProtectionLoginFailures, cfg.BotProtectionFailureWindow, appLogger.Named("bots"))
+	}
+	authHandler := handlers.NewAuthHandler(authService, waitlistService, botProtection, appLogger.Named("auth"), handlers.SessionCookies{
+		Enabled:  cfg.AuthCookiesEnabled,
+		Domain:   cfg.AuthCookieDomain,
+		Secure:   cfg.AuthCookieSecure,
+		SameSite: cfg.CookieSameSite(),
+		MaxAge:   cfg.RefreshExpiry,
+	})
+	postsHandler := handlers.NewPostsHandler(postsService, appLogger.Named("posts"), jwtManager)
+	socialHandler := handlers.NewSocialHandler(socialService, appLogger.Named("social"), jwtManager)
+	groupsHandler := handlers.NewGroupsHandler(socialService, appLogger.Named("groups"), jwtManager)
+	eventsHandler := handlers.NewEventsHandler(eventService, appLogger.Named("events"), jwtManager)
+	leaderboardHandler := handlers.NewLeaderboardHandler(leaderboardService, appLogger.Named("leaderboard"), jwtManager)
+	activityHandler := handlers.NewActivityHandler(activityService, appLogger.Named("activity"), jwtManager)
+	usersHandler := handlers.NewUsersHandler(authService, socialService, profileViewService, appLogger.Named("users"), jwtManager)
+	searchHandler := handlers.NewSearchHandler(db, embeddingService, appLogger.Named("search"), jwtManager, cfg.SearchSuggestCacheTTL, cfg.SearchSuggestTimeout)
+	notificationsHandler := handlers.NewNotificationsHandler(notificationsService, emailDigestService, appLogger.Named("notifications"), jwtManager)
+	streamHandler := handlers.NewStreamHandler(socialService, notificationsService, appLogger.Named("stream"), jwtManager, cfg.StreamPollInterval)
+	coursesHandler := handlers.NewCoursesHandler(services.NewCourseProgressService(db, notificationsService), appLogger.Named("courses"), jwtManager)
+	assignmentsHandler := handlers.NewAssignmentsHandler(assignmentService, aiService, appLogger.Named("assignments"), jwtManager)
+	certificatesHandler := handlers.NewCertificatesHandler(services.NewCertificateService(db, cfg.APIURL), appLogger.Named("certificates"), jwtManager)
+	flashcardsHandler := handlers.NewFlashcardsHandler(services.NewFlashcardService(db), aiService, postsService, appLogger.Named("flashcards"), jwtManager)
+	aiHandler := handlers.NewAIHandler(aiService, postsService, socialService, appLogger.Named("ai"), jwtManager)
+	analyticsHandler := handlers.NewAnalyticsHandler(analyticsService, appLogger.Named("analytics"), jwtManager)
+	gifsHandler := handlers.NewGIFsHandler(gifService, appLogger.Named("gifs"), jwtManager)
+	syndicationHandler := handlers.NewSyndicationHandler(services.NewSyndicationService(db, cfg.AppURL, cfg.APIURL), appLogger.Named("syndication"), jwtManager)
+	var graphQLHandler *handlers.GraphQLHandler
+	if cfg.GraphQLEnabled {
+		resolver := graph.NewResolver(authService, postsService, socialService, notificationsService)
+		graphQLHandler = handlers.NewGraphQLHandler(resolver, cfg.GraphQLComplexityLimit, appLogger.Named("graphql"), jwtManager)
+	}
+	adminHandler := handlers.NewAdminHandler(authService, contentFilterService, aiService, abuseService, duplicateService, appLogger.Named("admin"), jwtManager)
+	exportHandler := handlers.NewExportHandler(exportService, appLogger.Named("export"), jwtManager)
+	limitsHandler := handlers.NewLimitsHandler(aiQuotaService, appLogger.Named("limits"), jwtManager)
+	apiKeysHandler := handlers.NewAPIKeysHandler(authService, appLogger.Named("api-keys"), jwtManager)
+	recoveryHandler := handlers.NewRecoveryHandler(services.NewRecoveryService(db, authService, smtpMailer, cfg.AppURL, cfg.RecoveryTokenTTL),
+		authHandler, appLogger.Named("recovery"), jwtManager)
+	maintenanceMode := handlers.NewMaintenanceMode(cfg.MaintenanceMode, cfg.MaintenanceMessage, cfg.MaintenanceRetryAfter)
+	waitlistHandler := handlers.NewWaitlistHandler(waitlistService, appLogger.Named("waitlist"), jwtManager)
+	maintenanceHandler := handlers.NewMaintenanceHandler(maintenanceMode, appLogger.Named("maintenance"), jwtManager)
+	impersonationHandler := handlers.NewImpersonationHandler(impersonationService, appLogger.Named("impersonation"), jwtManager)
 
 	handlers := &httpRouter.Handlers{
 		Auth:          authHandler,
 		Posts:         postsHandler,
 		Social:        socialHandler,
+		Groups:        groupsHandler,
+		Events:        eventsHandler,
+		Leaderboard:   leaderboardHandler,
+		Activity:      activityHandler,
 		Users:         usersHandler,
 		Search:        searchHandler,
 		Notifications: notificationsHandler,
+		Stream:        streamHandler,
 		AI:            aiHandler,
+		Flashcards:    flashcardsHandler,
+		Courses:       coursesHandler,
+		Assignments:   assignmentsHandler,
+		Certificates:  certificatesHandler,
+		GIFs:          gifsHandler,
+		Analytics:     analyticsHandler,
+		Admin:         adminHandler,
+		Export:        exportHandler,
+		Limits:        limitsHandler,
+		APIKeys:       apiKeysHandler,
+		Impersonation: impersonationHandler,
+		Maintenance:   maintenanceHandler,
+		Waitlist:      waitlistHandler,
+		IPBlocks:      handlers.NewIPBlocksHandler(ipBlocklist, appLogger.Named("ip-blocks"), jwtManager),
+		PostLimits:    handlers.NewPostLimitsHandler(postLimitsService, appLogger.Named("post-limits"), jwtManager),
+		Recovery:      recoveryHandler,
+		BotProtection: botProtection,
 		Health:        &handlers.HealthHandler{Logger: appLogger},
+		Syndication:   syndicationHandler,
+		Sharing:       handlers.NewSharingHandler(services.NewSharingService(db, cfg.AppURL, cfg.APIURL), appLogger.Named("sharing"), jwtManager),
+		ShortLinks:    handlers.NewShortLinksHandler(services.NewShortLinkService(dbpool), appLogger.Named("short-links")),
+		GraphQL:       graphQLHandler,
 	}
 
 	// Create router
 	router := httpRouter.NewRouter(&httpRouter.Deps{
-		Config:     cfg,
-		Logger:     appLogger,
-		Handlers:   handlers,
-		JWTManager: jwtManager,
+		Config:        cfg,
+		Logger:        appLogger.Named("http"),
+		Handlers:      handlers,
+		JWTManager:    jwtManager,
+		AuthService:   authService,
+		AIQuota:       aiQuotaService,
+		Impersonation: impersonationService,
+		Presence:      presenceService,
+		IPBlocklist:   ipBlocklist,
+		Maintenance:   maintenanceMode,
+		ErrorReporter: sentry.Default,
+	})
+
+	// Re-read config on SIGHUP and apply the settings that can change at runtime
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	workers.Go("config-reload", func(ctx context.Context) {
+		defer signal.Stop(hup)
+		current := cfg
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				current = reloadConfig(current, appLogger, router, aiService)
+			}
+		}
 	})
 
 	// Start server
 	srv := &http.Server{
-		Addr:         ":" + cfg.Port,
-		Handler:      router,
-		ReadTimeout:  120 * time.Second, // Increased for AI requests
-		WriteTimeout: 120 * time.Second, // Increased for AI requests
+		Addr:              ":" + cfg.Port,
+		Handler:           router,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		// Route deadlines are enforced by the router; this is only a backstop
+		WriteTimeout: maxDuration(cfg.RequestTimeoutRead, cfg.RequestTimeoutWrite, cfg.RequestTimeoutAI, cfg.RequestTimeoutExport) + 5*time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Profiling and runtime stats stay off the public port. Profiles and
+	// traces stream for as long as asked, so there is no write timeout.
+	var diagnosticsSrv *http.Server
+	if cfg.DiagnosticsAddr != "" {
+		diagnosticsSrv = &http.Server{
+			Addr:              cfg.DiagnosticsAddr,
+			Handler:           httpRouter.NewDiagnosticsHandler(cfg.DiagnosticsToken, db, workers.Running),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			appLogger.Info("Starting diagnostics server", map[string]interface{}{
+				"addr": cfg.DiagnosticsAddr,
+			})
+			if err := diagnosticsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				appLogger.Error("Diagnostics server failed", map[string]interface{}{
+					"error": err.Error(),
+				})
+			}
+		}()
+	}
+
 	// Channel to listen for interrupt signal
 	done := make(chan bool, 1)
 	quit := make(chan os.Signal, 1)
@@ -130,44 +487,174 @@ func main() {
 	appLogger.Info("Server is shutting down...")
 
 	// Create context with timeout for graceful shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer cancel()
 
-	// Attempt graceful shutdown
+	// Long AI generations would otherwise hold the shutdown until its deadline
+	aiTimer := time.AfterFunc(cfg.ShutdownAIGrace, func() {
+		appLogger.Warn("Cancelling in-flight AI requests")
+		aiClient.CancelInFlight()
+	})
+	defer aiTimer.Stop()
+
+	// Stop accepting requests and wait for in-flight ones; they may still
+	// queue work for the background workers
 	if err := srv.Shutdown(ctx); err != nil {
 		appLogger.Error("Server forced to shutdown", map[string]interface{}{
 			"error": err.Error(),
 		})
 	}
+	aiClient.CancelInFlight()
+	if diagnosticsSrv != nil {
+		diagnosticsSrv.Close()
+	}
+
+	if err := workers.Shutdown(ctx); err != nil {
+		appLogger.Error("Background workers did not stop cleanly", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	db.Close()
 
 	close(done)
 	appLogger.Info("Server exited")
+	if asyncLogOutput != nil {
+		asyncLogOutput.Close()
+	}
+}
+
+func maxDuration(durations ...time.Duration) time.Duration {
+	var longest time.Duration
+	for _, d := range durations {
+		longest = max(longest, d)
+	}
+	return longest
 }
 
-func connectDB(databaseURL string) (*pgxpool.Pool, error) {
+// reloadConfig loads configuration again and applies log levels, CORS origins
+// and rate limits. On error the current config stays in effect.
+func reloadConfig(current *config.Config, appLogger *logger.Logger, router *httpRouter.Router, aiService *services.AIService) *config.Config {
+	next, err := config.Load()
+	if err != nil {
+		appLogger.Error("Failed to reload config", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return current
+	}
+
+	changed := current.Changed(next)
+	if next.LogLevel != current.LogLevel {
+		appLogger.SetLevels(next.LogLevel)
+	}
+	router.ApplyConfig(next)
+	aiService.SetLimits(aiLimits(next))
+
+	if restart := config.RequiresRestart(changed); len(restart) > 0 {
+		appLogger.Warn("Config changes ignored until restart", map[string]interface{}{
+			"settings": restart,
+		})
+	}
+	appLogger.Info("Config reloaded", map[string]interface{}{
+		"changed": changed,
+	})
+	return next
+}
+
+// aiLimits collects the AI completion limits from cfg
+func aiLimits(cfg *config.Config) services.AILimits {
+	return services.AILimits{
+		Text:        services.AITaskLimits{DefaultTokens: cfg.AITextDefaultTokens, MaxTokens: cfg.AITextMaxTokens, Temperature: cfg.AITextTemperature},
+		Post:        services.AITaskLimits{DefaultTokens: cfg.AIPostDefaultTokens, MaxTokens: cfg.AIPostMaxTokens, Temperature: cfg.AIPostTemperature},
+		Comment:     services.AITaskLimits{DefaultTokens: cfg.AICommentDefaultTokens, MaxTokens: cfg.AICommentMaxTokens, Temperature: cfg.AICommentTemperature},
+		Rewrite:     services.AITaskLimits{MaxTokens: cfg.AIRewriteMaxTokens, Temperature: cfg.AIRewriteTemperature},
+		StudyNotes:  services.AITaskLimits{MaxTokens: cfg.AIStudyNotesMaxTokens, Temperature: cfg.AIStudyNotesTemperature},
+		Quiz:        services.AITaskLimits{MaxTokens: cfg.AIQuizMaxTokens, Temperature: cfg.AIQuizTemperature},
+		Flashcards:  services.AITaskLimits{MaxTokens: cfg.AIFlashcardsMaxTokens, Temperature: cfg.AIFlashcardsTemperature},
+		Grading:     services.AITaskLimits{MaxTokens: cfg.AIGradingMaxTokens, Temperature: cfg.AIGradingTemperature},
+		Explain:     services.AITaskLimits{MaxTokens: cfg.AIExplainMaxTokens, Temperature: cfg.AIExplainTemperature},
+		DigestBatch: services.AITaskLimits{MaxTokens: cfg.AIDigestBatchMaxTokens, Temperature: cfg.AIDigestBatchTemperature},
+		Digest:      services.AITaskLimits{MaxTokens: cfg.AIDigestMaxTokens, Temperature: cfg.AIDigestTemperature},
+		Timeout:     cfg.AICompletionTimeout,
+	}
+}
+
+// Backoff between attempts to reach the database at startup
+const (
+	connectRetryBase = 500 * time.Millisecond
+	connectRetryMax  = 10 * time.Second
+)
+
+// connectDB creates the pool and waits up to maxWait for the database to
+// answer, e.g. while Postgres is still starting next to the API
+func connectDB(databaseURL string, tracer pgx.QueryTracer, maxWait time.Duration, log *logger.Logger) (*pgxpool.Pool, error) {
+	pool, err := newPool(databaseURL, tracer)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(maxWait)
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err = pool.Ping(ctx)
+		cancel()
+		if err == nil {
+			return pool, nil
+		}
+
+		delay := connectRetryDelay(attempt)
+		if time.Now().Add(delay).After(deadline) {
+			pool.Close()
+			return nil, fmt.Errorf("failed to ping database after %d attempts: %w", attempt, err)
+		}
+		log.Warn("Database not ready, retrying", map[string]interface{}{
+			"attempt":  attempt,
+			"retry_in": delay.String(),
+			"error":    err.Error(),
+		})
+		time.Sleep(delay)
+	}
+}
+
+func connectRetryDelay(attempt int) time.Duration {
+	delay := connectRetryBase
+	for i := 1; i < attempt && delay < connectRetryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, connectRetryMax)
+}
+
+// newPool creates a pool without connecting; connections are opened lazily
+func newPool(databaseURL string, tracer pgx.QueryTracer) (*pgxpool.Pool, error) {
 	config, err := pgxpool.ParseConfig(databaseURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse database config: %w", err)
 	}
+	config.ConnConfig.Tracer = tracer
+	// Scan timestamptz values in UTC so the API returns the same RFC 3339
+	// timestamps whatever the server's time zone is
+	config.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+		conn.TypeMap().RegisterType(&pgtype.Type{
+			Name:  "timestamptz",
+			OID:   pgtype.TimestamptzOID,
+			Codec: &pgtype.TimestamptzCodec{ScanLocation: time.UTC},
+		})
+		return nil
+	}
 
 	pool, err := pgxpool.NewWithConfig(context.Background(), config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create connection pool: %w", err)
 	}
-
-	// Test connection
-	if err := pool.Ping(context.Background()); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
-	}
-
 	return pool, nil
 }
 
 func runMigrations(databaseURL string) error {
-	m, err := migrate.New(
-		"file://api/internal/db/migrations",
-		databaseURL,
-	)
+	source, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return fmt.Errorf("failed to read migrations: %w", err)
+	}
+	m, err := migrate.NewWithSourceInstance("iofs", source, databaseURL)
 	if err != nil {
 		return fmt.Errorf("failed to create migrate instance: %w", err)
 	}
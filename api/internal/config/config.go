@@ -3,32 +3,359 @@ package config
 import (
 	"fmt"
 	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/kelseyhightower/envconfig"
+
+	"bailanysta/api/internal/pkg/auth"
+	"bailanysta/api/internal/pkg/logger"
+	"bailanysta/api/internal/pkg/s3"
 )
 
 type Config struct {
+	// Optional YAML file keyed by env var name; the environment takes
+	// precedence. Any setting can also be read from a file via NAME_FILE.
+	ConfigFile string `envconfig:"CONFIG_FILE"`
+
 	Port           string        `envconfig:"PORT" default:"8080"`
 	DatabaseURL    string        `envconfig:"DATABASE_URL" required:"true"`
-	JwtSecret      string        `envconfig:"JWT_SECRET" required:"true"`
+	JwtSecret      string        `envconfig:"JWT_SECRET"`
 	JwtExpiry      time.Duration `envconfig:"JWT_EXPIRY" default:"15m"`
 	RefreshExpiry  time.Duration `envconfig:"REFRESH_EXPIRY" default:"168h"`
-	CORSOrigin     string        `envconfig:"CORS_ORIGIN" default:"http://localhost:3000"`
+	CORSOrigin     string        `envconfig:"CORS_ORIGIN" default:"http://localhost:3000"` // comma-separated
 	MigrateOnStart bool          `envconfig:"MIGRATE_ON_START" default:"false"`
-	LogLevel       string        `envconfig:"LOG_LEVEL" default:"info"`
+	LogLevel       string        `envconfig:"LOG_LEVEL" default:"info"` // e.g. "info,http=warn,ai=debug"
+
+	// Reverse proxies, as comma-separated IPs or CIDRs, whose X-Forwarded-For
+	// and X-Real-IP headers name the client. From anyone else the headers are
+	// ignored, since they would let a client choose the address its rate
+	// limit, IP blocks and sessions go by.
+	TrustedProxies string `envconfig:"TRUSTED_PROXIES" default:"127.0.0.1/32,::1/128"`
+
+	// Startup compares the schema with the embedded migrations and warns on
+	// drift; this also checks that the indexes of feed and search queries exist
+	SchemaCheckIndexes bool `envconfig:"SCHEMA_CHECK_INDEXES" default:"true"`
+
+	// Access token signing: HS256 with JWT_SECRET, or RS256/EdDSA with
+	// JWT_PRIVATE_KEY (PEM), whose public keys are served as a JWKS. Previous
+	// secrets and public keys are still accepted while tokens signed with
+	// them expire.
+	JwtAlgorithm          string `envconfig:"JWT_ALGORITHM" default:"HS256"`
+	JwtPreviousSecrets    string `envconfig:"JWT_PREVIOUS_SECRETS"` // comma-separated
+	JwtPrivateKey         string `envconfig:"JWT_PRIVATE_KEY"`
+	JwtPreviousPublicKeys string `envconfig:"JWT_PREVIOUS_PUBLIC_KEYS"` // concatenated PEM blocks
+	JwtIssuer             string `envconfig:"JWT_ISSUER" default:"bailanysta"`
+	JwtAudience           string `envconfig:"JWT_AUDIENCE" default:"bailanysta-api"`
+
+	// Cookie session mode for web clients: the refresh token goes in an
+	// httpOnly cookie, guarded by a double-submit CSRF token.
+	// AUTH_COOKIE_SAMESITE is lax, strict or none (which needs Secure).
+	AuthCookiesEnabled bool   `envconfig:"AUTH_COOKIES_ENABLED" default:"false"`
+	AuthCookieDomain   string `envconfig:"AUTH_COOKIE_DOMAIN"`
+	AuthCookieSecure   bool   `envconfig:"AUTH_COOKIE_SECURE" default:"true"`
+	AuthCookieSameSite string `envconfig:"AUTH_COOKIE_SAMESITE" default:"lax"`
+
+	// Lifetime of tokens admins get to act as a user; they can't be refreshed
+	ImpersonationTTL time.Duration `envconfig:"IMPERSONATION_TTL" default:"15m"`
+
+	// Optional read replica for feed, search, notification listing and user
+	// lookups; reads fall back to the primary while it is unreachable
+	DatabaseReplicaURL string `envconfig:"DATABASE_REPLICA_URL"`
+
+	// How long startup waits for the database to accept connections, retrying
+	// with backoff (0 tries once)
+	DBConnectMaxWait time.Duration `envconfig:"DB_CONNECT_MAX_WAIT" default:"60s"`
+
+	// SQL statements at least this slow are logged at WARN by the "db"
+	// logger (0 disables); all statements are logged at DEBUG
+	DBSlowQueryThreshold time.Duration `envconfig:"DB_SLOW_QUERY_THRESHOLD" default:"200ms"`
+
+	// Log output: opt-in async buffering and per-message sampling of INFO/DEBUG
+	// (LOG_SAMPLE_INITIAL=0 disables sampling)
+	LogAsync            bool          `envconfig:"LOG_ASYNC" default:"false"`
+	LogFlushInterval    time.Duration `envconfig:"LOG_FLUSH_INTERVAL" default:"1s"`
+	LogSampleInitial    int           `envconfig:"LOG_SAMPLE_INITIAL" default:"0"`
+	LogSampleThereafter int           `envconfig:"LOG_SAMPLE_THEREAFTER" default:"100"`
+
+	// Debug body capture: requests under DEBUG_CAPTURE_ROUTES (comma-separated
+	// path prefixes), and admin requests sending X-Debug-Capture, are logged
+	// with their bodies, sanitized and cut at DEBUG_CAPTURE_MAX_BYTES
+	// (0 disables capture)
+	DebugCaptureRoutes   string `envconfig:"DEBUG_CAPTURE_ROUTES"`
+	DebugCaptureMaxBytes int    `envconfig:"DEBUG_CAPTURE_MAX_BYTES" default:"4096"`
+
+	// Request deadlines: reads (GET/HEAD/OPTIONS), writes, and /ai/* routes.
+	// Requests over the deadline get a 504. Streamed admin exports and the
+	// event stream are cut off at theirs; stream clients then reconnect.
+	RequestTimeoutRead   time.Duration `envconfig:"REQUEST_TIMEOUT_READ" default:"5s"`
+	RequestTimeoutWrite  time.Duration `envconfig:"REQUEST_TIMEOUT_WRITE" default:"15s"`
+	RequestTimeoutAI     time.Duration `envconfig:"REQUEST_TIMEOUT_AI" default:"120s"`
+	RequestTimeoutExport time.Duration `envconfig:"REQUEST_TIMEOUT_EXPORT" default:"10m"`
+	RequestTimeoutStream time.Duration `envconfig:"REQUEST_TIMEOUT_STREAM" default:"30m"`
+
+	// Graceful shutdown: HTTP requests and workers share ShutdownTimeout,
+	// AI calls still running after ShutdownAIGrace are cancelled
+	ShutdownTimeout time.Duration `envconfig:"SHUTDOWN_TIMEOUT" default:"30s"`
+	ShutdownAIGrace time.Duration `envconfig:"SHUTDOWN_AI_GRACE" default:"10s"`
 
 	// AI Configuration
-	OpenAIBaseURL string `envconfig:"OPENAI_BASE_URL" default:"https://api.openai.com/v1"`
-	OpenAIApiKey  string `envconfig:"OPENAI_API_KEY"`
+	OpenAIBaseURL string        `envconfig:"OPENAI_BASE_URL" default:"https://api.openai.com/v1"`
+	OpenAIApiKey  string        `envconfig:"OPENAI_API_KEY"`
+	AICacheTTL    time.Duration `envconfig:"AI_CACHE_TTL" default:"24h"` // 0 disables caching
+
+	// Per-user AI limits per UTC day; 0 is unlimited
+	AIDailyRequestQuota int   `envconfig:"AI_DAILY_REQUEST_QUOTA" default:"0"`
+	AIDailyTokenQuota   int64 `envconfig:"AI_DAILY_TOKEN_QUOTA" default:"0"`
+
+	// Completion limits per AI feature: the length used when the client
+	// doesn't ask (DEFAULT_TOKENS), the most it may ask for (MAX_TOKENS) and
+	// the default temperature. Rewrites are sized to the text, up to their
+	// cap. Each completion must finish within AI_COMPLETION_TIMEOUT. All of
+	// these apply on reload.
+	AITextDefaultTokens      int           `envconfig:"AI_TEXT_DEFAULT_TOKENS" default:"500"`
+	AITextMaxTokens          int           `envconfig:"AI_TEXT_MAX_TOKENS" default:"4000"`
+	AITextTemperature        float32       `envconfig:"AI_TEXT_TEMPERATURE" default:"0.7"`
+	AIPostDefaultTokens      int           `envconfig:"AI_POST_DEFAULT_TOKENS" default:"800"`
+	AIPostMaxTokens          int           `envconfig:"AI_POST_MAX_TOKENS" default:"2000"`
+	AIPostTemperature        float32       `envconfig:"AI_POST_TEMPERATURE" default:"0.7"`
+	AICommentDefaultTokens   int           `envconfig:"AI_COMMENT_DEFAULT_TOKENS" default:"200"`
+	AICommentMaxTokens       int           `envconfig:"AI_COMMENT_MAX_TOKENS" default:"500"`
+	AICommentTemperature     float32       `envconfig:"AI_COMMENT_TEMPERATURE" default:"0.8"`
+	AIRewriteMaxTokens       int           `envconfig:"AI_REWRITE_MAX_TOKENS" default:"2000"`
+	AIRewriteTemperature     float32       `envconfig:"AI_REWRITE_TEMPERATURE" default:"0.5"`
+	AIStudyNotesMaxTokens    int           `envconfig:"AI_STUDY_NOTES_MAX_TOKENS" default:"2500"`
+	AIStudyNotesTemperature  float32       `envconfig:"AI_STUDY_NOTES_TEMPERATURE" default:"0.3"`
+	AIQuizMaxTokens          int           `envconfig:"AI_QUIZ_MAX_TOKENS" default:"600"`
+	AIQuizTemperature        float32       `envconfig:"AI_QUIZ_TEMPERATURE" default:"0.5"`
+	AIFlashcardsMaxTokens    int           `envconfig:"AI_FLASHCARDS_MAX_TOKENS" default:"1500"`
+	AIFlashcardsTemperature  float32       `envconfig:"AI_FLASHCARDS_TEMPERATURE" default:"0.4"`
+	AIGradingMaxTokens       int           `envconfig:"AI_GRADING_MAX_TOKENS" default:"1200"`
+	AIGradingTemperature     float32       `envconfig:"AI_GRADING_TEMPERATURE" default:"0.2"`
+	AIExplainMaxTokens       int           `envconfig:"AI_EXPLAIN_MAX_TOKENS" default:"2000"`
+	AIExplainTemperature     float32       `envconfig:"AI_EXPLAIN_TEMPERATURE" default:"0.6"`
+	AIDigestBatchMaxTokens   int           `envconfig:"AI_DIGEST_BATCH_MAX_TOKENS" default:"600"`
+	AIDigestBatchTemperature float32       `envconfig:"AI_DIGEST_BATCH_TEMPERATURE" default:"0.4"`
+	AIDigestMaxTokens        int           `envconfig:"AI_DIGEST_MAX_TOKENS" default:"800"`
+	AIDigestTemperature      float32       `envconfig:"AI_DIGEST_TEMPERATURE" default:"0.3"`
+	AICompletionTimeout      time.Duration `envconfig:"AI_COMPLETION_TIMEOUT" default:"90s"`
+
+	// Semantic search (requires pgvector; model must produce 1536-dim vectors)
+	EmbeddingsEnabled bool          `envconfig:"EMBEDDINGS_ENABLED" default:"false"`
+	EmbeddingModel    string        `envconfig:"EMBEDDING_MODEL" default:"text-embedding-3-small"`
+	EmbeddingInterval time.Duration `envconfig:"EMBEDDING_INTERVAL" default:"1m"`
+
+	// Number of comments embedded in GET /posts/{id} (0 disables)
+	PostDetailComments int `envconfig:"POST_DETAIL_COMMENTS" default:"6"`
+
+	// Default post length limits, in characters and words (0 for no word
+	// limit); organizations can override them per course and post type
+	PostMaxChars int `envconfig:"POST_MAX_CHARS" default:"5000"`
+	PostMaxWords int `envconfig:"POST_MAX_WORDS" default:"0"`
+
+	// Concurrent reads of the same post detail or unread count share one
+	// query, and the result is kept this long (0 only coalesces)
+	PostDetailCacheTTL  time.Duration `envconfig:"POST_DETAIL_CACHE_TTL" default:"2s"`
+	UnreadCountCacheTTL time.Duration `envconfig:"UNREAD_COUNT_CACHE_TTL" default:"2s"`
+
+	// GET /search/suggest: results per organization and prefix are cached
+	// this long, and lookups taking longer than the timeout return nothing
+	SearchSuggestCacheTTL time.Duration `envconfig:"SEARCH_SUGGEST_CACHE_TTL" default:"30s"`
+	SearchSuggestTimeout  time.Duration `envconfig:"SEARCH_SUGGEST_TIMEOUT" default:"300ms"`
+
+	// Users' last activity is batched and written this often; presence shows
+	// "online" for activity within 5 minutes
+	PresenceWriteInterval time.Duration `envconfig:"PRESENCE_WRITE_INTERVAL" default:"1m"`
+
+	// GET /stream checks for new feed posts and unread notifications this
+	// often per connection
+	StreamPollInterval time.Duration `envconfig:"STREAM_POLL_INTERVAL" default:"5s"`
+
+	// GraphQL endpoint at /api/v1/graphql; queries scoring above the
+	// complexity limit (roughly one point per field) are rejected
+	GraphQLEnabled         bool `envconfig:"GRAPHQL_ENABLED" default:"true"`
+	GraphQLComplexityLimit int  `envconfig:"GRAPHQL_COMPLEXITY_LIMIT" default:"500"`
+
+	// Link previews: Open Graph metadata for URLs in posts, refreshed after the TTL
+	LinkPreviewsEnabled bool          `envconfig:"LINK_PREVIEWS_ENABLED" default:"true"`
+	LinkPreviewTTL      time.Duration `envconfig:"LINK_PREVIEW_TTL" default:"168h"`
+	LinkPreviewInterval time.Duration `envconfig:"LINK_PREVIEW_INTERVAL" default:"30s"`
+	LinkPreviewTimeout  time.Duration `envconfig:"LINK_PREVIEW_TIMEOUT" default:"5s"`
+
+	// GIF search proxied to tenor or giphy; empty GIF_PROVIDER disables it
+	GIFProvider string        `envconfig:"GIF_PROVIDER"`
+	GIFAPIKey   string        `envconfig:"GIF_API_KEY"`
+	GIFCacheTTL time.Duration `envconfig:"GIF_CACHE_TTL" default:"10m"`
+	GIFTimeout  time.Duration `envconfig:"GIF_TIMEOUT" default:"5s"`
+
+	// MaxMind DB (GeoLite2 City or Country) used to show roughly where
+	// sessions sign in from; empty leaves locations out
+	GeoIPDBPath string `envconfig:"GEOIP_DB_PATH"`
+	// GeoLite2 ASN database; blocking networks by ASN needs it
+	GeoIPASNDBPath string `envconfig:"GEOIP_ASN_DB_PATH"`
+
+	// Bot protection on registration and on login after repeated failures:
+	// hcaptcha or turnstile (verified with BOT_PROTECTION_SECRET), pow for a
+	// proof-of-work challenge, or empty to disable.
+	// BOT_PROTECTION_LOGIN_FAILURES=0 checks every login.
+	BotProtection              string        `envconfig:"BOT_PROTECTION"`
+	BotProtectionSiteKey       string        `envconfig:"BOT_PROTECTION_SITE_KEY"`
+	BotProtectionSecret        string        `envconfig:"BOT_PROTECTION_SECRET"`
+	BotProtectionTimeout       time.Duration `envconfig:"BOT_PROTECTION_TIMEOUT" default:"5s"`
+	BotProtectionPoWMaxNumber  int           `envconfig:"BOT_PROTECTION_POW_MAX_NUMBER" default:"100000"`
+	BotProtectionPoWTTL        time.Duration `envconfig:"BOT_PROTECTION_POW_TTL" default:"10m"`
+	BotProtectionLoginFailures int           `envconfig:"BOT_PROTECTION_LOGIN_FAILURES" default:"6"`
+	BotProtectionFailureWindow time.Duration `envconfig:"BOT_PROTECTION_FAILURE_WINDOW" default:"15m"`
+
+	// Content filter for new posts and comments. Each rule's action is
+	// reject, flag (recorded silently) or review (queued for moderators).
+	// CONTENT_FILTER_WORDS is comma- or newline-separated; use
+	// CONTENT_FILTER_WORDS_FILE for a longer list.
+	ContentFilterEnabled         bool          `envconfig:"CONTENT_FILTER_ENABLED" default:"true"`
+	ContentFilterWords           string        `envconfig:"CONTENT_FILTER_WORDS"`
+	ContentFilterWordsAction     string        `envconfig:"CONTENT_FILTER_WORDS_ACTION" default:"reject"`
+	ContentFilterMaxLinks        int           `envconfig:"CONTENT_FILTER_MAX_LINKS" default:"3"` // 0 disables
+	ContentFilterLinksAction     string        `envconfig:"CONTENT_FILTER_LINKS_ACTION" default:"review"`
+	ContentFilterDuplicateWindow time.Duration `envconfig:"CONTENT_FILTER_DUPLICATE_WINDOW" default:"1m"` // 0 disables
+	ContentFilterDuplicateAction string        `envconfig:"CONTENT_FILTER_DUPLICATE_ACTION" default:"reject"`
+
+	// Near-duplicate posts: a new post whose simhash is within
+	// DUPLICATE_MAX_DISTANCE bits of one from the last DUPLICATE_WINDOW in
+	// the organization is reported at /admin/duplicates
+	DuplicateWindow      time.Duration `envconfig:"DUPLICATE_WINDOW" default:"168h"` // 0 disables
+	DuplicateMaxDistance int           `envconfig:"DUPLICATE_MAX_DISTANCE" default:"6"`
+
+	// Ranked feed scoring (?sort=ranked)
+	FeedRankRecencyWeight    float64       `envconfig:"FEED_RANK_RECENCY_WEIGHT" default:"1.0"`
+	FeedRankEngagementWeight float64       `envconfig:"FEED_RANK_ENGAGEMENT_WEIGHT" default:"0.5"`
+	FeedRankAffinityWeight   float64       `envconfig:"FEED_RANK_AFFINITY_WEIGHT" default:"0.7"`
+	FeedRankCourseWeight     float64       `envconfig:"FEED_RANK_COURSE_WEIGHT" default:"0.3"`
+	FeedRankHalfLife         time.Duration `envconfig:"FEED_RANK_HALF_LIFE" default:"24h"`
+	FeedRankWindow           time.Duration `envconfig:"FEED_RANK_WINDOW" default:"336h"`
+	FeedRankAIPenalty        float64       `envconfig:"FEED_RANK_AI_PENALTY" default:"0.5"`
+
+	// Fan-out on write: new posts are copied into followers' feed_items and
+	// the chronological feed is read from there. Existing feeds are
+	// backfilled in the background; turning this off discards them.
+	FeedFanoutEnabled bool `envconfig:"FEED_FANOUT_ENABLED" default:"false"`
+
+	// Web Push (disabled when VAPID_PRIVATE_KEY is empty)
+	VAPIDPrivateKey string `envconfig:"VAPID_PRIVATE_KEY"`
+	VAPIDSubject    string `envconfig:"VAPID_SUBJECT" default:"mailto:admin@bailanysta.kz"`
 
-	// Rate limiting
-	RateLimitRPM int `envconfig:"RATE_LIMIT_RPM" default:"100"`
+	// Email (digests are disabled when SMTP_HOST is empty)
+	SMTPHost            string        `envconfig:"SMTP_HOST"`
+	SMTPPort            int           `envconfig:"SMTP_PORT" default:"587"`
+	SMTPUsername        string        `envconfig:"SMTP_USERNAME"`
+	SMTPPassword        string        `envconfig:"SMTP_PASSWORD"`
+	SMTPFrom            string        `envconfig:"SMTP_FROM" default:"Bailanysta <no-reply@bailanysta.kz>"`
+	EmailDigestInterval time.Duration `envconfig:"EMAIL_DIGEST_INTERVAL" default:"1h"`
+	EmailDigestHour     int           `envconfig:"EMAIL_DIGEST_HOUR" default:"8"` // in each user's time zone
+
+	// Notification retention: read notifications older than this many days
+	// are deleted or archived (0 keeps them forever)
+	NotificationRetentionDays   int           `envconfig:"NOTIFICATION_RETENTION_DAYS" default:"90"`
+	NotificationRetentionAction string        `envconfig:"NOTIFICATION_RETENTION_ACTION" default:"delete"` // delete or archive
+	NotificationCleanupInterval time.Duration `envconfig:"NOTIFICATION_CLEANUP_INTERVAL" default:"1h"`
+
+	// Registration: open, or waitlist to make /auth/register capture emails
+	// until an admin approves them; invites expire after WAITLIST_INVITE_TTL
+	RegistrationMode  string        `envconfig:"REGISTRATION_MODE" default:"open"`
+	WaitlistInviteTTL time.Duration `envconfig:"WAITLIST_INVITE_TTL" default:"168h"`
+
+	// Backup email verification and account recovery links expire after this
+	RecoveryTokenTTL time.Duration `envconfig:"RECOVERY_TOKEN_TTL" default:"1h"`
+
+	// Accounts that follow or like more than this many times a minute are
+	// throttled for ABUSE_THROTTLE and flagged for moderators (0 disables)
+	AbuseFollowsPerMinute int           `envconfig:"ABUSE_FOLLOWS_PER_MINUTE" default:"30"`
+	AbuseLikesPerMinute   int           `envconfig:"ABUSE_LIKES_PER_MINUTE" default:"60"`
+	AbuseThrottle         time.Duration `envconfig:"ABUSE_THROTTLE" default:"1h"`
+	// Also block the IP of a throttled account's latest session this long.
+	// Off by default: a school behind one NAT address would be blocked whole.
+	AbuseBlockIPFor time.Duration `envconfig:"ABUSE_BLOCK_IP_FOR" default:"0"`
+
+	// Profile views are kept this many days for users who track them
+	ProfileViewRetentionDays int `envconfig:"PROFILE_VIEW_RETENTION_DAYS" default:"30"`
+
+	// Event reminders go out this long before an event starts
+	EventReminderLead     time.Duration `envconfig:"EVENT_REMINDER_LEAD" default:"1h"`
+	EventReminderInterval time.Duration `envconfig:"EVENT_REMINDER_INTERVAL" default:"1m"`
+
+	// Students who haven't submitted are reminded this long before an
+	// assignment is due
+	AssignmentReminderLead time.Duration `envconfig:"ASSIGNMENT_REMINDER_LEAD" default:"24h"`
+
+	// How often leaderboard points are recomputed
+	LeaderboardRefreshInterval time.Duration `envconfig:"LEADERBOARD_REFRESH_INTERVAL" default:"5m"`
+
+	// Backups of user content to an S3-compatible bucket (disabled when
+	// BACKUP_S3_BUCKET is empty); BACKUP_RETENTION=0 keeps every backup
+	BackupS3Endpoint  string        `envconfig:"BACKUP_S3_ENDPOINT" default:"https://s3.amazonaws.com"`
+	BackupS3Region    string        `envconfig:"BACKUP_S3_REGION" default:"us-east-1"`
+	BackupS3Bucket    string        `envconfig:"BACKUP_S3_BUCKET"`
+	BackupS3AccessKey string        `envconfig:"BACKUP_S3_ACCESS_KEY"`
+	BackupS3SecretKey string        `envconfig:"BACKUP_S3_SECRET_KEY"`
+	BackupS3PathStyle bool          `envconfig:"BACKUP_S3_PATH_STYLE" default:"false"`
+	BackupS3Prefix    string        `envconfig:"BACKUP_S3_PREFIX" default:"backups/"`
+	BackupInterval    time.Duration `envconfig:"BACKUP_INTERVAL" default:"24h"`
+	BackupRetention   int           `envconfig:"BACKUP_RETENTION" default:"14"`
+
+	// Hour of the day, in each user's time zone, after which users about to
+	// lose a streak are reminded
+	StreakReminderHour int `envconfig:"STREAK_REMINDER_HOUR" default:"18"`
+
+	// Public URLs used in outgoing links
+	AppURL string `envconfig:"APP_URL" default:"http://localhost:3000"`
+	APIURL string `envconfig:"API_URL" default:"http://localhost:8080"`
+
+	// Maintenance mode: every request except health checks, logins and those
+	// of admins gets a 503 with the message and Retry-After. Admins can also switch it
+	// at /api/v1/admin/maintenance.
+	MaintenanceMode       bool          `envconfig:"MAINTENANCE_MODE" default:"false"`
+	MaintenanceMessage    string        `envconfig:"MAINTENANCE_MESSAGE" default:"Bailanysta is down for maintenance"`
+	MaintenanceRetryAfter time.Duration `envconfig:"MAINTENANCE_RETRY_AFTER" default:"5m"`
+
+	// Response compression (gzip/deflate) for the listed content types
+	CompressEnabled bool   `envconfig:"COMPRESS_ENABLED" default:"true"`
+	CompressMinSize int    `envconfig:"COMPRESS_MIN_SIZE" default:"1024"`
+	CompressTypes   string `envconfig:"COMPRESS_TYPES" default:"application/json,text/plain,text/html,text/css,text/javascript,application/javascript,image/svg+xml"`
+
+	// Prometheus metrics on /metrics; set METRICS_TOKEN to require a bearer token
+	MetricsEnabled bool   `envconfig:"METRICS_ENABLED" default:"true"`
+	MetricsToken   string `envconfig:"METRICS_TOKEN"`
+
+	// pprof and expvar runtime stats on a separate listener, e.g.
+	// 127.0.0.1:6060; off when DIAGNOSTICS_ADDR is empty. Listening beyond
+	// loopback requires DIAGNOSTICS_TOKEN as a bearer token.
+	DiagnosticsAddr  string `envconfig:"DIAGNOSTICS_ADDR"`
+	DiagnosticsToken string `envconfig:"DIAGNOSTICS_TOKEN"`
+
+	// Error reporting to a Sentry-compatible DSN; off when SENTRY_DSN is
+	// empty. SENTRY_RELEASE defaults to the commit the binary was built from.
+	SentryDSN         string `envconfig:"SENTRY_DSN"`
+	SentryEnvironment string `envconfig:"SENTRY_ENVIRONMENT" default:"production"`
+	SentryRelease     string `envconfig:"SENTRY_RELEASE"`
+
+	// Rate limiting (LOG_LEVEL, CORS_ORIGIN and the RATE_LIMIT_* settings are
+	// reloaded on SIGHUP). Each request takes its cost from a bucket refilled
+	// at RATE_LIMIT_RPM; reads cost 1.
+	RateLimitRPM       int `envconfig:"RATE_LIMIT_RPM" default:"100"`
+	RateLimitWriteCost int `envconfig:"RATE_LIMIT_WRITE_COST" default:"1"`
+	RateLimitAICost    int `envconfig:"RATE_LIMIT_AI_COST" default:"50"`
 }
 
 func Load() (*Config, error) {
+	values, err := resolveSources(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
 	var cfg Config
-	if err := envconfig.Process("", &cfg); err != nil {
+	if err := withEnv(values, func() error { return envconfig.Process("", &cfg) }); err != nil {
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
@@ -43,28 +370,422 @@ func (c *Config) Validate() error {
 	if c.DatabaseURL == "" {
 		return fmt.Errorf("DATABASE_URL is required")
 	}
-	if c.JwtSecret == "" {
-		return fmt.Errorf("JWT_SECRET is required")
+	switch c.JwtAlgorithm {
+	case auth.AlgorithmHS256:
+		if c.JwtSecret == "" {
+			return fmt.Errorf("JWT_SECRET is required")
+		}
+	case auth.AlgorithmRS256, auth.AlgorithmEdDSA:
+		if c.JwtPrivateKey == "" {
+			return fmt.Errorf("JWT_PRIVATE_KEY is required for %s", c.JwtAlgorithm)
+		}
+	default:
+		return fmt.Errorf("JWT_ALGORITHM must be HS256, RS256 or EdDSA")
+	}
+	if c.JwtIssuer == "" || c.JwtAudience == "" {
+		return fmt.Errorf("JWT_ISSUER and JWT_AUDIENCE are required")
 	}
 	if c.Port == "" {
 		return fmt.Errorf("PORT is required")
 	}
+	if len(c.CORSOrigins()) == 0 {
+		return fmt.Errorf("CORS_ORIGIN is required")
+	}
+	if _, err := c.TrustedProxyNets(); err != nil {
+		return err
+	}
+	switch c.AuthCookieSameSite {
+	case "lax", "strict":
+	case "none":
+		if !c.AuthCookieSecure {
+			return fmt.Errorf("AUTH_COOKIE_SAMESITE=none requires AUTH_COOKIE_SECURE")
+		}
+	default:
+		return fmt.Errorf("AUTH_COOKIE_SAMESITE must be lax, strict or none")
+	}
+	if c.ImpersonationTTL <= 0 {
+		return fmt.Errorf("IMPERSONATION_TTL must be positive")
+	}
+	if c.RateLimitRPM <= 0 {
+		return fmt.Errorf("RATE_LIMIT_RPM must be positive")
+	}
+	if c.RateLimitWriteCost <= 0 || c.RateLimitAICost <= 0 {
+		return fmt.Errorf("RATE_LIMIT_WRITE_COST and RATE_LIMIT_AI_COST must be positive")
+	}
+	if _, _, err := logger.ParseLevels(c.LogLevel); err != nil {
+		return fmt.Errorf("LOG_LEVEL is invalid: %w", err)
+	}
+	if c.LogAsync && c.LogFlushInterval <= 0 {
+		return fmt.Errorf("LOG_FLUSH_INTERVAL must be positive")
+	}
+	if c.MaintenanceRetryAfter <= 0 {
+		return fmt.Errorf("MAINTENANCE_RETRY_AFTER must be positive")
+	}
+	if c.DiagnosticsAddr != "" && c.DiagnosticsToken == "" {
+		host, _, err := net.SplitHostPort(c.DiagnosticsAddr)
+		if err != nil {
+			return fmt.Errorf("DIAGNOSTICS_ADDR is invalid: %w", err)
+		}
+		if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return fmt.Errorf("DIAGNOSTICS_TOKEN is required when DIAGNOSTICS_ADDR is not a loopback address")
+		}
+	}
+	if c.DBConnectMaxWait < 0 {
+		return fmt.Errorf("DB_CONNECT_MAX_WAIT must not be negative")
+	}
+	if c.DebugCaptureMaxBytes < 0 {
+		return fmt.Errorf("DEBUG_CAPTURE_MAX_BYTES must not be negative")
+	}
+	if c.RequestTimeoutRead <= 0 || c.RequestTimeoutWrite <= 0 || c.RequestTimeoutAI <= 0 || c.RequestTimeoutExport <= 0 || c.RequestTimeoutStream <= 0 {
+		return fmt.Errorf("REQUEST_TIMEOUT_READ, REQUEST_TIMEOUT_WRITE, REQUEST_TIMEOUT_AI, REQUEST_TIMEOUT_EXPORT and REQUEST_TIMEOUT_STREAM must be positive")
+	}
+	if c.AIDailyRequestQuota < 0 || c.AIDailyTokenQuota < 0 {
+		return fmt.Errorf("AI_DAILY_REQUEST_QUOTA and AI_DAILY_TOKEN_QUOTA must not be negative")
+	}
+	if err := c.validateAILimits(); err != nil {
+		return err
+	}
+	if c.ShutdownTimeout <= 0 || c.ShutdownAIGrace <= 0 {
+		return fmt.Errorf("SHUTDOWN_TIMEOUT and SHUTDOWN_AI_GRACE must be positive")
+	}
+	if c.CompressMinSize < 0 {
+		return fmt.Errorf("COMPRESS_MIN_SIZE must not be negative")
+	}
+	if c.SearchSuggestCacheTTL < 0 || c.SearchSuggestTimeout <= 0 {
+		return fmt.Errorf("SEARCH_SUGGEST_CACHE_TTL must not be negative and SEARCH_SUGGEST_TIMEOUT must be positive")
+	}
+	if c.PresenceWriteInterval <= 0 || c.PresenceWriteInterval > 2*time.Minute {
+		return fmt.Errorf("PRESENCE_WRITE_INTERVAL must be positive and at most 2m")
+	}
+	if c.StreamPollInterval < time.Second {
+		return fmt.Errorf("STREAM_POLL_INTERVAL must be at least 1s")
+	}
+	if c.PostDetailCacheTTL < 0 || c.UnreadCountCacheTTL < 0 {
+		return fmt.Errorf("POST_DETAIL_CACHE_TTL and UNREAD_COUNT_CACHE_TTL must not be negative")
+	}
+	if c.PostDetailComments < 0 || c.PostDetailComments > 50 {
+		return fmt.Errorf("POST_DETAIL_COMMENTS must be between 0 and 50")
+	}
+	if c.PostMaxChars <= 0 || c.PostMaxChars > 50000 {
+		return fmt.Errorf("POST_MAX_CHARS must be between 1 and 50000")
+	}
+	if c.PostMaxWords < 0 {
+		return fmt.Errorf("POST_MAX_WORDS must not be negative")
+	}
+	if c.GraphQLComplexityLimit <= 0 {
+		return fmt.Errorf("GRAPHQL_COMPLEXITY_LIMIT must be positive")
+	}
+	if c.LinkPreviewsEnabled && (c.LinkPreviewTTL <= 0 || c.LinkPreviewInterval <= 0 || c.LinkPreviewTimeout <= 0) {
+		return fmt.Errorf("LINK_PREVIEW_TTL, LINK_PREVIEW_INTERVAL and LINK_PREVIEW_TIMEOUT must be positive")
+	}
+	if c.GIFProvider != "" {
+		if c.GIFProvider != "tenor" && c.GIFProvider != "giphy" {
+			return fmt.Errorf("GIF_PROVIDER must be tenor or giphy")
+		}
+		if c.GIFAPIKey == "" {
+			return fmt.Errorf("GIF_API_KEY is required when GIF_PROVIDER is set")
+		}
+		if c.GIFCacheTTL < 0 || c.GIFTimeout <= 0 {
+			return fmt.Errorf("GIF_CACHE_TTL must not be negative and GIF_TIMEOUT must be positive")
+		}
+	}
+	switch c.BotProtection {
+	case "":
+	case "hcaptcha", "turnstile":
+		if c.BotProtectionSiteKey == "" || c.BotProtectionSecret == "" {
+			return fmt.Errorf("BOT_PROTECTION_SITE_KEY and BOT_PROTECTION_SECRET are required for %s", c.BotProtection)
+		}
+		if c.BotProtectionTimeout <= 0 {
+			return fmt.Errorf("BOT_PROTECTION_TIMEOUT must be positive")
+		}
+	case "pow":
+		if c.BotProtectionPoWMaxNumber < 1000 {
+			return fmt.Errorf("BOT_PROTECTION_POW_MAX_NUMBER must be at least 1000")
+		}
+		if c.BotProtectionPoWTTL <= 0 {
+			return fmt.Errorf("BOT_PROTECTION_POW_TTL must be positive")
+		}
+	default:
+		return fmt.Errorf("BOT_PROTECTION must be hcaptcha, turnstile or pow")
+	}
+	if c.BotProtectionLoginFailures < 0 || c.BotProtectionFailureWindow <= 0 {
+		return fmt.Errorf("BOT_PROTECTION_LOGIN_FAILURES must not be negative and BOT_PROTECTION_FAILURE_WINDOW must be positive")
+	}
+	for name, action := range map[string]string{
+		"CONTENT_FILTER_WORDS_ACTION":     c.ContentFilterWordsAction,
+		"CONTENT_FILTER_LINKS_ACTION":     c.ContentFilterLinksAction,
+		"CONTENT_FILTER_DUPLICATE_ACTION": c.ContentFilterDuplicateAction,
+	} {
+		if action != "reject" && action != "flag" && action != "review" {
+			return fmt.Errorf("%s must be reject, flag or review", name)
+		}
+	}
+	if c.ContentFilterMaxLinks < 0 || c.ContentFilterDuplicateWindow < 0 {
+		return fmt.Errorf("CONTENT_FILTER_MAX_LINKS and CONTENT_FILTER_DUPLICATE_WINDOW must not be negative")
+	}
+	if c.DuplicateWindow < 0 {
+		return fmt.Errorf("DUPLICATE_WINDOW must not be negative")
+	}
+	if c.DuplicateMaxDistance < 0 || c.DuplicateMaxDistance > 16 {
+		return fmt.Errorf("DUPLICATE_MAX_DISTANCE must be between 0 and 16")
+	}
+	if c.FeedRankHalfLife <= 0 || c.FeedRankWindow <= 0 {
+		return fmt.Errorf("FEED_RANK_HALF_LIFE and FEED_RANK_WINDOW must be positive")
+	}
+	if c.DBSlowQueryThreshold < 0 {
+		return fmt.Errorf("DB_SLOW_QUERY_THRESHOLD must not be negative")
+	}
+	if c.FeedRankAIPenalty < 0 || c.FeedRankAIPenalty > 1 {
+		return fmt.Errorf("FEED_RANK_AI_PENALTY must be between 0 and 1")
+	}
+	if c.EmbeddingsEnabled && c.EmbeddingInterval <= 0 {
+		return fmt.Errorf("EMBEDDING_INTERVAL must be positive")
+	}
+	if c.SMTPHost != "" && c.EmailDigestInterval <= 0 {
+		return fmt.Errorf("EMAIL_DIGEST_INTERVAL must be positive")
+	}
+	if c.EmailDigestHour < 0 || c.EmailDigestHour > 23 {
+		return fmt.Errorf("EMAIL_DIGEST_HOUR must be between 0 and 23")
+	}
+	if c.NotificationRetentionDays < 0 {
+		return fmt.Errorf("NOTIFICATION_RETENTION_DAYS must not be negative")
+	}
+	if c.NotificationRetentionDays > 0 {
+		if c.NotificationRetentionAction != "delete" && c.NotificationRetentionAction != "archive" {
+			return fmt.Errorf("NOTIFICATION_RETENTION_ACTION must be delete or archive")
+		}
+		if c.NotificationCleanupInterval <= 0 {
+			return fmt.Errorf("NOTIFICATION_CLEANUP_INTERVAL must be positive")
+		}
+	}
+	if c.RegistrationMode != "open" && c.RegistrationMode != "waitlist" {
+		return fmt.Errorf("REGISTRATION_MODE must be open or waitlist")
+	}
+	if c.RegistrationMode == "waitlist" {
+		if c.SMTPHost == "" {
+			return fmt.Errorf("SMTP_HOST is required when REGISTRATION_MODE is waitlist")
+		}
+		if c.WaitlistInviteTTL <= 0 {
+			return fmt.Errorf("WAITLIST_INVITE_TTL must be positive")
+		}
+	}
+	if c.RecoveryTokenTTL <= 0 {
+		return fmt.Errorf("RECOVERY_TOKEN_TTL must be positive")
+	}
+	if c.AbuseFollowsPerMinute < 0 || c.AbuseLikesPerMinute < 0 {
+		return fmt.Errorf("ABUSE_FOLLOWS_PER_MINUTE and ABUSE_LIKES_PER_MINUTE must not be negative")
+	}
+	if c.AbuseThrottle <= 0 {
+		return fmt.Errorf("ABUSE_THROTTLE must be positive")
+	}
+	if c.AbuseBlockIPFor < 0 {
+		return fmt.Errorf("ABUSE_BLOCK_IP_FOR must not be negative")
+	}
+	if c.ProfileViewRetentionDays < 1 || c.ProfileViewRetentionDays > 365 {
+		return fmt.Errorf("PROFILE_VIEW_RETENTION_DAYS must be between 1 and 365")
+	}
+	if c.EventReminderLead <= 0 || c.EventReminderInterval <= 0 {
+		return fmt.Errorf("EVENT_REMINDER_LEAD and EVENT_REMINDER_INTERVAL must be positive")
+	}
+	if c.AssignmentReminderLead <= 0 {
+		return fmt.Errorf("ASSIGNMENT_REMINDER_LEAD must be positive")
+	}
+	if c.LeaderboardRefreshInterval <= 0 {
+		return fmt.Errorf("LEADERBOARD_REFRESH_INTERVAL must be positive")
+	}
+	if c.BackupS3Bucket != "" {
+		if c.BackupS3AccessKey == "" || c.BackupS3SecretKey == "" {
+			return fmt.Errorf("BACKUP_S3_ACCESS_KEY and BACKUP_S3_SECRET_KEY are required for backups")
+		}
+		if c.BackupInterval <= 0 {
+			return fmt.Errorf("BACKUP_INTERVAL must be positive")
+		}
+		if c.BackupRetention < 0 {
+			return fmt.Errorf("BACKUP_RETENTION must not be negative")
+		}
+	}
+	if c.StreakReminderHour < 0 || c.StreakReminderHour > 23 {
+		return fmt.Errorf("STREAK_REMINDER_HOUR must be between 0 and 23")
+	}
+	if c.VAPIDPrivateKey != "" && !strings.HasPrefix(c.VAPIDSubject, "mailto:") && !strings.HasPrefix(c.VAPIDSubject, "https://") {
+		return fmt.Errorf("VAPID_SUBJECT must be a mailto: or https:// URL")
+	}
+	return nil
+}
+
+// validateAILimits checks the AI completion limits: positive token counts
+// with defaults within caps, and temperatures the API accepts
+func (c *Config) validateAILimits() error {
+	tokens := []struct {
+		name  string
+		value int
+	}{
+		{"AI_TEXT_DEFAULT_TOKENS", c.AITextDefaultTokens}, {"AI_TEXT_MAX_TOKENS", c.AITextMaxTokens},
+		{"AI_POST_DEFAULT_TOKENS", c.AIPostDefaultTokens}, {"AI_POST_MAX_TOKENS", c.AIPostMaxTokens},
+		{"AI_COMMENT_DEFAULT_TOKENS", c.AICommentDefaultTokens}, {"AI_COMMENT_MAX_TOKENS", c.AICommentMaxTokens},
+		{"AI_REWRITE_MAX_TOKENS", c.AIRewriteMaxTokens},
+		{"AI_STUDY_NOTES_MAX_TOKENS", c.AIStudyNotesMaxTokens},
+		{"AI_QUIZ_MAX_TOKENS", c.AIQuizMaxTokens},
+		{"AI_FLASHCARDS_MAX_TOKENS", c.AIFlashcardsMaxTokens},
+		{"AI_GRADING_MAX_TOKENS", c.AIGradingMaxTokens},
+		{"AI_EXPLAIN_MAX_TOKENS", c.AIExplainMaxTokens},
+		{"AI_DIGEST_BATCH_MAX_TOKENS", c.AIDigestBatchMaxTokens},
+		{"AI_DIGEST_MAX_TOKENS", c.AIDigestMaxTokens},
+	}
+	for _, t := range tokens {
+		if t.value <= 0 {
+			return fmt.Errorf("%s must be positive", t.name)
+		}
+	}
+	if c.AITextDefaultTokens > c.AITextMaxTokens || c.AIPostDefaultTokens > c.AIPostMaxTokens || c.AICommentDefaultTokens > c.AICommentMaxTokens {
+		return fmt.Errorf("AI_*_DEFAULT_TOKENS must not exceed the matching AI_*_MAX_TOKENS")
+	}
+
+	temperatures := []struct {
+		name  string
+		value float32
+	}{
+		{"AI_TEXT_TEMPERATURE", c.AITextTemperature},
+		{"AI_POST_TEMPERATURE", c.AIPostTemperature},
+		{"AI_COMMENT_TEMPERATURE", c.AICommentTemperature},
+		{"AI_REWRITE_TEMPERATURE", c.AIRewriteTemperature},
+		{"AI_STUDY_NOTES_TEMPERATURE", c.AIStudyNotesTemperature},
+		{"AI_QUIZ_TEMPERATURE", c.AIQuizTemperature},
+		{"AI_FLASHCARDS_TEMPERATURE", c.AIFlashcardsTemperature},
+		{"AI_GRADING_TEMPERATURE", c.AIGradingTemperature},
+		{"AI_EXPLAIN_TEMPERATURE", c.AIExplainTemperature},
+		{"AI_DIGEST_BATCH_TEMPERATURE", c.AIDigestBatchTemperature},
+		{"AI_DIGEST_TEMPERATURE", c.AIDigestTemperature},
+	}
+	for _, t := range temperatures {
+		if t.value < 0 || t.value > 2 {
+			return fmt.Errorf("%s must be between 0 and 2", t.name)
+		}
+	}
+
+	if c.AICompletionTimeout <= 0 || c.AICompletionTimeout > c.RequestTimeoutAI {
+		return fmt.Errorf("AI_COMPLETION_TIMEOUT must be positive and at most REQUEST_TIMEOUT_AI")
+	}
 	return nil
 }
 
 func (c *Config) PrintConfig() {
 	log.Printf("Configuration loaded:")
+	log.Printf("  Config File: %s", c.ConfigFile)
 	log.Printf("  Port: %s", c.Port)
 	log.Printf("  Database URL: %s", maskPassword(c.DatabaseURL))
+	log.Printf("  Database Connect Max Wait: %v", c.DBConnectMaxWait)
 	log.Printf("  JWT Secret: %s", maskSecret(c.JwtSecret))
+	log.Printf("  JWT Signing: %s (issuer %q, audience %q, private key %s)", c.JwtAlgorithm, c.JwtIssuer, c.JwtAudience, maskSecret(c.JwtPrivateKey))
 	log.Printf("  JWT Expiry: %v", c.JwtExpiry)
+	log.Printf("  Auth Cookies: %v (domain %q, secure %v, samesite %s)", c.AuthCookiesEnabled, c.AuthCookieDomain, c.AuthCookieSecure, c.AuthCookieSameSite)
+	log.Printf("  Impersonation TTL: %v", c.ImpersonationTTL)
 	log.Printf("  Refresh Expiry: %v", c.RefreshExpiry)
 	log.Printf("  CORS Origin: %s", c.CORSOrigin)
+	log.Printf("  Trusted Proxies: %s", c.TrustedProxies)
 	log.Printf("  Migrate on Start: %v", c.MigrateOnStart)
+	log.Printf("  Schema Check Indexes: %v", c.SchemaCheckIndexes)
 	log.Printf("  Log Level: %s", c.LogLevel)
+	log.Printf("  Log Async: %v (flush every %v)", c.LogAsync, c.LogFlushInterval)
+	log.Printf("  Log Sampling: initial=%d thereafter=%d", c.LogSampleInitial, c.LogSampleThereafter)
+	log.Printf("  Debug Capture: routes=%q max %d bytes", c.DebugCaptureRoutes, c.DebugCaptureMaxBytes)
+	log.Printf("  Request Timeouts: read=%v write=%v ai=%v export=%v stream=%v", c.RequestTimeoutRead, c.RequestTimeoutWrite, c.RequestTimeoutAI, c.RequestTimeoutExport, c.RequestTimeoutStream)
+	log.Printf("  Shutdown Timeout: %v (AI grace %v)", c.ShutdownTimeout, c.ShutdownAIGrace)
 	log.Printf("  OpenAI Base URL: %s", c.OpenAIBaseURL)
 	log.Printf("  OpenAI API Key: %s", maskSecret(c.OpenAIApiKey))
-	log.Printf("  Rate Limit RPM: %d", c.RateLimitRPM)
+	log.Printf("  AI Cache TTL: %v", c.AICacheTTL)
+	log.Printf("  AI Daily Quota: requests=%d tokens=%d (0 = unlimited)", c.AIDailyRequestQuota, c.AIDailyTokenQuota)
+	log.Printf("  AI Max Tokens: text=%d post=%d comment=%d rewrite=%d notes=%d quiz=%d flashcards=%d grading=%d explain=%d digest=%d/%d (timeout %v)",
+		c.AITextMaxTokens, c.AIPostMaxTokens, c.AICommentMaxTokens, c.AIRewriteMaxTokens, c.AIStudyNotesMaxTokens,
+		c.AIQuizMaxTokens, c.AIFlashcardsMaxTokens, c.AIGradingMaxTokens, c.AIExplainMaxTokens, c.AIDigestBatchMaxTokens, c.AIDigestMaxTokens, c.AICompletionTimeout)
+	log.Printf("  Embeddings Enabled: %v", c.EmbeddingsEnabled)
+	log.Printf("  Embedding Model: %s", c.EmbeddingModel)
+	log.Printf("  Post Detail Comments: %d", c.PostDetailComments)
+	log.Printf("  Post Limits: %d characters, %d words", c.PostMaxChars, c.PostMaxWords)
+	log.Printf("  Hot Read Cache: post detail %v, unread count %v", c.PostDetailCacheTTL, c.UnreadCountCacheTTL)
+	log.Printf("  Search Suggestions: cache %v, timeout %v", c.SearchSuggestCacheTTL, c.SearchSuggestTimeout)
+	log.Printf("  Presence Write Interval: %v", c.PresenceWriteInterval)
+	log.Printf("  Registration Mode: %s (invites valid %v)", c.RegistrationMode, c.WaitlistInviteTTL)
+	log.Printf("  Recovery Token TTL: %v", c.RecoveryTokenTTL)
+	log.Printf("  Duplicate Detection: %v window, up to %d bits apart", c.DuplicateWindow, c.DuplicateMaxDistance)
+	log.Printf("  Abuse Limits: %d follows, %d likes a minute (throttle %v, IP block %v)", c.AbuseFollowsPerMinute, c.AbuseLikesPerMinute, c.AbuseThrottle, c.AbuseBlockIPFor)
+	log.Printf("  Profile View Retention: %d days", c.ProfileViewRetentionDays)
+	log.Printf("  Event Stream Poll Interval: %v", c.StreamPollInterval)
+	log.Printf("  GraphQL Enabled: %v (complexity limit %d)", c.GraphQLEnabled, c.GraphQLComplexityLimit)
+	log.Printf("  Link Previews Enabled: %v (TTL %v)", c.LinkPreviewsEnabled, c.LinkPreviewTTL)
+	log.Printf("  GIF Provider: %s (key %s, cache %v)", c.GIFProvider, maskSecret(c.GIFAPIKey), c.GIFCacheTTL)
+	log.Printf("  GeoIP Database: %q (ASN %q)", c.GeoIPDBPath, c.GeoIPASNDBPath)
+	log.Printf("  Bot Protection: %q (secret %s, login after %d failures)", c.BotProtection, maskSecret(c.BotProtectionSecret), c.BotProtectionLoginFailures)
+	log.Printf("  Content Filter Enabled: %v (%d words)", c.ContentFilterEnabled, len(c.ContentFilterWordList()))
+	log.Printf("  Feed Rank Weights: recency=%.2f engagement=%.2f affinity=%.2f course=%.2f",
+		c.FeedRankRecencyWeight, c.FeedRankEngagementWeight, c.FeedRankAffinityWeight, c.FeedRankCourseWeight)
+	log.Printf("  Web Push Enabled: %v", c.VAPIDPrivateKey != "")
+	log.Printf("  SMTP Host: %s:%d", c.SMTPHost, c.SMTPPort)
+	log.Printf("  SMTP Password: %s", maskSecret(c.SMTPPassword))
+	log.Printf("  App URL: %s", c.AppURL)
+	log.Printf("  API URL: %s", c.APIURL)
+	log.Printf("  Compression: %v (min %d bytes)", c.CompressEnabled, c.CompressMinSize)
+	log.Printf("  Metrics Enabled: %v (token %s)", c.MetricsEnabled, maskSecret(c.MetricsToken))
+	log.Printf("  Diagnostics: %q (token %s)", c.DiagnosticsAddr, maskSecret(c.DiagnosticsToken))
+	log.Printf("  Error Reporting: %v (environment %s)", c.SentryDSN != "", c.SentryEnvironment)
+	log.Printf("  Maintenance Mode: %v (retry after %v)", c.MaintenanceMode, c.MaintenanceRetryAfter)
+	log.Printf("  Rate Limit RPM: %d (write cost %d, AI cost %d)", c.RateLimitRPM, c.RateLimitWriteCost, c.RateLimitAICost)
+	log.Printf("  Backups: bucket=%q every %v (keep %d, secret key %s)", c.BackupS3Bucket, c.BackupInterval, c.BackupRetention, maskSecret(c.BackupS3SecretKey))
+}
+
+// JWT returns the access token settings
+func (c *Config) JWT() auth.JWTConfig {
+	var previous []string
+	for _, secret := range strings.Split(c.JwtPreviousSecrets, ",") {
+		if secret = strings.TrimSpace(secret); secret != "" {
+			previous = append(previous, secret)
+		}
+	}
+	return auth.JWTConfig{
+		Algorithm:          c.JwtAlgorithm,
+		Secret:             c.JwtSecret,
+		PreviousSecrets:    previous,
+		PrivateKeyPEM:      c.JwtPrivateKey,
+		PreviousPublicKeys: c.JwtPreviousPublicKeys,
+		Issuer:             c.JwtIssuer,
+		Audience:           c.JwtAudience,
+		AccessExpiry:       c.JwtExpiry,
+		RefreshExpiry:      c.RefreshExpiry,
+	}
+}
+
+// CookieSameSite returns the SameSite mode of session cookies
+func (c *Config) CookieSameSite() http.SameSite {
+	switch c.AuthCookieSameSite {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	}
+	return http.SameSiteLaxMode
+}
+
+// BackupS3 returns the bucket settings for backups
+func (c *Config) BackupS3() s3.Config {
+	return s3.Config{
+		Endpoint:  c.BackupS3Endpoint,
+		Region:    c.BackupS3Region,
+		Bucket:    c.BackupS3Bucket,
+		AccessKey: c.BackupS3AccessKey,
+		SecretKey: c.BackupS3SecretKey,
+		PathStyle: c.BackupS3PathStyle,
+	}
+}
+
+// ContentFilterWordList splits CONTENT_FILTER_WORDS on commas and newlines
+func (c *Config) ContentFilterWordList() []string {
+	var words []string
+	for _, word := range strings.FieldsFunc(c.ContentFilterWords, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if word = strings.TrimSpace(word); word != "" {
+			words = append(words, word)
+		}
+	}
+	return words
 }
 
 func maskPassword(url string) string {
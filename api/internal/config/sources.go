@@ -0,0 +1,211 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// reloadable lists the settings that are applied on SIGHUP; everything
+// else needs a restart
+var reloadable = map[string]bool{
+	"LOG_LEVEL":             true,
+	"CORS_ORIGIN":           true,
+	"RATE_LIMIT_RPM":        true,
+	"RATE_LIMIT_WRITE_COST": true,
+	"RATE_LIMIT_AI_COST":    true,
+	// AI completion limits
+	"AI_TEXT_DEFAULT_TOKENS":      true,
+	"AI_TEXT_MAX_TOKENS":          true,
+	"AI_TEXT_TEMPERATURE":         true,
+	"AI_POST_DEFAULT_TOKENS":      true,
+	"AI_POST_MAX_TOKENS":          true,
+	"AI_POST_TEMPERATURE":         true,
+	"AI_COMMENT_DEFAULT_TOKENS":   true,
+	"AI_COMMENT_MAX_TOKENS":       true,
+	"AI_COMMENT_TEMPERATURE":      true,
+	"AI_REWRITE_MAX_TOKENS":       true,
+	"AI_REWRITE_TEMPERATURE":      true,
+	"AI_STUDY_NOTES_MAX_TOKENS":   true,
+	"AI_STUDY_NOTES_TEMPERATURE":  true,
+	"AI_QUIZ_MAX_TOKENS":          true,
+	"AI_QUIZ_TEMPERATURE":         true,
+	"AI_FLASHCARDS_MAX_TOKENS":    true,
+	"AI_FLASHCARDS_TEMPERATURE":   true,
+	"AI_GRADING_MAX_TOKENS":       true,
+	"AI_GRADING_TEMPERATURE":      true,
+	"AI_EXPLAIN_MAX_TOKENS":       true,
+	"AI_EXPLAIN_TEMPERATURE":      true,
+	"AI_DIGEST_BATCH_MAX_TOKENS":  true,
+	"AI_DIGEST_BATCH_TEMPERATURE": true,
+	"AI_DIGEST_MAX_TOKENS":        true,
+	"AI_DIGEST_TEMPERATURE":       true,
+	"AI_COMPLETION_TIMEOUT":       true,
+	// Only applied when changed, so a reload keeps a switch made by an admin
+	"MAINTENANCE_MODE":        true,
+	"MAINTENANCE_MESSAGE":     true,
+	"MAINTENANCE_RETRY_AFTER": true,
+}
+
+// envNames returns the environment variable name of every Config field
+func envNames() []string {
+	t := reflect.TypeOf(Config{})
+	names := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		if name := t.Field(i).Tag.Get("envconfig"); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// resolveSources returns values for settings that are not set directly in
+// the environment, taken from NAME_FILE or else from the YAML config file
+func resolveSources(configFile string) (map[string]string, error) {
+	fileValues, err := readConfigFile(configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string)
+	for _, name := range envNames() {
+		if _, ok := os.LookupEnv(name); ok {
+			continue
+		}
+
+		if path := os.Getenv(name + "_FILE"); path != "" {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s_FILE: %w", name, err)
+			}
+			values[name] = strings.TrimRight(string(data), "\r\n")
+			continue
+		}
+
+		if value, ok := fileValues[name]; ok {
+			values[name] = value
+		}
+	}
+	return values, nil
+}
+
+// readConfigFile parses a flat YAML mapping keyed by environment variable
+// name (case-insensitive). Lists are joined with commas.
+func readConfigFile(path string) (map[string]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	values := make(map[string]string, len(raw))
+	for key, value := range raw {
+		switch v := value.(type) {
+		case nil:
+			continue
+		case map[string]interface{}:
+			return nil, fmt.Errorf("config file key %s: nested mappings are not supported", key)
+		case []interface{}:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			values[strings.ToUpper(key)] = strings.Join(items, ",")
+		default:
+			values[strings.ToUpper(key)] = fmt.Sprint(v)
+		}
+	}
+	return values, nil
+}
+
+// withEnv sets values in the process environment while fn runs, so envconfig
+// sees them, and removes them again afterwards
+func withEnv(values map[string]string, fn func() error) error {
+	for name, value := range values {
+		os.Setenv(name, value)
+	}
+	defer func() {
+		for name := range values {
+			os.Unsetenv(name)
+		}
+	}()
+	return fn()
+}
+
+// Changed returns the names of settings that differ between c and other
+func (c *Config) Changed(other *Config) []string {
+	a := reflect.ValueOf(c).Elem()
+	b := reflect.ValueOf(other).Elem()
+	t := a.Type()
+
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		if !reflect.DeepEqual(a.Field(i).Interface(), b.Field(i).Interface()) {
+			names = append(names, t.Field(i).Tag.Get("envconfig"))
+		}
+	}
+	return names
+}
+
+// RequiresRestart filters names down to settings that SIGHUP does not apply
+func RequiresRestart(names []string) []string {
+	var restart []string
+	for _, name := range names {
+		if !reloadable[name] {
+			restart = append(restart, name)
+		}
+	}
+	return restart
+}
+
+// CORSOrigins splits CORS_ORIGIN into its comma-separated origins
+func (c *Config) CORSOrigins() []string {
+	var origins []string
+	for _, origin := range strings.Split(c.CORSOrigin, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
+// TrustedProxyNets parses TRUSTED_PROXIES; a bare IP is a network of one
+func (c *Config) TrustedProxyNets() ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, proxy := range strings.Split(c.TrustedProxies, ",") {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("TRUSTED_PROXIES has an invalid address %q", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("TRUSTED_PROXIES has an invalid network %q", proxy)
+		}
+		nets = append(nets, network)
+	}
+	return nets, nil
+}
@@ -0,0 +1,10 @@
+-- 0002_ai_response_cache.sql
+CREATE TABLE ai_response_cache (
+  cache_key TEXT PRIMARY KEY,      -- sha256(normalized prompt + model + params)
+  model TEXT NOT NULL,
+  response_text TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX ai_response_cache_expires_at_idx ON ai_response_cache (expires_at);
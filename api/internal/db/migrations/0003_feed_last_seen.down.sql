@@ -0,0 +1 @@
+ALTER TABLE users DROP COLUMN IF EXISTS feed_last_seen_at;
@@ -0,0 +1,2 @@
+-- 0003_feed_last_seen.sql
+ALTER TABLE users ADD COLUMN feed_last_seen_at TIMESTAMPTZ;
@@ -0,0 +1,13 @@
+-- 0004_post_embeddings.sql
+-- Requires the pgvector extension (pgvector/pgvector image); the API checks
+-- for it at startup and refuses to run without it
+CREATE EXTENSION IF NOT EXISTS vector;
+
+CREATE TABLE post_embeddings (
+  post_id UUID PRIMARY KEY REFERENCES posts(id) ON DELETE CASCADE,
+  model TEXT NOT NULL,
+  embedding vector(1536) NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX post_embeddings_embedding_idx ON post_embeddings USING hnsw (embedding vector_cosine_ops);
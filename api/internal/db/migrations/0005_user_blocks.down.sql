@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS posts_author_course_idx;
+DROP TABLE IF EXISTS user_blocks;
@@ -0,0 +1,10 @@
+-- 0005_user_blocks.sql
+CREATE TABLE user_blocks (
+  blocker_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  blocked_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (blocker_id, blocked_id)
+);
+
+CREATE INDEX user_blocks_blocked_id_idx ON user_blocks (blocked_id);
+CREATE INDEX posts_author_course_idx ON posts (author_id, course_id);
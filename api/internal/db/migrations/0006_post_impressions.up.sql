@@ -0,0 +1,13 @@
+-- 0006_post_impressions.sql
+ALTER TABLE posts ADD COLUMN view_count BIGINT NOT NULL DEFAULT 0;
+
+-- One row per viewer per post per day; view_count counts these unique daily views
+CREATE TABLE post_impressions (
+  post_id UUID REFERENCES posts(id) ON DELETE CASCADE,
+  viewer_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  viewed_on DATE NOT NULL DEFAULT current_date,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (post_id, viewer_id, viewed_on)
+);
+
+CREATE INDEX post_impressions_viewed_on_idx ON post_impressions (post_id, viewed_on);
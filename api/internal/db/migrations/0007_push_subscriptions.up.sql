@@ -0,0 +1,20 @@
+-- 0007_push_subscriptions.sql
+CREATE TABLE push_subscriptions (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  endpoint TEXT UNIQUE NOT NULL,
+  p256dh TEXT NOT NULL,
+  auth TEXT NOT NULL,
+  expires_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX push_subscriptions_user_id_idx ON push_subscriptions (user_id);
+
+-- Per-type delivery preferences; missing rows fall back to the type defaults
+CREATE TABLE notification_preferences (
+  user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  type TEXT NOT NULL,
+  push BOOLEAN NOT NULL,
+  PRIMARY KEY (user_id, type)
+);
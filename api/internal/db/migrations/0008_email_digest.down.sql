@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS notifications_unread_idx;
+ALTER TABLE notification_preferences DROP COLUMN IF EXISTS email;
+ALTER TABLE users DROP COLUMN IF EXISTS email_digest_sent_at;
+ALTER TABLE users DROP COLUMN IF EXISTS email_digest;
@@ -0,0 +1,9 @@
+-- 0008_email_digest.sql
+ALTER TABLE users ADD COLUMN email_digest TEXT NOT NULL DEFAULT 'weekly'
+  CHECK (email_digest IN ('off', 'daily', 'weekly'));
+ALTER TABLE users ADD COLUMN email_digest_sent_at TIMESTAMPTZ;
+
+-- NULL falls back to the type default
+ALTER TABLE notification_preferences ADD COLUMN email BOOLEAN;
+
+CREATE INDEX notifications_unread_idx ON notifications (user_id, created_at) WHERE read_at IS NULL;
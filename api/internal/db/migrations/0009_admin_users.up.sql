@@ -0,0 +1,3 @@
+-- 0009_admin_users.sql
+-- Grant with: UPDATE users SET is_admin = true WHERE email = '...';
+ALTER TABLE users ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT false;
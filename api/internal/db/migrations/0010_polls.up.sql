@@ -0,0 +1,25 @@
+-- 0010_polls.sql
+CREATE TABLE polls (
+  post_id UUID PRIMARY KEY REFERENCES posts(id) ON DELETE CASCADE,
+  expires_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE poll_options (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  post_id UUID NOT NULL REFERENCES polls(post_id) ON DELETE CASCADE,
+  position INT NOT NULL,
+  text TEXT NOT NULL,
+  UNIQUE (post_id, position)
+);
+
+-- One vote per user per poll
+CREATE TABLE poll_votes (
+  post_id UUID REFERENCES polls(post_id) ON DELETE CASCADE,
+  user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  option_id UUID NOT NULL REFERENCES poll_options(id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (post_id, user_id)
+);
+
+CREATE INDEX poll_votes_option_id_idx ON poll_votes (option_id);
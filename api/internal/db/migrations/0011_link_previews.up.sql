@@ -0,0 +1,27 @@
+-- 0011_link_previews.sql
+-- Open Graph metadata per URL, shared by all posts linking to it.
+-- Rows start as 'pending' and are filled in by the background fetcher;
+-- expires_at schedules the next refresh.
+CREATE TABLE link_previews (
+  url TEXT PRIMARY KEY,
+  status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'ok', 'failed')),
+  title TEXT,
+  description TEXT,
+  image_url TEXT,
+  site_name TEXT,
+  fetched_at TIMESTAMPTZ,
+  expires_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX link_previews_due_idx ON link_previews (expires_at) WHERE status <> 'pending';
+CREATE INDEX link_previews_pending_idx ON link_previews (created_at) WHERE status = 'pending';
+
+CREATE TABLE post_links (
+  post_id UUID REFERENCES posts(id) ON DELETE CASCADE,
+  url TEXT NOT NULL REFERENCES link_previews(url) ON DELETE CASCADE,
+  position INT NOT NULL,
+  PRIMARY KEY (post_id, url)
+);
+
+CREATE INDEX post_links_url_idx ON post_links (url);
@@ -0,0 +1,19 @@
+-- 0012_content_flags.sql
+-- Content filter hits. 'flag' rows are recorded silently; 'review' rows form
+-- the moderation queue until resolved.
+CREATE TABLE content_flags (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  content_type TEXT NOT NULL CHECK (content_type IN ('post', 'comment')),
+  content_id UUID NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  rule TEXT NOT NULL,
+  action TEXT NOT NULL CHECK (action IN ('flag', 'review')),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  resolved_at TIMESTAMPTZ,
+  resolved_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  decision TEXT CHECK (decision IN ('approve', 'remove'))
+);
+
+CREATE INDEX content_flags_open_review_idx ON content_flags (created_at)
+  WHERE action = 'review' AND resolved_at IS NULL;
+CREATE INDEX content_flags_content_idx ON content_flags (content_type, content_id);
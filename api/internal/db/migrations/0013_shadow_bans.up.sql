@@ -0,0 +1,4 @@
+-- 0013_shadow_bans.sql
+-- Shadow-banned users still see their own posts and comments; everyone else
+-- doesn't, and their activity creates no notifications.
+ALTER TABLE users ADD COLUMN shadow_banned BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,13 @@
+-- 0014_follow_requests.sql
+-- Following a private account needs the owner's approval; pending requests
+-- live here until approved (moved to follows) or declined (deleted).
+ALTER TABLE users ADD COLUMN is_private BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE follow_requests (
+  requester_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  target_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (requester_id, target_id)
+);
+
+CREATE INDEX follow_requests_target_id_idx ON follow_requests (target_id, created_at DESC);
@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS ai_content;
+DROP TABLE IF EXISTS ai_post_generations;
+ALTER TABLE posts DROP COLUMN IF EXISTS is_ai_generated;
@@ -0,0 +1,16 @@
+-- 0015_ai_generated_posts.sql
+-- Posts written with /ai/generate-post are labeled. Each generation is
+-- recorded so a post can only claim one the author actually requested.
+ALTER TABLE posts ADD COLUMN is_ai_generated BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE ai_post_generations (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX ai_post_generations_user_id_idx ON ai_post_generations (user_id);
+
+-- How the viewer's feeds treat other users' AI-generated posts
+ALTER TABLE users ADD COLUMN ai_content TEXT NOT NULL DEFAULT 'show'
+  CHECK (ai_content IN ('show', 'downrank', 'hide'));
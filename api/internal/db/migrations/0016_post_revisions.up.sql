@@ -0,0 +1,12 @@
+-- 0016_post_revisions.sql
+-- Each row is a post's text as it was before an edit replaced it.
+ALTER TABLE posts ADD COLUMN edited_at TIMESTAMPTZ;
+
+CREATE TABLE post_revisions (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+  text TEXT NOT NULL,
+  replaced_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX post_revisions_post_id_idx ON post_revisions (post_id, replaced_at DESC);
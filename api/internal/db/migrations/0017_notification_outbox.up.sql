@@ -0,0 +1,14 @@
+-- 0017_notification_outbox.sql
+-- Notification triggers written in the same transaction as the like, comment,
+-- post or follow that caused them; a background dispatcher turns them into
+-- notifications and deletes them.
+CREATE TABLE notification_outbox (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  event JSONB NOT NULL,
+  attempts INT NOT NULL DEFAULT 0,
+  last_error TEXT,
+  available_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX notification_outbox_available_at_idx ON notification_outbox (available_at);
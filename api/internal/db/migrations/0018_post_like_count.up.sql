@@ -0,0 +1,5 @@
+-- 0018_post_like_count.sql
+-- Denormalized like count, updated with the like row in LikePost/UnlikePost
+ALTER TABLE posts ADD COLUMN like_count INT NOT NULL DEFAULT 0;
+
+UPDATE posts p SET like_count = (SELECT COUNT(*) FROM likes l WHERE l.post_id = p.id);
@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS feed_backfilled_at;
+
+DROP TABLE IF EXISTS feed_items;
@@ -0,0 +1,15 @@
+-- 0019_feed_items.sql
+-- Materialized home feeds (fan-out on write): a row per follower for each
+-- new post when FEED_FANOUT_ENABLED is set. feed_backfilled_at marks users
+-- whose feed_items are complete; the others are served the computed feed.
+CREATE TABLE feed_items (
+  user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  post_id UUID REFERENCES posts(id) ON DELETE CASCADE,
+  author_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ NOT NULL,
+  PRIMARY KEY (user_id, post_id)
+);
+
+CREATE INDEX feed_items_user_created_at_idx ON feed_items (user_id, created_at DESC);
+
+ALTER TABLE users ADD COLUMN feed_backfilled_at TIMESTAMPTZ;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS notifications_read_created_at_idx;
+
+DROP TABLE IF EXISTS notifications_archive;
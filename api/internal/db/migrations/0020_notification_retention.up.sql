@@ -0,0 +1,11 @@
+-- 0020_notification_retention.sql
+-- Read notifications past NOTIFICATION_RETENTION_DAYS are deleted or, with
+-- NOTIFICATION_RETENTION_ACTION=archive, moved here by the cleanup job.
+CREATE TABLE notifications_archive (
+  LIKE notifications,
+  archived_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX notifications_archive_user_id_idx ON notifications_archive (user_id, created_at DESC);
+
+CREATE INDEX notifications_read_created_at_idx ON notifications (created_at) WHERE read_at IS NOT NULL;
@@ -0,0 +1,13 @@
+-- 0021_username_history.sql
+-- Previous usernames keep resolving to their owner so mentions and profile
+-- links survive a rename, and nobody else can take them.
+-- username_changed_at enforces the cooldown between changes.
+ALTER TABLE users ADD COLUMN username_changed_at TIMESTAMPTZ;
+
+CREATE TABLE username_history (
+  username TEXT PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  changed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX username_history_user_id_idx ON username_history (user_id);
@@ -0,0 +1,2 @@
+ALTER TABLE posts DROP COLUMN IF EXISTS slug;
+ALTER TABLE posts DROP COLUMN IF EXISTS seq;
@@ -0,0 +1,27 @@
+-- 0022_post_slugs.sql
+-- Human-friendly post URLs: seq is encoded in base62 and followed by a few
+-- words of the text when the post is created (e.g. "4c-intro-to-go").
+-- Slugs resolve by their base62 prefix alone.
+ALTER TABLE posts ADD COLUMN seq BIGSERIAL;
+CREATE UNIQUE INDEX posts_seq_idx ON posts (seq);
+
+ALTER TABLE posts ADD COLUMN slug TEXT;
+
+-- Existing posts get the base62 part only
+CREATE FUNCTION pg_temp.base62(n BIGINT) RETURNS TEXT AS $$
+DECLARE
+  alphabet CONSTANT TEXT := '0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ';
+  result TEXT := '';
+BEGIN
+  LOOP
+    result := substr(alphabet, (n % 62)::INT + 1, 1) || result;
+    n := n / 62;
+    EXIT WHEN n = 0;
+  END LOOP;
+  RETURN result;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+UPDATE posts SET slug = pg_temp.base62(seq);
+
+ALTER TABLE posts ALTER COLUMN slug SET NOT NULL;
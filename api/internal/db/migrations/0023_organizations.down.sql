@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS posts_org_id_created_at_idx;
+DROP INDEX IF EXISTS courses_org_id_idx;
+DROP INDEX IF EXISTS users_org_id_idx;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS org_id;
+ALTER TABLE courses DROP COLUMN IF EXISTS org_id;
+ALTER TABLE users DROP COLUMN IF EXISTS org_id;
+
+DROP TABLE IF EXISTS organizations;
@@ -0,0 +1,27 @@
+-- 0023_organizations.sql
+-- Each school is an organization. Users, courses and posts belong to one and
+-- only see data from their own; everything that exists so far goes to the
+-- default organization.
+CREATE TABLE organizations (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  slug TEXT UNIQUE NOT NULL,
+  name TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+INSERT INTO organizations (id, slug, name)
+VALUES ('00000000-0000-0000-0000-000000000001', 'default', 'Bailanysta');
+
+ALTER TABLE users ADD COLUMN org_id UUID NOT NULL
+  DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organizations(id);
+ALTER TABLE courses ADD COLUMN org_id UUID NOT NULL
+  DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organizations(id);
+
+-- Copied from the author so org-wide listings don't need to join users
+ALTER TABLE posts ADD COLUMN org_id UUID REFERENCES organizations(id);
+UPDATE posts p SET org_id = u.org_id FROM users u WHERE u.id = p.author_id;
+ALTER TABLE posts ALTER COLUMN org_id SET NOT NULL;
+
+CREATE INDEX users_org_id_idx ON users (org_id);
+CREATE INDEX courses_org_id_idx ON courses (org_id);
+CREATE INDEX posts_org_id_created_at_idx ON posts (org_id, created_at DESC);
@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT false;
+UPDATE users SET is_admin = true WHERE role = 'admin';
+ALTER TABLE users DROP COLUMN IF EXISTS role;
@@ -0,0 +1,7 @@
+-- 0024_roles.sql
+-- Replaces the admin flag with a role. What each role may do is decided in
+-- code (services/roles.go).
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'student'
+  CHECK (role IN ('student', 'teacher', 'moderator', 'admin'));
+UPDATE users SET role = 'admin' WHERE is_admin;
+ALTER TABLE users DROP COLUMN is_admin;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS comments_verified_answer_idx;
+
+ALTER TABLE comments DROP COLUMN IF EXISTS verified_at;
+ALTER TABLE comments DROP COLUMN IF EXISTS verified_by;
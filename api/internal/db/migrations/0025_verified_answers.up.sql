@@ -0,0 +1,6 @@
+-- 0025_verified_answers.sql
+-- A teacher can mark one comment per course post as the verified answer
+ALTER TABLE comments ADD COLUMN verified_by UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE comments ADD COLUMN verified_at TIMESTAMPTZ;
+
+CREATE UNIQUE INDEX comments_verified_answer_idx ON comments (post_id) WHERE verified_at IS NOT NULL;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS posts_group_id_created_at_idx;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS group_id;
+
+DROP TABLE IF EXISTS group_members;
+DROP TABLE IF EXISTS groups;
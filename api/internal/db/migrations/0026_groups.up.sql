@@ -0,0 +1,28 @@
+-- 0026_groups.sql
+-- Study groups within an organization. Group posts are only shown in the
+-- group's feed, never in home feeds, search or RSS.
+CREATE TABLE groups (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  org_id UUID NOT NULL REFERENCES organizations(id),
+  name TEXT NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  privacy TEXT NOT NULL DEFAULT 'open' CHECK (privacy IN ('open', 'request', 'invite')),
+  created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- A row is a member, a pending join request or a pending invitation
+CREATE TABLE group_members (
+  group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  role TEXT NOT NULL DEFAULT 'member' CHECK (role IN ('owner', 'admin', 'member')),
+  status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'requested', 'invited')),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (group_id, user_id)
+);
+
+ALTER TABLE posts ADD COLUMN group_id UUID REFERENCES groups(id) ON DELETE CASCADE;
+
+CREATE INDEX groups_org_id_idx ON groups (org_id);
+CREATE INDEX group_members_user_id_idx ON group_members (user_id);
+CREATE INDEX posts_group_id_created_at_idx ON posts (group_id, created_at DESC) WHERE group_id IS NOT NULL;
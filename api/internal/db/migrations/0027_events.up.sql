@@ -0,0 +1,31 @@
+-- 0027_events.sql
+-- Scheduled events (study sessions, office hours) of a course or a group.
+-- reminder_sent_at is set once the reminder job has notified attendees.
+CREATE TABLE events (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  org_id UUID NOT NULL REFERENCES organizations(id),
+  course_id UUID REFERENCES courses(id) ON DELETE CASCADE,
+  group_id UUID REFERENCES groups(id) ON DELETE CASCADE,
+  title TEXT NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  location TEXT NOT NULL DEFAULT '',
+  starts_at TIMESTAMPTZ NOT NULL,
+  ends_at TIMESTAMPTZ NOT NULL,
+  created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  reminder_sent_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CHECK ((course_id IS NULL) <> (group_id IS NULL)),
+  CHECK (ends_at > starts_at)
+);
+
+CREATE TABLE event_rsvps (
+  event_id UUID NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  status TEXT NOT NULL CHECK (status IN ('going', 'maybe', 'declined')),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (event_id, user_id)
+);
+
+CREATE INDEX events_org_id_starts_at_idx ON events (org_id, starts_at);
+CREATE INDEX events_reminder_idx ON events (starts_at) WHERE reminder_sent_at IS NULL;
+CREATE INDEX event_rsvps_user_id_idx ON event_rsvps (user_id);
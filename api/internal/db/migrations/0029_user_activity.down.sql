@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS streak_reminded_on;
+
+DROP TABLE IF EXISTS user_activity;
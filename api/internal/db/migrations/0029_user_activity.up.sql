@@ -0,0 +1,25 @@
+-- 0029_user_activity.sql
+-- Per-user daily activity counters (UTC days) for the activity calendar and
+-- streaks. streak_reminded_on keeps the streak reminder to once a day.
+CREATE TABLE user_activity (
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  day DATE NOT NULL,
+  posts INT NOT NULL DEFAULT 0,
+  comments INT NOT NULL DEFAULT 0,
+  quizzes INT NOT NULL DEFAULT 0,
+  PRIMARY KEY (user_id, day)
+);
+
+ALTER TABLE users ADD COLUMN streak_reminded_on DATE;
+
+INSERT INTO user_activity (user_id, day, posts, comments)
+SELECT user_id, day, SUM(posts), SUM(comments)
+FROM (
+  SELECT author_id AS user_id, (created_at AT TIME ZONE 'UTC')::date AS day, 1 AS posts, 0 AS comments FROM posts
+  UNION ALL
+  SELECT author_id, (created_at AT TIME ZONE 'UTC')::date, 0, 1 FROM comments
+) activity
+WHERE user_id IS NOT NULL
+GROUP BY user_id, day;
+
+CREATE INDEX user_activity_day_idx ON user_activity (day);
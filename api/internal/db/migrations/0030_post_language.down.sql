@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN IF EXISTS feed_languages;
+ALTER TABLE posts DROP COLUMN IF EXISTS language;
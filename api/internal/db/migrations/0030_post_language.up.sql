@@ -0,0 +1,12 @@
+-- 0030_post_language.sql
+-- Dominant language of each post (en, kk or ru; NULL when undetected) and
+-- the languages a user wants in their feeds; an empty list means all.
+ALTER TABLE posts ADD COLUMN language TEXT;
+ALTER TABLE users ADD COLUMN feed_languages TEXT[] NOT NULL DEFAULT '{}';
+
+-- Rough backfill; new posts use the service's detector
+UPDATE posts SET language = CASE
+  WHEN text ~ '[әғқңөұүһіӘҒҚҢӨҰҮҺІ]' THEN 'kk'
+  WHEN text ~ '[а-яА-ЯёЁ]' THEN 'ru'
+  WHEN text ~ '[a-zA-Z]' THEN 'en'
+END;
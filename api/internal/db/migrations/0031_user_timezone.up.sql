@@ -0,0 +1,4 @@
+-- 0031_user_timezone.sql
+-- IANA time zone used for the user's calendar days: digest send times,
+-- activity days and streak reminders.
+ALTER TABLE users ADD COLUMN timezone TEXT NOT NULL DEFAULT 'UTC';
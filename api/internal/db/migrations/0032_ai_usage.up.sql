@@ -0,0 +1,10 @@
+-- 0032_ai_usage.sql
+-- AI requests and tokens per user and UTC day, counted against the daily AI
+-- quota.
+CREATE TABLE ai_usage (
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  day DATE NOT NULL,
+  requests INT NOT NULL DEFAULT 0,
+  tokens BIGINT NOT NULL DEFAULT 0,
+  PRIMARY KEY (user_id, day)
+);
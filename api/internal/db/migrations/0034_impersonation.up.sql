@@ -0,0 +1,25 @@
+-- 0034_impersonation.sql
+-- Support staff acting as a user. Every request made during a session is
+-- logged in impersonation_actions.
+CREATE TABLE impersonation_sessions (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  impersonator_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  reason TEXT NOT NULL,
+  can_write BOOLEAN NOT NULL DEFAULT false,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_impersonation_sessions_user_id ON impersonation_sessions(user_id, created_at DESC);
+
+CREATE TABLE impersonation_actions (
+  id BIGSERIAL PRIMARY KEY,
+  session_id UUID NOT NULL REFERENCES impersonation_sessions(id) ON DELETE CASCADE,
+  method TEXT NOT NULL,
+  path TEXT NOT NULL,
+  status INT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_impersonation_actions_session_id ON impersonation_actions(session_id, created_at);
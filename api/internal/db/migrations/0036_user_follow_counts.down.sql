@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS follows_count_trigger ON follows;
+DROP FUNCTION IF EXISTS update_follow_counts();
+ALTER TABLE users DROP COLUMN IF EXISTS followers_count, DROP COLUMN IF EXISTS following_count;
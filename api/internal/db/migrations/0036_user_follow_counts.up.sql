@@ -0,0 +1,28 @@
+-- 0036_user_follow_counts.sql
+-- Denormalized follower/following counts. A trigger keeps them in step with
+-- follows, which is also changed by blocks, privacy changes and cascades
+-- from deleted users.
+ALTER TABLE users
+  ADD COLUMN followers_count INT NOT NULL DEFAULT 0,
+  ADD COLUMN following_count INT NOT NULL DEFAULT 0;
+
+UPDATE users u SET
+  followers_count = (SELECT COUNT(*) FROM follows f WHERE f.followee_id = u.id),
+  following_count = (SELECT COUNT(*) FROM follows f WHERE f.follower_id = u.id);
+
+CREATE FUNCTION update_follow_counts() RETURNS trigger AS $$
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    UPDATE users SET following_count = following_count + 1 WHERE id = NEW.follower_id;
+    UPDATE users SET followers_count = followers_count + 1 WHERE id = NEW.followee_id;
+  ELSE
+    UPDATE users SET following_count = GREATEST(following_count - 1, 0) WHERE id = OLD.follower_id;
+    UPDATE users SET followers_count = GREATEST(followers_count - 1, 0) WHERE id = OLD.followee_id;
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER follows_count_trigger
+  AFTER INSERT OR DELETE ON follows
+  FOR EACH ROW EXECUTE FUNCTION update_follow_counts();
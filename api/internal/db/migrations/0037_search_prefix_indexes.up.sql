@@ -0,0 +1,5 @@
+-- 0037_search_prefix_indexes.sql
+-- Case-insensitive prefix lookups for /search/suggest; text_pattern_ops
+-- lets LIKE 'abc%' use the index whatever the database collation
+CREATE INDEX users_username_prefix_idx ON users (lower(username) text_pattern_ops);
+CREATE INDEX hashtags_tag_prefix_idx ON hashtags (lower(tag) text_pattern_ops);
@@ -0,0 +1,6 @@
+-- 0038_user_presence.sql
+-- When each user was last active, written at most once per interval per API
+-- replica, and whether others may see it.
+ALTER TABLE users
+  ADD COLUMN last_active_at TIMESTAMPTZ,
+  ADD COLUMN show_presence BOOLEAN NOT NULL DEFAULT true;
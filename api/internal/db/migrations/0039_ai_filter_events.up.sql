@@ -0,0 +1,14 @@
+-- 0039_ai_filter_events.sql
+-- AI prompts that tried to override our instructions and responses the
+-- output filter blocked, kept for moderators to review.
+CREATE TABLE ai_filter_events (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+  feature TEXT NOT NULL,
+  stage TEXT NOT NULL CHECK (stage IN ('input', 'output')),
+  rule TEXT NOT NULL,
+  excerpt TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX ai_filter_events_created_at_idx ON ai_filter_events (created_at);
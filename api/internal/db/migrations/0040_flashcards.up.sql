@@ -0,0 +1,14 @@
+-- 0040_flashcards.sql
+-- Study cards a user generated with AI from a topic, a post or their notes.
+-- source_post_id is kept only while the post exists.
+CREATE TABLE flashcards (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  front TEXT NOT NULL,
+  back TEXT NOT NULL,
+  topic TEXT NOT NULL DEFAULT '',
+  source_post_id UUID REFERENCES posts(id) ON DELETE SET NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX flashcards_user_idx ON flashcards (user_id, created_at DESC);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS flashcard_reviews;
+ALTER TABLE flashcards DROP COLUMN IF EXISTS ease_factor, DROP COLUMN IF EXISTS interval_days, DROP COLUMN IF EXISTS repetitions, DROP COLUMN IF EXISTS due_at, DROP COLUMN IF EXISTS last_reviewed_at;
@@ -0,0 +1,21 @@
+-- 0041_flashcard_reviews.sql
+-- SM-2 scheduling state per card, and every review a user graded. New cards
+-- are due at once.
+ALTER TABLE flashcards
+  ADD COLUMN ease_factor DOUBLE PRECISION NOT NULL DEFAULT 2.5,
+  ADD COLUMN interval_days INT NOT NULL DEFAULT 0,
+  ADD COLUMN repetitions INT NOT NULL DEFAULT 0,
+  ADD COLUMN due_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  ADD COLUMN last_reviewed_at TIMESTAMPTZ;
+
+CREATE INDEX flashcards_due_idx ON flashcards (user_id, due_at);
+
+CREATE TABLE flashcard_reviews (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  flashcard_id UUID NOT NULL REFERENCES flashcards(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  grade SMALLINT NOT NULL CHECK (grade BETWEEN 0 AND 5),
+  reviewed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX flashcard_reviews_card_idx ON flashcard_reviews (flashcard_id, reviewed_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS course_completions;
+DROP TABLE IF EXISTS module_progress;
@@ -0,0 +1,18 @@
+-- 0042_module_progress.sql
+-- Modules each student marked complete, and the courses they finished. A
+-- course completion stays as an achievement even if modules are added later.
+CREATE TABLE module_progress (
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  module_id UUID NOT NULL REFERENCES modules(id) ON DELETE CASCADE,
+  completed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (user_id, module_id)
+);
+
+CREATE INDEX module_progress_module_idx ON module_progress (module_id);
+
+CREATE TABLE course_completions (
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  course_id UUID NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+  completed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (user_id, course_id)
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS assignment_submissions;
+DROP TABLE IF EXISTS assignments;
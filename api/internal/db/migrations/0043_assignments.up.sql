@@ -0,0 +1,34 @@
+-- 0043_assignments.sql
+-- Assignments teachers set on modules and the work students hand in. A
+-- student has one submission per assignment, replaced when they resubmit.
+-- reminder_sent_at is set once the reminder job has notified students.
+CREATE TABLE assignments (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  module_id UUID NOT NULL REFERENCES modules(id) ON DELETE CASCADE,
+  created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  title TEXT NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  due_at TIMESTAMPTZ,
+  reminder_sent_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX assignments_module_idx ON assignments (module_id, due_at);
+CREATE INDEX assignments_reminder_idx ON assignments (due_at) WHERE reminder_sent_at IS NULL;
+
+CREATE TABLE assignment_submissions (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  assignment_id UUID NOT NULL REFERENCES assignments(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  text TEXT NOT NULL DEFAULT '',
+  attachment_urls TEXT[] NOT NULL DEFAULT '{}',
+  status TEXT NOT NULL DEFAULT 'submitted' CHECK (status IN ('submitted', 'returned', 'graded')),
+  grade INT,
+  feedback TEXT NOT NULL DEFAULT '',
+  submitted_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  reviewed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  reviewed_at TIMESTAMPTZ,
+  UNIQUE (assignment_id, user_id)
+);
+
+CREATE INDEX assignment_submissions_user_idx ON assignment_submissions (user_id);
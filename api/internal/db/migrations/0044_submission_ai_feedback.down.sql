@@ -0,0 +1 @@
+ALTER TABLE assignment_submissions DROP COLUMN IF EXISTS ai_feedback;
@@ -0,0 +1,5 @@
+-- 0044_submission_ai_feedback.sql
+-- AI feedback a teacher asked for on a submission: rubric comments and a
+-- suggested score. It is only advice and is cleared when the student
+-- resubmits.
+ALTER TABLE assignment_submissions ADD COLUMN ai_feedback JSONB;
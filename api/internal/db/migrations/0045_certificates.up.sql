@@ -0,0 +1,19 @@
+-- 0045_certificates.sql
+-- A certificate is issued once per completed course. Its code is printed on
+-- the PDF and checked by the public verify endpoint. Courses completed
+-- before certificates existed get one here.
+CREATE TABLE certificates (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  course_id UUID NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+  code TEXT NOT NULL UNIQUE,
+  issued_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  UNIQUE (user_id, course_id)
+);
+
+INSERT INTO certificates (user_id, course_id, code, issued_at)
+SELECT user_id, course_id, concat_ws('-', substr(h, 1, 4), substr(h, 5, 4), substr(h, 9, 4), substr(h, 13, 4)), completed_at
+FROM (
+  SELECT user_id, course_id, completed_at, upper(md5(random()::text || user_id::text || course_id::text)) AS h
+  FROM course_completions
+) c;
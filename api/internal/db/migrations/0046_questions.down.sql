@@ -0,0 +1,34 @@
+DROP MATERIALIZED VIEW IF EXISTS leaderboard_points;
+CREATE MATERIALIZED VIEW leaderboard_points AS
+SELECT user_id, org_id, course_id, day, SUM(points)::int AS points
+FROM (
+  SELECT p.author_id AS user_id, p.org_id, p.course_id, (p.created_at AT TIME ZONE 'UTC')::date AS day, 10 AS points
+  FROM posts p
+  WHERE p.group_id IS NULL
+  UNION ALL
+  SELECT c.author_id, p.org_id, p.course_id, (c.created_at AT TIME ZONE 'UTC')::date, 3
+  FROM comments c JOIN posts p ON p.id = c.post_id
+  WHERE p.group_id IS NULL
+  UNION ALL
+  SELECT p.author_id, p.org_id, p.course_id, (l.created_at AT TIME ZONE 'UTC')::date, 2
+  FROM likes l JOIN posts p ON p.id = l.post_id
+  WHERE p.group_id IS NULL AND l.user_id <> p.author_id
+  UNION ALL
+  SELECT c.author_id, p.org_id, p.course_id, (c.verified_at AT TIME ZONE 'UTC')::date, 15
+  FROM comments c JOIN posts p ON p.id = c.post_id
+  WHERE p.group_id IS NULL AND c.verified_at IS NOT NULL
+) events
+WHERE user_id IS NOT NULL
+GROUP BY user_id, org_id, course_id, day;
+
+CREATE UNIQUE INDEX leaderboard_points_key ON leaderboard_points (user_id, org_id, course_id, day);
+CREATE INDEX leaderboard_points_org_day_idx ON leaderboard_points (org_id, day);
+
+DROP INDEX IF EXISTS posts_unanswered_idx;
+ALTER TABLE posts
+  DROP COLUMN IF EXISTS bounty_awarded_at,
+  DROP COLUMN IF EXISTS bounty_awarded_to,
+  DROP COLUMN IF EXISTS bounty,
+  DROP COLUMN IF EXISTS accepted_at,
+  DROP COLUMN IF EXISTS accepted_comment_id,
+  DROP COLUMN IF EXISTS post_type;
@@ -0,0 +1,54 @@
+-- 0046_questions.sql
+-- Posts can be questions. The asker accepts one comment as the answer and
+-- may put up a bounty of reputation (leaderboard points), which moves to the
+-- first accepted answerer other than themselves.
+ALTER TABLE posts
+  ADD COLUMN post_type TEXT NOT NULL DEFAULT 'post' CHECK (post_type IN ('post', 'question')),
+  ADD COLUMN accepted_comment_id UUID REFERENCES comments(id) ON DELETE SET NULL,
+  ADD COLUMN accepted_at TIMESTAMPTZ,
+  ADD COLUMN bounty INT NOT NULL DEFAULT 0 CHECK (bounty >= 0),
+  ADD COLUMN bounty_awarded_to UUID REFERENCES users(id) ON DELETE SET NULL,
+  ADD COLUMN bounty_awarded_at TIMESTAMPTZ;
+
+CREATE INDEX posts_unanswered_idx ON posts (org_id, created_at DESC)
+  WHERE post_type = 'question' AND accepted_comment_id IS NULL;
+
+-- Points as in 0028, plus 10 per accepted answer to someone else's question
+-- and the bounty, taken from the asker and given to the answerer
+DROP MATERIALIZED VIEW leaderboard_points;
+CREATE MATERIALIZED VIEW leaderboard_points AS
+SELECT user_id, org_id, course_id, day, SUM(points)::int AS points
+FROM (
+  SELECT p.author_id AS user_id, p.org_id, p.course_id, (p.created_at AT TIME ZONE 'UTC')::date AS day, 10 AS points
+  FROM posts p
+  WHERE p.group_id IS NULL
+  UNION ALL
+  SELECT c.author_id, p.org_id, p.course_id, (c.created_at AT TIME ZONE 'UTC')::date, 3
+  FROM comments c JOIN posts p ON p.id = c.post_id
+  WHERE p.group_id IS NULL
+  UNION ALL
+  SELECT p.author_id, p.org_id, p.course_id, (l.created_at AT TIME ZONE 'UTC')::date, 2
+  FROM likes l JOIN posts p ON p.id = l.post_id
+  WHERE p.group_id IS NULL AND l.user_id <> p.author_id
+  UNION ALL
+  SELECT c.author_id, p.org_id, p.course_id, (c.verified_at AT TIME ZONE 'UTC')::date, 15
+  FROM comments c JOIN posts p ON p.id = c.post_id
+  WHERE p.group_id IS NULL AND c.verified_at IS NOT NULL
+  UNION ALL
+  SELECT c.author_id, p.org_id, p.course_id, (p.accepted_at AT TIME ZONE 'UTC')::date, 10
+  FROM posts p JOIN comments c ON c.id = p.accepted_comment_id
+  WHERE p.group_id IS NULL AND c.author_id <> p.author_id
+  UNION ALL
+  SELECT p.bounty_awarded_to, p.org_id, p.course_id, (p.bounty_awarded_at AT TIME ZONE 'UTC')::date, p.bounty
+  FROM posts p
+  WHERE p.bounty_awarded_at IS NOT NULL
+  UNION ALL
+  SELECT p.author_id, p.org_id, p.course_id, (p.bounty_awarded_at AT TIME ZONE 'UTC')::date, -p.bounty
+  FROM posts p
+  WHERE p.bounty_awarded_at IS NOT NULL
+) events
+WHERE user_id IS NOT NULL
+GROUP BY user_id, org_id, course_id, day;
+
+CREATE UNIQUE INDEX leaderboard_points_key ON leaderboard_points (user_id, org_id, course_id, day);
+CREATE INDEX leaderboard_points_org_day_idx ON leaderboard_points (org_id, day);
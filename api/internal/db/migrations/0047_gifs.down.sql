@@ -0,0 +1,3 @@
+ALTER TABLE comments DROP COLUMN IF EXISTS gif_id;
+ALTER TABLE posts DROP COLUMN IF EXISTS gif_id;
+DROP TABLE IF EXISTS gifs;
@@ -0,0 +1,16 @@
+-- 0047_gifs.sql
+-- GIFs attached to posts and comments. Metadata is stored when a GIF is
+-- first attached so feeds don't call the provider; id is "provider:id".
+CREATE TABLE gifs (
+  id          TEXT PRIMARY KEY,
+  provider    TEXT NOT NULL,
+  title       TEXT NOT NULL DEFAULT '',
+  url         TEXT NOT NULL,
+  preview_url TEXT NOT NULL,
+  width       INT NOT NULL DEFAULT 0,
+  height      INT NOT NULL DEFAULT 0,
+  fetched_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE posts ADD COLUMN gif_id TEXT REFERENCES gifs(id);
+ALTER TABLE comments ADD COLUMN gif_id TEXT REFERENCES gifs(id);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS profile_views;
+ALTER TABLE users
+  DROP COLUMN IF EXISTS share_profile_views,
+  DROP COLUMN IF EXISTS profile_views_enabled;
@@ -0,0 +1,18 @@
+-- 0048_profile_views.sql
+-- Who viewed a profile, at most one row per viewer and UTC day, recorded
+-- only for users who enabled it and deleted after the retention period.
+-- viewer_shared records whether the viewer allowed showing their name at
+-- the time of the view.
+ALTER TABLE users
+  ADD COLUMN profile_views_enabled BOOLEAN NOT NULL DEFAULT false,
+  ADD COLUMN share_profile_views BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE profile_views (
+  profile_id    UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  viewer_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  day           DATE NOT NULL,
+  viewer_shared BOOLEAN NOT NULL DEFAULT false,
+  PRIMARY KEY (profile_id, day, viewer_id)
+);
+
+CREATE INDEX profile_views_day_idx ON profile_views (day);
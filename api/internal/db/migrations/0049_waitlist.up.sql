@@ -0,0 +1,16 @@
+-- 0049_waitlist.sql
+-- Waitlist for invite-only registration. Approving an entry issues a
+-- one-time registration link; only the token's SHA-256 hash is stored.
+CREATE TABLE waitlist (
+  id                UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  email             TEXT NOT NULL,
+  created_at        TIMESTAMPTZ NOT NULL DEFAULT now(),
+  approved_at       TIMESTAMPTZ,
+  approved_by       UUID REFERENCES users(id) ON DELETE SET NULL,
+  invite_token_hash TEXT UNIQUE,
+  invite_expires_at TIMESTAMPTZ,
+  registered_at     TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX waitlist_email_idx ON waitlist (lower(email));
+CREATE INDEX waitlist_pending_idx ON waitlist (created_at) WHERE approved_at IS NULL;
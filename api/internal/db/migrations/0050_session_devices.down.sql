@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS user_devices;
+ALTER TABLE refresh_tokens
+  DROP COLUMN IF EXISTS city,
+  DROP COLUMN IF EXISTS country,
+  DROP COLUMN IF EXISTS ip_address,
+  DROP COLUMN IF EXISTS device,
+  DROP COLUMN IF EXISTS user_agent;
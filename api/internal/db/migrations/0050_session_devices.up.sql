@@ -0,0 +1,19 @@
+-- 0050_session_devices.sql
+-- Where each session was last used from, and the devices each user has
+-- signed in on. A device is its browser, OS and device type, so browser
+-- updates don't make it new; locations come from the GeoIP database.
+ALTER TABLE refresh_tokens
+  ADD COLUMN user_agent  TEXT NOT NULL DEFAULT '',
+  ADD COLUMN device      TEXT NOT NULL DEFAULT '',
+  ADD COLUMN ip_address  TEXT NOT NULL DEFAULT '',
+  ADD COLUMN country     TEXT NOT NULL DEFAULT '',
+  ADD COLUMN city        TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE user_devices (
+  user_id       UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  fingerprint   TEXT NOT NULL,
+  device        TEXT NOT NULL,
+  first_seen_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  last_seen_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (user_id, fingerprint)
+);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS account_recovery_events;
+DROP TABLE IF EXISTS recovery_tokens;
+DROP TABLE IF EXISTS recovery_codes;
+
+ALTER TABLE users
+  DROP COLUMN IF EXISTS backup_email_verified_at,
+  DROP COLUMN IF EXISTS backup_email;
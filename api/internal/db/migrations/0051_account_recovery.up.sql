@@ -0,0 +1,41 @@
+-- 0051_account_recovery.sql
+-- Ways back into an account without its password: a verified backup email
+-- and one-time recovery codes. Only SHA-256 hashes of codes and emailed
+-- tokens are stored. account_recovery_events is the audit of both.
+ALTER TABLE users
+  ADD COLUMN backup_email             TEXT,
+  ADD COLUMN backup_email_verified_at TIMESTAMPTZ;
+
+CREATE TABLE recovery_codes (
+  id         UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id    UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  code_hash  TEXT NOT NULL UNIQUE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  used_at    TIMESTAMPTZ
+);
+
+CREATE INDEX recovery_codes_user_idx ON recovery_codes (user_id);
+
+-- purpose is 'verify_backup_email' (email is the address being verified)
+-- or 'recover' (sent to the verified backup email)
+CREATE TABLE recovery_tokens (
+  token_hash TEXT PRIMARY KEY,
+  user_id    UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  purpose    TEXT NOT NULL,
+  email      TEXT NOT NULL,
+  expires_at TIMESTAMPTZ NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX recovery_tokens_user_idx ON recovery_tokens (user_id, purpose);
+
+CREATE TABLE account_recovery_events (
+  id         UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id    UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  event      TEXT NOT NULL,
+  ip_address TEXT NOT NULL DEFAULT '',
+  user_agent TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX account_recovery_events_user_idx ON account_recovery_events (user_id, created_at DESC);
@@ -0,0 +1,5 @@
+-- 0052_account_anonymization.sql
+-- Accounts deleted with anonymization keep their row so posts and comments
+-- stay in their threads; the row is scrubbed to a "deleted-..." placeholder
+-- and marked here.
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMPTZ;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS abuse_flags;
+DROP TABLE IF EXISTS action_counts;
+ALTER TABLE users DROP COLUMN IF EXISTS throttled_until;
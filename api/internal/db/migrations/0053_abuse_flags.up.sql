@@ -0,0 +1,29 @@
+-- 0053_abuse_flags.sql
+-- Per-minute counts of rate-watched actions (follows, likes), kept for an
+-- hour. An account over a limit is throttled until throttled_until and
+-- flagged for moderators in abuse_flags.
+ALTER TABLE users ADD COLUMN throttled_until TIMESTAMPTZ;
+
+CREATE TABLE action_counts (
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  action  TEXT NOT NULL,
+  minute  TIMESTAMPTZ NOT NULL,
+  count   INT NOT NULL DEFAULT 0,
+  PRIMARY KEY (user_id, action, minute)
+);
+
+CREATE INDEX action_counts_minute_idx ON action_counts (minute);
+
+CREATE TABLE abuse_flags (
+  id          UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  rule        TEXT NOT NULL,
+  count       INT NOT NULL,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+  resolved_at TIMESTAMPTZ,
+  resolved_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  decision    TEXT CHECK (decision IN ('clear', 'confirm'))
+);
+
+CREATE INDEX abuse_flags_open_idx ON abuse_flags (created_at) WHERE resolved_at IS NULL;
+CREATE INDEX abuse_flags_user_idx ON abuse_flags (user_id);
@@ -0,0 +1,18 @@
+-- 0054_ip_blocks.sql
+-- Networks refused before authentication: an IP range (stored normalized,
+-- e.g. 203.0.113.0/24 or 198.51.100.7/32) or an autonomous system number.
+-- Rows without expires_at block until removed; source is 'admin' or
+-- 'abuse' for the temporary blocks the abuse heuristics add.
+CREATE TABLE ip_blocks (
+  id         UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  cidr       TEXT,
+  asn        BIGINT,
+  reason     TEXT NOT NULL DEFAULT '',
+  source     TEXT NOT NULL DEFAULT 'admin' CHECK (source IN ('admin', 'abuse')),
+  created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  expires_at TIMESTAMPTZ,
+  CHECK ((cidr IS NULL) <> (asn IS NULL))
+);
+
+CREATE INDEX ip_blocks_expires_at_idx ON ip_blocks (expires_at) WHERE expires_at IS NOT NULL;
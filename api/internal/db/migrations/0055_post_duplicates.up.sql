@@ -0,0 +1,21 @@
+-- 0055_post_duplicates.sql
+-- 64-bit simhash of each post's normalized text; posts whose hashes differ
+-- in a few bits are near-duplicates. A new post close to a recent one in
+-- the same organization is recorded in post_duplicates for moderators, who
+-- dismiss it or merge its likes and comments onto the original.
+ALTER TABLE posts ADD COLUMN simhash BIGINT;
+
+CREATE INDEX posts_simhash_created_at_idx ON posts (org_id, created_at) WHERE simhash IS NOT NULL;
+
+CREATE TABLE post_duplicates (
+  post_id     UUID PRIMARY KEY REFERENCES posts(id) ON DELETE CASCADE,
+  original_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+  distance    INT NOT NULL,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+  resolved_at TIMESTAMPTZ,
+  resolved_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  decision    TEXT CHECK (decision IN ('dismiss', 'merge'))
+);
+
+CREATE INDEX post_duplicates_open_idx ON post_duplicates (created_at) WHERE resolved_at IS NULL;
+CREATE INDEX post_duplicates_original_idx ON post_duplicates (original_id);
@@ -0,0 +1,14 @@
+-- 0056_post_limits.sql
+-- Post length limits an organization sets for itself, optionally narrowed to
+-- a course, a post type or both. The most specific row applies; without
+-- one the server's POST_MAX_CHARS and POST_MAX_WORDS do.
+CREATE TABLE post_limits (
+  id         UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  org_id     UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+  course_id  UUID REFERENCES courses(id) ON DELETE CASCADE,
+  post_type  TEXT CHECK (post_type IN ('post', 'question')),
+  max_chars  INT NOT NULL CHECK (max_chars > 0),
+  max_words  INT NOT NULL DEFAULT 0 CHECK (max_words >= 0), -- 0 for none
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  UNIQUE NULLS NOT DISTINCT (org_id, course_id, post_type)
+);
@@ -0,0 +1,2 @@
+UPDATE hashtags SET tag = display;
+ALTER TABLE hashtags DROP COLUMN IF EXISTS display;
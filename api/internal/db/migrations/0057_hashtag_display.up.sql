@@ -0,0 +1,23 @@
+-- 0057_hashtag_display.sql
+-- hashtags.tag becomes the canonical form posts are grouped under (NFC
+-- normalized and case folded), and display keeps the form it was first
+-- written in. Tags that differed only in case or normalization are merged
+-- into the one that sorts first.
+ALTER TABLE hashtags ADD COLUMN display TEXT;
+UPDATE hashtags SET display = tag;
+ALTER TABLE hashtags ALTER COLUMN display SET NOT NULL;
+
+CREATE TEMP TABLE hashtag_merges ON COMMIT DROP AS
+SELECT id, first_value(id) OVER (PARTITION BY lower(normalize(tag, NFC)) ORDER BY tag) AS keep_id
+FROM hashtags;
+
+INSERT INTO post_hashtags (post_id, hashtag_id)
+SELECT ph.post_id, m.keep_id
+FROM post_hashtags ph
+JOIN hashtag_merges m ON m.id = ph.hashtag_id
+WHERE m.id <> m.keep_id
+ON CONFLICT DO NOTHING;
+
+DELETE FROM hashtags h USING hashtag_merges m WHERE h.id = m.id AND m.id <> m.keep_id;
+
+UPDATE hashtags SET tag = lower(normalize(tag, NFC));
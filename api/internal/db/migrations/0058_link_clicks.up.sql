@@ -0,0 +1,11 @@
+-- 0058_link_clicks.sql
+-- Clicks on outbound links in posts, counted per day by the /l/{code}
+-- redirect. The code itself encodes the post and a hash of the URL, so
+-- only clicks are stored; bots are not counted.
+CREATE TABLE link_clicks (
+  post_id    UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+  url        TEXT NOT NULL,
+  clicked_on DATE NOT NULL DEFAULT CURRENT_DATE,
+  clicks     BIGINT NOT NULL DEFAULT 0,
+  PRIMARY KEY (post_id, url, clicked_on)
+);
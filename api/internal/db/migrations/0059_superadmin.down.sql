@@ -0,0 +1,4 @@
+UPDATE users SET role = 'admin' WHERE role = 'superadmin';
+ALTER TABLE users DROP CONSTRAINT users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check
+  CHECK (role IN ('student', 'teacher', 'moderator', 'admin'));
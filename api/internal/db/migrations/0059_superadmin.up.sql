@@ -0,0 +1,10 @@
+-- 0059_superadmin.sql
+-- Running the deployment (log levels, maintenance, organizations, IP
+-- blocks) moves from organization admins to superadmins, who are only made
+-- with the admin command. Admins of the default organization, which the
+-- deployment started with, become superadmins so nobody is locked out.
+ALTER TABLE users DROP CONSTRAINT users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check
+  CHECK (role IN ('student', 'teacher', 'moderator', 'admin', 'superadmin'));
+UPDATE users SET role = 'superadmin'
+WHERE role = 'admin' AND org_id = '00000000-0000-0000-0000-000000000001';
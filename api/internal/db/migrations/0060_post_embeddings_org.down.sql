@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS post_embeddings_org_id_model_idx;
+ALTER TABLE post_embeddings DROP COLUMN IF EXISTS org_id;
@@ -0,0 +1,8 @@
+-- 0060_post_embeddings_org.sql
+-- Semantic search picks its nearest-neighbour candidates from the searcher's
+-- organization only, so embeddings carry their post's org_id
+ALTER TABLE post_embeddings ADD COLUMN org_id UUID REFERENCES organizations(id);
+UPDATE post_embeddings e SET org_id = p.org_id FROM posts p WHERE p.id = e.post_id;
+ALTER TABLE post_embeddings ALTER COLUMN org_id SET NOT NULL;
+
+CREATE INDEX post_embeddings_org_id_model_idx ON post_embeddings (org_id, model);
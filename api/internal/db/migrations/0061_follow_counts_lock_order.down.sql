@@ -0,0 +1,12 @@
+CREATE OR REPLACE FUNCTION update_follow_counts() RETURNS trigger AS $$
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    UPDATE users SET following_count = following_count + 1 WHERE id = NEW.follower_id;
+    UPDATE users SET followers_count = followers_count + 1 WHERE id = NEW.followee_id;
+  ELSE
+    UPDATE users SET following_count = GREATEST(following_count - 1, 0) WHERE id = OLD.follower_id;
+    UPDATE users SET followers_count = GREATEST(followers_count - 1, 0) WHERE id = OLD.followee_id;
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
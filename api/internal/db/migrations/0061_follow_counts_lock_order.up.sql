@@ -0,0 +1,18 @@
+-- 0061_follow_counts_lock_order.sql
+-- A follows B while B follows A: each trigger updated its follower's row and
+-- then its followee's, taking the two row locks in opposite orders, so one
+-- follow could fail with a deadlock. Both rows are now locked by id first.
+CREATE OR REPLACE FUNCTION update_follow_counts() RETURNS trigger AS $$
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    PERFORM 1 FROM users WHERE id IN (NEW.follower_id, NEW.followee_id) ORDER BY id FOR UPDATE;
+    UPDATE users SET following_count = following_count + 1 WHERE id = NEW.follower_id;
+    UPDATE users SET followers_count = followers_count + 1 WHERE id = NEW.followee_id;
+  ELSE
+    PERFORM 1 FROM users WHERE id IN (OLD.follower_id, OLD.followee_id) ORDER BY id FOR UPDATE;
+    UPDATE users SET following_count = GREATEST(following_count - 1, 0) WHERE id = OLD.follower_id;
+    UPDATE users SET followers_count = GREATEST(followers_count - 1, 0) WHERE id = OLD.followee_id;
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
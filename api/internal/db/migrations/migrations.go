@@ -0,0 +1,230 @@
+// Package migrations embeds the SQL migrations, so the binary runs and
+// checks the schema it was built against
+package migrations
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed *.sql
+var FS embed.FS
+
+// ExpectedIndexes are the indexes the feed, search and notification queries
+// rely on; without them those queries fall back to sequential scans
+var ExpectedIndexes = []string{
+	"posts_text_trgm_idx",
+	"posts_created_at_idx",
+	"posts_author_id_idx",
+	"posts_org_id_created_at_idx",
+	"post_hashtags_hashtag_id_idx",
+	"follows_follower_id_idx",
+	"follows_followee_id_idx",
+	"feed_items_user_created_at_idx",
+	"notifications_user_id_idx",
+	"notifications_unread_idx",
+	"users_username_prefix_idx",
+	"hashtags_tag_prefix_idx",
+}
+
+// RequiredExtensions are the PostgreSQL extensions the migrations create.
+// pgvector (vector) is not part of PostgreSQL itself: use the
+// pgvector/pgvector image or install it on the server.
+var RequiredExtensions = []string{"uuid-ossp", "pg_trgm", "vector"}
+
+type migrationFile struct {
+	version   uint
+	direction string // "up" or "down"
+}
+
+// parseName splits 0001_init.up.sql into its version and direction
+func parseName(name string) (migrationFile, bool) {
+	base, ok := strings.CutSuffix(name, ".sql")
+	if !ok {
+		return migrationFile{}, false
+	}
+	number, rest, ok := strings.Cut(base, "_")
+	if !ok {
+		return migrationFile{}, false
+	}
+	version, err := strconv.ParseUint(number, 10, 64)
+	if err != nil {
+		return migrationFile{}, false
+	}
+	switch {
+	case strings.HasSuffix(rest, ".up"):
+		return migrationFile{version: uint(version), direction: "up"}, true
+	case strings.HasSuffix(rest, ".down"):
+		return migrationFile{version: uint(version), direction: "down"}, true
+	}
+	return migrationFile{}, false
+}
+
+// Lint reports problems with the embedded migrations: badly named files,
+// versions without both an up and a down file, and gaps in the numbering
+func Lint() []string {
+	entries, err := fs.ReadDir(FS, ".")
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	var problems []string
+	files := make(map[uint]map[string]string)
+	for _, entry := range entries {
+		file, ok := parseName(entry.Name())
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: not named NNNN_name.up.sql or NNNN_name.down.sql", entry.Name()))
+			continue
+		}
+		if files[file.version] == nil {
+			files[file.version] = make(map[string]string)
+		}
+		if other, ok := files[file.version][file.direction]; ok {
+			problems = append(problems, fmt.Sprintf("%s: version %d already used by %s", entry.Name(), file.version, other))
+			continue
+		}
+		files[file.version][file.direction] = entry.Name()
+	}
+
+	versions := make([]uint, 0, len(files))
+	for version := range files {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+
+	for i, version := range versions {
+		for _, direction := range []string{"up", "down"} {
+			if _, ok := files[version][direction]; !ok {
+				problems = append(problems, fmt.Sprintf("version %d has no %s migration", version, direction))
+			}
+		}
+		if i > 0 && version != versions[i-1]+1 {
+			problems = append(problems, fmt.Sprintf("versions %d to %d are missing", versions[i-1]+1, version-1))
+		}
+	}
+	return problems
+}
+
+// Latest returns the highest embedded migration version
+func Latest() (uint, error) {
+	entries, err := fs.ReadDir(FS, ".")
+	if err != nil {
+		return 0, err
+	}
+	var latest uint
+	for _, entry := range entries {
+		if file, ok := parseName(entry.Name()); ok && file.direction == "up" {
+			latest = max(latest, file.version)
+		}
+	}
+	if latest == 0 {
+		return 0, fmt.Errorf("no migrations embedded")
+	}
+	return latest, nil
+}
+
+// MissingExtensions lists the RequiredExtensions the server can't provide:
+// neither installed in the database nor available to CREATE EXTENSION
+func MissingExtensions(ctx context.Context, db *pgxpool.Pool) ([]string, error) {
+	rows, err := db.Query(ctx, `
+		SELECT r.name FROM unnest($1::text[]) AS r(name)
+		WHERE NOT EXISTS (SELECT 1 FROM pg_available_extensions a WHERE a.name = r.name)
+		  AND NOT EXISTS (SELECT 1 FROM pg_extension e WHERE e.extname = r.name)`, RequiredExtensions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check extensions: %w", err)
+	}
+	defer rows.Close()
+
+	var missing []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to check extensions: %w", err)
+		}
+		missing = append(missing, name)
+	}
+	return missing, rows.Err()
+}
+
+// Status compares a database's schema with the embedded migrations
+type Status struct {
+	Applied        uint // 0 when no migration has run
+	Dirty          bool
+	Latest         uint
+	MissingIndexes []string
+}
+
+// Inspect reads the version golang-migrate recorded and, with checkIndexes,
+// looks for ExpectedIndexes
+func Inspect(ctx context.Context, db *pgxpool.Pool, checkIndexes bool) (*Status, error) {
+	latest, err := Latest()
+	if err != nil {
+		return nil, err
+	}
+	status := &Status{Latest: latest}
+
+	var exists bool
+	err = db.QueryRow(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check schema_migrations: %w", err)
+	}
+	if exists {
+		var version int64
+		err = db.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &status.Dirty)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("failed to read migration version: %w", err)
+		}
+		status.Applied = uint(max(version, 0))
+	}
+
+	if checkIndexes {
+		rows, err := db.Query(ctx, `
+			SELECT name FROM unnest($1::text[]) AS name
+			WHERE to_regclass(name) IS NULL`, ExpectedIndexes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check indexes: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				return nil, fmt.Errorf("failed to check indexes: %w", err)
+			}
+			status.MissingIndexes = append(status.MissingIndexes, name)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to check indexes: %w", err)
+		}
+	}
+
+	return status, nil
+}
+
+// Problems describes how the schema differs from what this build expects
+func (s *Status) Problems() []string {
+	var problems []string
+	switch {
+	case s.Applied == 0:
+		problems = append(problems, "no migrations have been applied")
+	case s.Applied < s.Latest:
+		problems = append(problems, fmt.Sprintf("database is at migration %d, this build expects %d", s.Applied, s.Latest))
+	case s.Applied > s.Latest:
+		problems = append(problems, fmt.Sprintf("database is at migration %d, newer than this build's %d", s.Applied, s.Latest))
+	}
+	if s.Dirty {
+		problems = append(problems, fmt.Sprintf("migration %d failed part way (dirty); fix the schema and force the version", s.Applied))
+	}
+	for _, index := range s.MissingIndexes {
+		problems = append(problems, fmt.Sprintf("index %s is missing", index))
+	}
+	return problems
+}
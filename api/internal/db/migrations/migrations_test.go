@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLint(t *testing.T) {
+	assert.Empty(t, Lint())
+}
+
+func TestLatest(t *testing.T) {
+	latest, err := Latest()
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, latest, uint(35))
+}
+
+func TestStatusProblems(t *testing.T) {
+	tests := []struct {
+		name   string
+		status Status
+		want   []string
+	}{
+		{"current", Status{Applied: 35, Latest: 35}, nil},
+		{"behind", Status{Applied: 33, Latest: 35}, []string{"database is at migration 33, this build expects 35"}},
+		{"ahead", Status{Applied: 36, Latest: 35}, []string{"database is at migration 36, newer than this build's 35"}},
+		{"dirty with missing index", Status{Applied: 35, Latest: 35, Dirty: true, MissingIndexes: []string{"posts_text_trgm_idx"}}, []string{
+			"migration 35 failed part way (dirty); fix the schema and force the version",
+			"index posts_text_trgm_idx is missing",
+		}},
+		{"empty database", Status{Latest: 35}, []string{"no migrations have been applied"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.status.Problems())
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	"bailanysta/api/internal/pkg/dataloader"
+	"bailanysta/api/internal/services"
+)
+
+const (
+	loaderWait     = 2 * time.Millisecond
+	loaderMaxBatch = 100
+
+	maxPageSize = 100
+	maxComments = 20
+)
+
+type loadersKey struct{}
+
+// loaders batch the lookups made while resolving one request for its viewer
+type loaders struct {
+	viewerID uuid.UUID
+	users    *dataloader.Loader[uuid.UUID, *services.UserResponse]
+	comments *dataloader.Loader[commentsKey, []*services.Comment]
+}
+
+type commentsKey struct {
+	postID uuid.UUID
+	first  int
+}
+
+// WithLoaders returns ctx with fresh loaders for viewerID; every request
+// needs its own, as loaded values are cached
+func (r *Resolver) WithLoaders(ctx context.Context, viewerID uuid.UUID) context.Context {
+	l := &loaders{viewerID: viewerID}
+	l.users = dataloader.New(func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]*services.UserResponse, error) {
+		return r.authService.GetUsersByIDs(ctx, viewerID, ids)
+	}, loaderWait, loaderMaxBatch)
+	l.comments = dataloader.New(func(ctx context.Context, keys []commentsKey) (map[commentsKey][]*services.Comment, error) {
+		// Posts asking for the same number of comments share a query
+		postIDs := make(map[int][]uuid.UUID)
+		for _, key := range keys {
+			postIDs[key.first] = append(postIDs[key.first], key.postID)
+		}
+
+		comments := make(map[commentsKey][]*services.Comment, len(keys))
+		for first, ids := range postIDs {
+			byPost, err := r.postsService.GetFirstComments(ctx, ids, viewerID, first)
+			if err != nil {
+				return nil, err
+			}
+			for _, id := range ids {
+				comments[commentsKey{postID: id, first: first}] = byPost[id]
+			}
+		}
+		return comments, nil
+	}, loaderWait, loaderMaxBatch)
+
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFor(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+func viewerID(ctx context.Context) uuid.UUID {
+	return loadersFor(ctx).viewerID
+}
+
+// loadUser returns the user with id, or an error if there is none
+func loadUser(ctx context.Context, id uuid.UUID) (*services.UserResponse, error) {
+	user, err := loadersFor(ctx).users.Load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user %s not found", id)
+	}
+	return user, nil
+}
+
+// errUserArgs is built per call as the presenter sets the error's path
+func errUserArgs() error {
+	return gqlerror.Errorf("id or username is required")
+}
+
+// page validates limit and offset arguments
+func page(limit, offset *int) (int, int, error) {
+	if *limit < 1 || *limit > maxPageSize {
+		return 0, 0, gqlerror.Errorf("limit must be between 1 and %d", maxPageSize)
+	}
+	if *offset < 0 {
+		return 0, 0, gqlerror.Errorf("offset must not be negative")
+	}
+	return *limit, *offset, nil
+}
+
+// commentsLimit validates the comments(first) argument
+func commentsLimit(first *int) (int, error) {
+	if *first < 0 || *first > maxComments {
+		return 0, gqlerror.Errorf("first must be between 0 and %d", maxComments)
+	}
+	return *first, nil
+}
+
+// isNoRows reports whether err means there is nothing the viewer may see,
+// which resolves to null rather than an error
+func isNoRows(err error) bool {
+	return errors.Is(err, pgx.ErrNoRows) || errors.Is(err, services.ErrPostNotFound)
+}
+
+// feedPost converts a feed entry to the Post type the schema exposes
+func feedPost(post *services.FeedPost) *services.Post {
+	return &services.Post{
+		ID:            post.ID,
+		AuthorID:      post.AuthorID,
+		Text:          post.Text,
+		TextHTML:      post.TextHTML,
+		CourseID:      post.CourseID,
+		ModuleID:      post.ModuleID,
+		IsAIGenerated: post.IsAIGenerated,
+		Edited:        post.Edited,
+		Slug:          post.Slug,
+		CreatedAt:     post.CreatedAt,
+		UpdatedAt:     post.UpdatedAt,
+		LikeCount:     post.LikeCount,
+		CommentCount:  post.CommentCount,
+		Author:        post.Author,
+		IsLiked:       post.IsLiked,
+		Poll:          post.Poll,
+		LinkPreviews:  post.LinkPreviews,
+		Question:      post.Question,
+		GIF:           post.GIF,
+	}
+}
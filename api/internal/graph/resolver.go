@@ -0,0 +1,25 @@
+package graph
+
+//go:generate go run github.com/99designs/gqlgen generate
+
+import (
+	"bailanysta/api/internal/services"
+)
+
+// Resolver answers GraphQL queries through the same services as the REST
+// handlers
+type Resolver struct {
+	authService          *services.AuthService
+	postsService         *services.PostsService
+	socialService        *services.SocialService
+	notificationsService *services.NotificationService
+}
+
+func NewResolver(authService *services.AuthService, postsService *services.PostsService, socialService *services.SocialService, notificationsService *services.NotificationService) *Resolver {
+	return &Resolver{
+		authService:          authService,
+		postsService:         postsService,
+		socialService:        socialService,
+		notificationsService: notificationsService,
+	}
+}
@@ -0,0 +1,136 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.78
+
+import (
+	"bailanysta/api/internal/services"
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Author is the resolver for the author field.
+func (r *commentResolver) Author(ctx context.Context, obj *services.Comment) (*services.UserResponse, error) {
+	return loadUser(ctx, obj.AuthorID)
+}
+
+// Type is the resolver for the type field.
+func (r *notificationResolver) Type(ctx context.Context, obj *services.Notification) (string, error) {
+	return string(obj.Type), nil
+}
+
+// Read is the resolver for the read field.
+func (r *notificationResolver) Read(ctx context.Context, obj *services.Notification) (bool, error) {
+	return obj.ReadAt != nil, nil
+}
+
+// Author is the resolver for the author field.
+func (r *postResolver) Author(ctx context.Context, obj *services.Post) (*services.UserResponse, error) {
+	return loadUser(ctx, obj.AuthorID)
+}
+
+// Comments is the resolver for the comments field.
+func (r *postResolver) Comments(ctx context.Context, obj *services.Post, first *int) ([]*services.Comment, error) {
+	n, err := commentsLimit(first)
+	if err != nil || n == 0 || obj.CommentCount == 0 {
+		return []*services.Comment{}, err
+	}
+	return loadersFor(ctx).comments.Load(ctx, commentsKey{postID: obj.ID, first: n})
+}
+
+// Me is the resolver for the me field.
+func (r *queryResolver) Me(ctx context.Context) (*services.UserResponse, error) {
+	return r.authService.GetCurrentUser(ctx, viewerID(ctx))
+}
+
+// User is the resolver for the user field.
+func (r *queryResolver) User(ctx context.Context, id *uuid.UUID, username *string) (*services.UserResponse, error) {
+	switch {
+	case id != nil:
+		return loadersFor(ctx).users.Load(ctx, *id)
+	case username != nil:
+		// Former usernames resolve too, as in GET /users/by-username/{username}
+		userID, _, err := r.authService.ResolveUsername(ctx, *username)
+		if err != nil {
+			if err.Error() == "user not found" {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return loadersFor(ctx).users.Load(ctx, userID)
+	default:
+		return nil, errUserArgs()
+	}
+}
+
+// Post is the resolver for the post field.
+func (r *queryResolver) Post(ctx context.Context, id uuid.UUID) (*services.Post, error) {
+	post, err := r.postsService.GetPostByID(ctx, id, viewerID(ctx))
+	if isNoRows(err) {
+		return nil, nil
+	}
+	return post, err
+}
+
+// Feed is the resolver for the feed field.
+func (r *queryResolver) Feed(ctx context.Context, limit *int, offset *int) ([]*services.Post, error) {
+	n, skip, err := page(limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	feed, err := r.socialService.GetFeed(ctx, viewerID(ctx), services.FeedFilterAll, n, skip)
+	if err != nil {
+		return nil, err
+	}
+	posts := make([]*services.Post, len(feed))
+	for i, post := range feed {
+		posts[i] = feedPost(post)
+	}
+	return posts, nil
+}
+
+// Notifications is the resolver for the notifications field.
+func (r *queryResolver) Notifications(ctx context.Context, limit *int, offset *int) ([]*services.Notification, error) {
+	n, skip, err := page(limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return r.notificationsService.GetUserNotifications(ctx, viewerID(ctx), n, skip)
+}
+
+// UnreadNotificationCount is the resolver for the unreadNotificationCount field.
+func (r *queryResolver) UnreadNotificationCount(ctx context.Context) (int, error) {
+	return r.notificationsService.GetUnreadCount(ctx, viewerID(ctx))
+}
+
+// Email is the resolver for the email field.
+func (r *userResolver) Email(ctx context.Context, obj *services.UserResponse) (*string, error) {
+	if obj.ID != viewerID(ctx) {
+		return nil, nil
+	}
+	return &obj.Email, nil
+}
+
+// Comment returns CommentResolver implementation.
+func (r *Resolver) Comment() CommentResolver { return &commentResolver{r} }
+
+// Notification returns NotificationResolver implementation.
+func (r *Resolver) Notification() NotificationResolver { return &notificationResolver{r} }
+
+// Post returns PostResolver implementation.
+func (r *Resolver) Post() PostResolver { return &postResolver{r} }
+
+// Query returns QueryResolver implementation.
+func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
+
+// User returns UserResolver implementation.
+func (r *Resolver) User() UserResolver { return &userResolver{r} }
+
+type commentResolver struct{ *Resolver }
+type notificationResolver struct{ *Resolver }
+type postResolver struct{ *Resolver }
+type queryResolver struct{ *Resolver }
+type userResolver struct{ *Resolver }
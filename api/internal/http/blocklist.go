@@ -0,0 +1,40 @@
+package http
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+
+	"bailanysta/api/internal/services"
+)
+
+// ipBlocklistMiddleware refuses requests from blocked networks before any
+// authentication. Health checks stay reachable.
+func ipBlocklistMiddleware(blocklist *services.IPBlocklistService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/health" || !blocklist.Blocked(clientIP(r)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error": map[string]interface{}{
+					"code":    "IP_BLOCKED",
+					"message": "Access from your network is blocked",
+				},
+			})
+		})
+	}
+}
+
+// clientIP is r's address as set by realIPMiddleware, without a port
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
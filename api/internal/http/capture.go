@@ -0,0 +1,121 @@
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"bailanysta/api/internal/pkg/auth"
+)
+
+// CaptureHeader asks for the bodies of a request to be logged; only admins
+// may set it
+const CaptureHeader = "X-Debug-Capture"
+
+// bodyCapture decides which requests have their bodies logged: those under
+// one of routes, and those of admins sending CaptureHeader
+type bodyCapture struct {
+	routes     []string
+	maxBytes   int
+	jwtManager *auth.JWTManager
+}
+
+func newBodyCapture(routes []string, maxBytes int, jwtManager *auth.JWTManager) *bodyCapture {
+	var prefixes []string
+	for _, route := range routes {
+		if route = strings.TrimSpace(route); route != "" {
+			prefixes = append(prefixes, route)
+		}
+	}
+	return &bodyCapture{routes: prefixes, maxBytes: maxBytes, jwtManager: jwtManager}
+}
+
+func (c *bodyCapture) enabled(r *http.Request) bool {
+	if c.maxBytes <= 0 {
+		return false
+	}
+	for _, prefix := range c.routes {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			return true
+		}
+	}
+	if r.Header.Get(CaptureHeader) == "" {
+		return false
+	}
+	return isAdminRequest(r, c.jwtManager)
+}
+
+// captureRequest reads up to maxBytes of the request body, leaving the body
+// intact for the handler
+func (c *bodyCapture) captureRequest(r *http.Request) []byte {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+	head, _ := io.ReadAll(io.LimitReader(r.Body, int64(c.maxBytes)+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), r.Body), r.Body}
+	return head
+}
+
+// captureWriter copies up to limit bytes of the response as it is written
+type captureWriter struct {
+	*responseWriter
+	body  bytes.Buffer
+	limit int
+}
+
+func (cw *captureWriter) Write(p []byte) (int, error) {
+	if room := cw.limit + 1 - cw.body.Len(); room > 0 {
+		cw.body.Write(p[:min(len(p), room)])
+	}
+	return cw.responseWriter.Write(p)
+}
+
+var (
+	// Values are strings, arrays of strings (recovery codes) or numbers
+	// (one-time codes), possibly cut off by truncation
+	jsonSecretPattern = regexp.MustCompile(`(?i)("(?:[a-z]+_)?(?:password|token|secret|key|codes?)"\s*:\s*)` +
+		`(?:"(?:[^"\\]|\\.)*"?|\[(?:[^\]"]|"(?:[^"\\]|\\.)*"?)*\]?|-?\d+)`)
+	formSecretPattern = regexp.MustCompile(`(?i)((?:^|&)(?:[a-z]+_)?(?:password|token|secret|key|codes?)=)[^&]*`)
+)
+
+// sanitizeBody renders a captured body for the log: JSON and form values of
+// passwords, tokens, secrets, keys and codes are redacted, other media types
+// are only described, and bodies over maxBytes are cut off
+func sanitizeBody(body []byte, contentType, contentEncoding string, maxBytes int) string {
+	if len(body) == 0 {
+		return ""
+	}
+	if contentEncoding != "" && contentEncoding != "identity" {
+		return fmt.Sprintf("[%s-encoded body omitted]", contentEncoding)
+	}
+
+	truncated := len(body) > maxBytes
+	if truncated {
+		body = body[:maxBytes]
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	var text string
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") || mediaType == "application/x-ndjson":
+		text = jsonSecretPattern.ReplaceAllString(string(body), `$1"[REDACTED]"`)
+	case mediaType == "application/x-www-form-urlencoded":
+		text = formSecretPattern.ReplaceAllString(string(body), "$1[REDACTED]")
+	case strings.HasPrefix(mediaType, "text/"):
+		text = string(body)
+	default:
+		return fmt.Sprintf("[%s body omitted]", mediaType)
+	}
+
+	if truncated {
+		text += "…[truncated]"
+	}
+	return text
+}
@@ -0,0 +1,92 @@
+package http
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		encoding    string
+		maxBytes    int
+		want        string
+	}{
+		{
+			name:        "json secrets",
+			body:        `{"email":"a@b.kz","password":"hunter2","tokens":{"access_token":"eyJ\"x","refresh_token":"abc"}}`,
+			contentType: "application/json",
+			want:        `{"email":"a@b.kz","password":"[REDACTED]","tokens":{"access_token":"[REDACTED]","refresh_token":"[REDACTED]"}}`,
+		},
+		{
+			name:        "api key",
+			body:        `{"id":"1","key":"bly_secret","name":"bot"}`,
+			contentType: "application/json; charset=utf-8",
+			want:        `{"id":"1","key":"[REDACTED]","name":"bot"}`,
+		},
+		{
+			name:        "recovery codes",
+			body:        `{"codes":["a1b2-c3d4","e5f6-\"]7h8"],"count":2}`,
+			contentType: "application/json",
+			want:        `{"codes":"[REDACTED]","count":2}`,
+		},
+		{
+			name:        "one-time codes",
+			body:        `{"code": "123456","backup_code":"x-y","totp_code":654321}`,
+			contentType: "application/json",
+			want:        `{"code": "[REDACTED]","backup_code":"[REDACTED]","totp_code":"[REDACTED]"}`,
+		},
+		{
+			name:        "truncated inside codes",
+			body:        `{"codes":["a1b2-c3d4","e5f6-g7h8"]}`,
+			contentType: "application/json",
+			maxBytes:    20,
+			want:        `{"codes":"[REDACTED]"…[truncated]`,
+		},
+		{
+			name:        "form code",
+			body:        "code=123456&remember=1",
+			contentType: "application/x-www-form-urlencoded",
+			want:        "code=[REDACTED]&remember=1",
+		},
+		{
+			name:        "form",
+			body:        "username=a&new_password=x&remember=1",
+			contentType: "application/x-www-form-urlencoded",
+			want:        "username=a&new_password=[REDACTED]&remember=1",
+		},
+		{
+			name:        "truncated inside a secret",
+			body:        `{"password":"hunter2hunter2"}`,
+			contentType: "application/json",
+			maxBytes:    20,
+			want:        `{"password":"[REDACTED]"…[truncated]`,
+		},
+		{
+			name:        "binary",
+			body:        "\x89PNG",
+			contentType: "image/png",
+			want:        "[image/png body omitted]",
+		},
+		{
+			name:        "compressed",
+			body:        "\x1f\x8b",
+			contentType: "application/json",
+			encoding:    "gzip",
+			want:        "[gzip-encoded body omitted]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			maxBytes := tt.maxBytes
+			if maxBytes == 0 {
+				maxBytes = 1024
+			}
+			assert.Equal(t, tt.want, sanitizeBody([]byte(tt.body), tt.contentType, tt.encoding, maxBytes))
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package http
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	gzipWriters = sync.Pool{New: func() interface{} {
+		w, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return w
+	}}
+	flateWriters = sync.Pool{New: func() interface{} {
+		w, _ := flate.NewWriter(nil, flate.DefaultCompression)
+		return w
+	}}
+)
+
+// compressMiddleware compresses responses with gzip or deflate when the
+// client accepts it, the content type is in types and the body is at least
+// minSize bytes. Responses that are already encoded and event streams are
+// passed through unchanged.
+func compressMiddleware(minSize int, types []string) func(http.Handler) http.Handler {
+	allowed := make(map[string]bool, len(types))
+	for _, t := range types {
+		allowed[strings.ToLower(strings.TrimSpace(t))] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+			if encoding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{
+				ResponseWriter: w,
+				encoding:       encoding,
+				minSize:        minSize,
+				allowed:        allowed,
+				statusCode:     http.StatusOK,
+			}
+			defer cw.Close()
+
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// negotiateEncoding picks gzip or deflate from an Accept-Encoding header,
+// preferring gzip on equal quality. It returns "" if neither is acceptable.
+func negotiateEncoding(header string) string {
+	best, bestQ := "", 0.0
+	wildcardQ := -1.0
+	qualities := map[string]float64{}
+
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		if name == "*" {
+			wildcardQ = q
+		} else {
+			qualities[name] = q
+		}
+	}
+
+	for _, name := range []string{"gzip", "deflate"} {
+		q, ok := qualities[name]
+		if !ok {
+			q = wildcardQ
+		}
+		if q > bestQ {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+// compressWriter buffers output until it knows whether the response is
+// worth compressing: the content type must be allowed and the body must
+// reach minSize before the handler finishes or flushes.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+	allowed  map[string]bool
+
+	statusCode  int
+	wroteHeader bool
+	decided     bool
+	buf         []byte
+	compressor  io.WriteCloser
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if code < 200 {
+		// Informational responses such as 103 Early Hints precede the real one
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+	cw.statusCode = code
+
+	// Bodiless responses are never compressed
+	if code == http.StatusNoContent || code == http.StatusNotModified {
+		cw.decide(false)
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+
+	if !cw.decided {
+		if !cw.eligible() {
+			cw.decide(false)
+		} else {
+			cw.buf = append(cw.buf, p...)
+			if len(cw.buf) >= cw.minSize {
+				cw.decide(true)
+			}
+			return len(p), nil
+		}
+	}
+
+	if cw.compressor != nil {
+		return cw.compressor.Write(p)
+	}
+	return cw.ResponseWriter.Write(p)
+}
+
+// Flush sends what has been written so far; a response still below
+// minSize is sent uncompressed
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		cw.decide(false)
+	}
+	if f, ok := cw.compressor.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// Close finishes the response, sending a short body uncompressed
+func (cw *compressWriter) Close() error {
+	if !cw.decided {
+		if !cw.wroteHeader && len(cw.buf) == 0 {
+			// Nothing was written; let net/http send its default response
+			return nil
+		}
+		cw.decide(false)
+	}
+
+	if cw.compressor != nil {
+		err := cw.compressor.Close()
+		switch c := cw.compressor.(type) {
+		case *gzip.Writer:
+			gzipWriters.Put(c)
+		case *flate.Writer:
+			flateWriters.Put(c)
+		}
+		cw.compressor = nil
+		return err
+	}
+	return nil
+}
+
+// eligible reports whether the response may be compressed based on its headers
+func (cw *compressWriter) eligible() bool {
+	header := cw.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType == "text/event-stream" {
+		return false
+	}
+	return cw.allowed[mediaType]
+}
+
+// decide writes the status line and any buffered body, compressing if asked
+func (cw *compressWriter) decide(compress bool) {
+	cw.decided = true
+	header := cw.Header()
+
+	if cw.eligible() {
+		header.Add("Vary", "Accept-Encoding")
+	}
+
+	if compress {
+		header.Set("Content-Encoding", cw.encoding)
+		header.Del("Content-Length")
+
+		switch cw.encoding {
+		case "gzip":
+			gz := gzipWriters.Get().(*gzip.Writer)
+			gz.Reset(cw.ResponseWriter)
+			cw.compressor = gz
+		case "deflate":
+			fl := flateWriters.Get().(*flate.Writer)
+			fl.Reset(cw.ResponseWriter)
+			cw.compressor = fl
+		}
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.statusCode)
+
+	if len(cw.buf) > 0 {
+		if cw.compressor != nil {
+			cw.compressor.Write(cw.buf)
+		} else {
+			cw.ResponseWriter.Write(cw.buf)
+		}
+		cw.buf = nil
+	}
+}
@@ -0,0 +1,34 @@
+package http
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{"empty", "", ""},
+		{"gzip", "gzip", "gzip"},
+		{"deflate only", "deflate", "deflate"},
+		{"prefers gzip on tie", "deflate, gzip", "gzip"},
+		{"browser header", "gzip, deflate, br, zstd", "gzip"},
+		{"higher quality wins", "gzip;q=0.5, deflate;q=0.8", "deflate"},
+		{"gzip refused", "gzip;q=0, deflate", "deflate"},
+		{"wildcard", "*", "gzip"},
+		{"wildcard with explicit refusal", "*, gzip;q=0", "deflate"},
+		{"identity only", "identity", ""},
+		{"unsupported only", "br", ""},
+		{"case insensitive", "GZIP", "gzip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, negotiateEncoding(tt.header))
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"bailanysta/api/internal/http/handlers"
+)
+
+func TestValidCSRF(t *testing.T) {
+	tests := []struct {
+		name    string
+		refresh bool
+		cookie  string
+		header  string
+		want    bool
+	}{
+		{"no refresh cookie", false, "", "", true},
+		{"matching token", true, "abc123", "abc123", true},
+		{"missing header", true, "abc123", "", false},
+		{"wrong header", true, "abc123", "abc124", false},
+		{"missing csrf cookie", true, "", "abc123", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/auth/refresh", nil)
+			if tt.refresh {
+				r.AddCookie(&http.Cookie{Name: handlers.RefreshCookieName, Value: "token"})
+			}
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: handlers.CSRFCookieName, Value: tt.cookie})
+			}
+			if tt.header != "" {
+				r.Header.Set(handlers.CSRFHeader, tt.header)
+			}
+			assert.Equal(t, tt.want, validCSRF(r))
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package http
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"bailanysta/api/internal/pkg/database"
+)
+
+var publishOnce sync.Once
+
+// publishRuntimeStats adds goroutine, pool and worker stats to the expvar
+// variables, next to the memstats and cmdline expvar publishes itself
+func publishRuntimeStats(db *database.Pool, workers func() []string) {
+	publishOnce.Do(func() {
+		started := time.Now()
+		expvar.Publish("goroutines", expvar.Func(func() interface{} { return runtime.NumGoroutine() }))
+		expvar.Publish("uptime_seconds", expvar.Func(func() interface{} { return int64(time.Since(started).Seconds()) }))
+		expvar.Publish("go_version", expvar.Func(func() interface{} { return runtime.Version() }))
+		expvar.Publish("db_pools", expvar.Func(func() interface{} { return db.Stats() }))
+		expvar.Publish("workers", expvar.Func(func() interface{} { return workers() }))
+	})
+}
+
+// NewDiagnosticsHandler serves pprof under /debug/pprof/ and expvar runtime
+// stats on /debug/vars. It is meant for its own listener (DIAGNOSTICS_ADDR),
+// away from the public API; with token set it requires it as a bearer token.
+func NewDiagnosticsHandler(token string, db *database.Pool, workers func() []string) http.Handler {
+	publishRuntimeStats(db, workers)
+
+	r := chi.NewRouter()
+	r.Use(metricsAuthMiddleware(token))
+
+	r.Get("/debug/vars", expvar.Handler().ServeHTTP)
+	r.Get("/debug/pprof/cmdline", pprof.Cmdline)
+	r.Get("/debug/pprof/profile", pprof.Profile)
+	r.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	r.Get("/debug/pprof/trace", pprof.Trace)
+	// Index also serves the named profiles: heap, goroutine, block, mutex...
+	r.Get("/debug/pprof/*", pprof.Index)
+	return r
+}
@@ -0,0 +1,60 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bailanysta/api/internal/pkg/database"
+)
+
+func TestDiagnosticsHandler(t *testing.T) {
+	// pgxpool connects lazily, so stats are readable without a database
+	primary, err := pgxpool.New(context.Background(), "postgres://localhost:1/none")
+	require.NoError(t, err)
+	defer primary.Close()
+
+	handler := NewDiagnosticsHandler("secret", database.New(primary, nil, nil), func() []string {
+		return []string{"push-dispatcher"}
+	})
+
+	tests := []struct {
+		name  string
+		path  string
+		token string
+		want  int
+	}{
+		{"vars without token", "/debug/vars", "", http.StatusUnauthorized},
+		{"vars", "/debug/vars", "secret", http.StatusOK},
+		{"pprof without token", "/debug/pprof/", "wrong", http.StatusUnauthorized},
+		{"pprof index", "/debug/pprof/", "secret", http.StatusOK},
+		{"heap profile", "/debug/pprof/heap", "secret", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, tt.want, rec.Code)
+
+			if tt.path == "/debug/vars" && tt.want == http.StatusOK {
+				var vars map[string]json.RawMessage
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &vars))
+				for _, name := range []string{"goroutines", "memstats", "db_pools", "workers"} {
+					assert.Contains(t, vars, name)
+				}
+				assert.JSONEq(t, `["push-dispatcher"]`, string(vars["workers"]))
+			}
+		})
+	}
+}
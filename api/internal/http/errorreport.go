@@ -0,0 +1,109 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+
+	"bailanysta/api/internal/pkg/auth"
+	"bailanysta/api/internal/pkg/sentry"
+)
+
+// reportedBodyBytes is how much of a 5xx response is kept for the report
+const reportedBodyBytes = 1024
+
+// errorReportingMiddleware sends panics and 5xx responses to the error
+// reporter. It sits inside Recoverer and re-panics, so the client still gets
+// a 500 and the panic is still logged. 503s are left out: they are
+// maintenance and unavailable dependencies, which have their own alerts.
+func errorReportingMiddleware(reporter *sentry.Client, jwtManager *auth.JWTManager) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := &reportWriter{responseWriter: &responseWriter{ResponseWriter: w, statusCode: 200}}
+
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					if recovered != http.ErrAbortHandler {
+						event := sentry.NewPanicEvent(recovered)
+						reporter.Capture(describeRequest(event, r, http.StatusInternalServerError, jwtManager))
+					}
+					panic(recovered)
+				}
+			}()
+
+			next.ServeHTTP(rw, r)
+
+			if rw.statusCode >= 500 && rw.statusCode != http.StatusServiceUnavailable {
+				event := &sentry.Event{Level: "error", Message: responseError(rw.body.Bytes())}
+				reporter.Capture(describeRequest(event, r, rw.statusCode, jwtManager))
+			}
+		})
+	}
+}
+
+// describeRequest attaches the request, route, request ID and user to event
+func describeRequest(event *sentry.Event, r *http.Request, status int, jwtManager *auth.JWTManager) *sentry.Event {
+	route := r.URL.Path
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+		route = rctx.RoutePattern()
+	}
+	if event.Exception == nil {
+		event.Message = fmt.Sprintf("%s %s returned %d: %s", r.Method, route, status, event.Message)
+	}
+
+	event.Transaction = r.Method + " " + route
+	event.Request = sentry.NewRequest(r)
+	event.Tags = map[string]string{
+		"method":     r.Method,
+		"route":      route,
+		"status":     strconv.Itoa(status),
+		"request_id": middleware.GetReqID(r.Context()),
+	}
+	event.User = &sentry.User{IPAddress: r.RemoteAddr}
+
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		if claims, err := jwtManager.ValidateAccessToken(token); err == nil {
+			event.User.ID = claims.UserID.String()
+			event.Tags["org_id"] = claims.OrgID.String()
+			if claims.ImpersonatorID != nil {
+				event.Tags["impersonator_id"] = claims.ImpersonatorID.String()
+			}
+		}
+	}
+	return event
+}
+
+// responseError pulls the message out of an error envelope, falling back to
+// the body itself
+func responseError(body []byte) string {
+	var envelope struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &envelope) == nil && envelope.Error.Message != "" {
+		return envelope.Error.Message
+	}
+	return strings.TrimSpace(string(body))
+}
+
+// reportWriter keeps the start of 5xx responses for the report
+type reportWriter struct {
+	*responseWriter
+	body bytes.Buffer
+}
+
+func (rw *reportWriter) Write(p []byte) (int, error) {
+	if rw.statusCode >= 500 {
+		if room := reportedBodyBytes - rw.body.Len(); room > 0 {
+			rw.body.Write(p[:min(len(p), room)])
+		}
+	}
+	return rw.responseWriter.Write(p)
+}
@@ -0,0 +1,126 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bailanysta/api/internal/pkg/auth"
+	"bailanysta/api/internal/pkg/sentry"
+)
+
+func TestErrorReportingMiddleware(t *testing.T) {
+	events := make(chan sentry.Event, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/42/envelope/", r.URL.Path)
+		assert.Contains(t, r.Header.Get("X-Sentry-Auth"), "sentry_key=public")
+
+		// envelope header, item header, event
+		scanner := bufio.NewScanner(r.Body)
+		scanner.Buffer(nil, 1<<20)
+		var lines []string
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		require.Len(t, lines, 3)
+		var event sentry.Event
+		require.NoError(t, json.Unmarshal([]byte(lines[2]), &event))
+		events <- event
+	}))
+	defer server.Close()
+
+	reporter, err := sentry.New(sentry.Config{
+		DSN:         strings.Replace(server.URL, "http://", "http://public@", 1) + "/42",
+		Environment: "test",
+		Release:     "abc123",
+	})
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go reporter.Run(ctx)
+
+	jwtManager := auth.NewJWTManager("test-secret", 15*time.Minute, time.Hour)
+	userID := uuid.New()
+	tokens, err := jwtManager.GenerateTokenPair(userID, uuid.New(), "user", auth.SessionScopes)
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	r.Use(middleware.Recoverer)
+	r.Use(errorReportingMiddleware(reporter, jwtManager))
+	r.Get("/posts/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":{"code":"INTERNAL_ERROR","message":"Failed to get post"}}`))
+	})
+	r.Get("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	r.Get("/unavailable", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	r.Get("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name    string
+		path    string
+		status  int
+		message string // empty when nothing should be reported
+		panic   string
+	}{
+		{"ok", "/ok", http.StatusOK, "", ""},
+		{"unavailable", "/unavailable", http.StatusServiceUnavailable, "", ""},
+		{"handler error", "/posts/1?token=secret", http.StatusInternalServerError, "GET /posts/{id} returned 500: Failed to get post", ""},
+		{"panic", "/panic", http.StatusInternalServerError, "", "boom"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			assert.Equal(t, tt.status, rec.Code)
+
+			if tt.message == "" && tt.panic == "" {
+				select {
+				case event := <-events:
+					t.Fatalf("unexpected report: %+v", event)
+				case <-time.After(50 * time.Millisecond):
+				}
+				return
+			}
+
+			select {
+			case event := <-events:
+				assert.Equal(t, tt.message, event.Message)
+				assert.Equal(t, "abc123", event.Release)
+				assert.Equal(t, "test", event.Environment)
+				require.NotNil(t, event.User)
+				assert.Equal(t, userID.String(), event.User.ID)
+				require.NotNil(t, event.Request)
+				assert.NotContains(t, event.Request.Headers, "Authorization")
+				assert.NotContains(t, event.Request.QueryString, "secret")
+				if tt.panic != "" {
+					require.NotNil(t, event.Exception)
+					assert.Equal(t, tt.panic, event.Exception.Values[0].Value)
+					assert.NotEmpty(t, event.Exception.Values[0].Stacktrace.Frames)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("no report sent")
+			}
+		})
+	}
+}
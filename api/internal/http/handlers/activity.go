@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"bailanysta/api/internal/pkg/auth"
+	"bailanysta/api/internal/pkg/logger"
+	"bailanysta/api/internal/services"
+)
+
+type ActivityHandler struct {
+	activityService *services.ActivityService
+	logger          *logger.Logger
+	jwtManager      *auth.JWTManager
+}
+
+func NewActivityHandler(activityService *services.ActivityService, logger *logger.Logger, jwtManager *auth.JWTManager) *ActivityHandler {
+	return &ActivityHandler{
+		activityService: activityService,
+		logger:          logger,
+		jwtManager:      jwtManager,
+	}
+}
+
+// GetMyActivity returns the caller's contribution calendar for the last year
+// with their current and longest streaks
+func (h *ActivityHandler) GetMyActivity(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	summary, err := h.activityService.GetActivity(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("Failed to get activity", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		h.respondWithError(w, "Failed to get activity", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, summary, http.StatusOK)
+}
+
+func (h *ActivityHandler) respondWithJSON(w http.ResponseWriter, data interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *ActivityHandler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	h.respondWithJSON(w, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    getErrorCode(statusCode),
+			"message": message,
+		},
+	}, statusCode)
+}
+
+func (h *ActivityHandler) getUserIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	return h.jwtManager.GetUserIDFromContext(ctx)
+}
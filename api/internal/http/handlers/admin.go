@@ -0,0 +1,602 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+
+	"bailanysta/api/internal/pkg/auth"
+	"bailanysta/api/internal/pkg/logger"
+	"bailanysta/api/internal/services"
+)
+
+type AdminHandler struct {
+	authService   *services.AuthService
+	contentFilter *services.ContentFilterService
+	aiService     *services.AIService
+	abuse         *services.AbuseService
+	duplicates    *services.DuplicateService
+	logger        *logger.Logger
+	validator     *validator.Validate
+	jwtManager    *auth.JWTManager
+}
+
+func NewAdminHandler(authService *services.AuthService, contentFilter *services.ContentFilterService, aiService *services.AIService, abuse *services.AbuseService, duplicates *services.DuplicateService, logger *logger.Logger, jwtManager *auth.JWTManager) *AdminHandler {
+	return &AdminHandler{
+		authService:   authService,
+		contentFilter: contentFilter,
+		aiService:     aiService,
+		abuse:         abuse,
+		duplicates:    duplicates,
+		logger:        logger,
+		validator:     validator.New(),
+		jwtManager:    jwtManager,
+	}
+}
+
+func (h *AdminHandler) GetLogLevels(w http.ResponseWriter, r *http.Request) {
+	h.respondWithJSON(w, map[string]interface{}{
+		"levels": h.logger.Levels(),
+	}, http.StatusOK)
+}
+
+// UpdateLogLevels changes log levels at runtime, e.g. {"levels": "info,ai=debug"}.
+// The change is not persisted and is lost on restart.
+func (h *AdminHandler) UpdateLogLevels(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Levels string `json:"levels"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	previous := h.logger.Levels()
+	if err := h.logger.SetLevels(req.Levels); err != nil {
+		h.respondWithError(w, "Invalid log levels: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.logger.Warn("Log levels changed", map[string]interface{}{
+		"previous": previous,
+		"levels":   h.logger.Levels(),
+	})
+
+	h.GetLogLevels(w, r)
+}
+
+// GetReviewQueue lists posts and comments the content filter queued for review
+func (h *AdminHandler) GetReviewQueue(w http.ResponseWriter, r *http.Request) {
+	orgID, err := h.jwtManager.GetOrgIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	limit := 20
+	offset := 0
+
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.Atoi(limitParam); err == nil && parsedLimit > 0 && parsedLimit <= 100 {
+			limit = parsedLimit
+		}
+	}
+
+	if offsetParam := r.URL.Query().Get("offset"); offsetParam != "" {
+		if parsedOffset, err := strconv.Atoi(offsetParam); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	items, err := h.contentFilter.GetReviewQueue(r.Context(), orgID, limit, offset)
+	if err != nil {
+		h.logger.Error("Failed to get review queue", map[string]interface{}{
+			"error": err.Error(),
+		})
+		h.respondWithError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"items":  items,
+		"limit":  limit,
+		"offset": offset,
+	}, http.StatusOK)
+}
+
+// GetAIFilterEvents lists AI prompts and responses the safety filter caught,
+// newest first
+func (h *AdminHandler) GetAIFilterEvents(w http.ResponseWriter, r *http.Request) {
+	orgID, err := h.jwtManager.GetOrgIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	limit := 20
+	offset := 0
+
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.Atoi(limitParam); err == nil && parsedLimit > 0 && parsedLimit <= 100 {
+			limit = parsedLimit
+		}
+	}
+
+	if offsetParam := r.URL.Query().Get("offset"); offsetParam != "" {
+		if parsedOffset, err := strconv.Atoi(offsetParam); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	events, err := h.aiService.GetFilterEvents(r.Context(), orgID, limit, offset)
+	if err != nil {
+		h.logger.Error("Failed to get AI filter events", map[string]interface{}{
+			"error": err.Error(),
+		})
+		h.respondWithError(w, "Failed to get AI filter events", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"events": events,
+		"limit":  limit,
+		"offset": offset,
+	}, http.StatusOK)
+}
+
+// ResolveReview approves or removes queued content, e.g. {"decision": "remove"}
+func (h *AdminHandler) ResolveReview(w http.ResponseWriter, r *http.Request) {
+	moderatorID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	orgID, err := h.jwtManager.GetOrgIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	flagID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondWithError(w, "Invalid review item ID", http.StatusBadRequest)
+		return
+	}
+
+	var req services.ResolveReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.validator.Struct(req); err != nil {
+		h.respondWithError(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = h.contentFilter.ResolveReview(r.Context(), orgID, moderatorID, flagID, req.Decision)
+	if err != nil {
+		if err.Error() == "review item not found" {
+			h.respondWithError(w, "Review item not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("Failed to resolve review item", map[string]interface{}{
+			"error":   err.Error(),
+			"flag_id": flagID,
+		})
+		h.respondWithError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("Review item resolved", map[string]interface{}{
+		"flag_id":      flagID,
+		"decision":     req.Decision,
+		"moderator_id": moderatorID,
+	})
+
+	h.respondWithJSON(w, map[string]interface{}{"message": "Review item resolved"}, http.StatusOK)
+}
+
+// GetAbuseFlags lists accounts throttled for mass-following or like spam
+// that haven't been reviewed, oldest first
+func (h *AdminHandler) GetAbuseFlags(w http.ResponseWriter, r *http.Request) {
+	orgID, err := h.jwtManager.GetOrgIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	limit := 20
+	offset := 0
+
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.Atoi(limitParam); err == nil && parsedLimit > 0 && parsedLimit <= 100 {
+			limit = parsedLimit
+		}
+	}
+
+	if offsetParam := r.URL.Query().Get("offset"); offsetParam != "" {
+		if parsedOffset, err := strconv.Atoi(offsetParam); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	flags, err := h.abuse.GetOpenFlags(r.Context(), orgID, limit, offset)
+	if err != nil {
+		h.logger.Error("Failed to get abuse flags", map[string]interface{}{
+			"error": err.Error(),
+		})
+		h.respondWithError(w, "Failed to get abuse flags", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"flags":  flags,
+		"limit":  limit,
+		"offset": offset,
+	}, http.StatusOK)
+}
+
+// ResolveAbuseFlag closes an account's abuse flags, e.g. {"decision": "clear"}
+// to lift its throttle
+func (h *AdminHandler) ResolveAbuseFlag(w http.ResponseWriter, r *http.Request) {
+	moderatorID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	orgID, err := h.jwtManager.GetOrgIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	flagID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondWithError(w, "Invalid abuse flag ID", http.StatusBadRequest)
+		return
+	}
+
+	var req services.ResolveAbuseFlagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.validator.Struct(req); err != nil {
+		h.respondWithError(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.abuse.ResolveFlag(r.Context(), orgID, moderatorID, flagID, req.Decision); err != nil {
+		if err.Error() == "abuse flag not found" {
+			h.respondWithError(w, "Abuse flag not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("Failed to resolve abuse flag", map[string]interface{}{
+			"error":   err.Error(),
+			"flag_id": flagID,
+		})
+		h.respondWithError(w, "Failed to resolve abuse flag", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("Abuse flag resolved", map[string]interface{}{
+		"flag_id":      flagID,
+		"decision":     req.Decision,
+		"moderator_id": moderatorID,
+	})
+
+	h.respondWithJSON(w, map[string]interface{}{"message": "Abuse flag resolved"}, http.StatusOK)
+}
+
+// GetDuplicates lists near-duplicate posts that haven't been reviewed, each
+// with the earlier post it copies, oldest first
+func (h *AdminHandler) GetDuplicates(w http.ResponseWriter, r *http.Request) {
+	orgID, err := h.jwtManager.GetOrgIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	limit := 20
+	offset := 0
+
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.Atoi(limitParam); err == nil && parsedLimit > 0 && parsedLimit <= 100 {
+			limit = parsedLimit
+		}
+	}
+
+	if offsetParam := r.URL.Query().Get("offset"); offsetParam != "" {
+		if parsedOffset, err := strconv.Atoi(offsetParam); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	duplicates, err := h.duplicates.GetReport(r.Context(), orgID, limit, offset)
+	if err != nil {
+		h.logger.Error("Failed to get duplicate posts", map[string]interface{}{
+			"error": err.Error(),
+		})
+		h.respondWithError(w, "Failed to get duplicate posts", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"duplicates": duplicates,
+		"limit":      limit,
+		"offset":     offset,
+	}, http.StatusOK)
+}
+
+// ResolveDuplicate closes a duplicate report, e.g. {"decision": "merge"} to
+// fold the post's likes and comments into the original and delete it
+func (h *AdminHandler) ResolveDuplicate(w http.ResponseWriter, r *http.Request) {
+	moderatorID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	orgID, err := h.jwtManager.GetOrgIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	postID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondWithError(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	var req services.ResolveDuplicateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.validator.Struct(req); err != nil {
+		h.respondWithError(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.duplicates.Resolve(r.Context(), orgID, moderatorID, postID, req.Decision); err != nil {
+		switch err.Error() {
+		case "duplicate post not found":
+			h.respondWithError(w, "Duplicate post not found", http.StatusNotFound)
+		case "post not found":
+			h.respondWithError(w, "Original post not found", http.StatusNotFound)
+		default:
+			h.logger.Error("Failed to resolve duplicate post", map[string]interface{}{
+				"error":   err.Error(),
+				"post_id": postID,
+			})
+			h.respondWithError(w, "Failed to resolve duplicate post", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.logger.Info("Duplicate post resolved", map[string]interface{}{
+		"post_id":      postID,
+		"decision":     req.Decision,
+		"moderator_id": moderatorID,
+	})
+
+	h.respondWithJSON(w, map[string]interface{}{"message": "Duplicate post resolved"}, http.StatusOK)
+}
+
+// ShadowBanUser hides the user's content and activity from everyone else
+func (h *AdminHandler) ShadowBanUser(w http.ResponseWriter, r *http.Request) {
+	h.setShadowBanned(w, r, true)
+}
+
+func (h *AdminHandler) UnshadowBanUser(w http.ResponseWriter, r *http.Request) {
+	h.setShadowBanned(w, r, false)
+}
+
+func (h *AdminHandler) setShadowBanned(w http.ResponseWriter, r *http.Request, banned bool) {
+	moderatorID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	orgID, err := h.jwtManager.GetOrgIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondWithError(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.authService.SetShadowBanned(r.Context(), orgID, userID, banned)
+	if err != nil {
+		if err.Error() == "user not found" {
+			h.respondWithError(w, "User not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("Failed to update shadow ban", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		h.respondWithError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Warn("Shadow ban updated", map[string]interface{}{
+		"user_id":       userID,
+		"shadow_banned": banned,
+		"moderator_id":  moderatorID,
+	})
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"user_id":       userID,
+		"shadow_banned": banned,
+	}, http.StatusOK)
+}
+
+// GetUsersByRole lists the organization's users with ?role=
+func (h *AdminHandler) GetUsersByRole(w http.ResponseWriter, r *http.Request) {
+	orgID, err := h.jwtManager.GetOrgIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	role := services.Role(r.URL.Query().Get("role"))
+	if !services.ValidRole(role) {
+		h.respondWithError(w, "role must be one of student, teacher, moderator, admin", http.StatusBadRequest)
+		return
+	}
+
+	users, err := h.authService.GetUsersByRole(r.Context(), orgID, role)
+	if err != nil {
+		h.logger.Error("Failed to get users by role", map[string]interface{}{
+			"error": err.Error(),
+			"role":  role,
+		})
+		h.respondWithError(w, "Failed to get users", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{"users": users}, http.StatusOK)
+}
+
+// SetUserRole assigns a role to a user of the admin's organization
+func (h *AdminHandler) SetUserRole(w http.ResponseWriter, r *http.Request) {
+	adminID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	orgID, err := h.jwtManager.GetOrgIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondWithError(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Role services.Role `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	err = h.authService.SetRole(r.Context(), orgID, adminID, userID, req.Role)
+	if err != nil {
+		switch err.Error() {
+		case "invalid role":
+			h.respondWithError(w, "role must be one of student, teacher, moderator, admin", http.StatusBadRequest)
+		case "cannot change own role":
+			h.respondWithError(w, "You cannot change your own role", http.StatusBadRequest)
+		case "cannot change a superadmin's role":
+			h.respondWithError(w, "A superadmin's role can't be changed here", http.StatusForbidden)
+		case "user not found":
+			h.respondWithError(w, "User not found", http.StatusNotFound)
+		default:
+			h.logger.Error("Failed to set role", map[string]interface{}{
+				"error":   err.Error(),
+				"user_id": userID,
+			})
+			h.respondWithError(w, "Failed to set role", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.logger.Warn("Role changed", map[string]interface{}{
+		"user_id":  userID,
+		"role":     req.Role,
+		"admin_id": adminID,
+	})
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"user_id": userID,
+		"role":    req.Role,
+	}, http.StatusOK)
+}
+
+func (h *AdminHandler) GetOrganizations(w http.ResponseWriter, r *http.Request) {
+	orgs, err := h.authService.GetOrganizations(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to get organizations", map[string]interface{}{
+			"error": err.Error(),
+		})
+		h.respondWithError(w, "Failed to get organizations", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{"organizations": orgs}, http.StatusOK)
+}
+
+func (h *AdminHandler) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	var req services.CreateOrganizationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		h.respondWithError(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	org, err := h.authService.CreateOrganization(r.Context(), req)
+	if err != nil {
+		switch err.Error() {
+		case "invalid organization slug":
+			h.respondWithError(w, "Slug may only contain lowercase letters, digits and hyphens", http.StatusBadRequest)
+		case "organization already exists":
+			h.respondWithError(w, "Organization already exists", http.StatusConflict)
+		default:
+			h.logger.Error("Failed to create organization", map[string]interface{}{
+				"error": err.Error(),
+			})
+			h.respondWithError(w, "Failed to create organization", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.logger.Info("Organization created", map[string]interface{}{
+		"org_id": org.ID,
+		"slug":   org.Slug,
+	})
+
+	h.respondWithJSON(w, org, http.StatusCreated)
+}
+
+func (h *AdminHandler) respondWithJSON(w http.ResponseWriter, data interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *AdminHandler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	h.respondWithJSON(w, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    getErrorCode(statusCode),
+			"message": message,
+		},
+	}, statusCode)
+}
+
+func (h *AdminHandler) getUserIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	return h.jwtManager.GetUserIDFromContext(ctx)
+}
@@ -1,26 +1,45 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
 
+	"bailanysta/api/internal/pkg/auth"
 	"bailanysta/api/internal/pkg/logger"
 	"bailanysta/api/internal/services"
 )
 
+const (
+	feedDigestMaxPosts    = 60
+	feedDigestDefaultSpan = 24 * time.Hour
+	feedDigestMaxSpan     = 7 * 24 * time.Hour
+)
+
 type AIHandler struct {
-	aiService *services.AIService
-	logger    *logger.Logger
-	validator *validator.Validate
+	aiService     *services.AIService
+	postsService  *services.PostsService
+	socialService *services.SocialService
+	logger        *logger.Logger
+	validator     *validator.Validate
+	jwtManager    *auth.JWTManager
 }
 
-func NewAIHandler(aiService *services.AIService, logger *logger.Logger) *AIHandler {
+func NewAIHandler(aiService *services.AIService, postsService *services.PostsService, socialService *services.SocialService, logger *logger.Logger, jwtManager *auth.JWTManager) *AIHandler {
 	return &AIHandler{
-		aiService: aiService,
-		logger:    logger,
-		validator: validator.New(),
+		aiService:     aiService,
+		postsService:  postsService,
+		socialService: socialService,
+		logger:        logger,
+		validator:     validator.New(),
+		jwtManager:    jwtManager,
 	}
 }
 
@@ -48,19 +67,27 @@ func (h *AIHandler) GenerateText(w http.ResponseWriter, r *http.Request) {
 			"error":  err.Error(),
 			"prompt": req.Prompt,
 		})
-		h.respondWithError(w, "Failed to generate text: "+err.Error(), http.StatusInternalServerError)
+		h.respondWithGenerationError(w, "Failed to generate text", err)
 		return
 	}
 
 	h.logger.Info("Text generated successfully", map[string]interface{}{
 		"prompt_length":   len(req.Prompt),
 		"response_length": len(response.Text),
+		"model":           response.Model,
+		"total_tokens":    response.Usage.TotalTokens,
 	})
 
 	h.respondWithJSON(w, response, http.StatusOK)
 }
 
 func (h *AIHandler) GeneratePost(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	var req services.GeneratePostRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.logger.Warn("Failed to decode generate post request", map[string]interface{}{
@@ -78,13 +105,13 @@ func (h *AIHandler) GeneratePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response, err := h.aiService.GeneratePost(r.Context(), req)
+	response, err := h.aiService.GeneratePost(r.Context(), userID, req)
 	if err != nil {
 		h.logger.Error("Failed to generate post", map[string]interface{}{
 			"error": err.Error(),
 			"topic": req.Topic,
 		})
-		h.respondWithError(w, "Failed to generate post: "+err.Error(), http.StatusInternalServerError)
+		h.respondWithGenerationError(w, "Failed to generate post", err)
 		return
 	}
 
@@ -92,6 +119,7 @@ func (h *AIHandler) GeneratePost(w http.ResponseWriter, r *http.Request) {
 		"topic":           req.Topic,
 		"course":          req.Course,
 		"response_length": len(response.Text),
+		"total_tokens":    response.Usage.TotalTokens,
 	})
 
 	h.respondWithJSON(w, response, http.StatusOK)
@@ -120,12 +148,51 @@ func (h *AIHandler) GenerateComment(w http.ResponseWriter, r *http.Request) {
 		h.logger.Error("Failed to generate comment", map[string]interface{}{
 			"error": err.Error(),
 		})
-		h.respondWithError(w, "Failed to generate comment: "+err.Error(), http.StatusInternalServerError)
+		h.respondWithGenerationError(w, "Failed to generate comment", err)
 		return
 	}
 
 	h.logger.Info("Comment generated successfully", map[string]interface{}{
 		"response_length": len(response.Text),
+		"total_tokens":    response.Usage.TotalTokens,
+	})
+
+	h.respondWithJSON(w, response, http.StatusOK)
+}
+
+func (h *AIHandler) RewriteText(w http.ResponseWriter, r *http.Request) {
+	var req services.RewriteTextRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Warn("Failed to decode rewrite text request", map[string]interface{}{
+			"error": err.Error(),
+		})
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		h.logger.Warn("Rewrite text validation failed", map[string]interface{}{
+			"error": err.Error(),
+		})
+		h.respondWithError(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := h.aiService.RewriteText(r.Context(), req)
+	if err != nil {
+		h.logger.Error("Failed to rewrite text", map[string]interface{}{
+			"error": err.Error(),
+			"mode":  req.Mode,
+		})
+		h.respondWithGenerationError(w, "Failed to rewrite text", err)
+		return
+	}
+
+	h.logger.Info("Text rewritten successfully", map[string]interface{}{
+		"mode":            req.Mode,
+		"target_language": req.TargetLanguage,
+		"response_length": len(response.Text),
+		"total_tokens":    response.Usage.TotalTokens,
 	})
 
 	h.respondWithJSON(w, response, http.StatusOK)
@@ -133,8 +200,9 @@ func (h *AIHandler) GenerateComment(w http.ResponseWriter, r *http.Request) {
 
 func (h *AIHandler) GenerateStudyNotes(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		Topic  string `json:"topic" validate:"required,min=3,max=200"`
-		Course string `json:"course,omitempty"`
+		Topic   string `json:"topic" validate:"required,min=3,max=200"`
+		Course  string `json:"course,omitempty"`
+		Refresh bool   `json:"refresh,omitempty"` // Bypass the response cache
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -153,25 +221,33 @@ func (h *AIHandler) GenerateStudyNotes(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response, err := h.aiService.GenerateStudyNotes(r.Context(), req.Topic, req.Course)
+	response, err := h.aiService.GenerateStudyNotes(r.Context(), req.Topic, req.Course, req.Refresh)
 	if err != nil {
 		h.logger.Error("Failed to generate study notes", map[string]interface{}{
 			"error": err.Error(),
 			"topic": req.Topic,
 		})
-		h.respondWithError(w, "Failed to generate study notes: "+err.Error(), http.StatusInternalServerError)
+		h.respondWithGenerationError(w, "Failed to generate study notes", err)
 		return
 	}
 
 	h.logger.Info("Study notes generated successfully", map[string]interface{}{
-		"topic":  req.Topic,
-		"course": req.Course,
+		"topic":        req.Topic,
+		"course":       req.Course,
+		"cached":       response.Cached,
+		"total_tokens": response.Usage.TotalTokens,
 	})
 
 	h.respondWithJSON(w, response, http.StatusOK)
 }
 
 func (h *AIHandler) GenerateQuiz(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	var req struct {
 		Topic  string `json:"topic" validate:"required,min=3,max=200"`
 		Course string `json:"course,omitempty"`
@@ -193,19 +269,20 @@ func (h *AIHandler) GenerateQuiz(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response, err := h.aiService.GenerateQuiz(r.Context(), req.Topic, req.Course)
+	response, err := h.aiService.GenerateQuiz(r.Context(), userID, req.Topic, req.Course)
 	if err != nil {
 		h.logger.Error("Failed to generate quiz", map[string]interface{}{
 			"error": err.Error(),
 			"topic": req.Topic,
 		})
-		h.respondWithError(w, "Failed to generate quiz: "+err.Error(), http.StatusInternalServerError)
+		h.respondWithGenerationError(w, "Failed to generate quiz", err)
 		return
 	}
 
 	h.logger.Info("Quiz generated successfully", map[string]interface{}{
-		"topic":  req.Topic,
-		"course": req.Course,
+		"topic":        req.Topic,
+		"course":       req.Course,
+		"total_tokens": response.Usage.TotalTokens,
 	})
 
 	h.respondWithJSON(w, response, http.StatusOK)
@@ -215,6 +292,7 @@ func (h *AIHandler) ExplainConcept(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Concept string `json:"concept" validate:"required,min=3,max=200"`
 		Context string `json:"context,omitempty"`
+		Refresh bool   `json:"refresh,omitempty"` // Bypass the response cache
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -233,19 +311,143 @@ func (h *AIHandler) ExplainConcept(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response, err := h.aiService.ExplainConcept(r.Context(), req.Concept, req.Context)
+	response, err := h.aiService.ExplainConcept(r.Context(), req.Concept, req.Context, req.Refresh)
 	if err != nil {
 		h.logger.Error("Failed to explain concept", map[string]interface{}{
 			"error":   err.Error(),
 			"concept": req.Concept,
 		})
-		h.respondWithError(w, "Failed to explain concept: "+err.Error(), http.StatusInternalServerError)
+		h.respondWithGenerationError(w, "Failed to explain concept", err)
 		return
 	}
 
 	h.logger.Info("Concept explained successfully", map[string]interface{}{
-		"concept": req.Concept,
-		"context": req.Context,
+		"concept":      req.Concept,
+		"context":      req.Context,
+		"cached":       response.Cached,
+		"total_tokens": response.Usage.TotalTokens,
+	})
+
+	h.respondWithJSON(w, response, http.StatusOK)
+}
+
+// ExplainPost explains a post the user can see in simpler terms
+func (h *AIHandler) ExplainPost(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	postID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondWithError(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	// ?refresh=true bypasses the response cache
+	refresh := r.URL.Query().Get("refresh") == "true"
+
+	post, err := h.postsService.GetPostByID(r.Context(), postID, userID)
+	if err != nil {
+		h.logger.Warn("Post not found", map[string]interface{}{
+			"post_id": postID,
+			"error":   err.Error(),
+		})
+		h.respondWithError(w, "Post not found", http.StatusNotFound)
+		return
+	}
+	if strings.TrimSpace(post.Text) == "" {
+		h.respondWithError(w, "Post has no text to explain", http.StatusUnprocessableEntity)
+		return
+	}
+
+	response, err := h.aiService.ExplainPost(r.Context(), post, refresh)
+	if err != nil {
+		h.logger.Error("Failed to explain post", map[string]interface{}{
+			"error":   err.Error(),
+			"post_id": postID,
+		})
+		h.respondWithGenerationError(w, "Failed to explain post", err)
+		return
+	}
+
+	h.logger.Info("Post explained successfully", map[string]interface{}{
+		"post_id":      postID,
+		"cached":       response.Cached,
+		"total_tokens": response.Usage.TotalTokens,
+	})
+
+	h.respondWithJSON(w, response, http.StatusOK)
+}
+
+func (h *AIHandler) GetFeedDigest(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	lastSeen, err := h.socialService.GetFeedLastSeen(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("Failed to get feed last seen", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		h.respondWithError(w, "Failed to generate feed digest", http.StatusInternalServerError)
+		return
+	}
+
+	// Without a previous visit summarize the last day; never go back further than a week
+	since := time.Now().Add(-feedDigestDefaultSpan)
+	if lastSeen != nil {
+		since = *lastSeen
+	}
+	if oldest := time.Now().Add(-feedDigestMaxSpan); since.Before(oldest) {
+		since = oldest
+	}
+
+	// One post over the limit tells whether some are left for the next digest
+	items, err := h.socialService.GetFeedDigestItems(r.Context(), userID, since, feedDigestMaxPosts+1)
+	if err != nil {
+		h.logger.Error("Failed to get feed digest items", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		h.respondWithError(w, "Failed to generate feed digest", http.StatusInternalServerError)
+		return
+	}
+	hasMore := len(items) > feedDigestMaxPosts
+	if hasMore {
+		items = items[:feedDigestMaxPosts]
+	}
+
+	response, err := h.aiService.GenerateFeedDigest(r.Context(), items, since)
+	if err != nil {
+		h.logger.Error("Failed to generate feed digest", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		h.respondWithGenerationError(w, "Failed to generate feed digest", err)
+		return
+	}
+
+	response.HasMore = hasMore
+
+	// Items are oldest first; only what this digest covered counts as seen
+	if len(items) > 0 {
+		if err := h.socialService.MarkFeedSeen(r.Context(), userID, items[len(items)-1].CreatedAt); err != nil {
+			h.logger.Warn("Failed to mark feed seen", map[string]interface{}{
+				"error":   err.Error(),
+				"user_id": userID,
+			})
+		}
+	}
+
+	h.logger.Info("Feed digest generated successfully", map[string]interface{}{
+		"user_id":      userID,
+		"post_count":   response.PostCount,
+		"total_tokens": response.Usage.TotalTokens,
 	})
 
 	h.respondWithJSON(w, response, http.StatusOK)
@@ -257,6 +459,16 @@ func (h *AIHandler) respondWithJSON(w http.ResponseWriter, data interface{}, sta
 	json.NewEncoder(w).Encode(data)
 }
 
+// respondWithGenerationError reports a failed completion. A response the
+// safety filter blocked isn't a server fault, so it gets a 422.
+func (h *AIHandler) respondWithGenerationError(w http.ResponseWriter, message string, err error) {
+	if errors.Is(err, services.ErrAIResponseBlocked) {
+		h.respondWithError(w, "AI response blocked by the safety filter", http.StatusUnprocessableEntity)
+		return
+	}
+	h.respondWithError(w, message+": "+err.Error(), http.StatusInternalServerError)
+}
+
 func (h *AIHandler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
 	h.respondWithJSON(w, map[string]interface{}{
 		"error": map[string]interface{}{
@@ -265,3 +477,7 @@ func (h *AIHandler) respondWithError(w http.ResponseWriter, message string, stat
 		},
 	}, statusCode)
 }
+
+func (h *AIHandler) getUserIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	return h.jwtManager.GetUserIDFromContext(ctx)
+}
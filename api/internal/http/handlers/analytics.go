@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"bailanysta/api/internal/pkg/auth"
+	"bailanysta/api/internal/pkg/logger"
+	"bailanysta/api/internal/services"
+)
+
+const (
+	analyticsDefaultDays = 30
+	analyticsMaxDays     = 366
+)
+
+type AnalyticsHandler struct {
+	analyticsService *services.AnalyticsService
+	logger           *logger.Logger
+	jwtManager       *auth.JWTManager
+}
+
+func NewAnalyticsHandler(analyticsService *services.AnalyticsService, logger *logger.Logger, jwtManager *auth.JWTManager) *AnalyticsHandler {
+	return &AnalyticsHandler{
+		analyticsService: analyticsService,
+		logger:           logger,
+		jwtManager:       jwtManager,
+	}
+}
+
+// GetMyAnalytics returns stats for the caller's posts. The range is given as
+// ?from=YYYY-MM-DD&to=YYYY-MM-DD and defaults to the last 30 days.
+func (h *AnalyticsHandler) GetMyAnalytics(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	now := time.Now().UTC()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if toStr := r.URL.Query().Get("to"); toStr != "" {
+		to, err = time.Parse("2006-01-02", toStr)
+		if err != nil {
+			h.respondWithError(w, "Invalid 'to' date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+
+	from := to.AddDate(0, 0, -(analyticsDefaultDays - 1))
+	if fromStr := r.URL.Query().Get("from"); fromStr != "" {
+		from, err = time.Parse("2006-01-02", fromStr)
+		if err != nil {
+			h.respondWithError(w, "Invalid 'from' date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if from.After(to) {
+		h.respondWithError(w, "'from' must not be after 'to'", http.StatusBadRequest)
+		return
+	}
+	if to.Sub(from) >= analyticsMaxDays*24*time.Hour {
+		h.respondWithError(w, "Date range must not exceed 366 days", http.StatusBadRequest)
+		return
+	}
+
+	analytics, err := h.analyticsService.GetAuthorAnalytics(r.Context(), userID, from, to)
+	if err != nil {
+		h.logger.Error("Failed to get analytics", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		h.respondWithError(w, "Failed to get analytics", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, analytics, http.StatusOK)
+}
+
+func (h *AnalyticsHandler) respondWithJSON(w http.ResponseWriter, data interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *AnalyticsHandler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	h.respondWithJSON(w, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    getErrorCode(statusCode),
+			"message": message,
+		},
+	}, statusCode)
+}
+
+func (h *AnalyticsHandler) getUserIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	return h.jwtManager.GetUserIDFromContext(ctx)
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+
+	"bailanysta/api/internal/pkg/auth"
+	"bailanysta/api/internal/pkg/logger"
+	"bailanysta/api/internal/services"
+)
+
+type APIKeysHandler struct {
+	authService *services.AuthService
+	logger      *logger.Logger
+	validator   *validator.Validate
+	jwtManager  *auth.JWTManager
+}
+
+func NewAPIKeysHandler(authService *services.AuthService, logger *logger.Logger, jwtManager *auth.JWTManager) *APIKeysHandler {
+	return &APIKeysHandler{
+		authService: authService,
+		logger:      logger,
+		validator:   validator.New(),
+		jwtManager:  jwtManager,
+	}
+}
+
+// GetAPIKeys lists the caller's active API keys
+func (h *APIKeysHandler) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.sessionUserID(w, r)
+	if !ok {
+		return
+	}
+
+	keys, err := h.authService.ListAPIKeys(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("Failed to list API keys", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		h.respondWithError(w, "Failed to get API keys", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"api_keys": keys,
+	}, http.StatusOK)
+}
+
+// CreateAPIKey creates a read or write key; the response is the only time
+// the key is shown
+func (h *APIKeysHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.sessionUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req services.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.validator.Struct(req); err != nil {
+		h.respondWithError(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key, err := h.authService.CreateAPIKey(r.Context(), userID, req)
+	if err != nil {
+		switch err.Error() {
+		case "invalid API key scope":
+			h.respondWithError(w, "scope must be read or write", http.StatusBadRequest)
+		case "too many API keys":
+			h.respondWithError(w, "Too many API keys; revoke one first", http.StatusConflict)
+		default:
+			h.logger.Error("Failed to create API key", map[string]interface{}{
+				"error":   err.Error(),
+				"user_id": userID,
+			})
+			h.respondWithError(w, "Failed to create API key", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.logger.Info("API key created", map[string]interface{}{
+		"user_id": userID,
+		"key_id":  key.ID,
+		"scope":   key.Scope,
+	})
+
+	h.respondWithJSON(w, key, http.StatusCreated)
+}
+
+// RevokeAPIKey revokes one of the caller's keys
+func (h *APIKeysHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.sessionUserID(w, r)
+	if !ok {
+		return
+	}
+
+	keyID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondWithError(w, "Invalid API key ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.RevokeAPIKey(r.Context(), userID, keyID); err != nil {
+		if err.Error() == "API key not found" {
+			h.respondWithError(w, "API key not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("Failed to revoke API key", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+			"key_id":  keyID,
+		})
+		h.respondWithError(w, "Failed to revoke API key", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("API key revoked", map[string]interface{}{
+		"user_id": userID,
+		"key_id":  keyID,
+	})
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"message": "API key revoked",
+	}, http.StatusOK)
+}
+
+// sessionUserID returns the caller, refusing requests made with an API key
+// so a leaked key can't mint or revoke others, and impersonated requests
+func (h *APIKeysHandler) sessionUserID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return uuid.Nil, false
+	}
+	if _, viaKey := r.Context().Value("api_key_id").(string); viaKey {
+		h.respondWithError(w, "API keys cannot manage API keys", http.StatusForbidden)
+		return uuid.Nil, false
+	}
+	if _, impersonated := r.Context().Value("impersonation").(*services.ImpersonationInfo); impersonated {
+		h.respondWithError(w, "Not allowed while impersonating", http.StatusForbidden)
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+func (h *APIKeysHandler) respondWithJSON(w http.ResponseWriter, data interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *APIKeysHandler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	h.respondWithJSON(w, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    getErrorCode(statusCode),
+			"message": message,
+		},
+	}, statusCode)
+}
+
+func (h *APIKeysHandler) getUserIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	return h.jwtManager.GetUserIDFromContext(ctx)
+}
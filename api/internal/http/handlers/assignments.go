@@ -0,0 +1,409 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+
+	"bailanysta/api/internal/pkg/auth"
+	"bailanysta/api/internal/pkg/logger"
+	"bailanysta/api/internal/services"
+)
+
+type AssignmentsHandler struct {
+	assignmentService *services.AssignmentService
+	aiService         *services.AIService
+	logger            *logger.Logger
+	validator         *validator.Validate
+	jwtManager        *auth.JWTManager
+}
+
+func NewAssignmentsHandler(assignmentService *services.AssignmentService, aiService *services.AIService, logger *logger.Logger, jwtManager *auth.JWTManager) *AssignmentsHandler {
+	return &AssignmentsHandler{
+		assignmentService: assignmentService,
+		aiService:         aiService,
+		logger:            logger,
+		validator:         validator.New(),
+		jwtManager:        jwtManager,
+	}
+}
+
+// CreateAssignment adds an assignment to a module, e.g.
+// {"title": "...", "description": "...", "due_at": "2025-05-01T18:00:00Z"}
+func (h *AssignmentsHandler) CreateAssignment(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	orgID, err := h.jwtManager.GetOrgIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Organization not found", http.StatusBadRequest)
+		return
+	}
+
+	moduleID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondWithError(w, "Invalid module ID", http.StatusBadRequest)
+		return
+	}
+
+	var req services.CreateAssignmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.validator.Struct(req); err != nil {
+		h.respondWithError(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	assignment, err := h.assignmentService.CreateAssignment(r.Context(), userID, orgID, moduleID, req)
+	if err != nil {
+		if err.Error() == "module not found" {
+			h.respondWithError(w, "Module not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("Failed to create assignment", map[string]interface{}{
+			"error":     err.Error(),
+			"user_id":   userID,
+			"module_id": moduleID,
+		})
+		h.respondWithError(w, "Failed to create assignment", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, assignment, http.StatusCreated)
+}
+
+// GetModuleAssignments lists a module's assignments with the user's own
+// submission to each
+func (h *AssignmentsHandler) GetModuleAssignments(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	orgID, err := h.jwtManager.GetOrgIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Organization not found", http.StatusBadRequest)
+		return
+	}
+
+	moduleID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondWithError(w, "Invalid module ID", http.StatusBadRequest)
+		return
+	}
+
+	assignments, err := h.assignmentService.GetModuleAssignments(r.Context(), userID, orgID, moduleID)
+	if err != nil {
+		h.logger.Error("Failed to get assignments", map[string]interface{}{
+			"error":     err.Error(),
+			"user_id":   userID,
+			"module_id": moduleID,
+		})
+		h.respondWithError(w, "Failed to get assignments", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"assignments": assignments,
+	}, http.StatusOK)
+}
+
+// Submit hands in the user's work for an assignment, replacing an earlier
+// submission unless it was graded, e.g.
+// {"text": "...", "attachment_urls": ["https://..."]}
+func (h *AssignmentsHandler) Submit(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	orgID, err := h.jwtManager.GetOrgIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Organization not found", http.StatusBadRequest)
+		return
+	}
+
+	assignmentID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondWithError(w, "Invalid assignment ID", http.StatusBadRequest)
+		return
+	}
+
+	var req services.SubmitAssignmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.validator.Struct(req); err != nil {
+		h.respondWithError(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	submission, err := h.assignmentService.Submit(r.Context(), userID, orgID, assignmentID, req)
+	if err != nil {
+		switch err.Error() {
+		case "assignment not found":
+			h.respondWithError(w, "Assignment not found", http.StatusNotFound)
+		case "submission is empty":
+			h.respondWithError(w, "Text or attachments are required", http.StatusBadRequest)
+		case "too many attachments", "invalid attachment URL":
+			h.respondWithError(w, "Attachments must be up to 5 http(s) links", http.StatusBadRequest)
+		case "submission already graded":
+			h.respondWithError(w, "Submission already graded", http.StatusConflict)
+		default:
+			h.logger.Error("Failed to submit assignment", map[string]interface{}{
+				"error":         err.Error(),
+				"user_id":       userID,
+				"assignment_id": assignmentID,
+			})
+			h.respondWithError(w, "Failed to submit assignment", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.respondWithJSON(w, submission, http.StatusOK)
+}
+
+// GetMySubmission returns the user's own submission to an assignment
+func (h *AssignmentsHandler) GetMySubmission(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	assignmentID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondWithError(w, "Invalid assignment ID", http.StatusBadRequest)
+		return
+	}
+
+	submission, err := h.assignmentService.GetMySubmission(r.Context(), userID, assignmentID)
+	if err != nil {
+		if err.Error() == "submission not found" {
+			h.respondWithError(w, "Submission not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("Failed to get submission", map[string]interface{}{
+			"error":         err.Error(),
+			"user_id":       userID,
+			"assignment_id": assignmentID,
+		})
+		h.respondWithError(w, "Failed to get submission", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, submission, http.StatusOK)
+}
+
+// GetSubmissions lists the submissions to an assignment for teachers,
+// ungraded first; ?status= keeps one status
+func (h *AssignmentsHandler) GetSubmissions(w http.ResponseWriter, r *http.Request) {
+	orgID, err := h.jwtManager.GetOrgIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Organization not found", http.StatusBadRequest)
+		return
+	}
+
+	assignmentID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondWithError(w, "Invalid assignment ID", http.StatusBadRequest)
+		return
+	}
+
+	limit := 50
+	offset := 0
+
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.Atoi(limitParam); err == nil && parsedLimit > 0 && parsedLimit <= 100 {
+			limit = parsedLimit
+		}
+	}
+
+	if offsetParam := r.URL.Query().Get("offset"); offsetParam != "" {
+		if parsedOffset, err := strconv.Atoi(offsetParam); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", services.SubmissionStatusSubmitted, services.SubmissionStatusReturned, services.SubmissionStatusGraded:
+	default:
+		h.respondWithError(w, "Invalid status", http.StatusBadRequest)
+		return
+	}
+
+	submissions, err := h.assignmentService.GetSubmissions(r.Context(), orgID, assignmentID, status, limit, offset)
+	if err != nil {
+		h.logger.Error("Failed to get submissions", map[string]interface{}{
+			"error":         err.Error(),
+			"assignment_id": assignmentID,
+		})
+		h.respondWithError(w, "Failed to get submissions", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"submissions": submissions,
+		"limit":       limit,
+		"offset":      offset,
+	}, http.StatusOK)
+}
+
+// ReviewSubmission grades a submission or returns it for rework, e.g.
+// {"status": "graded", "grade": 85, "feedback": "..."}
+func (h *AssignmentsHandler) ReviewSubmission(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	orgID, err := h.jwtManager.GetOrgIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Organization not found", http.StatusBadRequest)
+		return
+	}
+
+	submissionID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondWithError(w, "Invalid submission ID", http.StatusBadRequest)
+		return
+	}
+
+	var req services.ReviewSubmissionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.validator.Struct(req); err != nil {
+		h.respondWithError(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	submission, err := h.assignmentService.ReviewSubmission(r.Context(), userID, orgID, submissionID, req)
+	if err != nil {
+		switch err.Error() {
+		case "submission not found":
+			h.respondWithError(w, "Submission not found", http.StatusNotFound)
+		case "grade is required":
+			h.respondWithError(w, "Grade is required", http.StatusBadRequest)
+		default:
+			h.logger.Error("Failed to review submission", map[string]interface{}{
+				"error":         err.Error(),
+				"user_id":       userID,
+				"submission_id": submissionID,
+			})
+			h.respondWithError(w, "Failed to review submission", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.respondWithJSON(w, submission, http.StatusOK)
+}
+
+// GenerateAIFeedback asks the AI for rubric comments and a suggested score
+// on a text submission and keeps them for the grading view. The grade is
+// left to the teacher.
+func (h *AssignmentsHandler) GenerateAIFeedback(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	orgID, err := h.jwtManager.GetOrgIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Organization not found", http.StatusBadRequest)
+		return
+	}
+
+	submissionID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondWithError(w, "Invalid submission ID", http.StatusBadRequest)
+		return
+	}
+
+	submission, assignment, err := h.assignmentService.GetSubmissionForGrading(r.Context(), orgID, submissionID)
+	if err != nil {
+		if err.Error() == "submission not found" {
+			h.respondWithError(w, "Submission not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("Failed to get submission", map[string]interface{}{
+			"error":         err.Error(),
+			"submission_id": submissionID,
+		})
+		h.respondWithError(w, "Failed to get submission", http.StatusInternalServerError)
+		return
+	}
+	if strings.TrimSpace(submission.Text) == "" {
+		h.respondWithError(w, "Submission has no text to assess", http.StatusUnprocessableEntity)
+		return
+	}
+
+	feedback, err := h.aiService.SuggestGrade(r.Context(), assignment.Title, assignment.Description, submission.Text)
+	if err != nil {
+		h.logger.Error("Failed to generate grading feedback", map[string]interface{}{
+			"error":         err.Error(),
+			"user_id":       userID,
+			"submission_id": submissionID,
+		})
+		switch {
+		case errors.Is(err, services.ErrAIResponseBlocked):
+			h.respondWithError(w, "AI response blocked by the safety filter", http.StatusUnprocessableEntity)
+		case err.Error() == "AI returned no feedback":
+			h.respondWithError(w, "AI returned no feedback", http.StatusBadGateway)
+		default:
+			h.respondWithError(w, "Failed to generate feedback: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err := h.assignmentService.SaveAIFeedback(r.Context(), submissionID, feedback); err != nil {
+		h.logger.Error("Failed to save grading feedback", map[string]interface{}{
+			"error":         err.Error(),
+			"submission_id": submissionID,
+		})
+		h.respondWithError(w, "Failed to save feedback", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("Grading feedback generated successfully", map[string]interface{}{
+		"user_id":       userID,
+		"submission_id": submissionID,
+		"total_tokens":  feedback.Usage.TotalTokens,
+	})
+
+	submission.AIFeedback = feedback
+	h.respondWithJSON(w, submission, http.StatusOK)
+}
+
+func (h *AssignmentsHandler) respondWithJSON(w http.ResponseWriter, data interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *AssignmentsHandler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	h.respondWithJSON(w, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    getErrorCode(statusCode),
+			"message": message,
+		},
+	}, statusCode)
+}
+
+func (h *AssignmentsHandler) getUserIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	return h.jwtManager.GetUserIDFromContext(ctx)
+}
@@ -1,29 +1,66 @@
 package handlers
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"net/http"
+	"time"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
 
+	"bailanysta/api/internal/pkg/auth"
 	"bailanysta/api/internal/pkg/logger"
 	"bailanysta/api/internal/services"
 )
 
+// Cookie session mode: web clients that send X-Auth-Mode: cookie on login
+// get the refresh token in an httpOnly cookie instead of the body, plus a
+// CSRF cookie whose value they echo in X-CSRF-Token (double submit)
+const (
+	AuthModeHeader    = "X-Auth-Mode"
+	CSRFHeader        = "X-CSRF-Token"
+	RefreshCookieName = "bly_refresh"
+	CSRFCookieName    = "bly_csrf"
+
+	// The refresh cookie is only sent to the auth routes
+	refreshCookiePath = "/api/v1/auth"
+)
+
+// SessionCookies configures cookie session mode
+type SessionCookies struct {
+	Enabled  bool
+	Domain   string
+	Secure   bool
+	SameSite http.SameSite
+	MaxAge   time.Duration // of the refresh cookie
+}
+
 type AuthHandler struct {
-	authService *services.AuthService
-	logger      *logger.Logger
-	validator   *validator.Validate
+	authService     *services.AuthService
+	waitlistService *services.WaitlistService // nil when registration is open
+	bots            *BotProtection            // nil when bot protection is off
+	logger          *logger.Logger
+	validator       *validator.Validate
+	cookies         SessionCookies
 }
 
-func NewAuthHandler(authService *services.AuthService, logger *logger.Logger) *AuthHandler {
+func NewAuthHandler(authService *services.AuthService, waitlistService *services.WaitlistService, bots *BotProtection, logger *logger.Logger, cookies SessionCookies) *AuthHandler {
 	return &AuthHandler{
-		authService: authService,
-		logger:      logger,
-		validator:   validator.New(),
+		authService:     authService,
+		waitlistService: waitlistService,
+		bots:            bots,
+		logger:          logger,
+		validator:       validator.New(),
+		cookies:         cookies,
 	}
 }
 
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	var req services.RegisterRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -34,6 +71,13 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// When registration is invite-only, signing up without an invite joins
+	// the waitlist
+	if h.waitlistService.Enabled() && req.InviteToken == "" {
+		h.joinWaitlist(w, r, req.Email)
+		return
+	}
+
 	// Validate request
 	if err := h.validator.Struct(req); err != nil {
 		h.logger.Warn("Register validation failed", map[string]interface{}{
@@ -43,13 +87,41 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var inviteID uuid.UUID
+	if h.waitlistService.Enabled() {
+		var err error
+		inviteID, err = h.waitlistService.ClaimInvite(r.Context(), req.InviteToken, req.Email)
+		if err != nil {
+			if err.Error() == "invalid invite" {
+				h.respondWithError(w, "Invite is invalid, used or expired", http.StatusForbidden)
+				return
+			}
+			h.logger.Error("Failed to claim invite", map[string]interface{}{
+				"error": err.Error(),
+			})
+			h.respondWithError(w, "Registration failed", http.StatusInternalServerError)
+			return
+		}
+	}
+
 	// Register user
-	response, err := h.authService.Register(r.Context(), req)
+	response, err := h.authService.Register(r.Context(), req, clientInfo(r))
 	if err != nil {
+		if inviteID != uuid.Nil {
+			if err := h.waitlistService.ReleaseInvite(r.Context(), inviteID); err != nil {
+				h.logger.Error("Failed to release invite", map[string]interface{}{
+					"error": err.Error(),
+				})
+			}
+		}
 		h.logger.Error("Registration failed", map[string]interface{}{
 			"error": err.Error(),
 			"email": req.Email,
 		})
+		if err.Error() == "organization not found" {
+			h.respondWithError(w, "Organization not found", http.StatusBadRequest)
+			return
+		}
 		h.respondWithError(w, err.Error(), http.StatusConflict)
 		return
 	}
@@ -59,9 +131,41 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		"username": response.User.Username,
 	})
 
+	if h.cookieMode(r) {
+		if err := h.setSessionCookies(w, &response.Tokens); err != nil {
+			h.respondWithError(w, "Failed to start session", http.StatusInternalServerError)
+			return
+		}
+	}
+
 	h.respondWithJSON(w, response, http.StatusCreated)
 }
 
+// clientInfo describes the client a session is issued to
+func clientInfo(r *http.Request) services.ClientInfo {
+	return services.ClientInfo{UserAgent: r.UserAgent(), IP: remoteIP(r)}
+}
+
+// joinWaitlist adds a signup to the waitlist; only the email is required
+func (h *AuthHandler) joinWaitlist(w http.ResponseWriter, r *http.Request, email string) {
+	if err := h.validator.Var(email, "required,email"); err != nil {
+		h.respondWithError(w, "A valid email is required", http.StatusBadRequest)
+		return
+	}
+	if err := h.waitlistService.Join(r.Context(), email); err != nil {
+		h.logger.Error("Failed to join waitlist", map[string]interface{}{
+			"error": err.Error(),
+		})
+		h.respondWithError(w, "Failed to join waitlist", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"waitlisted": true,
+		"message":    "You're on the waitlist. We'll email you an invite.",
+	}, http.StatusAccepted)
+}
+
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var req services.LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -81,8 +185,13 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.bots.checkLogin(w, r, req.Email) {
+		return
+	}
+
 	// Login user
-	response, err := h.authService.Login(r.Context(), req)
+	response, err := h.authService.Login(r.Context(), req, clientInfo(r))
+	h.bots.recordLogin(r, req.Email, err == nil)
 	if err != nil {
 		h.logger.Warn("Login failed", map[string]interface{}{
 			"error": err.Error(),
@@ -97,22 +206,138 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		"username": response.User.Username,
 	})
 
+	if h.cookieMode(r) {
+		if err := h.setSessionCookies(w, &response.Tokens); err != nil {
+			h.respondWithError(w, "Failed to start session", http.StatusInternalServerError)
+			return
+		}
+	}
+
 	h.respondWithJSON(w, response, http.StatusOK)
 }
 
+// Refresh exchanges a refresh token, from the cookie or the body, for a new
+// pair. Cookie sessions get their cookies rotated too.
 func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement refresh token functionality
-	h.logger.Info("Refresh token requested")
-	h.respondWithError(w, "Not implemented yet", http.StatusNotImplemented)
+	refreshToken, fromCookie := h.refreshToken(r)
+	if refreshToken == "" {
+		h.respondWithError(w, "Invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+
+	tokens, err := h.authService.RefreshToken(r.Context(), refreshToken, clientInfo(r))
+	if err != nil {
+		if err.Error() == "invalid refresh token" {
+			if fromCookie {
+				h.clearSessionCookies(w)
+			}
+			h.respondWithError(w, "Invalid refresh token", http.StatusUnauthorized)
+			return
+		}
+		h.logger.Error("Failed to refresh token", map[string]interface{}{
+			"error": err.Error(),
+		})
+		h.respondWithError(w, "Failed to refresh token", http.StatusInternalServerError)
+		return
+	}
+
+	if fromCookie {
+		if err := h.setSessionCookies(w, tokens); err != nil {
+			h.respondWithError(w, "Failed to start session", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"tokens": tokens,
+	}, http.StatusOK)
 }
 
+// Logout revokes the refresh token; access tokens stay valid until they
+// expire, which is kept short
 func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
-	// For stateless JWT, logout is handled client-side
-	// In production, you might want to blacklist tokens
+	refreshToken, fromCookie := h.refreshToken(r)
+	if refreshToken != "" {
+		if err := h.authService.RevokeRefreshToken(r.Context(), refreshToken); err != nil {
+			h.logger.Error("Failed to revoke refresh token", map[string]interface{}{
+				"error": err.Error(),
+			})
+			h.respondWithError(w, "Failed to log out", http.StatusInternalServerError)
+			return
+		}
+	}
+	if fromCookie {
+		h.clearSessionCookies(w)
+	}
+
 	h.logger.Info("User logged out")
 	h.respondWithJSON(w, map[string]interface{}{"message": "Logged out successfully"}, http.StatusOK)
 }
 
+// cookieMode reports whether the client asked for a cookie session and the
+// server allows them
+func (h *AuthHandler) cookieMode(r *http.Request) bool {
+	return h.cookies.Enabled && r.Header.Get(AuthModeHeader) == "cookie"
+}
+
+// refreshToken returns the refresh cookie if there is one, otherwise the
+// refresh_token of the body
+func (h *AuthHandler) refreshToken(r *http.Request) (string, bool) {
+	if h.cookies.Enabled {
+		if cookie, err := r.Cookie(RefreshCookieName); err == nil && cookie.Value != "" {
+			return cookie.Value, true
+		}
+	}
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return "", false
+	}
+	return req.RefreshToken, false
+}
+
+// setSessionCookies moves the refresh token from tokens into its cookie and
+// issues a new CSRF token
+func (h *AuthHandler) setSessionCookies(w http.ResponseWriter, tokens *auth.TokenPair) error {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		h.logger.Error("Failed to generate CSRF token", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return err
+	}
+
+	maxAge := int(h.cookies.MaxAge.Seconds())
+	http.SetCookie(w, h.cookie(RefreshCookieName, tokens.RefreshToken, refreshCookiePath, maxAge, true))
+	http.SetCookie(w, h.cookie(CSRFCookieName, hex.EncodeToString(bytes), "/", maxAge, false))
+	tokens.RefreshToken = ""
+	return nil
+}
+
+func (h *AuthHandler) clearSessionCookies(w http.ResponseWriter) {
+	http.SetCookie(w, h.cookie(RefreshCookieName, "", refreshCookiePath, -1, true))
+	http.SetCookie(w, h.cookie(CSRFCookieName, "", "/", -1, false))
+}
+
+func (h *AuthHandler) cookie(name, value, path string, maxAge int, httpOnly bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   h.cookies.Domain,
+		MaxAge:   maxAge,
+		Secure:   h.cookies.Secure,
+		HttpOnly: httpOnly,
+		SameSite: h.cookies.SameSite,
+	}
+}
+
+// GetJWKS serves the public keys access tokens are signed with, so other
+// services can verify them; the set is empty with HS256
+func (h *AuthHandler) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	h.respondWithJSON(w, h.authService.JWKS(), http.StatusOK)
+}
+
 func (h *AuthHandler) respondWithJSON(w http.ResponseWriter, data interface{}, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
@@ -140,8 +365,18 @@ func getErrorCode(statusCode int) string {
 		return "NOT_FOUND"
 	case http.StatusConflict:
 		return "CONFLICT"
+	case http.StatusUnprocessableEntity:
+		return "UNPROCESSABLE_ENTITY"
+	case http.StatusTooManyRequests:
+		return "TOO_MANY_REQUESTS"
 	case http.StatusInternalServerError:
 		return "INTERNAL_SERVER_ERROR"
+	case http.StatusBadGateway:
+		return "BAD_GATEWAY"
+	case http.StatusServiceUnavailable:
+		return "SERVICE_UNAVAILABLE"
+	case http.StatusGatewayTimeout:
+		return "GATEWAY_TIMEOUT"
 	default:
 		return "UNKNOWN_ERROR"
 	}
@@ -25,8 +25,8 @@ func TestAuthHandler_Register(t *testing.T) {
 	defer db.Close()
 
 	jwtManager := auth.NewJWTManager("test-secret", time.Hour, 24*time.Hour)
-	authService := services.NewAuthService(db, jwtManager)
-	handler := NewAuthHandler(authService, nil)
+	authService := services.NewAuthService(db, jwtManager, nil)
+	handler := NewAuthHandler(authService, nil, nil, nil, SessionCookies{})
 
 	// Create test request
 	reqBody := map[string]interface{}{
@@ -68,8 +68,8 @@ func TestAuthHandler_Register(t *testing.T) {
 
 func TestAuthHandler_Register_InvalidJSON(t *testing.T) {
 	jwtManager := auth.NewJWTManager("test-secret", time.Hour, 24*time.Hour)
-	authService := services.NewAuthService(nil, jwtManager)
-	handler := NewAuthHandler(authService, nil)
+	authService := services.NewAuthService(nil, jwtManager, nil)
+	handler := NewAuthHandler(authService, nil, nil, nil, SessionCookies{})
 
 	req := httptest.NewRequest("POST", "/auth/register", bytes.NewReader([]byte("invalid json")))
 	req.Header.Set("Content-Type", "application/json")
@@ -88,8 +88,8 @@ func TestAuthHandler_Register_InvalidJSON(t *testing.T) {
 
 func TestAuthHandler_Register_ValidationError(t *testing.T) {
 	jwtManager := auth.NewJWTManager("test-secret", time.Hour, 24*time.Hour)
-	authService := services.NewAuthService(nil, jwtManager)
-	handler := NewAuthHandler(authService, nil)
+	authService := services.NewAuthService(nil, jwtManager, nil)
+	handler := NewAuthHandler(authService, nil, nil, nil, SessionCookies{})
 
 	// Invalid request - missing required fields
 	reqBody := map[string]interface{}{
@@ -116,8 +116,8 @@ func TestAuthHandler_Register_ValidationError(t *testing.T) {
 
 func TestAuthHandler_Login(t *testing.T) {
 	jwtManager := auth.NewJWTManager("test-secret", time.Hour, 24*time.Hour)
-	authService := services.NewAuthService(nil, jwtManager)
-	handler := NewAuthHandler(authService, nil)
+	authService := services.NewAuthService(nil, jwtManager, nil)
+	handler := NewAuthHandler(authService, nil, nil, nil, SessionCookies{})
 
 	reqBody := map[string]interface{}{
 		"email":    "test@example.com",
@@ -138,8 +138,8 @@ func TestAuthHandler_Login(t *testing.T) {
 
 func TestAuthHandler_Refresh(t *testing.T) {
 	jwtManager := auth.NewJWTManager("test-secret", time.Hour, 24*time.Hour)
-	authService := services.NewAuthService(nil, jwtManager)
-	handler := NewAuthHandler(authService, nil)
+	authService := services.NewAuthService(nil, jwtManager, nil)
+	handler := NewAuthHandler(authService, nil, nil, nil, SessionCookies{})
 
 	reqBody := map[string]interface{}{
 		"refresh_token": "some-refresh-token",
@@ -158,8 +158,8 @@ func TestAuthHandler_Refresh(t *testing.T) {
 
 func TestAuthHandler_GetCurrentUser(t *testing.T) {
 	jwtManager := auth.NewJWTManager("test-secret", time.Hour, 24*time.Hour)
-	authService := services.NewAuthService(nil, jwtManager)
-	handler := NewAuthHandler(authService, nil)
+	authService := services.NewAuthService(nil, jwtManager, nil)
+	handler := NewAuthHandler(authService, nil, nil, nil, SessionCookies{})
 
 	req := httptest.NewRequest("GET", "/auth/me", nil)
 	req.Header.Set("Content-Type", "application/json")
@@ -179,8 +179,8 @@ func TestAuthHandler_GetCurrentUser(t *testing.T) {
 
 func TestAuthHandler_Logout(t *testing.T) {
 	jwtManager := auth.NewJWTManager("test-secret", time.Hour, 24*time.Hour)
-	authService := services.NewAuthService(nil, jwtManager)
-	handler := NewAuthHandler(authService, nil)
+	authService := services.NewAuthService(nil, jwtManager, nil)
+	handler := NewAuthHandler(authService, nil, nil, nil, SessionCookies{})
 
 	req := httptest.NewRequest("POST", "/auth/logout", nil)
 	req.Header.Set("Content-Type", "application/json")
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"bailanysta/api/internal/pkg/captcha"
+	"bailanysta/api/internal/pkg/logger"
+)
+
+// CaptchaHeader carries the solved CAPTCHA or proof-of-work token
+const CaptchaHeader = "X-Captcha-Token"
+
+// BotProtection asks clients to prove they're human on registration, on
+// login after repeated failures, and on any route wrapped in Require. A
+// nil *BotProtection lets everything through.
+type BotProtection struct {
+	provider      string
+	siteKey       string
+	verifier      captcha.Verifier
+	pow           *captcha.ProofOfWork // set for the pow provider
+	loginFailures int                  // failures before login needs a check
+	failureWindow time.Duration
+	logger        *logger.Logger
+
+	mu       sync.Mutex
+	failures map[string]*loginFailure // by email and by IP
+}
+
+type loginFailure struct {
+	count   int
+	resetAt time.Time
+}
+
+// NewBotProtection uses verifier, which for the pow provider must be a
+// *captcha.ProofOfWork. siteKey is passed to clients rendering a widget.
+func NewBotProtection(provider, siteKey string, verifier captcha.Verifier, loginFailures int, failureWindow time.Duration, logger *logger.Logger) *BotProtection {
+	pow, _ := verifier.(*captcha.ProofOfWork)
+	return &BotProtection{
+		provider:      provider,
+		siteKey:       siteKey,
+		verifier:      verifier,
+		pow:           pow,
+		loginFailures: loginFailures,
+		failureWindow: failureWindow,
+		logger:        logger,
+		failures:      make(map[string]*loginFailure),
+	}
+}
+
+// GetChallenge tells clients which check to solve: the provider and site
+// key for a widget, or a fresh proof-of-work challenge
+func (b *BotProtection) GetChallenge(w http.ResponseWriter, r *http.Request) {
+	if b == nil {
+		b.respondWithJSON(w, map[string]interface{}{"enabled": false}, http.StatusOK)
+		return
+	}
+
+	response := map[string]interface{}{
+		"enabled":  true,
+		"provider": b.provider,
+		"header":   CaptchaHeader,
+	}
+	if b.pow != nil {
+		challenge, err := b.pow.NewChallenge()
+		if err != nil {
+			b.logger.Error("Failed to create challenge", map[string]interface{}{
+				"error": err.Error(),
+			})
+			b.respondWithError(w, "Failed to create challenge", http.StatusInternalServerError)
+			return
+		}
+		response["challenge"] = challenge
+	} else {
+		response["site_key"] = b.siteKey
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	b.respondWithJSON(w, response, http.StatusOK)
+}
+
+// Require rejects requests without a valid token
+func (b *BotProtection) Require(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !b.check(w, r) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// check verifies the request's token, responding and returning false if
+// it doesn't pass
+func (b *BotProtection) check(w http.ResponseWriter, r *http.Request) bool {
+	if b == nil {
+		return true
+	}
+	err := b.verifier.Verify(r.Context(), r.Header.Get(CaptchaHeader), remoteIP(r))
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, captcha.ErrFailed) {
+		b.respondWithError(w, "Bot check failed; solve the challenge and retry", http.StatusForbidden)
+		return false
+	}
+	b.logger.Error("Failed to verify bot check", map[string]interface{}{
+		"error":    err.Error(),
+		"provider": b.provider,
+	})
+	b.respondWithError(w, "Bot check is unavailable; try again later", http.StatusServiceUnavailable)
+	return false
+}
+
+// checkLogin requires a check once the email or the client's IP has failed
+// to log in loginFailures times within the window
+func (b *BotProtection) checkLogin(w http.ResponseWriter, r *http.Request, email string) bool {
+	if b == nil || b.loginFailures == 0 {
+		return b.check(w, r)
+	}
+
+	b.mu.Lock()
+	needed := b.failureCount("email:"+strings.ToLower(email)) >= b.loginFailures ||
+		b.failureCount("ip:"+remoteIP(r)) >= b.loginFailures
+	b.mu.Unlock()
+	if !needed {
+		return true
+	}
+	return b.check(w, r)
+}
+
+// recordLogin counts a failed login, or forgets the email's failures after
+// a successful one
+func (b *BotProtection) recordLogin(r *http.Request, email string, succeeded bool) {
+	if b == nil {
+		return
+	}
+	keys := []string{"email:" + strings.ToLower(email), "ip:" + remoteIP(r)}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if succeeded {
+		delete(b.failures, keys[0])
+		return
+	}
+
+	now := time.Now()
+	for key, failure := range b.failures {
+		if now.After(failure.resetAt) {
+			delete(b.failures, key)
+		}
+	}
+	for _, key := range keys {
+		failure, ok := b.failures[key]
+		if !ok {
+			failure = &loginFailure{resetAt: now.Add(b.failureWindow)}
+			b.failures[key] = failure
+		}
+		failure.count++
+	}
+}
+
+// failureCount must be called with b.mu held
+func (b *BotProtection) failureCount(key string) int {
+	failure, ok := b.failures[key]
+	if !ok || time.Now().After(failure.resetAt) {
+		return 0
+	}
+	return failure.count
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func (b *BotProtection) respondWithJSON(w http.ResponseWriter, data interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (b *BotProtection) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	b.respondWithJSON(w, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    getErrorCode(statusCode),
+			"message": message,
+		},
+	}, statusCode)
+}
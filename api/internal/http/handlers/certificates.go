@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"bailanysta/api/internal/pkg/auth"
+	"bailanysta/api/internal/pkg/logger"
+	"bailanysta/api/internal/services"
+)
+
+type CertificatesHandler struct {
+	certificateService *services.CertificateService
+	logger             *logger.Logger
+	jwtManager         *auth.JWTManager
+}
+
+func NewCertificatesHandler(certificateService *services.CertificateService, logger *logger.Logger, jwtManager *auth.JWTManager) *CertificatesHandler {
+	return &CertificatesHandler{
+		certificateService: certificateService,
+		logger:             logger,
+		jwtManager:         jwtManager,
+	}
+}
+
+// GetMyCertificates lists the certificates of the courses the user completed
+func (h *CertificatesHandler) GetMyCertificates(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	certificates, err := h.certificateService.GetMyCertificates(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("Failed to get certificates", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		h.respondWithError(w, "Failed to get certificates", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"certificates": certificates,
+	}, http.StatusOK)
+}
+
+// VerifyCertificate checks a certificate code; it needs no sign-in so
+// anyone handed a certificate can check it
+func (h *CertificatesHandler) VerifyCertificate(w http.ResponseWriter, r *http.Request) {
+	certificate, err := h.certificateService.VerifyCertificate(r.Context(), chi.URLParam(r, "code"))
+	if err != nil {
+		if err.Error() == "certificate not found" {
+			h.respondWithError(w, "Certificate not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("Failed to verify certificate", map[string]interface{}{
+			"error": err.Error(),
+		})
+		h.respondWithError(w, "Failed to verify certificate", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"valid":        true,
+		"code":         certificate.Code,
+		"username":     certificate.Username,
+		"course_id":    certificate.CourseID,
+		"course_title": certificate.CourseTitle,
+		"issued_at":    certificate.IssuedAt,
+	}, http.StatusOK)
+}
+
+// DownloadCertificate returns one of the user's certificates as a PDF
+func (h *CertificatesHandler) DownloadCertificate(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	document, certificate, err := h.certificateService.CertificatePDF(r.Context(), userID, chi.URLParam(r, "code"))
+	if err != nil {
+		if err.Error() == "certificate not found" {
+			h.respondWithError(w, "Certificate not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("Failed to render certificate", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		h.respondWithError(w, "Failed to render certificate", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `attachment; filename="certificate-`+certificate.Code+`.pdf"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(document)
+}
+
+func (h *CertificatesHandler) respondWithJSON(w http.ResponseWriter, data interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *CertificatesHandler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	h.respondWithJSON(w, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    getErrorCode(statusCode),
+			"message": message,
+		},
+	}, statusCode)
+}
+
+func (h *CertificatesHandler) getUserIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	return h.jwtManager.GetUserIDFromContext(ctx)
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"bailanysta/api/internal/pkg/auth"
+	"bailanysta/api/internal/pkg/logger"
+	"bailanysta/api/internal/services"
+)
+
+// CoursesHandler serves a student's own work in courses; the course catalog
+// itself is served by SocialHandler
+type CoursesHandler struct {
+	progressService *services.CourseProgressService
+	logger          *logger.Logger
+	jwtManager      *auth.JWTManager
+}
+
+func NewCoursesHandler(progressService *services.CourseProgressService, logger *logger.Logger, jwtManager *auth.JWTManager) *CoursesHandler {
+	return &CoursesHandler{
+		progressService: progressService,
+		logger:          logger,
+		jwtManager:      jwtManager,
+	}
+}
+
+// GetMyCourses lists the courses the user has started, with their progress
+func (h *CoursesHandler) GetMyCourses(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	courses, err := h.progressService.GetMyCourses(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("Failed to get course progress", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		h.respondWithError(w, "Failed to get courses", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"courses": courses,
+	}, http.StatusOK)
+}
+
+// CompleteModule marks a module complete and returns the course's progress
+func (h *CoursesHandler) CompleteModule(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	orgID, err := h.jwtManager.GetOrgIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Organization not found", http.StatusBadRequest)
+		return
+	}
+
+	moduleID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondWithError(w, "Invalid module ID", http.StatusBadRequest)
+		return
+	}
+
+	progress, err := h.progressService.CompleteModule(r.Context(), userID, orgID, moduleID)
+	if err != nil {
+		if err.Error() == "module not found" {
+			h.respondWithError(w, "Module not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("Failed to complete module", map[string]interface{}{
+			"error":     err.Error(),
+			"user_id":   userID,
+			"module_id": moduleID,
+		})
+		h.respondWithError(w, "Failed to complete module", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, progress, http.StatusOK)
+}
+
+// UncompleteModule clears a module the user marked complete by mistake
+func (h *CoursesHandler) UncompleteModule(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	moduleID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondWithError(w, "Invalid module ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.progressService.UncompleteModule(r.Context(), userID, moduleID); err != nil {
+		if err.Error() == "module not completed" {
+			h.respondWithError(w, "Module not completed", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("Failed to clear module progress", map[string]interface{}{
+			"error":     err.Error(),
+			"user_id":   userID,
+			"module_id": moduleID,
+		})
+		h.respondWithError(w, "Failed to clear module progress", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"message": "Module progress cleared",
+	}, http.StatusOK)
+}
+
+func (h *CoursesHandler) respondWithJSON(w http.ResponseWriter, data interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *CoursesHandler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	h.respondWithJSON(w, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    getErrorCode(statusCode),
+			"message": message,
+		},
+	}, statusCode)
+}
+
+func (h *CoursesHandler) getUserIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	return h.jwtManager.GetUserIDFromContext(ctx)
+}
@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+
+	"bailanysta/api/internal/pkg/auth"
+	"bailanysta/api/internal/pkg/logger"
+	"bailanysta/api/internal/services"
+)
+
+type EventsHandler struct {
+	eventService *services.EventService
+	logger       *logger.Logger
+	validator    *validator.Validate
+	jwtManager   *auth.JWTManager
+}
+
+func NewEventsHandler(eventService *services.EventService, logger *logger.Logger, jwtManager *auth.JWTManager) *EventsHandler {
+	return &EventsHandler{
+		eventService: eventService,
+		logger:       logger,
+		validator:    validator.New(),
+		jwtManager:   jwtManager,
+	}
+}
+
+// eventErrors maps service errors to responses; other errors are logged and
+// reported as 500
+var eventErrors = map[string]struct {
+	message string
+	status  int
+}{
+	"event not found":                 {"Event not found", http.StatusNotFound},
+	"course not found":                {"Course not found", http.StatusNotFound},
+	"rsvp not found":                  {"You have not responded to this event", http.StatusNotFound},
+	"access denied":                   {"You cannot create events here", http.StatusForbidden},
+	"event has ended":                 {"Event has ended", http.StatusConflict},
+	"event needs a course or a group": {"Exactly one of course_id and group_id is required", http.StatusBadRequest},
+	"invalid rsvp status":             {"status must be going, maybe or declined", http.StatusBadRequest},
+}
+
+func (h *EventsHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req services.CreateEventRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		h.respondWithError(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	event, err := h.eventService.CreateEvent(r.Context(), userID, req)
+	if err != nil {
+		h.respondWithEventError(w, err, "Failed to create event")
+		return
+	}
+
+	h.respondWithJSON(w, event, http.StatusCreated)
+}
+
+// GetEvents lists upcoming events, optionally of one course_id or group_id;
+// from (RFC 3339) includes events that ended since then
+func (h *EventsHandler) GetEvents(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	filter := services.EventFilter{From: time.Now()}
+	filter.Limit, filter.Offset = pageParams(r)
+	query := r.URL.Query()
+	if raw := query.Get("course_id"); raw != "" {
+		courseID, err := uuid.Parse(raw)
+		if err != nil {
+			h.respondWithError(w, "Invalid course ID", http.StatusBadRequest)
+			return
+		}
+		filter.CourseID = &courseID
+	}
+	if raw := query.Get("group_id"); raw != "" {
+		groupID, err := uuid.Parse(raw)
+		if err != nil {
+			h.respondWithError(w, "Invalid group ID", http.StatusBadRequest)
+			return
+		}
+		filter.GroupID = &groupID
+	}
+	if raw := query.Get("from"); raw != "" {
+		from, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			h.respondWithError(w, "from must be an RFC 3339 time", http.StatusBadRequest)
+			return
+		}
+		filter.From = from
+	}
+
+	events, err := h.eventService.GetEvents(r.Context(), userID, filter)
+	if err != nil {
+		h.respondWithEventError(w, err, "Failed to get events")
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"events": events,
+		"limit":  filter.Limit,
+		"offset": filter.Offset,
+	}, http.StatusOK)
+}
+
+func (h *EventsHandler) GetEvent(w http.ResponseWriter, r *http.Request) {
+	userID, eventID, ok := h.eventRequest(w, r)
+	if !ok {
+		return
+	}
+
+	event, err := h.eventService.GetEvent(r.Context(), eventID, userID)
+	if err != nil {
+		h.respondWithEventError(w, err, "Failed to get event")
+		return
+	}
+
+	h.respondWithJSON(w, event, http.StatusOK)
+}
+
+// ExportEvent returns the event as an .ics file for calendar apps
+func (h *EventsHandler) ExportEvent(w http.ResponseWriter, r *http.Request) {
+	userID, eventID, ok := h.eventRequest(w, r)
+	if !ok {
+		return
+	}
+
+	event, err := h.eventService.GetEvent(r.Context(), eventID, userID)
+	if err != nil {
+		h.respondWithEventError(w, err, "Failed to get event")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="event-`+event.ID.String()+`.ics"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(h.eventService.ICalendar([]*services.Event{event})))
+}
+
+func (h *EventsHandler) SetRSVP(w http.ResponseWriter, r *http.Request) {
+	userID, eventID, ok := h.eventRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.eventService.SetRSVP(r.Context(), eventID, userID, req.Status); err != nil {
+		h.respondWithEventError(w, err, "Failed to save RSVP")
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"event_id": eventID,
+		"status":   req.Status,
+	}, http.StatusOK)
+}
+
+func (h *EventsHandler) RemoveRSVP(w http.ResponseWriter, r *http.Request) {
+	userID, eventID, ok := h.eventRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.eventService.RemoveRSVP(r.Context(), eventID, userID); err != nil {
+		h.respondWithEventError(w, err, "Failed to remove RSVP")
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{"message": "RSVP removed"}, http.StatusOK)
+}
+
+// GetAttendees lists who is going, or with ?status=maybe|declined the other
+// answers
+func (h *EventsHandler) GetAttendees(w http.ResponseWriter, r *http.Request) {
+	userID, eventID, ok := h.eventRequest(w, r)
+	if !ok {
+		return
+	}
+	limit, offset := pageParams(r)
+
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = services.RSVPGoing
+	}
+
+	attendees, err := h.eventService.GetEventAttendees(r.Context(), eventID, userID, status, limit, offset)
+	if err != nil {
+		h.respondWithEventError(w, err, "Failed to get event attendees")
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"attendees": attendees,
+		"limit":     limit,
+		"offset":    offset,
+	}, http.StatusOK)
+}
+
+// eventRequest reads the current user and the {id} event, responding with
+// an error if either is missing
+func (h *EventsHandler) eventRequest(w http.ResponseWriter, r *http.Request) (userID, eventID uuid.UUID, ok bool) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	eventID, err = uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondWithError(w, "Invalid event ID", http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return userID, eventID, true
+}
+
+func (h *EventsHandler) respondWithEventError(w http.ResponseWriter, err error, message string) {
+	if known, ok := eventErrors[err.Error()]; ok {
+		h.respondWithError(w, known.message, known.status)
+		return
+	}
+
+	h.logger.Error(message, map[string]interface{}{
+		"error": err.Error(),
+	})
+	h.respondWithError(w, message, http.StatusInternalServerError)
+}
+
+func (h *EventsHandler) respondWithJSON(w http.ResponseWriter, data interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *EventsHandler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	h.respondWithJSON(w, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    getErrorCode(statusCode),
+			"message": message,
+		},
+	}, statusCode)
+}
+
+func (h *EventsHandler) getUserIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	return h.jwtManager.GetUserIDFromContext(ctx)
+}
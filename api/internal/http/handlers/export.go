@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"bailanysta/api/internal/pkg/auth"
+	"bailanysta/api/internal/pkg/logger"
+	"bailanysta/api/internal/services"
+)
+
+type ExportHandler struct {
+	exportService *services.ExportService
+	logger        *logger.Logger
+	jwtManager    *auth.JWTManager
+}
+
+func NewExportHandler(exportService *services.ExportService, logger *logger.Logger, jwtManager *auth.JWTManager) *ExportHandler {
+	return &ExportHandler{
+		exportService: exportService,
+		logger:        logger,
+		jwtManager:    jwtManager,
+	}
+}
+
+// ExportPosts streams the organization's posts, oldest first, as NDJSON
+// (default) or CSV. Query parameters: format, fields (comma-separated), from
+// and to (RFC 3339), limit, and after, the last seq of a previous export to
+// resume from.
+func (h *ExportHandler) ExportPosts(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	orgID, err := h.jwtManager.GetOrgIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = "ndjson"
+	}
+	if format != "ndjson" && format != "csv" {
+		h.respondWithError(w, "format must be ndjson or csv", http.StatusBadRequest)
+		return
+	}
+
+	fields, err := services.ParseExportFields(query.Get("fields"))
+	if err != nil {
+		h.respondWithError(w, "fields must be a comma-separated list of: "+strings.Join(services.ExportFields, ", "), http.StatusBadRequest)
+		return
+	}
+
+	var filter services.ExportFilter
+	if from := query.Get("from"); from != "" {
+		parsed, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			h.respondWithError(w, "from must be an RFC 3339 time", http.StatusBadRequest)
+			return
+		}
+		filter.From = &parsed
+	}
+	if to := query.Get("to"); to != "" {
+		parsed, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			h.respondWithError(w, "to must be an RFC 3339 time", http.StatusBadRequest)
+			return
+		}
+		filter.To = &parsed
+	}
+	if after := query.Get("after"); after != "" {
+		parsed, err := strconv.ParseInt(after, 10, 64)
+		if err != nil || parsed < 0 {
+			h.respondWithError(w, "after must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		filter.After = parsed
+	}
+	if limit := query.Get("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil || parsed <= 0 {
+			h.respondWithError(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = parsed
+	}
+
+	filename := "posts-" + time.Now().UTC().Format("20060102-150405") + "." + format
+	contentType := "application/x-ndjson"
+	if format == "csv" {
+		contentType = "text/csv; charset=utf-8"
+	}
+
+	rc := http.NewResponseController(w)
+	csvWriter := csv.NewWriter(w)
+	encoder := json.NewEncoder(w)
+	exported := 0
+	started := false
+
+	// Headers go out with the first chunk, so a failing first query still
+	// gets a proper error response
+	start := func() {
+		started = true
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+		w.WriteHeader(http.StatusOK)
+		if format == "csv" {
+			csvWriter.Write(fields)
+			csvWriter.Flush()
+		}
+	}
+
+	err = h.exportService.ExportPosts(r.Context(), orgID, filter, func(posts []*services.ExportPost) error {
+		if !started {
+			start()
+		}
+
+		for _, post := range posts {
+			if format == "csv" {
+				record := make([]string, len(fields))
+				for i, field := range fields {
+					record[i] = post.CSVValue(field)
+				}
+				if err := csvWriter.Write(record); err != nil {
+					return err
+				}
+				continue
+			}
+
+			row := make(map[string]interface{}, len(fields))
+			for _, field := range fields {
+				row[field] = post.Value(field)
+			}
+			if err := encoder.Encode(row); err != nil {
+				return err
+			}
+		}
+		exported += len(posts)
+
+		if format == "csv" {
+			csvWriter.Flush()
+			if err := csvWriter.Error(); err != nil {
+				return err
+			}
+		}
+		rc.Flush()
+		return nil
+	})
+	if err != nil {
+		h.logger.Error("Failed to export posts", map[string]interface{}{
+			"error":    err.Error(),
+			"user_id":  userID,
+			"exported": exported,
+		})
+		// Once streaming has started the client only sees a truncated
+		// export and can resume with after
+		if !started {
+			h.respondWithError(w, "Failed to export posts", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if !started {
+		start()
+	}
+
+	h.logger.Info("Posts exported", map[string]interface{}{
+		"user_id":  userID,
+		"org_id":   orgID,
+		"format":   format,
+		"exported": exported,
+	})
+}
+
+func (h *ExportHandler) respondWithJSON(w http.ResponseWriter, data interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *ExportHandler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	h.respondWithJSON(w, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    getErrorCode(statusCode),
+			"message": message,
+		},
+	}, statusCode)
+}
+
+func (h *ExportHandler) getUserIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	return h.jwtManager.GetUserIDFromContext(ctx)
+}
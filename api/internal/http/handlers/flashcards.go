@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+
+	"bailanysta/api/internal/pkg/auth"
+	"bailanysta/api/internal/pkg/logger"
+	"bailanysta/api/internal/services"
+)
+
+type FlashcardsHandler struct {
+	flashcardService *services.FlashcardService
+	aiService        *services.AIService
+	postsService     *services.PostsService
+	logger           *logger.Logger
+	validator        *validator.Validate
+	jwtManager       *auth.JWTManager
+}
+
+func NewFlashcardsHandler(flashcardService *services.FlashcardService, aiService *services.AIService, postsService *services.PostsService, logger *logger.Logger, jwtManager *auth.JWTManager) *FlashcardsHandler {
+	return &FlashcardsHandler{
+		flashcardService: flashcardService,
+		aiService:        aiService,
+		postsService:     postsService,
+		logger:           logger,
+		validator:        validator.New(),
+		jwtManager:       jwtManager,
+	}
+}
+
+// GenerateFlashcards generates cards from a topic, a post the user can see or
+// their own text and saves them, e.g. {"post_id": "...", "count": 10}
+func (h *FlashcardsHandler) GenerateFlashcards(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req services.GenerateFlashcardsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.validator.Struct(req); err != nil {
+		h.respondWithError(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sources := 0
+	for _, set := range []bool{req.Topic != "", req.PostID != nil, strings.TrimSpace(req.Text) != ""} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		h.respondWithError(w, "Exactly one of topic, post_id or text is required", http.StatusBadRequest)
+		return
+	}
+
+	text := req.Text
+	if req.PostID != nil {
+		post, err := h.postsService.GetPostByID(r.Context(), *req.PostID, userID)
+		if err != nil {
+			h.respondWithError(w, "Post not found", http.StatusNotFound)
+			return
+		}
+		if strings.TrimSpace(post.Text) == "" {
+			h.respondWithError(w, "Post has no text to make flashcards from", http.StatusUnprocessableEntity)
+			return
+		}
+		text = post.Text
+	}
+
+	generated, err := h.aiService.GenerateFlashcards(r.Context(), req.Topic, req.Course, text, req.Count)
+	if err != nil {
+		h.logger.Error("Failed to generate flashcards", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		switch {
+		case errors.Is(err, services.ErrAIResponseBlocked):
+			h.respondWithError(w, "AI response blocked by the safety filter", http.StatusUnprocessableEntity)
+		case err.Error() == "AI returned no flashcards":
+			h.respondWithError(w, "AI returned no flashcards", http.StatusBadGateway)
+		default:
+			h.respondWithError(w, "Failed to generate flashcards: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	cards, err := h.flashcardService.CreateFlashcards(r.Context(), userID, req.Topic, req.PostID, generated.Cards)
+	if err != nil {
+		h.logger.Error("Failed to save flashcards", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		h.respondWithError(w, "Failed to save flashcards", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("Flashcards generated successfully", map[string]interface{}{
+		"user_id":      userID,
+		"count":        len(cards),
+		"total_tokens": generated.Usage.TotalTokens,
+	})
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"flashcards": cards,
+		"model":      generated.Model,
+		"usage":      generated.Usage,
+	}, http.StatusCreated)
+}
+
+// GetFlashcards lists the user's cards, newest first; ?post_id= limits them
+// to cards made from one post
+func (h *FlashcardsHandler) GetFlashcards(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	limit := 50
+	offset := 0
+
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.Atoi(limitParam); err == nil && parsedLimit > 0 && parsedLimit <= 100 {
+			limit = parsedLimit
+		}
+	}
+
+	if offsetParam := r.URL.Query().Get("offset"); offsetParam != "" {
+		if parsedOffset, err := strconv.Atoi(offsetParam); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	var postID *uuid.UUID
+	if postIDParam := r.URL.Query().Get("post_id"); postIDParam != "" {
+		parsed, err := uuid.Parse(postIDParam)
+		if err != nil {
+			h.respondWithError(w, "Invalid post ID", http.StatusBadRequest)
+			return
+		}
+		postID = &parsed
+	}
+
+	cards, err := h.flashcardService.GetFlashcards(r.Context(), userID, postID, limit, offset)
+	if err != nil {
+		h.logger.Error("Failed to get flashcards", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		h.respondWithError(w, "Failed to get flashcards", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"flashcards": cards,
+		"limit":      limit,
+		"offset":     offset,
+	}, http.StatusOK)
+}
+
+// GetDueFlashcards lists the user's cards due for review, most overdue
+// first, with how many are due in all
+func (h *FlashcardsHandler) GetDueFlashcards(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	limit := 20
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.Atoi(limitParam); err == nil && parsedLimit > 0 && parsedLimit <= 100 {
+			limit = parsedLimit
+		}
+	}
+
+	cards, total, err := h.flashcardService.GetDueFlashcards(r.Context(), userID, limit)
+	if err != nil {
+		h.logger.Error("Failed to get due flashcards", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		h.respondWithError(w, "Failed to get flashcards", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"flashcards": cards,
+		"due_count":  total,
+	}, http.StatusOK)
+}
+
+// ReviewFlashcard grades a review of one of the user's cards and returns it
+// with its next due date, e.g. {"grade": 4}
+func (h *FlashcardsHandler) ReviewFlashcard(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	cardID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondWithError(w, "Invalid flashcard ID", http.StatusBadRequest)
+		return
+	}
+
+	var req services.ReviewFlashcardRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.validator.Struct(req); err != nil {
+		h.respondWithError(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	card, err := h.flashcardService.ReviewFlashcard(r.Context(), userID, cardID, *req.Grade)
+	if err != nil {
+		if err.Error() == "flashcard not found" {
+			h.respondWithError(w, "Flashcard not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("Failed to review flashcard", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+			"card_id": cardID,
+		})
+		h.respondWithError(w, "Failed to review flashcard", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, card, http.StatusOK)
+}
+
+// DeleteFlashcard deletes one of the user's cards
+func (h *FlashcardsHandler) DeleteFlashcard(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	cardID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondWithError(w, "Invalid flashcard ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.flashcardService.DeleteFlashcard(r.Context(), userID, cardID); err != nil {
+		if err.Error() == "flashcard not found" {
+			h.respondWithError(w, "Flashcard not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("Failed to delete flashcard", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+			"card_id": cardID,
+		})
+		h.respondWithError(w, "Failed to delete flashcard", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"message": "Flashcard deleted",
+	}, http.StatusOK)
+}
+
+func (h *FlashcardsHandler) respondWithJSON(w http.ResponseWriter, data interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *FlashcardsHandler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	h.respondWithJSON(w, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    getErrorCode(statusCode),
+			"message": message,
+		},
+	}, statusCode)
+}
+
+func (h *FlashcardsHandler) getUserIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	return h.jwtManager.GetUserIDFromContext(ctx)
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"bailanysta/api/internal/pkg/auth"
+	"bailanysta/api/internal/pkg/logger"
+	"bailanysta/api/internal/services"
+)
+
+type GIFsHandler struct {
+	gifService *services.GIFService
+	logger     *logger.Logger
+	jwtManager *auth.JWTManager
+}
+
+func NewGIFsHandler(gifService *services.GIFService, logger *logger.Logger, jwtManager *auth.JWTManager) *GIFsHandler {
+	return &GIFsHandler{
+		gifService: gifService,
+		logger:     logger,
+		jwtManager: jwtManager,
+	}
+}
+
+// Search returns GIFs for ?query=&limit= (default 20, at most 50), or
+// trending GIFs without a query. Attach one to a post or comment with its id
+// as gif_id.
+func (h *GIFsHandler) Search(w http.ResponseWriter, r *http.Request) {
+	if !h.gifService.Enabled() {
+		h.respondWithError(w, "GIF search is not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	query := r.URL.Query().Get("query")
+	if len([]rune(query)) > 100 {
+		h.respondWithError(w, "Query must be at most 100 characters", http.StatusBadRequest)
+		return
+	}
+
+	limit := 20
+	if parsed, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && parsed > 0 && parsed <= 50 {
+		limit = parsed
+	}
+
+	results, err := h.gifService.Search(r.Context(), query, limit)
+	if err != nil {
+		h.logger.Error("Failed to search GIFs", map[string]interface{}{
+			"error": err.Error(),
+			"query": query,
+		})
+		h.respondWithError(w, "Failed to search GIFs", http.StatusBadGateway)
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"gifs":  results,
+		"query": query,
+	}, http.StatusOK)
+}
+
+func (h *GIFsHandler) respondWithJSON(w http.ResponseWriter, data interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *GIFsHandler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	h.respondWithJSON(w, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    getErrorCode(statusCode),
+			"message": message,
+		},
+	}, statusCode)
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/google/uuid"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	"bailanysta/api/internal/graph"
+	"bailanysta/api/internal/pkg/auth"
+	"bailanysta/api/internal/pkg/logger"
+)
+
+type GraphQLHandler struct {
+	resolver   *graph.Resolver
+	server     *handler.Server
+	logger     *logger.Logger
+	jwtManager *auth.JWTManager
+}
+
+func NewGraphQLHandler(resolver *graph.Resolver, complexityLimit int, logger *logger.Logger, jwtManager *auth.JWTManager) *GraphQLHandler {
+	h := &GraphQLHandler{
+		resolver:   resolver,
+		logger:     logger,
+		jwtManager: jwtManager,
+	}
+
+	srv := handler.New(graph.NewExecutableSchema(graph.Config{Resolvers: resolver}))
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+	srv.SetQueryCache(lru.New[*ast.QueryDocument](1000))
+	srv.Use(extension.Introspection{})
+	srv.Use(extension.FixedComplexityLimit(complexityLimit))
+	srv.SetErrorPresenter(h.presentError)
+	h.server = srv
+
+	return h
+}
+
+// ServeGraphQL executes a query for the current user
+func (h *GraphQLHandler) ServeGraphQL(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx := h.resolver.WithLoaders(r.Context(), userID)
+	h.server.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// presentError passes query and argument errors through but hides service
+// failures, which may carry SQL details, behind a generic message
+func (h *GraphQLHandler) presentError(ctx context.Context, err error) *gqlerror.Error {
+	var gqlErr *gqlerror.Error
+	if errors.As(err, &gqlErr) {
+		return graphql.DefaultErrorPresenter(ctx, err)
+	}
+
+	h.logger.Error("Failed to resolve GraphQL field", map[string]interface{}{
+		"error": err.Error(),
+		"path":  graphql.GetPath(ctx).String(),
+	})
+	return gqlerror.ErrorPathf(graphql.GetPath(ctx), "Internal server error")
+}
+
+func (h *GraphQLHandler) respondWithJSON(w http.ResponseWriter, data interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *GraphQLHandler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	h.respondWithJSON(w, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    getErrorCode(statusCode),
+			"message": message,
+		},
+	}, statusCode)
+}
+
+func (h *GraphQLHandler) getUserIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	return h.jwtManager.GetUserIDFromContext(ctx)
+}
@@ -0,0 +1,346 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+
+	"bailanysta/api/internal/pkg/auth"
+	"bailanysta/api/internal/pkg/logger"
+	"bailanysta/api/internal/services"
+)
+
+type GroupsHandler struct {
+	socialService *services.SocialService
+	logger        *logger.Logger
+	validator     *validator.Validate
+	jwtManager    *auth.JWTManager
+}
+
+func NewGroupsHandler(socialService *services.SocialService, logger *logger.Logger, jwtManager *auth.JWTManager) *GroupsHandler {
+	return &GroupsHandler{
+		socialService: socialService,
+		logger:        logger,
+		validator:     validator.New(),
+		jwtManager:    jwtManager,
+	}
+}
+
+// groupErrors maps service errors to responses; other errors are logged and
+// reported as 500
+var groupErrors = map[string]struct {
+	message string
+	status  int
+}{
+	"group not found":          {"Group not found", http.StatusNotFound},
+	"member not found":         {"Member not found", http.StatusNotFound},
+	"join request not found":   {"Join request not found", http.StatusNotFound},
+	"user not found":           {"User not found", http.StatusNotFound},
+	"not a group member":       {"You are not a member of this group", http.StatusNotFound},
+	"access denied":            {"Only group admins can do this", http.StatusForbidden},
+	"invitation required":      {"This group is invite-only", http.StatusForbidden},
+	"group owner cannot leave": {"The group owner cannot leave the group", http.StatusConflict},
+	"already invited":          {"User is already invited or a member", http.StatusConflict},
+	"invalid group role":       {"role must be admin or member", http.StatusBadRequest},
+	"invalid member status":    {"status must be active, requested or invited", http.StatusBadRequest},
+}
+
+func (h *GroupsHandler) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req services.CreateGroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		h.respondWithError(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	group, err := h.socialService.CreateGroup(r.Context(), userID, req)
+	if err != nil {
+		h.respondWithGroupError(w, err, "Failed to create group")
+		return
+	}
+
+	h.respondWithJSON(w, group, http.StatusCreated)
+}
+
+func (h *GroupsHandler) GetGroups(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	groups, err := h.socialService.GetGroups(r.Context(), userID)
+	if err != nil {
+		h.respondWithGroupError(w, err, "Failed to get groups")
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{"groups": groups}, http.StatusOK)
+}
+
+func (h *GroupsHandler) GetGroup(w http.ResponseWriter, r *http.Request) {
+	userID, groupID, ok := h.groupRequest(w, r)
+	if !ok {
+		return
+	}
+
+	group, err := h.socialService.GetGroup(r.Context(), groupID, userID)
+	if err != nil {
+		h.respondWithGroupError(w, err, "Failed to get group")
+		return
+	}
+
+	h.respondWithJSON(w, group, http.StatusOK)
+}
+
+func (h *GroupsHandler) GetGroupFeed(w http.ResponseWriter, r *http.Request) {
+	userID, groupID, ok := h.groupRequest(w, r)
+	if !ok {
+		return
+	}
+	limit, offset := pageParams(r)
+
+	posts, err := h.socialService.GetGroupFeed(r.Context(), groupID, userID, limit, offset)
+	if err != nil {
+		h.respondWithGroupError(w, err, "Failed to get group feed")
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"posts":  posts,
+		"limit":  limit,
+		"offset": offset,
+	}, http.StatusOK)
+}
+
+// JoinGroup joins, asks to join or accepts an invitation, depending on the
+// group's privacy
+func (h *GroupsHandler) JoinGroup(w http.ResponseWriter, r *http.Request) {
+	userID, groupID, ok := h.groupRequest(w, r)
+	if !ok {
+		return
+	}
+
+	membership, err := h.socialService.JoinGroup(r.Context(), groupID, userID)
+	if err != nil {
+		h.respondWithGroupError(w, err, "Failed to join group")
+		return
+	}
+
+	h.respondWithJSON(w, membership, http.StatusOK)
+}
+
+// LeaveGroup also withdraws a join request or declines an invitation
+func (h *GroupsHandler) LeaveGroup(w http.ResponseWriter, r *http.Request) {
+	userID, groupID, ok := h.groupRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.socialService.LeaveGroup(r.Context(), groupID, userID); err != nil {
+		h.respondWithGroupError(w, err, "Failed to leave group")
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{"message": "Left group"}, http.StatusOK)
+}
+
+// GetGroupMembers lists members, or with ?status=requested|invited the
+// pending requests and invitations
+func (h *GroupsHandler) GetGroupMembers(w http.ResponseWriter, r *http.Request) {
+	userID, groupID, ok := h.groupRequest(w, r)
+	if !ok {
+		return
+	}
+	limit, offset := pageParams(r)
+
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = services.GroupMemberActive
+	}
+
+	members, err := h.socialService.GetGroupMembers(r.Context(), groupID, userID, status, limit, offset)
+	if err != nil {
+		h.respondWithGroupError(w, err, "Failed to get group members")
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"members": members,
+		"limit":   limit,
+		"offset":  offset,
+	}, http.StatusOK)
+}
+
+func (h *GroupsHandler) InviteToGroup(w http.ResponseWriter, r *http.Request) {
+	userID, groupID, ok := h.groupRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		UserID uuid.UUID `json:"user_id" validate:"required"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		h.respondWithError(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.socialService.InviteToGroup(r.Context(), groupID, userID, req.UserID); err != nil {
+		h.respondWithGroupError(w, err, "Failed to invite to group")
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{"message": "Invitation sent"}, http.StatusOK)
+}
+
+func (h *GroupsHandler) ApproveJoinRequest(w http.ResponseWriter, r *http.Request) {
+	userID, groupID, memberID, ok := h.memberRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.socialService.ApproveJoinRequest(r.Context(), groupID, userID, memberID); err != nil {
+		h.respondWithGroupError(w, err, "Failed to approve join request")
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{"message": "Join request approved"}, http.StatusOK)
+}
+
+// RemoveGroupMember also declines a join request or withdraws an invitation
+func (h *GroupsHandler) RemoveGroupMember(w http.ResponseWriter, r *http.Request) {
+	userID, groupID, memberID, ok := h.memberRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.socialService.RemoveGroupMember(r.Context(), groupID, userID, memberID); err != nil {
+		h.respondWithGroupError(w, err, "Failed to remove group member")
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{"message": "Member removed"}, http.StatusOK)
+}
+
+func (h *GroupsHandler) SetGroupMemberRole(w http.ResponseWriter, r *http.Request) {
+	userID, groupID, memberID, ok := h.memberRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Role services.GroupRole `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.socialService.SetGroupMemberRole(r.Context(), groupID, userID, memberID, req.Role); err != nil {
+		h.respondWithGroupError(w, err, "Failed to update group role")
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"user_id": memberID,
+		"role":    req.Role,
+	}, http.StatusOK)
+}
+
+// groupRequest reads the current user and the {id} group, responding with
+// an error if either is missing
+func (h *GroupsHandler) groupRequest(w http.ResponseWriter, r *http.Request) (userID, groupID uuid.UUID, ok bool) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	groupID, err = uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondWithError(w, "Invalid group ID", http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return userID, groupID, true
+}
+
+// memberRequest is groupRequest plus the {userID} member
+func (h *GroupsHandler) memberRequest(w http.ResponseWriter, r *http.Request) (userID, groupID, memberID uuid.UUID, ok bool) {
+	userID, groupID, ok = h.groupRequest(w, r)
+	if !ok {
+		return uuid.Nil, uuid.Nil, uuid.Nil, false
+	}
+
+	memberID, err := uuid.Parse(chi.URLParam(r, "userID"))
+	if err != nil {
+		h.respondWithError(w, "Invalid user ID", http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, uuid.Nil, false
+	}
+
+	return userID, groupID, memberID, true
+}
+
+func (h *GroupsHandler) respondWithGroupError(w http.ResponseWriter, err error, message string) {
+	if known, ok := groupErrors[err.Error()]; ok {
+		h.respondWithError(w, known.message, known.status)
+		return
+	}
+
+	h.logger.Error(message, map[string]interface{}{
+		"error": err.Error(),
+	})
+	h.respondWithError(w, message, http.StatusInternalServerError)
+}
+
+// pageParams reads limit (1-100, default 20) and offset from the query
+func pageParams(r *http.Request) (limit, offset int) {
+	limit = 20
+	if parsed, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && parsed > 0 && parsed <= 100 {
+		limit = parsed
+	}
+	if parsed, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && parsed >= 0 {
+		offset = parsed
+	}
+	return limit, offset
+}
+
+func (h *GroupsHandler) respondWithJSON(w http.ResponseWriter, data interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *GroupsHandler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	h.respondWithJSON(w, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    getErrorCode(statusCode),
+			"message": message,
+		},
+	}, statusCode)
+}
+
+func (h *GroupsHandler) getUserIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	return h.jwtManager.GetUserIDFromContext(ctx)
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+
+	"bailanysta/api/internal/pkg/auth"
+	"bailanysta/api/internal/pkg/logger"
+	"bailanysta/api/internal/services"
+)
+
+type ImpersonationHandler struct {
+	impersonationService *services.ImpersonationService
+	logger               *logger.Logger
+	validator            *validator.Validate
+	jwtManager           *auth.JWTManager
+}
+
+func NewImpersonationHandler(impersonationService *services.ImpersonationService, logger *logger.Logger, jwtManager *auth.JWTManager) *ImpersonationHandler {
+	return &ImpersonationHandler{
+		impersonationService: impersonationService,
+		logger:               logger,
+		validator:            validator.New(),
+		jwtManager:           jwtManager,
+	}
+}
+
+// StartImpersonation issues a short-lived token acting as the user. The
+// reason is kept with the session for the audit log.
+func (h *ImpersonationHandler) StartImpersonation(w http.ResponseWriter, r *http.Request) {
+	adminID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	orgID, err := h.jwtManager.GetOrgIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	// Sessions can't be chained, nor started with a key that may have leaked
+	if _, impersonated := r.Context().Value("impersonation").(*services.ImpersonationInfo); impersonated {
+		h.respondWithError(w, "Not allowed while impersonating", http.StatusForbidden)
+		return
+	}
+	if _, viaKey := r.Context().Value("api_key_id").(string); viaKey {
+		h.respondWithError(w, "API keys cannot start impersonation", http.StatusForbidden)
+		return
+	}
+
+	userID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondWithError(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req services.ImpersonateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.validator.Struct(req); err != nil {
+		h.respondWithError(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	token, err := h.impersonationService.Start(r.Context(), orgID, adminID, userID, req)
+	if err != nil {
+		switch err.Error() {
+		case "cannot impersonate yourself":
+			h.respondWithError(w, "Cannot impersonate yourself", http.StatusBadRequest)
+		case "cannot impersonate an admin":
+			h.respondWithError(w, "Cannot impersonate an admin", http.StatusForbidden)
+		case "user not found":
+			h.respondWithError(w, "User not found", http.StatusNotFound)
+		default:
+			h.logger.Error("Failed to start impersonation", map[string]interface{}{
+				"error":    err.Error(),
+				"admin_id": adminID,
+				"user_id":  userID,
+			})
+			h.respondWithError(w, "Failed to start impersonation", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.logger.Warn("Impersonation started", map[string]interface{}{
+		"admin_id":   adminID,
+		"user_id":    userID,
+		"session_id": token.SessionID,
+		"can_write":  token.CanWrite,
+		"reason":     req.Reason,
+	})
+
+	h.respondWithJSON(w, token, http.StatusCreated)
+}
+
+// GetImpersonations lists recent sessions with the requests made in them,
+// optionally only those of ?user_id=
+func (h *ImpersonationHandler) GetImpersonations(w http.ResponseWriter, r *http.Request) {
+	orgID, err := h.jwtManager.GetOrgIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var userID *uuid.UUID
+	if raw := r.URL.Query().Get("user_id"); raw != "" {
+		parsed, err := uuid.Parse(raw)
+		if err != nil {
+			h.respondWithError(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+		userID = &parsed
+	}
+
+	limit := 20
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed > 0 && parsed <= 100 {
+			limit = parsed
+		}
+	}
+
+	sessions, err := h.impersonationService.GetSessions(r.Context(), orgID, userID, limit)
+	if err != nil {
+		h.logger.Error("Failed to get impersonation sessions", map[string]interface{}{
+			"error": err.Error(),
+		})
+		h.respondWithError(w, "Failed to get impersonation sessions", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"sessions": sessions,
+	}, http.StatusOK)
+}
+
+func (h *ImpersonationHandler) respondWithJSON(w http.ResponseWriter, data interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *ImpersonationHandler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	h.respondWithJSON(w, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    getErrorCode(statusCode),
+			"message": message,
+		},
+	}, statusCode)
+}
+
+func (h *ImpersonationHandler) getUserIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	return h.jwtManager.GetUserIDFromContext(ctx)
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+
+	"bailanysta/api/internal/pkg/auth"
+	"bailanysta/api/internal/pkg/logger"
+	"bailanysta/api/internal/services"
+)
+
+type IPBlocksHandler struct {
+	blocklist  *services.IPBlocklistService
+	logger     *logger.Logger
+	jwtManager *auth.JWTManager
+	validator  *validator.Validate
+}
+
+func NewIPBlocksHandler(blocklist *services.IPBlocklistService, logger *logger.Logger, jwtManager *auth.JWTManager) *IPBlocksHandler {
+	return &IPBlocksHandler{
+		blocklist:  blocklist,
+		logger:     logger,
+		jwtManager: jwtManager,
+		validator:  validator.New(),
+	}
+}
+
+// GetIPBlocks lists the IP and ASN blocks in force, newest first
+func (h *IPBlocksHandler) GetIPBlocks(w http.ResponseWriter, r *http.Request) {
+	blocks, err := h.blocklist.List(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to get IP blocks", map[string]interface{}{
+			"error": err.Error(),
+		})
+		h.respondWithError(w, "Failed to get IP blocks", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{"blocks": blocks}, http.StatusOK)
+}
+
+// CreateIPBlock blocks an IP, CIDR range or ASN, e.g.
+// {"cidr": "203.0.113.0/24", "reason": "scraper", "ttl_seconds": 86400}
+func (h *IPBlocksHandler) CreateIPBlock(w http.ResponseWriter, r *http.Request) {
+	adminID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req services.CreateIPBlockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.validator.Struct(req); err != nil {
+		h.respondWithError(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	block, err := h.blocklist.Create(r.Context(), adminID, req)
+	if err != nil {
+		switch err.Error() {
+		case "cidr or asn is required":
+			h.respondWithError(w, "Either cidr or asn is required", http.StatusBadRequest)
+		case "invalid cidr":
+			h.respondWithError(w, "cidr must be an IP address or CIDR range", http.StatusBadRequest)
+		case "asn lookups are not configured":
+			h.respondWithError(w, "ASN blocks need GEOIP_ASN_DB_PATH", http.StatusBadRequest)
+		default:
+			h.logger.Error("Failed to create IP block", map[string]interface{}{
+				"error": err.Error(),
+			})
+			h.respondWithError(w, "Failed to create IP block", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.logger.Info("IP block created", map[string]interface{}{
+		"block_id": block.ID,
+		"cidr":     block.CIDR,
+		"asn":      block.ASN,
+		"admin_id": adminID,
+	})
+	h.respondWithJSON(w, block, http.StatusCreated)
+}
+
+// DeleteIPBlock lifts a block
+func (h *IPBlocksHandler) DeleteIPBlock(w http.ResponseWriter, r *http.Request) {
+	adminID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	blockID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondWithError(w, "Invalid IP block ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.blocklist.Delete(r.Context(), blockID); err != nil {
+		if err.Error() == "ip block not found" {
+			h.respondWithError(w, "IP block not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("Failed to delete IP block", map[string]interface{}{
+			"error":    err.Error(),
+			"block_id": blockID,
+		})
+		h.respondWithError(w, "Failed to delete IP block", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("IP block deleted", map[string]interface{}{
+		"block_id": blockID,
+		"admin_id": adminID,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *IPBlocksHandler) respondWithJSON(w http.ResponseWriter, data interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *IPBlocksHandler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	h.respondWithJSON(w, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    getErrorCode(statusCode),
+			"message": message,
+		},
+	}, statusCode)
+}
+
+func (h *IPBlocksHandler) getUserIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	return h.jwtManager.GetUserIDFromContext(ctx)
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"bailanysta/api/internal/pkg/auth"
+	"bailanysta/api/internal/pkg/logger"
+	"bailanysta/api/internal/services"
+)
+
+type LeaderboardHandler struct {
+	leaderboardService *services.LeaderboardService
+	logger             *logger.Logger
+	jwtManager         *auth.JWTManager
+}
+
+func NewLeaderboardHandler(leaderboardService *services.LeaderboardService, logger *logger.Logger, jwtManager *auth.JWTManager) *LeaderboardHandler {
+	return &LeaderboardHandler{
+		leaderboardService: leaderboardService,
+		logger:             logger,
+		jwtManager:         jwtManager,
+	}
+}
+
+// GetLeaderboard returns the top users of the caller's organization, as
+// ?scope=org|course:{id}&period=day|week|all&limit=N (defaults org, week, 10)
+func (h *LeaderboardHandler) GetLeaderboard(w http.ResponseWriter, r *http.Request) {
+	orgID, err := h.jwtManager.GetOrgIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	scope, err := services.ParseLeaderboardScope(query.Get("scope"))
+	if err != nil {
+		h.respondWithError(w, "scope must be org or course:{id}", http.StatusBadRequest)
+		return
+	}
+
+	period := query.Get("period")
+	if period == "" {
+		period = services.LeaderboardWeek
+	}
+
+	limit := 10
+	if parsed, err := strconv.Atoi(query.Get("limit")); err == nil && parsed > 0 && parsed <= 100 {
+		limit = parsed
+	}
+
+	entries, err := h.leaderboardService.GetLeaderboard(r.Context(), orgID, scope, period, limit)
+	if err != nil {
+		switch err.Error() {
+		case "invalid leaderboard period":
+			h.respondWithError(w, "period must be day, week or all", http.StatusBadRequest)
+		case "course not found":
+			h.respondWithError(w, "Course not found", http.StatusNotFound)
+		default:
+			h.logger.Error("Failed to get leaderboard", map[string]interface{}{
+				"error": err.Error(),
+			})
+			h.respondWithError(w, "Failed to get leaderboard", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"entries": entries,
+		"period":  period,
+	}, http.StatusOK)
+}
+
+// GetMyReputation returns the caller's reputation and how much of it is
+// free to offer as question bounties
+func (h *LeaderboardHandler) GetMyReputation(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.jwtManager.GetUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	reputation, err := h.leaderboardService.GetReputation(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("Failed to get reputation", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		h.respondWithError(w, "Failed to get reputation", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, reputation, http.StatusOK)
+}
+
+func (h *LeaderboardHandler) respondWithJSON(w http.ResponseWriter, data interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *LeaderboardHandler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	h.respondWithJSON(w, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    getErrorCode(statusCode),
+			"message": message,
+		},
+	}, statusCode)
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"bailanysta/api/internal/pkg/auth"
+	"bailanysta/api/internal/pkg/logger"
+	"bailanysta/api/internal/services"
+)
+
+// RateLimitStatus is the caller's request bucket after the current request.
+// The rate limit middleware puts it into the request context as "rate_limit".
+type RateLimitStatus struct {
+	Limit      int           `json:"limit"`
+	Remaining  int           `json:"remaining"`
+	ResetAt    time.Time     `json:"reset_at"`
+	RetryAfter time.Duration `json:"-"`
+}
+
+type LimitsHandler struct {
+	quotaService *services.AIQuotaService
+	logger       *logger.Logger
+	jwtManager   *auth.JWTManager
+}
+
+func NewLimitsHandler(quotaService *services.AIQuotaService, logger *logger.Logger, jwtManager *auth.JWTManager) *LimitsHandler {
+	return &LimitsHandler{
+		quotaService: quotaService,
+		logger:       logger,
+		jwtManager:   jwtManager,
+	}
+}
+
+// GetMyLimits returns the caller's rate limit and AI quota status
+func (h *LimitsHandler) GetMyLimits(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	quota, err := h.quotaService.Status(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("Failed to get AI quota", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		h.respondWithError(w, "Failed to get limits", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"ai_quota": quota,
+	}
+	if rateLimit, ok := r.Context().Value("rate_limit").(RateLimitStatus); ok {
+		response["rate_limit"] = rateLimit
+	}
+
+	h.respondWithJSON(w, response, http.StatusOK)
+}
+
+func (h *LimitsHandler) respondWithJSON(w http.ResponseWriter, data interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *LimitsHandler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	h.respondWithJSON(w, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    getErrorCode(statusCode),
+			"message": message,
+		},
+	}, statusCode)
+}
+
+func (h *LimitsHandler) getUserIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	return h.jwtManager.GetUserIDFromContext(ctx)
+}
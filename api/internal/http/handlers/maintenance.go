@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"bailanysta/api/internal/pkg/auth"
+	"bailanysta/api/internal/pkg/logger"
+)
+
+// MaintenanceStatus is what clients are told while the API is down
+type MaintenanceStatus struct {
+	Enabled    bool          `json:"enabled"`
+	Message    string        `json:"message"`
+	RetryAfter time.Duration `json:"-"`
+	Since      *time.Time    `json:"since,omitempty"`
+}
+
+// MaintenanceMode holds whether the API is in maintenance. It starts from
+// MAINTENANCE_MODE and can be switched by admins or a config reload; the
+// switch is per instance and lost on restart.
+type MaintenanceMode struct {
+	mu         sync.RWMutex
+	status     MaintenanceStatus
+	configured MaintenanceStatus // as last read from config
+	hasConfig  bool
+}
+
+func NewMaintenanceMode(enabled bool, message string, retryAfter time.Duration) *MaintenanceMode {
+	m := &MaintenanceMode{}
+	m.ApplyConfig(enabled, message, retryAfter)
+	return m
+}
+
+// Status returns the current state
+func (m *MaintenanceMode) Status() MaintenanceStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.status
+}
+
+// Set switches maintenance on or off
+func (m *MaintenanceMode) Set(enabled bool, message string, retryAfter time.Duration) MaintenanceStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if enabled && !m.status.Enabled {
+		now := time.Now()
+		m.status.Since = &now
+	}
+	if !enabled {
+		m.status.Since = nil
+	}
+	m.status.Enabled = enabled
+	m.status.Message = message
+	m.status.RetryAfter = retryAfter
+	return m.status
+}
+
+// ApplyConfig applies the config's settings if they changed since the last
+// time, so a reload doesn't undo a switch made through the API
+func (m *MaintenanceMode) ApplyConfig(enabled bool, message string, retryAfter time.Duration) {
+	configured := MaintenanceStatus{Enabled: enabled, Message: message, RetryAfter: retryAfter}
+
+	m.mu.Lock()
+	changed := !m.hasConfig || m.configured != configured
+	m.configured = configured
+	m.hasConfig = true
+	m.mu.Unlock()
+
+	if changed {
+		m.Set(enabled, message, retryAfter)
+	}
+}
+
+type MaintenanceHandler struct {
+	mode       *MaintenanceMode
+	logger     *logger.Logger
+	jwtManager *auth.JWTManager
+}
+
+func NewMaintenanceHandler(mode *MaintenanceMode, logger *logger.Logger, jwtManager *auth.JWTManager) *MaintenanceHandler {
+	return &MaintenanceHandler{
+		mode:       mode,
+		logger:     logger,
+		jwtManager: jwtManager,
+	}
+}
+
+type maintenanceResponse struct {
+	MaintenanceStatus
+	RetryAfterSeconds int `json:"retry_after_seconds"`
+}
+
+// GetMaintenance reports whether maintenance mode is on
+func (h *MaintenanceHandler) GetMaintenance(w http.ResponseWriter, r *http.Request) {
+	h.respondWithStatus(w, h.mode.Status())
+}
+
+// UpdateMaintenance switches maintenance mode, e.g. {"enabled": true,
+// "message": "Upgrading the database", "retry_after_seconds": 600}. Omitted
+// fields keep their value.
+func (h *MaintenanceHandler) UpdateMaintenance(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Enabled           bool    `json:"enabled"`
+		Message           *string `json:"message"`
+		RetryAfterSeconds *int    `json:"retry_after_seconds"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	current := h.mode.Status()
+	message, retryAfter := current.Message, current.RetryAfter
+	if req.Message != nil && *req.Message != "" {
+		message = *req.Message
+	}
+	if req.RetryAfterSeconds != nil {
+		if *req.RetryAfterSeconds <= 0 {
+			h.respondWithError(w, "retry_after_seconds must be positive", http.StatusBadRequest)
+			return
+		}
+		retryAfter = time.Duration(*req.RetryAfterSeconds) * time.Second
+	}
+
+	userID, _ := h.jwtManager.GetUserIDFromContext(r.Context())
+	status := h.mode.Set(req.Enabled, message, retryAfter)
+
+	h.logger.Warn("Maintenance mode changed", map[string]interface{}{
+		"enabled": status.Enabled,
+		"message": status.Message,
+		"user_id": userID,
+	})
+
+	h.respondWithStatus(w, status)
+}
+
+func (h *MaintenanceHandler) respondWithStatus(w http.ResponseWriter, status MaintenanceStatus) {
+	h.respondWithJSON(w, maintenanceResponse{
+		MaintenanceStatus: status,
+		RetryAfterSeconds: int(status.RetryAfter.Seconds()),
+	}, http.StatusOK)
+}
+
+func (h *MaintenanceHandler) respondWithJSON(w http.ResponseWriter, data interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *MaintenanceHandler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	h.respondWithJSON(w, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    getErrorCode(statusCode),
+			"message": message,
+		},
+	}, statusCode)
+}
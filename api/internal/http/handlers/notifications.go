@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 
 	"bailanysta/api/internal/pkg/auth"
@@ -16,14 +18,18 @@ import (
 
 type NotificationsHandler struct {
 	notificationsService *services.NotificationService
+	emailDigestService   *services.EmailDigestService
 	logger               *logger.Logger
+	validator            *validator.Validate
 	jwtManager           *auth.JWTManager
 }
 
-func NewNotificationsHandler(notificationsService *services.NotificationService, logger *logger.Logger, jwtManager *auth.JWTManager) *NotificationsHandler {
+func NewNotificationsHandler(notificationsService *services.NotificationService, emailDigestService *services.EmailDigestService, logger *logger.Logger, jwtManager *auth.JWTManager) *NotificationsHandler {
 	return &NotificationsHandler{
 		notificationsService: notificationsService,
+		emailDigestService:   emailDigestService,
 		logger:               logger,
+		validator:            validator.New(),
 		jwtManager:           jwtManager,
 	}
 }
@@ -140,6 +146,44 @@ func (h *NotificationsHandler) MarkAsRead(w http.ResponseWriter, r *http.Request
 	}, http.StatusOK)
 }
 
+// GetTarget resolves a notification to the resource a client should open.
+// ?mark_read=true also marks it read.
+func (h *NotificationsHandler) GetTarget(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	notificationID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondWithError(w, "Invalid notification ID", http.StatusBadRequest)
+		return
+	}
+
+	markRead := r.URL.Query().Get("mark_read") == "true"
+
+	target, err := h.notificationsService.ResolveTarget(r.Context(), notificationID, userID, markRead)
+	if err != nil {
+		switch err.Error() {
+		case "notification not found":
+			h.respondWithError(w, "Notification not found", http.StatusNotFound)
+		case "notification target not found":
+			h.respondWithError(w, "The notification's target no longer exists", http.StatusNotFound)
+		default:
+			h.logger.Error("Failed to resolve notification target", map[string]interface{}{
+				"error":           err.Error(),
+				"user_id":         userID,
+				"notification_id": notificationID,
+			})
+			h.respondWithError(w, "Failed to resolve notification target", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.respondWithJSON(w, target, http.StatusOK)
+}
+
 func (h *NotificationsHandler) MarkAllAsRead(w http.ResponseWriter, r *http.Request) {
 	userID, err := h.getUserIDFromContext(r.Context())
 	if err != nil {
@@ -223,6 +267,186 @@ func (h *NotificationsHandler) DeleteNotification(w http.ResponseWriter, r *http
 	}, http.StatusOK)
 }
 
+// GetVAPIDPublicKey returns the key browsers need to create a push subscription
+func (h *NotificationsHandler) GetVAPIDPublicKey(w http.ResponseWriter, r *http.Request) {
+	if !h.notificationsService.PushEnabled() {
+		h.respondWithError(w, "Push notifications are not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"public_key": h.notificationsService.VAPIDPublicKey(),
+	}, http.StatusOK)
+}
+
+func (h *NotificationsHandler) SubscribePush(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if !h.notificationsService.PushEnabled() {
+		h.respondWithError(w, "Push notifications are not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req services.PushSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		h.respondWithError(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.notificationsService.SavePushSubscription(r.Context(), userID, req); err != nil {
+		h.logger.Error("Failed to save push subscription", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		h.respondWithError(w, "Failed to save push subscription", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("Push subscription saved", map[string]interface{}{
+		"user_id": userID,
+	})
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"message": "Push subscription saved",
+	}, http.StatusCreated)
+}
+
+func (h *NotificationsHandler) UnsubscribePush(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Endpoint string `json:"endpoint" validate:"required"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		h.respondWithError(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = h.notificationsService.DeletePushSubscription(r.Context(), userID, req.Endpoint)
+	if err != nil {
+		if err.Error() == "subscription not found" {
+			h.respondWithError(w, "Subscription not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("Failed to delete push subscription", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		h.respondWithError(w, "Failed to delete push subscription", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"message": "Push subscription deleted",
+	}, http.StatusOK)
+}
+
+func (h *NotificationsHandler) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	settings, err := h.notificationsService.GetNotificationSettings(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("Failed to get notification preferences", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		h.respondWithError(w, "Failed to get notification preferences", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, settings, http.StatusOK)
+}
+
+func (h *NotificationsHandler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req services.UpdateNotificationPreferencesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		h.respondWithError(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.notificationsService.UpdateNotificationSettings(r.Context(), userID, req); err != nil {
+		if strings.HasPrefix(err.Error(), "unknown notification type") {
+			h.respondWithError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.logger.Error("Failed to update notification preferences", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		h.respondWithError(w, "Failed to update notification preferences", http.StatusInternalServerError)
+		return
+	}
+
+	h.GetPreferences(w, r)
+}
+
+// UnsubscribeEmail handles digest unsubscribe links (GET) and one-click
+// unsubscribe from mail clients (POST); the signed token replaces auth
+func (h *NotificationsHandler) UnsubscribeEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		h.respondWithError(w, "Token is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.emailDigestService.Unsubscribe(r.Context(), token); err != nil {
+		if err.Error() == "invalid token" {
+			h.respondWithError(w, "Invalid unsubscribe link", http.StatusBadRequest)
+			return
+		}
+		h.logger.Error("Failed to unsubscribe from email digest", map[string]interface{}{
+			"error": err.Error(),
+		})
+		h.respondWithError(w, "Failed to unsubscribe", http.StatusInternalServerError)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("<!DOCTYPE html><html><body style=\"font-family: Arial, sans-serif;\">" +
+			"<p>You have been unsubscribed from Bailanysta email digests.</p></body></html>"))
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"message": "Unsubscribed from email digests",
+	}, http.StatusOK)
+}
+
 func (h *NotificationsHandler) respondWithJSON(w http.ResponseWriter, data interface{}, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+
+	"bailanysta/api/internal/pkg/auth"
+	"bailanysta/api/internal/pkg/logger"
+	"bailanysta/api/internal/services"
+)
+
+type PostLimitsHandler struct {
+	postLimits *services.PostLimitsService
+	logger     *logger.Logger
+	jwtManager *auth.JWTManager
+	validator  *validator.Validate
+}
+
+func NewPostLimitsHandler(postLimits *services.PostLimitsService, logger *logger.Logger, jwtManager *auth.JWTManager) *PostLimitsHandler {
+	return &PostLimitsHandler{
+		postLimits: postLimits,
+		logger:     logger,
+		jwtManager: jwtManager,
+		validator:  validator.New(),
+	}
+}
+
+// GetCapabilities tells clients what the current user's posts may hold, for
+// the course in ?course_id= if given, so they can show counters
+func (h *PostLimitsHandler) GetCapabilities(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var courseID *uuid.UUID
+	if courseParam := r.URL.Query().Get("course_id"); courseParam != "" {
+		parsed, err := uuid.Parse(courseParam)
+		if err != nil {
+			h.respondWithError(w, "Invalid course ID", http.StatusBadRequest)
+			return
+		}
+		courseID = &parsed
+	}
+
+	postLimits, err := h.postLimits.Capabilities(r.Context(), userID, courseID)
+	if err != nil {
+		h.logger.Error("Failed to get capabilities", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		h.respondWithError(w, "Failed to get capabilities", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{"post_limits": postLimits}, http.StatusOK)
+}
+
+// GetPostLimits lists the admin's organization's post limits
+func (h *PostLimitsHandler) GetPostLimits(w http.ResponseWriter, r *http.Request) {
+	orgID, err := h.jwtManager.GetOrgIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	rules, err := h.postLimits.List(r.Context(), orgID)
+	if err != nil {
+		h.logger.Error("Failed to get post limits", map[string]interface{}{
+			"error":  err.Error(),
+			"org_id": orgID,
+		})
+		h.respondWithError(w, "Failed to get post limits", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{"limits": rules}, http.StatusOK)
+}
+
+// SetPostLimit creates or replaces a post limit, e.g.
+// {"course_id": "...", "post_type": "question", "max_chars": 2000}
+func (h *PostLimitsHandler) SetPostLimit(w http.ResponseWriter, r *http.Request) {
+	orgID, err := h.jwtManager.GetOrgIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req services.SetPostLimitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.validator.Struct(req); err != nil {
+		h.respondWithError(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.postLimits.Set(r.Context(), orgID, req)
+	if err != nil {
+		if err.Error() == "course not found" {
+			h.respondWithError(w, "Unknown course_id", http.StatusBadRequest)
+			return
+		}
+		h.logger.Error("Failed to set post limit", map[string]interface{}{
+			"error":  err.Error(),
+			"org_id": orgID,
+		})
+		h.respondWithError(w, "Failed to set post limit", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, rule, http.StatusOK)
+}
+
+// DeletePostLimit removes a post limit, falling back to the next less
+// specific one
+func (h *PostLimitsHandler) DeletePostLimit(w http.ResponseWriter, r *http.Request) {
+	orgID, err := h.jwtManager.GetOrgIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ruleID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondWithError(w, "Invalid post limit ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.postLimits.Delete(r.Context(), orgID, ruleID); err != nil {
+		if err.Error() == "post limit not found" {
+			h.respondWithError(w, "Post limit not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("Failed to delete post limit", map[string]interface{}{
+			"error":    err.Error(),
+			"limit_id": ruleID,
+		})
+		h.respondWithError(w, "Failed to delete post limit", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *PostLimitsHandler) respondWithJSON(w http.ResponseWriter, data interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *PostLimitsHandler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	h.respondWithJSON(w, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    getErrorCode(statusCode),
+			"message": message,
+		},
+	}, statusCode)
+}
+
+func (h *PostLimitsHandler) getUserIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	return h.jwtManager.GetUserIDFromContext(ctx)
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"bailanysta/api/internal/services"
+)
+
+// minTextQuery is the shortest text query the trigram index can serve;
+// shorter ones scan every post of the organization
+const minTextQuery = 3
+
+// PostSearchFilter narrows post search. Zero fields don't filter; To is
+// exclusive.
+type PostSearchFilter struct {
+	Query    string
+	AuthorID *uuid.UUID
+	CourseID *uuid.UUID
+	Hashtag  string
+	From     *time.Time
+	To       *time.Time
+	HasMedia bool
+	Type     string // post or question
+	// Unanswered keeps questions without an accepted answer
+	Unanswered bool
+}
+
+// selective reports whether the filter has a condition that an index narrows
+// to a small set of posts on its own
+func (f PostSearchFilter) selective() bool {
+	return f.AuthorID != nil || f.CourseID != nil || f.Hashtag != "" || f.Unanswered
+}
+
+// parsePostSearchFilter reads ?query=&author_id=&course_id=&hashtag=
+// &from=YYYY-MM-DD&to=YYYY-MM-DD&has_media=true&type=question&unanswered=true.
+// to is inclusive. A text query too short for the trigram index needs a
+// selective filter beside it.
+func parsePostSearchFilter(params url.Values) (PostSearchFilter, error) {
+	filter := PostSearchFilter{
+		Query:      strings.TrimSpace(params.Get("query")),
+		Hashtag:    strings.TrimPrefix(strings.TrimSpace(params.Get("hashtag")), "#"),
+		HasMedia:   params.Get("has_media") == "true",
+		Type:       params.Get("type"),
+		Unanswered: params.Get("unanswered") == "true",
+	}
+
+	switch filter.Type {
+	case "", services.PostTypePost, services.PostTypeQuestion:
+	default:
+		return filter, fmt.Errorf("Invalid type, expected post or question")
+	}
+	if filter.Unanswered {
+		if filter.Type == services.PostTypePost {
+			return filter, fmt.Errorf("unanswered only applies to questions")
+		}
+		filter.Type = services.PostTypeQuestion
+	}
+
+	if value := params.Get("author_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return filter, fmt.Errorf("Invalid user ID")
+		}
+		filter.AuthorID = &id
+	}
+	if value := params.Get("course_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return filter, fmt.Errorf("Invalid course ID")
+		}
+		filter.CourseID = &id
+	}
+	if filter.Hashtag != "" && !services.ValidHashtag(filter.Hashtag) {
+		return filter, fmt.Errorf("Invalid hashtag")
+	}
+
+	if value := params.Get("from"); value != "" {
+		from, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return filter, fmt.Errorf("Invalid 'from' date, expected YYYY-MM-DD")
+		}
+		filter.From = &from
+	}
+	if value := params.Get("to"); value != "" {
+		to, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return filter, fmt.Errorf("Invalid 'to' date, expected YYYY-MM-DD")
+		}
+		to = to.AddDate(0, 0, 1)
+		filter.To = &to
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return filter, fmt.Errorf("'from' must not be after 'to'")
+	}
+
+	switch {
+	case filter.Query == "" && !filter.selective():
+		return filter, fmt.Errorf("Query parameter is required")
+	case filter.Query != "" && len([]rune(filter.Query)) < minTextQuery && !filter.selective():
+		return filter, fmt.Errorf("Query must be at least 3 characters unless author_id, course_id, hashtag or unanswered is given")
+	}
+	return filter, nil
+}
+
+// postQuery builds the WHERE clause of a post listing, numbering
+// placeholders as arguments are added. $1 is always the viewer.
+type postQuery struct {
+	conditions []string
+	args       []interface{}
+}
+
+// newPostQuery starts with what every listing applies: the viewer's
+// organization, no group posts, and only authors the viewer may see
+func newPostQuery(orgID, viewerID uuid.UUID) *postQuery {
+	q := &postQuery{}
+	viewer := q.arg(viewerID)
+	q.where("p.org_id = " + q.arg(orgID))
+	q.where("p.group_id IS NULL")
+	q.where(fmt.Sprintf("(NOT u.shadow_banned OR u.id = %s)", viewer))
+	q.where(fmt.Sprintf("(NOT u.is_private OR u.id = %[1]s OR EXISTS (SELECT 1 FROM follows fv WHERE fv.follower_id = %[1]s AND fv.followee_id = u.id))", viewer))
+	return q
+}
+
+// arg adds an argument and returns its placeholder
+func (q *postQuery) arg(value interface{}) string {
+	q.args = append(q.args, value)
+	return fmt.Sprintf("$%d", len(q.args))
+}
+
+func (q *postQuery) where(condition string) {
+	q.conditions = append(q.conditions, condition)
+}
+
+// filter adds the conditions of f. Each maps onto an index: author_id and
+// course_id onto theirs, hashtag onto post_hashtags, dates onto
+// (org_id, created_at), unanswered onto the partial index of open questions
+// and text onto the trigram index.
+func (q *postQuery) filter(f PostSearchFilter) {
+	if f.Query != "" {
+		q.where(fmt.Sprintf("p.text ILIKE '%%' || %s || '%%'", q.arg(f.Query)))
+	}
+	if f.AuthorID != nil {
+		q.where("p.author_id = " + q.arg(*f.AuthorID))
+	}
+	if f.CourseID != nil {
+		q.where("p.course_id = " + q.arg(*f.CourseID))
+	}
+	if f.Hashtag != "" {
+		q.where(fmt.Sprintf(`EXISTS (
+		      SELECT 1 FROM post_hashtags ph JOIN hashtags h ON ph.hashtag_id = h.id
+		      WHERE ph.post_id = p.id AND h.tag = %s)`, q.arg(services.CanonicalHashtag(f.Hashtag))))
+	}
+	if f.From != nil {
+		q.where("p.created_at >= " + q.arg(*f.From))
+	}
+	if f.To != nil {
+		q.where("p.created_at < " + q.arg(*f.To))
+	}
+	if f.Type != "" {
+		q.where("p.post_type = " + q.arg(f.Type))
+	}
+	if f.Unanswered {
+		q.where("p.accepted_comment_id IS NULL")
+	}
+	if f.HasMedia {
+		q.where(`EXISTS (
+		      SELECT 1 FROM post_links pl JOIN link_previews lp ON lp.url = pl.url
+		      WHERE pl.post_id = p.id AND lp.status = 'ok' AND lp.image_url IS NOT NULL)`)
+	}
+}
+
+// whereClause joins the conditions for use after WHERE
+func (q *postQuery) whereClause() string {
+	return strings.Join(q.conditions, "\n\t\t  AND ")
+}
@@ -57,6 +57,53 @@ func (h *PostsHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
 
 	post, err := h.postsService.CreatePost(r.Context(), userID, req)
 	if err != nil {
+		if err.Error() == "post is too long" {
+			h.respondWithError(w, "Post is longer than the character limit", http.StatusBadRequest)
+			return
+		}
+		if err.Error() == "post has too many words" {
+			h.respondWithError(w, "Post is over the word limit", http.StatusBadRequest)
+			return
+		}
+		if err.Error() == "invalid poll expiry" {
+			h.respondWithError(w, "Poll expiry must be in the future and at most 30 days away", http.StatusBadRequest)
+			return
+		}
+		if err.Error() == "content rejected" {
+			h.logger.Warn("Post rejected by content filter", map[string]interface{}{
+				"user_id": userID,
+			})
+			h.respondWithError(w, "Post was rejected by the content filter", http.StatusBadRequest)
+			return
+		}
+		if err.Error() == "AI generation not found" {
+			h.respondWithError(w, "Unknown or already used ai_generation_id", http.StatusBadRequest)
+			return
+		}
+		if err.Error() == "course not found" {
+			h.respondWithError(w, "Unknown course_id", http.StatusBadRequest)
+			return
+		}
+		if err.Error() == "not a group member" {
+			h.respondWithError(w, "Only group members can post to a group", http.StatusForbidden)
+			return
+		}
+		if err.Error() == "GIF not found" {
+			h.respondWithError(w, "Unknown gif_id", http.StatusBadRequest)
+			return
+		}
+		if err.Error() == "GIFs are not enabled" {
+			h.respondWithError(w, "GIF search is not enabled", http.StatusBadRequest)
+			return
+		}
+		if err.Error() == "only questions can have a bounty" {
+			h.respondWithError(w, "Only questions can have a bounty", http.StatusBadRequest)
+			return
+		}
+		if err.Error() == "insufficient reputation" {
+			h.respondWithError(w, "Not enough reputation for this bounty", http.StatusBadRequest)
+			return
+		}
 		h.logger.Error("Failed to create post", map[string]interface{}{
 			"error":   err.Error(),
 			"user_id": userID,
@@ -73,15 +120,28 @@ func (h *PostsHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
 	h.respondWithJSON(w, post, http.StatusCreated)
 }
 
+// GetPostByID accepts the post's UUID or its slug
 func (h *PostsHandler) GetPostByID(w http.ResponseWriter, r *http.Request) {
 	postIDParam := chi.URLParam(r, "id")
 	postID, err := uuid.Parse(postIDParam)
 	if err != nil {
-		h.respondWithError(w, "Invalid post ID", http.StatusBadRequest)
-		return
+		postID, err = h.postsService.ResolvePostSlug(r.Context(), postIDParam)
+		if err != nil {
+			if err.Error() != "post not found" {
+				h.logger.Error("Failed to resolve post slug", map[string]interface{}{
+					"slug":  postIDParam,
+					"error": err.Error(),
+				})
+			}
+			h.respondWithError(w, "Post not found", http.StatusNotFound)
+			return
+		}
 	}
 
-	post, err := h.postsService.GetPostByID(r.Context(), postID)
+	// Anonymous viewers see poll results without their own vote
+	userID, userErr := h.getUserIDFromContext(r.Context())
+
+	post, err := h.postsService.GetPostByID(r.Context(), postID, userID)
 	if err != nil {
 		h.logger.Warn("Post not found", map[string]interface{}{
 			"post_id": postID,
@@ -91,9 +151,86 @@ func (h *PostsHandler) GetPostByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// View counts are private to the author; everyone else counts as a view
+	if userErr != nil || userID != post.AuthorID {
+		post.ViewCount = nil
+	}
+	if userErr == nil && userID != post.AuthorID {
+		if _, err := h.postsService.RecordImpressions(r.Context(), userID, []uuid.UUID{postID}); err != nil {
+			h.logger.Warn("Failed to record post view", map[string]interface{}{
+				"post_id": postID,
+				"error":   err.Error(),
+			})
+		}
+	}
+
 	h.respondWithJSON(w, post, http.StatusOK)
 }
 
+// RecordImpressions accepts batched post views reported by clients (e.g. feed scroll)
+func (h *PostsHandler) RecordImpressions(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req services.RecordImpressionsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		h.respondWithError(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	recorded, err := h.postsService.RecordImpressions(r.Context(), userID, req.PostIDs)
+	if err != nil {
+		h.logger.Error("Failed to record impressions", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		h.respondWithError(w, "Failed to record impressions", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{"recorded": recorded}, http.StatusOK)
+}
+
+// GetPostsBatch hydrates up to 100 posts by ID in one request
+func (h *PostsHandler) GetPostsBatch(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req services.BatchPostsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		h.respondWithError(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	posts, err := h.postsService.GetPostsByIDs(r.Context(), req.PostIDs, userID)
+	if err != nil {
+		h.logger.Error("Failed to get posts batch", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		h.respondWithError(w, "Failed to get posts", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{"posts": posts}, http.StatusOK)
+}
+
 func (h *PostsHandler) UpdatePost(w http.ResponseWriter, r *http.Request) {
 	userID, err := h.getUserIDFromContext(r.Context())
 	if err != nil {
@@ -134,6 +271,12 @@ func (h *PostsHandler) UpdatePost(w http.ResponseWriter, r *http.Request) {
 		})
 		if err.Error() == "access denied" {
 			h.respondWithError(w, "Access denied", http.StatusForbidden)
+		} else if err.Error() == "post is too long" {
+			h.respondWithError(w, "Post is longer than the character limit", http.StatusBadRequest)
+		} else if err.Error() == "post has too many words" {
+			h.respondWithError(w, "Post is over the word limit", http.StatusBadRequest)
+		} else if err.Error() == "course not found" {
+			h.respondWithError(w, "Unknown course_id", http.StatusBadRequest)
 		} else {
 			h.respondWithError(w, err.Error(), http.StatusInternalServerError)
 		}
@@ -148,6 +291,42 @@ func (h *PostsHandler) UpdatePost(w http.ResponseWriter, r *http.Request) {
 	h.respondWithJSON(w, post, http.StatusOK)
 }
 
+// GetPostHistory returns the earlier versions of an edited post
+func (h *PostsHandler) GetPostHistory(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	postID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondWithError(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	revisions, err := h.postsService.GetPostHistory(r.Context(), postID, userID)
+	if err != nil {
+		switch err.Error() {
+		case "post not found":
+			h.respondWithError(w, "Post not found", http.StatusNotFound)
+		case "access denied":
+			h.respondWithError(w, "Access denied", http.StatusForbidden)
+		default:
+			h.logger.Error("Failed to get post history", map[string]interface{}{
+				"error":   err.Error(),
+				"post_id": postID,
+			})
+			h.respondWithError(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"revisions": revisions,
+	}, http.StatusOK)
+}
+
 func (h *PostsHandler) DeletePost(w http.ResponseWriter, r *http.Request) {
 	userID, err := h.getUserIDFromContext(r.Context())
 	if err != nil {
@@ -199,8 +378,16 @@ func (h *PostsHandler) LikePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.postsService.LikePost(r.Context(), userID, postID)
+	state, err := h.postsService.LikePost(r.Context(), userID, postID)
 	if err != nil {
+		if err.Error() == "post not found" {
+			h.respondWithError(w, "Post not found", http.StatusNotFound)
+			return
+		}
+		if err.Error() == "account is throttled" {
+			h.respondWithError(w, "Too many likes or follows; try again later", http.StatusTooManyRequests)
+			return
+		}
 		h.logger.Error("Failed to like post", map[string]interface{}{
 			"error":   err.Error(),
 			"user_id": userID,
@@ -215,7 +402,11 @@ func (h *PostsHandler) LikePost(w http.ResponseWriter, r *http.Request) {
 		"user_id": userID,
 	})
 
-	h.respondWithJSON(w, map[string]interface{}{"message": "Post liked successfully"}, http.StatusOK)
+	h.respondWithJSON(w, map[string]interface{}{
+		"message":    "Post liked successfully",
+		"like_count": state.LikeCount,
+		"is_liked":   state.IsLiked,
+	}, http.StatusOK)
 }
 
 func (h *PostsHandler) UnlikePost(w http.ResponseWriter, r *http.Request) {
@@ -232,8 +423,12 @@ func (h *PostsHandler) UnlikePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.postsService.UnlikePost(r.Context(), userID, postID)
+	state, err := h.postsService.UnlikePost(r.Context(), userID, postID)
 	if err != nil {
+		if err.Error() == "post not found" {
+			h.respondWithError(w, "Post not found", http.StatusNotFound)
+			return
+		}
 		h.logger.Error("Failed to unlike post", map[string]interface{}{
 			"error":   err.Error(),
 			"user_id": userID,
@@ -248,10 +443,20 @@ func (h *PostsHandler) UnlikePost(w http.ResponseWriter, r *http.Request) {
 		"user_id": userID,
 	})
 
-	h.respondWithJSON(w, map[string]interface{}{"message": "Post unliked successfully"}, http.StatusOK)
+	h.respondWithJSON(w, map[string]interface{}{
+		"message":    "Post unliked successfully",
+		"like_count": state.LikeCount,
+		"is_liked":   state.IsLiked,
+	}, http.StatusOK)
 }
 
 func (h *PostsHandler) GetComments(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	postIDParam := chi.URLParam(r, "id")
 	postID, err := uuid.Parse(postIDParam)
 	if err != nil {
@@ -274,8 +479,45 @@ func (h *PostsHandler) GetComments(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	comments, err := h.postsService.GetComments(r.Context(), postID, limit, offset)
+	sort := r.URL.Query().Get("sort")
+	if sort == "" {
+		sort = services.CommentSortOldest
+	}
+	if !services.ValidCommentSort(sort) {
+		h.respondWithError(w, "sort must be oldest, newest or top", http.StatusBadRequest)
+		return
+	}
+
+	// ?comment= jumps to the page holding that comment, for notification links
+	if commentParam := r.URL.Query().Get("comment"); commentParam != "" {
+		commentID, err := uuid.Parse(commentParam)
+		if err != nil {
+			h.respondWithError(w, "Invalid comment ID", http.StatusBadRequest)
+			return
+		}
+
+		offset, err = h.postsService.CommentPageOffset(r.Context(), postID, commentID, userID, sort, limit)
+		if err != nil {
+			if err.Error() == "comment not found" {
+				h.respondWithError(w, "Comment not found", http.StatusNotFound)
+				return
+			}
+			h.logger.Error("Failed to locate comment", map[string]interface{}{
+				"error":      err.Error(),
+				"post_id":    postID,
+				"comment_id": commentID,
+			})
+			h.respondWithError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	comments, err := h.postsService.GetComments(r.Context(), postID, userID, sort, limit, offset)
 	if err != nil {
+		if err.Error() == "post not found" {
+			h.respondWithError(w, "Post not found", http.StatusNotFound)
+			return
+		}
 		h.logger.Error("Failed to get comments", map[string]interface{}{
 			"error":   err.Error(),
 			"post_id": postID,
@@ -284,13 +526,133 @@ func (h *PostsHandler) GetComments(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	total, err := h.postsService.CountComments(r.Context(), postID, userID)
+	if err != nil {
+		h.logger.Error("Failed to count comments", map[string]interface{}{
+			"error":   err.Error(),
+			"post_id": postID,
+		})
+		h.respondWithError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	h.respondWithJSON(w, map[string]interface{}{
 		"comments": comments,
+		"total":    total,
+		"sort":     sort,
 		"limit":    limit,
 		"offset":   offset,
 	}, http.StatusOK)
 }
 
+// VotePoll casts the user's vote in a post's poll and returns the new results
+func (h *PostsHandler) VotePoll(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	postIDParam := chi.URLParam(r, "id")
+	postID, err := uuid.Parse(postIDParam)
+	if err != nil {
+		h.respondWithError(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	var req services.VotePollRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		h.respondWithError(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	poll, err := h.postsService.VotePoll(r.Context(), userID, postID, req.OptionID)
+	if err != nil {
+		switch err.Error() {
+		case "post not found":
+			h.respondWithError(w, "Post not found", http.StatusNotFound)
+		case "poll not found":
+			h.respondWithError(w, "Poll not found", http.StatusNotFound)
+		case "invalid poll option":
+			h.respondWithError(w, "Invalid poll option", http.StatusBadRequest)
+		case "poll closed":
+			h.respondWithError(w, "Poll is closed", http.StatusConflict)
+		case "already voted":
+			h.respondWithError(w, "Already voted in this poll", http.StatusConflict)
+		default:
+			h.logger.Error("Failed to vote in poll", map[string]interface{}{
+				"error":   err.Error(),
+				"post_id": postID,
+				"user_id": userID,
+			})
+			h.respondWithError(w, "Failed to vote", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.respondWithJSON(w, poll, http.StatusOK)
+}
+
+// GetLikes lists the users who liked a post
+func (h *PostsHandler) GetLikes(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	postIDParam := chi.URLParam(r, "id")
+	postID, err := uuid.Parse(postIDParam)
+	if err != nil {
+		h.respondWithError(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	limit := 20
+	offset := 0
+
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.Atoi(limitParam); err == nil && parsedLimit > 0 && parsedLimit <= 100 {
+			limit = parsedLimit
+		}
+	}
+
+	if offsetParam := r.URL.Query().Get("offset"); offsetParam != "" {
+		if parsedOffset, err := strconv.Atoi(offsetParam); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	users, total, err := h.postsService.GetPostLikes(r.Context(), postID, userID, limit, offset)
+	if err != nil {
+		if err.Error() == "post not found" {
+			h.respondWithError(w, "Post not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("Failed to get likes", map[string]interface{}{
+			"error":   err.Error(),
+			"post_id": postID,
+		})
+		h.respondWithError(w, "Failed to get likes", http.StatusInternalServerError)
+		return
+	}
+	if users == nil {
+		users = []*services.UserResponse{}
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"users":  users,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	}, http.StatusOK)
+}
+
 func (h *PostsHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
 	userID, err := h.getUserIDFromContext(r.Context())
 	if err != nil {
@@ -324,6 +686,26 @@ func (h *PostsHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
 
 	comment, err := h.postsService.CreateComment(r.Context(), userID, postID, req)
 	if err != nil {
+		if err.Error() == "post not found" {
+			h.respondWithError(w, "Post not found", http.StatusNotFound)
+			return
+		}
+		if err.Error() == "content rejected" {
+			h.logger.Warn("Comment rejected by content filter", map[string]interface{}{
+				"user_id": userID,
+				"post_id": postID,
+			})
+			h.respondWithError(w, "Comment was rejected by the content filter", http.StatusBadRequest)
+			return
+		}
+		if err.Error() == "GIF not found" {
+			h.respondWithError(w, "Unknown gif_id", http.StatusBadRequest)
+			return
+		}
+		if err.Error() == "GIFs are not enabled" {
+			h.respondWithError(w, "GIF search is not enabled", http.StatusBadRequest)
+			return
+		}
 		h.logger.Error("Failed to create comment", map[string]interface{}{
 			"error":   err.Error(),
 			"user_id": userID,
@@ -342,6 +724,116 @@ func (h *PostsHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
 	h.respondWithJSON(w, comment, http.StatusCreated)
 }
 
+// VerifyComment marks a comment as the verified answer of its course post
+func (h *PostsHandler) VerifyComment(w http.ResponseWriter, r *http.Request) {
+	h.setVerifiedAnswer(w, r, true)
+}
+
+func (h *PostsHandler) UnverifyComment(w http.ResponseWriter, r *http.Request) {
+	h.setVerifiedAnswer(w, r, false)
+}
+
+func (h *PostsHandler) setVerifiedAnswer(w http.ResponseWriter, r *http.Request, verified bool) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	postID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondWithError(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	commentID, err := uuid.Parse(chi.URLParam(r, "commentID"))
+	if err != nil {
+		h.respondWithError(w, "Invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.postsService.SetVerifiedAnswer(r.Context(), userID, postID, commentID, verified)
+	if err != nil {
+		switch err.Error() {
+		case "comment not found":
+			h.respondWithError(w, "Comment not found", http.StatusNotFound)
+		case "post has no course":
+			h.respondWithError(w, "Only comments on course posts can be verified answers", http.StatusBadRequest)
+		default:
+			h.logger.Error("Failed to update verified answer", map[string]interface{}{
+				"error":      err.Error(),
+				"post_id":    postID,
+				"comment_id": commentID,
+			})
+			h.respondWithError(w, "Failed to update verified answer", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"comment_id":      commentID,
+		"verified_answer": verified,
+	}, http.StatusOK)
+}
+
+// AcceptAnswer marks a comment as the answer to the asker's question
+func (h *PostsHandler) AcceptAnswer(w http.ResponseWriter, r *http.Request) {
+	h.setAcceptedAnswer(w, r, true)
+}
+
+func (h *PostsHandler) UnacceptAnswer(w http.ResponseWriter, r *http.Request) {
+	h.setAcceptedAnswer(w, r, false)
+}
+
+func (h *PostsHandler) setAcceptedAnswer(w http.ResponseWriter, r *http.Request, accepted bool) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	postID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondWithError(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	commentID, err := uuid.Parse(chi.URLParam(r, "commentID"))
+	if err != nil {
+		h.respondWithError(w, "Invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	var question *services.Question
+	if accepted {
+		question, err = h.postsService.AcceptAnswer(r.Context(), userID, postID, commentID)
+	} else {
+		question, err = h.postsService.UnacceptAnswer(r.Context(), userID, postID, commentID)
+	}
+	if err != nil {
+		switch err.Error() {
+		case "comment not found":
+			h.respondWithError(w, "Comment not found", http.StatusNotFound)
+		case "post not found":
+			h.respondWithError(w, "Post not found", http.StatusNotFound)
+		case "post is not a question":
+			h.respondWithError(w, "Only questions have accepted answers", http.StatusBadRequest)
+		case "only the asker can accept an answer":
+			h.respondWithError(w, "Only the author of the question can accept an answer", http.StatusForbidden)
+		default:
+			h.logger.Error("Failed to update accepted answer", map[string]interface{}{
+				"error":      err.Error(),
+				"post_id":    postID,
+				"comment_id": commentID,
+			})
+			h.respondWithError(w, "Failed to update accepted answer", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.respondWithJSON(w, question, http.StatusOK)
+}
+
 func (h *PostsHandler) respondWithJSON(w http.ResponseWriter, data interface{}, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
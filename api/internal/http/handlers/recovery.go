@@ -0,0 +1,310 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+
+	"bailanysta/api/internal/pkg/auth"
+	"bailanysta/api/internal/pkg/logger"
+	"bailanysta/api/internal/services"
+)
+
+type RecoveryHandler struct {
+	recoveryService *services.RecoveryService
+	auth            *AuthHandler // starts the session of a recovered account
+	logger          *logger.Logger
+	jwtManager      *auth.JWTManager
+	validator       *validator.Validate
+}
+
+func NewRecoveryHandler(recoveryService *services.RecoveryService, authHandler *AuthHandler, logger *logger.Logger, jwtManager *auth.JWTManager) *RecoveryHandler {
+	return &RecoveryHandler{
+		recoveryService: recoveryService,
+		auth:            authHandler,
+		logger:          logger,
+		jwtManager:      jwtManager,
+		validator:       validator.New(),
+	}
+}
+
+// GetRecovery shows the current user's backup email, how many recovery
+// codes are left and the latest recovery activity
+func (h *RecoveryHandler) GetRecovery(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	status, err := h.recoveryService.Status(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("Failed to get recovery status", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		h.respondWithError(w, "Failed to get recovery status", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, status, http.StatusOK)
+}
+
+// SetBackupEmail sets an unverified backup email and sends it a
+// verification link
+func (h *RecoveryHandler) SetBackupEmail(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.sessionUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Password string `json:"password" validate:"required"`
+		Email    string `json:"email" validate:"required,email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.validator.Struct(req); err != nil {
+		h.respondWithError(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.recoveryService.SetBackupEmail(r.Context(), userID, req.Password, req.Email, clientInfo(r)); err != nil {
+		switch err.Error() {
+		case "email is not configured":
+			h.respondWithError(w, "Email is not configured", http.StatusServiceUnavailable)
+		case "invalid password":
+			h.respondWithError(w, "Invalid password", http.StatusForbidden)
+		case "backup email must differ from account email":
+			h.respondWithError(w, "Backup email must differ from account email", http.StatusBadRequest)
+		default:
+			h.logger.Error("Failed to set backup email", map[string]interface{}{
+				"error":   err.Error(),
+				"user_id": userID,
+			})
+			h.respondWithError(w, "Failed to set backup email", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"message": "Check the backup email for a verification link",
+	}, http.StatusAccepted)
+}
+
+// RemoveBackupEmail removes the current user's backup email
+func (h *RecoveryHandler) RemoveBackupEmail(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.sessionUserID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.recoveryService.RemoveBackupEmail(r.Context(), userID, clientInfo(r)); err != nil {
+		if err.Error() == "no backup email" {
+			h.respondWithError(w, "No backup email is set", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("Failed to remove backup email", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		h.respondWithError(w, "Failed to remove backup email", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// VerifyBackupEmail confirms a backup email from its verification link
+func (h *RecoveryHandler) VerifyBackupEmail(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token string `json:"token" validate:"required"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.validator.Struct(req); err != nil {
+		h.respondWithError(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.recoveryService.VerifyBackupEmail(r.Context(), req.Token, clientInfo(r)); err != nil {
+		if err.Error() == "invalid token" {
+			h.respondWithError(w, "Invalid or expired link", http.StatusBadRequest)
+			return
+		}
+		h.logger.Error("Failed to verify backup email", map[string]interface{}{
+			"error": err.Error(),
+		})
+		h.respondWithError(w, "Failed to verify backup email", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{"verified": true}, http.StatusOK)
+}
+
+// RegenerateCodes replaces the current user's recovery codes. The new codes
+// are only shown in this response.
+func (h *RecoveryHandler) RegenerateCodes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.sessionUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Password string `json:"password" validate:"required"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.validator.Struct(req); err != nil {
+		h.respondWithError(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.recoveryService.RegenerateCodes(r.Context(), userID, req.Password, clientInfo(r))
+	if err != nil {
+		if err.Error() == "invalid password" {
+			h.respondWithError(w, "Invalid password", http.StatusForbidden)
+			return
+		}
+		h.logger.Error("Failed to generate recovery codes", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		h.respondWithError(w, "Failed to generate recovery codes", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("Recovery codes generated", map[string]interface{}{
+		"user_id": userID,
+	})
+	w.Header().Set("Cache-Control", "no-store")
+	h.respondWithJSON(w, map[string]interface{}{"codes": codes}, http.StatusCreated)
+}
+
+// StartRecovery emails a recovery link to the account's verified backup
+// email. It answers the same whether or not there is one.
+func (h *RecoveryHandler) StartRecovery(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.validator.Struct(req); err != nil {
+		h.respondWithError(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.recoveryService.StartRecovery(r.Context(), req.Email, clientInfo(r)); err != nil {
+		if err.Error() == "email is not configured" {
+			h.respondWithError(w, "Email is not configured", http.StatusServiceUnavailable)
+			return
+		}
+		h.logger.Error("Failed to start account recovery", map[string]interface{}{
+			"error": err.Error(),
+		})
+		h.respondWithError(w, "Failed to start account recovery", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"message": "If the account has a verified backup email, a recovery link was sent to it",
+	}, http.StatusAccepted)
+}
+
+// RecoverAccount sets a new password with a recovery link token or a
+// recovery code and signs in, ending every other session
+func (h *RecoveryHandler) RecoverAccount(w http.ResponseWriter, r *http.Request) {
+	var req services.RecoverAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.validator.Struct(req); err != nil {
+		h.respondWithError(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := h.recoveryService.Recover(r.Context(), req, clientInfo(r))
+	if err != nil {
+		switch err.Error() {
+		case "token or email and code is required":
+			h.respondWithError(w, "Either token, or email and code, is required", http.StatusBadRequest)
+		case "invalid recovery credentials":
+			h.logger.Warn("Account recovery failed", map[string]interface{}{
+				"email": req.Email,
+				"ip":    remoteIP(r),
+			})
+			h.respondWithError(w, "Invalid or used recovery code or link", http.StatusUnauthorized)
+		default:
+			h.logger.Error("Failed to recover account", map[string]interface{}{
+				"error": err.Error(),
+			})
+			h.respondWithError(w, "Failed to recover account", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.logger.Info("Account recovered", map[string]interface{}{
+		"user_id":   response.User.ID,
+		"with_code": req.Code != "",
+	})
+
+	if h.auth.cookieMode(r) {
+		if err := h.auth.setSessionCookies(w, &response.Tokens); err != nil {
+			h.respondWithError(w, "Failed to start session", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	h.respondWithJSON(w, response, http.StatusOK)
+}
+
+func (h *RecoveryHandler) respondWithJSON(w http.ResponseWriter, data interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *RecoveryHandler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	h.respondWithJSON(w, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    getErrorCode(statusCode),
+			"message": message,
+		},
+	}, statusCode)
+}
+
+// sessionUserID is the signed-in user for changes to recovery settings,
+// which API keys and impersonators may not make: they could otherwise take
+// over the account
+func (h *RecoveryHandler) sessionUserID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return uuid.Nil, false
+	}
+	if _, viaKey := r.Context().Value("api_key_id").(string); viaKey {
+		h.respondWithError(w, "API keys cannot change account recovery", http.StatusForbidden)
+		return uuid.Nil, false
+	}
+	if _, impersonated := r.Context().Value("impersonation").(*services.ImpersonationInfo); impersonated {
+		h.respondWithError(w, "Not allowed while impersonating", http.StatusForbidden)
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+func (h *RecoveryHandler) getUserIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	return h.jwtManager.GetUserIDFromContext(ctx)
+}
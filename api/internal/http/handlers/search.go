@@ -6,20 +6,26 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
-	"github.com/jackc/pgx/v5/pgxpool"
 
 	"bailanysta/api/internal/pkg/auth"
+	"bailanysta/api/internal/pkg/coalesce"
+	"bailanysta/api/internal/pkg/database"
 	"bailanysta/api/internal/pkg/logger"
 	"bailanysta/api/internal/services"
 )
 
 type SearchHandler struct {
-	db         *pgxpool.Pool
-	logger     *logger.Logger
-	jwtManager *auth.JWTManager
+	db               *database.Pool
+	embeddingService *services.EmbeddingService
+	logger           *logger.Logger
+	jwtManager       *auth.JWTManager
+	suggestions      *coalesce.Group[*Suggestions]
+	suggestCacheTTL  time.Duration
+	suggestTimeout   time.Duration
 }
 
 type SearchResult struct {
@@ -30,20 +36,31 @@ type SearchResult struct {
 	TotalUsers int                      `json:"total_users"`
 }
 
-func NewSearchHandler(db *pgxpool.Pool, logger *logger.Logger, jwtManager *auth.JWTManager) *SearchHandler {
+// NewSearchHandler creates a search handler; embeddingService may be nil when semantic search is disabled.
+// Searches run on the read replica when one is configured. Post results in
+// the viewer's feed languages come first. Suggestions are cached for
+// suggestCacheTTL and given up on after suggestTimeout.
+func NewSearchHandler(db *database.Pool, embeddingService *services.EmbeddingService, logger *logger.Logger, jwtManager *auth.JWTManager, suggestCacheTTL, suggestTimeout time.Duration) *SearchHandler {
 	return &SearchHandler{
-		db:         db,
-		logger:     logger,
-		jwtManager: jwtManager,
+		db:               db,
+		embeddingService: embeddingService,
+		logger:           logger,
+		jwtManager:       jwtManager,
+		suggestions:      coalesce.New[*Suggestions]("search_suggest", suggestCacheTTL),
+		suggestCacheTTL:  suggestCacheTTL,
+		suggestTimeout:   suggestTimeout,
 	}
 }
 
+// SearchPosts searches posts by text and the filters parsePostSearchFilter
+// reads, and users by the text alone
 func (h *SearchHandler) SearchPosts(w http.ResponseWriter, r *http.Request) {
-	query := strings.TrimSpace(r.URL.Query().Get("query"))
-	if query == "" {
-		h.respondWithError(w, "Query parameter is required", http.StatusBadRequest)
+	filter, err := parsePostSearchFilter(r.URL.Query())
+	if err != nil {
+		h.respondWithError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	query := filter.Query
 
 	limit := 20
 	offset := 0
@@ -65,6 +82,11 @@ func (h *SearchHandler) SearchPosts(w http.ResponseWriter, r *http.Request) {
 	if userID, err := h.getUserIDFromContext(r.Context()); err == nil {
 		currentUserID = userID
 	}
+	orgID, err := h.jwtManager.GetOrgIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Organization not found", http.StatusBadRequest)
+		return
+	}
 
 	result := &SearchResult{
 		Query:      query,
@@ -74,10 +96,9 @@ func (h *SearchHandler) SearchPosts(w http.ResponseWriter, r *http.Request) {
 		TotalUsers: 0,
 	}
 
-	// Search posts - always use text search for better results
-	posts, total, err := h.searchPostsByText(r.Context(), query, currentUserID, limit, offset)
+	posts, total, err := h.searchPosts(r.Context(), filter, orgID, currentUserID, limit, offset)
 	if err != nil {
-		h.logger.Error("Failed to search posts by text", map[string]interface{}{
+		h.logger.Error("Failed to search posts", map[string]interface{}{
 			"error": err.Error(),
 			"query": query,
 		})
@@ -89,20 +110,22 @@ func (h *SearchHandler) SearchPosts(w http.ResponseWriter, r *http.Request) {
 	}
 	result.TotalPosts = total
 
-	// Search users
-	users, userTotal, err := h.searchUsers(r.Context(), query, currentUserID, 10, 0)
-	if err != nil {
-		h.logger.Error("Failed to search users", map[string]interface{}{
-			"error": err.Error(),
-			"query": query,
-		})
-		h.respondWithError(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	if users != nil {
-		result.Users = users
+	// Search users; filters only apply to posts
+	if query != "" {
+		users, userTotal, err := h.searchUsers(r.Context(), query, orgID, currentUserID, 10, 0)
+		if err != nil {
+			h.logger.Error("Failed to search users", map[string]interface{}{
+				"error": err.Error(),
+				"query": query,
+			})
+			h.respondWithError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if users != nil {
+			result.Users = users
+		}
+		result.TotalUsers = userTotal
 	}
-	result.TotalUsers = userTotal
 
 	h.logger.Info("Search completed", map[string]interface{}{
 		"query":       query,
@@ -113,92 +136,98 @@ func (h *SearchHandler) SearchPosts(w http.ResponseWriter, r *http.Request) {
 	h.respondWithJSON(w, result, http.StatusOK)
 }
 
-func (h *SearchHandler) searchPostsByText(ctx context.Context, query string, currentUserID uuid.UUID, limit, offset int) ([]*services.Post, int, error) {
-	var total int
-	err := h.db.QueryRow(ctx, "SELECT COUNT(*) FROM posts WHERE text ILIKE '%' || $1 || '%'", query).Scan(&total)
-	if err != nil {
-		return nil, 0, err
+func (h *SearchHandler) SemanticSearch(w http.ResponseWriter, r *http.Request) {
+	if h.embeddingService == nil {
+		h.respondWithError(w, "Semantic search is disabled", http.StatusServiceUnavailable)
+		return
 	}
 
-	rows, err := h.db.Query(ctx, `
-		SELECT p.id, p.author_id, p.text, p.course_id, p.module_id, p.created_at, p.updated_at,
-		       COUNT(DISTINCT l.user_id) as like_count,
-		       COUNT(DISTINCT c.id) as comment_count,
-		       u.username, u.email, u.bio, u.avatar_url,
-		       CASE WHEN ul.user_id IS NOT NULL THEN true ELSE false END as is_liked
-		FROM posts p
-		JOIN users u ON p.author_id = u.id
-		LEFT JOIN likes l ON p.id = l.post_id
-		LEFT JOIN comments c ON p.id = c.post_id
-		LEFT JOIN likes ul ON p.id = ul.post_id AND ul.user_id = $1
-		WHERE p.text ILIKE '%' || $2 || '%'
-		GROUP BY p.id, u.username, u.email, u.bio, u.avatar_url, ul.user_id
-		ORDER BY p.created_at DESC
-		LIMIT $3 OFFSET $4`, currentUserID, query, limit, offset)
-	if err != nil {
-		return nil, 0, err
+	query := strings.TrimSpace(r.URL.Query().Get("query"))
+	if query == "" {
+		h.respondWithError(w, "Query parameter is required", http.StatusBadRequest)
+		return
+	}
+	if len(query) > 500 {
+		h.respondWithError(w, "Query is too long", http.StatusBadRequest)
+		return
 	}
-	defer rows.Close()
 
-	var posts []*services.Post
-	for rows.Next() {
-		var post services.Post
-		var courseID, moduleID pgtype.UUID
-		var bio, avatarURL pgtype.Text
+	limit := 20
+	offset := 0
 
-		err := rows.Scan(
-			&post.ID, &post.AuthorID, &post.Text, &courseID, &moduleID,
-			&post.CreatedAt, &post.UpdatedAt, &post.LikeCount, &post.CommentCount,
-			&post.Author.Username, &post.Author.Email, &bio, &avatarURL, &post.IsLiked)
-		if err != nil {
-			return nil, 0, err
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.Atoi(limitParam); err == nil && parsedLimit > 0 && parsedLimit <= 100 {
+			limit = parsedLimit
 		}
+	}
 
-		if courseID.Valid {
-			courseUUID := uuid.UUID(courseID.Bytes)
-			post.CourseID = &courseUUID
-		}
-		if moduleID.Valid {
-			moduleUUID := uuid.UUID(moduleID.Bytes)
-			post.ModuleID = &moduleUUID
+	if offsetParam := r.URL.Query().Get("offset"); offsetParam != "" {
+		if parsedOffset, err := strconv.Atoi(offsetParam); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
 		}
-		post.Author.Bio = getPgtypeTextValue(bio)
-		post.Author.AvatarURL = getPgtypeTextPtr(avatarURL)
+	}
 
-		posts = append(posts, &post)
+	currentUserID := uuid.Nil
+	if userID, err := h.getUserIDFromContext(r.Context()); err == nil {
+		currentUserID = userID
+	}
+	orgID, err := h.jwtManager.GetOrgIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Organization not found", http.StatusBadRequest)
+		return
 	}
 
-	return posts, total, nil
+	posts, err := h.embeddingService.SemanticSearch(r.Context(), query, orgID, currentUserID, limit, offset)
+	if err != nil {
+		h.logger.Error("Failed to run semantic search", map[string]interface{}{
+			"error": err.Error(),
+			"query": query,
+		})
+		h.respondWithError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if posts == nil {
+		posts = []*services.SemanticPost{}
+	}
+
+	h.logger.Info("Semantic search completed", map[string]interface{}{
+		"query":       query,
+		"posts_found": len(posts),
+	})
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"posts":  posts,
+		"query":  query,
+		"limit":  limit,
+		"offset": offset,
+	}, http.StatusOK)
 }
 
-func (h *SearchHandler) searchPostsByHashtag(ctx context.Context, hashtag string, currentUserID uuid.UUID, limit, offset int) ([]*services.Post, int, error) {
+func (h *SearchHandler) searchPosts(ctx context.Context, filter PostSearchFilter, orgID, currentUserID uuid.UUID, limit, offset int) ([]*services.Post, int, error) {
+	q := newPostQuery(orgID, currentUserID)
+	q.filter(filter)
+
 	var total int
-	err := h.db.QueryRow(ctx, `
+	err := h.db.Reader().QueryRow(ctx, `
 		SELECT COUNT(*) FROM posts p
-		JOIN post_hashtags ph ON p.id = ph.post_id
-		JOIN hashtags h ON ph.hashtag_id = h.id
-		WHERE h.tag = $1`, hashtag).Scan(&total)
+		JOIN users u ON p.author_id = u.id
+		WHERE `+q.whereClause(), q.args...).Scan(&total)
 	if err != nil {
 		return nil, 0, err
 	}
 
-	rows, err := h.db.Query(ctx, `
-		SELECT p.id, p.author_id, p.text, p.course_id, p.module_id, p.created_at, p.updated_at,
-		       COUNT(DISTINCT l.user_id) as like_count,
-		       COUNT(DISTINCT c.id) as comment_count,
+	rows, err := h.db.Reader().Query(ctx, `
+		SELECT p.id, p.author_id, p.text, p.course_id, p.module_id, p.is_ai_generated, p.edited_at IS NOT NULL, p.slug, p.language, p.created_at, p.updated_at,
+		       p.like_count,
+		       (SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id) AS comment_count,
 		       u.username, u.email, u.bio, u.avatar_url,
-		       CASE WHEN ul.user_id IS NOT NULL THEN true ELSE false END as is_liked
+		       EXISTS(SELECT 1 FROM likes ul WHERE ul.post_id = p.id AND ul.user_id = $1) AS is_liked,
+		       p.post_type, p.accepted_comment_id, p.accepted_at, p.bounty, p.bounty_awarded_to
 		FROM posts p
 		JOIN users u ON p.author_id = u.id
-		JOIN post_hashtags ph ON p.id = ph.post_id
-		JOIN hashtags h ON ph.hashtag_id = h.id
-		LEFT JOIN likes l ON p.id = l.post_id
-		LEFT JOIN comments c ON p.id = c.post_id
-		LEFT JOIN likes ul ON p.id = ul.post_id AND ul.user_id = $1
-		WHERE h.tag = $2
-		GROUP BY p.id, u.username, u.email, u.bio, u.avatar_url, ul.user_id
-		ORDER BY p.created_at DESC
-		LIMIT $3 OFFSET $4`, currentUserID, hashtag, limit, offset)
+		WHERE `+q.whereClause()+`
+		ORDER BY COALESCE(p.language = ANY((SELECT feed_languages FROM users WHERE id = $1)), false) DESC, p.created_at DESC
+		LIMIT `+q.arg(limit)+` OFFSET `+q.arg(offset), q.args...)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -209,14 +238,20 @@ func (h *SearchHandler) searchPostsByHashtag(ctx context.Context, hashtag string
 		var post services.Post
 		var courseID, moduleID pgtype.UUID
 		var bio, avatarURL pgtype.Text
+		var postType string
+		var question services.Question
 
 		err := rows.Scan(
-			&post.ID, &post.AuthorID, &post.Text, &courseID, &moduleID,
+			&post.ID, &post.AuthorID, &post.Text, &courseID, &moduleID, &post.IsAIGenerated, &post.Edited, &post.Slug, &post.Language,
 			&post.CreatedAt, &post.UpdatedAt, &post.LikeCount, &post.CommentCount,
-			&post.Author.Username, &post.Author.Email, &bio, &avatarURL, &post.IsLiked)
+			&post.Author.Username, &post.Author.Email, &bio, &avatarURL, &post.IsLiked,
+			&postType, &question.AcceptedCommentID, &question.AcceptedAt, &question.Bounty, &question.BountyAwardedTo)
 		if err != nil {
 			return nil, 0, err
 		}
+		if postType == services.PostTypeQuestion {
+			post.Question = &question
+		}
 
 		if courseID.Valid {
 			courseUUID := uuid.UUID(courseID.Bytes)
@@ -226,39 +261,36 @@ func (h *SearchHandler) searchPostsByHashtag(ctx context.Context, hashtag string
 			moduleUUID := uuid.UUID(moduleID.Bytes)
 			post.ModuleID = &moduleUUID
 		}
+		post.TextHTML = services.RenderPostText(post.Slug, post.Text)
 		post.Author.Bio = getPgtypeTextValue(bio)
 		post.Author.AvatarURL = getPgtypeTextPtr(avatarURL)
 
 		posts = append(posts, &post)
 	}
 
-	return posts, total, nil
+	return posts, total, rows.Err()
 }
 
-func (h *SearchHandler) searchUsers(ctx context.Context, query string, currentUserID uuid.UUID, limit, offset int) ([]*services.UserResponse, int, error) {
+func (h *SearchHandler) searchUsers(ctx context.Context, query string, orgID, currentUserID uuid.UUID, limit, offset int) ([]*services.UserResponse, int, error) {
 	var total int
-	err := h.db.QueryRow(ctx, "SELECT COUNT(*) FROM users WHERE username ILIKE '%' || $1 || '%' OR bio ILIKE '%' || $1 || '%'", query).Scan(&total)
+	err := h.db.Reader().QueryRow(ctx, `
+		SELECT COUNT(*) FROM users
+		WHERE (username ILIKE '%' || $1 || '%' OR bio ILIKE '%' || $1 || '%')
+		  AND org_id = $3 AND (NOT shadow_banned OR id = $2)`, query, currentUserID, orgID).Scan(&total)
 	if err != nil {
 		return nil, 0, err
 	}
 
-	rows, err := h.db.Query(ctx, `
+	rows, err := h.db.Reader().Query(ctx, `
 		SELECT u.id, u.username, u.email, u.bio, u.avatar_url,
-		       COALESCE(f.followers_count, 0), COALESCE(ff.following_count, 0),
+		       u.followers_count, u.following_count,
 		       CASE WHEN fl.follower_id IS NOT NULL THEN true ELSE false END as is_following
 		FROM users u
-		LEFT JOIN (
-		    SELECT followee_id, COUNT(*) as followers_count
-		    FROM follows GROUP BY followee_id
-		) f ON u.id = f.followee_id
-		LEFT JOIN (
-		    SELECT follower_id, COUNT(*) as following_count
-		    FROM follows GROUP BY follower_id
-		) ff ON u.id = ff.follower_id
 		LEFT JOIN follows fl ON fl.followee_id = u.id AND fl.follower_id = $1
-		WHERE u.username ILIKE '%' || $2 || '%' OR u.bio ILIKE '%' || $2 || '%'
+		WHERE (u.username ILIKE '%' || $2 || '%' OR u.bio ILIKE '%' || $2 || '%')
+		  AND u.org_id = $5 AND (NOT u.shadow_banned OR u.id = $1)
 		ORDER BY u.username
-		LIMIT $3 OFFSET $4`, currentUserID, query, limit, offset)
+		LIMIT $3 OFFSET $4`, currentUserID, query, limit, offset, orgID)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"bailanysta/api/internal/services"
+)
+
+const (
+	suggestLimit        = 3
+	suggestMaxQuery     = 100
+	suggestPostTextSize = 120
+)
+
+// Suggestions are the quick results shown while typing. They are the same
+// for everyone in an organization, so they leave out shadow-banned users
+// and posts of private accounts rather than checking the viewer.
+type Suggestions struct {
+	Query    string               `json:"query"`
+	Users    []*UserSuggestion    `json:"users"`
+	Hashtags []*HashtagSuggestion `json:"hashtags"`
+	Posts    []*PostSuggestion    `json:"posts"`
+}
+
+type UserSuggestion struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	AvatarURL *string   `json:"avatar_url,omitempty"`
+}
+
+type HashtagSuggestion struct {
+	Tag       string `json:"tag"`
+	PostCount int    `json:"post_count"`
+}
+
+type PostSuggestion struct {
+	ID             uuid.UUID `json:"id"`
+	Slug           string    `json:"slug"`
+	Text           string    `json:"text"` // the start of the post
+	AuthorUsername string    `json:"author_username"`
+}
+
+// Suggest returns the top users, hashtags and posts for a prefix typed into
+// the search box. Results are cached per organization and prefix; when the
+// latency budget runs out the client gets empty lists rather than a wait.
+func (h *SearchHandler) Suggest(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("query"))
+	if query == "" {
+		h.respondWithError(w, "Query parameter is required", http.StatusBadRequest)
+		return
+	}
+	if len(query) > suggestMaxQuery {
+		h.respondWithError(w, "Query is too long", http.StatusBadRequest)
+		return
+	}
+
+	orgID, err := h.jwtManager.GetOrgIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Organization not found", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.suggestTimeout)
+	defer cancel()
+
+	key := orgID.String() + ":" + strings.ToLower(query)
+	suggestions, err := h.suggestions.Do(ctx, key, func(ctx context.Context) (*Suggestions, error) {
+		ctx, cancel := context.WithTimeout(ctx, h.suggestTimeout)
+		defer cancel()
+		return h.loadSuggestions(ctx, query, orgID)
+	})
+	cacheControl := "private, max-age=" + strconv.Itoa(int(h.suggestCacheTTL.Seconds()))
+	if errors.Is(err, context.DeadlineExceeded) {
+		h.logger.Warn("Search suggestions timed out", map[string]interface{}{
+			"query": query,
+		})
+		suggestions = &Suggestions{Users: []*UserSuggestion{}, Hashtags: []*HashtagSuggestion{}, Posts: []*PostSuggestion{}}
+		cacheControl = "no-store"
+	} else if err != nil {
+		h.logger.Error("Failed to get search suggestions", map[string]interface{}{
+			"error": err.Error(),
+			"query": query,
+		})
+		h.respondWithError(w, "Failed to get suggestions", http.StatusInternalServerError)
+		return
+	}
+
+	response := *suggestions
+	response.Query = query
+	w.Header().Set("Cache-Control", cacheControl)
+	h.respondWithJSON(w, response, http.StatusOK)
+}
+
+// loadSuggestions sends the three lookups as one batch. Users and hashtags
+// match by prefix on their lower(...) text_pattern_ops indexes; posts need
+// three characters for the trigram index.
+func (h *SearchHandler) loadSuggestions(ctx context.Context, query string, orgID uuid.UUID) (*Suggestions, error) {
+	suggestions := &Suggestions{Users: []*UserSuggestion{}, Hashtags: []*HashtagSuggestion{}, Posts: []*PostSuggestion{}}
+	prefix := escapeLike(strings.ToLower(strings.TrimPrefix(query, "@"))) + "%"
+
+	batch := &pgx.Batch{}
+	batch.Queue(`
+		SELECT id, username, avatar_url FROM users
+		WHERE org_id = $1 AND NOT shadow_banned AND lower(username) LIKE $2
+		ORDER BY followers_count DESC, username
+		LIMIT $3`, orgID, prefix, suggestLimit).Query(func(rows pgx.Rows) error {
+		for rows.Next() {
+			var user UserSuggestion
+			var avatarURL pgtype.Text
+			if err := rows.Scan(&user.ID, &user.Username, &avatarURL); err != nil {
+				return err
+			}
+			user.AvatarURL = getPgtypeTextPtr(avatarURL)
+			suggestions.Users = append(suggestions.Users, &user)
+		}
+		return rows.Err()
+	})
+	batch.Queue(`
+		SELECT h.display, COUNT(*) FROM hashtags h
+		JOIN post_hashtags ph ON ph.hashtag_id = h.id
+		JOIN posts p ON p.id = ph.post_id AND p.org_id = $1 AND p.group_id IS NULL
+		WHERE lower(h.tag) LIKE $2
+		GROUP BY h.id
+		ORDER BY COUNT(*) DESC, h.tag
+		LIMIT $3`, orgID, escapeLike(services.CanonicalHashtag(strings.TrimPrefix(query, "#")))+"%", suggestLimit).Query(func(rows pgx.Rows) error {
+		for rows.Next() {
+			var hashtag HashtagSuggestion
+			if err := rows.Scan(&hashtag.Tag, &hashtag.PostCount); err != nil {
+				return err
+			}
+			suggestions.Hashtags = append(suggestions.Hashtags, &hashtag)
+		}
+		return rows.Err()
+	})
+	if len([]rune(query)) >= minTextQuery {
+		batch.Queue(`
+			SELECT p.id, p.slug, left(p.text, $3), u.username
+			FROM posts p
+			JOIN users u ON p.author_id = u.id
+			WHERE p.org_id = $1 AND p.group_id IS NULL AND NOT u.shadow_banned AND NOT u.is_private
+			  AND p.text ILIKE '%' || $2 || '%'
+			ORDER BY p.created_at DESC
+			LIMIT $4`, orgID, escapeLike(query), suggestPostTextSize, suggestLimit).Query(func(rows pgx.Rows) error {
+			for rows.Next() {
+				var post PostSuggestion
+				if err := rows.Scan(&post.ID, &post.Slug, &post.Text, &post.AuthorUsername); err != nil {
+					return err
+				}
+				suggestions.Posts = append(suggestions.Posts, &post)
+			}
+			return rows.Err()
+		})
+	}
+
+	if err := h.db.Reader().SendBatch(ctx, batch).Close(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	return suggestions, nil
+}
+
+// escapeLike escapes LIKE wildcards so s matches literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
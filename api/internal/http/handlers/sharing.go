@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"bailanysta/api/internal/pkg/auth"
+	"bailanysta/api/internal/pkg/logger"
+	"bailanysta/api/internal/services"
+)
+
+// Share images are fetched by crawlers without credentials and change only
+// when a post is edited. Metadata is cached for less so edited titles show
+// up sooner in new unfurls.
+const (
+	sharingCacheControl     = "public, max-age=3600"
+	sharingMetaCacheControl = "public, max-age=300"
+)
+
+type SharingHandler struct {
+	sharingService *services.SharingService
+	logger         *logger.Logger
+	jwtManager     *auth.JWTManager
+}
+
+func NewSharingHandler(sharingService *services.SharingService, logger *logger.Logger, jwtManager *auth.JWTManager) *SharingHandler {
+	return &SharingHandler{
+		sharingService: sharingService,
+		logger:         logger,
+		jwtManager:     jwtManager,
+	}
+}
+
+// GetMeta serves /posts/{id}/meta: the title, description, image and
+// canonical URL of a public post, for a server-side renderer or edge
+// function to put in Open Graph tags without fetching the whole post
+func (h *SharingHandler) GetMeta(w http.ResponseWriter, r *http.Request) {
+	orgID, err := h.jwtManager.GetOrgIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Post not found", http.StatusNotFound)
+		return
+	}
+
+	postID := chi.URLParam(r, "id")
+	meta, err := h.sharingService.Meta(r.Context(), orgID, postID)
+	if err != nil {
+		if err.Error() == "post not found" {
+			h.respondWithError(w, "Post not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("Failed to get post metadata", map[string]interface{}{
+			"error":   err.Error(),
+			"post_id": postID,
+		})
+		h.respondWithError(w, "Failed to get post metadata", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", sharingMetaCacheControl)
+	h.respondWithJSON(w, meta, http.StatusOK)
+}
+
+// GetShareCard serves /posts/{id}/share-card.png, the og:image of a post
+func (h *SharingHandler) GetShareCard(w http.ResponseWriter, r *http.Request) {
+	h.servePNG(w, r, "share card", h.sharingService.ShareCard)
+}
+
+// GetQRCode serves /posts/{id}/qr.png, a QR code of the post's link
+func (h *SharingHandler) GetQRCode(w http.ResponseWriter, r *http.Request) {
+	h.servePNG(w, r, "QR code", h.sharingService.QRCode)
+}
+
+func (h *SharingHandler) servePNG(w http.ResponseWriter, r *http.Request, what string,
+	render func(ctx context.Context, orgID uuid.UUID, idOrSlug string) ([]byte, error)) {
+	orgID, err := h.jwtManager.GetOrgIDFromContext(r.Context())
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	postID := chi.URLParam(r, "id")
+	image, err := render(r.Context(), orgID, postID)
+	if err != nil {
+		if err.Error() == "post not found" {
+			http.NotFound(w, r)
+			return
+		}
+		h.logger.Error("Failed to render "+what, map[string]interface{}{
+			"error":   err.Error(),
+			"post_id": postID,
+		})
+		http.Error(w, "Failed to render image", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Content-Length", strconv.Itoa(len(image)))
+	w.Header().Set("Cache-Control", sharingCacheControl)
+	w.WriteHeader(http.StatusOK)
+	w.Write(image)
+}
+
+func (h *SharingHandler) respondWithJSON(w http.ResponseWriter, data interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *SharingHandler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	h.respondWithJSON(w, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    getErrorCode(statusCode),
+			"message": message,
+		},
+	}, statusCode)
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"bailanysta/api/internal/pkg/logger"
+	"bailanysta/api/internal/services"
+)
+
+type ShortLinksHandler struct {
+	shortLinkService *services.ShortLinkService
+	logger           *logger.Logger
+}
+
+func NewShortLinksHandler(shortLinkService *services.ShortLinkService, logger *logger.Logger) *ShortLinksHandler {
+	return &ShortLinksHandler{
+		shortLinkService: shortLinkService,
+		logger:           logger,
+	}
+}
+
+// Redirect serves /l/{code}: it counts the click and sends the browser on to
+// the post's link. A failure to count never holds up the redirect.
+func (h *ShortLinksHandler) Redirect(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+	postID, url, err := h.shortLinkService.Resolve(r.Context(), code)
+	if err != nil {
+		if err.Error() != "link not found" {
+			h.logger.Error("Failed to resolve short link", map[string]interface{}{
+				"error": err.Error(),
+				"code":  code,
+			})
+		}
+		http.NotFound(w, r)
+		return
+	}
+
+	if err := h.shortLinkService.RecordClick(r.Context(), postID, url, r.UserAgent()); err != nil {
+		h.logger.Error("Failed to record link click", map[string]interface{}{
+			"error":   err.Error(),
+			"post_id": postID,
+		})
+	}
+
+	// Every click has to reach us to be counted, and the destination need
+	// not learn which post it came from
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	http.Redirect(w, r, url, http.StatusFound)
+}
@@ -7,6 +7,7 @@ import (
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 
 	"bailanysta/api/internal/pkg/auth"
@@ -17,6 +18,7 @@ import (
 type SocialHandler struct {
 	socialService *services.SocialService
 	logger        *logger.Logger
+	validator     *validator.Validate
 	jwtManager    *auth.JWTManager
 }
 
@@ -24,6 +26,7 @@ func NewSocialHandler(socialService *services.SocialService, logger *logger.Logg
 	return &SocialHandler{
 		socialService: socialService,
 		logger:        logger,
+		validator:     validator.New(),
 		jwtManager:    jwtManager,
 	}
 }
@@ -50,11 +53,32 @@ func (h *SocialHandler) GetFeed(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	posts, err := h.socialService.GetFeed(r.Context(), userID, limit, offset)
+	sort := r.URL.Query().Get("sort")
+	if sort == "" {
+		sort = "latest"
+	}
+
+	filter, err := services.ParseFeedFilter(r.URL.Query().Get("type"))
+	if err != nil {
+		h.respondWithError(w, "Invalid type parameter, expected all, questions or unanswered", http.StatusBadRequest)
+		return
+	}
+
+	var posts []*services.FeedPost
+	switch sort {
+	case "latest":
+		posts, err = h.socialService.GetFeed(r.Context(), userID, filter, limit, offset)
+	case "ranked":
+		posts, err = h.socialService.GetRankedFeed(r.Context(), userID, filter, limit, offset)
+	default:
+		h.respondWithError(w, "Invalid sort parameter, expected latest or ranked", http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		h.logger.Error("Failed to get feed", map[string]interface{}{
 			"error":   err.Error(),
 			"user_id": userID,
+			"sort":    sort,
 		})
 		h.respondWithError(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -64,11 +88,62 @@ func (h *SocialHandler) GetFeed(w http.ResponseWriter, r *http.Request) {
 		"posts":  posts,
 		"limit":  limit,
 		"offset": offset,
+		"sort":   sort,
+	}, http.StatusOK)
+}
+
+// GetFeedUpdates handles GET /feed/updates?since_id=&limit=, returning feed
+// posts newer than since_id and their total count so clients can offer to
+// show them without reloading the feed. limit=0 returns only the count.
+func (h *SocialHandler) GetFeedUpdates(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sinceID, err := uuid.Parse(r.URL.Query().Get("since_id"))
+	if err != nil {
+		h.respondWithError(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	limit := 20
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.Atoi(limitParam); err == nil && parsedLimit >= 0 && parsedLimit <= 50 {
+			limit = parsedLimit
+		}
+	}
+
+	posts, count, err := h.socialService.GetFeedUpdates(r.Context(), userID, sinceID, limit)
+	if err != nil {
+		if err.Error() == "post not found" {
+			h.respondWithError(w, "Post not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("Failed to get feed updates", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		h.respondWithError(w, "Failed to get feed updates", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"posts":    posts,
+		"count":    count,
+		"since_id": sinceID,
 	}, http.StatusOK)
 }
 
 func (h *SocialHandler) GetCourses(w http.ResponseWriter, r *http.Request) {
-	courses, err := h.socialService.GetCourses(r.Context())
+	orgID, err := h.jwtManager.GetOrgIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Organization not found", http.StatusBadRequest)
+		return
+	}
+
+	courses, err := h.socialService.GetCourses(r.Context(), orgID)
 	if err != nil {
 		h.logger.Error("Failed to get courses", map[string]interface{}{
 			"error": err.Error(),
@@ -82,6 +157,78 @@ func (h *SocialHandler) GetCourses(w http.ResponseWriter, r *http.Request) {
 	}, http.StatusOK)
 }
 
+// CreateCourse is for teachers; the course belongs to their organization
+func (h *SocialHandler) CreateCourse(w http.ResponseWriter, r *http.Request) {
+	orgID, err := h.jwtManager.GetOrgIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req services.CreateCourseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		h.respondWithError(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	course, err := h.socialService.CreateCourse(r.Context(), orgID, req)
+	if err != nil {
+		h.logger.Error("Failed to create course", map[string]interface{}{
+			"error": err.Error(),
+		})
+		h.respondWithError(w, "Failed to create course", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, course, http.StatusCreated)
+}
+
+func (h *SocialHandler) CreateModule(w http.ResponseWriter, r *http.Request) {
+	courseID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondWithError(w, "Invalid course ID", http.StatusBadRequest)
+		return
+	}
+
+	orgID, err := h.jwtManager.GetOrgIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req services.CreateModuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		h.respondWithError(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	module, err := h.socialService.CreateModule(r.Context(), orgID, courseID, req)
+	if err != nil {
+		if err.Error() == "course not found" {
+			h.respondWithError(w, "Course not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("Failed to create module", map[string]interface{}{
+			"error":     err.Error(),
+			"course_id": courseID,
+		})
+		h.respondWithError(w, "Failed to create module", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, module, http.StatusCreated)
+}
+
 func (h *SocialHandler) GetModulesByCourse(w http.ResponseWriter, r *http.Request) {
 	courseIDParam := chi.URLParam(r, "id")
 	courseID, err := uuid.Parse(courseIDParam)
@@ -90,7 +237,13 @@ func (h *SocialHandler) GetModulesByCourse(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	modules, err := h.socialService.GetModulesByCourse(r.Context(), courseID)
+	orgID, err := h.jwtManager.GetOrgIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Organization not found", http.StatusBadRequest)
+		return
+	}
+
+	modules, err := h.socialService.GetModulesByCourse(r.Context(), orgID, courseID)
 	if err != nil {
 		h.logger.Error("Failed to get modules", map[string]interface{}{
 			"error":     err.Error(),
@@ -119,8 +272,12 @@ func (h *SocialHandler) FollowUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.socialService.FollowUser(r.Context(), followerID, followeeID)
+	requested, err := h.socialService.FollowUser(r.Context(), followerID, followeeID)
 	if err != nil {
+		if err.Error() == "account is throttled" {
+			h.respondWithError(w, "Too many likes or follows; try again later", http.StatusTooManyRequests)
+			return
+		}
 		h.logger.Error("Failed to follow user", map[string]interface{}{
 			"error":       err.Error(),
 			"follower_id": followerID,
@@ -130,6 +287,14 @@ func (h *SocialHandler) FollowUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if requested {
+		h.respondWithJSON(w, map[string]interface{}{
+			"message":   "Follow request sent",
+			"requested": true,
+		}, http.StatusOK)
+		return
+	}
+
 	h.logger.Info("User followed successfully", map[string]interface{}{
 		"follower_id": followerID,
 		"followee_id": followeeID,
@@ -175,6 +340,361 @@ func (h *SocialHandler) UnfollowUser(w http.ResponseWriter, r *http.Request) {
 	}, http.StatusOK)
 }
 
+func (h *SocialHandler) GetFollowRequests(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	limit := 20
+	offset := 0
+
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.Atoi(limitParam); err == nil && parsedLimit > 0 && parsedLimit <= 100 {
+			limit = parsedLimit
+		}
+	}
+
+	if offsetParam := r.URL.Query().Get("offset"); offsetParam != "" {
+		if parsedOffset, err := strconv.Atoi(offsetParam); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	requests, err := h.socialService.GetFollowRequests(r.Context(), userID, limit, offset)
+	if err != nil {
+		h.logger.Error("Failed to get follow requests", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		h.respondWithError(w, "Failed to get follow requests", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"requests": requests,
+		"limit":    limit,
+		"offset":   offset,
+	}, http.StatusOK)
+}
+
+func (h *SocialHandler) ApproveFollowRequest(w http.ResponseWriter, r *http.Request) {
+	h.resolveFollowRequest(w, r, true)
+}
+
+func (h *SocialHandler) DeclineFollowRequest(w http.ResponseWriter, r *http.Request) {
+	h.resolveFollowRequest(w, r, false)
+}
+
+func (h *SocialHandler) resolveFollowRequest(w http.ResponseWriter, r *http.Request, approve bool) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	requesterID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondWithError(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	message := "Follow request approved"
+	if approve {
+		err = h.socialService.ApproveFollowRequest(r.Context(), userID, requesterID)
+	} else {
+		message = "Follow request declined"
+		err = h.socialService.DeclineFollowRequest(r.Context(), userID, requesterID)
+	}
+	if err != nil {
+		if err.Error() == "follow request not found" {
+			h.respondWithError(w, "Follow request not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("Failed to resolve follow request", map[string]interface{}{
+			"error":        err.Error(),
+			"user_id":      userID,
+			"requester_id": requesterID,
+			"approve":      approve,
+		})
+		h.respondWithError(w, "Failed to resolve follow request", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"message": message,
+	}, http.StatusOK)
+}
+
+func (h *SocialHandler) UpdatePrivacy(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		IsPrivate    *bool `json:"is_private"`
+		ShowPresence *bool `json:"show_presence"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.IsPrivate == nil && req.ShowPresence == nil {
+		h.respondWithError(w, "is_private or show_presence is required", http.StatusBadRequest)
+		return
+	}
+
+	if req.IsPrivate != nil {
+		err = h.socialService.SetPrivate(r.Context(), userID, *req.IsPrivate)
+	}
+	if err == nil && req.ShowPresence != nil {
+		err = h.socialService.SetShowPresence(r.Context(), userID, *req.ShowPresence)
+	}
+	if err != nil {
+		h.logger.Error("Failed to update privacy", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		h.respondWithError(w, "Failed to update privacy", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{}
+	if req.IsPrivate != nil {
+		response["is_private"] = *req.IsPrivate
+	}
+	if req.ShowPresence != nil {
+		response["show_presence"] = *req.ShowPresence
+	}
+	h.respondWithJSON(w, response, http.StatusOK)
+}
+
+// GetFollowers lists the followers of the user in the URL, with presence
+func (h *SocialHandler) GetFollowers(w http.ResponseWriter, r *http.Request) {
+	h.listConnections(w, r, h.socialService.GetFollowers)
+}
+
+// GetFollowing lists the users followed by the user in the URL, with presence
+func (h *SocialHandler) GetFollowing(w http.ResponseWriter, r *http.Request) {
+	h.listConnections(w, r, h.socialService.GetFollowing)
+}
+
+func (h *SocialHandler) listConnections(w http.ResponseWriter, r *http.Request,
+	list func(ctx context.Context, userID, viewerID uuid.UUID, limit, offset int) ([]*services.UserResponse, error)) {
+	viewerID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondWithError(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	limit := 20
+	offset := 0
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.Atoi(limitParam); err == nil && parsedLimit > 0 && parsedLimit <= 100 {
+			limit = parsedLimit
+		}
+	}
+	if offsetParam := r.URL.Query().Get("offset"); offsetParam != "" {
+		if parsedOffset, err := strconv.Atoi(offsetParam); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	users, err := list(r.Context(), userID, viewerID, limit, offset)
+	if err != nil {
+		switch err.Error() {
+		case "user not found":
+			h.respondWithError(w, "User not found", http.StatusNotFound)
+		case "account is private":
+			h.respondWithError(w, "This account is private", http.StatusForbidden)
+		default:
+			h.logger.Error("Failed to list connections", map[string]interface{}{
+				"error":   err.Error(),
+				"user_id": userID,
+			})
+			h.respondWithError(w, "Failed to get users", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"users":  users,
+		"limit":  limit,
+		"offset": offset,
+	}, http.StatusOK)
+}
+
+// UpdateFeedPreferences sets how the user's feeds treat AI-generated posts
+// and which post languages they show; omitted fields are left unchanged
+func (h *SocialHandler) UpdateFeedPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		AIContent *string   `json:"ai_content"`
+		Languages *[]string `json:"languages"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.AIContent == nil && req.Languages == nil {
+		h.respondWithError(w, "ai_content or languages is required", http.StatusBadRequest)
+		return
+	}
+
+	response := map[string]interface{}{}
+	if req.AIContent != nil {
+		if err := h.socialService.SetAIContentPreference(r.Context(), userID, *req.AIContent); err != nil {
+			if err.Error() == "invalid ai_content preference" {
+				h.respondWithError(w, "ai_content must be show, downrank or hide", http.StatusBadRequest)
+				return
+			}
+			h.logger.Error("Failed to update feed preferences", map[string]interface{}{
+				"error":   err.Error(),
+				"user_id": userID,
+			})
+			h.respondWithError(w, "Failed to update feed preferences", http.StatusInternalServerError)
+			return
+		}
+		response["ai_content"] = *req.AIContent
+	}
+
+	if req.Languages != nil {
+		languages, err := h.socialService.SetFeedLanguages(r.Context(), userID, *req.Languages)
+		if err != nil {
+			if err.Error() == "invalid feed language" {
+				h.respondWithError(w, "languages may only contain en, kk and ru", http.StatusBadRequest)
+				return
+			}
+			h.logger.Error("Failed to update feed preferences", map[string]interface{}{
+				"error":   err.Error(),
+				"user_id": userID,
+			})
+			h.respondWithError(w, "Failed to update feed preferences", http.StatusInternalServerError)
+			return
+		}
+		response["languages"] = languages
+	}
+
+	h.respondWithJSON(w, response, http.StatusOK)
+}
+
+func (h *SocialHandler) BlockUser(w http.ResponseWriter, r *http.Request) {
+	blockerID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userIDParam := chi.URLParam(r, "id")
+	blockedID, err := uuid.Parse(userIDParam)
+	if err != nil {
+		h.respondWithError(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.socialService.BlockUser(r.Context(), blockerID, blockedID)
+	if err != nil {
+		h.logger.Error("Failed to block user", map[string]interface{}{
+			"error":      err.Error(),
+			"blocker_id": blockerID,
+			"blocked_id": blockedID,
+		})
+		h.respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.logger.Info("User blocked successfully", map[string]interface{}{
+		"blocker_id": blockerID,
+		"blocked_id": blockedID,
+	})
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"message": "User blocked successfully",
+	}, http.StatusOK)
+}
+
+func (h *SocialHandler) UnblockUser(w http.ResponseWriter, r *http.Request) {
+	blockerID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userIDParam := chi.URLParam(r, "id")
+	blockedID, err := uuid.Parse(userIDParam)
+	if err != nil {
+		h.respondWithError(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.socialService.UnblockUser(r.Context(), blockerID, blockedID)
+	if err != nil {
+		h.logger.Error("Failed to unblock user", map[string]interface{}{
+			"error":      err.Error(),
+			"blocker_id": blockerID,
+			"blocked_id": blockedID,
+		})
+		h.respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.logger.Info("User unblocked successfully", map[string]interface{}{
+		"blocker_id": blockerID,
+		"blocked_id": blockedID,
+	})
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"message": "User unblocked successfully",
+	}, http.StatusOK)
+}
+
+func (h *SocialHandler) GetRecommendedUsers(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	limit := 10
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.Atoi(limitParam); err == nil && parsedLimit > 0 && parsedLimit <= 50 {
+			limit = parsedLimit
+		}
+	}
+
+	recommendations, err := h.socialService.GetRecommendedUsers(r.Context(), userID, limit)
+	if err != nil {
+		h.logger.Error("Failed to get recommended users", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		h.respondWithError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if recommendations == nil {
+		recommendations = []*services.UserRecommendation{}
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"users": recommendations,
+		"limit": limit,
+	}, http.StatusOK)
+}
+
 func (h *SocialHandler) respondWithJSON(w http.ResponseWriter, data interface{}, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"bailanysta/api/internal/pkg/auth"
+	"bailanysta/api/internal/pkg/logger"
+	"bailanysta/api/internal/services"
+)
+
+const (
+	// streamHeartbeat keeps idle streams from being closed by proxies
+	streamHeartbeat = 25 * time.Second
+	// streamOverlap re-reads posts this far behind the cursor, since a post
+	// commits a little after its created_at and could otherwise be skipped
+	streamOverlap = 10 * time.Second
+	// streamMaxReplay bounds how far back a resumed stream looks
+	streamMaxReplay = 24 * time.Hour
+)
+
+// StreamHandler serves server-sent events for clients that can't use
+// WebSockets. The stream polls on the client's behalf, so it works the same
+// whichever replica a post or notification was written on.
+type StreamHandler struct {
+	socialService       *services.SocialService
+	notificationService *services.NotificationService
+	logger              *logger.Logger
+	jwtManager          *auth.JWTManager
+	pollInterval        time.Duration
+}
+
+func NewStreamHandler(socialService *services.SocialService, notificationService *services.NotificationService, logger *logger.Logger, jwtManager *auth.JWTManager, pollInterval time.Duration) *StreamHandler {
+	return &StreamHandler{
+		socialService:       socialService,
+		notificationService: notificationService,
+		logger:              logger,
+		jwtManager:          jwtManager,
+		pollInterval:        pollInterval,
+	}
+}
+
+// Stream handles GET /stream. It sends:
+//
+//	event: feed    data: {"post_ids": [...]}  new feed posts, newest first
+//	event: unread  data: {"count": N}         the unread notification count,
+//	                                          on connect and when it changes
+//
+// Every event's id is a feed position. A client reconnecting with
+// Last-Event-ID (or ?last_event_id= where the header can't be set) gets the
+// feed posts it missed; a post near the position may be sent again.
+func (h *StreamHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	now := time.Now()
+	cursor := now
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	resumed := lastEventID != ""
+	if resumed {
+		micros, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil {
+			h.respondWithError(w, "Invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+		cursor = time.UnixMicro(micros)
+		if oldest := now.Add(-streamMaxReplay); cursor.Before(oldest) {
+			cursor = oldest
+		}
+		if cursor.After(now) {
+			cursor = now
+		}
+	}
+
+	// The server write timeout is a backstop for ordinary requests; the
+	// stream is bounded by its route deadline instead
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	stream := &eventStream{w: w, rc: rc}
+	stream.retry(h.pollInterval)
+
+	ctx := r.Context()
+	ticker := time.NewTicker(h.pollInterval)
+	defer ticker.Stop()
+
+	seen := make(map[uuid.UUID]time.Time)
+	unread := -1
+	first := true
+	for {
+		refs, err := h.socialService.GetFeedPostsSince(ctx, userID, cursor.Add(-streamOverlap))
+		if err != nil && ctx.Err() == nil {
+			h.logger.Warn("Failed to poll feed for stream", map[string]interface{}{
+				"error":   err.Error(),
+				"user_id": userID,
+			})
+		}
+		var postIDs []uuid.UUID
+		for _, ref := range refs {
+			if _, ok := seen[ref.ID]; ok {
+				continue
+			}
+			seen[ref.ID] = ref.CreatedAt
+			postIDs = append(postIDs, ref.ID)
+			if ref.CreatedAt.After(cursor) {
+				cursor = ref.CreatedAt
+			}
+		}
+		for id, createdAt := range seen {
+			if createdAt.Before(cursor.Add(-streamOverlap)) {
+				delete(seen, id)
+			}
+		}
+		// A fresh stream starts at now; posts just behind it aren't new
+		if len(postIDs) > 0 && (resumed || !first) {
+			stream.send("feed", cursor, map[string]interface{}{"post_ids": postIDs})
+		}
+
+		count, err := h.notificationService.GetUnreadCount(ctx, userID)
+		if err != nil && ctx.Err() == nil {
+			h.logger.Warn("Failed to poll unread count for stream", map[string]interface{}{
+				"error":   err.Error(),
+				"user_id": userID,
+			})
+		}
+		if err == nil && count != unread {
+			unread = count
+			stream.send("unread", cursor, map[string]interface{}{"count": count})
+		}
+
+		if time.Since(stream.lastWrite) >= streamHeartbeat {
+			stream.comment("keepalive")
+		}
+		if stream.err != nil {
+			return
+		}
+		first = false
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// eventStream writes server-sent events, flushing each one. After a write
+// fails the rest are skipped and err is set.
+type eventStream struct {
+	w         http.ResponseWriter
+	rc        *http.ResponseController
+	lastWrite time.Time
+	err       error
+}
+
+func (s *eventStream) retry(interval time.Duration) {
+	s.write(fmt.Sprintf("retry: %d\n\n", interval.Milliseconds()))
+}
+
+func (s *eventStream) send(event string, position time.Time, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		s.err = err
+		return
+	}
+	s.write(fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", position.UnixMicro(), event, payload))
+}
+
+func (s *eventStream) comment(text string) {
+	s.write(": " + text + "\n\n")
+}
+
+func (s *eventStream) write(chunk string) {
+	if s.err != nil {
+		return
+	}
+	if _, err := s.w.Write([]byte(chunk)); err != nil {
+		s.err = err
+		return
+	}
+	s.err = s.rc.Flush()
+	s.lastWrite = time.Now()
+}
+
+func (h *StreamHandler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    getErrorCode(statusCode),
+			"message": message,
+		},
+	})
+}
+
+func (h *StreamHandler) getUserIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	return h.jwtManager.GetUserIDFromContext(ctx)
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"bailanysta/api/internal/pkg/auth"
+	"bailanysta/api/internal/pkg/logger"
+	"bailanysta/api/internal/services"
+)
+
+// Feeds and the sitemap are public and change slowly
+const syndicationCacheControl = "public, max-age=300"
+
+type SyndicationHandler struct {
+	syndicationService *services.SyndicationService
+	logger             *logger.Logger
+	jwtManager         *auth.JWTManager
+}
+
+func NewSyndicationHandler(syndicationService *services.SyndicationService, logger *logger.Logger, jwtManager *auth.JWTManager) *SyndicationHandler {
+	return &SyndicationHandler{
+		syndicationService: syndicationService,
+		logger:             logger,
+		jwtManager:         jwtManager,
+	}
+}
+
+// GetUserFeed serves /feeds/user/{username}.rss
+func (h *SyndicationHandler) GetUserFeed(w http.ResponseWriter, r *http.Request) {
+	// Usernames may contain dots, so the extension is stripped here rather
+	// than matched by the route
+	username, ok := strings.CutSuffix(chi.URLParam(r, "file"), ".rss")
+	if !ok || username == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	orgID, err := h.jwtManager.GetOrgIDFromContext(r.Context())
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	feed, err := h.syndicationService.UserFeed(r.Context(), orgID, username)
+	if err != nil {
+		if err.Error() == "user not found" {
+			http.NotFound(w, r)
+			return
+		}
+		h.logger.Error("Failed to build user feed", map[string]interface{}{
+			"error":    err.Error(),
+			"username": username,
+		})
+		http.Error(w, "Failed to build feed", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithXML(w, "application/rss+xml; charset=utf-8", feed)
+}
+
+// GetHashtagFeed serves /feeds/hashtag/{tag}.rss
+func (h *SyndicationHandler) GetHashtagFeed(w http.ResponseWriter, r *http.Request) {
+	tag, ok := strings.CutSuffix(chi.URLParam(r, "file"), ".rss")
+	tag = strings.TrimPrefix(tag, "#")
+	if !ok || tag == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	orgID, err := h.jwtManager.GetOrgIDFromContext(r.Context())
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	feed, err := h.syndicationService.HashtagFeed(r.Context(), orgID, tag)
+	if err != nil {
+		h.logger.Error("Failed to build hashtag feed", map[string]interface{}{
+			"error": err.Error(),
+			"tag":   tag,
+		})
+		http.Error(w, "Failed to build feed", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithXML(w, "application/rss+xml; charset=utf-8", feed)
+}
+
+func (h *SyndicationHandler) GetSitemap(w http.ResponseWriter, r *http.Request) {
+	orgID, err := h.jwtManager.GetOrgIDFromContext(r.Context())
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	sitemap, err := h.syndicationService.Sitemap(r.Context(), orgID)
+	if err != nil {
+		h.logger.Error("Failed to build sitemap", map[string]interface{}{
+			"error": err.Error(),
+		})
+		http.Error(w, "Failed to build sitemap", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithXML(w, "application/xml; charset=utf-8", sitemap)
+}
+
+func (h *SyndicationHandler) respondWithXML(w http.ResponseWriter, contentType string, data interface{}) {
+	body, err := xml.MarshalIndent(data, "", "  ")
+	if err != nil {
+		h.logger.Error("Failed to encode XML", map[string]interface{}{
+			"error": err.Error(),
+		})
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", syndicationCacheControl)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(xml.Header))
+	w.Write(body)
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -15,18 +17,20 @@ import (
 )
 
 type UsersHandler struct {
-	authService   *services.AuthService
-	socialService *services.SocialService
-	logger        *logger.Logger
-	jwtManager    *auth.JWTManager
+	authService        *services.AuthService
+	socialService      *services.SocialService
+	profileViewService *services.ProfileViewService
+	logger             *logger.Logger
+	jwtManager         *auth.JWTManager
 }
 
-func NewUsersHandler(authService *services.AuthService, socialService *services.SocialService, logger *logger.Logger, jwtManager *auth.JWTManager) *UsersHandler {
+func NewUsersHandler(authService *services.AuthService, socialService *services.SocialService, profileViewService *services.ProfileViewService, logger *logger.Logger, jwtManager *auth.JWTManager) *UsersHandler {
 	return &UsersHandler{
-		authService:   authService,
-		socialService: socialService,
-		logger:        logger,
-		jwtManager:    jwtManager,
+		authService:        authService,
+		socialService:      socialService,
+		profileViewService: profileViewService,
+		logger:             logger,
+		jwtManager:         jwtManager,
 	}
 }
 
@@ -46,6 +50,9 @@ func (h *UsersHandler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
 		h.respondWithError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if impersonation, ok := r.Context().Value("impersonation").(*services.ImpersonationInfo); ok {
+		user.Impersonation = impersonation
+	}
 
 	h.respondWithJSON(w, user, http.StatusOK)
 }
@@ -100,32 +107,30 @@ func (h *UsersHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	orgID, err := h.jwtManager.GetOrgIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	// Get total count
 	var total int
-	err = h.authService.GetDB().QueryRow(r.Context(), "SELECT COUNT(*) FROM users WHERE id != $1", currentUserID).Scan(&total)
+	err = h.authService.GetReadDB().QueryRow(r.Context(), "SELECT COUNT(*) FROM users WHERE id != $1 AND org_id = $2", currentUserID, orgID).Scan(&total)
 	if err != nil {
 		h.respondWithError(w, "Failed to get users count", http.StatusInternalServerError)
 		return
 	}
 
 	// Get users with follow stats
-	rows, err := h.authService.GetDB().Query(r.Context(), `
+	rows, err := h.authService.GetReadDB().Query(r.Context(), `
 		SELECT u.id, u.username, u.email, u.bio, u.avatar_url,
-		       COALESCE(f.followers_count, 0), COALESCE(ff.following_count, 0),
+		       u.followers_count, u.following_count,
 		       CASE WHEN fl.follower_id IS NOT NULL THEN true ELSE false END as is_following
 		FROM users u
-		LEFT JOIN (
-		    SELECT followee_id, COUNT(*) as followers_count
-		    FROM follows GROUP BY followee_id
-		) f ON u.id = f.followee_id
-		LEFT JOIN (
-		    SELECT follower_id, COUNT(*) as following_count
-		    FROM follows GROUP BY follower_id
-		) ff ON u.id = ff.follower_id
 		LEFT JOIN follows fl ON fl.followee_id = u.id AND fl.follower_id = $1
-		WHERE u.id != $1
+		WHERE u.id != $1 AND u.org_id = $4
 		ORDER BY u.username
-		LIMIT $2 OFFSET $3`, currentUserID, limit, offset)
+		LIMIT $2 OFFSET $3`, currentUserID, limit, offset, orgID)
 	if err != nil {
 		h.respondWithError(w, "Failed to get users", http.StatusInternalServerError)
 		return
@@ -174,15 +179,53 @@ func (h *UsersHandler) GetUserByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.respondWithProfile(w, r, userID)
+}
+
+// GetUserByUsername returns the profile for a username. A former username
+// gets a 301 pointing at the current one, with the user's ID in the body.
+func (h *UsersHandler) GetUserByUsername(w http.ResponseWriter, r *http.Request) {
+	username := chi.URLParam(r, "username")
+
+	userID, current, err := h.authService.ResolveUsername(r.Context(), username)
+	if err != nil {
+		if err.Error() == "user not found" {
+			h.respondWithError(w, "User not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("Failed to resolve username", map[string]interface{}{
+			"error":    err.Error(),
+			"username": username,
+		})
+		h.respondWithError(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	if current != username {
+		w.Header().Set("Location", "/api/v1/users/by-username/"+url.PathEscape(current))
+		h.respondWithJSON(w, map[string]interface{}{
+			"user_id":  userID,
+			"username": current,
+		}, http.StatusMovedPermanently)
+		return
+	}
+
+	h.respondWithProfile(w, r, userID)
+}
+
+func (h *UsersHandler) respondWithProfile(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
 	currentUserID, _ := h.getUserIDFromContext(r.Context())
+	orgID, _ := h.jwtManager.GetOrgIDFromContext(r.Context())
 
-	// Get basic user info
+	// Get basic user info; users of other organizations don't exist here
 	var user services.UserResponse
 	var bio, avatarURL string
-	err = h.authService.GetDB().QueryRow(r.Context(), `
-		SELECT username, email, bio, avatar_url
-		FROM users WHERE id = $1`, userID).Scan(
-		&user.Username, &user.Email, &bio, &avatarURL)
+	var lastActive *time.Time
+	var showPresence bool
+	err := h.authService.GetReadDB().QueryRow(r.Context(), `
+		SELECT username, email, bio, avatar_url, is_private, last_active_at, show_presence
+		FROM users WHERE id = $1 AND org_id = $2`, userID, orgID).Scan(
+		&user.Username, &user.Email, &bio, &avatarURL, &user.IsPrivate, &lastActive, &showPresence)
 	if err != nil {
 		h.respondWithError(w, "User not found", http.StatusNotFound)
 		return
@@ -191,6 +234,8 @@ func (h *UsersHandler) GetUserByID(w http.ResponseWriter, r *http.Request) {
 	user.ID = userID
 	user.Bio = bio
 	user.AvatarURL = &avatarURL
+	// Users always see their own presence, even when hiding it from others
+	user.Presence = services.PresenceOf(lastActive, showPresence || userID == currentUserID, time.Now())
 
 	// Get follow stats
 	stats, err := h.socialService.GetFollowStats(r.Context(), userID, currentUserID)
@@ -202,11 +247,267 @@ func (h *UsersHandler) GetUserByID(w http.ResponseWriter, r *http.Request) {
 		user.FollowersCount = stats.FollowersCount
 		user.FollowingCount = stats.FollowingCount
 		user.IsFollowing = stats.IsFollowing
+		user.FollowRequested = stats.FollowRequested
+	}
+
+	if currentUserID != uuid.Nil {
+		if err := h.profileViewService.RecordView(r.Context(), userID, currentUserID); err != nil {
+			h.logger.Warn("Failed to record profile view", map[string]interface{}{
+				"error":      err.Error(),
+				"profile_id": userID,
+			})
+		}
 	}
 
 	h.respondWithJSON(w, user, http.StatusOK)
 }
 
+// GetProfileViews returns how many people viewed the caller's profile each
+// day over the last ?days= days (default and max: the retention period)
+func (h *UsersHandler) GetProfileViews(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	days := h.profileViewService.RetentionDays()
+	if value := r.URL.Query().Get("days"); value != "" {
+		days, err = strconv.Atoi(value)
+		if err != nil || days < 1 || days > h.profileViewService.RetentionDays() {
+			h.respondWithError(w, "days is out of range", http.StatusBadRequest)
+			return
+		}
+	}
+
+	stats, err := h.profileViewService.GetProfileViews(r.Context(), userID, days)
+	if err != nil {
+		h.logger.Error("Failed to get profile views", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		h.respondWithError(w, "Failed to get profile views", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, stats, http.StatusOK)
+}
+
+// UpdateProfileViewSettings turns profile view tracking and identity
+// sharing on or off. Turning tracking off deletes the recorded views.
+func (h *UsersHandler) UpdateProfileViewSettings(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Enabled       *bool `json:"enabled"`
+		ShareIdentity *bool `json:"share_identity"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Enabled == nil && req.ShareIdentity == nil {
+		h.respondWithError(w, "enabled or share_identity is required", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.profileViewService.SetSettings(r.Context(), userID, req.Enabled, req.ShareIdentity)
+	if err != nil {
+		h.logger.Error("Failed to update profile view settings", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		h.respondWithError(w, "Failed to update profile view settings", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, settings, http.StatusOK)
+}
+
+// ChangeUsername renames the current user, at most once per cooldown period
+func (h *UsersHandler) ChangeUsername(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req services.ChangeUsernameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	change, err := h.authService.ChangeUsername(r.Context(), userID, req.Username)
+	if err != nil {
+		switch err.Error() {
+		case "invalid username":
+			h.respondWithError(w, "Username must be 3-50 letters, digits, '_', '.' or '-' and start with a letter, digit or '_'", http.StatusBadRequest)
+		case "username is unchanged":
+			h.respondWithError(w, "Username is unchanged", http.StatusBadRequest)
+		case "username is already taken":
+			h.respondWithError(w, "Username is already taken", http.StatusConflict)
+		case "username was changed recently":
+			h.respondWithError(w, "Username can only be changed once every 30 days", http.StatusTooManyRequests)
+		default:
+			h.logger.Error("Failed to change username", map[string]interface{}{
+				"error":   err.Error(),
+				"user_id": userID,
+			})
+			h.respondWithError(w, "Failed to change username", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.logger.Info("Username changed", map[string]interface{}{
+		"user_id":  userID,
+		"username": change.Username,
+	})
+
+	h.respondWithJSON(w, change, http.StatusOK)
+}
+
+// UpdateTimezone sets the IANA time zone used for the caller's digests,
+// activity days and streak reminders
+func (h *UsersHandler) UpdateTimezone(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req services.SetTimezoneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	timezone, err := h.authService.SetTimezone(r.Context(), userID, req.Timezone)
+	if err != nil {
+		switch err.Error() {
+		case "invalid timezone":
+			h.respondWithError(w, "timezone must be an IANA time zone such as Asia/Almaty", http.StatusBadRequest)
+		case "user not found":
+			h.respondWithError(w, "User not found", http.StatusNotFound)
+		default:
+			h.logger.Error("Failed to update timezone", map[string]interface{}{
+				"error":   err.Error(),
+				"user_id": userID,
+			})
+			h.respondWithError(w, "Failed to update timezone", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"timezone": timezone,
+	}, http.StatusOK)
+}
+
+// GetSessions lists the devices the caller is signed in on
+func (h *UsersHandler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessions, err := h.authService.GetSessions(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("Failed to get sessions", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		h.respondWithError(w, "Failed to get sessions", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"sessions": sessions,
+	}, http.StatusOK)
+}
+
+// RevokeSession signs the caller out of one of their sessions
+func (h *UsersHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessionID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondWithError(w, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.RevokeSession(r.Context(), userID, sessionID); err != nil {
+		if err.Error() == "session not found" {
+			h.respondWithError(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("Failed to revoke session", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		h.respondWithError(w, "Failed to revoke session", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteAccount deletes the current user's account, or with mode
+// "anonymize" keeps their posts and comments under a placeholder author
+func (h *UsersHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req services.DeleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Password == "" {
+		h.respondWithError(w, "password is required", http.StatusBadRequest)
+		return
+	}
+	if req.Mode != services.AccountDeletionDelete && req.Mode != services.AccountDeletionAnonymize {
+		h.respondWithError(w, "mode must be delete or anonymize", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.DeleteAccount(r.Context(), userID, req); err != nil {
+		switch err.Error() {
+		case "invalid password":
+			h.respondWithError(w, "Invalid password", http.StatusForbidden)
+		case "user not found":
+			h.respondWithError(w, "User not found", http.StatusNotFound)
+		default:
+			h.logger.Error("Failed to delete account", map[string]interface{}{
+				"error":   err.Error(),
+				"user_id": userID,
+				"mode":    req.Mode,
+			})
+			h.respondWithError(w, "Failed to delete account", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.logger.Info("Account deleted", map[string]interface{}{
+		"user_id": userID,
+		"mode":    req.Mode,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (h *UsersHandler) respondWithJSON(w http.ResponseWriter, data interface{}, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
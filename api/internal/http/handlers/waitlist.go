@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+
+	"bailanysta/api/internal/pkg/auth"
+	"bailanysta/api/internal/pkg/logger"
+	"bailanysta/api/internal/services"
+)
+
+type WaitlistHandler struct {
+	waitlistService *services.WaitlistService // nil when registration is open
+	logger          *logger.Logger
+	jwtManager      *auth.JWTManager
+	validator       *validator.Validate
+}
+
+func NewWaitlistHandler(waitlistService *services.WaitlistService, logger *logger.Logger, jwtManager *auth.JWTManager) *WaitlistHandler {
+	return &WaitlistHandler{
+		waitlistService: waitlistService,
+		logger:          logger,
+		jwtManager:      jwtManager,
+		validator:       validator.New(),
+	}
+}
+
+// GetWaitlist lists waitlist entries oldest first, optionally filtered by
+// ?status=pending|approved|registered
+func (h *WaitlistHandler) GetWaitlist(w http.ResponseWriter, r *http.Request) {
+	if !h.waitlistService.Enabled() {
+		h.respondWithError(w, "Registration is open; there is no waitlist", http.StatusNotFound)
+		return
+	}
+
+	limit := 50
+	offset := 0
+
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.Atoi(limitParam); err == nil && parsedLimit > 0 && parsedLimit <= 100 {
+			limit = parsedLimit
+		}
+	}
+
+	if offsetParam := r.URL.Query().Get("offset"); offsetParam != "" {
+		if parsedOffset, err := strconv.Atoi(offsetParam); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	entries, err := h.waitlistService.List(r.Context(), r.URL.Query().Get("status"), limit, offset)
+	if err != nil {
+		if err.Error() == "invalid waitlist status" {
+			h.respondWithError(w, "status must be one of pending, approved, registered", http.StatusBadRequest)
+			return
+		}
+		h.logger.Error("Failed to get waitlist", map[string]interface{}{
+			"error": err.Error(),
+		})
+		h.respondWithError(w, "Failed to get waitlist", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"entries": entries,
+		"limit":   limit,
+		"offset":  offset,
+	}, http.StatusOK)
+}
+
+// ApproveWaitlist approves a batch of entries and emails each a one-time
+// registration link
+func (h *WaitlistHandler) ApproveWaitlist(w http.ResponseWriter, r *http.Request) {
+	adminID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		h.respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if !h.waitlistService.Enabled() {
+		h.respondWithError(w, "Registration is open; there is no waitlist", http.StatusNotFound)
+		return
+	}
+
+	var req services.ApproveWaitlistRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.validator.Struct(req); err != nil {
+		h.respondWithError(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entries, err := h.waitlistService.Approve(r.Context(), adminID, req)
+	if err != nil {
+		if err.Error() == "ids or count is required" {
+			h.respondWithError(w, "Either ids or count is required", http.StatusBadRequest)
+			return
+		}
+		h.logger.Error("Failed to approve waitlist", map[string]interface{}{
+			"error":    err.Error(),
+			"admin_id": adminID,
+		})
+		h.respondWithError(w, "Failed to approve waitlist", http.StatusInternalServerError)
+		return
+	}
+
+	emailed := 0
+	for _, entry := range entries {
+		if entry.Emailed != nil && *entry.Emailed {
+			emailed++
+		}
+	}
+	h.logger.Info("Waitlist approved", map[string]interface{}{
+		"admin_id": adminID,
+		"approved": len(entries),
+		"emailed":  emailed,
+	})
+
+	h.respondWithJSON(w, map[string]interface{}{
+		"approved": entries,
+		"emailed":  emailed,
+	}, http.StatusOK)
+}
+
+func (h *WaitlistHandler) respondWithJSON(w http.ResponseWriter, data interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *WaitlistHandler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	h.respondWithJSON(w, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    getErrorCode(statusCode),
+			"message": message,
+		},
+	}, statusCode)
+}
+
+func (h *WaitlistHandler) getUserIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	return h.jwtManager.GetUserIDFromContext(ctx)
+}
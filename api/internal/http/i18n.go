@@ -0,0 +1,127 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"bailanysta/api/internal/pkg/i18n"
+)
+
+// languageMiddleware resolves the client's language from Accept-Language,
+// stores it in the request context and translates the message of JSON error
+// envelopes ({"error": {"code", "message"}}) written by handlers
+func languageMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lang := i18n.Resolve(r.Header.Get("Accept-Language"))
+		r = r.WithContext(i18n.WithLanguage(r.Context(), lang))
+
+		w.Header().Add("Vary", "Accept-Language")
+		w.Header().Set("Content-Language", lang)
+		if lang == i18n.Default {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		lw := &localizeWriter{ResponseWriter: w, lang: lang}
+		defer lw.Close()
+
+		next.ServeHTTP(lw, r)
+	})
+}
+
+// localizeWriter buffers JSON error responses so their message can be
+// translated; everything else passes through untouched
+type localizeWriter struct {
+	http.ResponseWriter
+	lang string
+
+	statusCode  int
+	wroteHeader bool
+	buffering   bool
+	buf         []byte
+}
+
+func (lw *localizeWriter) WriteHeader(code int) {
+	if code < 200 {
+		lw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if lw.wroteHeader {
+		return
+	}
+	lw.wroteHeader = true
+	lw.statusCode = code
+
+	if code >= 400 && strings.HasPrefix(lw.Header().Get("Content-Type"), "application/json") {
+		lw.buffering = true
+		return
+	}
+	lw.ResponseWriter.WriteHeader(code)
+}
+
+func (lw *localizeWriter) Write(p []byte) (int, error) {
+	if !lw.wroteHeader {
+		lw.WriteHeader(http.StatusOK)
+	}
+	if lw.buffering {
+		lw.buf = append(lw.buf, p...)
+		return len(p), nil
+	}
+	return lw.ResponseWriter.Write(p)
+}
+
+// Flush is a no-op while an error response is buffered; error envelopes
+// are small and sent by Close
+func (lw *localizeWriter) Flush() {
+	if lw.buffering {
+		return
+	}
+	if f, ok := lw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (lw *localizeWriter) Unwrap() http.ResponseWriter {
+	return lw.ResponseWriter
+}
+
+// Close sends a buffered error response, translated
+func (lw *localizeWriter) Close() {
+	if !lw.buffering {
+		return
+	}
+	lw.Header().Del("Content-Length")
+	lw.ResponseWriter.WriteHeader(lw.statusCode)
+	lw.ResponseWriter.Write(localizeErrorBody(lw.buf, lw.lang, lw.statusCode))
+}
+
+// localizeErrorBody translates error.message in a JSON error envelope. 5xx
+// messages without a translation become a generic internal error; bodies
+// that aren't envelopes are returned as is.
+func localizeErrorBody(body []byte, lang string, statusCode int) []byte {
+	var envelope map[string]interface{}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return body
+	}
+	errorObject, ok := envelope["error"].(map[string]interface{})
+	if !ok {
+		return body
+	}
+	message, ok := errorObject["message"].(string)
+	if !ok {
+		return body
+	}
+
+	translated := i18n.Translate(lang, message)
+	if translated == message && statusCode >= http.StatusInternalServerError {
+		translated = i18n.Translate(lang, "Internal server error")
+	}
+	errorObject["message"] = translated
+
+	localized, err := json.Marshal(envelope)
+	if err != nil {
+		return body
+	}
+	return append(localized, '\n')
+}
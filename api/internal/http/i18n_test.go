@@ -0,0 +1,62 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalizeErrorBody(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		lang   string
+		status int
+		want   string
+	}{
+		{
+			"known message",
+			`{"error":{"code":"NOT_FOUND","message":"Post not found"}}`,
+			"ru", http.StatusNotFound,
+			`{"error":{"code":"NOT_FOUND","message":"Пост не найден"}}` + "\n",
+		},
+		{
+			"prefix with detail",
+			`{"error":{"code":"BAD_REQUEST","message":"Validation failed: Key: 'Text' failed"}}`,
+			"kk", http.StatusBadRequest,
+			`{"error":{"code":"BAD_REQUEST","message":"Тексеруден өтпеді: Key: 'Text' failed"}}` + "\n",
+		},
+		{
+			"unknown client error stays English",
+			`{"error":{"code":"BAD_REQUEST","message":"Something specific"}}`,
+			"ru", http.StatusBadRequest,
+			`{"error":{"code":"BAD_REQUEST","message":"Something specific"}}` + "\n",
+		},
+		{
+			"unknown server error becomes generic",
+			`{"error":{"code":"INTERNAL_ERROR","message":"Failed to create post"}}`,
+			"kk", http.StatusInternalServerError,
+			`{"error":{"code":"INTERNAL_ERROR","message":"Сервердің ішкі қатесі"}}` + "\n",
+		},
+		{
+			"not an envelope",
+			`{"message":"ok"}`,
+			"ru", http.StatusBadRequest,
+			`{"message":"ok"}`,
+		},
+		{
+			"not JSON",
+			`oops`,
+			"ru", http.StatusBadRequest,
+			`oops`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := localizeErrorBody([]byte(tt.body), tt.lang, tt.status)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"bailanysta/api/internal/http/handlers"
+	"bailanysta/api/internal/pkg/auth"
+)
+
+// maintenanceExempt reports whether r is served during maintenance: health
+// checks and metrics, and logging in so admins can get a token
+func maintenanceExempt(r *http.Request) bool {
+	switch r.URL.Path {
+	case "/health", "/metrics", "/.well-known/jwks.json", "/api/v1/auth/login", "/api/v1/auth/refresh":
+		return true
+	}
+	return false
+}
+
+// maintenanceMiddleware answers 503 while maintenance mode is on, except to
+// admins and for the routes maintenanceExempt allows
+func maintenanceMiddleware(mode *handlers.MaintenanceMode, jwtManager *auth.JWTManager) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			status := mode.Status()
+			if !status.Enabled || maintenanceExempt(r) || isAdminRequest(r, jwtManager) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", strconv.Itoa(max(ceilSeconds(status.RetryAfter), 1)))
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error": map[string]interface{}{
+					"code":    "MAINTENANCE",
+					"message": status.Message,
+				},
+				"maintenance": status,
+			})
+		})
+	}
+}
+
+// isAdminRequest reports whether r carries an admin's or superadmin's own
+// access token
+func isAdminRequest(r *http.Request, jwtManager *auth.JWTManager) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	claims, err := jwtManager.ValidateAccessToken(token)
+	if err != nil || claims.ImpersonatorID != nil {
+		return false
+	}
+	return claims.Role == "admin" || claims.Role == "superadmin"
+}
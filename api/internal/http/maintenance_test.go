@@ -0,0 +1,63 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bailanysta/api/internal/http/handlers"
+	"bailanysta/api/internal/pkg/auth"
+)
+
+func TestMaintenanceMiddleware(t *testing.T) {
+	jwtManager := auth.NewJWTManager("test-secret", 15*time.Minute, time.Hour)
+	admin, err := jwtManager.GenerateTokenPair(uuid.New(), uuid.New(), "admin", auth.SessionScopes)
+	require.NoError(t, err)
+	user, err := jwtManager.GenerateTokenPair(uuid.New(), uuid.New(), "user", auth.SessionScopes)
+	require.NoError(t, err)
+
+	mode := handlers.NewMaintenanceMode(true, "Upgrading", 2*time.Minute)
+	handler := maintenanceMiddleware(mode, jwtManager)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name  string
+		path  string
+		token string
+		want  int
+	}{
+		{"anonymous", "/api/v1/posts", "", http.StatusServiceUnavailable},
+		{"user", "/api/v1/posts", user.AccessToken, http.StatusServiceUnavailable},
+		{"admin", "/api/v1/posts", admin.AccessToken, http.StatusOK},
+		{"health check", "/health", "", http.StatusOK},
+		{"login", "/api/v1/auth/login", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			assert.Equal(t, tt.want, w.Code)
+			if tt.want == http.StatusServiceUnavailable {
+				assert.Equal(t, "120", w.Header().Get("Retry-After"))
+				assert.Contains(t, w.Body.String(), `"message":"Upgrading"`)
+			}
+		})
+	}
+
+	// A reload with unchanged config keeps an admin's switch
+	mode.Set(false, "Upgrading", 2*time.Minute)
+	mode.ApplyConfig(true, "Upgrading", 2*time.Minute)
+	assert.False(t, mode.Status().Enabled)
+}
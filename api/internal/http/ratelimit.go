@@ -0,0 +1,254 @@
+package http
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/time/rate"
+
+	"bailanysta/api/internal/http/handlers"
+	"bailanysta/api/internal/pkg/auth"
+	"bailanysta/api/internal/services"
+)
+
+// Buckets are swept this often; only full ones are dropped, so a sweep never
+// forgives a client
+const rateLimitSweepInterval = time.Minute
+
+// apiKeyCheckTTL is how long the limiter remembers whether an API key
+// authenticates
+const apiKeyCheckTTL = time.Minute
+
+// RouteCosts are how many tokens a request takes from its client's bucket
+// per route class; reads cost 1
+type RouteCosts struct {
+	Write int // anything but GET, HEAD and OPTIONS
+	AI    int // /api/v1/ai/*, regardless of method
+}
+
+// For returns the cost of r
+func (c RouteCosts) For(r *http.Request) int {
+	if strings.HasPrefix(r.URL.Path, "/api/v1/ai/") {
+		return c.AI
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return 1
+	default:
+		return c.Write
+	}
+}
+
+func (c RouteCosts) max() int {
+	return max(c.Write, c.AI, 1)
+}
+
+// keyedLimiter keeps a token bucket per client. Each bucket refills at rpm
+// tokens per minute and holds at most rpm/4, or enough for the costliest
+// request if that is more.
+type keyedLimiter struct {
+	mu        sync.Mutex
+	rpm       int
+	costs     RouteCosts
+	buckets   map[string]*rate.Limiter
+	lastSweep time.Time
+}
+
+func newKeyedLimiter(rpm int, costs RouteCosts) *keyedLimiter {
+	return &keyedLimiter{
+		rpm:     rpm,
+		costs:   costs,
+		buckets: make(map[string]*rate.Limiter),
+	}
+}
+
+func (l *keyedLimiter) burst() int {
+	return max(l.rpm/4, l.costs.max())
+}
+
+// setLimits changes the rate and costs of every bucket, existing ones
+// included
+func (l *keyedLimiter) setLimits(rpm int, costs RouteCosts) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.rpm = rpm
+	l.costs = costs
+	for _, bucket := range l.buckets {
+		bucket.SetLimit(rate.Limit(rpm) / 60)
+		bucket.SetBurst(l.burst())
+	}
+}
+
+// cost returns what r takes from its bucket
+func (l *keyedLimiter) cost(r *http.Request) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.costs.For(r)
+}
+
+// allow takes cost tokens from key's bucket, reporting whether the request
+// was allowed and the bucket's state afterwards
+func (l *keyedLimiter) allow(key string, cost int, now time.Time) (bool, handlers.RateLimitStatus) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) >= rateLimitSweepInterval {
+		l.sweep(now)
+	}
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = rate.NewLimiter(rate.Limit(l.rpm)/60, l.burst())
+		l.buckets[key] = bucket
+	}
+
+	allowed := bucket.AllowN(now, cost)
+	return allowed, l.status(bucket, cost, now)
+}
+
+// sweep drops full buckets; recreating them later gives the same state
+func (l *keyedLimiter) sweep(now time.Time) {
+	for key, bucket := range l.buckets {
+		if bucket.TokensAt(now) >= float64(bucket.Burst()) {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+func (l *keyedLimiter) status(bucket *rate.Limiter, cost int, now time.Time) handlers.RateLimitStatus {
+	tokens := bucket.TokensAt(now)
+	burst := bucket.Burst()
+	perSecond := float64(bucket.Limit())
+
+	status := handlers.RateLimitStatus{
+		Limit:     burst,
+		Remaining: max(int(math.Floor(tokens)), 0),
+		ResetAt:   now,
+	}
+	if perSecond > 0 && tokens < float64(burst) {
+		status.ResetAt = now.Add(time.Duration((float64(burst) - tokens) / perSecond * float64(time.Second)))
+	}
+	if perSecond > 0 && tokens < float64(cost) {
+		status.RetryAfter = time.Duration((float64(cost) - tokens) / perSecond * float64(time.Second))
+	}
+	return status
+}
+
+// apiKeyChecker remembers which API keys authenticate and whose they are, so
+// a request with a made-up key can't get a bucket of its own and a user's
+// keys share the user's bucket. Answers, including negative ones, are kept
+// for apiKeyCheckTTL to spare the database.
+type apiKeyChecker struct {
+	mu           sync.Mutex
+	authenticate func(ctx context.Context, key string) (uuid.UUID, error)
+	checked      map[string]apiKeyCheck // by key hash
+	lastSweep    time.Time
+}
+
+type apiKeyCheck struct {
+	valid     bool
+	userID    uuid.UUID
+	expiresAt time.Time
+}
+
+// newAPIKeyChecker checks keys with authenticate, which returns the key's
+// owner
+func newAPIKeyChecker(authenticate func(ctx context.Context, key string) (uuid.UUID, error)) *apiKeyChecker {
+	return &apiKeyChecker{
+		authenticate: authenticate,
+		checked:      make(map[string]apiKeyCheck),
+	}
+}
+
+// owner returns the user whose key with hash authenticates; ok is false for
+// keys that don't. Lookup failures count as invalid but aren't remembered.
+func (c *apiKeyChecker) owner(ctx context.Context, key, hash string, now time.Time) (userID uuid.UUID, ok bool) {
+	c.mu.Lock()
+	if now.Sub(c.lastSweep) >= rateLimitSweepInterval {
+		for h, check := range c.checked {
+			if !now.Before(check.expiresAt) {
+				delete(c.checked, h)
+			}
+		}
+		c.lastSweep = now
+	}
+	check, cached := c.checked[hash]
+	c.mu.Unlock()
+	if cached && now.Before(check.expiresAt) {
+		return check.userID, check.valid
+	}
+
+	userID, err := c.authenticate(ctx, key)
+	if err != nil && err.Error() != "invalid API key" {
+		return uuid.Nil, false
+	}
+	c.mu.Lock()
+	c.checked[hash] = apiKeyCheck{valid: err == nil, userID: userID, expiresAt: now.Add(apiKeyCheckTTL)}
+	c.mu.Unlock()
+	return userID, err == nil
+}
+
+// rateLimitKey identifies the client: the user for requests with a valid
+// access token or API key, otherwise the client IP
+func rateLimitKey(r *http.Request, jwtManager *auth.JWTManager, apiKeys *apiKeyChecker, now time.Time) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		if strings.HasPrefix(token, services.APIKeyPrefix) {
+			sum := sha256.Sum256([]byte(token))
+			hash := hex.EncodeToString(sum[:16])
+			if userID, ok := apiKeys.owner(r.Context(), token, hash, now); ok {
+				return "user:" + userID.String()
+			}
+		} else if claims, err := jwtManager.ValidateAccessToken(token); err == nil {
+			return "user:" + claims.UserID.String()
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// rateLimitMiddleware limits each client to its bucket and reports the
+// bucket in X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset
+// (seconds until it is full again). X-RateLimit-Cost is what the request
+// took.
+func rateLimitMiddleware(limiter *keyedLimiter, jwtManager *auth.JWTManager, apiKeys *apiKeyChecker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			now := time.Now()
+			cost := limiter.cost(r)
+			allowed, status := limiter.allow(rateLimitKey(r, jwtManager, apiKeys, now), cost, now)
+
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(status.Limit))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(status.Remaining))
+			w.Header().Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(status.ResetAt.Sub(now))))
+			w.Header().Set("X-RateLimit-Cost", strconv.Itoa(cost))
+
+			if !allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(max(ceilSeconds(status.RetryAfter), 1)))
+				http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), "rate_limit", status)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,140 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"bailanysta/api/internal/pkg/auth"
+)
+
+func TestKeyedLimiter(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	limiter := newKeyedLimiter(60, RouteCosts{Write: 1, AI: 10}) // 1 token per second, burst 15
+
+	for i := 0; i < 15; i++ {
+		allowed, status := limiter.allow("user:a", 1, now)
+		assert.True(t, allowed)
+		assert.Equal(t, 15, status.Limit)
+		assert.Equal(t, 14-i, status.Remaining)
+	}
+
+	allowed, status := limiter.allow("user:a", 1, now)
+	assert.False(t, allowed)
+	assert.Equal(t, 0, status.Remaining)
+	assert.Equal(t, now.Add(15*time.Second), status.ResetAt)
+	assert.Equal(t, time.Second, status.RetryAfter)
+
+	// Other clients have their own bucket
+	allowed, _ = limiter.allow("ip:10.0.0.1", 1, now)
+	assert.True(t, allowed)
+
+	allowed, status = limiter.allow("user:a", 1, now.Add(2*time.Second))
+	assert.True(t, allowed)
+	assert.Equal(t, 1, status.Remaining)
+
+	// Only full buckets are swept
+	limiter.allow("ip:10.0.0.2", 1, now.Add(time.Hour))
+	assert.Len(t, limiter.buckets, 1)
+	assert.Contains(t, limiter.buckets, "ip:10.0.0.2")
+
+	limiter.setLimits(120, RouteCosts{Write: 1, AI: 10})
+	_, status = limiter.allow("ip:10.0.0.2", 1, now.Add(time.Hour))
+	assert.Equal(t, 30, status.Limit)
+}
+
+func TestKeyedLimiterCost(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	limiter := newKeyedLimiter(60, RouteCosts{Write: 1, AI: 50}) // burst raised to fit an AI request
+
+	allowed, status := limiter.allow("user:a", 50, now)
+	assert.True(t, allowed)
+	assert.Equal(t, 50, status.Limit)
+	assert.Equal(t, 0, status.Remaining)
+
+	// A second AI request has to wait for the whole cost to refill
+	allowed, status = limiter.allow("user:a", 50, now.Add(10*time.Second))
+	assert.False(t, allowed)
+	assert.Equal(t, 40*time.Second, status.RetryAfter)
+
+	// Cheaper requests still get through as the bucket refills
+	allowed, _ = limiter.allow("user:a", 1, now.Add(10*time.Second))
+	assert.True(t, allowed)
+}
+
+func TestRouteCostsFor(t *testing.T) {
+	costs := RouteCosts{Write: 2, AI: 50}
+
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{http.MethodGet, "/api/v1/posts", 1},
+		{http.MethodPost, "/api/v1/posts", 2},
+		{http.MethodPost, "/api/v1/ai/generate", 50},
+		{http.MethodGet, "/api/v1/ai/feed-digest", 50},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.path, nil)
+		assert.Equal(t, tt.want, costs.For(r), tt.method+" "+tt.path)
+	}
+}
+
+func TestRateLimitKeyAPIKeys(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	jwtManager := auth.NewJWTManager("test-secret", time.Minute, time.Hour)
+
+	ownerID := uuid.New()
+	lookups := 0
+	apiKeys := newAPIKeyChecker(func(ctx context.Context, key string) (uuid.UUID, error) {
+		lookups++
+		switch key {
+		case "bly_valid", "bly_second":
+			return ownerID, nil
+		case "bly_broken":
+			return uuid.Nil, fmt.Errorf("failed to authenticate API key: connection refused")
+		}
+		return uuid.Nil, fmt.Errorf("invalid API key")
+	})
+
+	request := func(token string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/posts", nil)
+		r.RemoteAddr = "10.0.0.1:5000"
+		r.Header.Set("Authorization", "Bearer "+token)
+		return r
+	}
+
+	// A key that authenticates uses its owner's bucket
+	assert.Equal(t, "user:"+ownerID.String(), rateLimitKey(request("bly_valid"), jwtManager, apiKeys, now))
+	assert.Equal(t, "ip:10.0.0.1", rateLimitKey(request("bly_made_up"), jwtManager, apiKeys, now))
+	assert.Equal(t, "ip:10.0.0.1", rateLimitKey(request("bly_broken"), jwtManager, apiKeys, now))
+	assert.Equal(t, 3, lookups)
+
+	// Answers are remembered, except failed lookups
+	rateLimitKey(request("bly_valid"), jwtManager, apiKeys, now.Add(time.Second))
+	rateLimitKey(request("bly_made_up"), jwtManager, apiKeys, now.Add(time.Second))
+	assert.Equal(t, 3, lookups)
+	rateLimitKey(request("bly_broken"), jwtManager, apiKeys, now.Add(time.Second))
+	assert.Equal(t, 4, lookups)
+
+	rateLimitKey(request("bly_valid"), jwtManager, apiKeys, now.Add(apiKeyCheckTTL))
+	assert.Equal(t, 5, lookups)
+
+	// More keys don't add buckets
+	assert.Equal(t, "user:"+ownerID.String(), rateLimitKey(request("bly_second"), jwtManager, apiKeys, now))
+
+	// Invalid access tokens fall back to the IP too
+	assert.Equal(t, "ip:10.0.0.1", rateLimitKey(request("not-a-jwt"), jwtManager, apiKeys, now))
+	userID := uuid.New()
+	tokens, err := jwtManager.GenerateTokenPair(userID, uuid.New(), "user", auth.SessionScopes)
+	assert.NoError(t, err)
+	assert.Equal(t, "user:"+userID.String(), rateLimitKey(request(tokens.AccessToken), jwtManager, apiKeys, now))
+}
@@ -0,0 +1,73 @@
+package http
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// realIPMiddleware sets r.RemoteAddr to the client's address. It is only
+// taken from X-Forwarded-For or X-Real-IP when the request comes from one of
+// the trusted proxies; anyone else could put any address there.
+func realIPMiddleware(trusted []*net.IPNet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ip := forwardedClientIP(r, trusted); ip != "" {
+				r.RemoteAddr = ip
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forwardedClientIP returns the client address the trusted proxies in
+// front of r report, or "" to keep the peer's own. X-Forwarded-For is read
+// from the right: each proxy appends the address it got the request from,
+// so the last one that isn't a trusted proxy is the client; entries to its
+// left are whatever the client sent.
+func forwardedClientIP(r *http.Request, trusted []*net.IPNet) string {
+	if !isTrustedProxy(parseHostIP(r.RemoteAddr), trusted) {
+		return ""
+	}
+
+	if forwardedFor := r.Header.Values("X-Forwarded-For"); len(forwardedFor) > 0 {
+		hops := strings.Split(strings.Join(forwardedFor, ","), ",")
+		client := ""
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := parseHostIP(strings.TrimSpace(hops[i]))
+			if ip == nil {
+				break
+			}
+			client = ip.String()
+			if !isTrustedProxy(ip, trusted) {
+				break
+			}
+		}
+		return client
+	}
+
+	if ip := parseHostIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+		return ip.String()
+	}
+	return ""
+}
+
+// parseHostIP parses an address with or without a port
+func parseHostIP(addr string) net.IP {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	return net.ParseIP(addr)
+}
+
+func isTrustedProxy(ip net.IP, trusted []*net.IPNet) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package http
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRealIPMiddleware(t *testing.T) {
+	_, docker, _ := net.ParseCIDR("172.16.0.0/12")
+	_, loopback, _ := net.ParseCIDR("127.0.0.1/32")
+	trusted := []*net.IPNet{docker, loopback}
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		realIP       string
+		want         string
+	}{
+		{"direct client", "203.0.113.7:5000", nil, "", "203.0.113.7:5000"},
+		{"spoofed headers from an untrusted peer", "203.0.113.7:5000", []string{"198.51.100.1"}, "198.51.100.2", "203.0.113.7:5000"},
+		{"proxy", "172.18.0.5:40000", []string{"198.51.100.1"}, "", "198.51.100.1"},
+		{"client-supplied hops are skipped", "172.18.0.5:40000", []string{"10.0.0.1, 198.51.100.1"}, "", "198.51.100.1"},
+		{"chained trusted proxies", "172.18.0.5:40000", []string{"198.51.100.1, 127.0.0.1"}, "", "198.51.100.1"},
+		{"several headers", "172.18.0.5:40000", []string{"10.0.0.1", "198.51.100.1"}, "", "198.51.100.1"},
+		{"X-Real-IP from a proxy", "127.0.0.1:40000", nil, "198.51.100.2", "198.51.100.2"},
+		{"IPv6 client", "172.18.0.5:40000", []string{"2001:db8::1"}, "", "2001:db8::1"},
+		{"garbage is ignored", "172.18.0.5:40000", []string{"not-an-ip"}, "", "172.18.0.5:40000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := realIPMiddleware(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.RemoteAddr
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/posts", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwardedFor {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -2,29 +2,45 @@ package http
 
 import (
 	"context"
+	"crypto/subtle"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
-	"golang.org/x/time/rate"
+	"github.com/google/uuid"
 
 	"bailanysta/api/internal/config"
 	"bailanysta/api/internal/http/handlers"
 	"bailanysta/api/internal/pkg/auth"
 	"bailanysta/api/internal/pkg/logger"
+	"bailanysta/api/internal/pkg/metrics"
+	"bailanysta/api/internal/pkg/sentry"
+	"bailanysta/api/internal/services"
 )
 
 type Router struct {
 	*chi.Mux
+	corsOrigins *originList
+	limiter     *keyedLimiter
+	maintenance *handlers.MaintenanceMode
 }
 
 type Deps struct {
-	Config     *config.Config
-	Logger     *logger.Logger
-	Handlers   *Handlers
-	JWTManager *auth.JWTManager
+	Config        *config.Config
+	Logger        *logger.Logger
+	Handlers      *Handlers
+	JWTManager    *auth.JWTManager
+	AuthService   *services.AuthService
+	AIQuota       *services.AIQuotaService
+	Impersonation *services.ImpersonationService
+	Presence      *services.PresenceService
+	IPBlocklist   *services.IPBlocklistService
+	Maintenance   *handlers.MaintenanceMode
+	ErrorReporter *sentry.Client // nil when SENTRY_DSN is unset
 }
 
 type Handlers struct {
@@ -32,10 +48,36 @@ type Handlers struct {
 	Users         *handlers.UsersHandler
 	Posts         *handlers.PostsHandler
 	Social        *handlers.SocialHandler
+	Groups        *handlers.GroupsHandler
+	Events        *handlers.EventsHandler
+	Leaderboard   *handlers.LeaderboardHandler
+	Activity      *handlers.ActivityHandler
 	Search        *handlers.SearchHandler
 	Notifications *handlers.NotificationsHandler
+	Stream        *handlers.StreamHandler
 	AI            *handlers.AIHandler
+	Flashcards    *handlers.FlashcardsHandler
+	Courses       *handlers.CoursesHandler
+	Assignments   *handlers.AssignmentsHandler
+	Certificates  *handlers.CertificatesHandler
+	GIFs          *handlers.GIFsHandler
+	Analytics     *handlers.AnalyticsHandler
+	Admin         *handlers.AdminHandler
+	Export        *handlers.ExportHandler
+	Limits        *handlers.LimitsHandler
+	APIKeys       *handlers.APIKeysHandler
+	Impersonation *handlers.ImpersonationHandler
+	Maintenance   *handlers.MaintenanceHandler
+	Waitlist      *handlers.WaitlistHandler
+	IPBlocks      *handlers.IPBlocksHandler
+	PostLimits    *handlers.PostLimitsHandler
+	Recovery      *handlers.RecoveryHandler
+	BotProtection *handlers.BotProtection // nil when BOT_PROTECTION is off
 	Health        *handlers.HealthHandler
+	Syndication   *handlers.SyndicationHandler
+	ShortLinks    *handlers.ShortLinksHandler
+	Sharing       *handlers.SharingHandler
+	GraphQL       *handlers.GraphQLHandler // nil when GRAPHQL_ENABLED is off
 }
 
 func NewRouter(deps *Deps) *Router {
@@ -43,93 +85,373 @@ func NewRouter(deps *Deps) *Router {
 
 	// Basic middleware
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
+	// Client addresses come from proxy headers only when a trusted proxy
+	// sent them; config.Load has already checked TRUSTED_PROXIES
+	trustedProxies, _ := deps.Config.TrustedProxyNets()
+	r.Use(realIPMiddleware(trustedProxies))
 	r.Use(middleware.Recoverer)
-	r.Use(loggerMiddleware(deps.Logger))
+	if deps.ErrorReporter != nil {
+		r.Use(errorReportingMiddleware(deps.ErrorReporter, deps.JWTManager))
+	}
+	r.Use(loggerMiddleware(deps.Logger, newBodyCapture(strings.Split(deps.Config.DebugCaptureRoutes, ","), deps.Config.DebugCaptureMaxBytes, deps.JWTManager)))
+
+	// Blocked networks are refused before anything else looks at the request
+	r.Use(ipBlocklistMiddleware(deps.IPBlocklist))
+	if deps.Config.CompressEnabled {
+		r.Use(compressMiddleware(deps.Config.CompressMinSize, strings.Split(deps.Config.CompressTypes, ",")))
+	}
+
+	// Accept-Language decides the language of error messages and AI output
+	r.Use(languageMiddleware)
 
-	// CORS middleware
+	// CORS middleware; origins can change on config reload
+	corsOrigins := newOriginList(deps.Config.CORSOrigins())
 	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{deps.Config.CORSOrigin},
+		AllowOriginFunc:  corsOrigins.allowed,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Auth-Mode", "X-CSRF-Token", "X-Debug-Capture", "X-Organization"},
+		ExposedHeaders:   []string{"Link", "Retry-After", "X-RateLimit-Cost", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
 
-	// Rate limiting middleware
-	r.Use(rateLimitMiddleware(deps.Config.RateLimitRPM))
+	// Everyone but admins gets a 503 during maintenance
+	r.Use(maintenanceMiddleware(deps.Maintenance, deps.JWTManager))
+
+	// Rate limiting per user, whether by access token or API key, or per IP
+	// for anonymous requests, weighted by what each route costs
+	limiter := newKeyedLimiter(deps.Config.RateLimitRPM, routeCosts(deps.Config))
+	apiKeys := newAPIKeyChecker(func(ctx context.Context, key string) (uuid.UUID, error) {
+		identity, err := deps.AuthService.AuthenticateAPIKey(ctx, key)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		return identity.UserID, nil
+	})
+	r.Use(rateLimitMiddleware(limiter, deps.JWTManager, apiKeys))
+
+	// Per-route deadlines; only AI routes may run long
+	r.Use(timeoutMiddleware(RouteTimeouts{
+		Read:   deps.Config.RequestTimeoutRead,
+		Write:  deps.Config.RequestTimeoutWrite,
+		AI:     deps.Config.RequestTimeoutAI,
+		Export: deps.Config.RequestTimeoutExport,
+		Stream: deps.Config.RequestTimeoutStream,
+	}))
+
+	// Scope public routes to the organization named by the client
+	r.Use(OrgMiddleware(deps.AuthService, deps.Logger))
 
 	// Health endpoint (no auth required)
 	r.Get("/health", deps.Handlers.Health.HealthCheck)
+	r.Get("/sitemap.xml", deps.Handlers.Syndication.GetSitemap)
+	r.Get("/.well-known/jwks.json", deps.Handlers.Auth.GetJWKS)
+	r.Get("/l/{code}", deps.Handlers.ShortLinks.Redirect)
+
+	// Prometheus metrics, optionally behind a bearer token
+	if deps.Config.MetricsEnabled {
+		r.With(metricsAuthMiddleware(deps.Config.MetricsToken)).Handle("/metrics", metrics.Default)
+	}
 
 	// API v1 routes
 	r.Route("/api/v1", func(r chi.Router) {
 		// Auth routes (no auth required)
 		r.Route("/auth", func(r chi.Router) {
-			r.Post("/register", deps.Handlers.Auth.Register)
+			r.Get("/challenge", deps.Handlers.BotProtection.GetChallenge)
+			r.With(deps.Handlers.BotProtection.Require).Post("/register", deps.Handlers.Auth.Register)
 			r.Post("/login", deps.Handlers.Auth.Login)
-			r.Post("/refresh", deps.Handlers.Auth.Refresh)
-			r.Post("/logout", deps.Handlers.Auth.Logout)
+			r.With(csrfMiddleware).Post("/refresh", deps.Handlers.Auth.Refresh)
+			r.With(csrfMiddleware).Post("/logout", deps.Handlers.Auth.Logout)
+			r.Post("/recovery/verify-email", deps.Handlers.Recovery.VerifyBackupEmail)
+			r.With(deps.Handlers.BotProtection.Require).Post("/recovery/start", deps.Handlers.Recovery.StartRecovery)
+			r.With(deps.Handlers.BotProtection.Require).Post("/recovery/complete", deps.Handlers.Recovery.RecoverAccount)
 		})
 
 		// Public routes (no auth required)
 		r.Get("/courses", deps.Handlers.Social.GetCourses)
 		r.Get("/courses/{id}/modules", deps.Handlers.Social.GetModulesByCourse)
 		r.Get("/search", deps.Handlers.Search.SearchPosts)
+		r.Get("/search/semantic", deps.Handlers.Search.SemanticSearch)
+		r.Get("/search/suggest", deps.Handlers.Search.Suggest)
+		r.Get("/feeds/user/{file}", deps.Handlers.Syndication.GetUserFeed)
+		r.Get("/feeds/hashtag/{file}", deps.Handlers.Syndication.GetHashtagFeed)
+		r.Get("/posts/{id}/meta", deps.Handlers.Sharing.GetMeta)
+		r.Get("/posts/{id}/share-card.png", deps.Handlers.Sharing.GetShareCard)
+		r.Get("/posts/{id}/qr.png", deps.Handlers.Sharing.GetQRCode)
+		r.Get("/certificates/{code}/verify", deps.Handlers.Certificates.VerifyCertificate)
+		r.Get("/push/vapid-public-key", deps.Handlers.Notifications.GetVAPIDPublicKey)
+		r.Get("/email/unsubscribe", deps.Handlers.Notifications.UnsubscribeEmail)
+		r.Post("/email/unsubscribe", deps.Handlers.Notifications.UnsubscribeEmail)
 
 		// Protected routes
 		r.Route("/", func(r chi.Router) {
-			r.Use(AuthMiddleware(deps.JWTManager, deps.Logger))
+			r.Use(AuthMiddleware(deps.JWTManager, deps.AuthService, deps.Logger))
+			r.Use(ImpersonationAuditMiddleware(deps.Impersonation, deps.Logger))
+			r.Use(PresenceMiddleware(deps.Presence))
 
 			// User routes
 			r.Get("/me", deps.Handlers.Users.GetCurrentUser)
 			r.Patch("/me", deps.Handlers.Users.UpdateCurrentUser)
+			r.Delete("/me", deps.Handlers.Users.DeleteAccount)
+			r.Get("/me/analytics", deps.Handlers.Analytics.GetMyAnalytics)
+			r.Get("/me/activity", deps.Handlers.Activity.GetMyActivity)
+			r.Get("/me/limits", deps.Handlers.Limits.GetMyLimits)
+			r.Get("/me/capabilities", deps.Handlers.PostLimits.GetCapabilities)
+			r.Get("/me/reputation", deps.Handlers.Leaderboard.GetMyReputation)
+			r.Get("/me/api-keys", deps.Handlers.APIKeys.GetAPIKeys)
+			r.Post("/me/api-keys", deps.Handlers.APIKeys.CreateAPIKey)
+			r.Delete("/me/api-keys/{id}", deps.Handlers.APIKeys.RevokeAPIKey)
+			r.Put("/me/username", deps.Handlers.Users.ChangeUsername)
+			r.Get("/me/sessions", deps.Handlers.Users.GetSessions)
+			r.Delete("/me/sessions/{id}", deps.Handlers.Users.RevokeSession)
+			r.Get("/me/recovery", deps.Handlers.Recovery.GetRecovery)
+			r.Put("/me/recovery/email", deps.Handlers.Recovery.SetBackupEmail)
+			r.Delete("/me/recovery/email", deps.Handlers.Recovery.RemoveBackupEmail)
+			r.Post("/me/recovery/codes", deps.Handlers.Recovery.RegenerateCodes)
+			r.Put("/me/privacy", deps.Handlers.Social.UpdatePrivacy)
+			r.Get("/me/profile-views", deps.Handlers.Users.GetProfileViews)
+			r.Put("/me/profile-views", deps.Handlers.Users.UpdateProfileViewSettings)
+			r.Put("/me/timezone", deps.Handlers.Users.UpdateTimezone)
+			r.Put("/me/feed-preferences", deps.Handlers.Social.UpdateFeedPreferences)
+			r.Get("/me/follow-requests", deps.Handlers.Social.GetFollowRequests)
+			r.Post("/me/follow-requests/{id}/approve", deps.Handlers.Social.ApproveFollowRequest)
+			r.Post("/me/follow-requests/{id}/decline", deps.Handlers.Social.DeclineFollowRequest)
 			r.Get("/users", deps.Handlers.Users.GetAllUsers)
+			r.Get("/users/by-username/{username}", deps.Handlers.Users.GetUserByUsername)
 			r.Get("/users/{id}", deps.Handlers.Users.GetUserByID)
+			r.Get("/users/{id}/followers", deps.Handlers.Social.GetFollowers)
+			r.Get("/users/{id}/following", deps.Handlers.Social.GetFollowing)
 			r.Post("/users/{id}/follow", deps.Handlers.Social.FollowUser)
 			r.Delete("/users/{id}/follow", deps.Handlers.Social.UnfollowUser)
+			r.Post("/users/{id}/block", deps.Handlers.Social.BlockUser)
+			r.Delete("/users/{id}/block", deps.Handlers.Social.UnblockUser)
+			r.Get("/recommendations/users", deps.Handlers.Social.GetRecommendedUsers)
 
 			// Posts routes
 			r.Post("/posts", deps.Handlers.Posts.CreatePost)
+			r.Post("/posts/impressions", deps.Handlers.Posts.RecordImpressions)
+			r.Post("/posts/batch", deps.Handlers.Posts.GetPostsBatch)
 			r.Get("/posts/{id}", deps.Handlers.Posts.GetPostByID)
 			r.Patch("/posts/{id}", deps.Handlers.Posts.UpdatePost)
 			r.Delete("/posts/{id}", deps.Handlers.Posts.DeletePost)
+			r.Get("/posts/{id}/history", deps.Handlers.Posts.GetPostHistory)
 			r.Post("/posts/{id}/like", deps.Handlers.Posts.LikePost)
 			r.Delete("/posts/{id}/like", deps.Handlers.Posts.UnlikePost)
+			r.Get("/posts/{id}/likes", deps.Handlers.Posts.GetLikes)
+			r.Post("/posts/{id}/poll/vote", deps.Handlers.Posts.VotePoll)
 			r.Get("/posts/{id}/comments", deps.Handlers.Posts.GetComments)
 			r.Post("/posts/{id}/comments", deps.Handlers.Posts.CreateComment)
+			r.With(PermissionMiddleware(deps.AuthService, services.PermissionVerifyAnswers, deps.Logger)).
+				Put("/posts/{id}/comments/{commentID}/verified", deps.Handlers.Posts.VerifyComment)
+			r.With(PermissionMiddleware(deps.AuthService, services.PermissionVerifyAnswers, deps.Logger)).
+				Delete("/posts/{id}/comments/{commentID}/verified", deps.Handlers.Posts.UnverifyComment)
+			r.Put("/posts/{id}/comments/{commentID}/accepted", deps.Handlers.Posts.AcceptAnswer)
+			r.Delete("/posts/{id}/comments/{commentID}/accepted", deps.Handlers.Posts.UnacceptAnswer)
 
 			// Feed
 			r.Get("/feed", deps.Handlers.Social.GetFeed)
+			r.Get("/feed/updates", deps.Handlers.Social.GetFeedUpdates)
+			r.Get("/stream", deps.Handlers.Stream.Stream)
+
+			// Study groups
+			r.Get("/groups", deps.Handlers.Groups.GetGroups)
+			r.Post("/groups", deps.Handlers.Groups.CreateGroup)
+			r.Get("/groups/{id}", deps.Handlers.Groups.GetGroup)
+			r.Get("/groups/{id}/feed", deps.Handlers.Groups.GetGroupFeed)
+			r.Post("/groups/{id}/join", deps.Handlers.Groups.JoinGroup)
+			r.Delete("/groups/{id}/membership", deps.Handlers.Groups.LeaveGroup)
+			r.Post("/groups/{id}/invites", deps.Handlers.Groups.InviteToGroup)
+			r.Get("/groups/{id}/members", deps.Handlers.Groups.GetGroupMembers)
+			r.Post("/groups/{id}/members/{userID}/approve", deps.Handlers.Groups.ApproveJoinRequest)
+			r.Put("/groups/{id}/members/{userID}/role", deps.Handlers.Groups.SetGroupMemberRole)
+			r.Delete("/groups/{id}/members/{userID}", deps.Handlers.Groups.RemoveGroupMember)
+
+			// Events
+			r.Get("/events", deps.Handlers.Events.GetEvents)
+			r.Post("/events", deps.Handlers.Events.CreateEvent)
+			r.Get("/events/{id}", deps.Handlers.Events.GetEvent)
+			r.Get("/events/{id}/calendar.ics", deps.Handlers.Events.ExportEvent)
+			r.Put("/events/{id}/rsvp", deps.Handlers.Events.SetRSVP)
+			r.Delete("/events/{id}/rsvp", deps.Handlers.Events.RemoveRSVP)
+			r.Get("/events/{id}/attendees", deps.Handlers.Events.GetAttendees)
+
+			r.Get("/leaderboard", deps.Handlers.Leaderboard.GetLeaderboard)
+
+			// GraphQL
+			if deps.Handlers.GraphQL != nil {
+				r.Get("/graphql", deps.Handlers.GraphQL.ServeGraphQL)
+				r.Post("/graphql", deps.Handlers.GraphQL.ServeGraphQL)
+			}
 
 			// Notifications
 			r.Get("/notifications", deps.Handlers.Notifications.GetNotifications)
 			r.Post("/notifications/mark-read", deps.Handlers.Notifications.MarkAllAsRead)
 			r.Get("/notifications/unread-count", deps.Handlers.Notifications.GetUnreadCount)
 			r.Post("/notifications/{id}/mark-read", deps.Handlers.Notifications.MarkAsRead)
+			r.Get("/notifications/{id}/target", deps.Handlers.Notifications.GetTarget)
 			r.Delete("/notifications/{id}", deps.Handlers.Notifications.DeleteNotification)
+			r.Get("/me/notification-preferences", deps.Handlers.Notifications.GetPreferences)
+			r.Put("/me/notification-preferences", deps.Handlers.Notifications.UpdatePreferences)
+			r.Post("/me/push-subscriptions", deps.Handlers.Notifications.SubscribePush)
+			r.Delete("/me/push-subscriptions", deps.Handlers.Notifications.UnsubscribePush)
 
 			// AI
-			r.Post("/ai/generate", deps.Handlers.AI.GenerateText)
-			r.Post("/ai/generate-post", deps.Handlers.AI.GeneratePost)
-			r.Post("/ai/generate-comment", deps.Handlers.AI.GenerateComment)
-			r.Post("/ai/generate-study-notes", deps.Handlers.AI.GenerateStudyNotes)
-			r.Post("/ai/generate-quiz", deps.Handlers.AI.GenerateQuiz)
-			r.Post("/ai/explain-concept", deps.Handlers.AI.ExplainConcept)
+			r.Group(func(r chi.Router) {
+				r.Use(AIQuotaMiddleware(deps.AIQuota, deps.Logger))
+
+				r.Post("/ai/generate", deps.Handlers.AI.GenerateText)
+				r.Post("/ai/generate-post", deps.Handlers.AI.GeneratePost)
+				r.Post("/ai/generate-comment", deps.Handlers.AI.GenerateComment)
+				r.Post("/ai/rewrite", deps.Handlers.AI.RewriteText)
+				r.Post("/ai/generate-study-notes", deps.Handlers.AI.GenerateStudyNotes)
+				r.Post("/ai/generate-quiz", deps.Handlers.AI.GenerateQuiz)
+				r.Post("/ai/explain-concept", deps.Handlers.AI.ExplainConcept)
+				r.Post("/posts/{id}/ai/explain", deps.Handlers.AI.ExplainPost)
+				r.Post("/ai/generate-flashcards", deps.Handlers.Flashcards.GenerateFlashcards)
+				r.Get("/ai/feed-digest", deps.Handlers.AI.GetFeedDigest)
+			})
+
+			// Course progress
+			r.Get("/me/courses", deps.Handlers.Courses.GetMyCourses)
+			r.Post("/modules/{id}/complete", deps.Handlers.Courses.CompleteModule)
+			r.Delete("/modules/{id}/complete", deps.Handlers.Courses.UncompleteModule)
+
+			// Certificates
+			r.Get("/me/certificates", deps.Handlers.Certificates.GetMyCertificates)
+			r.Get("/certificates/{code}/pdf", deps.Handlers.Certificates.DownloadCertificate)
+
+			// GIFs
+			r.Get("/gifs/search", deps.Handlers.GIFs.Search)
+
+			// Assignments
+			r.Get("/modules/{id}/assignments", deps.Handlers.Assignments.GetModuleAssignments)
+			r.Put("/assignments/{id}/submission", deps.Handlers.Assignments.Submit)
+			r.Get("/assignments/{id}/submission", deps.Handlers.Assignments.GetMySubmission)
+
+			// Flashcards
+			r.Get("/me/flashcards", deps.Handlers.Flashcards.GetFlashcards)
+			r.Get("/me/flashcards/due", deps.Handlers.Flashcards.GetDueFlashcards)
+			r.Post("/flashcards/{id}/review", deps.Handlers.Flashcards.ReviewFlashcard)
+			r.Delete("/flashcards/{id}", deps.Handlers.Flashcards.DeleteFlashcard)
+
+			// Courses
+			r.Group(func(r chi.Router) {
+				r.Use(PermissionMiddleware(deps.AuthService, services.PermissionManageCourses, deps.Logger))
+
+				r.Post("/courses", deps.Handlers.Social.CreateCourse)
+				r.Post("/courses/{id}/modules", deps.Handlers.Social.CreateModule)
+				r.Post("/modules/{id}/assignments", deps.Handlers.Assignments.CreateAssignment)
+				r.Get("/assignments/{id}/submissions", deps.Handlers.Assignments.GetSubmissions)
+				r.Post("/submissions/{id}/review", deps.Handlers.Assignments.ReviewSubmission)
+				r.With(AIQuotaMiddleware(deps.AIQuota, deps.Logger)).Post("/submissions/{id}/ai-feedback", deps.Handlers.Assignments.GenerateAIFeedback)
+			})
+
+			// Admin
+			r.Route("/admin", func(r chi.Router) {
+				r.Group(func(r chi.Router) {
+					r.Use(PermissionMiddleware(deps.AuthService, services.PermissionModerate, deps.Logger))
+
+					r.Get("/review-queue", deps.Handlers.Admin.GetReviewQueue)
+					r.Post("/review-queue/{id}", deps.Handlers.Admin.ResolveReview)
+					r.Get("/ai-filter-events", deps.Handlers.Admin.GetAIFilterEvents)
+					r.Get("/abuse-flags", deps.Handlers.Admin.GetAbuseFlags)
+					r.Post("/abuse-flags/{id}", deps.Handlers.Admin.ResolveAbuseFlag)
+					r.Get("/duplicates", deps.Handlers.Admin.GetDuplicates)
+					r.Post("/duplicates/{id}", deps.Handlers.Admin.ResolveDuplicate)
+					r.Put("/users/{id}/shadow-ban", deps.Handlers.Admin.ShadowBanUser)
+					r.Delete("/users/{id}/shadow-ban", deps.Handlers.Admin.UnshadowBanUser)
+				})
+
+				r.Group(func(r chi.Router) {
+					r.Use(PermissionMiddleware(deps.AuthService, services.PermissionManageRoles, deps.Logger))
+
+					r.Get("/users", deps.Handlers.Admin.GetUsersByRole)
+					r.Put("/users/{id}/role", deps.Handlers.Admin.SetUserRole)
+					r.Get("/waitlist", deps.Handlers.Waitlist.GetWaitlist)
+					r.Post("/waitlist/approve", deps.Handlers.Waitlist.ApproveWaitlist)
+					r.Get("/post-limits", deps.Handlers.PostLimits.GetPostLimits)
+					r.Put("/post-limits", deps.Handlers.PostLimits.SetPostLimit)
+					r.Delete("/post-limits/{id}", deps.Handlers.PostLimits.DeletePostLimit)
+				})
+
+				r.Group(func(r chi.Router) {
+					r.Use(PermissionMiddleware(deps.AuthService, services.PermissionManageSystem, deps.Logger))
+
+					r.Get("/log-levels", deps.Handlers.Admin.GetLogLevels)
+					r.Put("/log-levels", deps.Handlers.Admin.UpdateLogLevels)
+					r.Get("/maintenance", deps.Handlers.Maintenance.GetMaintenance)
+					r.Put("/maintenance", deps.Handlers.Maintenance.UpdateMaintenance)
+					r.Get("/organizations", deps.Handlers.Admin.GetOrganizations)
+					r.Post("/organizations", deps.Handlers.Admin.CreateOrganization)
+					r.Get("/ip-blocks", deps.Handlers.IPBlocks.GetIPBlocks)
+					r.Post("/ip-blocks", deps.Handlers.IPBlocks.CreateIPBlock)
+					r.Delete("/ip-blocks/{id}", deps.Handlers.IPBlocks.DeleteIPBlock)
+				})
+
+				r.Group(func(r chi.Router) {
+					r.Use(PermissionMiddleware(deps.AuthService, services.PermissionImpersonate, deps.Logger))
+
+					r.Post("/users/{id}/impersonate", deps.Handlers.Impersonation.StartImpersonation)
+					r.Get("/impersonations", deps.Handlers.Impersonation.GetImpersonations)
+				})
+
+				r.Group(func(r chi.Router) {
+					r.Use(PermissionMiddleware(deps.AuthService, services.PermissionExportData, deps.Logger))
+
+					r.Get("/export/posts", deps.Handlers.Export.ExportPosts)
+				})
+			})
 		})
 	})
 
-	return &Router{Mux: r}
+	return &Router{Mux: r, corsOrigins: corsOrigins, limiter: limiter, maintenance: deps.Maintenance}
+}
+
+// ApplyConfig updates the settings that can change without a restart
+func (rt *Router) ApplyConfig(cfg *config.Config) {
+	rt.corsOrigins.set(cfg.CORSOrigins())
+	rt.limiter.setLimits(cfg.RateLimitRPM, routeCosts(cfg))
+	rt.maintenance.ApplyConfig(cfg.MaintenanceMode, cfg.MaintenanceMessage, cfg.MaintenanceRetryAfter)
 }
 
-func rateLimitMiddleware(rpm int) func(http.Handler) http.Handler {
-	limiter := rate.NewLimiter(rate.Limit(rpm)/60, rpm/4) // burst size = rpm/4
+func routeCosts(cfg *config.Config) RouteCosts {
+	return RouteCosts{Write: cfg.RateLimitWriteCost, AI: cfg.RateLimitAICost}
+}
+
+// originList is the set of allowed CORS origins; "*" allows any origin
+type originList struct {
+	mu      sync.RWMutex
+	origins []string
+}
+
+func newOriginList(origins []string) *originList {
+	return &originList{origins: origins}
+}
+
+func (l *originList) set(origins []string) {
+	l.mu.Lock()
+	l.origins = origins
+	l.mu.Unlock()
+}
+
+func (l *originList) allowed(r *http.Request, origin string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
 
+	for _, allowed := range l.origins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// metricsAuthMiddleware requires "Authorization: Bearer <token>" when token is set
+func metricsAuthMiddleware(token string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if !limiter.Allow() {
-				http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+			if token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
 				return
 			}
 			next.ServeHTTP(w, r)
@@ -137,7 +459,10 @@ func rateLimitMiddleware(rpm int) func(http.Handler) http.Handler {
 	}
 }
 
-func loggerMiddleware(log *logger.Logger) func(http.Handler) http.Handler {
+// loggerMiddleware logs every request. Requests picked by capture also get
+// their sanitized bodies logged, at WARN so the usual http=warn level and
+// sampling don't hide them.
+func loggerMiddleware(log *logger.Logger, capture *bodyCapture) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
@@ -145,9 +470,34 @@ func loggerMiddleware(log *logger.Logger) func(http.Handler) http.Handler {
 			// Create a custom ResponseWriter to capture status code
 			rw := &responseWriter{ResponseWriter: w, statusCode: 200}
 
+			if capture.enabled(r) {
+				requestBody := capture.captureRequest(r)
+				cw := &captureWriter{responseWriter: rw, limit: capture.maxBytes}
+
+				next.ServeHTTP(cw, r)
+
+				log.Warn("HTTP request captured", map[string]interface{}{
+					"method":        r.Method,
+					"path":          r.URL.Path,
+					"query":         formSecretPattern.ReplaceAllString(r.URL.RawQuery, "$1[REDACTED]"),
+					"status":        rw.statusCode,
+					"duration_ms":   time.Since(start).Milliseconds(),
+					"user_agent":    r.Header.Get("User-Agent"),
+					"request_body":  sanitizeBody(requestBody, r.Header.Get("Content-Type"), r.Header.Get("Content-Encoding"), capture.maxBytes),
+					"response_body": sanitizeBody(cw.body.Bytes(), rw.Header().Get("Content-Type"), rw.Header().Get("Content-Encoding"), capture.maxBytes),
+				})
+				return
+			}
+
 			next.ServeHTTP(rw, r)
 
-			log.Info("HTTP request", map[string]interface{}{
+			// Health probes get their own message so sampling them doesn't thin out real traffic
+			message := "HTTP request"
+			if r.URL.Path == "/health" {
+				message = "Health check"
+			}
+
+			log.Info(message, map[string]interface{}{
 				"method":      r.Method,
 				"path":        r.URL.Path,
 				"status":      rw.statusCode,
@@ -168,7 +518,14 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
-func AuthMiddleware(jwtManager *auth.JWTManager, logger *logger.Logger) func(http.Handler) http.Handler {
+// Unwrap lets http.ResponseController flush streamed responses such as exports
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// AuthMiddleware authenticates the request with a JWT access token or, for
+// integrations, a personal API key; read-only keys and tokens may only read
+func AuthMiddleware(jwtManager *auth.JWTManager, authService *services.AuthService, logger *logger.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
@@ -189,21 +546,224 @@ func AuthMiddleware(jwtManager *auth.JWTManager, logger *logger.Logger) func(htt
 			}
 
 			tokenString := authHeader[7:]
+			ctx := r.Context()
+
+			var userID, orgID uuid.UUID
+			if strings.HasPrefix(tokenString, services.APIKeyPrefix) {
+				identity, err := authService.AuthenticateAPIKey(r.Context(), tokenString)
+				if err != nil {
+					logger.Warn("Invalid API key", map[string]interface{}{
+						"path":  r.URL.Path,
+						"error": err.Error(),
+					})
+					http.Error(w, "Invalid API key", http.StatusUnauthorized)
+					return
+				}
+				if !services.APIKeyAllows(identity.Scope, r.Method) {
+					http.Error(w, "API key is read-only", http.StatusForbidden)
+					return
+				}
+				userID, orgID = identity.UserID, identity.OrgID
+				ctx = context.WithValue(ctx, "api_key_id", identity.KeyID.String())
+			} else {
+				// Validate JWT token
+				claims, err := jwtManager.ValidateAccessToken(tokenString)
+				if err != nil {
+					logger.Warn("Invalid JWT token", map[string]interface{}{
+						"path":  r.URL.Path,
+						"error": err.Error(),
+					})
+					http.Error(w, "Invalid token", http.StatusUnauthorized)
+					return
+				}
+
+				if !claims.HasScope(auth.ScopeWrite) && !services.APIKeyAllows(services.APIKeyScopeRead, r.Method) {
+					http.Error(w, "Token is read-only", http.StatusForbidden)
+					return
+				}
+
+				// Tokens issued before organizations existed carry none
+				userID, orgID = claims.UserID, claims.OrgID
+				if orgID == uuid.Nil {
+					orgID = services.DefaultOrganizationID
+				}
+
+				if impersonation := services.ImpersonationFromClaims(claims); impersonation != nil {
+					ctx = context.WithValue(ctx, "impersonation", impersonation)
+				}
+			}
+
+			// A user can only act within their own organization
+			requested, _ := r.Context().Value("org_id").(string)
+			if requestedOrgSlug(r) != "" && requested != orgID.String() {
+				logger.Warn("Organization mismatch", map[string]interface{}{
+					"path":    r.URL.Path,
+					"user_id": userID,
+				})
+				http.Error(w, "Organization access denied", http.StatusForbidden)
+				return
+			}
+
+			// Add user and organization IDs to context
+			ctx = context.WithValue(ctx, "user_id", userID.String())
+			ctx = context.WithValue(ctx, "org_id", orgID.String())
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
 
-			// Validate JWT token
-			claims, err := jwtManager.ValidateAccessToken(tokenString)
+// orgHeader names the organization, by slug, for unauthenticated requests.
+// RSS readers can't set headers, so the org query parameter works too.
+const orgHeader = "X-Organization"
+
+// OrgMiddleware puts the requested organization, or the default one, into
+// the context. AuthMiddleware later replaces it with the token's.
+func OrgMiddleware(authService *services.AuthService, logger *logger.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			orgID := services.DefaultOrganizationID
+
+			if slug := requestedOrgSlug(r); slug != "" {
+				org, err := authService.GetOrganizationBySlug(r.Context(), slug)
+				if err != nil {
+					if err.Error() != "organization not found" {
+						logger.Error("Failed to resolve organization", map[string]interface{}{
+							"error": err.Error(),
+							"slug":  slug,
+						})
+						http.Error(w, "Internal server error", http.StatusInternalServerError)
+						return
+					}
+					http.Error(w, "Organization not found", http.StatusNotFound)
+					return
+				}
+				orgID = org.ID
+			}
+
+			ctx := context.WithValue(r.Context(), "org_id", orgID.String())
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func requestedOrgSlug(r *http.Request) string {
+	if slug := r.Header.Get(orgHeader); slug != "" {
+		return slug
+	}
+	return r.URL.Query().Get("org")
+}
+
+// PermissionMiddleware allows only users whose role grants permission; it
+// must run after AuthMiddleware
+func PermissionMiddleware(authService *services.AuthService, permission services.Permission, logger *logger.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userIDStr, _ := r.Context().Value("user_id").(string)
+			userID, err := uuid.Parse(userIDStr)
 			if err != nil {
-				logger.Warn("Invalid JWT token", map[string]interface{}{
-					"path":  r.URL.Path,
-					"error": err.Error(),
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			allowed, err := authService.HasPermission(r.Context(), userID, permission)
+			if err != nil || !allowed {
+				logger.Warn("Permission denied", map[string]interface{}{
+					"path":       r.URL.Path,
+					"user_id":    userID,
+					"permission": permission,
 				})
-				http.Error(w, "Invalid token", http.StatusUnauthorized)
+				http.Error(w, "Permission denied", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// AIQuotaMiddleware counts the request against the user's daily AI quota,
+// rejecting it once the quota is used up, and records the tokens it used
+func AIQuotaMiddleware(quota *services.AIQuotaService, logger *logger.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userIDStr, _ := r.Context().Value("user_id").(string)
+			userID, err := uuid.Parse(userIDStr)
+			if err != nil {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
 				return
 			}
 
-			// Add user ID to context
-			ctx := context.WithValue(r.Context(), "user_id", claims.UserID.String())
+			if err := quota.Reserve(r.Context(), userID); err != nil {
+				if err.Error() == "AI quota exceeded" {
+					http.Error(w, "AI quota exceeded", http.StatusTooManyRequests)
+					return
+				}
+				// Fail open so a database hiccup doesn't block AI
+				logger.Error("Failed to check AI quota", map[string]interface{}{
+					"error":   err.Error(),
+					"user_id": userID,
+				})
+			}
+
+			ctx, meter := services.WithAIUsageMeter(r.Context(), userID)
 			next.ServeHTTP(w, r.WithContext(ctx))
+
+			// The request context may already be cancelled
+			if err := quota.RecordTokens(context.WithoutCancel(ctx), userID, meter.Tokens()); err != nil {
+				logger.Error("Failed to record AI usage", map[string]interface{}{
+					"error":   err.Error(),
+					"user_id": userID,
+				})
+			}
+		})
+	}
+}
+
+// PresenceMiddleware marks the user active. Requests made with an API key or
+// by support staff impersonating the user don't count as the user's own.
+func PresenceMiddleware(presence *services.PresenceService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, viaAPIKey := r.Context().Value("api_key_id").(string)
+			_, impersonated := r.Context().Value("impersonation").(*services.ImpersonationInfo)
+			if !viaAPIKey && !impersonated {
+				userIDStr, _ := r.Context().Value("user_id").(string)
+				if userID, err := uuid.Parse(userIDStr); err == nil {
+					presence.Touch(userID)
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ImpersonationAuditMiddleware logs every request made with an impersonation
+// token, with the status it got
+func ImpersonationAuditMiddleware(impersonation *services.ImpersonationService, logger *logger.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			info, ok := r.Context().Value("impersonation").(*services.ImpersonationInfo)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(rw, r)
+
+			logger.Info("Impersonated request", map[string]interface{}{
+				"session_id":      info.SessionID,
+				"impersonator_id": info.ImpersonatorID,
+				"method":          r.Method,
+				"path":            r.URL.Path,
+				"status":          rw.statusCode,
+			})
+			if err := impersonation.LogAction(context.WithoutCancel(r.Context()), info.SessionID, r.Method, r.URL.Path, rw.statusCode); err != nil {
+				logger.Error("Failed to log impersonated request", map[string]interface{}{
+					"error":      err.Error(),
+					"session_id": info.SessionID,
+				})
+			}
 		})
 	}
 }
@@ -0,0 +1,149 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RouteTimeouts are the request deadlines per route class
+type RouteTimeouts struct {
+	Read   time.Duration // GET, HEAD and OPTIONS
+	Write  time.Duration // everything else
+	AI     time.Duration // /api/v1/ai/*, regardless of method
+	Export time.Duration // /api/v1/admin/export/*, which stream
+	Stream time.Duration // /api/v1/stream, the server-sent event stream
+}
+
+const (
+	exportPathPrefix = "/api/v1/admin/export/"
+	streamPath       = "/api/v1/stream"
+)
+
+// For returns the deadline that applies to r
+func (t RouteTimeouts) For(r *http.Request) time.Duration {
+	if strings.HasPrefix(r.URL.Path, "/api/v1/ai/") {
+		return t.AI
+	}
+	if strings.HasPrefix(r.URL.Path, exportPathPrefix) {
+		return t.Export
+	}
+	if r.URL.Path == streamPath {
+		return t.Stream
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return t.Read
+	default:
+		return t.Write
+	}
+}
+
+// timeoutMiddleware runs the handler with a context deadline for its route
+// class. If the deadline passes first, the context is cancelled and the
+// client gets a 504; anything the handler writes afterwards is discarded.
+// Exports and the event stream write as they go, so they only get the
+// deadline and are cut off at it.
+func timeoutMiddleware(timeouts RouteTimeouts) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeouts.For(r))
+			defer cancel()
+
+			if strings.HasPrefix(r.URL.Path, exportPathPrefix) || r.URL.Path == streamPath {
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			tw := &timeoutWriter{header: make(http.Header)}
+			done := make(chan struct{})
+			panicked := make(chan interface{}, 1)
+
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicked <- p
+					}
+				}()
+				next.ServeHTTP(tw, r.WithContext(ctx))
+				close(done)
+			}()
+
+			select {
+			case p := <-panicked:
+				// Re-panic on the request goroutine so Recoverer handles it
+				panic(p)
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+
+				dst := w.Header()
+				for key, values := range tw.header {
+					dst[key] = values
+				}
+				if tw.code == 0 {
+					tw.code = http.StatusOK
+				}
+				w.WriteHeader(tw.code)
+				w.Write(tw.buf.Bytes())
+			case <-ctx.Done():
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				tw.timedOut = true
+
+				// A client that went away gets no response
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusGatewayTimeout)
+					json.NewEncoder(w).Encode(map[string]interface{}{
+						"error": map[string]interface{}{
+							"code":    "GATEWAY_TIMEOUT",
+							"message": "Request timed out",
+						},
+					})
+				}
+			}
+		})
+	}
+}
+
+// timeoutWriter buffers the handler's response so it can be dropped on timeout
+type timeoutWriter struct {
+	mu       sync.Mutex
+	header   http.Header
+	buf      bytes.Buffer
+	code     int
+	timedOut bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut || tw.code != 0 || code < 200 {
+		return
+	}
+	tw.code = code
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.code == 0 {
+		tw.code = http.StatusOK
+	}
+	return tw.buf.Write(p)
+}
@@ -0,0 +1,51 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouteTimeoutsFor(t *testing.T) {
+	timeouts := RouteTimeouts{Read: time.Second, Write: 2 * time.Second, AI: 3 * time.Second, Export: 4 * time.Second, Stream: 5 * time.Second}
+
+	tests := []struct {
+		method string
+		path   string
+		want   time.Duration
+	}{
+		{http.MethodGet, "/api/v1/posts", time.Second},
+		{http.MethodPost, "/api/v1/posts", 2 * time.Second},
+		{http.MethodPost, "/api/v1/ai/generate", 3 * time.Second},
+		{http.MethodGet, "/api/v1/admin/export/posts", 4 * time.Second},
+		{http.MethodGet, "/api/v1/stream", 5 * time.Second},
+		{http.MethodGet, "/api/v1/stream/other", time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			assert.Equal(t, tt.want, timeouts.For(r))
+		})
+	}
+}
+
+func TestTimeoutMiddlewareStreamsUnbuffered(t *testing.T) {
+	timeouts := RouteTimeouts{Read: 50 * time.Millisecond, Stream: time.Minute}
+	handler := timeoutMiddleware(timeouts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(": hello\n\n"))
+		// A buffered response would be replaced by a 504 here
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte(": still here\n\n"))
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/stream", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, ": hello\n\n: still here\n\n", w.Body.String())
+}
@@ -4,30 +4,68 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"time"
 )
 
+// DefaultModel is the model used for all completions
+const DefaultModel = "openai/gpt-oss-120b"
+
+// ErrShuttingDown is returned for requests aborted by CancelInFlight
+var ErrShuttingDown = errors.New("AI client is shutting down")
+
 // Client represents OpenAI-compatible API client
 type Client struct {
 	baseURL    string
 	apiKey     string
 	httpClient *http.Client
+
+	// stopCtx is cancelled by CancelInFlight to abort every pending request
+	stopCtx context.Context
+	stop    context.CancelFunc
 }
 
 // NewClient creates a new OpenAI-compatible API client
 func NewClient(baseURL, apiKey string) *Client {
+	stopCtx, stop := context.WithCancel(context.Background())
 	return &Client{
 		baseURL: baseURL,
 		apiKey:  apiKey,
 		httpClient: &http.Client{
 			Timeout: 180 * time.Second, // Increased for long AI generation requests
 		},
+		stopCtx: stopCtx,
+		stop:    stop,
 	}
 }
 
+// CancelInFlight aborts pending requests and rejects new ones. It is used on
+// shutdown so slow generations don't hold the server past its deadline.
+func (c *Client) CancelInFlight() {
+	c.stop()
+}
+
+// withStop derives a request context that is also cancelled by CancelInFlight
+func (c *Client) withStop(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	stopAfter := context.AfterFunc(c.stopCtx, cancel)
+	return ctx, func() {
+		stopAfter()
+		cancel()
+	}
+}
+
+// requestError reports aborted requests as ErrShuttingDown
+func (c *Client) requestError(err error) error {
+	if c.stopCtx.Err() != nil {
+		return ErrShuttingDown
+	}
+	return fmt.Errorf("failed to make request: %w", err)
+}
+
 // ChatMessage represents a single message in chat
 type ChatMessage struct {
 	Role    string `json:"role"`
@@ -43,6 +81,20 @@ type ChatCompletionRequest struct {
 	Stream      bool          `json:"stream,omitempty"`
 }
 
+// Usage represents token accounting reported by the API
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// Completion is the result of a single text generation
+type Completion struct {
+	Text  string
+	Model string
+	Usage Usage
+}
+
 // ChatCompletionResponse represents the response from chat completion
 type ChatCompletionResponse struct {
 	ID      string `json:"id"`
@@ -54,11 +106,24 @@ type ChatCompletionResponse struct {
 		Message      ChatMessage `json:"message"`
 		FinishReason string      `json:"finish_reason"`
 	} `json:"choices"`
-	Usage struct {
-		PromptTokens     int `json:"prompt_tokens"`
-		CompletionTokens int `json:"completion_tokens"`
-		TotalTokens      int `json:"total_tokens"`
-	} `json:"usage"`
+	Usage Usage `json:"usage"`
+}
+
+// EmbeddingRequest represents a request for text embeddings
+type EmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// EmbeddingResponse represents the response from embeddings endpoint
+type EmbeddingResponse struct {
+	Object string `json:"object"`
+	Model  string `json:"model"`
+	Data   []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Usage Usage `json:"usage"`
 }
 
 // ModelsResponse represents the response from models endpoint
@@ -73,13 +138,19 @@ type ModelsResponse struct {
 }
 
 // GenerateText generates text using the OpenAI-compatible API
-func (c *Client) GenerateText(ctx context.Context, prompt string, maxTokens int, temperature float32) (string, error) {
+func (c *Client) GenerateText(ctx context.Context, prompt string, maxTokens int, temperature float32) (*Completion, error) {
+	if c.stopCtx.Err() != nil {
+		return nil, ErrShuttingDown
+	}
+	ctx, cancel := c.withStop(ctx)
+	defer cancel()
+
 	if c.apiKey == "" {
-		return "", fmt.Errorf("API key is required")
+		return nil, fmt.Errorf("API key is required")
 	}
 
 	request := ChatCompletionRequest{
-		Model: "openai/gpt-oss-120b", // Based on the provided API response
+		Model: DefaultModel,
 		Messages: []ChatMessage{
 			{
 				Role:    "user",
@@ -93,13 +164,13 @@ func (c *Client) GenerateText(ctx context.Context, prompt string, maxTokens int,
 
 	jsonData, err := json.Marshal(request)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	url := fmt.Sprintf("%s/chat/completions", c.baseURL)
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -107,25 +178,91 @@ func (c *Client) GenerateText(ctx context.Context, prompt string, maxTokens int,
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to make request: %w", err)
+		return nil, c.requestError(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
 	}
 
 	var response ChatCompletionResponse
 	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
+		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
 	if len(response.Choices) == 0 {
-		return "", fmt.Errorf("no completion choices returned")
+		return nil, fmt.Errorf("no completion choices returned")
+	}
+
+	model := response.Model
+	if model == "" {
+		model = request.Model
+	}
+
+	return &Completion{
+		Text:  response.Choices[0].Message.Content,
+		Model: model,
+		Usage: response.Usage,
+	}, nil
+}
+
+// CreateEmbeddings returns one embedding per input, in input order
+func (c *Client) CreateEmbeddings(ctx context.Context, model string, inputs []string) ([][]float32, error) {
+	if c.stopCtx.Err() != nil {
+		return nil, ErrShuttingDown
+	}
+	ctx, cancel := c.withStop(ctx)
+	defer cancel()
+
+	if c.apiKey == "" {
+		return nil, fmt.Errorf("API key is required")
+	}
+
+	jsonData, err := json.Marshal(EmbeddingRequest{Model: model, Input: inputs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/embeddings", c.baseURL)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, c.requestError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var response EmbeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if len(response.Data) != len(inputs) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(inputs), len(response.Data))
+	}
+
+	embeddings := make([][]float32, len(inputs))
+	for _, item := range response.Data {
+		if item.Index < 0 || item.Index >= len(inputs) {
+			return nil, fmt.Errorf("embedding index %d out of range", item.Index)
+		}
+		embeddings[item.Index] = item.Embedding
 	}
 
-	return response.Choices[0].Message.Content, nil
+	return embeddings, nil
 }
 
 // ListModels retrieves available models from the API
@@ -142,7 +279,7 @@ func (c *Client) ListModels(ctx context.Context) (*ModelsResponse, error) {
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+		return nil, c.requestError(err)
 	}
 	defer resp.Body.Close()
 
@@ -2,58 +2,292 @@ package auth
 
 import (
 	"context"
+	"crypto"
+	"crypto/ed25519"
 	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
 	"encoding/hex"
+	"encoding/pem"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+
+	"bailanysta/api/pkg/jwks"
+)
+
+// Signing algorithms. Tokens signed with RS256 or EdDSA can be verified by
+// other services through the JWKS endpoint.
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+const (
+	DefaultIssuer   = "bailanysta"
+	DefaultAudience = "bailanysta-api"
+)
+
+// Token scopes; a token without ScopeWrite may only read
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
 )
 
+// SessionScopes are granted to tokens issued at login
+var SessionScopes = []string{ScopeRead, ScopeWrite}
+
+// JWTConfig selects how access tokens are signed. Previous secrets and
+// public keys are still accepted so keys can be rotated without logging
+// everyone out.
+type JWTConfig struct {
+	Algorithm          string // HS256 (default), RS256 or EdDSA
+	Secret             string // HS256
+	PreviousSecrets    []string
+	PrivateKeyPEM      string // RS256 (PKCS#1 or PKCS#8) or EdDSA (PKCS#8)
+	PreviousPublicKeys string // RSA or Ed25519, concatenated PEM blocks
+	Issuer             string
+	Audience           string
+	AccessExpiry       time.Duration
+	RefreshExpiry      time.Duration
+}
+
 type JWTManager struct {
-	secretKey     []byte
+	method        jwt.SigningMethod
+	signingKey    interface{}
+	keyID         string
+	keys          map[string]verificationKey // by kid
+	issuer        string
+	audience      string
 	accessExpiry  time.Duration
 	refreshExpiry time.Duration
 }
 
+type verificationKey struct {
+	method jwt.SigningMethod
+	key    interface{}
+}
+
+// Claims identify the user. Role is informational: permissions are always
+// checked against the current role in the database.
 type Claims struct {
 	UserID uuid.UUID `json:"user_id"`
+	OrgID  uuid.UUID `json:"org_id"`
+	Role   string    `json:"role,omitempty"`
+	Scopes []string  `json:"scopes,omitempty"`
+	// ImpersonatorID is set on impersonation tokens, whose ID (jti) is the
+	// impersonation session
+	ImpersonatorID *uuid.UUID `json:"impersonator_id,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// HasScope reports whether the token grants scope
+func (c *Claims) HasScope(scope string) bool {
+	for _, s := range c.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
 type TokenPair struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
 }
 
+// NewJWTManager creates an HS256 manager with the default issuer and audience
 func NewJWTManager(secretKey string, accessExpiry, refreshExpiry time.Duration) *JWTManager {
-	return &JWTManager{
-		secretKey:     []byte(secretKey),
+	jm := &JWTManager{
+		issuer:        DefaultIssuer,
+		audience:      DefaultAudience,
 		accessExpiry:  accessExpiry,
 		refreshExpiry: refreshExpiry,
+		keys:          make(map[string]verificationKey),
 	}
+	jm.method, jm.signingKey, jm.keyID = jwt.SigningMethodHS256, []byte(secretKey), jm.addHMACKey(secretKey)
+	return jm
 }
 
-func (jm *JWTManager) GenerateTokenPair(userID uuid.UUID) (*TokenPair, error) {
+// NewJWTManagerFromConfig creates a manager for cfg
+func NewJWTManagerFromConfig(cfg JWTConfig) (*JWTManager, error) {
+	jm := &JWTManager{
+		issuer:        cfg.Issuer,
+		audience:      cfg.Audience,
+		accessExpiry:  cfg.AccessExpiry,
+		refreshExpiry: cfg.RefreshExpiry,
+		keys:          make(map[string]verificationKey),
+	}
+	if jm.issuer == "" {
+		jm.issuer = DefaultIssuer
+	}
+	if jm.audience == "" {
+		jm.audience = DefaultAudience
+	}
+
+	switch cfg.Algorithm {
+	case "", AlgorithmHS256:
+		if cfg.Secret == "" {
+			return nil, fmt.Errorf("HS256 needs a secret")
+		}
+		jm.method, jm.signingKey, jm.keyID = jwt.SigningMethodHS256, []byte(cfg.Secret), jm.addHMACKey(cfg.Secret)
+	case AlgorithmRS256, AlgorithmEdDSA:
+		privateKey, err := parsePrivateKey(cfg.PrivateKeyPEM)
+		if err != nil {
+			return nil, err
+		}
+		keyID, method, err := jm.addPublicKey(privateKey.Public())
+		if err != nil {
+			return nil, err
+		}
+		if method.Alg() != cfg.Algorithm {
+			return nil, fmt.Errorf("%s needs a matching private key, got one for %s", cfg.Algorithm, method.Alg())
+		}
+		jm.method, jm.signingKey, jm.keyID = method, privateKey, keyID
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", cfg.Algorithm)
+	}
+
+	// Previous keys of either kind verify tokens issued before a rotation,
+	// including a switch between algorithms
+	for _, secret := range cfg.PreviousSecrets {
+		if secret != "" {
+			jm.addHMACKey(secret)
+		}
+	}
+	publicKeys, err := parsePublicKeys(cfg.PreviousPublicKeys)
+	if err != nil {
+		return nil, err
+	}
+	for _, publicKey := range publicKeys {
+		if _, _, err := jm.addPublicKey(publicKey); err != nil {
+			return nil, err
+		}
+	}
+
+	return jm, nil
+}
+
+// Key IDs are derived from the key so every instance agrees on them
+func (jm *JWTManager) addHMACKey(secret string) string {
+	sum := sha256.Sum256([]byte("hs256:" + secret))
+	keyID := hex.EncodeToString(sum[:8])
+	jm.keys[keyID] = verificationKey{method: jwt.SigningMethodHS256, key: []byte(secret)}
+	return keyID
+}
+
+func (jm *JWTManager) addPublicKey(publicKey crypto.PublicKey) (string, jwt.SigningMethod, error) {
+	var method jwt.SigningMethod
+	switch publicKey.(type) {
+	case *rsa.PublicKey:
+		method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		method = jwt.SigningMethodEdDSA
+	default:
+		return "", nil, fmt.Errorf("unsupported public key type %T", publicKey)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encode public key: %w", err)
+	}
+	sum := sha256.Sum256(der)
+	keyID := hex.EncodeToString(sum[:8])
+	jm.keys[keyID] = verificationKey{method: method, key: publicKey}
+	return keyID, method, nil
+}
+
+func parsePrivateKey(data string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, fmt.Errorf("JWT_PRIVATE_KEY must be a PEM private key")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		return key, nil
+	case ed25519.PrivateKey:
+		return key, nil
+	}
+	return nil, fmt.Errorf("private key must be RSA or Ed25519")
+}
+
+func parsePublicKeys(data string) ([]crypto.PublicKey, error) {
+	var keys []crypto.PublicKey
+	rest := []byte(data)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return keys, nil
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse public key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+}
+
+// JWKS returns the public keys tokens may be signed with, current key
+// first. It is empty for HS256, whose secrets must never be published.
+func (jm *JWTManager) JWKS() jwks.Set {
+	set := jwks.Set{Keys: []jwks.Key{}}
+	if key, ok := jm.keys[jm.keyID]; ok && key.method != jwt.SigningMethodHS256 {
+		if jwk, err := jwks.FromPublicKey(jm.keyID, key.key); err == nil {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+
+	previous := make([]string, 0, len(jm.keys))
+	for keyID, key := range jm.keys {
+		if keyID != jm.keyID && key.method != jwt.SigningMethodHS256 {
+			previous = append(previous, keyID)
+		}
+	}
+	sort.Strings(previous)
+	for _, keyID := range previous {
+		if jwk, err := jwks.FromPublicKey(keyID, jm.keys[keyID].key); err == nil {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+// GenerateTokenPair issues tokens for a user with the given role and scopes
+func (jm *JWTManager) GenerateTokenPair(userID, orgID uuid.UUID, role string, scopes []string) (*TokenPair, error) {
 	now := time.Now()
 
 	// Generate access token
 	accessClaims := Claims{
 		UserID: userID,
+		OrgID:  orgID,
+		Role:   role,
+		Scopes: scopes,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(now.Add(jm.accessExpiry)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
-			Issuer:    "bailanysta",
+			Issuer:    jm.issuer,
+			Audience:  jwt.ClaimStrings{jm.audience},
 			Subject:   userID.String(),
 		},
 	}
 
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims)
-	accessTokenString, err := accessToken.SignedString(jm.secretKey)
+	accessTokenString, err := jm.sign(accessClaims)
 	if err != nil {
-		return nil, fmt.Errorf("failed to sign access token: %w", err)
+		return nil, err
 	}
 
 	// Generate refresh token (random string)
@@ -68,13 +302,57 @@ func (jm *JWTManager) GenerateTokenPair(userID uuid.UUID) (*TokenPair, error) {
 	}, nil
 }
 
+// GenerateImpersonationToken issues an access token, without a refresh
+// token, that acts as userID on behalf of impersonatorID until expiresAt
+func (jm *JWTManager) GenerateImpersonationToken(userID, orgID uuid.UUID, role string, scopes []string, impersonatorID, sessionID uuid.UUID, expiresAt time.Time) (string, error) {
+	now := time.Now()
+	return jm.sign(Claims{
+		UserID:         userID,
+		OrgID:          orgID,
+		Role:           role,
+		Scopes:         scopes,
+		ImpersonatorID: &impersonatorID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        sessionID.String(),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    jm.issuer,
+			Audience:  jwt.ClaimStrings{jm.audience},
+			Subject:   userID.String(),
+		},
+	})
+}
+
+func (jm *JWTManager) sign(claims Claims) (string, error) {
+	token := jwt.NewWithClaims(jm.method, claims)
+	token.Header["kid"] = jm.keyID
+	signed, err := token.SignedString(jm.signingKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign access token: %w", err)
+	}
+	return signed, nil
+}
+
+// ValidateAccessToken checks the signature against the key named by the kid
+// header, then expiry, issuer and audience
 func (jm *JWTManager) ValidateAccessToken(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		keyID, _ := token.Header["kid"].(string)
+		key, ok := jm.keys[keyID]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", keyID)
+		}
+		if token.Method.Alg() != key.method.Alg() {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return jm.secretKey, nil
-	})
+		return key.key, nil
+	},
+		jwt.WithValidMethods([]string{AlgorithmHS256, AlgorithmRS256, AlgorithmEdDSA}),
+		jwt.WithIssuer(jm.issuer),
+		jwt.WithAudience(jm.audience),
+		jwt.WithExpirationRequired(),
+	)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse token: %w", err)
@@ -92,8 +370,14 @@ func (jm *JWTManager) ValidateAccessToken(tokenString string) (*Claims, error) {
 	return claims, nil
 }
 
+// RefreshExpiry is how long refresh tokens stay valid
+func (jm *JWTManager) RefreshExpiry() time.Duration {
+	return jm.refreshExpiry
+}
+
+// ValidateRefreshToken only checks the token's form; refresh tokens are
+// looked up by the auth service
 func (jm *JWTManager) ValidateRefreshToken(refreshToken string) error {
-	// For now, just check if it's not empty (in production, store in database/redis)
 	if refreshToken == "" {
 		return fmt.Errorf("empty refresh token")
 	}
@@ -114,6 +398,21 @@ func (jm *JWTManager) GetUserIDFromContext(ctx context.Context) (uuid.UUID, erro
 	return userID, nil
 }
 
+// GetOrgIDFromContext returns the organization the request is scoped to
+func (jm *JWTManager) GetOrgIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	orgIDStr, ok := ctx.Value("org_id").(string)
+	if !ok {
+		return uuid.Nil, fmt.Errorf("org_id not found in context")
+	}
+
+	orgID, err := uuid.Parse(orgIDStr)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid org_id format: %w", err)
+	}
+
+	return orgID, nil
+}
+
 func generateRandomToken() (string, error) {
 	bytes := make([]byte, 32)
 	if _, err := rand.Read(bytes); err != nil {
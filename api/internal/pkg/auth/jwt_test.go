@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rsaKeyPEM(t *testing.T) (string, string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return keyPEMs(t, key, &key.PublicKey)
+}
+
+func ed25519KeyPEM(t *testing.T) (string, string) {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return keyPEMs(t, private, public)
+}
+
+// keyPEMs encodes a key pair as PKCS#8 and PKIX PEM blocks
+func keyPEMs(t *testing.T, private, public interface{}) (string, string) {
+	t.Helper()
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}))
+}
+
+func assertRoundTrip(t *testing.T, issuer, validator *JWTManager) {
+	t.Helper()
+	userID, orgID := uuid.New(), uuid.New()
+	pair, err := issuer.GenerateTokenPair(userID, orgID, "member", SessionScopes)
+	require.NoError(t, err)
+
+	claims, err := validator.ValidateAccessToken(pair.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, userID, claims.UserID)
+	assert.Equal(t, orgID, claims.OrgID)
+	assert.True(t, claims.HasScope(ScopeWrite))
+}
+
+func TestJWTRoundTrip(t *testing.T) {
+	rsaPrivate, _ := rsaKeyPEM(t)
+	edPrivate, _ := ed25519KeyPEM(t)
+
+	tests := []struct {
+		name string
+		cfg  JWTConfig
+	}{
+		{"HS256", JWTConfig{Algorithm: AlgorithmHS256, Secret: "test-secret"}},
+		{"RS256", JWTConfig{Algorithm: AlgorithmRS256, PrivateKeyPEM: rsaPrivate}},
+		{"EdDSA", JWTConfig{Algorithm: AlgorithmEdDSA, PrivateKeyPEM: edPrivate}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.AccessExpiry, tt.cfg.RefreshExpiry = time.Minute, time.Hour
+			jm, err := NewJWTManagerFromConfig(tt.cfg)
+			require.NoError(t, err)
+			assertRoundTrip(t, jm, jm)
+		})
+	}
+}
+
+func TestJWTRotatedKeys(t *testing.T) {
+	rsaPrivate, rsaPublic := rsaKeyPEM(t)
+	edPrivate, edPublic := ed25519KeyPEM(t)
+	newEdPrivate, _ := ed25519KeyPEM(t)
+
+	oldHS, err := NewJWTManagerFromConfig(JWTConfig{Secret: "old-secret", AccessExpiry: time.Minute})
+	require.NoError(t, err)
+	oldRS, err := NewJWTManagerFromConfig(JWTConfig{Algorithm: AlgorithmRS256, PrivateKeyPEM: rsaPrivate, AccessExpiry: time.Minute})
+	require.NoError(t, err)
+	oldEd, err := NewJWTManagerFromConfig(JWTConfig{Algorithm: AlgorithmEdDSA, PrivateKeyPEM: edPrivate, AccessExpiry: time.Minute})
+	require.NoError(t, err)
+
+	current, err := NewJWTManagerFromConfig(JWTConfig{
+		Algorithm:          AlgorithmEdDSA,
+		PrivateKeyPEM:      newEdPrivate,
+		PreviousSecrets:    []string{"old-secret"},
+		PreviousPublicKeys: rsaPublic + edPublic,
+		AccessExpiry:       time.Minute,
+	})
+	require.NoError(t, err)
+
+	t.Run("previous HS256 secret", func(t *testing.T) { assertRoundTrip(t, oldHS, current) })
+	t.Run("previous RS256 key", func(t *testing.T) { assertRoundTrip(t, oldRS, current) })
+	t.Run("previous EdDSA key", func(t *testing.T) { assertRoundTrip(t, oldEd, current) })
+	t.Run("current key", func(t *testing.T) { assertRoundTrip(t, current, current) })
+
+	// A key that was never configured is rejected
+	other, err := NewJWTManagerFromConfig(JWTConfig{Secret: "other-secret", AccessExpiry: time.Minute})
+	require.NoError(t, err)
+	pair, err := other.GenerateTokenPair(uuid.New(), uuid.New(), "member", SessionScopes)
+	require.NoError(t, err)
+	_, err = current.ValidateAccessToken(pair.AccessToken)
+	assert.Error(t, err)
+
+	// Keys that are no longer listed stop working
+	_, err = oldHS.ValidateAccessToken(mustToken(t, current))
+	assert.Error(t, err)
+}
+
+func mustToken(t *testing.T, jm *JWTManager) string {
+	t.Helper()
+	pair, err := jm.GenerateTokenPair(uuid.New(), uuid.New(), "member", SessionScopes)
+	require.NoError(t, err)
+	return pair.AccessToken
+}
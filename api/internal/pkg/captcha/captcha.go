@@ -0,0 +1,98 @@
+// Package captcha verifies that a request comes from a person: an hCaptcha
+// or Turnstile token checked with the provider, or an Altcha-style
+// proof-of-work solution checked locally.
+package captcha
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Providers
+const (
+	ProviderHCaptcha  = "hcaptcha"
+	ProviderTurnstile = "turnstile"
+	ProviderPoW       = "pow"
+)
+
+const (
+	hcaptchaURL  = "https://api.hcaptcha.com/siteverify"
+	turnstileURL = "https://challenges.cloudflare.com/turnstile/v0/siteverify"
+)
+
+// ErrFailed is returned for a missing, wrong or reused token
+var ErrFailed = errors.New("bot check failed")
+
+// Verifier checks the token a client solved
+type Verifier interface {
+	Verify(ctx context.Context, token, remoteIP string) error
+}
+
+// SiteVerifier checks hCaptcha and Turnstile tokens with the provider
+type SiteVerifier struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+// NewSiteVerifier returns a verifier for provider (hcaptcha or turnstile)
+func NewSiteVerifier(provider, secret string, timeout time.Duration) (*SiteVerifier, error) {
+	var verifyURL string
+	switch provider {
+	case ProviderHCaptcha:
+		verifyURL = hcaptchaURL
+	case ProviderTurnstile:
+		verifyURL = turnstileURL
+	default:
+		return nil, fmt.Errorf("unknown captcha provider %q", provider)
+	}
+	return &SiteVerifier{
+		url:    verifyURL,
+		secret: secret,
+		client: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Verify asks the provider whether token is valid. Both providers take the
+// same form and answer with {"success": bool}.
+func (v *SiteVerifier) Verify(ctx context.Context, token, remoteIP string) error {
+	if token == "" {
+		return ErrFailed
+	}
+	form := url.Values{"secret": {v.secret}, "response": {token}}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("captcha verification request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("captcha provider returned status %d", resp.StatusCode)
+	}
+	var result struct {
+		Success bool `json:"success"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode captcha response: %w", err)
+	}
+	if !result.Success {
+		return ErrFailed
+	}
+	return nil
+}
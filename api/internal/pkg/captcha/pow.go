@@ -0,0 +1,157 @@
+package captcha
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const powAlgorithm = "SHA-256"
+
+// Challenge is an Altcha-compatible proof-of-work challenge: find the
+// number in [0, MaxNumber] for which SHA-256(Salt + number) is Challenge.
+// Signature lets the server check it issued the challenge without storing it.
+type Challenge struct {
+	Algorithm string `json:"algorithm"`
+	Challenge string `json:"challenge"`
+	MaxNumber int    `json:"maxnumber"`
+	Salt      string `json:"salt"`
+	Signature string `json:"signature"`
+}
+
+// solution is what clients send back, base64-encoded JSON
+type solution struct {
+	Algorithm string `json:"algorithm"`
+	Challenge string `json:"challenge"`
+	Number    int    `json:"number"`
+	Salt      string `json:"salt"`
+	Signature string `json:"signature"`
+}
+
+// ProofOfWork issues and verifies challenges. Solved challenges are
+// remembered until they expire so each works once; that memory is per
+// instance.
+type ProofOfWork struct {
+	key       []byte
+	maxNumber int
+	ttl       time.Duration
+
+	mu   sync.Mutex
+	used map[string]time.Time // challenge -> expiry
+}
+
+// NewProofOfWork returns a verifier whose challenges take on average
+// maxNumber/2 hashes to solve and expire after ttl
+func NewProofOfWork(key string, maxNumber int, ttl time.Duration) *ProofOfWork {
+	return &ProofOfWork{
+		key:       []byte("captcha:" + key),
+		maxNumber: maxNumber,
+		ttl:       ttl,
+		used:      make(map[string]time.Time),
+	}
+}
+
+// NewChallenge issues a challenge
+func (p *ProofOfWork) NewChallenge() (*Challenge, error) {
+	random := make([]byte, 12)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	number, err := rand.Int(rand.Reader, big.NewInt(int64(p.maxNumber)+1))
+	if err != nil {
+		return nil, err
+	}
+
+	salt := hex.EncodeToString(random) + "?expires=" + strconv.FormatInt(time.Now().Add(p.ttl).Unix(), 10)
+	challenge := hashHex(salt + number.String())
+	return &Challenge{
+		Algorithm: powAlgorithm,
+		Challenge: challenge,
+		MaxNumber: p.maxNumber,
+		Salt:      salt,
+		Signature: p.sign(challenge),
+	}, nil
+}
+
+// Verify checks a base64-encoded solution
+func (p *ProofOfWork) Verify(ctx context.Context, token, remoteIP string) error {
+	payload, err := base64.StdEncoding.DecodeString(token)
+	if err != nil {
+		return ErrFailed
+	}
+	var solved solution
+	if err := json.Unmarshal(payload, &solved); err != nil {
+		return ErrFailed
+	}
+
+	expiresAt, ok := saltExpiry(solved.Salt)
+	if solved.Algorithm != powAlgorithm || !ok || time.Now().After(expiresAt) {
+		return ErrFailed
+	}
+	if !hmac.Equal([]byte(solved.Signature), []byte(p.sign(solved.Challenge))) {
+		return ErrFailed
+	}
+	if hashHex(solved.Salt+strconv.Itoa(solved.Number)) != solved.Challenge {
+		return ErrFailed
+	}
+	if !p.markUsed(solved.Challenge, expiresAt) {
+		return ErrFailed
+	}
+	return nil
+}
+
+// markUsed records challenge as solved, reporting false if it already was
+func (p *ProofOfWork) markUsed(challenge string, expiresAt time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for used, expiry := range p.used {
+		if now.After(expiry) {
+			delete(p.used, used)
+		}
+	}
+	if _, ok := p.used[challenge]; ok {
+		return false
+	}
+	p.used[challenge] = expiresAt
+	return true
+}
+
+func (p *ProofOfWork) sign(challenge string) string {
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write([]byte(challenge))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// saltExpiry reads the expiry NewChallenge put in the salt
+func saltExpiry(salt string) (time.Time, bool) {
+	_, query, ok := strings.Cut(salt, "?")
+	if !ok {
+		return time.Time{}, false
+	}
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return time.Time{}, false
+	}
+	expires, err := strconv.ParseInt(params.Get("expires"), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(expires, 0), true
+}
+
+func hashHex(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
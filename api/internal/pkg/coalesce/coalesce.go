@@ -0,0 +1,119 @@
+// Package coalesce merges concurrent identical reads into one and keeps the
+// result for a short while, so a burst of requests for the same hot row
+// costs a single query
+package coalesce
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"bailanysta/api/internal/pkg/metrics"
+)
+
+// maxEntries bounds the cache; when full, expired entries are swept and, if
+// that frees nothing, the cache starts over
+const maxEntries = 10000
+
+var lookups = metrics.NewCounterVec("coalesce_lookups_total",
+	"Coalesced lookups by result: hit (cached), shared (joined an in-flight query) or miss", "group", "result")
+
+// Group coalesces lookups of V by key. Errors are never cached. With a zero
+// TTL lookups are only coalesced.
+type Group[V any] struct {
+	name   string
+	ttl    time.Duration
+	flight singleflight.Group
+
+	mu      sync.Mutex
+	entries map[string]entry[V]
+}
+
+type entry[V any] struct {
+	value   V
+	expires time.Time
+}
+
+// New creates a group; name labels its metrics
+func New[V any](name string, ttl time.Duration) *Group[V] {
+	return &Group[V]{name: name, ttl: ttl, entries: make(map[string]entry[V])}
+}
+
+// Do returns the cached value for key or calls fetch, sharing its result with
+// concurrent callers of the same key. fetch runs with a context that is not
+// cancelled when ctx is, since other callers may be waiting on it; ctx only
+// bounds how long this caller waits.
+func (g *Group[V]) Do(ctx context.Context, key string, fetch func(ctx context.Context) (V, error)) (V, error) {
+	if value, ok := g.cached(key); ok {
+		lookups.Inc(g.name, "hit")
+		return value, nil
+	}
+
+	result := g.flight.DoChan(key, func() (interface{}, error) {
+		value, err := fetch(context.WithoutCancel(ctx))
+		if err == nil {
+			g.store(key, value)
+		}
+		return value, err
+	})
+
+	select {
+	case r := <-result:
+		if r.Shared {
+			lookups.Inc(g.name, "shared")
+		} else {
+			lookups.Inc(g.name, "miss")
+		}
+		if r.Err != nil {
+			var zero V
+			return zero, r.Err
+		}
+		return r.Val.(V), nil
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// Forget drops key so the next lookup fetches it again. A fetch already in
+// flight may still store its older result.
+func (g *Group[V]) Forget(key string) {
+	g.flight.Forget(key)
+	g.mu.Lock()
+	delete(g.entries, key)
+	g.mu.Unlock()
+}
+
+func (g *Group[V]) cached(key string) (V, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	e, ok := g.entries[key]
+	if !ok || time.Now().After(e.expires) {
+		var zero V
+		return zero, false
+	}
+	return e.value, true
+}
+
+func (g *Group[V]) store(key string, value V) {
+	if g.ttl <= 0 {
+		return
+	}
+	now := time.Now()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.entries) >= maxEntries {
+		for k, e := range g.entries {
+			if now.After(e.expires) {
+				delete(g.entries, k)
+			}
+		}
+		if len(g.entries) >= maxEntries {
+			g.entries = make(map[string]entry[V])
+		}
+	}
+	g.entries[key] = entry[V]{value: value, expires: now.Add(g.ttl)}
+}
@@ -0,0 +1,134 @@
+// Package database routes read-only queries to an optional read replica.
+package database
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"bailanysta/api/internal/pkg/logger"
+)
+
+const (
+	healthCheckInterval = 5 * time.Second
+	healthCheckTimeout  = 2 * time.Second
+)
+
+// Pool is the primary connection pool plus an optional replica. The embedded
+// primary serves writes and anything that must see them; Reader serves
+// queries that tolerate replication lag.
+type Pool struct {
+	*pgxpool.Pool
+
+	replica        *pgxpool.Pool
+	replicaHealthy atomic.Bool
+	logger         *logger.Logger
+}
+
+// New wraps primary and replica; replica may be nil. The replica is unused
+// until RunHealthCheck has reached it.
+func New(primary, replica *pgxpool.Pool, logger *logger.Logger) *Pool {
+	return &Pool{Pool: primary, replica: replica, logger: logger}
+}
+
+// Reader returns the replica if it is configured and healthy, otherwise the
+// primary
+func (p *Pool) Reader() *pgxpool.Pool {
+	if p.replica != nil && p.replicaHealthy.Load() {
+		return p.replica
+	}
+	return p.Pool
+}
+
+// HasReplica reports whether a replica is configured
+func (p *Pool) HasReplica() bool {
+	return p.replica != nil
+}
+
+// RunHealthCheck pings the replica until ctx is cancelled, sending reads to
+// the primary while it is unreachable
+func (p *Pool) RunHealthCheck(ctx context.Context) {
+	if p.replica == nil {
+		return
+	}
+
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		p.checkReplica(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Pool) checkReplica(ctx context.Context) {
+	pingCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	err := p.replica.Ping(pingCtx)
+	cancel()
+	if ctx.Err() != nil {
+		return
+	}
+
+	healthy := err == nil
+	if p.replicaHealthy.Swap(healthy) == healthy {
+		return
+	}
+	if healthy {
+		p.logger.Info("Read replica available, routing reads to it")
+	} else {
+		p.logger.Warn("Read replica unreachable, routing reads to primary", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+}
+
+// Close closes both pools
+func (p *Pool) Close() {
+	if p.replica != nil {
+		p.replica.Close()
+	}
+	p.Pool.Close()
+}
+
+// PoolStats is a snapshot of one pool's connections
+type PoolStats struct {
+	TotalConns           int32         `json:"total_conns"`
+	AcquiredConns        int32         `json:"acquired_conns"`
+	IdleConns            int32         `json:"idle_conns"`
+	MaxConns             int32         `json:"max_conns"`
+	AcquireCount         int64         `json:"acquire_count"`
+	EmptyAcquireCount    int64         `json:"empty_acquire_count"`
+	CanceledAcquireCount int64         `json:"canceled_acquire_count"`
+	AcquireDuration      time.Duration `json:"acquire_duration_ns"`
+}
+
+func poolStats(pool *pgxpool.Pool) PoolStats {
+	stat := pool.Stat()
+	return PoolStats{
+		TotalConns:           stat.TotalConns(),
+		AcquiredConns:        stat.AcquiredConns(),
+		IdleConns:            stat.IdleConns(),
+		MaxConns:             stat.MaxConns(),
+		AcquireCount:         stat.AcquireCount(),
+		EmptyAcquireCount:    stat.EmptyAcquireCount(),
+		CanceledAcquireCount: stat.CanceledAcquireCount(),
+		AcquireDuration:      stat.AcquireDuration(),
+	}
+}
+
+// Stats returns connection stats for the primary and, if configured, the
+// replica
+func (p *Pool) Stats() map[string]PoolStats {
+	stats := map[string]PoolStats{"primary": poolStats(p.Pool)}
+	if p.replica != nil {
+		stats["replica"] = poolStats(p.replica)
+	}
+	return stats
+}
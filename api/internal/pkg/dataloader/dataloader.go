@@ -0,0 +1,96 @@
+package dataloader
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// BatchFunc fetches values for many keys at once. Keys missing from the
+// returned map load as the zero value.
+type BatchFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+// Loader collects the keys requested within a short window and fetches them
+// with one BatchFunc call. Results are cached for the loader's lifetime, so
+// a loader should live no longer than one request.
+type Loader[K comparable, V any] struct {
+	fetch    BatchFunc[K, V]
+	wait     time.Duration
+	maxBatch int
+
+	mu      sync.Mutex
+	results map[K]*result[V]
+	pending *batch[K, V]
+}
+
+type result[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+type batch[K comparable, V any] struct {
+	once    sync.Once
+	keys    []K
+	results []*result[V]
+}
+
+// New creates a loader that waits up to wait for more keys before fetching,
+// or fetches as soon as maxBatch keys are pending
+func New[K comparable, V any](fetch BatchFunc[K, V], wait time.Duration, maxBatch int) *Loader[K, V] {
+	return &Loader[K, V]{
+		fetch:    fetch,
+		wait:     wait,
+		maxBatch: maxBatch,
+		results:  make(map[K]*result[V]),
+	}
+}
+
+// Load returns the value for key, fetching it with other pending keys. The
+// batch is fetched with the context of the Load call that started it.
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+	res, ok := l.results[key]
+	if !ok {
+		res = &result[V]{done: make(chan struct{})}
+		l.results[key] = res
+
+		if l.pending == nil {
+			b := &batch[K, V]{}
+			l.pending = b
+			time.AfterFunc(l.wait, func() { l.dispatch(ctx, b) })
+		}
+		b := l.pending
+		b.keys = append(b.keys, key)
+		b.results = append(b.results, res)
+		if len(b.keys) >= l.maxBatch {
+			l.pending = nil
+			go l.dispatch(ctx, b)
+		}
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-res.done:
+		return res.value, res.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+func (l *Loader[K, V]) dispatch(ctx context.Context, b *batch[K, V]) {
+	b.once.Do(func() {
+		l.mu.Lock()
+		if l.pending == b {
+			l.pending = nil
+		}
+		l.mu.Unlock()
+
+		values, err := l.fetch(ctx, b.keys)
+		for i, key := range b.keys {
+			b.results[i].value, b.results[i].err = values[key], err
+			close(b.results[i].done)
+		}
+	})
+}
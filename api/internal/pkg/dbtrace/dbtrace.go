@@ -0,0 +1,187 @@
+// Package dbtrace times SQL statements run through pgx. Every statement is
+// recorded in the db_query_duration_seconds histogram under a short query_id;
+// statements slower than the threshold are logged at WARN with their
+// normalized SQL, and all of them at DEBUG.
+package dbtrace
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"bailanysta/api/internal/pkg/logger"
+	"bailanysta/api/internal/pkg/metrics"
+)
+
+var queryDuration = metrics.NewHistogramVec("db_query_duration_seconds",
+	"SQL statement latency by normalized query.", metrics.DefBuckets, "query_id")
+
+// Tracer implements pgx.QueryTracer and pgx.BatchTracer
+type Tracer struct {
+	logger    *logger.Logger
+	threshold time.Duration
+
+	// normalized SQL and query_id by raw SQL; statements are mostly constants
+	queries sync.Map
+}
+
+type normalizedQuery struct {
+	sql string
+	id  string
+}
+
+type traceKey struct{}
+
+type trace struct {
+	sql   string
+	args  int
+	start time.Time
+}
+
+// New creates a tracer that logs statements taking at least threshold; zero
+// turns slow-query logging off but keeps the histogram
+func New(logger *logger.Logger, threshold time.Duration) *Tracer {
+	return &Tracer{logger: logger, threshold: threshold}
+}
+
+func (t *Tracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, traceKey{}, &trace{sql: data.SQL, args: len(data.Args), start: time.Now()})
+}
+
+func (t *Tracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	tr, ok := ctx.Value(traceKey{}).(*trace)
+	if !ok {
+		return
+	}
+	t.record(tr.sql, tr.args, data.CommandTag.RowsAffected(), data.Err, time.Since(tr.start))
+}
+
+func (t *Tracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceBatchStartData) context.Context {
+	return context.WithValue(ctx, traceKey{}, &trace{start: time.Now()})
+}
+
+// TraceBatchQuery is called as each batched statement's results are read,
+// so a statement is timed from the previous one's end
+func (t *Tracer) TraceBatchQuery(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchQueryData) {
+	tr, ok := ctx.Value(traceKey{}).(*trace)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	t.record(data.SQL, len(data.Args), data.CommandTag.RowsAffected(), data.Err, now.Sub(tr.start))
+	tr.start = now
+}
+
+func (t *Tracer) TraceBatchEnd(context.Context, *pgx.Conn, pgx.TraceBatchEndData) {}
+
+func (t *Tracer) record(rawSQL string, args int, rows int64, err error, elapsed time.Duration) {
+	query := t.normalized(rawSQL)
+	queryDuration.Observe(elapsed.Seconds(), query.id)
+
+	fields := map[string]interface{}{
+		"query_id":    query.id,
+		"sql":         query.sql,
+		"args":        args,
+		"rows":        rows,
+		"duration_ms": float64(elapsed.Microseconds()) / 1000,
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+	}
+
+	if t.threshold > 0 && elapsed >= t.threshold {
+		t.logger.Warn("Slow query", fields)
+		return
+	}
+	t.logger.Debug("Query", fields)
+}
+
+func (t *Tracer) normalized(rawSQL string) normalizedQuery {
+	if cached, ok := t.queries.Load(rawSQL); ok {
+		return cached.(normalizedQuery)
+	}
+
+	sql := Normalize(rawSQL)
+	hash := fnv.New32a()
+	hash.Write([]byte(sql))
+	query := normalizedQuery{sql: sql, id: fmt.Sprintf("%08x", hash.Sum32())}
+	t.queries.Store(rawSQL, query)
+	return query
+}
+
+// Normalize collapses whitespace and replaces string and number literals
+// with ? so statements that differ only in inlined values look the same.
+// Placeholders like $1 are kept.
+func Normalize(sql string) string {
+	var b strings.Builder
+	b.Grow(len(sql))
+
+	space := false
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+			continue
+
+		case c == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			// Line comment
+			for i < len(sql) && sql[i] != '\n' {
+				i++
+			}
+			space = true
+			continue
+		}
+
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+
+		switch {
+		case c == '\'':
+			// String literal; '' is an escaped quote
+			for i++; i < len(sql); i++ {
+				if sql[i] == '\'' {
+					if i+1 < len(sql) && sql[i+1] == '\'' {
+						i++
+						continue
+					}
+					break
+				}
+			}
+			b.WriteByte('?')
+
+		case isDigit(c) && (i == 0 || !isIdentByte(sql[i-1])):
+			for i+1 < len(sql) && (isDigit(sql[i+1]) || sql[i+1] == '.') {
+				i++
+			}
+			b.WriteByte('?')
+
+		case c == '$':
+			// Placeholder: keep it whole so $10 doesn't become $?
+			b.WriteByte(c)
+			for i+1 < len(sql) && isDigit(sql[i+1]) {
+				i++
+				b.WriteByte(sql[i])
+			}
+
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || isDigit(c) || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
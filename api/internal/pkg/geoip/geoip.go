@@ -0,0 +1,340 @@
+// Package geoip looks up the approximate location or the network (ASN) of
+// an IP address in a local MaxMind DB file (GeoLite2 or GeoIP2 City/Country,
+// or GeoLite2 ASN). Only what the lookups need of the MMDB format is
+// implemented.
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+)
+
+var metadataMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+// dataSeparator is the gap between the search tree and the data section
+const dataSeparator = 16
+
+var errInvalid = errors.New("invalid MaxMind DB")
+
+// Location is where an IP address is, as far as the database knows
+type Location struct {
+	CountryCode string `json:"country_code,omitempty"`
+	Country     string `json:"country,omitempty"`
+	City        string `json:"city,omitempty"`
+}
+
+// DB is an MMDB file loaded into memory. It is safe for concurrent use.
+type DB struct {
+	buffer     []byte
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	treeSize   uint
+	// ipv4Start is the node IPv4 lookups start from in an IPv6 tree
+	ipv4Start uint
+}
+
+// Open loads the database at path
+func Open(path string) (*DB, error) {
+	buffer, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	start := bytes.LastIndex(buffer, metadataMarker)
+	if start < 0 {
+		return nil, fmt.Errorf("%w: no metadata", errInvalid)
+	}
+	metadataStart := uint(start + len(metadataMarker))
+	metadata := decoder{buffer: buffer[metadataStart:]}
+	value, _, err := metadata.decode(0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalid, err)
+	}
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: metadata is not a map", errInvalid)
+	}
+
+	db := &DB{
+		buffer:     buffer,
+		nodeCount:  uintField(fields, "node_count"),
+		recordSize: uintField(fields, "record_size"),
+		ipVersion:  uintField(fields, "ip_version"),
+	}
+	if db.recordSize != 24 && db.recordSize != 28 && db.recordSize != 32 {
+		return nil, fmt.Errorf("%w: record size %d", errInvalid, db.recordSize)
+	}
+	db.treeSize = db.nodeCount * db.recordSize / 4
+	if db.treeSize+dataSeparator > metadataStart {
+		return nil, fmt.Errorf("%w: search tree is too large", errInvalid)
+	}
+
+	if db.ipVersion == 6 {
+		node := uint(0)
+		for i := 0; i < 96 && node < db.nodeCount; i++ {
+			node = db.record(node, 0)
+		}
+		db.ipv4Start = node
+	}
+	return db, nil
+}
+
+// Lookup returns the location of ip; nil if the database doesn't have it
+func (db *DB) Lookup(ip net.IP) (*Location, error) {
+	record, err := db.find(ip)
+	if err != nil || record == nil {
+		return nil, err
+	}
+
+	fields, ok := record.(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+	location := &Location{
+		CountryCode: stringPath(fields, "country", "iso_code"),
+		Country:     stringPath(fields, "country", "names", "en"),
+		City:        stringPath(fields, "city", "names", "en"),
+	}
+	if *location == (Location{}) {
+		return nil, nil
+	}
+	return location, nil
+}
+
+// ASN returns the autonomous system number of ip from a GeoLite2 ASN
+// database; 0 if the database doesn't have it
+func (db *DB) ASN(ip net.IP) (uint, error) {
+	record, err := db.find(ip)
+	if err != nil || record == nil {
+		return 0, err
+	}
+	fields, ok := record.(map[string]interface{})
+	if !ok {
+		return 0, nil
+	}
+	return uintField(fields, "autonomous_system_number"), nil
+}
+
+// find walks the search tree for ip and decodes its record
+func (db *DB) find(ip net.IP) (interface{}, error) {
+	node := uint(0)
+	bits := ip.To16()
+	if ipv4 := ip.To4(); ipv4 != nil {
+		bits = ipv4
+		if db.ipVersion == 6 {
+			node = db.ipv4Start
+		}
+	} else if bits == nil || db.ipVersion == 4 {
+		return nil, nil
+	}
+
+	for i := 0; i < len(bits)*8 && node < db.nodeCount; i++ {
+		bit := uint(bits[i/8]>>(7-uint(i%8))) & 1
+		node = db.record(node, bit)
+	}
+	if node == db.nodeCount {
+		return nil, nil // not found
+	}
+	if node < db.nodeCount {
+		return nil, fmt.Errorf("%w: search tree ended early", errInvalid)
+	}
+
+	data := decoder{buffer: db.buffer[db.treeSize+dataSeparator:]}
+	value, _, err := data.decode(node - db.nodeCount - dataSeparator)
+	return value, err
+}
+
+// record reads the left (bit 0) or right (bit 1) record of node
+func (db *DB) record(node, bit uint) uint {
+	offset := node * db.recordSize / 4
+	b := db.buffer[offset:]
+	switch db.recordSize {
+	case 24:
+		b = b[bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		if bit == 0 {
+			return uint(b[3]&0xf0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0f)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		return uint(binary.BigEndian.Uint32(b[bit*4:]))
+	}
+}
+
+// Data section types
+const (
+	typeExtended = 0
+	typePointer  = 1
+	typeString   = 2
+	typeDouble   = 3
+	typeBytes    = 4
+	typeUint16   = 5
+	typeUint32   = 6
+	typeMap      = 7
+	typeInt32    = 8
+	typeUint64   = 9
+	typeUint128  = 10
+	typeArray    = 11
+	typeBool     = 14
+	typeFloat    = 15
+)
+
+type decoder struct {
+	buffer []byte
+}
+
+// decode reads the value at offset, returning it and the offset after it
+func (d decoder) decode(offset uint) (interface{}, uint, error) {
+	if offset >= uint(len(d.buffer)) {
+		return nil, 0, errors.New("offset out of range")
+	}
+	control := d.buffer[offset]
+	offset++
+
+	kind := uint(control >> 5)
+	if kind == typePointer {
+		pointer, next, err := d.pointer(control, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		value, _, err := d.decode(pointer)
+		return value, next, err
+	}
+	if kind == typeExtended {
+		if offset >= uint(len(d.buffer)) {
+			return nil, 0, errors.New("offset out of range")
+		}
+		kind = 7 + uint(d.buffer[offset])
+		offset++
+	}
+
+	size := uint(control & 0x1f)
+	if size >= 29 {
+		extra := size - 28
+		if offset+extra > uint(len(d.buffer)) {
+			return nil, 0, errors.New("offset out of range")
+		}
+		n := uint(0)
+		for _, b := range d.buffer[offset : offset+extra] {
+			n = n<<8 | uint(b)
+		}
+		size = []uint{29, 285, 65821}[extra-1] + n
+		offset += extra
+	}
+
+	switch kind {
+	case typeMap:
+		fields := make(map[string]interface{}, size)
+		for i := uint(0); i < size; i++ {
+			key, next, err := d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				return nil, 0, errors.New("map key is not a string")
+			}
+			value, next, err := d.decode(next)
+			if err != nil {
+				return nil, 0, err
+			}
+			fields[name] = value
+			offset = next
+		}
+		return fields, offset, nil
+	case typeArray:
+		values := make([]interface{}, 0, size)
+		for i := uint(0); i < size; i++ {
+			value, next, err := d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			values = append(values, value)
+			offset = next
+		}
+		return values, offset, nil
+	case typeBool:
+		return size != 0, offset, nil
+	}
+
+	if offset+size > uint(len(d.buffer)) {
+		return nil, 0, errors.New("offset out of range")
+	}
+	raw := d.buffer[offset : offset+size]
+	next := offset + size
+	switch kind {
+	case typeString:
+		return string(raw), next, nil
+	case typeBytes, typeUint128:
+		return append([]byte(nil), raw...), next, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, errors.New("invalid double")
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(raw)), next, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, errors.New("invalid float")
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(raw))), next, nil
+	case typeUint16, typeUint32, typeUint64, typeInt32:
+		n := uint64(0)
+		for _, b := range raw {
+			n = n<<8 | uint64(b)
+		}
+		if kind == typeInt32 {
+			return int64(int32(uint32(n))), next, nil
+		}
+		return n, next, nil
+	}
+	return nil, 0, fmt.Errorf("unsupported data type %d", kind)
+}
+
+// pointer reads a pointer whose control byte is control, returning the
+// offset it points to and the offset after it
+func (d decoder) pointer(control byte, offset uint) (uint, uint, error) {
+	size := uint(control>>3)&0x3 + 1
+	if offset+size > uint(len(d.buffer)) {
+		return 0, 0, errors.New("offset out of range")
+	}
+	b := d.buffer[offset : offset+size]
+
+	var pointer uint
+	switch size {
+	case 1:
+		pointer = uint(control&0x7)<<8 | uint(b[0])
+	case 2:
+		pointer = (uint(control&0x7)<<16 | uint(b[0])<<8 | uint(b[1])) + 2048
+	case 3:
+		pointer = (uint(control&0x7)<<24 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])) + 526336
+	case 4:
+		pointer = uint(binary.BigEndian.Uint32(b))
+	}
+	return pointer, offset + size, nil
+}
+
+func uintField(fields map[string]interface{}, name string) uint {
+	n, _ := fields[name].(uint64)
+	return uint(n)
+}
+
+// stringPath follows keys through nested maps to a string
+func stringPath(fields map[string]interface{}, keys ...string) string {
+	var value interface{} = fields
+	for _, key := range keys {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		value = m[key]
+	}
+	s, _ := value.(string)
+	return s
+}
@@ -0,0 +1,241 @@
+// Package gifs searches Tenor or Giphy for GIFs. The API key stays on the
+// server; clients search through the API and attach results by ID.
+package gifs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Providers
+const (
+	ProviderTenor = "tenor"
+	ProviderGiphy = "giphy"
+)
+
+const (
+	tenorURL = "https://tenor.googleapis.com/v2"
+	giphyURL = "https://api.giphy.com/v1/gifs"
+	// maxBodyBytes bounds a provider response
+	maxBodyBytes = 2 << 20
+)
+
+// ErrNotFound is returned by Get for IDs the provider doesn't know
+var ErrNotFound = errors.New("gif not found")
+
+// GIF is one result. URL is the full animation; PreviewURL a small
+// rendition for pickers and feeds.
+type GIF struct {
+	ID         string
+	Title      string
+	URL        string
+	PreviewURL string
+	Width      int
+	Height     int
+}
+
+type Client struct {
+	provider string
+	apiKey   string
+	client   *http.Client
+}
+
+// NewClient returns a client for provider (tenor or giphy)
+func NewClient(provider, apiKey string, timeout time.Duration) (*Client, error) {
+	if provider != ProviderTenor && provider != ProviderGiphy {
+		return nil, fmt.Errorf("unknown GIF provider %q", provider)
+	}
+	return &Client{
+		provider: provider,
+		apiKey:   apiKey,
+		client:   &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Provider returns the provider's name
+func (c *Client) Provider() string {
+	return c.provider
+}
+
+// Search returns up to limit GIFs for query, or trending GIFs for an empty
+// query. Results are filtered for a general audience.
+func (c *Client) Search(ctx context.Context, query string, limit int) ([]GIF, error) {
+	params := url.Values{}
+	params.Set("limit", strconv.Itoa(limit))
+
+	if c.provider == ProviderTenor {
+		endpoint := "/featured"
+		if query != "" {
+			endpoint = "/search"
+			params.Set("q", query)
+		}
+		var response tenorResponse
+		if err := c.getTenor(ctx, endpoint, params, &response); err != nil {
+			return nil, err
+		}
+		return response.gifs(), nil
+	}
+
+	endpoint := "/trending"
+	if query != "" {
+		endpoint = "/search"
+		params.Set("q", query)
+	}
+	var response giphyListResponse
+	if err := c.getGiphy(ctx, endpoint, params, &response); err != nil {
+		return nil, err
+	}
+	gifs := make([]GIF, 0, len(response.Data))
+	for _, item := range response.Data {
+		if gif := item.gif(); gif.URL != "" {
+			gifs = append(gifs, gif)
+		}
+	}
+	return gifs, nil
+}
+
+// Get looks one GIF up by its provider ID
+func (c *Client) Get(ctx context.Context, id string) (*GIF, error) {
+	if c.provider == ProviderTenor {
+		var response tenorResponse
+		if err := c.getTenor(ctx, "/posts", url.Values{"ids": {id}}, &response); err != nil {
+			return nil, err
+		}
+		gifs := response.gifs()
+		if len(gifs) == 0 {
+			return nil, ErrNotFound
+		}
+		return &gifs[0], nil
+	}
+
+	var response giphyItemResponse
+	if err := c.getGiphy(ctx, "/"+url.PathEscape(id), url.Values{}, &response); err != nil {
+		return nil, err
+	}
+	if response.Data.ID == "" {
+		return nil, ErrNotFound
+	}
+	gif := response.Data.gif()
+	return &gif, nil
+}
+
+func (c *Client) getTenor(ctx context.Context, endpoint string, params url.Values, out interface{}) error {
+	params.Set("key", c.apiKey)
+	params.Set("client_key", "bailanysta")
+	params.Set("media_filter", "gif,tinygif")
+	params.Set("contentfilter", "medium")
+	return c.get(ctx, tenorURL+endpoint+"?"+params.Encode(), out)
+}
+
+func (c *Client) getGiphy(ctx context.Context, endpoint string, params url.Values, out interface{}) error {
+	params.Set("api_key", c.apiKey)
+	params.Set("rating", "pg-13")
+	return c.get(ctx, giphyURL+endpoint+"?"+params.Encode(), out)
+}
+
+func (c *Client) get(ctx context.Context, rawURL string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("GIF provider request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GIF provider returned status %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxBodyBytes)).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode GIF provider response: %w", err)
+	}
+	return nil
+}
+
+type tenorMedia struct {
+	URL  string `json:"url"`
+	Dims []int  `json:"dims"`
+}
+
+type tenorResponse struct {
+	Results []struct {
+		ID                 string                `json:"id"`
+		Title              string                `json:"title"`
+		ContentDescription string                `json:"content_description"`
+		MediaFormats       map[string]tenorMedia `json:"media_formats"`
+	} `json:"results"`
+}
+
+// gifs keeps the results that have a GIF rendition
+func (r tenorResponse) gifs() []GIF {
+	gifs := make([]GIF, 0, len(r.Results))
+	for _, result := range r.Results {
+		full, ok := result.MediaFormats["gif"]
+		preview, hasPreview := result.MediaFormats["tinygif"]
+		if !ok || full.URL == "" {
+			continue
+		}
+		if !hasPreview || preview.URL == "" {
+			preview = full
+		}
+		gif := GIF{ID: result.ID, Title: result.Title, URL: full.URL, PreviewURL: preview.URL}
+		if gif.Title == "" {
+			gif.Title = result.ContentDescription
+		}
+		if len(full.Dims) == 2 {
+			gif.Width, gif.Height = full.Dims[0], full.Dims[1]
+		}
+		gifs = append(gifs, gif)
+	}
+	return gifs
+}
+
+// Giphy sends dimensions as strings
+type giphyImage struct {
+	URL    string `json:"url"`
+	Width  string `json:"width"`
+	Height string `json:"height"`
+}
+
+type giphyItem struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Images struct {
+		Original        giphyImage `json:"original"`
+		FixedWidthSmall giphyImage `json:"fixed_width_small"`
+	} `json:"images"`
+}
+
+func (item giphyItem) gif() GIF {
+	gif := GIF{
+		ID:         item.ID,
+		Title:      item.Title,
+		URL:        item.Images.Original.URL,
+		PreviewURL: item.Images.FixedWidthSmall.URL,
+	}
+	if gif.PreviewURL == "" {
+		gif.PreviewURL = gif.URL
+	}
+	gif.Width, _ = strconv.Atoi(item.Images.Original.Width)
+	gif.Height, _ = strconv.Atoi(item.Images.Original.Height)
+	return gif
+}
+
+type giphyListResponse struct {
+	Data []giphyItem `json:"data"`
+}
+
+type giphyItemResponse struct {
+	Data giphyItem `json:"data"`
+}
@@ -0,0 +1,250 @@
+package i18n
+
+var kazakh = map[string]string{
+	// Generic
+	"Bad Request":           "Қате сұраныс",
+	"Unauthorized":          "Авторизация қажет",
+	"Forbidden":             "Тыйым салынған",
+	"Not Found":             "Табылмады",
+	"Conflict":              "Қайшылық",
+	"Internal Server Error": "Сервердің ішкі қатесі",
+	"Internal server error": "Сервердің ішкі қатесі",
+	"Not implemented yet":   "Әлі іске асырылмаған",
+	"Rate limit exceeded":   "Сұраныстар шегінен асып кетті",
+	"AI quota exceeded":     "ЖИ-дің күндік квотасы таусылды",
+	"Access denied":         "Қол жеткізуге тыйым салынған",
+	"Permission denied":     "Рұқсат жеткіліксіз",
+	"Invalid request body":  "Сұраныс денесі қате",
+	"Validation failed":     "Тексеруден өтпеді",
+
+	// Auth and organizations
+	"Authorization header required":                               "Authorization тақырыбы қажет",
+	"Invalid authorization header format":                         "Authorization тақырыбының пішімі қате",
+	"Invalid token":                                               "Токен жарамсыз",
+	"Invalid JWT token":                                           "Токен жарамсыз",
+	"Token is required":                                           "Токен қажет",
+	"Invalid email or password":                                   "Email немесе құпиясөз қате",
+	"Organization not found":                                      "Ұйым табылмады",
+	"Organization access denied":                                  "Ұйымға қол жеткізу жоқ",
+	"Organization already exists":                                 "Ұйым бұрыннан бар",
+	"Slug may only contain lowercase letters, digits and hyphens": "Slug тек кіші әріптерден, сандардан және дефистерден тұра алады",
+	"You cannot change your own role":                             "Өз рөліңізді өзгерте алмайсыз",
+	"A superadmin's role can't be changed here":                   "Суперәкімшінің рөлін мұнда өзгертуге болмайды",
+	"role must be one of student, teacher, moderator, admin":      "role мәні student, teacher, moderator, admin мәндерінің бірі болуы керек",
+	"Invalid log levels":                                          "Журнал деңгейлері қате",
+
+	// Users
+	"User not found":                                  "Пайдаланушы табылмады",
+	"Invalid user ID":                                 "Пайдаланушы ID-і қате",
+	"Username is already taken":                       "Бұл пайдаланушы аты бос емес",
+	"Username is unchanged":                           "Пайдаланушы аты өзгермеді",
+	"Username can only be changed once every 30 days": "Пайдаланушы атын 30 күнде бір рет қана өзгертуге болады",
+	"Username must be 3-50 letters, digits, '_', '.' or '-' and start with a letter, digit or '_'": "Пайдаланушы аты 3–50 әріптен, саннан, '_', '.' немесе '-' таңбаларынан тұрып, әріппен, санмен немесе '_' таңбасымен басталуы керек",
+	"Follow request not found":                               "Жазылу сұрауы табылмады",
+	"is_private or show_presence is required":                "is_private немесе show_presence қажет",
+	"This account is private":                                "Бұл жабық аккаунт",
+	"ai_content must be show, downrank or hide":              "ai_content мәні show, downrank немесе hide болуы керек",
+	"timezone must be an IANA time zone such as Asia/Almaty": "timezone мәні IANA уақыт белдеуі болуы керек, мысалы Asia/Almaty",
+	"ai_content or languages is required":                    "ai_content немесе languages қажет",
+	"languages may only contain en, kk and ru":               "languages тек en, kk және ru мәндерінен тұра алады",
+	"Failed to get limits":                                   "Шектеулерді алу мүмкін болмады",
+	"Invalid API key":                                        "API кілті жарамсыз",
+	"API key is read-only":                                   "API кілті тек оқуға арналған",
+	"Token is read-only":                                     "Токен тек оқуға арналған",
+	"Invalid refresh token":                                  "Жаңарту токені жарамсыз",
+	"Failed to refresh token":                                "Токенді жаңарту мүмкін болмады",
+	"Failed to start session":                                "Сеансты бастау мүмкін болмады",
+	"Failed to log out":                                      "Шығу мүмкін болмады",
+	"Invalid CSRF token":                                     "CSRF токені жарамсыз",
+	"Bailanysta is down for maintenance":                     "Bailanysta техникалық жұмыстарға байланысты уақытша қолжетімсіз",
+	"retry_after_seconds must be positive":                   "retry_after_seconds оң сан болуы керек",
+	"Invalid hashtag":                                        "Хэштег қате",
+	"Failed to get suggestions":                              "Ұсыныстарды алу мүмкін болмады",
+	"Failed to get feed updates":                             "Таспадағы жаңа жазбаларды алу мүмкін болмады",
+	"Invalid Last-Event-ID":                                  "Last-Event-ID қате",
+	"API keys cannot manage API keys":                        "API кілттерін API кілтімен басқаруға болмайды",
+	"API keys cannot change account recovery":                "Қолжетімділікті қалпына келтіру баптауларын API кілтімен өзгертуге болмайды",
+	"Not allowed while impersonating":                        "Имперсонация режимінде қолжетімсіз",
+	"API keys cannot start impersonation":                    "API кілтімен имперсонацияны бастауға болмайды",
+	"Cannot impersonate yourself":                            "Өз атыңыздан кіру мүмкін емес",
+	"Cannot impersonate an admin":                            "Әкімші атынан кіру мүмкін емес",
+	"Failed to start impersonation":                          "Имперсонацияны бастау мүмкін болмады",
+	"Failed to get impersonation sessions":                   "Имперсонация сеанстарын алу мүмкін болмады",
+	"Failed to get API keys":                                 "API кілттерін алу мүмкін болмады",
+	"scope must be read or write":                            "scope мәні read немесе write болуы керек",
+	"Too many API keys; revoke one first":                    "API кілттері тым көп; алдымен біреуін қайтарып алыңыз",
+	"Failed to create API key":                               "API кілтін жасау мүмкін болмады",
+	"Invalid API key ID":                                     "API кілтінің ID-і жарамсыз",
+	"API key not found":                                      "API кілті табылмады",
+	"Failed to revoke API key":                               "API кілтін қайтарып алу мүмкін болмады",
+	"API key revoked":                                        "API кілті қайтарып алынды",
+
+	// Posts and comments
+	"Post not found":                                                "Жазба табылмады",
+	"Invalid post ID":                                               "Жазба ID-і қате",
+	"Comment not found":                                             "Пікір табылмады",
+	"Invalid comment ID":                                            "Пікір ID-і қате",
+	"Post was rejected by the content filter":                       "Жазбаны мазмұн сүзгісі қабылдамады",
+	"Comment was rejected by the content filter":                    "Пікірді мазмұн сүзгісі қабылдамады",
+	"AI response blocked by the safety filter":                      "ЖИ жауабын қауіпсіздік сүзгісі бұғаттады",
+	"Post has no text to explain":                                   "Жазбада түсіндіретін мәтін жоқ",
+	"Exactly one of topic, post_id or text is required":             "topic, post_id немесе text өрістерінің біреуі ғана көрсетілуі керек",
+	"Post has no text to make flashcards from":                      "Жазбада карточкаларға арналған мәтін жоқ",
+	"AI returned no flashcards":                                     "ЖИ карточкаларды қайтармады",
+	"Invalid flashcard ID":                                          "Карточка ID-і қате",
+	"Flashcard not found":                                           "Карточка табылмады",
+	"Invalid module ID":                                             "Модуль ID-і қате",
+	"Module not found":                                              "Модуль табылмады",
+	"Module not completed":                                          "Модуль аяқталған деп белгіленбеген",
+	"Invalid assignment ID":                                         "Тапсырма ID-і қате",
+	"Assignment not found":                                          "Тапсырма табылмады",
+	"Text or attachments are required":                              "Мәтін немесе тіркемелер қажет",
+	"Attachments must be up to 5 http(s) links":                     "Тіркемелер — 5-тен аспайтын http(s) сілтеме",
+	"Submission already graded":                                     "Жұмыс бағаланып қойған",
+	"Submission not found":                                          "Тапсырылған жұмыс табылмады",
+	"Invalid submission ID":                                         "Тапсырылған жұмыс ID-і қате",
+	"Grade is required":                                             "Баға қажет",
+	"Invalid status":                                                "Мәртебе қате",
+	"Submission has no text to assess":                              "Жұмыста бағалайтын мәтін жоқ",
+	"AI returned no feedback":                                       "ЖИ пікір қайтармады",
+	"Failed to save feedback":                                       "Пікірді сақтау мүмкін болмады",
+	"Certificate not found":                                         "Сертификат табылмады",
+	"Only questions can have a bounty":                              "Сыйақыны тек сұраққа тағайындауға болады",
+	"Not enough reputation for this bounty":                         "Мұндай сыйақыға беделіңіз жеткіліксіз",
+	"Only questions have accepted answers":                          "Қабылданған жауап тек сұрақтарда болады",
+	"Only the author of the question can accept an answer":          "Жауапты тек сұрақ авторы қабылдай алады",
+	"Failed to update accepted answer":                              "Қабылданған жауапты жаңарту мүмкін болмады",
+	"Invalid type parameter, expected all, questions or unanswered": "type параметрі қате, all, questions немесе unanswered күтіледі",
+	"Invalid type, expected post or question":                       "type қате, post немесе question күтіледі",
+	"unanswered only applies to questions":                          "unanswered тек сұрақтарға қолданылады",
+	"Failed to get reputation":                                      "Беделді алу мүмкін болмады",
+	"GIF search is not enabled":                                     "GIF іздеу қосылмаған",
+	"Query must be at most 100 characters":                          "Сұрау 100 таңбадан аспауы керек",
+	"Failed to search GIFs":                                         "GIF іздеу мүмкін болмады",
+	"Unknown gif_id":                                                "Белгісіз gif_id",
+	"days is out of range":                                          "days параметрі рұқсат етілген ауқымнан тыс",
+	"enabled or share_identity is required":                         "enabled немесе share_identity қажет",
+	"Failed to get profile views":                                   "Профиль қаралымдарын алу мүмкін болмады",
+	"Failed to update profile view settings":                        "Профиль қаралымдарының баптауларын жаңарту мүмкін болмады",
+	"Invite is invalid, used or expired":                            "Шақыру жарамсыз, пайдаланылған немесе мерзімі өткен",
+	"A valid email is required":                                     "Дұрыс email қажет",
+	"Failed to join waitlist":                                       "Күту тізіміне жазылу мүмкін болмады",
+	"Registration failed":                                           "Тіркелу сәтсіз аяқталды",
+	"Registration is open; there is no waitlist":                    "Тіркелу ашық, күту тізімі жоқ",
+	"status must be one of pending, approved, registered":           "status мәні pending, approved немесе registered болуы керек",
+	"Failed to get waitlist":                                        "Күту тізімін алу мүмкін болмады",
+	"Either ids or count is required":                               "ids немесе count қажет",
+	"Failed to approve waitlist":                                    "Күту тізіміндегі өтінімдерді мақұлдау мүмкін болмады",
+	"Bot check failed; solve the challenge and retry":               "Роботқа тексеру өтпеді; тапсырманы шешіп, қайталаңыз",
+	"Bot check is unavailable; try again later":                     "Роботқа тексеру қолжетімсіз; кейінірек қайталаңыз",
+	"Failed to create challenge":                                    "Тексеру тапсырмасын жасау мүмкін болмады",
+	"Invalid session ID":                                            "Сессия ID қате",
+	"Session not found":                                             "Сессия табылмады",
+	"Failed to get sessions":                                        "Сессияларды алу мүмкін болмады",
+	"Failed to revoke session":                                      "Сессияны аяқтау мүмкін болмады",
+	"Failed to get recovery status":                                 "Қалпына келтіру баптауларын алу мүмкін болмады",
+	"Email is not configured":                                       "Пошта жіберу бапталмаған",
+	"Backup email must differ from account email":                   "Қосалқы email аккаунт email-інен өзгеше болуы керек",
+	"Failed to set backup email":                                    "Қосалқы email орнату мүмкін болмады",
+	"No backup email is set":                                        "Қосалқы email орнатылмаған",
+	"Failed to remove backup email":                                 "Қосалқы email жою мүмкін болмады",
+	"Invalid or expired link":                                       "Сілтеме жарамсыз немесе мерзімі өткен",
+	"Failed to verify backup email":                                 "Қосалқы email растау мүмкін болмады",
+	"Invalid password":                                              "Құпиясөз қате",
+	"Failed to generate recovery codes":                             "Қалпына келтіру кодтарын жасау мүмкін болмады",
+	"Failed to start account recovery":                              "Аккаунтты қалпына келтіруді бастау мүмкін болмады",
+	"Either token, or email and code, is required":                  "token немесе email мен code қажет",
+	"Invalid or used recovery code or link":                         "Қалпына келтіру коды немесе сілтемесі жарамсыз не пайдаланылған",
+	"Failed to recover account":                                     "Аккаунтты қалпына келтіру мүмкін болмады",
+	"password is required":                                          "password қажет",
+	"mode must be delete or anonymize":                              "mode delete немесе anonymize болуы керек",
+	"Failed to delete account":                                      "Аккаунтты жою мүмкін болмады",
+	"Too many likes or follows; try again later":                    "Лайктар не жазылымдар тым көп; кейінірек қайталаңыз",
+	"Failed to get abuse flags":                                     "Теріс пайдалану белгілерін алу мүмкін болмады",
+	"Invalid abuse flag ID":                                         "Теріс пайдалану белгісінің ID қате",
+	"Abuse flag not found":                                          "Теріс пайдалану белгісі табылмады",
+	"Failed to resolve abuse flag":                                  "Теріс пайдалану белгісін жабу мүмкін болмады",
+	"Failed to get IP blocks":                                       "IP бұғаттауларын алу мүмкін болмады",
+	"Either cidr or asn is required":                                "cidr немесе asn қажет",
+	"cidr must be an IP address or CIDR range":                      "cidr IP мекенжайы немесе CIDR ауқымы болуы керек",
+	"ASN blocks need GEOIP_ASN_DB_PATH":                             "ASN бойынша бұғаттау үшін GEOIP_ASN_DB_PATH қажет",
+	"Failed to create IP block":                                     "IP бұғаттауын жасау мүмкін болмады",
+	"Invalid IP block ID":                                           "IP бұғаттауының ID қате",
+	"IP block not found":                                            "IP бұғаттауы табылмады",
+	"Failed to delete IP block":                                     "IP бұғаттауын жою мүмкін болмады",
+	"Failed to get duplicate posts":                                 "Посттардың көшірмелерін алу мүмкін болмады",
+	"Duplicate post not found":                                      "Посттың көшірмесі табылмады",
+	"Original post not found":                                       "Бастапқы пост табылмады",
+	"Failed to resolve duplicate post":                              "Посттың көшірмесін өңдеу мүмкін болмады",
+	"Post is longer than the character limit":                       "Пост рұқсат етілген таңба санынан ұзын",
+	"Post is over the word limit":                                   "Постта рұқсат етілгеннен көп сөз бар",
+	"Failed to get capabilities":                                    "Шектеулерді алу мүмкін болмады",
+	"Failed to get post limits":                                     "Пост шектеулерін алу мүмкін болмады",
+	"Failed to set post limit":                                      "Пост шектеуін сақтау мүмкін болмады",
+	"Invalid post limit ID":                                         "Пост шектеуінің ID қате",
+	"Post limit not found":                                          "Пост шектеуі табылмады",
+	"Failed to delete post limit":                                   "Пост шектеуін жою мүмкін болмады",
+	"Failed to get post metadata":                                   "Жазбаның метадеректерін алу мүмкін болмады",
+	"Access from your network is blocked":                           "Сіздің желіңізден кіруге тыйым салынған",
+	"Query must be at least 3 characters unless author_id, course_id, hashtag or unanswered is given": "author_id, course_id, hashtag немесе unanswered берілмесе, сұрау кемінде 3 таңбадан тұруы керек",
+	"Only comments on course posts can be verified answers":                                           "Расталған жауап тек курс жазбаларындағы пікір бола алады",
+	"Unknown course_id":                                          "Белгісіз course_id",
+	"Unknown or already used ai_generation_id":                   "Белгісіз немесе бұрын қолданылған ai_generation_id",
+	"sort must be oldest, newest or top":                         "sort мәні oldest, newest немесе top болуы керек",
+	"Invalid sort parameter, expected latest or ranked":          "sort параметрі қате, latest немесе ranked күтіледі",
+	"Poll not found":                                             "Сауалнама табылмады",
+	"Poll is closed":                                             "Сауалнама жабылған",
+	"Invalid poll option":                                        "Сауалнама нұсқасы қате",
+	"Already voted in this poll":                                 "Сіз бұл сауалнамада дауыс беріп қойдыңыз",
+	"Poll expiry must be in the future and at most 30 days away": "Сауалнаманың аяқталу уақыты болашақта және 30 күннен алыс емес болуы керек",
+
+	// Courses, groups and events
+	"Course not found":                                  "Курс табылмады",
+	"Invalid course ID":                                 "Курс ID-і қате",
+	"Group not found":                                   "Топ табылмады",
+	"Invalid group ID":                                  "Топ ID-і қате",
+	"Member not found":                                  "Мүше табылмады",
+	"Join request not found":                            "Қосылу өтінімі табылмады",
+	"You are not a member of this group":                "Сіз бұл топтың мүшесі емессіз",
+	"Only group admins can do this":                     "Мұны тек топ әкімшілері жасай алады",
+	"Only group members can post to a group":            "Топқа тек оның мүшелері жаза алады",
+	"This group is invite-only":                         "Бұл топқа тек шақыру арқылы қосылуға болады",
+	"The group owner cannot leave the group":            "Топ иесі топтан шыға алмайды",
+	"User is already invited or a member":               "Пайдаланушы шақырылған немесе топ мүшесі",
+	"role must be admin or member":                      "role мәні admin немесе member болуы керек",
+	"status must be active, requested or invited":       "status мәні active, requested немесе invited болуы керек",
+	"Event not found":                                   "Іс-шара табылмады",
+	"Invalid event ID":                                  "Іс-шара ID-і қате",
+	"Event has ended":                                   "Іс-шара аяқталды",
+	"You cannot create events here":                     "Мұнда іс-шара құра алмайсыз",
+	"You have not responded to this event":              "Сіз бұл іс-шараға жауап бермедіңіз",
+	"Exactly one of course_id and group_id is required": "course_id және group_id мәндерінің дәл біреуі қажет",
+	"status must be going, maybe or declined":           "status мәні going, maybe немесе declined болуы керек",
+	"scope must be org or course:{id}":                  "scope мәні org немесе course:{id} болуы керек",
+	"period must be day, week or all":                   "period мәні day, week немесе all болуы керек",
+
+	// Notifications
+	"Notification not found":                     "Хабарландыру табылмады",
+	"Invalid notification ID":                    "Хабарландыру ID-і қате",
+	"The notification's target no longer exists": "Хабарландыру нысаны енді жоқ",
+	"Push notifications are not enabled":         "Push-хабарландырулар қосылмаған",
+	"Subscription not found":                     "Жазылым табылмады",
+	"Invalid unsubscribe link":                   "Жазылымнан бас тарту сілтемесі жарамсыз",
+
+	// Search, analytics and moderation
+	"Query parameter is required":              "Сұраныс параметрі қажет",
+	"Query is too long":                        "Сұраныс тым ұзын",
+	"Semantic search is disabled":              "Семантикалық іздеу өшірулі",
+	"Date range must not exceed 366 days":      "Күндер аралығы 366 күннен аспауы керек",
+	"'from' must not be after 'to'":            "'from' мәні 'to' мәнінен кейін болмауы керек",
+	"Invalid 'from' date, expected YYYY-MM-DD": "'from' күні қате, YYYY-MM-DD пішімі күтіледі",
+	"Invalid 'to' date, expected YYYY-MM-DD":   "'to' күні қате, YYYY-MM-DD пішімі күтіледі",
+	"from must be an RFC 3339 time":            "from мәні RFC 3339 пішіміндегі уақыт болуы керек",
+	"format must be ndjson or csv":             "format мәні ndjson немесе csv болуы керек",
+	"fields must be a comma-separated list of": "fields мәні үтірмен бөлінген тізім болуы керек",
+	"to must be an RFC 3339 time":              "to мәні RFC 3339 пішіміндегі уақыт болуы керек",
+	"after must be a non-negative integer":     "after мәні теріс емес бүтін сан болуы керек",
+	"limit must be a positive integer":         "limit мәні оң бүтін сан болуы керек",
+	"Review item not found":                    "Тексеру элементі табылмады",
+	"Invalid review item ID":                   "Тексеру элементінің ID-і қате",
+}
@@ -0,0 +1,250 @@
+package i18n
+
+var russian = map[string]string{
+	// Generic
+	"Bad Request":           "Некорректный запрос",
+	"Unauthorized":          "Требуется авторизация",
+	"Forbidden":             "Доступ запрещён",
+	"Not Found":             "Не найдено",
+	"Conflict":              "Конфликт",
+	"Internal Server Error": "Внутренняя ошибка сервера",
+	"Internal server error": "Внутренняя ошибка сервера",
+	"Not implemented yet":   "Пока не реализовано",
+	"Rate limit exceeded":   "Превышен лимит запросов",
+	"AI quota exceeded":     "Превышена дневная квота ИИ",
+	"Access denied":         "Доступ запрещён",
+	"Permission denied":     "Недостаточно прав",
+	"Invalid request body":  "Неверное тело запроса",
+	"Validation failed":     "Ошибка валидации",
+
+	// Auth and organizations
+	"Authorization header required":                               "Требуется заголовок Authorization",
+	"Invalid authorization header format":                         "Неверный формат заголовка Authorization",
+	"Invalid token":                                               "Недействительный токен",
+	"Invalid JWT token":                                           "Недействительный токен",
+	"Token is required":                                           "Требуется токен",
+	"Invalid email or password":                                   "Неверный email или пароль",
+	"Organization not found":                                      "Организация не найдена",
+	"Organization access denied":                                  "Нет доступа к организации",
+	"Organization already exists":                                 "Организация уже существует",
+	"Slug may only contain lowercase letters, digits and hyphens": "Slug может содержать только строчные буквы, цифры и дефисы",
+	"You cannot change your own role":                             "Нельзя изменить собственную роль",
+	"A superadmin's role can't be changed here":                   "Роль суперадминистратора здесь изменить нельзя",
+	"role must be one of student, teacher, moderator, admin":      "role должен быть одним из: student, teacher, moderator, admin",
+	"Invalid log levels":                                          "Неверные уровни логирования",
+
+	// Users
+	"User not found":                                  "Пользователь не найден",
+	"Invalid user ID":                                 "Неверный ID пользователя",
+	"Username is already taken":                       "Имя пользователя уже занято",
+	"Username is unchanged":                           "Имя пользователя не изменилось",
+	"Username can only be changed once every 30 days": "Имя пользователя можно менять раз в 30 дней",
+	"Username must be 3-50 letters, digits, '_', '.' or '-' and start with a letter, digit or '_'": "Имя пользователя должно содержать 3–50 букв, цифр, '_', '.' или '-' и начинаться с буквы, цифры или '_'",
+	"Follow request not found":                               "Запрос на подписку не найден",
+	"is_private or show_presence is required":                "Требуется is_private или show_presence",
+	"This account is private":                                "Это закрытый аккаунт",
+	"ai_content must be show, downrank or hide":              "ai_content должен быть show, downrank или hide",
+	"timezone must be an IANA time zone such as Asia/Almaty": "timezone должен быть часовым поясом IANA, например Asia/Almaty",
+	"ai_content or languages is required":                    "Требуется ai_content или languages",
+	"languages may only contain en, kk and ru":               "languages может содержать только en, kk и ru",
+	"Failed to get limits":                                   "Не удалось получить лимиты",
+	"Invalid API key":                                        "Недействительный API-ключ",
+	"API key is read-only":                                   "API-ключ доступен только для чтения",
+	"Token is read-only":                                     "Токен доступен только для чтения",
+	"Invalid refresh token":                                  "Недействительный токен обновления",
+	"Failed to refresh token":                                "Не удалось обновить токен",
+	"Failed to start session":                                "Не удалось начать сеанс",
+	"Failed to log out":                                      "Не удалось выйти",
+	"Invalid CSRF token":                                     "Недействительный CSRF-токен",
+	"Bailanysta is down for maintenance":                     "Bailanysta временно недоступна из-за технических работ",
+	"retry_after_seconds must be positive":                   "retry_after_seconds должно быть положительным",
+	"Invalid hashtag":                                        "Неверный хештег",
+	"Failed to get suggestions":                              "Не удалось получить подсказки",
+	"Failed to get feed updates":                             "Не удалось получить новые посты ленты",
+	"Invalid Last-Event-ID":                                  "Неверный Last-Event-ID",
+	"API keys cannot manage API keys":                        "API-ключами нельзя управлять с помощью API-ключа",
+	"API keys cannot change account recovery":                "Настройки восстановления доступа нельзя менять с помощью API-ключа",
+	"Not allowed while impersonating":                        "Недоступно в режиме имперсонации",
+	"API keys cannot start impersonation":                    "API-ключом нельзя начать имперсонацию",
+	"Cannot impersonate yourself":                            "Нельзя войти от своего имени",
+	"Cannot impersonate an admin":                            "Нельзя войти от имени администратора",
+	"Failed to start impersonation":                          "Не удалось начать имперсонацию",
+	"Failed to get impersonation sessions":                   "Не удалось получить сеансы имперсонации",
+	"Failed to get API keys":                                 "Не удалось получить API-ключи",
+	"scope must be read or write":                            "scope должен быть read или write",
+	"Too many API keys; revoke one first":                    "Слишком много API-ключей; сначала отзовите один",
+	"Failed to create API key":                               "Не удалось создать API-ключ",
+	"Invalid API key ID":                                     "Неверный ID API-ключа",
+	"API key not found":                                      "API-ключ не найден",
+	"Failed to revoke API key":                               "Не удалось отозвать API-ключ",
+	"API key revoked":                                        "API-ключ отозван",
+
+	// Posts and comments
+	"Post not found":                                                "Пост не найден",
+	"Invalid post ID":                                               "Неверный ID поста",
+	"Comment not found":                                             "Комментарий не найден",
+	"Invalid comment ID":                                            "Неверный ID комментария",
+	"Post was rejected by the content filter":                       "Пост отклонён фильтром контента",
+	"Comment was rejected by the content filter":                    "Комментарий отклонён фильтром контента",
+	"AI response blocked by the safety filter":                      "Ответ ИИ заблокирован фильтром безопасности",
+	"Post has no text to explain":                                   "В посте нет текста для объяснения",
+	"Exactly one of topic, post_id or text is required":             "Нужно указать ровно одно из: topic, post_id или text",
+	"Post has no text to make flashcards from":                      "В посте нет текста для карточек",
+	"AI returned no flashcards":                                     "ИИ не вернул карточек",
+	"Invalid flashcard ID":                                          "Неверный ID карточки",
+	"Flashcard not found":                                           "Карточка не найдена",
+	"Invalid module ID":                                             "Неверный ID модуля",
+	"Module not found":                                              "Модуль не найден",
+	"Module not completed":                                          "Модуль не отмечен как пройденный",
+	"Invalid assignment ID":                                         "Неверный ID задания",
+	"Assignment not found":                                          "Задание не найдено",
+	"Text or attachments are required":                              "Нужен текст или вложения",
+	"Attachments must be up to 5 http(s) links":                     "Вложения — не более 5 ссылок http(s)",
+	"Submission already graded":                                     "Работа уже оценена",
+	"Submission not found":                                          "Сдача не найдена",
+	"Invalid submission ID":                                         "Неверный ID сдачи",
+	"Grade is required":                                             "Нужна оценка",
+	"Invalid status":                                                "Неверный статус",
+	"Submission has no text to assess":                              "В работе нет текста для оценки",
+	"AI returned no feedback":                                       "ИИ не вернул отзыв",
+	"Failed to save feedback":                                       "Не удалось сохранить отзыв",
+	"Certificate not found":                                         "Сертификат не найден",
+	"Only questions can have a bounty":                              "Вознаграждение можно назначить только вопросу",
+	"Not enough reputation for this bounty":                         "Недостаточно репутации для такого вознаграждения",
+	"Only questions have accepted answers":                          "Принятый ответ есть только у вопросов",
+	"Only the author of the question can accept an answer":          "Принять ответ может только автор вопроса",
+	"Failed to update accepted answer":                              "Не удалось обновить принятый ответ",
+	"Invalid type parameter, expected all, questions or unanswered": "Неверный параметр type, ожидается all, questions или unanswered",
+	"Invalid type, expected post or question":                       "Неверный type, ожидается post или question",
+	"unanswered only applies to questions":                          "unanswered применяется только к вопросам",
+	"Failed to get reputation":                                      "Не удалось получить репутацию",
+	"GIF search is not enabled":                                     "Поиск GIF не включён",
+	"Query must be at most 100 characters":                          "Запрос должен содержать не более 100 символов",
+	"Failed to search GIFs":                                         "Не удалось найти GIF",
+	"Unknown gif_id":                                                "Неизвестный gif_id",
+	"days is out of range":                                          "Параметр days вне допустимого диапазона",
+	"enabled or share_identity is required":                         "Требуется enabled или share_identity",
+	"Failed to get profile views":                                   "Не удалось получить просмотры профиля",
+	"Failed to update profile view settings":                        "Не удалось обновить настройки просмотров профиля",
+	"Invite is invalid, used or expired":                            "Приглашение недействительно, уже использовано или истекло",
+	"A valid email is required":                                     "Требуется корректный email",
+	"Failed to join waitlist":                                       "Не удалось записаться в лист ожидания",
+	"Registration failed":                                           "Регистрация не удалась",
+	"Registration is open; there is no waitlist":                    "Регистрация открыта, листа ожидания нет",
+	"status must be one of pending, approved, registered":           "status должен быть pending, approved или registered",
+	"Failed to get waitlist":                                        "Не удалось получить лист ожидания",
+	"Either ids or count is required":                               "Требуется ids или count",
+	"Failed to approve waitlist":                                    "Не удалось одобрить заявки из листа ожидания",
+	"Bot check failed; solve the challenge and retry":               "Проверка на робота не пройдена; решите задачу и повторите",
+	"Bot check is unavailable; try again later":                     "Проверка на робота недоступна; попробуйте позже",
+	"Failed to create challenge":                                    "Не удалось создать задачу проверки",
+	"Invalid session ID":                                            "Неверный ID сессии",
+	"Session not found":                                             "Сессия не найдена",
+	"Failed to get sessions":                                        "Не удалось получить сессии",
+	"Failed to revoke session":                                      "Не удалось завершить сессию",
+	"Failed to get recovery status":                                 "Не удалось получить настройки восстановления",
+	"Email is not configured":                                       "Отправка почты не настроена",
+	"Backup email must differ from account email":                   "Резервный email должен отличаться от email аккаунта",
+	"Failed to set backup email":                                    "Не удалось задать резервный email",
+	"No backup email is set":                                        "Резервный email не задан",
+	"Failed to remove backup email":                                 "Не удалось удалить резервный email",
+	"Invalid or expired link":                                       "Ссылка недействительна или устарела",
+	"Failed to verify backup email":                                 "Не удалось подтвердить резервный email",
+	"Invalid password":                                              "Неверный пароль",
+	"Failed to generate recovery codes":                             "Не удалось создать коды восстановления",
+	"Failed to start account recovery":                              "Не удалось начать восстановление аккаунта",
+	"Either token, or email and code, is required":                  "Нужен либо token, либо email и code",
+	"Invalid or used recovery code or link":                         "Код или ссылка восстановления недействительны или уже использованы",
+	"Failed to recover account":                                     "Не удалось восстановить аккаунт",
+	"password is required":                                          "Требуется password",
+	"mode must be delete or anonymize":                              "mode должен быть delete или anonymize",
+	"Failed to delete account":                                      "Не удалось удалить аккаунт",
+	"Too many likes or follows; try again later":                    "Слишком много лайков или подписок; попробуйте позже",
+	"Failed to get abuse flags":                                     "Не удалось получить отметки о злоупотреблениях",
+	"Invalid abuse flag ID":                                         "Неверный ID отметки о злоупотреблении",
+	"Abuse flag not found":                                          "Отметка о злоупотреблении не найдена",
+	"Failed to resolve abuse flag":                                  "Не удалось закрыть отметку о злоупотреблении",
+	"Failed to get IP blocks":                                       "Не удалось получить блокировки IP",
+	"Either cidr or asn is required":                                "Нужен либо cidr, либо asn",
+	"cidr must be an IP address or CIDR range":                      "cidr должен быть IP-адресом или диапазоном CIDR",
+	"ASN blocks need GEOIP_ASN_DB_PATH":                             "Для блокировки по ASN нужен GEOIP_ASN_DB_PATH",
+	"Failed to create IP block":                                     "Не удалось создать блокировку IP",
+	"Invalid IP block ID":                                           "Неверный ID блокировки IP",
+	"IP block not found":                                            "Блокировка IP не найдена",
+	"Failed to delete IP block":                                     "Не удалось удалить блокировку IP",
+	"Failed to get duplicate posts":                                 "Не удалось получить дубликаты постов",
+	"Duplicate post not found":                                      "Дубликат поста не найден",
+	"Original post not found":                                       "Исходный пост не найден",
+	"Failed to resolve duplicate post":                              "Не удалось обработать дубликат поста",
+	"Post is longer than the character limit":                       "Пост длиннее допустимого числа символов",
+	"Post is over the word limit":                                   "В посте больше слов, чем допустимо",
+	"Failed to get capabilities":                                    "Не удалось получить ограничения",
+	"Failed to get post limits":                                     "Не удалось получить ограничения постов",
+	"Failed to set post limit":                                      "Не удалось сохранить ограничение постов",
+	"Invalid post limit ID":                                         "Неверный ID ограничения постов",
+	"Post limit not found":                                          "Ограничение постов не найдено",
+	"Failed to delete post limit":                                   "Не удалось удалить ограничение постов",
+	"Failed to get post metadata":                                   "Не удалось получить метаданные поста",
+	"Access from your network is blocked":                           "Доступ из вашей сети заблокирован",
+	"Query must be at least 3 characters unless author_id, course_id, hashtag or unanswered is given": "Запрос должен содержать не менее 3 символов, если не указаны author_id, course_id, hashtag или unanswered",
+	"Only comments on course posts can be verified answers":                                           "Подтверждённым ответом может быть только комментарий к посту курса",
+	"Unknown course_id":                                          "Неизвестный course_id",
+	"Unknown or already used ai_generation_id":                   "Неизвестный или уже использованный ai_generation_id",
+	"sort must be oldest, newest or top":                         "sort должен быть oldest, newest или top",
+	"Invalid sort parameter, expected latest or ranked":          "Неверный параметр sort, ожидается latest или ranked",
+	"Poll not found":                                             "Опрос не найден",
+	"Poll is closed":                                             "Опрос закрыт",
+	"Invalid poll option":                                        "Неверный вариант ответа",
+	"Already voted in this poll":                                 "Вы уже проголосовали в этом опросе",
+	"Poll expiry must be in the future and at most 30 days away": "Срок опроса должен быть в будущем и не дальше 30 дней",
+
+	// Courses, groups and events
+	"Course not found":                                  "Курс не найден",
+	"Invalid course ID":                                 "Неверный ID курса",
+	"Group not found":                                   "Группа не найдена",
+	"Invalid group ID":                                  "Неверный ID группы",
+	"Member not found":                                  "Участник не найден",
+	"Join request not found":                            "Заявка на вступление не найдена",
+	"You are not a member of this group":                "Вы не состоите в этой группе",
+	"Only group admins can do this":                     "Это могут делать только администраторы группы",
+	"Only group members can post to a group":            "Публиковать в группе могут только её участники",
+	"This group is invite-only":                         "В эту группу можно вступить только по приглашению",
+	"The group owner cannot leave the group":            "Владелец группы не может выйти из неё",
+	"User is already invited or a member":               "Пользователь уже приглашён или состоит в группе",
+	"role must be admin or member":                      "role должен быть admin или member",
+	"status must be active, requested or invited":       "status должен быть active, requested или invited",
+	"Event not found":                                   "Событие не найдено",
+	"Invalid event ID":                                  "Неверный ID события",
+	"Event has ended":                                   "Событие уже завершилось",
+	"You cannot create events here":                     "Вы не можете создавать здесь события",
+	"You have not responded to this event":              "Вы не ответили на это событие",
+	"Exactly one of course_id and group_id is required": "Нужно указать ровно одно из course_id и group_id",
+	"status must be going, maybe or declined":           "status должен быть going, maybe или declined",
+	"scope must be org or course:{id}":                  "scope должен быть org или course:{id}",
+	"period must be day, week or all":                   "period должен быть day, week или all",
+
+	// Notifications
+	"Notification not found":                     "Уведомление не найдено",
+	"Invalid notification ID":                    "Неверный ID уведомления",
+	"The notification's target no longer exists": "Объект уведомления больше не существует",
+	"Push notifications are not enabled":         "Push-уведомления не включены",
+	"Subscription not found":                     "Подписка не найдена",
+	"Invalid unsubscribe link":                   "Недействительная ссылка для отписки",
+
+	// Search, analytics and moderation
+	"Query parameter is required":              "Требуется параметр запроса",
+	"Query is too long":                        "Слишком длинный запрос",
+	"Semantic search is disabled":              "Семантический поиск отключён",
+	"Date range must not exceed 366 days":      "Диапазон дат не может превышать 366 дней",
+	"'from' must not be after 'to'":            "'from' не может быть позже 'to'",
+	"Invalid 'from' date, expected YYYY-MM-DD": "Неверная дата 'from', ожидается YYYY-MM-DD",
+	"Invalid 'to' date, expected YYYY-MM-DD":   "Неверная дата 'to', ожидается YYYY-MM-DD",
+	"from must be an RFC 3339 time":            "from должен быть временем в формате RFC 3339",
+	"format must be ndjson or csv":             "format должен быть ndjson или csv",
+	"fields must be a comma-separated list of": "fields должен быть списком через запятую из",
+	"to must be an RFC 3339 time":              "to должен быть временем в формате RFC 3339",
+	"after must be a non-negative integer":     "after должен быть неотрицательным целым числом",
+	"limit must be a positive integer":         "limit должен быть положительным целым числом",
+	"Review item not found":                    "Элемент проверки не найден",
+	"Invalid review item ID":                   "Неверный ID элемента проверки",
+}
@@ -0,0 +1,124 @@
+// Package i18n picks the client's language from Accept-Language and
+// translates server-generated messages. Catalogs are keyed by the English
+// message, so code keeps writing English and untranslated messages fall
+// back to it.
+package i18n
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	English = "en"
+	Kazakh  = "kk"
+	Russian = "ru"
+
+	Default = English
+)
+
+type contextKey struct{}
+
+// names are the languages' English names, as used in AI prompts
+var names = map[string]string{
+	English: "English",
+	Kazakh:  "Kazakh",
+	Russian: "Russian",
+}
+
+var catalogs = map[string]map[string]string{
+	Kazakh:  kazakh,
+	Russian: russian,
+}
+
+// aliases maps tags clients commonly send for a supported language
+var aliases = map[string]string{
+	"kz": Kazakh,
+}
+
+// Supported reports whether lang is one of en, kk and ru
+func Supported(lang string) bool {
+	_, ok := names[lang]
+	return ok
+}
+
+// Name returns the English name of a supported language, or "" otherwise
+func Name(lang string) string {
+	return names[lang]
+}
+
+// Resolve picks the supported language the client prefers most in an
+// Accept-Language header, matching on the primary subtag ("ru-RU" is ru).
+// Earlier entries win ties; without a match it returns Default.
+func Resolve(header string) string {
+	type candidate struct {
+		lang string
+		q    float64
+	}
+	var candidates []candidate
+
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if alias, ok := aliases[primary]; ok {
+			primary = alias
+		}
+		if !Supported(primary) {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{primary, q})
+		}
+	}
+
+	if len(candidates) == 0 {
+		return Default
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+	return candidates[0].lang
+}
+
+// WithLanguage returns a copy of ctx carrying lang
+func WithLanguage(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, contextKey{}, lang)
+}
+
+// FromContext returns the language set by WithLanguage, or Default
+func FromContext(ctx context.Context) string {
+	if lang, ok := ctx.Value(contextKey{}).(string); ok && Supported(lang) {
+		return lang
+	}
+	return Default
+}
+
+// Translate returns message in lang. A message with a detail after ": "
+// (such as "Validation failed: ...") has only its prefix translated.
+// Unknown messages are returned unchanged.
+func Translate(lang, message string) string {
+	catalog, ok := catalogs[lang]
+	if !ok {
+		return message
+	}
+	if translated, ok := catalog[message]; ok {
+		return translated
+	}
+	if prefix, detail, ok := strings.Cut(message, ": "); ok {
+		if translated, ok := catalog[prefix]; ok {
+			return translated + ": " + detail
+		}
+	}
+	return message
+}
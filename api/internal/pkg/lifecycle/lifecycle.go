@@ -0,0 +1,97 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"bailanysta/api/internal/pkg/logger"
+	"bailanysta/api/internal/pkg/sentry"
+)
+
+// Manager runs background workers and stops them together on shutdown.
+// Workers receive a context that is cancelled when shutdown begins and are
+// expected to drain pending work and return.
+type Manager struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	logger *logger.Logger
+
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	running map[string]int
+}
+
+func New(logger *logger.Logger) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{
+		ctx:     ctx,
+		cancel:  cancel,
+		logger:  logger,
+		running: make(map[string]int),
+	}
+}
+
+// Go starts a named worker. A panicking worker is logged, reported and
+// treated as exited.
+func (m *Manager) Go(name string, worker func(ctx context.Context)) {
+	m.mu.Lock()
+	m.running[name]++
+	m.mu.Unlock()
+	m.wg.Add(1)
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				m.logger.Error("Background worker panicked", map[string]interface{}{
+					"worker": name,
+					"panic":  fmt.Sprint(r),
+				})
+				sentry.CapturePanic(r, map[string]string{"worker": name})
+			}
+
+			m.mu.Lock()
+			m.running[name]--
+			if m.running[name] == 0 {
+				delete(m.running, name)
+			}
+			m.mu.Unlock()
+			m.wg.Done()
+		}()
+
+		worker(m.ctx)
+	}()
+}
+
+// Shutdown signals all workers to stop and waits until they exit or ctx
+// expires. On timeout it returns an error naming the workers still running.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("workers did not stop in time: %v", m.Running())
+	}
+}
+
+// Running returns the names of workers that have not exited yet
+func (m *Manager) Running() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.running))
+	for name := range m.running {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
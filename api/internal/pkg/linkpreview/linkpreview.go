@@ -0,0 +1,231 @@
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	maxBodyBytes = 512 * 1024
+	maxRedirects = 3
+	maxTextChars = 300
+)
+
+var (
+	// ErrBlockedAddress is returned for URLs that resolve to private,
+	// loopback or otherwise internal addresses
+	ErrBlockedAddress = errors.New("address not allowed")
+	ErrNotHTML        = errors.New("not an HTML page")
+
+	// Carrier-grade NAT and the IPv4 "this network" block are not covered by
+	// netip's classification helpers
+	extraBlocked = []netip.Prefix{
+		netip.MustParsePrefix("0.0.0.0/8"),
+		netip.MustParsePrefix("100.64.0.0/10"),
+		netip.MustParsePrefix("192.0.0.0/24"),
+		netip.MustParsePrefix("198.18.0.0/15"),
+	}
+
+	metaTagRe = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	attrRe    = regexp.MustCompile(`(?is)([a-z:_-]+)\s*=\s*("[^"]*"|'[^']*'|[^\s"'>]+)`)
+	titleRe   = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+)
+
+// Preview is the Open Graph metadata of a page
+type Preview struct {
+	Title       string
+	Description string
+	ImageURL    string
+	SiteName    string
+}
+
+// Fetcher downloads pages for previews. Only http and https on the default
+// ports are fetched, and connections to non-public addresses are refused
+// after DNS resolution, so redirects and DNS rebinding can't reach internal
+// services.
+type Fetcher struct {
+	client    *http.Client
+	userAgent string
+}
+
+func NewFetcher(timeout time.Duration) *Fetcher {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return ErrBlockedAddress
+			}
+			if !publicAddr(addrPort.Addr()) {
+				return ErrBlockedAddress
+			}
+			return nil
+		},
+	}
+
+	transport := &http.Transport{
+		// No proxy: requests must go through the address checks above
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+
+	return &Fetcher{
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= maxRedirects {
+					return fmt.Errorf("too many redirects")
+				}
+				return checkURL(req.URL)
+			},
+		},
+		userAgent: "BailanystaBot/1.0 (+link preview)",
+	}
+}
+
+// Fetch downloads rawURL and extracts its preview metadata
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*Preview, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	if err := checkURL(u); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", f.userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch page: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, ErrNotHTML
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read page: %w", err)
+	}
+
+	return Parse(string(body), resp.Request.URL), nil
+}
+
+// Parse extracts Open Graph metadata from an HTML document, falling back to
+// the <title> and description meta tags. Relative image URLs are resolved
+// against base.
+func Parse(doc string, base *url.URL) *Preview {
+	meta := make(map[string]string)
+	for _, tag := range metaTagRe.FindAllString(doc, -1) {
+		attrs := make(map[string]string)
+		for _, m := range attrRe.FindAllStringSubmatch(tag, -1) {
+			attrs[strings.ToLower(m[1])] = html.UnescapeString(strings.Trim(m[2], `"'`))
+		}
+
+		key := attrs["property"]
+		if key == "" {
+			key = attrs["name"]
+		}
+		key = strings.ToLower(key)
+		if _, seen := meta[key]; key != "" && !seen {
+			meta[key] = strings.TrimSpace(attrs["content"])
+		}
+	}
+
+	preview := &Preview{
+		Title:       first(meta["og:title"], meta["twitter:title"]),
+		Description: first(meta["og:description"], meta["twitter:description"], meta["description"]),
+		SiteName:    meta["og:site_name"],
+	}
+	if preview.Title == "" {
+		if m := titleRe.FindStringSubmatch(doc); m != nil {
+			preview.Title = strings.TrimSpace(html.UnescapeString(m[1]))
+		}
+	}
+	preview.Title = truncate(preview.Title)
+	preview.Description = truncate(preview.Description)
+
+	if image := first(meta["og:image:secure_url"], meta["og:image"], meta["twitter:image"]); image != "" {
+		if ref, err := url.Parse(image); err == nil {
+			if resolved := base.ResolveReference(ref); resolved.Scheme == "http" || resolved.Scheme == "https" {
+				preview.ImageURL = resolved.String()
+			}
+		}
+	}
+
+	return preview
+}
+
+// checkURL allows only http(s) URLs on default ports without credentials
+func checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	if u.User != nil || u.Hostname() == "" {
+		return ErrBlockedAddress
+	}
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		return ErrBlockedAddress
+	}
+	return nil
+}
+
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsMulticast() ||
+		addr.IsInterfaceLocalMulticast() {
+		return false
+	}
+	for _, prefix := range extraBlocked {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+func first(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func truncate(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if runes := []rune(s); len(runes) > maxTextChars {
+		return string(runes[:maxTextChars-1]) + "…"
+	}
+	return s
+}
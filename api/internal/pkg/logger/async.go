@@ -0,0 +1,117 @@
+package logger
+
+import (
+	"bufio"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	asyncQueueSize  = 4096
+	asyncBufferSize = 64 * 1024
+)
+
+// AsyncWriter moves log output off the calling goroutine. Lines are queued,
+// written through a buffer by a background goroutine and flushed every
+// flushInterval. When the queue is full, writers block rather than drop logs.
+type AsyncWriter struct {
+	out   io.Writer
+	lines chan []byte
+	flush chan chan error
+	done  chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+func NewAsyncWriter(out io.Writer, flushInterval time.Duration) *AsyncWriter {
+	w := &AsyncWriter{
+		out:   out,
+		lines: make(chan []byte, asyncQueueSize),
+		flush: make(chan chan error),
+		done:  make(chan struct{}),
+	}
+	go w.run(flushInterval)
+	return w
+}
+
+func (w *AsyncWriter) Write(p []byte) (int, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		return w.out.Write(p)
+	}
+
+	// The caller may reuse p after Write returns
+	line := make([]byte, len(p))
+	copy(line, p)
+	w.lines <- line
+	return len(p), nil
+}
+
+// Sync blocks until everything written so far has reached the underlying writer
+func (w *AsyncWriter) Sync() error {
+	ack := make(chan error, 1)
+	select {
+	case w.flush <- ack:
+		return <-ack
+	case <-w.done:
+		return nil
+	}
+}
+
+// Close flushes pending lines and stops the background goroutine. Later
+// writes go straight to the underlying writer.
+func (w *AsyncWriter) Close() error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.lines)
+	}
+	w.mu.Unlock()
+
+	<-w.done
+	return nil
+}
+
+func (w *AsyncWriter) run(flushInterval time.Duration) {
+	defer close(w.done)
+
+	buf := bufio.NewWriterSize(w.out, asyncBufferSize)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case line, ok := <-w.lines:
+			if !ok {
+				buf.Flush()
+				return
+			}
+			buf.Write(line)
+		case ack := <-w.flush:
+			// Lines queued before Sync was called must be written first
+			w.drainInto(buf)
+			ack <- buf.Flush()
+		case <-ticker.C:
+			buf.Flush()
+		}
+	}
+}
+
+// drainInto writes queued lines without waiting for new ones
+func (w *AsyncWriter) drainInto(buf *bufio.Writer) {
+	for {
+		select {
+		case line, ok := <-w.lines:
+			if !ok {
+				return
+			}
+			buf.Write(line)
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package logger
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// levelSet holds the default level and per-component overrides shared by a
+// logger and all of its named children, so they can be changed at runtime
+type levelSet struct {
+	mu        sync.RWMutex
+	def       Level
+	overrides map[string]Level
+}
+
+func (s *levelSet) enabled(component string, level Level) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	min := s.def
+	if override, ok := s.overrides[component]; ok {
+		min = override
+	}
+	return level >= min
+}
+
+func (s *levelSet) set(def Level, overrides map[string]Level) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.def = def
+	s.overrides = overrides
+}
+
+func (s *levelSet) String() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	parts := []string{strings.ToLower(s.def.String())}
+	components := make([]string, 0, len(s.overrides))
+	for component := range s.overrides {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+	for _, component := range components {
+		parts = append(parts, component+"="+strings.ToLower(s.overrides[component].String()))
+	}
+	return strings.Join(parts, ",")
+}
+
+// ParseLevel converts a level name such as "warn" to a Level
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return DebugLevel, nil
+	case "info":
+		return InfoLevel, nil
+	case "warn", "warning":
+		return WarnLevel, nil
+	case "error":
+		return ErrorLevel, nil
+	case "fatal":
+		return FatalLevel, nil
+	default:
+		return InfoLevel, fmt.Errorf("unknown log level %q", name)
+	}
+}
+
+// ParseLevels parses a spec like "info,http=warn,ai=debug": a bare level sets
+// the default, component=level pairs override it. The default is info.
+func ParseLevels(spec string) (Level, map[string]Level, error) {
+	def := InfoLevel
+	overrides := make(map[string]Level)
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		component, name, isOverride := strings.Cut(part, "=")
+		if !isOverride {
+			level, err := ParseLevel(part)
+			if err != nil {
+				return InfoLevel, nil, err
+			}
+			def = level
+			continue
+		}
+
+		component = strings.TrimSpace(component)
+		if component == "" {
+			return InfoLevel, nil, fmt.Errorf("missing component in %q", part)
+		}
+		level, err := ParseLevel(name)
+		if err != nil {
+			return InfoLevel, nil, err
+		}
+		overrides[component] = level
+	}
+
+	return def, overrides, nil
+}
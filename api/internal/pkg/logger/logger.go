@@ -7,7 +7,6 @@ import (
 	"log"
 	"os"
 	"runtime"
-	"strings"
 	"time"
 )
 
@@ -39,58 +38,101 @@ func (l Level) String() string {
 }
 
 type Logger struct {
-	level  Level
-	writer io.Writer
+	component string
+	levels    *levelSet
+	writer    io.Writer
+	sampler   *sampler
 }
 
 type LogEntry struct {
-	Time    time.Time `json:"time"`
-	Level   string    `json:"level"`
-	Message string    `json:"message"`
-	Fields  Fields    `json:"fields,omitempty"`
-	File    string    `json:"file,omitempty"`
-	Line    int       `json:"line,omitempty"`
+	Time      time.Time `json:"time"`
+	Level     string    `json:"level"`
+	Component string    `json:"component,omitempty"`
+	Message   string    `json:"message"`
+	Fields    Fields    `json:"fields,omitempty"`
+	File      string    `json:"file,omitempty"`
+	Line      int       `json:"line,omitempty"`
 }
 
 type Fields map[string]interface{}
 
+// New creates a root logger. level accepts per-component overrides, see
+// ParseLevels; an invalid spec falls back to info.
 func New(level string, writer io.Writer) *Logger {
 	if writer == nil {
 		writer = os.Stdout
 	}
 
-	var lvl Level
-	switch strings.ToLower(level) {
-	case "debug":
-		lvl = DebugLevel
-	case "info":
-		lvl = InfoLevel
-	case "warn":
-		lvl = WarnLevel
-	case "error":
-		lvl = ErrorLevel
-	case "fatal":
-		lvl = FatalLevel
-	default:
-		lvl = InfoLevel
+	def, overrides, err := ParseLevels(level)
+	if err != nil {
+		def, overrides = InfoLevel, nil
 	}
 
 	return &Logger{
-		level:  lvl,
+		levels: &levelSet{def: def, overrides: overrides},
 		writer: writer,
 	}
 }
 
+// Named returns a logger for a component. It shares output, sampling and
+// levels with its parent, so configure sampling before creating children.
+func (l *Logger) Named(component string) *Logger {
+	child := *l
+	child.component = component
+	return &child
+}
+
+// SetLevels replaces the default level and component overrides at runtime
+// for this logger and every logger derived from the same root
+func (l *Logger) SetLevels(spec string) error {
+	def, overrides, err := ParseLevels(spec)
+	if err != nil {
+		return err
+	}
+	l.levels.set(def, overrides)
+	return nil
+}
+
+// Levels returns the current level spec, e.g. "info,http=warn"
+func (l *Logger) Levels() string {
+	return l.levels.String()
+}
+
+// SetSampling limits DEBUG and INFO entries to `initial` per message per
+// second, then keeps every `thereafter`-th. Warnings and errors are never
+// sampled. initial <= 0 disables sampling.
+func (l *Logger) SetSampling(initial, thereafter int) {
+	if initial <= 0 {
+		l.sampler = nil
+		return
+	}
+	l.sampler = newSampler(initial, thereafter, time.Second)
+}
+
+// Sync flushes buffered output, if the writer buffers
+func (l *Logger) Sync() error {
+	if syncer, ok := l.writer.(interface{ Sync() error }); ok {
+		return syncer.Sync()
+	}
+	return nil
+}
+
 func (l *Logger) log(level Level, message string, fields Fields) {
-	if level < l.level {
+	if !l.levels.enabled(l.component, level) {
+		return
+	}
+
+	now := time.Now()
+	if l.sampler != nil && level <= InfoLevel && !l.sampler.allow(l.component+"|"+message, now) {
 		return
 	}
 
 	entry := LogEntry{
-		Time:    time.Now(),
-		Level:   level.String(),
-		Message: message,
-		Fields:  fields,
+		Time:      now,
+		Level:     level.String(),
+		Component: l.component,
+		Message:   message,
+		Fields:    fields,
 	}
 
 	// Add caller info for non-Info levels
@@ -108,7 +150,7 @@ func (l *Logger) log(level Level, message string, fields Fields) {
 		return
 	}
 
-	fmt.Fprintln(l.writer, string(jsonData))
+	l.writer.Write(append(jsonData, '\n'))
 }
 
 func (l *Logger) Debug(message string, fields ...Fields) {
@@ -149,6 +191,7 @@ func (l *Logger) Fatal(message string, fields ...Fields) {
 		f = fields[0]
 	}
 	l.log(FatalLevel, message, f)
+	l.Sync()
 	os.Exit(1)
 }
 
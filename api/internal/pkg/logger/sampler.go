@@ -0,0 +1,44 @@
+package logger
+
+import (
+	"sync"
+	"time"
+)
+
+// sampler caps repetitive entries: within each tick, the first `initial`
+// entries with the same message are logged, then every `thereafter`-th one
+type sampler struct {
+	initial    int
+	thereafter int
+	tick       time.Duration
+
+	mu          sync.Mutex
+	windowStart time.Time
+	counts      map[string]int
+}
+
+func newSampler(initial, thereafter int, tick time.Duration) *sampler {
+	return &sampler{
+		initial:    initial,
+		thereafter: thereafter,
+		tick:       tick,
+		counts:     make(map[string]int),
+	}
+}
+
+func (s *sampler) allow(message string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.windowStart) >= s.tick {
+		s.windowStart = now
+		clear(s.counts)
+	}
+
+	s.counts[message]++
+	n := s.counts[message]
+	if n <= s.initial {
+		return true
+	}
+	return s.thereafter > 0 && (n-s.initial)%s.thereafter == 0
+}
@@ -0,0 +1,310 @@
+// Package markdown renders the small Markdown subset used in posts and
+// comments to HTML that is safe to insert into a page.
+//
+// Supported: paragraphs and line breaks, **bold**, *italic*, ~~strike~~,
+// `code`, fenced code blocks, "- " and "1. " lists, "> " quotes, [links](url),
+// bare URLs, @mentions and #hashtags.
+//
+// Output is sanitized by construction against an allowlist: source text,
+// including any raw HTML, is always escaped; the only tags emitted are those
+// in allowedTags; link targets must use an allowed scheme or be app-relative.
+package markdown
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// allowedTags documents every element the renderer can produce
+var allowedTags = map[string]bool{
+	"p": true, "br": true, "strong": true, "em": true, "del": true, "code": true,
+	"pre": true, "ul": true, "ol": true, "li": true, "blockquote": true, "a": true,
+}
+
+var allowedSchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+
+var (
+	orderedItemRe = regexp.MustCompile(`^\d{1,9}[.)] `)
+	linkRe        = regexp.MustCompile(`^\[([^\[\]\n]+)\]\(([^()\s]+)\)`)
+	autolinkRe    = regexp.MustCompile(`^https?://[^\s<>"'` + "`" + `]+`)
+	mentionRe     = regexp.MustCompile(`^@(\w[\w.-]{1,49})`)
+	hashtagRe     = regexp.MustCompile(`^#([\p{L}\p{M}\p{N}_]+)`)
+)
+
+// writer collects the HTML; wrap, if set, rewrites external http(s) link
+// targets
+type writer struct {
+	strings.Builder
+	wrap func(href string) string
+}
+
+// Render converts Markdown source to sanitized HTML
+func Render(src string) string {
+	return RenderLinks(src, nil)
+}
+
+// RenderLinks is Render with every external http(s) link target passed
+// through wrap, e.g. to send clicks through a redirect
+func RenderLinks(src string, wrap func(href string) string) string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	b := writer{wrap: wrap}
+	renderBlocks(&b, strings.Split(src, "\n"))
+	return b.String()
+}
+
+// Links returns the external http(s) link targets in src, in the form
+// RenderLinks passes them to wrap
+func Links(src string) []string {
+	var links []string
+	RenderLinks(src, func(href string) string {
+		links = append(links, href)
+		return href
+	})
+	return links
+}
+
+func renderBlocks(b *writer, lines []string) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			i++
+
+		case strings.HasPrefix(trimmed, "```"):
+			// Fenced code runs to the closing fence or the end of the text
+			i++
+			var code []string
+			for i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```") {
+				code = append(code, lines[i])
+				i++
+			}
+			i++
+			openTag(b, "pre")
+			openTag(b, "code")
+			b.WriteString(html.EscapeString(strings.Join(code, "\n")))
+			closeTag(b, "code")
+			closeTag(b, "pre")
+
+		case strings.HasPrefix(trimmed, ">"):
+			var quoted []string
+			for i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">") {
+				inner := strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")
+				quoted = append(quoted, strings.TrimPrefix(inner, " "))
+				i++
+			}
+			openTag(b, "blockquote")
+			renderBlocks(b, quoted)
+			closeTag(b, "blockquote")
+
+		case isBulletItem(trimmed):
+			openTag(b, "ul")
+			for i < len(lines) && isBulletItem(strings.TrimSpace(lines[i])) {
+				openTag(b, "li")
+				renderInline(b, strings.TrimSpace(lines[i])[2:], true)
+				closeTag(b, "li")
+				i++
+			}
+			closeTag(b, "ul")
+
+		case orderedItemRe.MatchString(trimmed):
+			openTag(b, "ol")
+			for i < len(lines) && orderedItemRe.MatchString(strings.TrimSpace(lines[i])) {
+				item := strings.TrimSpace(lines[i])
+				openTag(b, "li")
+				renderInline(b, item[len(orderedItemRe.FindString(item)):], true)
+				closeTag(b, "li")
+				i++
+			}
+			closeTag(b, "ol")
+
+		default:
+			// A paragraph runs until a blank line or the start of another block
+			openTag(b, "p")
+			first := true
+			for i < len(lines) && startsParagraphLine(lines[i]) {
+				if !first {
+					b.WriteString("<br>")
+				}
+				renderInline(b, strings.TrimSpace(lines[i]), true)
+				first = false
+				i++
+			}
+			closeTag(b, "p")
+		}
+	}
+}
+
+func isBulletItem(line string) bool {
+	return strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ")
+}
+
+func startsParagraphLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed != "" && !strings.HasPrefix(trimmed, "```") && !strings.HasPrefix(trimmed, ">") &&
+		!isBulletItem(trimmed) && !orderedItemRe.MatchString(trimmed)
+}
+
+// renderInline writes one line of inline Markdown. Inside link text, links
+// is false so anchors are never nested.
+func renderInline(b *writer, s string, links bool) {
+	for i := 0; i < len(s); {
+		rest := s[i:]
+		atWordStart := i == 0 || !isWordByte(s[i-1])
+
+		switch {
+		case rest[0] == '`':
+			if end := strings.IndexByte(rest[1:], '`'); end > 0 {
+				openTag(b, "code")
+				b.WriteString(html.EscapeString(rest[1 : end+1]))
+				closeTag(b, "code")
+				i += end + 2
+				continue
+			}
+
+		case rest[0] == '[' && links:
+			if m := linkRe.FindStringSubmatch(rest); m != nil {
+				if href, ok := safeURL(m[2]); ok {
+					writeLink(b, href, "")
+					renderInline(b, m[1], false)
+					closeTag(b, "a")
+					i += len(m[0])
+					continue
+				}
+			}
+
+		case strings.HasPrefix(rest, "**"):
+			if end := strings.Index(rest[2:], "**"); end > 0 {
+				openTag(b, "strong")
+				renderInline(b, rest[2:end+2], links)
+				closeTag(b, "strong")
+				i += end + 4
+				continue
+			}
+
+		case strings.HasPrefix(rest, "~~"):
+			if end := strings.Index(rest[2:], "~~"); end > 0 {
+				openTag(b, "del")
+				renderInline(b, rest[2:end+2], links)
+				closeTag(b, "del")
+				i += end + 4
+				continue
+			}
+
+		case rest[0] == '*':
+			if end := strings.IndexByte(rest[1:], '*'); end > 0 && rest[1] != ' ' {
+				openTag(b, "em")
+				renderInline(b, rest[1:end+1], links)
+				closeTag(b, "em")
+				i += end + 2
+				continue
+			}
+
+		case rest[0] == 'h' && atWordStart && links:
+			if m := autolinkRe.FindString(rest); m != "" {
+				link := trimURL(m)
+				if href, ok := safeURL(link); ok {
+					writeLink(b, href, "")
+					b.WriteString(html.EscapeString(link))
+					closeTag(b, "a")
+					i += len(link)
+					continue
+				}
+			}
+
+		case rest[0] == '@' && atWordStart && links:
+			if m := mentionRe.FindStringSubmatch(rest); m != nil {
+				name := strings.TrimRight(m[1], ".-")
+				writeLink(b, "/profile/"+url.PathEscape(name), "mention")
+				b.WriteString("@" + html.EscapeString(name))
+				closeTag(b, "a")
+				i += 1 + len(name)
+				continue
+			}
+
+		case rest[0] == '#' && atWordStart && links:
+			if m := hashtagRe.FindStringSubmatch(rest); m != nil {
+				writeLink(b, "/search?q="+url.QueryEscape("#"+m[1]), "hashtag")
+				b.WriteString(html.EscapeString(m[0]))
+				closeTag(b, "a")
+				i += len(m[0])
+				continue
+			}
+		}
+
+		// Plain text up to the next character that may start markup
+		next := strings.IndexAny(rest[1:], "`[*~h@#")
+		if next < 0 {
+			next = len(rest) - 1
+		}
+		b.WriteString(html.EscapeString(rest[:next+1]))
+		i += next + 1
+	}
+}
+
+// safeURL returns the normalized URL if its scheme is allowed or it is an
+// app-relative path
+func safeURL(raw string) (string, bool) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", false
+	}
+	if u.Scheme == "" {
+		// Browsers read "/\host" like "//host", a link to another site
+		if !strings.HasPrefix(raw, "/") || strings.HasPrefix(raw, "//") || strings.HasPrefix(raw, `/\`) {
+			return "", false
+		}
+	} else if !allowedSchemes[strings.ToLower(u.Scheme)] {
+		return "", false
+	}
+	return u.String(), true
+}
+
+func isWebURL(href string) bool {
+	lower := strings.ToLower(href)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// trimURL drops trailing punctuation and an unbalanced closing parenthesis
+func trimURL(link string) string {
+	link = strings.TrimRight(link, ".,;:!?")
+	if strings.HasSuffix(link, ")") && strings.Count(link, "(") < strings.Count(link, ")") {
+		link = strings.TrimRight(strings.TrimSuffix(link, ")"), ".,;:!?")
+	}
+	return link
+}
+
+func writeLink(b *writer, href, class string) {
+	external := !strings.HasPrefix(href, "/")
+	if b.wrap != nil && isWebURL(href) {
+		href = b.wrap(href)
+	}
+	b.WriteString(`<a href="`)
+	b.WriteString(html.EscapeString(href))
+	b.WriteString(`"`)
+	if class != "" {
+		b.WriteString(` class="` + class + `"`)
+	}
+	if external {
+		b.WriteString(` rel="nofollow noopener noreferrer" target="_blank"`)
+	}
+	b.WriteString(">")
+}
+
+func openTag(b *writer, tag string) {
+	if !allowedTags[tag] {
+		panic("markdown: tag not in allowlist: " + tag)
+	}
+	b.WriteString("<" + tag + ">")
+}
+
+func closeTag(b *writer, tag string) {
+	b.WriteString("</" + tag + ">")
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
@@ -0,0 +1,144 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const externalRel = ` rel="nofollow noopener noreferrer" target="_blank"`
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "paragraph with line break",
+			src:  "one\r\ntwo",
+			want: "<p>one<br>two</p>",
+		},
+		{
+			name: "raw HTML is escaped",
+			src:  `<script>alert(1)</script><img src=x onerror="alert(1)">`,
+			want: "<p>&lt;script&gt;alert(1)&lt;/script&gt;&lt;img src=x onerror=&#34;alert(1)&#34;&gt;</p>",
+		},
+		{
+			name: "HTML inside inline code is escaped",
+			src:  "`<b>`",
+			want: "<p><code>&lt;b&gt;</code></p>",
+		},
+		{
+			name: "javascript link is left as text",
+			src:  "[x](javascript:alert(1))",
+			want: "<p>[x](javascript:alert(1))</p>",
+		},
+		{
+			name: "mixed-case javascript link is left as text",
+			src:  "[x](JaVaScRiPt:alert)",
+			want: "<p>[x](JaVaScRiPt:alert)</p>",
+		},
+		{
+			name: "data link is left as text",
+			src:  "[x](data:text/html,hi)",
+			want: "<p>[x](data:text/html,hi)</p>",
+		},
+		{
+			name: "protocol-relative link is left as text",
+			src:  "[x](//evil.example)",
+			want: "<p>[x](//evil.example)</p>",
+		},
+		{
+			name: "backslash host link is left as text",
+			src:  `[x](/\evil.example)`,
+			want: `<p>[x](/\evil.example)</p>`,
+		},
+		{
+			name: "app-relative link",
+			src:  "[settings](/settings)",
+			want: `<p><a href="/settings">settings</a></p>`,
+		},
+		{
+			name: "external link",
+			src:  "[site](https://example.com/a?b=1&c=2)",
+			want: `<p><a href="https://example.com/a?b=1&amp;c=2"` + externalRel + `>site</a></p>`,
+		},
+		{
+			name: "nested emphasis inside link",
+			src:  "[**bold** *it* ~~old~~](https://example.com)",
+			want: `<p><a href="https://example.com"` + externalRel + `><strong>bold</strong> <em>it</em> <del>old</del></a></p>`,
+		},
+		{
+			name: "no links nested inside link text",
+			src:  "[see https://b.example @bob #go](https://a.example)",
+			want: `<p><a href="https://a.example"` + externalRel + `>see https://b.example @bob #go</a></p>`,
+		},
+		{
+			name: "bare URL drops trailing punctuation",
+			src:  "see https://example.com/x.",
+			want: `<p>see <a href="https://example.com/x"` + externalRel + `>https://example.com/x</a>.</p>`,
+		},
+		{
+			name: "fenced code",
+			src:  "```go\nif a < b {\n```\nafter",
+			want: "<pre><code>if a &lt; b {</code></pre><p>after</p>",
+		},
+		{
+			name: "unclosed fence runs to the end",
+			src:  "```\ncode <b>\n**not bold**",
+			want: "<pre><code>code &lt;b&gt;\n**not bold**</code></pre>",
+		},
+		{
+			name: "mentions",
+			src:  "hi @bob. and @a.b-c",
+			want: `<p>hi <a href="/profile/bob" class="mention">@bob</a>. and <a href="/profile/a.b-c" class="mention">@a.b-c</a></p>`,
+		},
+		{
+			name: "mention inside a word is text",
+			src:  "mail@example.com",
+			want: "<p>mail@example.com</p>",
+		},
+		{
+			name: "hashtags",
+			src:  "#go, #тег",
+			want: `<p><a href="/search?q=%23go" class="hashtag">#go</a>, <a href="/search?q=%23%D1%82%D0%B5%D0%B3" class="hashtag">#тег</a></p>`,
+		},
+		{
+			name: "hashtag inside a word is text",
+			src:  "C#sharp",
+			want: "<p>C#sharp</p>",
+		},
+		{
+			name: "lists and quote",
+			src:  "- a\n- b\n1. c\n> d",
+			want: "<ul><li>a</li><li>b</li></ul><ol><li>c</li></ol><blockquote><p>d</p></blockquote>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Render(tt.src))
+		})
+	}
+}
+
+func TestRenderLinksWrapsOnlyExternal(t *testing.T) {
+	got := RenderLinks("[a](https://a.example) [b](/b) @bob https://c.example", func(href string) string {
+		return "/out?u=" + href
+	})
+
+	assert.Contains(t, got, `href="/out?u=https://a.example"`)
+	assert.Contains(t, got, `href="/out?u=https://c.example"`)
+	assert.Contains(t, got, `href="/b"`)
+	assert.Contains(t, got, `href="/profile/bob"`)
+	assert.Equal(t, 2, strings.Count(got, "/out?u="))
+}
+
+func TestLinks(t *testing.T) {
+	assert.Equal(t,
+		[]string{"https://a.example", "http://b.example/x"},
+		Links("[a](https://a.example) [m](mailto:x@example.com) http://b.example/x. [c](/c)"))
+	assert.Nil(t, Links("[x](javascript:alert(1)) //evil.example"))
+}
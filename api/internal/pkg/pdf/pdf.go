@@ -0,0 +1,152 @@
+// Package pdf writes simple one-page PDF documents covering what
+// certificates need: lines of text and rectangles. Text is set in the
+// standard Helvetica fonts, which readers provide, so nothing is embedded.
+// Those fonts only cover Latin-1; Cyrillic is transliterated and other
+// characters print as "?".
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"bailanysta/api/internal/pkg/translit"
+)
+
+// Page sizes in points
+const (
+	A4Width  = 595.0
+	A4Height = 842.0
+)
+
+type Align int
+
+const (
+	AlignLeft Align = iota
+	AlignCenter
+)
+
+// Page is a single page under construction. Coordinates are in points from
+// the bottom-left corner.
+type Page struct {
+	width, height float64
+	content       strings.Builder
+}
+
+// NewPage starts a page of the given size; swap A4Width and A4Height for
+// landscape
+func NewPage(width, height float64) *Page {
+	return &Page{width: width, height: height}
+}
+
+// Text writes a line of text at baseline y. With AlignCenter, x is the
+// center of the line.
+func (p *Page) Text(x, y, size float64, bold bool, align Align, text string) {
+	encoded := encode(text)
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	if align == AlignCenter {
+		x -= textWidth(encoded, bold) * size / 1000 / 2
+	}
+	fmt.Fprintf(&p.content, "BT /%s %.2f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, escape(encoded))
+}
+
+// Rect strokes a rectangle with its bottom-left corner at x, y
+func (p *Page) Rect(x, y, width, height, lineWidth float64) {
+	fmt.Fprintf(&p.content, "%.2f w %.2f %.2f %.2f %.2f re S\n", lineWidth, x, y, width, height)
+}
+
+// Bytes renders the document
+func (p *Page) Bytes() []byte {
+	content := p.content.String()
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+			"/Resources << /Font << /F1 4 0 R /F2 5 0 R >> >> /Contents 6 0 R >>", p.width, p.height),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content),
+	}
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return b.Bytes()
+}
+
+// encode converts text to WinAnsi bytes, which match Latin-1 from 0xA0 up
+func encode(text string) []byte {
+	var out []byte
+	for _, r := range text {
+		if r >= 0x20 && r < 0x7F || r >= 0xA0 && r <= 0xFF {
+			out = append(out, byte(r))
+		} else if latin, ok := translit.Latin(r); ok {
+			out = append(out, latin...)
+		} else {
+			out = append(out, '?')
+		}
+	}
+	return out
+}
+
+func escape(encoded []byte) string {
+	var b strings.Builder
+	for _, c := range encoded {
+		if c == '(' || c == ')' || c == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// textWidth is the width of encoded in thousandths of the font size.
+// Characters past ASCII are counted as wide as a digit.
+func textWidth(encoded []byte, bold bool) float64 {
+	widths := helveticaWidths
+	if bold {
+		widths = helveticaBoldWidths
+	}
+	var width float64
+	for _, c := range encoded {
+		if c >= 0x20 && c < 0x7F {
+			width += float64(widths[c-0x20])
+		} else {
+			width += 556
+		}
+	}
+	return width
+}
+
+// Glyph widths of ASCII 0x20-0x7E from the Adobe font metrics
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var helveticaBoldWidths = [95]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
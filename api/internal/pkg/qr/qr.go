@@ -0,0 +1,466 @@
+// Package qr encodes short texts, such as links, as QR codes (ISO/IEC
+// 18004): byte mode at error correction level M, versions 1 to 10, which
+// holds up to 213 bytes.
+package qr
+
+import (
+	"errors"
+	"image"
+	"image/color"
+)
+
+// ErrTooLong is returned for texts that don't fit in a version 10 code
+var ErrTooLong = errors.New("qr: text too long")
+
+const maxVersion = 10
+
+// Level M error correction per version: codewords per block and blocks in
+// each of the (up to) two groups; group 2 blocks hold one more data codeword
+var blocks = [maxVersion + 1]struct{ ec, group1, data1, group2 int }{
+	1:  {10, 1, 16, 0},
+	2:  {16, 1, 28, 0},
+	3:  {26, 1, 44, 0},
+	4:  {18, 2, 32, 0},
+	5:  {24, 2, 43, 0},
+	6:  {16, 4, 27, 0},
+	7:  {18, 4, 31, 0},
+	8:  {22, 2, 38, 2},
+	9:  {22, 3, 36, 2},
+	10: {26, 4, 43, 1},
+}
+
+var alignmentPositions = [maxVersion + 1][]int{
+	2: {6, 18}, 3: {6, 22}, 4: {6, 26}, 5: {6, 30}, 6: {6, 34},
+	7: {6, 22, 38}, 8: {6, 24, 42}, 9: {6, 26, 46}, 10: {6, 28, 50},
+}
+
+// Code is an encoded QR symbol, without the quiet zone
+type Code struct {
+	size     int
+	modules  []bool // dark modules, row by row
+	function []bool // modules that are not data
+}
+
+// Encode returns the smallest code holding text
+func Encode(text string) (*Code, error) {
+	data := []byte(text)
+	version := 1
+	for ; version <= maxVersion; version++ {
+		if len(data) <= capacity(version) {
+			break
+		}
+	}
+	if version > maxVersion {
+		return nil, ErrTooLong
+	}
+
+	size := 17 + 4*version
+	c := &Code{size: size, modules: make([]bool, size*size), function: make([]bool, size*size)}
+	c.drawFunctionPatterns(version)
+	c.drawCodewords(interleave(version, dataCodewords(version, data)))
+
+	// Keep the mask with the lowest penalty
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		c.applyMask(mask)
+		c.drawFormat(mask)
+		if penalty := c.penalty(); bestPenalty < 0 || penalty < bestPenalty {
+			best, bestPenalty = mask, penalty
+		}
+		c.applyMask(mask)
+	}
+	c.applyMask(best)
+	c.drawFormat(best)
+	return c, nil
+}
+
+// Size is the width of the code in modules
+func (c *Code) Size() int {
+	return c.size
+}
+
+// Dark reports whether the module in column x, row y is dark
+func (c *Code) Dark(x, y int) bool {
+	return c.modules[y*c.size+x]
+}
+
+// Image draws the code with scale pixels per module and the four-module
+// quiet zone scanners need
+func (c *Code) Image(scale int) *image.Paletted {
+	const quiet = 4
+	width := (c.size + 2*quiet) * scale
+	img := image.NewPaletted(image.Rect(0, 0, width, width), color.Palette{color.White, color.Black})
+	for y := 0; y < c.size; y++ {
+		for x := 0; x < c.size; x++ {
+			if !c.Dark(x, y) {
+				continue
+			}
+			for py := 0; py < scale; py++ {
+				row := ((quiet+y)*scale + py) * img.Stride
+				for px := 0; px < scale; px++ {
+					img.Pix[row+(quiet+x)*scale+px] = 1
+				}
+			}
+		}
+	}
+	return img
+}
+
+func capacity(version int) int {
+	countBits := 8
+	if version >= 10 {
+		countBits = 16
+	}
+	return (totalData(version)*8 - 4 - countBits) / 8
+}
+
+func totalData(version int) int {
+	b := blocks[version]
+	return b.group1*b.data1 + b.group2*(b.data1+1)
+}
+
+// dataCodewords lays data out in byte mode and pads it to the version's
+// data capacity
+func dataCodewords(version int, data []byte) []byte {
+	var bits bitBuffer
+	bits.append(0b0100, 4)
+	if version >= 10 {
+		bits.append(len(data), 16)
+	} else {
+		bits.append(len(data), 8)
+	}
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+
+	total := totalData(version) * 8
+	terminator := total - len(bits)
+	if terminator > 4 {
+		terminator = 4
+	}
+	bits.append(0, terminator)
+	bits.append(0, (8-len(bits)%8)%8)
+	for pad := 0xEC; len(bits) < total; pad ^= 0xEC ^ 0x11 {
+		bits.append(pad, 8)
+	}
+	return bits.bytes()
+}
+
+// interleave splits the data into blocks, adds error correction to each and
+// interleaves the result
+func interleave(version int, data []byte) []byte {
+	b := blocks[version]
+	generator := rsGenerator(b.ec)
+
+	var dataBlocks, ecBlocks [][]byte
+	for i := 0; i < b.group1+b.group2; i++ {
+		n := b.data1
+		if i >= b.group1 {
+			n++
+		}
+		dataBlocks = append(dataBlocks, data[:n])
+		ecBlocks = append(ecBlocks, rsRemainder(data[:n], generator))
+		data = data[n:]
+	}
+
+	var result []byte
+	for i := 0; i <= b.data1; i++ {
+		for _, block := range dataBlocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := 0; i < b.ec; i++ {
+		for _, block := range ecBlocks {
+			result = append(result, block[i])
+		}
+	}
+	return result
+}
+
+func (c *Code) set(x, y int, dark bool) {
+	c.modules[y*c.size+x] = dark
+	c.function[y*c.size+x] = true
+}
+
+func (c *Code) drawFunctionPatterns(version int) {
+	for i := 0; i < c.size; i++ {
+		c.set(6, i, i%2 == 0)
+		c.set(i, 6, i%2 == 0)
+	}
+
+	// Finders, with their light separators
+	for _, center := range [][2]int{{3, 3}, {c.size - 4, 3}, {3, c.size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := center[0]+dx, center[1]+dy
+				if x < 0 || y < 0 || x >= c.size || y >= c.size {
+					continue
+				}
+				dist := max(abs(dx), abs(dy))
+				c.set(x, y, dist != 2 && dist != 4)
+			}
+		}
+	}
+
+	positions := alignmentPositions[version]
+	last := len(positions) - 1
+	for i, cy := range positions {
+		for j, cx := range positions {
+			if i == 0 && j == 0 || i == 0 && j == last || i == last && j == 0 {
+				continue // under a finder
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					c.set(cx+dx, cy+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	// Reserve the format areas until the mask is chosen
+	c.drawFormat(0)
+
+	if version >= 7 {
+		rem := version
+		for i := 0; i < 12; i++ {
+			rem = rem<<1 ^ (rem>>11)*0x1F25
+		}
+		bits := version<<12 | rem
+		for i := 0; i < 18; i++ {
+			dark := bits>>i&1 == 1
+			a, b := c.size-11+i%3, i/3
+			c.set(a, b, dark)
+			c.set(b, a, dark)
+		}
+	}
+}
+
+// drawFormat writes both copies of the format information for mask, and the
+// dark module
+func (c *Code) drawFormat(mask int) {
+	const levelM = 0b00
+	data := levelM<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return bits>>i&1 == 1 }
+
+	for i := 0; i <= 5; i++ {
+		c.set(8, i, bit(i))
+	}
+	c.set(8, 7, bit(6))
+	c.set(8, 8, bit(7))
+	c.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.set(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		c.set(c.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.set(8, c.size-15+i, bit(i))
+	}
+	c.set(8, c.size-8, true)
+}
+
+// drawCodewords fills the data modules in the standard zigzag, two columns
+// at a time from the bottom right, skipping the vertical timing pattern.
+// Left over modules (remainder bits) stay light.
+func (c *Code) drawCodewords(codewords []byte) {
+	i := 0
+	for right := c.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < c.size; vert++ {
+			y := vert
+			if upward {
+				y = c.size - 1 - vert
+			}
+			for j := 0; j < 2; j++ {
+				x := right - j
+				if c.function[y*c.size+x] {
+					continue
+				}
+				if i < len(codewords)*8 {
+					c.modules[y*c.size+x] = codewords[i/8]>>(7-i%8)&1 == 1
+					i++
+				}
+			}
+		}
+	}
+}
+
+// applyMask flips the data modules selected by mask; applying it twice
+// undoes it
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.size; y++ {
+		for x := 0; x < c.size; x++ {
+			if c.function[y*c.size+x] {
+				continue
+			}
+			var flip bool
+			switch mask {
+			case 0:
+				flip = (x+y)%2 == 0
+			case 1:
+				flip = y%2 == 0
+			case 2:
+				flip = x%3 == 0
+			case 3:
+				flip = (x+y)%3 == 0
+			case 4:
+				flip = (y/2+x/3)%2 == 0
+			case 5:
+				flip = x*y%2+x*y%3 == 0
+			case 6:
+				flip = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				flip = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if flip {
+				c.modules[y*c.size+x] = !c.modules[y*c.size+x]
+			}
+		}
+	}
+}
+
+// penalty scores how hard the code is to scan, by the four rules of the
+// standard: long runs, 2x2 blocks, finder-like patterns and imbalance
+func (c *Code) penalty() int {
+	penalty := 0
+	dark := 0
+	for a := 0; a < c.size; a++ {
+		row := make([]bool, c.size)
+		col := make([]bool, c.size)
+		for b := 0; b < c.size; b++ {
+			row[b] = c.Dark(b, a)
+			col[b] = c.Dark(a, b)
+			if row[b] {
+				dark++
+			}
+		}
+		penalty += linePenalty(row) + linePenalty(col)
+	}
+
+	for y := 0; y < c.size-1; y++ {
+		for x := 0; x < c.size-1; x++ {
+			m := c.Dark(x, y)
+			if m == c.Dark(x+1, y) && m == c.Dark(x, y+1) && m == c.Dark(x+1, y+1) {
+				penalty += 3
+			}
+		}
+	}
+
+	percent := dark * 100 / (c.size * c.size)
+	penalty += abs(percent-50) / 5 * 10
+	return penalty
+}
+
+var finderLike = [2][11]bool{
+	{true, false, true, true, true, false, true, false, false, false, false},
+	{false, false, false, false, true, false, true, true, true, false, true},
+}
+
+func linePenalty(line []bool) int {
+	penalty := 0
+	run := 1
+	for i := 1; i <= len(line); i++ {
+		if i < len(line) && line[i] == line[i-1] {
+			run++
+			continue
+		}
+		if run >= 5 {
+			penalty += run - 2
+		}
+		run = 1
+	}
+
+	for i := 0; i+11 <= len(line); i++ {
+		for _, pattern := range finderLike {
+			match := true
+			for j, dark := range pattern {
+				if line[i+j] != dark {
+					match = false
+					break
+				}
+			}
+			if match {
+				penalty += 40
+			}
+		}
+	}
+	return penalty
+}
+
+type bitBuffer []bool
+
+func (b *bitBuffer) append(value, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*b = append(*b, value>>i&1 == 1)
+	}
+}
+
+func (b bitBuffer) bytes() []byte {
+	result := make([]byte, len(b)/8)
+	for i, bit := range b {
+		if bit {
+			result[i/8] |= 1 << (7 - i%8)
+		}
+	}
+	return result
+}
+
+// rsGenerator returns the Reed-Solomon generator polynomial of the given
+// degree, highest coefficient (always 1) first and omitted
+func rsGenerator(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+func rsRemainder(data, generator []byte) []byte {
+	result := make([]byte, len(generator))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coefficient := range generator {
+			result[i] ^= gfMultiply(coefficient, factor)
+		}
+	}
+	return result
+}
+
+// gfMultiply multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1
+func gfMultiply(x, y byte) byte {
+	var z int
+	for i := 7; i >= 0; i-- {
+		z = z<<1 ^ (z>>7)*0x11D
+		z ^= int(y>>i&1) * int(x)
+	}
+	return byte(z)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
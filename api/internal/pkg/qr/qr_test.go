@@ -0,0 +1,271 @@
+package qr
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRSRemainder(t *testing.T) {
+	// "HELLO WORLD" as 1-M in alphanumeric mode, the worked example of the
+	// standard's Annex I
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+
+	assert.Equal(t, want, rsRemainder(data, rsGenerator(10)))
+}
+
+func TestCapacity(t *testing.T) {
+	// Byte mode capacities at level M from the standard's Table 7
+	want := []int{1: 14, 26, 42, 62, 84, 106, 122, 152, 180, 213}
+	for version := 1; version <= maxVersion; version++ {
+		assert.Equal(t, want[version], capacity(version), "version %d", version)
+	}
+}
+
+func TestDataModules(t *testing.T) {
+	// Every module outside the function patterns holds a codeword bit or
+	// one of the version's remainder bits
+	totalCodewords := []int{1: 26, 44, 70, 100, 134, 172, 196, 242, 292, 346}
+	remainderBits := []int{1: 0, 7, 7, 7, 7, 7, 0, 0, 0, 0}
+	for version := 1; version <= maxVersion; version++ {
+		size := 17 + 4*version
+		c := &Code{size: size, modules: make([]bool, size*size), function: make([]bool, size*size)}
+		c.drawFunctionPatterns(version)
+
+		data := 0
+		for _, function := range c.function {
+			if !function {
+				data++
+			}
+		}
+		assert.Equal(t, totalCodewords[version]*8+remainderBits[version], data, "version %d", version)
+
+		b := blocks[version]
+		assert.Equal(t, totalCodewords[version], totalData(version)+(b.group1+b.group2)*b.ec, "version %d", version)
+	}
+}
+
+func TestFormatBits(t *testing.T) {
+	code, err := Encode("x")
+	require.NoError(t, err)
+	code.drawFormat(0)
+
+	// Level M, mask 0
+	assert.Equal(t, 0b101010000010010, formatBits(code, false))
+	assert.Equal(t, 0b101010000010010, formatBits(code, true))
+	assert.True(t, code.Dark(8, code.size-8), "dark module")
+}
+
+func TestVersionBits(t *testing.T) {
+	code, err := Encode(strings.Repeat("a", capacity(6)+1))
+	require.NoError(t, err)
+	require.Equal(t, 45, code.Size())
+
+	var topRight, bottomLeft int
+	for i := 17; i >= 0; i-- {
+		topRight = topRight<<1 | bit(code.Dark(code.size-11+i%3, i/3))
+		bottomLeft = bottomLeft<<1 | bit(code.Dark(i/3, code.size-11+i%3))
+	}
+	assert.Equal(t, 0b000111110010010100, topRight)
+	assert.Equal(t, 0b000111110010010100, bottomLeft)
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	texts := []string{
+		"",
+		"HELLO WORLD",
+		"https://bailanysta.kz/post/abc123",
+		"Сәлем, әлем! 👋",
+		strings.Repeat("x", capacity(1)),
+		strings.Repeat("x", capacity(1)+1),
+		strings.Repeat("y", capacity(7)),
+		strings.Repeat("z", capacity(9)+1),
+		strings.Repeat("w", capacity(maxVersion)),
+	}
+
+	for _, text := range texts {
+		code, err := Encode(text)
+		require.NoError(t, err)
+		assert.Equal(t, text, decode(t, code), "%d bytes, size %d", len(text), code.Size())
+	}
+}
+
+func TestEncodeVersion(t *testing.T) {
+	for version, text := range map[int]string{
+		1:  "",
+		2:  strings.Repeat("a", capacity(1)+1),
+		10: strings.Repeat("a", capacity(maxVersion)),
+	} {
+		code, err := Encode(text)
+		require.NoError(t, err)
+		assert.Equal(t, 17+4*version, code.Size())
+	}
+
+	_, err := Encode(strings.Repeat("a", capacity(maxVersion)+1))
+	assert.ErrorIs(t, err, ErrTooLong)
+}
+
+func TestFinderPatterns(t *testing.T) {
+	code, err := Encode("finder")
+	require.NoError(t, err)
+
+	for _, corner := range [][2]int{{0, 0}, {code.size - 7, 0}, {0, code.size - 7}} {
+		for dy := 0; dy < 7; dy++ {
+			for dx := 0; dx < 7; dx++ {
+				ring := max(abs(dx-3), abs(dy-3))
+				assert.Equal(t, ring != 2, code.Dark(corner[0]+dx, corner[1]+dy), "finder at %v", corner)
+			}
+		}
+	}
+}
+
+func TestImage(t *testing.T) {
+	code, err := Encode("image")
+	require.NoError(t, err)
+
+	img := code.Image(3)
+	assert.Equal(t, (code.Size()+8)*3, img.Bounds().Dx())
+	assert.Equal(t, uint8(0), img.ColorIndexAt(0, 0), "quiet zone")
+	assert.Equal(t, uint8(1), img.ColorIndexAt(4*3, 4*3), "top left finder")
+}
+
+func bit(dark bool) int {
+	if dark {
+		return 1
+	}
+	return 0
+}
+
+// formatBits reads the first copy of the format information, around the top
+// left finder, or the second, split between the other two
+func formatBits(c *Code, second bool) int {
+	var modules [15][2]int
+	for i := 0; i < 15; i++ {
+		switch {
+		case second && i < 8:
+			modules[i] = [2]int{c.size - 1 - i, 8}
+		case second:
+			modules[i] = [2]int{8, c.size - 15 + i}
+		case i < 6:
+			modules[i] = [2]int{8, i}
+		case i < 8:
+			modules[i] = [2]int{8, i + 1}
+		case i == 8:
+			modules[i] = [2]int{7, 8}
+		default:
+			modules[i] = [2]int{14 - i, 8}
+		}
+	}
+
+	bits := 0
+	for i := 14; i >= 0; i-- {
+		bits = bits<<1 | bit(c.Dark(modules[i][0], modules[i][1]))
+	}
+	return bits
+}
+
+// decode reads a code back: it unmasks the data modules, collects the
+// codewords, checks each block's error correction and returns the byte mode
+// segment
+func decode(t *testing.T, c *Code) string {
+	t.Helper()
+	version := (c.size - 17) / 4
+
+	format := formatBits(c, false) ^ 0x5412
+	require.Equal(t, 0b00, format>>13, "error correction level M")
+	mask := format >> 10 & 0b111
+
+	var bits []bool
+	for right := c.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < c.size; vert++ {
+			y := vert
+			if upward {
+				y = c.size - 1 - vert
+			}
+			for x := right; x > right-2; x-- {
+				if !c.function[y*c.size+x] {
+					bits = append(bits, c.Dark(x, y) != masked(mask, x, y))
+				}
+			}
+		}
+	}
+	codewords := bitBuffer(bits).bytes()
+
+	// Undo the interleaving
+	b := blocks[version]
+	count := b.group1 + b.group2
+	dataBlocks := make([][]byte, count)
+	next := 0
+	for i := 0; i <= b.data1; i++ {
+		for j := range dataBlocks {
+			if i < b.data1 || j >= b.group1 {
+				dataBlocks[j] = append(dataBlocks[j], codewords[next])
+				next++
+			}
+		}
+	}
+	var data []byte
+	for j, block := range dataBlocks {
+		ec := make([]byte, b.ec)
+		for i := range ec {
+			ec[i] = codewords[next+i*count+j]
+		}
+		assert.Equal(t, rsRemainder(block, rsGenerator(b.ec)), ec, "block %d", j)
+		data = append(data, block...)
+	}
+
+	reader := bitReader{data: data}
+	require.Equal(t, 0b0100, reader.read(4), "byte mode")
+	countBits := 8
+	if version >= 10 {
+		countBits = 16
+	}
+	text := make([]byte, reader.read(countBits))
+	for i := range text {
+		text[i] = byte(reader.read(8))
+	}
+	return string(text)
+}
+
+// masked is the mask condition of the standard's Table 10
+func masked(mask, x, y int) bool {
+	switch mask {
+	case 0:
+		return (y+x)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (y+x)%3 == 0
+	case 4:
+		return (y/2+x/3)%2 == 0
+	case 5:
+		return (y*x)%2+(y*x)%3 == 0
+	case 6:
+		return ((y*x)%2+(y*x)%3)%2 == 0
+	default:
+		return ((y+x)%2+(y*x)%3)%2 == 0
+	}
+}
+
+type bitReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *bitReader) read(n int) int {
+	value := 0
+	for i := 0; i < n; i++ {
+		value = value<<1 | int(r.data[r.pos/8]>>(7-r.pos%8)&1)
+		r.pos++
+	}
+	return value
+}
@@ -0,0 +1,406 @@
+// Package sentry reports errors to Sentry or any service speaking its
+// envelope protocol, such as GlitchTip or a self-hosted Sentry
+package sentry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const queueSize = 100
+
+// Default is the client CaptureError and CapturePanic report to; nil until
+// main sets it, in which case they do nothing
+var Default *Client
+
+// Config configures a Client. Release defaults to the VCS revision the
+// binary was built from.
+type Config struct {
+	DSN         string // https://<key>@<host>/<project id>
+	Environment string
+	Release     string
+}
+
+// Client queues events and sends them from Run. A nil Client drops
+// everything, so callers need not check whether reporting is configured.
+type Client struct {
+	endpoint    string
+	auth        string
+	environment string
+	release     string
+	serverName  string
+	httpClient  *http.Client
+	queue       chan *Event
+
+	mu         sync.Mutex
+	retryAfter time.Time // set when the server rate limits us
+}
+
+// New parses the DSN and creates a client
+func New(cfg Config) (*Client, error) {
+	dsn, err := url.Parse(cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Sentry DSN: %w", err)
+	}
+	if dsn.Scheme != "https" && dsn.Scheme != "http" {
+		return nil, fmt.Errorf("invalid Sentry DSN: scheme must be http or https")
+	}
+	key := dsn.User.Username()
+	if key == "" {
+		return nil, fmt.Errorf("invalid Sentry DSN: missing public key")
+	}
+	path, projectID := "", strings.Trim(dsn.Path, "/")
+	if i := strings.LastIndex(projectID, "/"); i >= 0 {
+		path, projectID = "/"+projectID[:i], projectID[i+1:]
+	}
+	if _, err := strconv.ParseUint(projectID, 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid Sentry DSN: missing project ID")
+	}
+
+	release := cfg.Release
+	if release == "" {
+		release = vcsRevision()
+	}
+	serverName, _ := os.Hostname()
+
+	return &Client{
+		endpoint:    fmt.Sprintf("%s://%s%s/api/%s/envelope/", dsn.Scheme, dsn.Host, path, projectID),
+		auth:        fmt.Sprintf("Sentry sentry_version=7, sentry_client=bailanysta/1.0, sentry_key=%s", key),
+		environment: cfg.Environment,
+		release:     release,
+		serverName:  serverName,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+		queue:       make(chan *Event, queueSize),
+	}, nil
+}
+
+func vcsRevision() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" {
+			return setting.Value
+		}
+	}
+	return ""
+}
+
+// Event is a single error report
+type Event struct {
+	EventID     string                 `json:"event_id"`
+	Timestamp   time.Time              `json:"timestamp"`
+	Platform    string                 `json:"platform"`
+	Level       string                 `json:"level"`
+	Release     string                 `json:"release,omitempty"`
+	Environment string                 `json:"environment,omitempty"`
+	ServerName  string                 `json:"server_name,omitempty"`
+	Message     string                 `json:"message,omitempty"`
+	Transaction string                 `json:"transaction,omitempty"`
+	Exception   *Exceptions            `json:"exception,omitempty"`
+	Request     *Request               `json:"request,omitempty"`
+	User        *User                  `json:"user,omitempty"`
+	Tags        map[string]string      `json:"tags,omitempty"`
+	Extra       map[string]interface{} `json:"extra,omitempty"`
+}
+
+type Exceptions struct {
+	Values []Exception `json:"values"`
+}
+
+type Exception struct {
+	Type       string      `json:"type"`
+	Value      string      `json:"value"`
+	Stacktrace *Stacktrace `json:"stacktrace,omitempty"`
+}
+
+type Stacktrace struct {
+	Frames []Frame `json:"frames"`
+}
+
+type Frame struct {
+	Function string `json:"function"`
+	Module   string `json:"module,omitempty"`
+	Filename string `json:"abs_path"`
+	Lineno   int    `json:"lineno"`
+	InApp    bool   `json:"in_app"`
+}
+
+type Request struct {
+	URL         string            `json:"url"`
+	Method      string            `json:"method"`
+	QueryString string            `json:"query_string,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Env         map[string]string `json:"env,omitempty"`
+}
+
+type User struct {
+	ID        string `json:"id,omitempty"`
+	IPAddress string `json:"ip_address,omitempty"`
+}
+
+// NewErrorEvent creates an event for err with the stack of its caller
+func NewErrorEvent(err error) *Event {
+	return newErrorEvent(err)
+}
+
+func newErrorEvent(err error) *Event {
+	return &Event{
+		Level: "error",
+		Exception: &Exceptions{Values: []Exception{{
+			Type:       fmt.Sprintf("%T", err),
+			Value:      err.Error(),
+			Stacktrace: stacktrace(4),
+		}}},
+	}
+}
+
+// NewPanicEvent creates an event for a recovered panic; call it from the
+// deferred function that recovered, so the stack leads to the panic
+func NewPanicEvent(recovered interface{}) *Event {
+	return newPanicEvent(recovered)
+}
+
+func newPanicEvent(recovered interface{}) *Event {
+	return &Event{
+		Level: "fatal",
+		Exception: &Exceptions{Values: []Exception{{
+			Type:       "panic",
+			Value:      fmt.Sprint(recovered),
+			Stacktrace: stacktrace(4),
+		}}},
+	}
+}
+
+// stacktrace returns the stack above the skip innermost frames (counting
+// runtime.Callers and stacktrace itself), oldest first as Sentry expects
+func stacktrace(skip int) *Stacktrace {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(skip, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var result []Frame
+	for {
+		frame, more := frames.Next()
+		module, function := splitFunction(frame.Function)
+		result = append(result, Frame{
+			Function: function,
+			Module:   module,
+			Filename: frame.File,
+			Lineno:   frame.Line,
+			InApp:    strings.HasPrefix(module, "bailanysta/"),
+		})
+		if !more {
+			break
+		}
+	}
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+	return &Stacktrace{Frames: result}
+}
+
+// splitFunction splits bailanysta/api/internal/services.(*PostService).Get
+// into its package path and function name
+func splitFunction(name string) (string, string) {
+	slash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[slash+1:], ".")
+	if dot < 0 {
+		return "", name
+	}
+	return name[:slash+1+dot], name[slash+1+dot+1:]
+}
+
+// sensitiveHeaders are left out of reported requests
+var sensitiveHeaders = map[string]bool{
+	"Authorization": true,
+	"Cookie":        true,
+	"X-Csrf-Token":  true,
+}
+
+// NewRequest describes r for an event, without credentials
+func NewRequest(r *http.Request) *Request {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+
+	headers := make(map[string]string)
+	for name, values := range r.Header {
+		if !sensitiveHeaders[name] {
+			headers[name] = strings.Join(values, ", ")
+		}
+	}
+
+	query := r.URL.Query()
+	for name := range query {
+		lower := strings.ToLower(name)
+		if strings.Contains(lower, "token") || strings.Contains(lower, "key") || strings.Contains(lower, "secret") || strings.Contains(lower, "password") {
+			query.Set(name, "[REDACTED]")
+		}
+	}
+
+	return &Request{
+		URL:         scheme + "://" + r.Host + r.URL.Path,
+		Method:      r.Method,
+		QueryString: query.Encode(),
+		Headers:     headers,
+		Env:         map[string]string{"REMOTE_ADDR": r.RemoteAddr},
+	}
+}
+
+// Capture queues event for sending; it is dropped when the queue is full
+func (c *Client) Capture(event *Event) {
+	if c == nil {
+		return
+	}
+	if event.EventID == "" {
+		event.EventID = newEventID()
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+	if event.Level == "" {
+		event.Level = "error"
+	}
+	event.Platform = "go"
+	event.Release = c.release
+	event.Environment = c.environment
+	event.ServerName = c.serverName
+
+	select {
+	case c.queue <- event:
+	default:
+		fmt.Printf("Error report queue full, dropping event %s\n", event.EventID)
+	}
+}
+
+// CaptureError reports err to Default, tagged with tags
+func CaptureError(err error, tags map[string]string) {
+	if Default == nil || err == nil {
+		return
+	}
+	event := newErrorEvent(err)
+	event.Tags = tags
+	Default.Capture(event)
+}
+
+// CapturePanic reports a recovered panic to Default; call it from the
+// deferred function that recovered
+func CapturePanic(recovered interface{}, tags map[string]string) {
+	if Default == nil {
+		return
+	}
+	event := newPanicEvent(recovered)
+	event.Tags = tags
+	Default.Capture(event)
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Run sends queued events until ctx is cancelled, then sends what is left
+func (c *Client) Run(ctx context.Context) {
+	for {
+		select {
+		case event := <-c.queue:
+			c.send(ctx, event)
+		case <-ctx.Done():
+			drainCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			for {
+				select {
+				case event := <-c.queue:
+					c.send(drainCtx, event)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, event *Event) {
+	c.mu.Lock()
+	limited := time.Now().Before(c.retryAfter)
+	c.mu.Unlock()
+	if limited {
+		return
+	}
+
+	body, err := envelope(event)
+	if err != nil {
+		fmt.Printf("Failed to encode error report: %v\n", err)
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		fmt.Printf("Failed to send error report: %v\n", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", c.auth)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		fmt.Printf("Failed to send error report: %v\n", err)
+		return
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+		if err != nil || seconds <= 0 {
+			seconds = 60
+		}
+		c.mu.Lock()
+		c.retryAfter = time.Now().Add(time.Duration(seconds) * time.Second)
+		c.mu.Unlock()
+		return
+	}
+	if resp.StatusCode >= 300 {
+		fmt.Printf("Failed to send error report: status %d\n", resp.StatusCode)
+	}
+}
+
+// envelope wraps event in Sentry's envelope format: an envelope header, an
+// item header and the item, one JSON document per line
+func envelope(event *Event) ([]byte, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	json.NewEncoder(&buf).Encode(map[string]interface{}{
+		"event_id": event.EventID,
+		"sent_at":  time.Now().UTC().Format(time.RFC3339),
+	})
+	json.NewEncoder(&buf).Encode(map[string]interface{}{
+		"type":   "event",
+		"length": len(payload),
+	})
+	buf.Write(payload)
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
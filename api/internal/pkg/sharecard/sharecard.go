@@ -0,0 +1,154 @@
+// Package sharecard draws the preview images social sites show for shared
+// posts (Open Graph, 1200x630): the logo, the author and the start of the
+// text. Text is set in a built-in 5x7 bitmap font, scaled up, so no font
+// files are needed. Like the pdf package it only covers ASCII: Cyrillic is
+// transliterated and other characters, such as emoji, are left out.
+package sharecard
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+	"unicode"
+
+	"bailanysta/api/internal/pkg/translit"
+)
+
+// Image size in pixels
+const (
+	Width  = 1200
+	Height = 630
+)
+
+const (
+	margin     = 80
+	textScale  = 5
+	textLines  = 6
+	lineHeight = 54
+)
+
+var (
+	background = color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}
+	brand      = color.RGBA{0x7C, 0x3A, 0xED, 0xFF}
+	ink        = color.RGBA{0x11, 0x18, 0x27, 0xFF}
+	muted      = color.RGBA{0x6B, 0x72, 0x80, 0xFF}
+)
+
+// Card is what goes on an image
+type Card struct {
+	Username string // shown as @Username
+	Text     string // plain text; only the start fits
+	Site     string // shown in the footer, e.g. the app's host
+}
+
+// Render draws card
+func Render(card Card) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, Width, Height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+
+	// Logo: a "B" badge and the name
+	fillRect(img, image.Rect(margin, 56, margin+72, 128), brand)
+	drawText(img, margin+21, 71, 6, background, "B")
+	drawText(img, margin+100, 71, 6, ink, "Bailanysta")
+
+	drawText(img, margin, 172, 5, muted, "@"+ascii(card.Username))
+
+	lineChars := (Width - 2*margin) / (6 * textScale)
+	for i, line := range wrap(ascii(card.Text), lineChars, textLines) {
+		drawText(img, margin, 240+i*lineHeight, textScale, ink, line)
+	}
+
+	if site := ascii(card.Site); site != "" {
+		drawText(img, Width-margin-textWidth(site, 4), Height-64, 4, muted, site)
+	}
+	fillRect(img, image.Rect(0, Height-16, Width, Height), brand)
+	return img
+}
+
+// ascii transliterates s, drops what the font can't draw and collapses
+// whitespace
+func ascii(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case unicode.IsSpace(r):
+			b.WriteByte(' ')
+		case r >= 0x20 && r < 0x7F:
+			b.WriteRune(r)
+		default:
+			if latin, ok := translit.Latin(r); ok {
+				b.WriteString(latin)
+			}
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// wrap breaks text into at most maxLines lines of up to width characters,
+// splitting words only when they are longer than a line. Text that doesn't
+// fit ends in "...".
+func wrap(text string, width, maxLines int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		for len(word) > width {
+			if line != "" {
+				lines = append(lines, line)
+				line = ""
+			}
+			lines = append(lines, word[:width])
+			word = word[width:]
+		}
+		switch {
+		case line == "":
+			line = word
+		case len(line)+1+len(word) <= width:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+
+	if len(lines) > maxLines {
+		lines = lines[:maxLines]
+		last := lines[maxLines-1]
+		if len(last) > width-3 {
+			last = strings.TrimRight(last[:width-3], " ")
+		}
+		lines[maxLines-1] = last + "..."
+	}
+	return lines
+}
+
+func textWidth(s string, scale int) int {
+	return (len(s)*6 - 1) * scale
+}
+
+// drawText draws s with its top-left corner at x, y; each font pixel is a
+// scale x scale square
+func drawText(img *image.RGBA, x, y, scale int, c color.RGBA, s string) {
+	for i := 0; i < len(s); i++ {
+		glyph := font[0]
+		if s[i] >= 0x20 && s[i] < 0x7F {
+			glyph = font[s[i]-0x20]
+		}
+		for col, bits := range glyph {
+			for row := 0; row < 8; row++ {
+				if bits>>row&1 == 0 {
+					continue
+				}
+				px, py := x+(i*6+col)*scale, y+row*scale
+				fillRect(img, image.Rect(px, py, px+scale, py+scale), c)
+			}
+		}
+	}
+}
+
+func fillRect(img *image.RGBA, r image.Rectangle, c color.RGBA) {
+	draw.Draw(img, r, image.NewUniform(c), image.Point{}, draw.Src)
+}
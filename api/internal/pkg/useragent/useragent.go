@@ -0,0 +1,90 @@
+// Package useragent makes a coarse guess at the browser, OS and kind of
+// device from a User-Agent header, enough to tell a user's devices apart.
+package useragent
+
+import "strings"
+
+// Device types
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+	DeviceOther   = "other"
+)
+
+// Agent is what Parse makes of a User-Agent. Versions are left out on
+// purpose so updates don't look like a new device.
+type Agent struct {
+	Browser string `json:"browser"`
+	OS      string `json:"os"`
+	Device  string `json:"device"`
+}
+
+// String describes the agent as "Chrome on Windows"
+func (a Agent) String() string {
+	return a.Browser + " on " + a.OS
+}
+
+// The first match wins, so more specific tokens come first
+var browsers = []struct{ token, name string }{
+	{"edg/", "Edge"},
+	{"edga/", "Edge"},
+	{"edgios/", "Edge"},
+	{"opr/", "Opera"},
+	{"yabrowser/", "Yandex Browser"},
+	{"samsungbrowser/", "Samsung Internet"},
+	{"firefox/", "Firefox"},
+	{"fxios/", "Firefox"},
+	{"crios/", "Chrome"},
+	{"chrome/", "Chrome"},
+	{"safari/", "Safari"},
+	{"okhttp/", "Android app"},
+	{"cfnetwork/", "iOS app"},
+	{"curl/", "curl"},
+}
+
+var systems = []struct{ token, name string }{
+	{"windows", "Windows"},
+	{"iphone", "iOS"},
+	{"ipad", "iPadOS"},
+	{"android", "Android"},
+	{"cros", "ChromeOS"},
+	{"mac os x", "macOS"},
+	{"macintosh", "macOS"},
+	{"darwin", "iOS"},
+	{"linux", "Linux"},
+}
+
+// Parse guesses what sent userAgent. Unknown parts are "Unknown".
+func Parse(userAgent string) Agent {
+	ua := strings.ToLower(userAgent)
+	agent := Agent{Browser: "Unknown", OS: "Unknown", Device: DeviceOther}
+
+	for _, browser := range browsers {
+		if strings.Contains(ua, browser.token) {
+			agent.Browser = browser.name
+			break
+		}
+	}
+	for _, system := range systems {
+		if strings.Contains(ua, system.token) {
+			agent.OS = system.name
+			break
+		}
+	}
+
+	switch {
+	case ua == "":
+	case strings.Contains(ua, "bot") || strings.Contains(ua, "spider") || strings.Contains(ua, "crawl"):
+		agent.Device = DeviceBot
+	case strings.Contains(ua, "ipad") || strings.Contains(ua, "tablet") ||
+		(strings.Contains(ua, "android") && !strings.Contains(ua, "mobile")):
+		agent.Device = DeviceTablet
+	case strings.Contains(ua, "mobile") || strings.Contains(ua, "iphone") || agent.OS == "iOS" || agent.OS == "Android":
+		agent.Device = DeviceMobile
+	case agent.OS == "Windows" || agent.OS == "macOS" || agent.OS == "Linux" || agent.OS == "ChromeOS":
+		agent.Device = DeviceDesktop
+	}
+	return agent
+}
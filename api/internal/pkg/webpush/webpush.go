@@ -0,0 +1,246 @@
+package webpush
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/hkdf"
+)
+
+// ErrSubscriptionGone is returned when the push service reports that the
+// subscription no longer exists and should be deleted
+var ErrSubscriptionGone = errors.New("push subscription expired")
+
+const (
+	recordSize = 4096
+	// Push services accept at most 4096 bytes of body: 86 bytes of header,
+	// the padding delimiter and the 16 byte AEAD tag leave the rest for payload
+	maxPayloadSize = 4096 - 86 - 1 - 16
+)
+
+// Subscription is a browser PushSubscription
+type Subscription struct {
+	Endpoint string
+	P256dh   string // base64url encoded client public key
+	Auth     string // base64url encoded auth secret
+}
+
+// Options control delivery of a single message
+type Options struct {
+	TTL     time.Duration
+	Urgency string // very-low|low|normal|high
+}
+
+// Client sends Web Push messages signed with a VAPID key
+type Client struct {
+	privateKey *ecdsa.PrivateKey
+	publicKey  []byte
+	subject    string
+	httpClient *http.Client
+}
+
+// NewClient creates a Web Push client from a base64url encoded P-256 private
+// key. subject is a mailto: or https: contact for the push service operator.
+func NewClient(privateKey, subject string) (*Client, error) {
+	raw, err := decodeBase64(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode VAPID private key: %w", err)
+	}
+
+	key, err := ecdh.P256().NewPrivateKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+	publicKey := key.PublicKey().Bytes()
+
+	return &Client{
+		privateKey: &ecdsa.PrivateKey{
+			PublicKey: ecdsa.PublicKey{
+				Curve: elliptic.P256(),
+				X:     new(big.Int).SetBytes(publicKey[1:33]),
+				Y:     new(big.Int).SetBytes(publicKey[33:]),
+			},
+			D: new(big.Int).SetBytes(raw),
+		},
+		publicKey: publicKey,
+		subject:   subject,
+		httpClient: &http.Client{
+			Timeout: 15 * time.Second,
+		},
+	}, nil
+}
+
+// PublicKey returns the VAPID application server key for PushManager.subscribe
+func (c *Client) PublicKey() string {
+	return base64.RawURLEncoding.EncodeToString(c.publicKey)
+}
+
+// Send encrypts payload for the subscription and delivers it to the push service
+func (c *Client) Send(ctx context.Context, sub Subscription, payload []byte, opts Options) error {
+	body, err := encrypt(sub, payload)
+	if err != nil {
+		return err
+	}
+
+	authorization, err := c.vapidAuthorization(sub.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	ttl := opts.TTL
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", strconv.Itoa(int(ttl.Seconds())))
+	req.Header.Set("Authorization", authorization)
+	if opts.Urgency != "" {
+		req.Header.Set("Urgency", opts.Urgency)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send push message: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrSubscriptionGone
+	case resp.StatusCode >= 300:
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("push service error %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	return nil
+}
+
+// vapidAuthorization builds the RFC 8292 Authorization header for the
+// push service that owns endpoint
+func (c *Client) vapidAuthorization(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid push endpoint: %w", err)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": time.Now().Add(12 * time.Hour).Unix(),
+		"sub": c.subject,
+	})
+	signed, err := token.SignedString(c.privateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign VAPID token: %w", err)
+	}
+
+	return fmt.Sprintf("vapid t=%s, k=%s", signed, c.PublicKey()), nil
+}
+
+// encrypt implements the aes128gcm content encoding from RFC 8291 as a single record
+func encrypt(sub Subscription, payload []byte) ([]byte, error) {
+	clientKeyBytes, err := decodeBase64(sub.P256dh)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %w", err)
+	}
+	authSecret, err := decodeBase64(sub.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth secret: %w", err)
+	}
+	if len(payload) > maxPayloadSize {
+		return nil, fmt.Errorf("payload too large: %d bytes", len(payload))
+	}
+
+	clientKey, err := ecdh.P256().NewPublicKey(clientKeyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %w", err)
+	}
+	serverKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	sharedSecret, err := serverKey.ECDH(clientKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive shared secret: %w", err)
+	}
+	serverPublic := serverKey.PublicKey().Bytes()
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	keyInfo := append([]byte("WebPush: info\x00"), clientKeyBytes...)
+	keyInfo = append(keyInfo, serverPublic...)
+	ikm, err := expand(authSecret, sharedSecret, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := expand(salt, ikm, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := expand(salt, ikm, []byte("Content-Encoding: nonce\x00"), 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+
+	// 0x02 marks the last (and only) record
+	plaintext := append(append([]byte{}, payload...), 0x02)
+
+	header := make([]byte, 0, 16+4+1+len(serverPublic))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, recordSize)
+	header = append(header, byte(len(serverPublic)))
+	header = append(header, serverPublic...)
+
+	return gcm.Seal(header, nonce, plaintext, nil), nil
+}
+
+func expand(salt, secret, info []byte, length int) ([]byte, error) {
+	out := make([]byte, length)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, info), out); err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	return out, nil
+}
+
+// decodeBase64 accepts both padded and unpadded base64url, as browsers differ
+func decodeBase64(value string) ([]byte, error) {
+	if decoded, err := base64.RawURLEncoding.DecodeString(value); err == nil {
+		return decoded, nil
+	}
+	return base64.URLEncoding.DecodeString(value)
+}
@@ -0,0 +1,272 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"bailanysta/api/internal/pkg/database"
+	"bailanysta/api/internal/pkg/metrics"
+	"bailanysta/api/internal/pkg/sentry"
+)
+
+// Rate-watched actions, which are also the rules of their abuse flags
+const (
+	AbuseActionFollow = "follow"
+	AbuseActionLike   = "like"
+)
+
+// actionCountRetention is how long per-minute action counts are kept
+const actionCountRetention = time.Hour
+
+var (
+	abuseDetections = metrics.NewCounterVec("abuse_detections_total",
+		"Accounts throttled and flagged for going over an action rate limit, by action.", "action")
+	abuseThrottled = metrics.NewCounterVec("abuse_throttled_actions_total",
+		"Actions rejected because the account is throttled, by action.", "action")
+)
+
+// AbuseLimits are how many of each action an account may take in a minute
+// (0 for no limit) and how long going over throttles it
+type AbuseLimits struct {
+	FollowsPerMinute int
+	LikesPerMinute   int
+	Throttle         time.Duration
+	// BlockIPFor also blocks the IP of the account's latest session; 0 doesn't
+	BlockIPFor time.Duration
+}
+
+// AbuseService catches mass-following and like spam. An account that goes
+// over a per-minute limit is throttled, so the watched actions fail until
+// the throttle lapses or a moderator clears it, and is flagged for review.
+type AbuseService struct {
+	db        *database.Pool
+	limits    AbuseLimits
+	blocklist *IPBlocklistService
+}
+
+type AbuseFlag struct {
+	ID             uuid.UUID  `json:"id"`
+	UserID         uuid.UUID  `json:"user_id"`
+	Username       string     `json:"username"`
+	Rule           string     `json:"rule"`
+	Count          int        `json:"count"` // actions in the minute it was raised
+	ThrottledUntil *time.Time `json:"throttled_until,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type ResolveAbuseFlagRequest struct {
+	// clear lifts the throttle; confirm leaves it to run out
+	Decision string `json:"decision" validate:"required,oneof=clear confirm"`
+}
+
+func NewAbuseService(db *database.Pool, limits AbuseLimits, blocklist *IPBlocklistService) *AbuseService {
+	return &AbuseService{
+		db:        db,
+		limits:    limits,
+		blocklist: blocklist,
+	}
+}
+
+func (s *AbuseService) limit(action string) int {
+	switch action {
+	case AbuseActionFollow:
+		return s.limits.FollowsPerMinute
+	case AbuseActionLike:
+		return s.limits.LikesPerMinute
+	}
+	return 0
+}
+
+// Allow counts an attempt at action by userID and fails with "account is
+// throttled" if the account is throttled, or becomes so by going over the
+// limit. Attempts are counted whether or not the action then succeeds.
+func (s *AbuseService) Allow(ctx context.Context, userID uuid.UUID, action string) error {
+	if s == nil || s.limit(action) == 0 {
+		return nil
+	}
+
+	var throttled bool
+	err := s.db.QueryRow(ctx, `
+		SELECT COALESCE(throttled_until > now(), false) FROM users WHERE id = $1`, userID).Scan(&throttled)
+	if err != nil {
+		return fmt.Errorf("failed to check throttle: %w", err)
+	}
+	if throttled {
+		abuseThrottled.Inc(action)
+		return fmt.Errorf("account is throttled")
+	}
+
+	var count int
+	err = s.db.QueryRow(ctx, `
+		INSERT INTO action_counts (user_id, action, minute, count)
+		VALUES ($1, $2, date_trunc('minute', now()), 1)
+		ON CONFLICT (user_id, action, minute) DO UPDATE SET count = action_counts.count + 1
+		RETURNING count`, userID, action).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to count action: %w", err)
+	}
+	if count <= s.limit(action) {
+		return nil
+	}
+
+	if err := s.throttle(ctx, userID, action, count); err != nil {
+		return err
+	}
+	return fmt.Errorf("account is throttled")
+}
+
+// throttle throttles userID and flags them, unless a concurrent attempt
+// already did
+func (s *AbuseService) throttle(ctx context.Context, userID uuid.UUID, action string, count int) error {
+	var flagged bool
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			UPDATE users SET throttled_until = now() + make_interval(secs => $2::float8)
+			WHERE id = $1 AND (throttled_until IS NULL OR throttled_until <= now())`,
+			userID, s.limits.Throttle.Seconds())
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			return nil
+		}
+		flagged = true
+		_, err = tx.Exec(ctx, `
+			INSERT INTO abuse_flags (user_id, rule, count) VALUES ($1, $2, $3)`, userID, action, count)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to throttle account: %w", err)
+	}
+
+	if flagged {
+		abuseDetections.Inc(action)
+		fmt.Printf("Abuse: throttled user %s for %v after %d %s actions in a minute\n", userID, s.limits.Throttle, count, action)
+		s.blockSessionIP(ctx, userID, action)
+	}
+	return nil
+}
+
+// blockSessionIP blocks the IP userID last signed in or refreshed from for
+// BlockIPFor, if that is set
+func (s *AbuseService) blockSessionIP(ctx context.Context, userID uuid.UUID, action string) {
+	if s.blocklist == nil || s.limits.BlockIPFor <= 0 {
+		return
+	}
+	var ip string
+	err := s.db.QueryRow(ctx, `
+		SELECT ip_address FROM refresh_tokens
+		WHERE user_id = $1 AND ip_address <> ''
+		ORDER BY created_at DESC
+		LIMIT 1`, userID).Scan(&ip)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return
+	}
+	if err != nil {
+		fmt.Printf("Failed to get session IP for abuse block: %v\n", err)
+		return
+	}
+	reason := fmt.Sprintf("%s rate limit exceeded by user %s", action, userID)
+	if err := s.blocklist.BlockTemporarily(ctx, ip, reason, s.limits.BlockIPFor); err != nil {
+		fmt.Printf("Failed to block IP %s: %v\n", ip, err)
+		return
+	}
+	fmt.Printf("Abuse: blocked IP %s for %v\n", ip, s.limits.BlockIPFor)
+}
+
+// GetOpenFlags lists the unresolved abuse flags of orgID's users, oldest
+// first
+func (s *AbuseService) GetOpenFlags(ctx context.Context, orgID uuid.UUID, limit, offset int) ([]*AbuseFlag, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT f.id, f.user_id, u.username, f.rule, f.count, u.throttled_until, f.created_at
+		FROM abuse_flags f
+		JOIN users u ON u.id = f.user_id
+		WHERE f.resolved_at IS NULL AND u.org_id = $1
+		ORDER BY f.created_at
+		LIMIT $2 OFFSET $3`, orgID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get abuse flags: %w", err)
+	}
+	defer rows.Close()
+
+	flags := []*AbuseFlag{}
+	for rows.Next() {
+		var flag AbuseFlag
+		err := rows.Scan(&flag.ID, &flag.UserID, &flag.Username, &flag.Rule, &flag.Count, &flag.ThrottledUntil, &flag.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan abuse flag: %w", err)
+		}
+		flags = append(flags, &flag)
+	}
+	return flags, rows.Err()
+}
+
+// ResolveFlag closes a flag on one of orgID's users and every other open
+// flag on the same account. Clearing lifts the account's throttle.
+func (s *AbuseService) ResolveFlag(ctx context.Context, orgID, moderatorID, flagID uuid.UUID, decision string) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		var userID uuid.UUID
+		err := tx.QueryRow(ctx, `
+			SELECT f.user_id FROM abuse_flags f
+			JOIN users u ON u.id = f.user_id
+			WHERE f.id = $1 AND f.resolved_at IS NULL AND u.org_id = $2
+			FOR UPDATE OF f`, flagID, orgID).Scan(&userID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("abuse flag not found")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get abuse flag: %w", err)
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE abuse_flags
+			SET resolved_at = now(), resolved_by = $2, decision = $3
+			WHERE user_id = $1 AND resolved_at IS NULL`, userID, moderatorID, decision)
+		if err != nil {
+			return fmt.Errorf("failed to resolve abuse flag: %w", err)
+		}
+
+		if decision == "clear" {
+			if _, err := tx.Exec(ctx, `UPDATE users SET throttled_until = NULL WHERE id = $1`, userID); err != nil {
+				return fmt.Errorf("failed to lift throttle: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// PurgeCounts deletes action counts past actionCountRetention
+func (s *AbuseService) PurgeCounts(ctx context.Context) (int64, error) {
+	result, err := s.db.Exec(ctx, `
+		DELETE FROM action_counts WHERE minute < now() - make_interval(secs => $1::float8)`,
+		actionCountRetention.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge action counts: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+// Run purges old action counts every retention period until ctx is
+// cancelled
+func (s *AbuseService) Run(ctx context.Context) {
+	ticker := time.NewTicker(actionCountRetention)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.PurgeCounts(ctx); err != nil {
+			fmt.Printf("Failed to purge action counts: %v\n", err)
+			sentry.CaptureError(err, map[string]string{"job": "abuse-cleanup"})
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAbuseServiceLimit(t *testing.T) {
+	s := NewAbuseService(nil, AbuseLimits{FollowsPerMinute: 30, LikesPerMinute: 60}, nil)
+
+	assert.Equal(t, 30, s.limit(AbuseActionFollow))
+	assert.Equal(t, 60, s.limit(AbuseActionLike))
+	assert.Equal(t, 0, s.limit("comment"))
+}
+
+func TestAbuseServiceAllowWithoutLimits(t *testing.T) {
+	// Neither a nil service nor a zero limit touches the database
+	var disabled *AbuseService
+	assert.NoError(t, disabled.Allow(context.Background(), uuid.New(), AbuseActionLike))
+
+	s := NewAbuseService(nil, AbuseLimits{FollowsPerMinute: 30}, nil)
+	assert.NoError(t, s.Allow(context.Background(), uuid.New(), AbuseActionLike))
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Account deletion modes
+const (
+	// AccountDeletionDelete removes the account and everything it wrote
+	AccountDeletionDelete = "delete"
+	// AccountDeletionAnonymize keeps posts and comments under a placeholder
+	// author and scrubs everything that identifies the user
+	AccountDeletionAnonymize = "anonymize"
+)
+
+// DeletedUsernamePrefix starts the username of every anonymized account
+const DeletedUsernamePrefix = "deleted-"
+
+type DeleteAccountRequest struct {
+	Password string `json:"password" validate:"required"`
+	Mode     string `json:"mode" validate:"required,oneof=delete anonymize"`
+}
+
+// personalTables are deleted outright when an account is anonymized, by
+// their column referencing the user
+var personalTables = []struct{ table, column string }{
+	{"refresh_tokens", "user_id"},
+	{"api_keys", "user_id"},
+	{"user_devices", "user_id"},
+	{"push_subscriptions", "user_id"},
+	{"notification_preferences", "user_id"},
+	{"notifications", "user_id"},
+	{"notifications_archive", "user_id"},
+	{"username_history", "user_id"},
+	{"recovery_codes", "user_id"},
+	{"recovery_tokens", "user_id"},
+	{"account_recovery_events", "user_id"},
+	{"follows", "follower_id"},
+	{"follows", "followee_id"},
+	{"follow_requests", "requester_id"},
+	{"follow_requests", "target_id"},
+	{"user_blocks", "blocker_id"},
+	{"user_blocks", "blocked_id"},
+	{"profile_views", "profile_id"},
+	{"profile_views", "viewer_id"},
+	{"post_impressions", "viewer_id"},
+	{"user_activity", "user_id"},
+	{"group_members", "user_id"},
+	{"action_counts", "user_id"},
+}
+
+// DeleteAccount deletes or anonymizes userID's account after checking
+// their password. Either way every session ends.
+func (s *AuthService) DeleteAccount(ctx context.Context, userID uuid.UUID, req DeleteAccountRequest) error {
+	var passwordHash string
+	err := s.db.QueryRow(ctx, `SELECT password_hash FROM users WHERE id = $1 AND deleted_at IS NULL`, userID).Scan(&passwordHash)
+	if err != nil {
+		return fmt.Errorf("user not found")
+	}
+	if !checkPasswordHash(req.Password, passwordHash) {
+		return fmt.Errorf("invalid password")
+	}
+
+	switch req.Mode {
+	case AccountDeletionDelete:
+		if _, err := s.db.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID); err != nil {
+			return fmt.Errorf("failed to delete account: %w", err)
+		}
+		return nil
+	case AccountDeletionAnonymize:
+		if err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error { return anonymizeUser(ctx, tx, userID) }); err != nil {
+			return fmt.Errorf("failed to anonymize account: %w", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("invalid deletion mode")
+}
+
+// anonymizeUser turns the user into a placeholder: the row stays so their
+// posts, comments and likes keep their place, but it can't sign in and
+// nothing on it or linked to it identifies the person
+func anonymizeUser(ctx context.Context, tx pgx.Tx, userID uuid.UUID) error {
+	// An empty hash never matches a password
+	_, err := tx.Exec(ctx, `
+		UPDATE users SET
+			username = $2 || replace(id::text, '-', ''),
+			email = 'deleted-' || id::text || '@deleted.invalid',
+			password_hash = '',
+			bio = '',
+			avatar_url = NULL,
+			backup_email = NULL,
+			backup_email_verified_at = NULL,
+			is_private = false,
+			show_presence = false,
+			last_active_at = NULL,
+			profile_views_enabled = false,
+			share_profile_views = false,
+			email_digest = 'off',
+			feed_languages = '{}',
+			timezone = 'UTC',
+			deleted_at = now()
+		WHERE id = $1`, userID, DeletedUsernamePrefix)
+	if err != nil {
+		return err
+	}
+
+	for _, personal := range personalTables {
+		_, err := tx.Exec(ctx, `DELETE FROM `+personal.table+` WHERE `+personal.column+` = $1`, userID)
+		if err != nil {
+			return fmt.Errorf("failed to clear %s: %w", personal.table, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"bailanysta/api/internal/pkg/database"
+	"bailanysta/api/internal/pkg/sentry"
+)
+
+// ActivityKind is a kind of activity that counts towards streaks
+type ActivityKind string
+
+const (
+	ActivityPost    ActivityKind = "post"
+	ActivityComment ActivityKind = "comment"
+	ActivityQuiz    ActivityKind = "quiz"
+)
+
+const (
+	activityCalendarDays   = 365
+	streakReminderInterval = 15 * time.Minute
+	streakReminderMinDays  = 2 // no reminders for a streak of a single day
+)
+
+// activityColumns maps each kind to its user_activity counter
+var activityColumns = map[ActivityKind]string{
+	ActivityPost:    "posts",
+	ActivityComment: "comments",
+	ActivityQuiz:    "quizzes",
+}
+
+// ActivityService builds activity calendars and streaks from user_activity
+// and reminds users whose streak is about to break
+type ActivityService struct {
+	db                   *database.Pool
+	notificationsService *NotificationService
+	reminderHour         int
+}
+
+type ActivityDay struct {
+	Date     string `json:"date"` // YYYY-MM-DD in the user's time zone
+	Posts    int    `json:"posts"`
+	Comments int    `json:"comments"`
+	Quizzes  int    `json:"quizzes"`
+	Count    int    `json:"count"`
+}
+
+// ActivitySummary is a contribution calendar of the last year, listing only
+// active days, plus streak stats over the user's whole history
+type ActivitySummary struct {
+	From          string         `json:"from"`
+	To            string         `json:"to"`
+	Days          []*ActivityDay `json:"days"`
+	ActiveDays    int            `json:"active_days"`
+	CurrentStreak int            `json:"current_streak"`
+	LongestStreak int            `json:"longest_streak"`
+}
+
+// execer is satisfied by pools and transactions
+type execer interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+func NewActivityService(db *database.Pool, notificationsService *NotificationService, reminderHour int) *ActivityService {
+	return &ActivityService{
+		db:                   db,
+		notificationsService: notificationsService,
+		reminderHour:         reminderHour,
+	}
+}
+
+// recordActivity counts one activity of kind for userID on today's date in
+// their time zone
+func recordActivity(ctx context.Context, db execer, userID uuid.UUID, kind ActivityKind) error {
+	column, ok := activityColumns[kind]
+	if !ok {
+		return fmt.Errorf("unknown activity kind %q", kind)
+	}
+
+	_, err := db.Exec(ctx, `
+		INSERT INTO user_activity (user_id, day, `+column+`)
+		VALUES ($1, (now() AT TIME ZONE (SELECT timezone FROM users WHERE id = $1))::date, 1)
+		ON CONFLICT (user_id, day) DO UPDATE SET `+column+` = user_activity.`+column+` + 1`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to record activity: %w", err)
+	}
+	return nil
+}
+
+// GetActivity returns the user's activity calendar and streaks
+func (s *ActivityService) GetActivity(ctx context.Context, userID uuid.UUID) (*ActivitySummary, error) {
+	var timezone string
+	err := s.db.Reader().QueryRow(ctx, `SELECT timezone FROM users WHERE id = $1`, userID).Scan(&timezone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get activity: %w", err)
+	}
+	today := localDate(time.Now(), userLocation(timezone))
+	from := today.AddDate(0, 0, -(activityCalendarDays - 1))
+
+	summary := &ActivitySummary{
+		From: from.Format("2006-01-02"),
+		To:   today.Format("2006-01-02"),
+		Days: []*ActivityDay{},
+	}
+
+	rows, err := s.db.Reader().Query(ctx, `
+		SELECT day, posts, comments, quizzes
+		FROM user_activity
+		WHERE user_id = $1
+		ORDER BY day`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get activity: %w", err)
+	}
+	defer rows.Close()
+
+	var activeDays []time.Time
+	for rows.Next() {
+		var day time.Time
+		var activity ActivityDay
+		if err := rows.Scan(&day, &activity.Posts, &activity.Comments, &activity.Quizzes); err != nil {
+			return nil, fmt.Errorf("failed to scan activity: %w", err)
+		}
+		activity.Count = activity.Posts + activity.Comments + activity.Quizzes
+		if activity.Count == 0 {
+			continue
+		}
+		activeDays = append(activeDays, day)
+
+		if !day.Before(from) && !day.After(today) {
+			activity.Date = day.Format("2006-01-02")
+			summary.Days = append(summary.Days, &activity)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get activity: %w", err)
+	}
+
+	summary.ActiveDays = len(summary.Days)
+	summary.CurrentStreak, summary.LongestStreak = computeStreaks(activeDays, today)
+	return summary, nil
+}
+
+// computeStreaks returns the current and longest runs of consecutive days in
+// days, which must be sorted and distinct. The current streak still counts
+// while today has no activity yet, as long as yesterday had some.
+func computeStreaks(days []time.Time, today time.Time) (current, longest int) {
+	run := 0
+	var previous time.Time
+	for i, day := range days {
+		if i > 0 && day.Sub(previous) == 24*time.Hour {
+			run++
+		} else {
+			run = 1
+		}
+		longest = max(longest, run)
+		previous = day
+	}
+
+	if len(days) > 0 {
+		if gap := today.Sub(previous); gap >= 0 && gap <= 24*time.Hour {
+			current = run
+		}
+	}
+	return current, longest
+}
+
+// Run sends due streak reminders every streakReminderInterval until ctx is
+// cancelled
+func (s *ActivityService) Run(ctx context.Context) {
+	ticker := time.NewTicker(streakReminderInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.SendStreakReminders(ctx); err != nil {
+			fmt.Printf("Failed to send streak reminders: %v\n", err)
+			sentry.CaptureError(err, map[string]string{"job": "streak-reminders"})
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendStreakReminders notifies users who were active yesterday but not yet
+// today, once it is past the reminder hour in their time zone, at most once
+// a day each
+func (s *ActivityService) SendStreakReminders(ctx context.Context) error {
+	rows, err := s.db.Query(ctx, `
+		WITH local AS (
+		    SELECT id, (now() AT TIME ZONE timezone)::date AS today
+		    FROM users
+		    WHERE EXTRACT(HOUR FROM now() AT TIME ZONE timezone) >= $1
+		)
+		UPDATE users u SET streak_reminded_on = local.today
+		FROM local
+		WHERE u.id = local.id
+		  AND (u.streak_reminded_on IS NULL OR u.streak_reminded_on < local.today)
+		  AND EXISTS(SELECT 1 FROM user_activity a WHERE a.user_id = u.id AND a.day = local.today - 1)
+		  AND NOT EXISTS(SELECT 1 FROM user_activity a WHERE a.user_id = u.id AND a.day = local.today)
+		RETURNING u.id, local.today`, s.reminderHour)
+	if err != nil {
+		return fmt.Errorf("failed to claim streak reminders: %w", err)
+	}
+	type reminder struct {
+		userID uuid.UUID
+		today  time.Time
+	}
+	reminders, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (reminder, error) {
+		var r reminder
+		err := row.Scan(&r.userID, &r.today)
+		return r, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to claim streak reminders: %w", err)
+	}
+
+	for _, r := range reminders {
+		userID, today := r.userID, r.today
+		rows, err := s.db.Query(ctx, `
+			SELECT day FROM user_activity WHERE user_id = $1 ORDER BY day`, userID)
+		if err != nil {
+			return fmt.Errorf("failed to get activity: %w", err)
+		}
+		days, err := pgx.CollectRows(rows, pgx.RowTo[time.Time])
+		if err != nil {
+			return fmt.Errorf("failed to get activity: %w", err)
+		}
+
+		streak, _ := computeStreaks(days, today)
+		if streak < streakReminderMinDays {
+			continue
+		}
+		if err := s.notificationsService.NotifyStreakRisk(ctx, userID, streak); err != nil {
+			// Log error but continue with other reminders
+			fmt.Printf("Failed to create streak reminder for user %s: %v\n", userID, err)
+		}
+	}
+	return nil
+}
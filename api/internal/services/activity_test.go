@@ -0,0 +1,38 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputeStreaks(t *testing.T) {
+	today := time.Date(2026, 5, 20, 0, 0, 0, 0, time.UTC)
+	day := func(offset int) time.Time {
+		return today.AddDate(0, 0, offset)
+	}
+
+	tests := []struct {
+		name    string
+		days    []time.Time
+		current int
+		longest int
+	}{
+		{"no activity", nil, 0, 0},
+		{"active today only", []time.Time{day(0)}, 1, 1},
+		{"streak ending today", []time.Time{day(-2), day(-1), day(0)}, 3, 3},
+		{"streak ending yesterday is still current", []time.Time{day(-3), day(-2), day(-1)}, 3, 3},
+		{"streak broken two days ago", []time.Time{day(-4), day(-3), day(-2)}, 0, 3},
+		{"longest streak in the past", []time.Time{day(-10), day(-9), day(-8), day(-7), day(-1), day(0)}, 2, 4},
+		{"gaps reset the run", []time.Time{day(-6), day(-4), day(-2), day(0)}, 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current, longest := computeStreaks(tt.days, today)
+			assert.Equal(t, tt.current, current)
+			assert.Equal(t, tt.longest, longest)
+		})
+	}
+}
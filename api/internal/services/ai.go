@@ -4,12 +4,22 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
 
 	"bailanysta/api/internal/pkg/ai"
+	"bailanysta/api/internal/pkg/i18n"
 )
 
 type AIService struct {
-	client *ai.Client
+	client       *ai.Client
+	db           *pgxpool.Pool
+	cacheTTL     time.Duration
+	limits       atomic.Pointer[AILimits]
+	blockedWords []string // normalized; completions containing them are blocked
 }
 
 type GenerateTextRequest struct {
@@ -20,13 +30,13 @@ type GenerateTextRequest struct {
 }
 
 type GenerateTextResponse struct {
-	Text  string `json:"text"`
-	Model string `json:"model"`
-	Usage struct {
-		PromptTokens     int `json:"prompt_tokens"`
-		CompletionTokens int `json:"completion_tokens"`
-		TotalTokens      int `json:"total_tokens"`
-	} `json:"usage,omitempty"`
+	Text   string   `json:"text"`
+	Model  string   `json:"model"`
+	Usage  ai.Usage `json:"usage"`
+	Cached bool     `json:"cached"`
+	// GenerationID is set for generated posts; passing it as ai_generation_id
+	// when creating the post labels it as AI-generated
+	GenerationID *uuid.UUID `json:"generation_id,omitempty"`
 }
 
 type GeneratePostRequest struct {
@@ -43,60 +53,99 @@ type GenerateCommentRequest struct {
 	MaxTokens   int    `json:"max_tokens,omitempty"`
 }
 
-func NewAIService(client *ai.Client) *AIService {
-	return &AIService{client: client}
+type RewriteTextRequest struct {
+	Text           string `json:"text" validate:"required,min=1,max=5000"`
+	Mode           string `json:"mode" validate:"required,oneof=improve shorten expand translate grammar"`
+	TargetLanguage string `json:"target_language,omitempty" validate:"required_if=Mode translate,omitempty,oneof=kk ru en"`
 }
 
-func (s *AIService) GenerateText(ctx context.Context, req GenerateTextRequest) (*GenerateTextResponse, error) {
-	// Enhance prompt with context if provided
-	prompt := req.Prompt
-	if req.Context != "" {
-		prompt = fmt.Sprintf("Context: %s\n\nRequest: %s", req.Context, req.Prompt)
+func NewAIService(client *ai.Client, db *pgxpool.Pool, cacheTTL time.Duration, limits AILimits, blockedWords []string) *AIService {
+	s := &AIService{
+		client:   client,
+		db:       db,
+		cacheTTL: cacheTTL,
+	}
+	for _, word := range blockedWords {
+		if normalized := strings.TrimSpace(normalizeWords(word)); normalized != "" {
+			s.blockedWords = append(s.blockedWords, normalized)
+		}
 	}
+	s.SetLimits(limits)
+	return s
+}
 
-	// Set default values
-	maxTokens := req.MaxTokens
-	if maxTokens <= 0 {
-		maxTokens = 500
+// SetLimits replaces the completion limits; completions already running keep
+// theirs
+func (s *AIService) SetLimits(limits AILimits) {
+	s.limits.Store(&limits)
+}
+
+// complete runs a completion for feature within the completion timeout,
+// counting its tokens towards the request's AI usage meter. Completions the
+// output filter rejects fail with ErrAIResponseBlocked.
+func (s *AIService) complete(ctx context.Context, feature, prompt string, maxTokens int, temperature float32) (*ai.Completion, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.limits.Load().Timeout)
+	defer cancel()
+
+	completion, err := s.client.GenerateText(ctx, prompt, maxTokens, temperature)
+	if err != nil {
+		return nil, err
 	}
-	if maxTokens > 4000 {
-		maxTokens = 4000
+	meterAIUsage(ctx, completion.Usage)
+	if err := s.screenOutput(ctx, feature, completion.Text); err != nil {
+		return nil, err
 	}
+	return completion, nil
+}
 
-	temperature := req.Temperature
-	if temperature <= 0 {
-		temperature = 0.7
+// GenerateText completes a prompt from the client, holding its length and
+// temperature to the text limits
+func (s *AIService) GenerateText(ctx context.Context, req GenerateTextRequest) (*GenerateTextResponse, error) {
+	limits := s.limits.Load().Text
+	req.MaxTokens = limits.tokens(req.MaxTokens)
+	if req.Temperature <= 0 {
+		req.Temperature = limits.Temperature
 	}
-	if temperature > 2.0 {
-		temperature = 2.0
+	if req.Temperature > 2.0 {
+		req.Temperature = 2.0
 	}
+	// The prompt is the user's own request, so it isn't quoted
+	s.screenInput(ctx, AIFeatureText, req.Prompt, req.Context)
+	return s.generate(ctx, AIFeatureText, req)
+}
 
-	text, err := s.client.GenerateText(ctx, prompt, maxTokens, temperature)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate text: %w", err)
+// generate completes req as given
+func (s *AIService) generate(ctx context.Context, feature string, req GenerateTextRequest) (*GenerateTextResponse, error) {
+	// Enhance prompt with context if provided
+	prompt := req.Prompt
+	if req.Context != "" {
+		prompt = fmt.Sprintf("Context: %s\n\nRequest: %s", req.Context, req.Prompt)
 	}
 
-	response := &GenerateTextResponse{
-		Text:  strings.TrimSpace(text),
-		Model: "openai/gpt-oss-120b",
+	completion, err := s.complete(ctx, feature, prompt, req.MaxTokens, req.Temperature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate text: %w", err)
 	}
 
-	return response, nil
+	return newTextResponse(completion), nil
 }
 
-func (s *AIService) GeneratePost(ctx context.Context, req GeneratePostRequest) (*GenerateTextResponse, error) {
+func (s *AIService) GeneratePost(ctx context.Context, userID uuid.UUID, req GeneratePostRequest) (*GenerateTextResponse, error) {
+	s.screenInput(ctx, AIFeaturePost, req.Topic, req.Course, req.Module)
+
 	// Build enhanced prompt for post generation
 	var promptBuilder strings.Builder
 
+	promptBuilder.WriteString(untrustedInstruction)
 	promptBuilder.WriteString("Create an educational post about: ")
-	promptBuilder.WriteString(req.Topic)
+	promptBuilder.WriteString(quoteUntrustedLine(req.Topic))
 	promptBuilder.WriteString("\n\n")
 
 	if req.Course != "" {
-		promptBuilder.WriteString(fmt.Sprintf("Course: %s\n", req.Course))
+		promptBuilder.WriteString(fmt.Sprintf("Course: %s\n", quoteUntrustedLine(req.Course)))
 	}
 	if req.Module != "" {
-		promptBuilder.WriteString(fmt.Sprintf("Module: %s\n", req.Module))
+		promptBuilder.WriteString(fmt.Sprintf("Module: %s\n", quoteUntrustedLine(req.Module)))
 	}
 
 	// Style instructions
@@ -117,32 +166,37 @@ func (s *AIService) GeneratePost(ctx context.Context, req GeneratePostRequest) (
 	}
 
 	promptBuilder.WriteString("\nMake the post comprehensive but concise. Include relevant hashtags at the end.")
+	promptBuilder.WriteString(languageInstruction(ctx))
 	promptBuilder.WriteString("\n\nWrite the post content:")
 
-	maxTokens := req.MaxTokens
-	if maxTokens <= 0 {
-		maxTokens = 800
-	}
-
-	text, err := s.client.GenerateText(ctx, promptBuilder.String(), maxTokens, 0.7)
+	limits := s.limits.Load().Post
+	completion, err := s.complete(ctx, AIFeaturePost, promptBuilder.String(), limits.tokens(req.MaxTokens), limits.Temperature)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate post: %w", err)
 	}
 
-	response := &GenerateTextResponse{
-		Text:  strings.TrimSpace(text),
-		Model: "openai/gpt-oss-120b",
+	var generationID uuid.UUID
+	err = s.db.QueryRow(ctx, `
+		INSERT INTO ai_post_generations (user_id) VALUES ($1)
+		RETURNING id`, userID).Scan(&generationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record post generation: %w", err)
 	}
 
+	response := newTextResponse(completion)
+	response.GenerationID = &generationID
 	return response, nil
 }
 
 func (s *AIService) GenerateComment(ctx context.Context, req GenerateCommentRequest) (*GenerateTextResponse, error) {
+	s.screenInput(ctx, AIFeatureComment, req.PostContent)
+
 	// Build prompt for comment generation
 	var promptBuilder strings.Builder
 
+	promptBuilder.WriteString(untrustedInstruction)
 	promptBuilder.WriteString("Write a thoughtful comment for this post:\n\n")
-	promptBuilder.WriteString(fmt.Sprintf("Post content: %s\n\n", req.PostContent))
+	promptBuilder.WriteString(fmt.Sprintf("Post content: %s\n\n", quoteUntrusted(req.PostContent)))
 
 	// Style instructions
 	style := req.Style
@@ -163,68 +217,179 @@ func (s *AIService) GenerateComment(ctx context.Context, req GenerateCommentRequ
 
 	promptBuilder.WriteString("\nKeep the comment concise and natural. Write the comment:")
 
-	maxTokens := req.MaxTokens
-	if maxTokens <= 0 {
-		maxTokens = 200
-	}
-
-	text, err := s.client.GenerateText(ctx, promptBuilder.String(), maxTokens, 0.8)
+	limits := s.limits.Load().Comment
+	completion, err := s.complete(ctx, AIFeatureComment, promptBuilder.String(), limits.tokens(req.MaxTokens), limits.Temperature)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate comment: %w", err)
 	}
 
-	response := &GenerateTextResponse{
-		Text:  strings.TrimSpace(text),
-		Model: "openai/gpt-oss-120b",
+	return newTextResponse(completion), nil
+}
+
+func (s *AIService) RewriteText(ctx context.Context, req RewriteTextRequest) (*GenerateTextResponse, error) {
+	s.screenInput(ctx, AIFeatureRewrite, req.Text)
+
+	var promptBuilder strings.Builder
+	promptBuilder.WriteString(untrustedInstruction)
+
+	// Modes that must stay close to the text run cooler than the default
+	limits := s.limits.Load().Rewrite
+	temperature := limits.Temperature
+
+	switch req.Mode {
+	case "improve":
+		promptBuilder.WriteString("Rewrite the following post to improve clarity and flow. Keep the original meaning, tone and language.\n")
+	case "shorten":
+		promptBuilder.WriteString("Shorten the following post while keeping its key points. Keep the original tone and language.\n")
+	case "expand":
+		promptBuilder.WriteString("Expand the following post with more detail, explanations and examples. Keep the original tone and language.\n")
+		temperature = 0.7
+	case "translate":
+		language := i18n.Name(req.TargetLanguage)
+		if language == "" {
+			return nil, fmt.Errorf("unsupported target language: %s", req.TargetLanguage)
+		}
+		promptBuilder.WriteString(fmt.Sprintf("Translate the following post into %s. Preserve formatting, hashtags and mentions.\n", language))
+		temperature = 0.3
+	case "grammar":
+		promptBuilder.WriteString("Fix grammar, spelling and punctuation in the following post. Change nothing else.\n")
+		temperature = 0.2
+	default:
+		return nil, fmt.Errorf("unsupported rewrite mode: %s", req.Mode)
+	}
+
+	promptBuilder.WriteString("Return only the rewritten text without any commentary.\n\n")
+	promptBuilder.WriteString("Post:\n")
+	promptBuilder.WriteString(quoteUntrusted(req.Text))
+
+	completion, err := s.complete(ctx, AIFeatureRewrite, promptBuilder.String(), rewriteTokens(limits, req.Mode, req.Text), temperature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rewrite text: %w", err)
 	}
 
-	return response, nil
+	return newTextResponse(completion), nil
+}
+
+// languageInstruction asks for output in the request's language. English,
+// the default, needs no instruction, which keeps existing cache keys valid.
+func languageInstruction(ctx context.Context) string {
+	lang := i18n.FromContext(ctx)
+	if lang == i18n.Default {
+		return ""
+	}
+	return fmt.Sprintf(" Write the response in %s.", i18n.Name(lang))
 }
 
 func (s *AIService) ValidateConnection(ctx context.Context) error {
 	return s.client.ValidateConnection(ctx)
 }
 
+func newTextResponse(completion *ai.Completion) *GenerateTextResponse {
+	return &GenerateTextResponse{
+		Text:  strings.TrimSpace(completion.Text),
+		Model: completion.Model,
+		Usage: completion.Usage,
+	}
+}
+
 // Helper methods for specific use cases
 
-func (s *AIService) GenerateStudyNotes(ctx context.Context, topic, course string) (*GenerateTextResponse, error) {
-	prompt := fmt.Sprintf("Create comprehensive study notes about '%s'", topic)
+func (s *AIService) GenerateStudyNotes(ctx context.Context, topic, course string, refresh bool) (*GenerateTextResponse, error) {
+	s.screenInput(ctx, AIFeatureStudyNotes, topic, course)
+
+	prompt := untrustedInstruction + fmt.Sprintf("Create comprehensive study notes about %s", quoteUntrustedLine(topic))
 	if course != "" {
-		prompt += fmt.Sprintf(" for the course '%s'", course)
+		prompt += fmt.Sprintf(" for the course %s", quoteUntrustedLine(course))
 	}
 	prompt += ". Include key concepts, definitions, important points to remember, examples, and detailed explanations. Format as markdown with headers and lists."
+	prompt += languageInstruction(ctx)
 
-	return s.GenerateText(ctx, GenerateTextRequest{
+	limits := s.limits.Load().StudyNotes
+	return s.generateCached(ctx, AIFeatureStudyNotes, GenerateTextRequest{
 		Prompt:      prompt,
-		MaxTokens:   2500,
-		Temperature: 0.3, // Lower temperature for more factual content
-	})
+		MaxTokens:   limits.MaxTokens,
+		Temperature: limits.Temperature,
+	}, refresh)
 }
 
-func (s *AIService) GenerateQuiz(ctx context.Context, topic, course string) (*GenerateTextResponse, error) {
-	prompt := fmt.Sprintf("Create a 5-question quiz about '%s'", topic)
+// GenerateQuiz creates a quiz for userID; each quiz counts as activity
+// towards the user's streak
+func (s *AIService) GenerateQuiz(ctx context.Context, userID uuid.UUID, topic, course string) (*GenerateTextResponse, error) {
+	s.screenInput(ctx, AIFeatureQuiz, topic, course)
+
+	prompt := untrustedInstruction + fmt.Sprintf("Create a 5-question quiz about %s", quoteUntrustedLine(topic))
 	if course != "" {
-		prompt += fmt.Sprintf(" from the course '%s'", course)
+		prompt += fmt.Sprintf(" from the course %s", quoteUntrustedLine(course))
 	}
 	prompt += ". Include multiple choice questions with 4 options each and indicate the correct answers."
+	prompt += languageInstruction(ctx)
 
-	return s.GenerateText(ctx, GenerateTextRequest{
+	limits := s.limits.Load().Quiz
+	response, err := s.generate(ctx, AIFeatureQuiz, GenerateTextRequest{
 		Prompt:      prompt,
-		MaxTokens:   600,
-		Temperature: 0.5,
+		MaxTokens:   limits.MaxTokens,
+		Temperature: limits.Temperature,
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordActivity(ctx, s.db, userID, ActivityQuiz); err != nil {
+		fmt.Printf("Failed to record quiz activity for user %s: %v\n", userID, err)
+	}
+	return response, nil
 }
 
-func (s *AIService) ExplainConcept(ctx context.Context, concept, context string) (*GenerateTextResponse, error) {
-	prompt := fmt.Sprintf("Provide a detailed explanation of the concept '%s'", concept)
+func (s *AIService) ExplainConcept(ctx context.Context, concept, context string, refresh bool) (*GenerateTextResponse, error) {
+	s.screenInput(ctx, AIFeatureExplain, concept, context)
+
+	prompt := untrustedInstruction + fmt.Sprintf("Provide a detailed explanation of the concept %s", quoteUntrustedLine(concept))
 	if context != "" {
-		prompt += fmt.Sprintf(" in the context of %s", context)
+		prompt += fmt.Sprintf(" in the context of %s", quoteUntrustedLine(context))
 	}
 	prompt += ". Include: 1) Clear definition, 2) Key characteristics, 3) Practical examples, 4) How it works, 5) Why it's important. Use simple language but be comprehensive. Format as markdown with headers."
+	prompt += languageInstruction(ctx)
 
-	return s.GenerateText(ctx, GenerateTextRequest{
+	limits := s.limits.Load().Explain
+	return s.generateCached(ctx, AIFeatureExplain, GenerateTextRequest{
 		Prompt:      prompt,
-		MaxTokens:   2000,
-		Temperature: 0.6,
-	})
+		MaxTokens:   limits.MaxTokens,
+		Temperature: limits.Temperature,
+	}, refresh)
+}
+
+// ExplainPost explains a post in simpler terms, using its course and module
+// as context. The cache is keyed by the prompt, so an edited post gets a
+// fresh explanation.
+func (s *AIService) ExplainPost(ctx context.Context, post *Post, refresh bool) (*GenerateTextResponse, error) {
+	var course, module string
+	if post.CourseID != nil || post.ModuleID != nil {
+		err := s.db.QueryRow(ctx, `
+			SELECT COALESCE((SELECT title FROM courses WHERE id = $1), ''),
+			       COALESCE((SELECT title FROM modules WHERE id = $2), '')`,
+			post.CourseID, post.ModuleID).Scan(&course, &module)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get post course: %w", err)
+		}
+	}
+
+	s.screenInput(ctx, AIFeatureExplainPost, post.Text)
+
+	prompt := untrustedInstruction + "A student found the following post hard to follow. Explain what it says in simple terms"
+	if course != "" {
+		prompt += fmt.Sprintf(" for a student of the course %s", quoteUntrustedLine(course))
+		if module != "" {
+			prompt += fmt.Sprintf(", module %s", quoteUntrustedLine(module))
+		}
+	}
+	prompt += ". Define any terms it uses, give a short example where it helps and don't add claims the post doesn't make. Format as markdown."
+	prompt += languageInstruction(ctx)
+	prompt += "\n\nPost:\n" + quoteUntrusted(post.Text)
+
+	limits := s.limits.Load().Explain
+	return s.generateCached(ctx, AIFeatureExplainPost, GenerateTextRequest{
+		Prompt:      prompt,
+		MaxTokens:   limits.MaxTokens,
+		Temperature: limits.Temperature,
+	}, refresh)
 }
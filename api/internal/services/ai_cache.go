@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"bailanysta/api/internal/pkg/ai"
+	"bailanysta/api/internal/pkg/sentry"
+)
+
+const aiCachePurgeInterval = time.Hour
+
+// generateCached serves identical prompts from ai_response_cache while the
+// entry is fresh. refresh forces a new generation and overwrites the entry.
+func (s *AIService) generateCached(ctx context.Context, feature string, req GenerateTextRequest, refresh bool) (*GenerateTextResponse, error) {
+	if s.db == nil || s.cacheTTL <= 0 {
+		return s.generate(ctx, feature, req)
+	}
+
+	key := aiCacheKey(req.Prompt, ai.DefaultModel, req.MaxTokens, req.Temperature)
+
+	if !refresh {
+		cached, err := s.getCachedResponse(ctx, key)
+		if err != nil {
+			// Cache failures must never block generation
+			fmt.Printf("Failed to read AI cache: %v\n", err)
+		} else if cached != nil {
+			return cached, nil
+		}
+	}
+
+	response, err := s.generate(ctx, feature, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.storeCachedResponse(ctx, key, response); err != nil {
+		fmt.Printf("Failed to write AI cache: %v\n", err)
+	}
+
+	return response, nil
+}
+
+func (s *AIService) getCachedResponse(ctx context.Context, key string) (*GenerateTextResponse, error) {
+	var response GenerateTextResponse
+	err := s.db.QueryRow(ctx, `
+		SELECT response_text, model
+		FROM ai_response_cache
+		WHERE cache_key = $1 AND expires_at > now()`, key).Scan(&response.Text, &response.Model)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cached response: %w", err)
+	}
+
+	response.Cached = true
+	return &response, nil
+}
+
+func (s *AIService) storeCachedResponse(ctx context.Context, key string, response *GenerateTextResponse) error {
+	_, err := s.db.Exec(ctx, `
+		INSERT INTO ai_response_cache (cache_key, model, response_text, created_at, expires_at)
+		VALUES ($1, $2, $3, now(), $4)
+		ON CONFLICT (cache_key) DO UPDATE
+		SET model = EXCLUDED.model,
+		    response_text = EXCLUDED.response_text,
+		    created_at = EXCLUDED.created_at,
+		    expires_at = EXCLUDED.expires_at`,
+		key, response.Model, response.Text, time.Now().Add(s.cacheTTL))
+	if err != nil {
+		return fmt.Errorf("failed to store cached response: %w", err)
+	}
+	return nil
+}
+
+// PurgeExpiredCache removes cache entries past their TTL
+func (s *AIService) PurgeExpiredCache(ctx context.Context) (int64, error) {
+	if s.db == nil {
+		return 0, nil
+	}
+
+	result, err := s.db.Exec(ctx, `DELETE FROM ai_response_cache WHERE expires_at <= now()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge AI cache: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+// RunCachePurge purges expired cache entries every aiCachePurgeInterval
+// until ctx is cancelled
+func (s *AIService) RunCachePurge(ctx context.Context) {
+	ticker := time.NewTicker(aiCachePurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.PurgeExpiredCache(ctx); err != nil {
+			fmt.Printf("Failed to purge AI cache: %v\n", err)
+			sentry.CaptureError(err, map[string]string{"job": "ai-cache-cleanup"})
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// aiCacheKey builds a content address for a prompt. Whitespace and case are
+// normalized so trivially different spellings of a topic share an entry.
+func aiCacheKey(prompt, model string, maxTokens int, temperature float32) string {
+	normalized := strings.ToLower(strings.Join(strings.Fields(prompt), " "))
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%d|%.2f", model, normalized, maxTokens, temperature)))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,18 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAICacheKey(t *testing.T) {
+	base := aiCacheKey("Explain  Recursion", "model-a", 2000, 0.6)
+
+	assert.Len(t, base, 64)
+	assert.Equal(t, base, aiCacheKey("  explain recursion\n", "model-a", 2000, 0.6))
+	assert.NotEqual(t, base, aiCacheKey("Explain Recursion", "model-b", 2000, 0.6))
+	assert.NotEqual(t, base, aiCacheKey("Explain Recursion", "model-a", 1000, 0.6))
+	assert.NotEqual(t, base, aiCacheKey("Explain Recursion", "model-a", 2000, 0.3))
+	assert.NotEqual(t, base, aiCacheKey("Explain Iteration", "model-a", 2000, 0.6))
+}
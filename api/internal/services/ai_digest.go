@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"bailanysta/api/internal/pkg/ai"
+)
+
+const (
+	feedDigestBatchSize   = 20
+	feedDigestPostExcerpt = 300
+)
+
+type FeedDigestGroup struct {
+	Label   string      `json:"label"`
+	PostIDs []uuid.UUID `json:"post_ids"`
+}
+
+type FeedDigestResponse struct {
+	Digest    string            `json:"digest"`
+	Groups    []FeedDigestGroup `json:"groups"`
+	PostCount int               `json:"post_count"`
+	Since     time.Time         `json:"since"`
+	Model     string            `json:"model,omitempty"`
+	Usage     ai.Usage          `json:"usage"`
+
+	// HasMore is set when newer posts didn't fit; the next digest starts
+	// after the newest post in this one
+	HasMore bool `json:"has_more"`
+}
+
+// GenerateFeedDigest summarizes missed feed posts grouped by course or hashtag.
+// Large backlogs are summarized batch by batch and then merged.
+func (s *AIService) GenerateFeedDigest(ctx context.Context, items []*FeedDigestItem, since time.Time) (*FeedDigestResponse, error) {
+	response := &FeedDigestResponse{
+		Groups:    []FeedDigestGroup{},
+		PostCount: len(items),
+		Since:     since,
+	}
+	if len(items) == 0 {
+		return response, nil
+	}
+
+	labels, grouped := groupDigestItems(items)
+	ordered := make([]*FeedDigestItem, 0, len(items))
+	for _, label := range labels {
+		group := FeedDigestGroup{Label: label}
+		for _, item := range grouped[label] {
+			group.PostIDs = append(group.PostIDs, item.PostID)
+			ordered = append(ordered, item)
+		}
+		response.Groups = append(response.Groups, group)
+	}
+
+	limits := s.limits.Load()
+	var summaries []string
+	for start := 0; start < len(ordered); start += feedDigestBatchSize {
+		end := start + feedDigestBatchSize
+		if end > len(ordered) {
+			end = len(ordered)
+		}
+
+		completion, err := s.complete(ctx, AIFeatureDigest, buildDigestBatchPrompt(ordered[start:end])+languageInstruction(ctx), limits.DigestBatch.MaxTokens, limits.DigestBatch.Temperature)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate feed digest: %w", err)
+		}
+		addUsage(&response.Usage, completion.Usage)
+		response.Model = completion.Model
+		summaries = append(summaries, strings.TrimSpace(completion.Text))
+	}
+
+	if len(summaries) == 1 {
+		response.Digest = summaries[0]
+		return response, nil
+	}
+
+	// Merge per-batch summaries into a single digest
+	var promptBuilder strings.Builder
+	promptBuilder.WriteString("Merge the following partial feed digests into one short digest. ")
+	promptBuilder.WriteString("Keep topic headers, merge duplicates and keep the post ID references in square brackets.\n\n")
+	for i, summary := range summaries {
+		promptBuilder.WriteString(fmt.Sprintf("Part %d:\n%s\n\n", i+1, summary))
+	}
+
+	promptBuilder.WriteString(languageInstruction(ctx))
+	completion, err := s.complete(ctx, AIFeatureDigest, promptBuilder.String(), limits.Digest.MaxTokens, limits.Digest.Temperature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge feed digest: %w", err)
+	}
+	addUsage(&response.Usage, completion.Usage)
+	response.Model = completion.Model
+	response.Digest = strings.TrimSpace(completion.Text)
+
+	return response, nil
+}
+
+func buildDigestBatchPrompt(items []*FeedDigestItem) string {
+	var promptBuilder strings.Builder
+
+	promptBuilder.WriteString(untrustedInstruction)
+	promptBuilder.WriteString("Summarize what a student missed in their feed. ")
+	promptBuilder.WriteString("Group the summary by the topic headers given below, write 1-3 short bullet points per topic ")
+	promptBuilder.WriteString("and reference the posts you mention by their ID in square brackets, e.g. [post-id].\n\n")
+
+	labels, grouped := groupDigestItems(items)
+	for _, label := range labels {
+		promptBuilder.WriteString(fmt.Sprintf("## %s\n", label))
+		for _, item := range grouped[label] {
+			text := truncateText(strings.Join(strings.Fields(item.Text), " "), feedDigestPostExcerpt)
+			promptBuilder.WriteString(fmt.Sprintf("- [%s] @%s: %s\n", item.PostID, item.AuthorUsername, quoteUntrusted(text)))
+		}
+		promptBuilder.WriteString("\n")
+	}
+
+	promptBuilder.WriteString("Write the digest:")
+	return promptBuilder.String()
+}
+
+// groupDigestItems groups posts by course, falling back to their first
+// hashtag. Labels are returned in order of first appearance.
+func groupDigestItems(items []*FeedDigestItem) ([]string, map[string][]*FeedDigestItem) {
+	var labels []string
+	grouped := make(map[string][]*FeedDigestItem)
+
+	for _, item := range items {
+		label := "General"
+		if item.CourseTitle != "" {
+			label = item.CourseTitle
+		} else if len(item.Hashtags) > 0 {
+			label = "#" + item.Hashtags[0]
+		}
+
+		if _, ok := grouped[label]; !ok {
+			labels = append(labels, label)
+		}
+		grouped[label] = append(grouped[label], item)
+	}
+
+	return labels, grouped
+}
+
+func addUsage(total *ai.Usage, usage ai.Usage) {
+	total.PromptTokens += usage.PromptTokens
+	total.CompletionTokens += usage.CompletionTokens
+	total.TotalTokens += usage.TotalTokens
+}
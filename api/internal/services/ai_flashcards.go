@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"bailanysta/api/internal/pkg/ai"
+)
+
+const (
+	DefaultFlashcardCount = 10
+	MaxFlashcardCount     = 20
+	// maxFlashcardSide bounds each side of a card, in characters
+	maxFlashcardSide = 500
+)
+
+// GenerateFlashcardsRequest asks for cards from exactly one of a topic, a
+// post or the user's own text, such as study notes
+type GenerateFlashcardsRequest struct {
+	Topic  string     `json:"topic,omitempty" validate:"omitempty,min=3,max=200"`
+	Course string     `json:"course,omitempty" validate:"max=200"`
+	PostID *uuid.UUID `json:"post_id,omitempty"`
+	Text   string     `json:"text,omitempty" validate:"max=5000"`
+	Count  int        `json:"count,omitempty" validate:"omitempty,min=1,max=20"`
+}
+
+// FlashcardDraft is a generated card that hasn't been saved yet
+type FlashcardDraft struct {
+	Front string `json:"front"`
+	Back  string `json:"back"`
+}
+
+type GeneratedFlashcards struct {
+	Cards []FlashcardDraft
+	Model string
+	Usage ai.Usage
+}
+
+// GenerateFlashcards writes up to count question-and-answer cards about
+// topic, or about text when it is set
+func (s *AIService) GenerateFlashcards(ctx context.Context, topic, course, text string, count int) (*GeneratedFlashcards, error) {
+	if count <= 0 || count > MaxFlashcardCount {
+		count = DefaultFlashcardCount
+	}
+	s.screenInput(ctx, AIFeatureFlashcards, topic, course, text)
+
+	prompt := untrustedInstruction
+	if text != "" {
+		prompt += fmt.Sprintf("Create %d study flashcards covering the key points of the following text", count)
+	} else {
+		prompt += fmt.Sprintf("Create %d study flashcards about %s", count, quoteUntrustedLine(topic))
+	}
+	if course != "" {
+		prompt += fmt.Sprintf(" for the course %s", quoteUntrustedLine(course))
+	}
+	prompt += `. Each card has a "front" with a question or term and a "back" with a short answer or definition.`
+	prompt += languageInstruction(ctx)
+	prompt += ` Return only a JSON array of objects with "front" and "back" keys, without any commentary.`
+	if text != "" {
+		prompt += "\n\nText:\n" + quoteUntrusted(text)
+	}
+
+	limits := s.limits.Load().Flashcards
+	completion, err := s.complete(ctx, AIFeatureFlashcards, prompt, limits.MaxTokens, limits.Temperature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate flashcards: %w", err)
+	}
+
+	cards, err := parseFlashcards(completion.Text, count)
+	if err != nil {
+		return nil, err
+	}
+	return &GeneratedFlashcards{Cards: cards, Model: completion.Model, Usage: completion.Usage}, nil
+}
+
+// parseFlashcards reads the JSON array of cards out of a completion, which
+// may wrap it in a code fence or a sentence. Incomplete cards are dropped,
+// long sides cut and at most limit cards kept.
+func parseFlashcards(text string, limit int) ([]FlashcardDraft, error) {
+	start, end := strings.Index(text, "["), strings.LastIndex(text, "]")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("AI returned no flashcards")
+	}
+
+	var raw []FlashcardDraft
+	if err := json.Unmarshal([]byte(text[start:end+1]), &raw); err != nil {
+		return nil, fmt.Errorf("AI returned no flashcards")
+	}
+
+	cards := make([]FlashcardDraft, 0, len(raw))
+	for _, card := range raw {
+		front, back := strings.TrimSpace(card.Front), strings.TrimSpace(card.Back)
+		if front == "" || back == "" {
+			continue
+		}
+		cards = append(cards, FlashcardDraft{Front: truncateRunes(front, maxFlashcardSide), Back: truncateRunes(back, maxFlashcardSide)})
+		if len(cards) == limit {
+			break
+		}
+	}
+	if len(cards) == 0 {
+		return nil, fmt.Errorf("AI returned no flashcards")
+	}
+	return cards, nil
+}
+
+// truncateRunes cuts text to at most n characters
+func truncateRunes(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n])
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFlashcards(t *testing.T) {
+	t.Run("fenced array", func(t *testing.T) {
+		text := "Here you go:\n```json\n[{\"front\": \"What is a goroutine?\", \"back\": \"A lightweight thread\"}]\n```"
+		cards, err := parseFlashcards(text, 10)
+		require.NoError(t, err)
+		assert.Equal(t, []FlashcardDraft{{Front: "What is a goroutine?", Back: "A lightweight thread"}}, cards)
+	})
+
+	t.Run("drops incomplete cards and keeps the limit", func(t *testing.T) {
+		text := `[{"front": "A", "back": "1"}, {"front": " ", "back": "2"}, {"front": "B", "back": "3"}, {"front": "C", "back": "4"}]`
+		cards, err := parseFlashcards(text, 2)
+		require.NoError(t, err)
+		assert.Equal(t, []FlashcardDraft{{Front: "A", Back: "1"}, {Front: "B", Back: "3"}}, cards)
+	})
+
+	t.Run("cuts long sides", func(t *testing.T) {
+		long := strings.Repeat("я", maxFlashcardSide+10)
+		cards, err := parseFlashcards(`[{"front": "Q", "back": "`+long+`"}]`, 10)
+		require.NoError(t, err)
+		assert.Equal(t, maxFlashcardSide, len([]rune(cards[0].Back)))
+	})
+
+	for _, text := range []string{"Sorry, I can't help with that.", "[not json]", `[{"front": "", "back": ""}]`} {
+		t.Run("rejects "+text, func(t *testing.T) {
+			_, err := parseFlashcards(text, 10)
+			assert.EqualError(t, err, "AI returned no flashcards")
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"bailanysta/api/internal/pkg/ai"
+)
+
+const (
+	maxGradingCriteria = 8
+	// maxGradingComment bounds each rubric comment and the summary, in
+	// characters
+	maxGradingComment = 1000
+)
+
+// GradingCriterion is one rubric line of AI feedback
+type GradingCriterion struct {
+	Criterion string `json:"criterion"`
+	Comment   string `json:"comment"`
+}
+
+// GradingSuggestion is AI feedback on a submission, shown to the teacher
+// while grading. SuggestedScore (0-100) is advice only and is never saved
+// as the grade.
+type GradingSuggestion struct {
+	Criteria       []GradingCriterion `json:"criteria"`
+	Summary        string             `json:"summary"`
+	SuggestedScore int                `json:"suggested_score"`
+	Model          string             `json:"model"`
+	GeneratedAt    time.Time          `json:"generated_at"`
+	Usage          ai.Usage           `json:"-"`
+}
+
+// SuggestGrade assesses a text submission to an assignment against a short
+// rubric and suggests a score
+func (s *AIService) SuggestGrade(ctx context.Context, title, description, text string) (*GradingSuggestion, error) {
+	s.screenInput(ctx, AIFeatureGrading, title, description, text)
+
+	prompt := untrustedInstruction
+	prompt += fmt.Sprintf("You are a teaching assistant helping a teacher grade the assignment %s.", quoteUntrustedLine(title))
+	if description != "" {
+		prompt += "\n\nAssignment description:\n" + quoteUntrusted(description)
+	}
+	prompt += "\n\nAssess the student's submission below on 3 to 5 criteria that fit the assignment, such as" +
+		" understanding, accuracy, structure and clarity. For each criterion write a short, specific comment" +
+		" the teacher could pass on, then a one-paragraph summary and a suggested score from 0 to 100."
+	prompt += languageInstruction(ctx)
+	prompt += ` Return only a JSON object of the form {"criteria": [{"criterion": "...", "comment": "..."}],` +
+		` "summary": "...", "suggested_score": 0}, without any commentary.`
+	prompt += "\n\nSubmission:\n" + quoteUntrusted(text)
+
+	limits := s.limits.Load().Grading
+	completion, err := s.complete(ctx, AIFeatureGrading, prompt, limits.MaxTokens, limits.Temperature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate grading feedback: %w", err)
+	}
+
+	suggestion, err := parseGradingSuggestion(completion.Text)
+	if err != nil {
+		return nil, err
+	}
+	suggestion.Model = completion.Model
+	suggestion.GeneratedAt = time.Now()
+	suggestion.Usage = completion.Usage
+	return suggestion, nil
+}
+
+// parseGradingSuggestion reads the JSON object out of a completion, which
+// may wrap it in a code fence or a sentence. Empty criteria are dropped,
+// long comments cut and the score held to 0-100.
+func parseGradingSuggestion(text string) (*GradingSuggestion, error) {
+	start, end := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("AI returned no feedback")
+	}
+
+	var raw GradingSuggestion
+	if err := json.Unmarshal([]byte(text[start:end+1]), &raw); err != nil {
+		return nil, fmt.Errorf("AI returned no feedback")
+	}
+
+	suggestion := &GradingSuggestion{
+		Criteria:       make([]GradingCriterion, 0, len(raw.Criteria)),
+		Summary:        truncateRunes(strings.TrimSpace(raw.Summary), maxGradingComment),
+		SuggestedScore: min(max(raw.SuggestedScore, 0), 100),
+	}
+	for _, c := range raw.Criteria {
+		criterion, comment := strings.TrimSpace(c.Criterion), strings.TrimSpace(c.Comment)
+		if criterion == "" || comment == "" {
+			continue
+		}
+		suggestion.Criteria = append(suggestion.Criteria, GradingCriterion{
+			Criterion: truncateRunes(criterion, 200),
+			Comment:   truncateRunes(comment, maxGradingComment),
+		})
+		if len(suggestion.Criteria) == maxGradingCriteria {
+			break
+		}
+	}
+	if len(suggestion.Criteria) == 0 {
+		return nil, fmt.Errorf("AI returned no feedback")
+	}
+	return suggestion, nil
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"time"
+	"unicode/utf8"
+)
+
+// rewriteMinTokens is the smallest budget a rewrite gets, however short the
+// text, so short posts aren't cut off mid-sentence
+const rewriteMinTokens = 200
+
+// AITaskLimits bound one kind of completion
+type AITaskLimits struct {
+	DefaultTokens int     // when the client doesn't ask for a length; 0 means MaxTokens
+	MaxTokens     int     // the most a completion may use
+	Temperature   float32 // when the client doesn't choose one
+}
+
+// tokens returns the budget for a completion the client asked requested
+// tokens for (0 for no preference)
+func (l AITaskLimits) tokens(requested int) int {
+	if requested <= 0 {
+		requested = l.DefaultTokens
+	}
+	if requested <= 0 || requested > l.MaxTokens {
+		return l.MaxTokens
+	}
+	return requested
+}
+
+// AILimits are the token caps and default temperatures of each kind of
+// completion, and how long a single completion may take. They are read from
+// config and replaced on reload.
+type AILimits struct {
+	Text        AITaskLimits // POST /ai/generate
+	Post        AITaskLimits
+	Comment     AITaskLimits
+	Rewrite     AITaskLimits // scaled down to the length of the text
+	StudyNotes  AITaskLimits
+	Quiz        AITaskLimits
+	Flashcards  AITaskLimits
+	Grading     AITaskLimits // feedback on assignment submissions
+	Explain     AITaskLimits
+	DigestBatch AITaskLimits // summaries of up to feedDigestBatchSize posts
+	Digest      AITaskLimits // the merged digest
+	Timeout     time.Duration
+}
+
+// estimateTokens guesses how many tokens text takes. Cyrillic runs at about
+// three characters a token, which also overestimates English a little.
+func estimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 2) / 3
+}
+
+// rewriteTokens sizes a rewrite of text by what the mode does to its length:
+// shortening needs no more than the original, translation into Kazakh or
+// Russian may need twice as much, expanding about three times.
+func rewriteTokens(limits AITaskLimits, mode, text string) int {
+	input := estimateTokens(text)
+	var budget int
+	switch mode {
+	case "shorten":
+		budget = input
+	case "translate":
+		budget = input * 2
+	case "expand":
+		budget = input * 3
+	default:
+		budget = input * 5 / 4
+	}
+	budget += 100
+	return min(max(budget, rewriteMinTokens), limits.MaxTokens)
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAITaskLimitsTokens(t *testing.T) {
+	limits := AITaskLimits{DefaultTokens: 500, MaxTokens: 4000}
+
+	assert.Equal(t, 500, limits.tokens(0))
+	assert.Equal(t, 1000, limits.tokens(1000))
+	assert.Equal(t, 4000, limits.tokens(100000))
+	assert.Equal(t, 300, AITaskLimits{MaxTokens: 300}.tokens(0))
+}
+
+func TestRewriteTokens(t *testing.T) {
+	limits := AITaskLimits{MaxTokens: 2000}
+	medium := strings.Repeat("слово ", 150) // 900 runes, about 300 tokens
+
+	tests := []struct {
+		name string
+		mode string
+		text string
+		want int
+	}{
+		{"short text gets the floor", "improve", "Hi", rewriteMinTokens},
+		{"shorten stays within the original", "shorten", medium, 400},
+		{"improve leaves some room", "improve", medium, 475},
+		{"translate doubles", "translate", medium, 700},
+		{"expand triples", "expand", medium, 1000},
+		{"long text hits the cap", "expand", strings.Repeat("слово ", 1000), 2000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, rewriteTokens(limits, tt.mode, tt.text))
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"bailanysta/api/internal/pkg/ai"
+)
+
+// AIQuotaService enforces per-user daily limits on AI requests and tokens.
+// Days are UTC; a limit of 0 is unlimited. The token limit is soft: a
+// request is allowed while the user is under it, however many tokens it
+// then uses.
+type AIQuotaService struct {
+	db            *pgxpool.Pool
+	dailyRequests int
+	dailyTokens   int64
+}
+
+// AIQuotaStatus is a user's AI usage for the current day. Limits and
+// remaining counts are nil when unlimited.
+type AIQuotaStatus struct {
+	RequestsLimit     *int      `json:"requests_limit"`
+	RequestsUsed      int       `json:"requests_used"`
+	RequestsRemaining *int      `json:"requests_remaining"`
+	TokensLimit       *int64    `json:"tokens_limit"`
+	TokensUsed        int64     `json:"tokens_used"`
+	TokensRemaining   *int64    `json:"tokens_remaining"`
+	ResetAt           time.Time `json:"reset_at"`
+}
+
+func NewAIQuotaService(db *pgxpool.Pool, dailyRequests int, dailyTokens int64) *AIQuotaService {
+	return &AIQuotaService{
+		db:            db,
+		dailyRequests: dailyRequests,
+		dailyTokens:   dailyTokens,
+	}
+}
+
+// Reserve counts an AI request for userID, failing with "AI quota exceeded"
+// when either daily limit is used up
+func (s *AIQuotaService) Reserve(ctx context.Context, userID uuid.UUID) error {
+	var requests int
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO ai_usage (user_id, day, requests)
+		VALUES ($1, $2, 1)
+		ON CONFLICT (user_id, day) DO UPDATE SET requests = ai_usage.requests + 1
+		WHERE ($3 = 0 OR ai_usage.requests < $3) AND ($4 = 0 OR ai_usage.tokens < $4)
+		RETURNING requests`, userID, quotaDay(time.Now()), s.dailyRequests, s.dailyTokens).Scan(&requests)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("AI quota exceeded")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to reserve AI quota: %w", err)
+	}
+	return nil
+}
+
+// RecordTokens adds tokens used by a reserved request to today's usage
+func (s *AIQuotaService) RecordTokens(ctx context.Context, userID uuid.UUID, tokens int64) error {
+	if tokens <= 0 {
+		return nil
+	}
+	_, err := s.db.Exec(ctx, `
+		INSERT INTO ai_usage (user_id, day, tokens)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, day) DO UPDATE SET tokens = ai_usage.tokens + EXCLUDED.tokens`,
+		userID, quotaDay(time.Now()), tokens)
+	if err != nil {
+		return fmt.Errorf("failed to record AI usage: %w", err)
+	}
+	return nil
+}
+
+// Status returns userID's usage and remaining quota for today
+func (s *AIQuotaService) Status(ctx context.Context, userID uuid.UUID) (*AIQuotaStatus, error) {
+	now := time.Now()
+	status := &AIQuotaStatus{ResetAt: quotaDay(now).AddDate(0, 0, 1)}
+
+	err := s.db.QueryRow(ctx, `
+		SELECT COALESCE(SUM(requests), 0), COALESCE(SUM(tokens), 0)
+		FROM ai_usage
+		WHERE user_id = $1 AND day = $2`, userID, quotaDay(now)).Scan(&status.RequestsUsed, &status.TokensUsed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get AI usage: %w", err)
+	}
+
+	if s.dailyRequests > 0 {
+		limit, remaining := s.dailyRequests, max(s.dailyRequests-status.RequestsUsed, 0)
+		status.RequestsLimit, status.RequestsRemaining = &limit, &remaining
+	}
+	if s.dailyTokens > 0 {
+		limit, remaining := s.dailyTokens, max(s.dailyTokens-status.TokensUsed, 0)
+		status.TokensLimit, status.TokensRemaining = &limit, &remaining
+	}
+	return status, nil
+}
+
+// quotaDay is the UTC day quota usage of t is counted on
+func quotaDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// AIUsageMeter adds up the tokens of every completion made with its context
+// and records whose request they were made for
+type AIUsageMeter struct {
+	userID uuid.UUID
+	tokens atomic.Int64
+}
+
+type aiUsageMeterKey struct{}
+
+// WithAIUsageMeter returns a context whose AI completions for userID are
+// counted by the returned meter
+func WithAIUsageMeter(ctx context.Context, userID uuid.UUID) (context.Context, *AIUsageMeter) {
+	meter := &AIUsageMeter{userID: userID}
+	return context.WithValue(ctx, aiUsageMeterKey{}, meter), meter
+}
+
+// Tokens is the total number of tokens counted so far
+func (m *AIUsageMeter) Tokens() int64 {
+	return m.tokens.Load()
+}
+
+func meterAIUsage(ctx context.Context, usage ai.Usage) {
+	if meter, ok := ctx.Value(aiUsageMeterKey{}).(*AIUsageMeter); ok {
+		meter.tokens.Add(int64(usage.TotalTokens))
+	}
+}
+
+// aiRequestUser is the user an AI completion is made for, or nil outside a
+// metered request
+func aiRequestUser(ctx context.Context) *uuid.UUID {
+	if meter, ok := ctx.Value(aiUsageMeterKey{}).(*AIUsageMeter); ok {
+		return &meter.userID
+	}
+	return nil
+}
@@ -0,0 +1,399 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	analyticsTopPosts    = 50
+	analyticsTopHashtags = 10
+	analyticsTopLinks    = 20
+	analyticsDateLayout  = "2006-01-02"
+)
+
+type AnalyticsService struct {
+	db *pgxpool.Pool
+}
+
+type AnalyticsTotals struct {
+	Views        int64 `json:"views"`
+	Likes        int64 `json:"likes"`
+	Comments     int64 `json:"comments"`
+	LinkClicks   int64 `json:"link_clicks"`
+	NewFollowers int64 `json:"new_followers"`
+	Followers    int64 `json:"followers"`
+	Posts        int64 `json:"posts"`
+}
+
+type DailyAnalytics struct {
+	Date         string `json:"date"`
+	Views        int64  `json:"views"`
+	Likes        int64  `json:"likes"`
+	Comments     int64  `json:"comments"`
+	LinkClicks   int64  `json:"link_clicks"`
+	NewFollowers int64  `json:"new_followers"`
+}
+
+type PostAnalytics struct {
+	PostID     uuid.UUID `json:"post_id"`
+	Text       string    `json:"text"`
+	CreatedAt  time.Time `json:"created_at"`
+	Views      int64     `json:"views"`
+	Likes      int64     `json:"likes"`
+	Comments   int64     `json:"comments"`
+	LinkClicks int64     `json:"link_clicks"`
+	Hashtags   []string  `json:"hashtags"`
+}
+
+// LinkAnalytics counts clicks on one outbound link in one post, through the
+// /l/ redirect
+type LinkAnalytics struct {
+	PostID uuid.UUID `json:"post_id"`
+	URL    string    `json:"url"`
+	Clicks int64     `json:"clicks"`
+}
+
+type HashtagAnalytics struct {
+	Tag      string `json:"tag"`
+	Posts    int    `json:"posts"`
+	Views    int64  `json:"views"`
+	Likes    int64  `json:"likes"`
+	Comments int64  `json:"comments"`
+}
+
+type HourAnalytics struct {
+	Hour              int     `json:"hour"`
+	Posts             int     `json:"posts"`
+	AverageEngagement float64 `json:"average_engagement"`
+}
+
+type AuthorAnalytics struct {
+	From        string              `json:"from"`
+	To          string              `json:"to"`
+	Totals      AnalyticsTotals     `json:"totals"`
+	Daily       []DailyAnalytics    `json:"daily"`
+	Posts       []*PostAnalytics    `json:"posts"`
+	TopHashtags []*HashtagAnalytics `json:"top_hashtags"`
+	TopLinks    []*LinkAnalytics    `json:"top_links"`
+	BestHours   []*HourAnalytics    `json:"best_hours"`
+}
+
+func NewAnalyticsService(db *pgxpool.Pool) *AnalyticsService {
+	return &AnalyticsService{db: db}
+}
+
+// GetAuthorAnalytics aggregates views, likes, comments, link clicks and
+// follower growth for the author's posts between from and to (inclusive dates, UTC).
+// Engagement on a post counts toward the range when it happened in the range,
+// regardless of when the post was published.
+func (s *AnalyticsService) GetAuthorAnalytics(ctx context.Context, authorID uuid.UUID, from, to time.Time) (*AuthorAnalytics, error) {
+	analytics := &AuthorAnalytics{
+		From:        from.Format(analyticsDateLayout),
+		To:          to.Format(analyticsDateLayout),
+		Daily:       []DailyAnalytics{},
+		Posts:       []*PostAnalytics{},
+		TopHashtags: []*HashtagAnalytics{},
+		TopLinks:    []*LinkAnalytics{},
+		BestHours:   []*HourAnalytics{},
+	}
+
+	daily, err := s.getDailyAnalytics(ctx, authorID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	analytics.Daily = daily
+	for _, day := range daily {
+		analytics.Totals.Views += day.Views
+		analytics.Totals.Likes += day.Likes
+		analytics.Totals.Comments += day.Comments
+		analytics.Totals.LinkClicks += day.LinkClicks
+		analytics.Totals.NewFollowers += day.NewFollowers
+	}
+
+	err = s.db.QueryRow(ctx, `SELECT followers_count FROM users WHERE id = $1`, authorID).Scan(&analytics.Totals.Followers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count followers: %w", err)
+	}
+
+	posts, err := s.getPostAnalytics(ctx, authorID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	links, err := s.getLinkAnalytics(ctx, authorID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	analytics.TopLinks = links
+
+	// Hours are judged on posts published in the range only
+	fromTime, toTime := from, to.AddDate(0, 0, 1)
+	var published []*PostAnalytics
+	for _, post := range posts {
+		if !post.CreatedAt.Before(fromTime) && post.CreatedAt.Before(toTime) {
+			published = append(published, post)
+		}
+	}
+	analytics.Totals.Posts = int64(len(published))
+	analytics.BestHours = bestPostingHours(published)
+	analytics.TopHashtags = topHashtags(posts, analyticsTopHashtags)
+
+	sort.SliceStable(posts, func(i, j int) bool {
+		ei, ej := posts[i].Likes+posts[i].Comments, posts[j].Likes+posts[j].Comments
+		if ei != ej {
+			return ei > ej
+		}
+		return posts[i].Views > posts[j].Views
+	})
+	if len(posts) > analyticsTopPosts {
+		posts = posts[:analyticsTopPosts]
+	}
+	for _, post := range posts {
+		post.Text = truncateText(post.Text, 140)
+	}
+	analytics.Posts = posts
+
+	return analytics, nil
+}
+
+func (s *AnalyticsService) getDailyAnalytics(ctx context.Context, authorID uuid.UUID, from, to time.Time) ([]DailyAnalytics, error) {
+	rows, err := s.db.Query(ctx, `
+		WITH days AS (
+		    SELECT generate_series($2::date, $3::date, interval '1 day')::date AS day
+		),
+		mine AS (
+		    SELECT id FROM posts WHERE author_id = $1
+		),
+		v AS (
+		    SELECT i.viewed_on AS day, COUNT(*) AS n
+		    FROM post_impressions i
+		    JOIN mine ON mine.id = i.post_id
+		    WHERE i.viewed_on BETWEEN $2::date AND $3::date
+		    GROUP BY i.viewed_on
+		),
+		l AS (
+		    SELECT l.created_at::date AS day, COUNT(*) AS n
+		    FROM likes l
+		    JOIN mine ON mine.id = l.post_id
+		    WHERE l.created_at >= $2::date AND l.created_at < $3::date + 1
+		    GROUP BY l.created_at::date
+		),
+		c AS (
+		    SELECT c.created_at::date AS day, COUNT(*) AS n
+		    FROM comments c
+		    JOIN mine ON mine.id = c.post_id
+		    WHERE c.author_id <> $1 AND c.created_at >= $2::date AND c.created_at < $3::date + 1
+		    GROUP BY c.created_at::date
+		),
+		k AS (
+		    SELECT k.clicked_on AS day, SUM(k.clicks)::bigint AS n
+		    FROM link_clicks k
+		    JOIN mine ON mine.id = k.post_id
+		    WHERE k.clicked_on BETWEEN $2::date AND $3::date
+		    GROUP BY k.clicked_on
+		),
+		f AS (
+		    SELECT created_at::date AS day, COUNT(*) AS n
+		    FROM follows
+		    WHERE followee_id = $1 AND created_at >= $2::date AND created_at < $3::date + 1
+		    GROUP BY created_at::date
+		)
+		SELECT days.day, COALESCE(v.n, 0), COALESCE(l.n, 0), COALESCE(c.n, 0), COALESCE(k.n, 0), COALESCE(f.n, 0)
+		FROM days
+		LEFT JOIN v ON v.day = days.day
+		LEFT JOIN l ON l.day = days.day
+		LEFT JOIN c ON c.day = days.day
+		LEFT JOIN k ON k.day = days.day
+		LEFT JOIN f ON f.day = days.day
+		ORDER BY days.day`, authorID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily analytics: %w", err)
+	}
+	defer rows.Close()
+
+	var daily []DailyAnalytics
+	for rows.Next() {
+		var day DailyAnalytics
+		var date time.Time
+		if err := rows.Scan(&date, &day.Views, &day.Likes, &day.Comments, &day.LinkClicks, &day.NewFollowers); err != nil {
+			return nil, fmt.Errorf("failed to scan daily analytics: %w", err)
+		}
+		day.Date = date.Format(analyticsDateLayout)
+		daily = append(daily, day)
+	}
+
+	return daily, rows.Err()
+}
+
+// getPostAnalytics returns stats for posts that were published or received
+// engagement in the range
+func (s *AnalyticsService) getPostAnalytics(ctx context.Context, authorID uuid.UUID, from, to time.Time) ([]*PostAnalytics, error) {
+	rows, err := s.db.Query(ctx, `
+		WITH mine AS (
+		    SELECT id, text, created_at FROM posts WHERE author_id = $1
+		),
+		v AS (
+		    SELECT i.post_id, COUNT(*) AS n
+		    FROM post_impressions i
+		    JOIN mine ON mine.id = i.post_id
+		    WHERE i.viewed_on BETWEEN $2::date AND $3::date
+		    GROUP BY i.post_id
+		),
+		l AS (
+		    SELECT l.post_id, COUNT(*) AS n
+		    FROM likes l
+		    JOIN mine ON mine.id = l.post_id
+		    WHERE l.created_at >= $2::date AND l.created_at < $3::date + 1
+		    GROUP BY l.post_id
+		),
+		c AS (
+		    SELECT c.post_id, COUNT(*) AS n
+		    FROM comments c
+		    JOIN mine ON mine.id = c.post_id
+		    WHERE c.author_id <> $1 AND c.created_at >= $2::date AND c.created_at < $3::date + 1
+		    GROUP BY c.post_id
+		),
+		k AS (
+		    SELECT k.post_id, SUM(k.clicks)::bigint AS n
+		    FROM link_clicks k
+		    JOIN mine ON mine.id = k.post_id
+		    WHERE k.clicked_on BETWEEN $2::date AND $3::date
+		    GROUP BY k.post_id
+		),
+		tags AS (
+		    SELECT ph.post_id, array_agg(h.display ORDER BY h.tag) AS tags
+		    FROM post_hashtags ph
+		    JOIN mine ON mine.id = ph.post_id
+		    JOIN hashtags h ON h.id = ph.hashtag_id
+		    GROUP BY ph.post_id
+		)
+		SELECT mine.id, mine.text, mine.created_at,
+		       COALESCE(v.n, 0), COALESCE(l.n, 0), COALESCE(c.n, 0), COALESCE(k.n, 0),
+		       COALESCE(tags.tags, '{}')
+		FROM mine
+		LEFT JOIN v ON v.post_id = mine.id
+		LEFT JOIN l ON l.post_id = mine.id
+		LEFT JOIN c ON c.post_id = mine.id
+		LEFT JOIN k ON k.post_id = mine.id
+		LEFT JOIN tags ON tags.post_id = mine.id
+		WHERE v.n IS NOT NULL OR l.n IS NOT NULL OR c.n IS NOT NULL OR k.n IS NOT NULL
+		   OR (mine.created_at >= $2::date AND mine.created_at < $3::date + 1)
+		ORDER BY mine.created_at DESC`, authorID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post analytics: %w", err)
+	}
+	defer rows.Close()
+
+	var posts []*PostAnalytics
+	for rows.Next() {
+		var post PostAnalytics
+		err := rows.Scan(&post.PostID, &post.Text, &post.CreatedAt,
+			&post.Views, &post.Likes, &post.Comments, &post.LinkClicks, &post.Hashtags)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan post analytics: %w", err)
+		}
+		posts = append(posts, &post)
+	}
+
+	return posts, rows.Err()
+}
+
+// getLinkAnalytics returns the author's most clicked links in the range
+func (s *AnalyticsService) getLinkAnalytics(ctx context.Context, authorID uuid.UUID, from, to time.Time) ([]*LinkAnalytics, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT k.post_id, k.url, SUM(k.clicks)::bigint
+		FROM link_clicks k
+		JOIN posts p ON p.id = k.post_id
+		WHERE p.author_id = $1 AND k.clicked_on BETWEEN $2::date AND $3::date
+		GROUP BY k.post_id, k.url
+		ORDER BY SUM(k.clicks) DESC, k.url
+		LIMIT $4`, authorID, from, to, analyticsTopLinks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get link analytics: %w", err)
+	}
+	defer rows.Close()
+
+	links := []*LinkAnalytics{}
+	for rows.Next() {
+		var link LinkAnalytics
+		if err := rows.Scan(&link.PostID, &link.URL, &link.Clicks); err != nil {
+			return nil, fmt.Errorf("failed to scan link analytics: %w", err)
+		}
+		links = append(links, &link)
+	}
+
+	return links, rows.Err()
+}
+
+// topHashtags sums post stats per hashtag and orders them by engagement
+// (likes + comments), then views
+func topHashtags(posts []*PostAnalytics, limit int) []*HashtagAnalytics {
+	byTag := make(map[string]*HashtagAnalytics)
+	for _, post := range posts {
+		for _, tag := range post.Hashtags {
+			stat, ok := byTag[tag]
+			if !ok {
+				stat = &HashtagAnalytics{Tag: tag}
+				byTag[tag] = stat
+			}
+			stat.Posts++
+			stat.Views += post.Views
+			stat.Likes += post.Likes
+			stat.Comments += post.Comments
+		}
+	}
+
+	result := make([]*HashtagAnalytics, 0, len(byTag))
+	for _, stat := range byTag {
+		result = append(result, stat)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		ei, ej := result[i].Likes+result[i].Comments, result[j].Likes+result[j].Comments
+		if ei != ej {
+			return ei > ej
+		}
+		if result[i].Views != result[j].Views {
+			return result[i].Views > result[j].Views
+		}
+		return result[i].Tag < result[j].Tag
+	})
+
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+// bestPostingHours groups posts by UTC publishing hour and orders the hours
+// by average engagement per post
+func bestPostingHours(posts []*PostAnalytics) []*HourAnalytics {
+	var hours [24]*HourAnalytics
+	var totals [24]int64
+	for _, post := range posts {
+		hour := post.CreatedAt.UTC().Hour()
+		if hours[hour] == nil {
+			hours[hour] = &HourAnalytics{Hour: hour}
+		}
+		hours[hour].Posts++
+		totals[hour] += post.Likes + post.Comments
+	}
+
+	result := []*HourAnalytics{}
+	for hour, stat := range hours {
+		if stat == nil {
+			continue
+		}
+		stat.AverageEngagement = float64(totals[hour]) / float64(stat.Posts)
+		result = append(result, stat)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].AverageEngagement > result[j].AverageEngagement
+	})
+
+	return result
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopHashtags(t *testing.T) {
+	posts := []*PostAnalytics{
+		{Views: 10, Likes: 2, Comments: 1, Hashtags: []string{"go", "db"}},
+		{Views: 50, Likes: 0, Comments: 0, Hashtags: []string{"ai"}},
+		{Views: 5, Likes: 4, Comments: 0, Hashtags: []string{"go"}},
+		{Views: 1, Likes: 0, Comments: 0},
+	}
+
+	result := topHashtags(posts, 2)
+
+	assert.Len(t, result, 2)
+	assert.Equal(t, "go", result[0].Tag)
+	assert.Equal(t, 2, result[0].Posts)
+	assert.Equal(t, int64(15), result[0].Views)
+	assert.Equal(t, int64(6), result[0].Likes)
+	assert.Equal(t, "db", result[1].Tag)
+}
+
+func TestBestPostingHours(t *testing.T) {
+	at := func(hour int) time.Time {
+		return time.Date(2025, 3, 1, hour, 30, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		posts    []*PostAnalytics
+		expected []HourAnalytics
+	}{
+		{
+			name:     "no posts",
+			posts:    nil,
+			expected: []HourAnalytics{},
+		},
+		{
+			name: "hours ordered by average engagement",
+			posts: []*PostAnalytics{
+				{CreatedAt: at(9), Likes: 1},
+				{CreatedAt: at(9), Likes: 3, Comments: 2},
+				{CreatedAt: at(20), Likes: 5},
+				{CreatedAt: at(3)},
+			},
+			expected: []HourAnalytics{
+				{Hour: 20, Posts: 1, AverageEngagement: 5},
+				{Hour: 9, Posts: 2, AverageEngagement: 3},
+				{Hour: 3, Posts: 1, AverageEngagement: 0},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := bestPostingHours(tt.posts)
+			actual := make([]HourAnalytics, 0, len(result))
+			for _, hour := range result {
+				actual = append(actual, *hour)
+			}
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
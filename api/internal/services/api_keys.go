@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// APIKeyPrefix starts every API key, telling it apart from a JWT
+	APIKeyPrefix = "bly_"
+
+	APIKeyScopeRead  = "read"
+	APIKeyScopeWrite = "write"
+
+	maxAPIKeysPerUser = 20
+	// last_used_at is only written this often per key
+	apiKeyUsageResolution = time.Minute
+)
+
+type APIKey struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scope      string     `json:"scope"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name  string `json:"name" validate:"required,min=1,max=100"`
+	Scope string `json:"scope" validate:"required,oneof=read write"`
+}
+
+// CreatedAPIKey carries the key itself, which is only ever shown once
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// APIKeyIdentity is who an API key acts as
+type APIKeyIdentity struct {
+	KeyID  uuid.UUID
+	UserID uuid.UUID
+	OrgID  uuid.UUID
+	Scope  string
+}
+
+// hashToken is how API keys and refresh tokens are stored and looked up
+func hashToken(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func generateAPIKey() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return APIKeyPrefix + base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// CreateAPIKey creates a key for userID. The returned key can't be
+// recovered later.
+func (s *AuthService) CreateAPIKey(ctx context.Context, userID uuid.UUID, req CreateAPIKeyRequest) (*CreatedAPIKey, error) {
+	if req.Scope != APIKeyScopeRead && req.Scope != APIKeyScopeWrite {
+		return nil, fmt.Errorf("invalid API key scope")
+	}
+
+	key, err := generateAPIKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+
+	created := &CreatedAPIKey{
+		APIKey: APIKey{
+			Name:   strings.TrimSpace(req.Name),
+			Prefix: key[:len(APIKeyPrefix)+6],
+			Scope:  req.Scope,
+		},
+		Key: key,
+	}
+
+	// The count and the insert share a statement so concurrent requests
+	// can't exceed the limit
+	err = s.db.QueryRow(ctx, `
+		INSERT INTO api_keys (user_id, name, prefix, key_hash, scope)
+		SELECT $1, $2, $3, $4, $5
+		WHERE (SELECT COUNT(*) FROM api_keys WHERE user_id = $1 AND revoked_at IS NULL) < $6
+		RETURNING id, created_at`,
+		userID, created.Name, created.Prefix, hashToken(key), created.Scope, maxAPIKeysPerUser,
+	).Scan(&created.ID, &created.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("too many API keys")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	return created, nil
+}
+
+// ListAPIKeys returns userID's active keys, newest first
+func (s *AuthService) ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]*APIKey, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT id, name, prefix, scope, created_at, last_used_at
+		FROM api_keys
+		WHERE user_id = $1 AND revoked_at IS NULL
+		ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []*APIKey{}
+	for rows.Next() {
+		var key APIKey
+		if err := rows.Scan(&key.ID, &key.Name, &key.Prefix, &key.Scope, &key.CreatedAt, &key.LastUsedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		keys = append(keys, &key)
+	}
+	return keys, rows.Err()
+}
+
+// RevokeAPIKey stops keyID from authenticating
+func (s *AuthService) RevokeAPIKey(ctx context.Context, userID, keyID uuid.UUID) error {
+	tag, err := s.db.Exec(ctx, `
+		UPDATE api_keys SET revoked_at = now()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`, keyID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("API key not found")
+	}
+	return nil
+}
+
+// AuthenticateAPIKey resolves an active key to the user it acts as
+func (s *AuthService) AuthenticateAPIKey(ctx context.Context, key string) (*APIKeyIdentity, error) {
+	if !strings.HasPrefix(key, APIKeyPrefix) {
+		return nil, fmt.Errorf("invalid API key")
+	}
+
+	var identity APIKeyIdentity
+	var lastUsedAt *time.Time
+	err := s.db.QueryRow(ctx, `
+		SELECT k.id, k.user_id, u.org_id, k.scope, k.last_used_at
+		FROM api_keys k
+		JOIN users u ON u.id = k.user_id
+		WHERE k.key_hash = $1 AND k.revoked_at IS NULL`, hashToken(key),
+	).Scan(&identity.KeyID, &identity.UserID, &identity.OrgID, &identity.Scope, &lastUsedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("invalid API key")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to authenticate API key: %w", err)
+	}
+
+	if lastUsedAt == nil || time.Since(*lastUsedAt) >= apiKeyUsageResolution {
+		if _, err := s.db.Exec(ctx, `UPDATE api_keys SET last_used_at = now() WHERE id = $1`, identity.KeyID); err != nil {
+			fmt.Printf("Failed to update API key last use: %v\n", err)
+		}
+	}
+
+	return &identity, nil
+}
+
+// APIKeyAllows reports whether a key of scope may make a request with method
+func APIKeyAllows(scope, method string) bool {
+	if scope == APIKeyScopeWrite {
+		return true
+	}
+	switch method {
+	case "GET", "HEAD", "OPTIONS":
+		return true
+	}
+	return false
+}
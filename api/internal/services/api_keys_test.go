@@ -0,0 +1,43 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyAllows(t *testing.T) {
+	tests := []struct {
+		scope  string
+		method string
+		want   bool
+	}{
+		{APIKeyScopeRead, "GET", true},
+		{APIKeyScopeRead, "HEAD", true},
+		{APIKeyScopeRead, "POST", false},
+		{APIKeyScopeRead, "DELETE", false},
+		{APIKeyScopeWrite, "POST", true},
+		{APIKeyScopeWrite, "PATCH", true},
+		{"admin", "POST", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.scope+" "+tt.method, func(t *testing.T) {
+			assert.Equal(t, tt.want, APIKeyAllows(tt.scope, tt.method))
+		})
+	}
+}
+
+func TestGenerateAPIKey(t *testing.T) {
+	key, err := generateAPIKey()
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, APIKeyPrefix))
+
+	other, err := generateAPIKey()
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other)
+	assert.NotEqual(t, hashToken(key), hashToken(other))
+	assert.Len(t, hashToken(key), 64)
+}
@@ -0,0 +1,444 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"bailanysta/api/internal/pkg/database"
+	"bailanysta/api/internal/pkg/sentry"
+)
+
+const (
+	assignmentReminderInterval = 5 * time.Minute
+	// MaxSubmissionAttachments bounds the links a submission can carry
+	MaxSubmissionAttachments = 5
+)
+
+// Submission statuses: a returned submission goes back to the student for
+// rework, a graded one is final
+const (
+	SubmissionStatusSubmitted = "submitted"
+	SubmissionStatusReturned  = "returned"
+	SubmissionStatusGraded    = "graded"
+)
+
+type Assignment struct {
+	ID          uuid.UUID  `json:"id"`
+	ModuleID    uuid.UUID  `json:"module_id"`
+	CourseID    uuid.UUID  `json:"course_id"`
+	CreatedBy   *uuid.UUID `json:"created_by,omitempty"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	DueAt       *time.Time `json:"due_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	// MySubmission is the viewer's own submission, when listing for a student
+	MySubmission *Submission `json:"my_submission,omitempty"`
+}
+
+type Submission struct {
+	ID             uuid.UUID  `json:"id"`
+	AssignmentID   uuid.UUID  `json:"assignment_id"`
+	UserID         uuid.UUID  `json:"user_id"`
+	Username       string     `json:"username,omitempty"`
+	Text           string     `json:"text"`
+	AttachmentURLs []string   `json:"attachment_urls"`
+	Status         string     `json:"status"`
+	Late           bool       `json:"late"`
+	Grade          *int       `json:"grade"`
+	Feedback       string     `json:"feedback"`
+	SubmittedAt    time.Time  `json:"submitted_at"`
+	ReviewedAt     *time.Time `json:"reviewed_at"`
+	// AIFeedback is only shown to teachers
+	AIFeedback *GradingSuggestion `json:"ai_feedback,omitempty"`
+}
+
+type CreateAssignmentRequest struct {
+	Title       string     `json:"title" validate:"required,max=200"`
+	Description string     `json:"description" validate:"max=5000"`
+	DueAt       *time.Time `json:"due_at"`
+}
+
+// SubmitAssignmentRequest needs text, attachments or both. Attachments are
+// links to files the student uploaded elsewhere.
+type SubmitAssignmentRequest struct {
+	Text           string   `json:"text" validate:"max=20000"`
+	AttachmentURLs []string `json:"attachment_urls" validate:"max=5,dive,max=2048"`
+}
+
+type ReviewSubmissionRequest struct {
+	Status   string `json:"status" validate:"required,oneof=returned graded"`
+	Grade    *int   `json:"grade" validate:"omitempty,min=0,max=100"`
+	Feedback string `json:"feedback" validate:"max=5000"`
+}
+
+type AssignmentService struct {
+	db                  *database.Pool
+	notificationService *NotificationService
+	reminderLead        time.Duration
+}
+
+func NewAssignmentService(db *database.Pool, notificationService *NotificationService, reminderLead time.Duration) *AssignmentService {
+	return &AssignmentService{
+		db:                  db,
+		notificationService: notificationService,
+		reminderLead:        reminderLead,
+	}
+}
+
+// validateAttachmentURLs accepts absolute http(s) links only, so a
+// submission can't point at javascript: or data: URLs
+func validateAttachmentURLs(urls []string) error {
+	if len(urls) > MaxSubmissionAttachments {
+		return fmt.Errorf("too many attachments")
+	}
+	for _, raw := range urls {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid attachment URL")
+		}
+	}
+	return nil
+}
+
+// CreateAssignment adds an assignment to a module of orgID; it fails with
+// "module not found" for modules of other organizations
+func (s *AssignmentService) CreateAssignment(ctx context.Context, userID, orgID, moduleID uuid.UUID, req CreateAssignmentRequest) (*Assignment, error) {
+	assignment := Assignment{ModuleID: moduleID, CreatedBy: &userID, Title: req.Title, Description: req.Description, DueAt: req.DueAt}
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO assignments (module_id, created_by, title, description, due_at)
+		SELECT m.id, $3, $4, $5, $6 FROM modules m
+		JOIN courses c ON c.id = m.course_id
+		WHERE m.id = $1 AND c.org_id = $2
+		RETURNING id, (SELECT course_id FROM modules WHERE id = $1), created_at`,
+		moduleID, orgID, userID, req.Title, req.Description, req.DueAt).Scan(&assignment.ID, &assignment.CourseID, &assignment.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("module not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create assignment: %w", err)
+	}
+	return &assignment, nil
+}
+
+// GetModuleAssignments lists a module's assignments, soonest due first,
+// each with userID's own submission
+func (s *AssignmentService) GetModuleAssignments(ctx context.Context, userID, orgID, moduleID uuid.UUID) ([]*Assignment, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT `+assignmentColumns+`,
+		       sub.id, sub.text, sub.attachment_urls, sub.status, sub.submitted_at > a.due_at,
+		       sub.grade, sub.feedback, sub.submitted_at, sub.reviewed_at
+		FROM assignments a
+		JOIN modules m ON m.id = a.module_id
+		JOIN courses c ON c.id = m.course_id
+		LEFT JOIN assignment_submissions sub ON sub.assignment_id = a.id AND sub.user_id = $1
+		WHERE a.module_id = $2 AND c.org_id = $3
+		ORDER BY a.due_at NULLS LAST, a.created_at`, userID, moduleID, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assignments: %w", err)
+	}
+	defer rows.Close()
+
+	assignments := []*Assignment{}
+	for rows.Next() {
+		var a Assignment
+		var subID *uuid.UUID
+		var sub Submission
+		var text, status, feedback *string
+		var late *bool
+		var submittedAt *time.Time
+		err := rows.Scan(&a.ID, &a.ModuleID, &a.CourseID, &a.CreatedBy, &a.Title, &a.Description, &a.DueAt, &a.CreatedAt,
+			&subID, &text, &sub.AttachmentURLs, &status, &late, &sub.Grade, &feedback, &submittedAt, &sub.ReviewedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan assignment: %w", err)
+		}
+		if subID != nil {
+			sub.ID, sub.AssignmentID, sub.UserID = *subID, a.ID, userID
+			sub.Text, sub.Status, sub.Feedback, sub.SubmittedAt = *text, *status, *feedback, *submittedAt
+			sub.Late = late != nil && *late
+			a.MySubmission = &sub
+		}
+		assignments = append(assignments, &a)
+	}
+
+	return assignments, rows.Err()
+}
+
+// getAssignment returns an assignment of orgID, or "assignment not found"
+func (s *AssignmentService) getAssignment(ctx context.Context, tx pgx.Tx, orgID, assignmentID uuid.UUID) (*Assignment, error) {
+	var a Assignment
+	err := tx.QueryRow(ctx, `
+		SELECT `+assignmentColumns+`
+		FROM assignments a
+		JOIN modules m ON m.id = a.module_id
+		JOIN courses c ON c.id = m.course_id
+		WHERE a.id = $1 AND c.org_id = $2`, assignmentID, orgID).Scan(
+		&a.ID, &a.ModuleID, &a.CourseID, &a.CreatedBy, &a.Title, &a.Description, &a.DueAt, &a.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("assignment not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assignment: %w", err)
+	}
+	return &a, nil
+}
+
+// Submit hands in, or replaces, userID's work for an assignment of orgID.
+// A graded submission is final; a returned one goes back to submitted.
+// Work handed in after the due date is accepted and marked late.
+func (s *AssignmentService) Submit(ctx context.Context, userID, orgID, assignmentID uuid.UUID, req SubmitAssignmentRequest) (*Submission, error) {
+	if strings.TrimSpace(req.Text) == "" && len(req.AttachmentURLs) == 0 {
+		return nil, fmt.Errorf("submission is empty")
+	}
+	if req.AttachmentURLs == nil {
+		req.AttachmentURLs = []string{}
+	}
+	if err := validateAttachmentURLs(req.AttachmentURLs); err != nil {
+		return nil, err
+	}
+
+	var sub *Submission
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		if _, err := s.getAssignment(ctx, tx, orgID, assignmentID); err != nil {
+			return err
+		}
+
+		rows, err := tx.Query(ctx, `
+			INSERT INTO assignment_submissions (assignment_id, user_id, text, attachment_urls)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (assignment_id, user_id) DO UPDATE
+			SET text = EXCLUDED.text, attachment_urls = EXCLUDED.attachment_urls,
+			    status = 'submitted', submitted_at = now(), ai_feedback = NULL
+			WHERE assignment_submissions.status <> 'graded'
+			RETURNING `+submissionReturning, assignmentID, userID, req.Text, req.AttachmentURLs)
+		if err != nil {
+			return fmt.Errorf("failed to submit assignment: %w", err)
+		}
+		subs, err := scanSubmissions(rows, false)
+		if err != nil {
+			return err
+		}
+		if len(subs) == 0 {
+			return fmt.Errorf("submission already graded")
+		}
+		sub = subs[0]
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// GetMySubmission returns userID's submission for an assignment
+func (s *AssignmentService) GetMySubmission(ctx context.Context, userID, assignmentID uuid.UUID) (*Submission, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT `+submissionColumns+`
+		FROM assignment_submissions sub
+		JOIN assignments a ON a.id = sub.assignment_id
+		JOIN users u ON u.id = sub.user_id
+		WHERE sub.assignment_id = $1 AND sub.user_id = $2`, assignmentID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get submission: %w", err)
+	}
+	subs, err := scanSubmissions(rows, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(subs) == 0 {
+		return nil, fmt.Errorf("submission not found")
+	}
+	return subs[0], nil
+}
+
+// GetSubmissions lists the submissions to an assignment of orgID, ungraded
+// first, for teachers. status, when set, keeps only that status.
+func (s *AssignmentService) GetSubmissions(ctx context.Context, orgID, assignmentID uuid.UUID, status string, limit, offset int) ([]*Submission, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT `+submissionColumns+`, sub.ai_feedback
+		FROM assignment_submissions sub
+		JOIN assignments a ON a.id = sub.assignment_id
+		JOIN modules m ON m.id = a.module_id
+		JOIN courses c ON c.id = m.course_id
+		JOIN users u ON u.id = sub.user_id
+		WHERE sub.assignment_id = $1 AND c.org_id = $2 AND ($3 = '' OR sub.status = $3)
+		ORDER BY sub.status = 'graded', sub.submitted_at
+		LIMIT $4 OFFSET $5`, assignmentID, orgID, status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get submissions: %w", err)
+	}
+	return scanSubmissions(rows, true)
+}
+
+// ReviewSubmission grades a submission to an assignment of orgID, or
+// returns it to the student for rework
+func (s *AssignmentService) ReviewSubmission(ctx context.Context, reviewerID, orgID, submissionID uuid.UUID, req ReviewSubmissionRequest) (*Submission, error) {
+	if req.Status == SubmissionStatusGraded && req.Grade == nil {
+		return nil, fmt.Errorf("grade is required")
+	}
+	if req.Status == SubmissionStatusReturned {
+		req.Grade = nil
+	}
+
+	rows, err := s.db.Query(ctx, `
+		WITH reviewed AS (
+		    UPDATE assignment_submissions sub
+		    SET status = $3, grade = $4, feedback = $5, reviewed_by = $6, reviewed_at = now()
+		    FROM assignments a, modules m, courses c
+		    WHERE sub.id = $1 AND a.id = sub.assignment_id AND m.id = a.module_id
+		      AND c.id = m.course_id AND c.org_id = $2
+		    RETURNING sub.*
+		)
+		SELECT `+submissionColumns+`, sub.ai_feedback
+		FROM reviewed sub
+		JOIN assignments a ON a.id = sub.assignment_id
+		JOIN users u ON u.id = sub.user_id`,
+		submissionID, orgID, req.Status, req.Grade, req.Feedback, reviewerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to review submission: %w", err)
+	}
+	subs, err := scanSubmissions(rows, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(subs) == 0 {
+		return nil, fmt.Errorf("submission not found")
+	}
+	return subs[0], nil
+}
+
+// GetSubmissionForGrading returns a submission to an assignment of orgID
+// with the assignment it answers, for teachers
+func (s *AssignmentService) GetSubmissionForGrading(ctx context.Context, orgID, submissionID uuid.UUID) (*Submission, *Assignment, error) {
+	var assignment Assignment
+	rows, err := s.db.Query(ctx, `
+		SELECT `+submissionColumns+`, sub.ai_feedback,
+		       `+assignmentColumns+`
+		FROM assignment_submissions sub
+		JOIN assignments a ON a.id = sub.assignment_id
+		JOIN modules m ON m.id = a.module_id
+		JOIN courses c ON c.id = m.course_id
+		JOIN users u ON u.id = sub.user_id
+		WHERE sub.id = $1 AND c.org_id = $2`, submissionID, orgID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get submission: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, nil, fmt.Errorf("failed to get submission: %w", err)
+		}
+		return nil, nil, fmt.Errorf("submission not found")
+	}
+	var sub Submission
+	err = rows.Scan(append(submissionFields(&sub, true),
+		&assignment.ID, &assignment.ModuleID, &assignment.CourseID, &assignment.CreatedBy, &assignment.Title,
+		&assignment.Description, &assignment.DueAt, &assignment.CreatedAt)...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to scan submission: %w", err)
+	}
+	return &sub, &assignment, nil
+}
+
+// SaveAIFeedback keeps AI feedback on a submission for the grading view,
+// replacing any earlier feedback. It doesn't touch the grade or status.
+func (s *AssignmentService) SaveAIFeedback(ctx context.Context, submissionID uuid.UUID, feedback *GradingSuggestion) error {
+	_, err := s.db.Exec(ctx, `
+		UPDATE assignment_submissions SET ai_feedback = $2 WHERE id = $1`, submissionID, feedback)
+	if err != nil {
+		return fmt.Errorf("failed to save AI feedback: %w", err)
+	}
+	return nil
+}
+
+// Run sends assignment due-date reminders every assignmentReminderInterval
+// until ctx is cancelled
+func (s *AssignmentService) Run(ctx context.Context) {
+	ticker := time.NewTicker(assignmentReminderInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.SendReminders(ctx); err != nil {
+			fmt.Printf("Failed to send assignment reminders: %v\n", err)
+			sentry.CaptureError(err, map[string]string{"job": "assignment-reminders"})
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendReminders claims the assignments due within the reminder lead time
+// and reminds the students who haven't handed anything in. Assignments
+// already past due are never reminded.
+func (s *AssignmentService) SendReminders(ctx context.Context) error {
+	rows, err := s.db.Query(ctx, `
+		UPDATE assignments SET reminder_sent_at = now()
+		WHERE reminder_sent_at IS NULL
+		  AND due_at > now()
+		  AND due_at <= now() + make_interval(secs => $1::float8)
+		RETURNING id`, s.reminderLead.Seconds())
+	if err != nil {
+		return fmt.Errorf("failed to claim assignment reminders: %w", err)
+	}
+	assignmentIDs, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return fmt.Errorf("failed to claim assignment reminders: %w", err)
+	}
+
+	for _, assignmentID := range assignmentIDs {
+		if err := s.notificationService.NotifyAssignmentDue(ctx, assignmentID); err != nil {
+			// Log error but continue with other reminders
+			fmt.Printf("Failed to send reminders for assignment %s: %v\n", assignmentID, err)
+		}
+	}
+	return nil
+}
+
+const assignmentColumns = `a.id, a.module_id, m.course_id, a.created_by, a.title, a.description, a.due_at, a.created_at`
+
+const submissionColumns = `sub.id, sub.assignment_id, sub.user_id, u.username, sub.text, sub.attachment_urls, sub.status,
+		       COALESCE(sub.submitted_at > a.due_at, false), sub.grade, sub.feedback, sub.submitted_at, sub.reviewed_at`
+
+// submissionReturning is submissionColumns for a RETURNING clause, where the
+// assignment and user are looked up by subquery
+const submissionReturning = `id, assignment_id, user_id, (SELECT username FROM users WHERE id = user_id), text, attachment_urls, status,
+		       COALESCE(submitted_at > (SELECT due_at FROM assignments WHERE id = assignment_id), false),
+		       grade, feedback, submitted_at, reviewed_at`
+
+// submissionFields are the scan targets for submissionColumns, followed by
+// sub.ai_feedback for teachers
+func submissionFields(sub *Submission, withAIFeedback bool) []any {
+	fields := []any{&sub.ID, &sub.AssignmentID, &sub.UserID, &sub.Username, &sub.Text, &sub.AttachmentURLs, &sub.Status,
+		&sub.Late, &sub.Grade, &sub.Feedback, &sub.SubmittedAt, &sub.ReviewedAt}
+	if withAIFeedback {
+		fields = append(fields, &sub.AIFeedback)
+	}
+	return fields
+}
+
+func scanSubmissions(rows pgx.Rows, withAIFeedback bool) ([]*Submission, error) {
+	defer rows.Close()
+
+	subs := []*Submission{}
+	for rows.Next() {
+		var sub Submission
+		err := rows.Scan(submissionFields(&sub, withAIFeedback)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan submission: %w", err)
+		}
+		subs = append(subs, &sub)
+	}
+
+	return subs, rows.Err()
+}
@@ -0,0 +1,33 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateAttachmentURLs(t *testing.T) {
+	tests := []struct {
+		name    string
+		urls    []string
+		wantErr string
+	}{
+		{"none", nil, ""},
+		{"http and https", []string{"https://drive.example.com/essay.pdf", "http://files.example.com/a.zip"}, ""},
+		{"javascript link", []string{"javascript:alert(1)"}, "invalid attachment URL"},
+		{"data link", []string{"data:text/html,hi"}, "invalid attachment URL"},
+		{"relative path", []string{"/uploads/essay.pdf"}, "invalid attachment URL"},
+		{"too many", []string{"https://a.io/1", "https://a.io/2", "https://a.io/3", "https://a.io/4", "https://a.io/5", "https://a.io/6"}, "too many attachments"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAttachmentURLs(tt.urls)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -7,15 +7,19 @@ import (
 	"strings"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"golang.org/x/crypto/bcrypt"
 
 	"bailanysta/api/internal/pkg/auth"
+	"bailanysta/api/internal/pkg/database"
+	"bailanysta/api/pkg/jwks"
 )
 
 type AuthService struct {
-	db         *pgxpool.Pool
+	db         *database.Pool
 	jwtManager *auth.JWTManager
+	devices    *DeviceService
 }
 
 type User struct {
@@ -24,12 +28,17 @@ type User struct {
 	Email     string         `json:"email"`
 	Bio       sql.NullString `json:"bio"`
 	AvatarURL sql.NullString `json:"avatar_url"`
+	OrgID     uuid.UUID      `json:"org_id"`
 }
 
 type RegisterRequest struct {
 	Username string `json:"username" validate:"required,min=3,max=50"`
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required,min=6"`
+	// Slug of the school to join; the default organization when empty
+	Organization string `json:"organization,omitempty"`
+	// Token from a waitlist invite; required when registration is invite-only
+	InviteToken string `json:"invite_token,omitempty"`
 }
 
 type LoginRequest struct {
@@ -43,24 +52,35 @@ type AuthResponse struct {
 }
 
 type UserResponse struct {
-	ID             uuid.UUID `json:"id"`
-	Username       string    `json:"username"`
-	Email          string    `json:"email"`
-	Bio            string    `json:"bio"`
-	AvatarURL      *string   `json:"avatar_url,omitempty"`
-	FollowersCount int       `json:"followers_count,omitempty"`
-	FollowingCount int       `json:"following_count,omitempty"`
-	IsFollowing    bool      `json:"is_following,omitempty"`
+	ID              uuid.UUID `json:"id"`
+	Username        string    `json:"username"`
+	Email           string    `json:"email"`
+	Bio             string    `json:"bio"`
+	AvatarURL       *string   `json:"avatar_url,omitempty"`
+	FollowersCount  int       `json:"followers_count,omitempty"`
+	FollowingCount  int       `json:"following_count,omitempty"`
+	IsFollowing     bool      `json:"is_following,omitempty"`
+	IsPrivate       bool      `json:"is_private,omitempty"`
+	FollowRequested bool      `json:"follow_requested,omitempty"`
+	AIContent       string    `json:"ai_content,omitempty"`     // own profile only
+	FeedLanguages   []string  `json:"feed_languages,omitempty"` // own profile only
+	Timezone        string    `json:"timezone,omitempty"`       // own profile only
+	ShowPresence    *bool     `json:"show_presence,omitempty"`  // own profile only
+	Presence        *Presence `json:"presence,omitempty"`       // unset when hidden
+	Role            Role      `json:"role,omitempty"`
+	// Impersonation is set in /me while support staff act as the user
+	Impersonation *ImpersonationInfo `json:"impersonation,omitempty"`
 }
 
-func NewAuthService(db *pgxpool.Pool, jwtManager *auth.JWTManager) *AuthService {
+func NewAuthService(db *database.Pool, jwtManager *auth.JWTManager, devices *DeviceService) *AuthService {
 	return &AuthService{
 		db:         db,
 		jwtManager: jwtManager,
+		devices:    devices,
 	}
 }
 
-func (s *AuthService) Register(ctx context.Context, req RegisterRequest) (*AuthResponse, error) {
+func (s *AuthService) Register(ctx context.Context, req RegisterRequest, client ClientInfo) (*AuthResponse, error) {
 	// Check if user already exists
 	var existingUser User
 	err := s.db.QueryRow(ctx, "SELECT id, username, email FROM users WHERE email = $1", req.Email).Scan(&existingUser.ID, &existingUser.Username, &existingUser.Email)
@@ -73,6 +93,25 @@ func (s *AuthService) Register(ctx context.Context, req RegisterRequest) (*AuthR
 		return nil, fmt.Errorf("user with this username already exists")
 	}
 
+	// Former usernames stay reserved for their owner
+	var reserved bool
+	err = s.db.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM username_history WHERE username = $1)", req.Username).Scan(&reserved)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check username: %w", err)
+	}
+	if reserved {
+		return nil, fmt.Errorf("user with this username already exists")
+	}
+
+	orgID := DefaultOrganizationID
+	if req.Organization != "" {
+		org, err := s.GetOrganizationBySlug(ctx, req.Organization)
+		if err != nil {
+			return nil, err
+		}
+		orgID = org.ID
+	}
+
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -81,18 +120,19 @@ func (s *AuthService) Register(ctx context.Context, req RegisterRequest) (*AuthR
 
 	// Create user
 	var user User
+	var role Role
 	err = s.db.QueryRow(ctx, `
-		INSERT INTO users (username, email, password_hash, bio, avatar_url)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, username, email, bio, avatar_url`,
-		req.Username, req.Email, string(hashedPassword), nil, nil).Scan(
-		&user.ID, &user.Username, &user.Email, &user.Bio, &user.AvatarURL)
+		INSERT INTO users (username, email, password_hash, bio, avatar_url, org_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, username, email, bio, avatar_url, org_id, role`,
+		req.Username, req.Email, string(hashedPassword), nil, nil, orgID).Scan(
+		&user.ID, &user.Username, &user.Email, &user.Bio, &user.AvatarURL, &user.OrgID, &role)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
 	// Generate tokens
-	tokens, err := s.jwtManager.GenerateTokenPair(user.ID)
+	tokens, err := s.startSession(ctx, user.ID, user.OrgID, role, client)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate tokens: %w", err)
 	}
@@ -109,14 +149,15 @@ func (s *AuthService) Register(ctx context.Context, req RegisterRequest) (*AuthR
 	}, nil
 }
 
-func (s *AuthService) Login(ctx context.Context, req LoginRequest) (*AuthResponse, error) {
+func (s *AuthService) Login(ctx context.Context, req LoginRequest, client ClientInfo) (*AuthResponse, error) {
 	// Get user by email
 	var user User
 	var passwordHash string
+	var role Role
 	err := s.db.QueryRow(ctx, `
-		SELECT id, username, email, password_hash, bio, avatar_url
+		SELECT id, username, email, password_hash, bio, avatar_url, org_id, role
 		FROM users WHERE email = $1`, req.Email).Scan(
-		&user.ID, &user.Username, &user.Email, &passwordHash, &user.Bio, &user.AvatarURL)
+		&user.ID, &user.Username, &user.Email, &passwordHash, &user.Bio, &user.AvatarURL, &user.OrgID, &role)
 	if err != nil {
 		return nil, fmt.Errorf("invalid email or password")
 	}
@@ -128,7 +169,7 @@ func (s *AuthService) Login(ctx context.Context, req LoginRequest) (*AuthRespons
 	}
 
 	// Generate tokens
-	tokens, err := s.jwtManager.GenerateTokenPair(user.ID)
+	tokens, err := s.startSession(ctx, user.ID, user.OrgID, role, client)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate tokens: %w", err)
 	}
@@ -145,44 +186,95 @@ func (s *AuthService) Login(ctx context.Context, req LoginRequest) (*AuthRespons
 	}, nil
 }
 
-func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (*auth.TokenPair, error) {
-	// Validate refresh token
-	if err := s.jwtManager.ValidateRefreshToken(refreshToken); err != nil {
-		return nil, fmt.Errorf("invalid refresh token: %w", err)
-	}
-
-	// For now, we need to extract user ID from refresh token
-	// In production, you'd store refresh tokens in database with user association
-	return nil, fmt.Errorf("refresh token functionality not implemented yet")
-}
-
 func (s *AuthService) ValidateToken(tokenString string) (*auth.Claims, error) {
 	return s.jwtManager.ValidateAccessToken(tokenString)
 }
 
+// JWKS returns the public keys of the access token signer
+func (s *AuthService) JWKS() jwks.Set {
+	return s.jwtManager.JWKS()
+}
+
 func (s *AuthService) GetDB() *pgxpool.Pool {
-	return s.db
+	return s.db.Pool
+}
+
+// GetReadDB returns the pool for lookups that tolerate replication lag
+func (s *AuthService) GetReadDB() *pgxpool.Pool {
+	return s.db.Reader()
 }
 
 func (s *AuthService) GetCurrentUser(ctx context.Context, userID uuid.UUID) (*UserResponse, error) {
 	var user User
+	var isPrivate bool
+	var aiContent string
+	var feedLanguages []string
+	var timezone string
+	var role Role
+	var showPresence bool
 	err := s.db.QueryRow(ctx, `
-		SELECT id, username, email, bio, avatar_url
+		SELECT id, username, email, bio, avatar_url, is_private, ai_content, feed_languages, timezone, role, show_presence
 		FROM users WHERE id = $1`, userID).Scan(
-		&user.ID, &user.Username, &user.Email, &user.Bio, &user.AvatarURL)
+		&user.ID, &user.Username, &user.Email, &user.Bio, &user.AvatarURL, &isPrivate, &aiContent, &feedLanguages, &timezone, &role, &showPresence)
 	if err != nil {
 		return nil, fmt.Errorf("user not found: %w", err)
 	}
 
 	return &UserResponse{
-		ID:        user.ID,
-		Username:  user.Username,
-		Email:     user.Email,
-		Bio:       getNullStringValue(user.Bio),
-		AvatarURL: getNullStringPtr(user.AvatarURL),
+		ID:            user.ID,
+		Username:      user.Username,
+		Email:         user.Email,
+		Bio:           getNullStringValue(user.Bio),
+		AvatarURL:     getNullStringPtr(user.AvatarURL),
+		IsPrivate:     isPrivate,
+		AIContent:     aiContent,
+		FeedLanguages: feedLanguages,
+		Timezone:      timezone,
+		ShowPresence:  &showPresence,
+		Role:          role,
 	}, nil
 }
 
+// GetUsersByIDs returns the public profiles of many users in viewerID's
+// organization at once, keyed by ID; other IDs are left out
+func (s *AuthService) GetUsersByIDs(ctx context.Context, viewerID uuid.UUID, userIDs []uuid.UUID) (map[uuid.UUID]*UserResponse, error) {
+	rows, err := s.db.Reader().Query(ctx, `
+		SELECT id, username, email, bio, avatar_url, is_private
+		FROM users
+		WHERE id = ANY($1) AND org_id = (SELECT org_id FROM users WHERE id = $2)`, userIDs, viewerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+	defer rows.Close()
+
+	users := make(map[uuid.UUID]*UserResponse, len(userIDs))
+	for rows.Next() {
+		var user UserResponse
+		var bio, avatarURL pgtype.Text
+		if err := rows.Scan(&user.ID, &user.Username, &user.Email, &bio, &avatarURL, &user.IsPrivate); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		user.Bio = getPgtypeTextValue(bio)
+		user.AvatarURL = getPgtypeTextPtr(avatarURL)
+		users[user.ID] = &user
+	}
+	return users, rows.Err()
+}
+
+// SetShadowBanned hides or unhides a user's posts, comments and activity
+// from everyone but the user
+func (s *AuthService) SetShadowBanned(ctx context.Context, orgID, userID uuid.UUID, banned bool) error {
+	result, err := s.db.Exec(ctx, `
+		UPDATE users SET shadow_banned = $3 WHERE id = $1 AND org_id = $2`, userID, orgID, banned)
+	if err != nil {
+		return fmt.Errorf("failed to update shadow ban: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
+
 // Helper functions
 func hashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
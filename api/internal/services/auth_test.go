@@ -1,6 +1,7 @@
 package services
 
 import (
+	"strings"
 	"testing"
 
 	"golang.org/x/crypto/bcrypt"
@@ -30,3 +31,26 @@ func TestCheckPasswordHash(t *testing.T) {
 	result = checkPasswordHash("wrongpassword", string(hashed))
 	assert.False(t, result)
 }
+
+func TestValidUsername(t *testing.T) {
+	tests := []struct {
+		username string
+		want     bool
+	}{
+		{"aigerim", true},
+		{"john.doe-99", true},
+		{"_underscore", true},
+		{"ab", false},
+		{".dotfirst", false},
+		{"has space", false},
+		{"emoji😀", false},
+		{strings.Repeat("a", 50), true},
+		{strings.Repeat("a", 51), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.username, func(t *testing.T) {
+			assert.Equal(t, tt.want, ValidUsername(tt.username))
+		})
+	}
+}
@@ -0,0 +1,407 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"bailanysta/api/internal/pkg/database"
+	"bailanysta/api/internal/pkg/s3"
+	"bailanysta/api/internal/pkg/sentry"
+)
+
+const (
+	backupCheckInterval = 15 * time.Minute
+	backupManifestName  = "manifest.json"
+	backupIDLayout      = "20060102T150405Z"
+	// Session advisory lock so only one instance backs up at a time
+	backupLockKey = 7_300_531
+)
+
+// BackupTables are the tables a backup holds, in restore order
+var BackupTables = []string{"users", "posts", "comments", "follows"}
+
+// BackupService snapshots BackupTables to S3 on a schedule and restores
+// them. A backup is a folder of gzipped CSV files, one per table, plus a
+// manifest written last, so folders without one are incomplete.
+type BackupService struct {
+	db        *database.Pool
+	store     *s3.Client
+	prefix    string
+	interval  time.Duration
+	retention int
+}
+
+// Backup is a complete snapshot in the bucket
+type Backup struct {
+	ID        string         `json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	Size      int64          `json:"size"`
+	Tables    []*BackupTable `json:"tables"`
+}
+
+type BackupTable struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	Rows    int64    `json:"rows"`
+}
+
+// NewBackupService creates the backup job; it keeps the newest retention
+// backups (0 keeps all) under prefix
+func NewBackupService(db *database.Pool, store *s3.Client, prefix string, interval time.Duration, retention int) *BackupService {
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return &BackupService{
+		db:        db,
+		store:     store,
+		prefix:    prefix,
+		interval:  interval,
+		retention: retention,
+	}
+}
+
+// Run takes a backup whenever the newest one is older than the interval,
+// until ctx is cancelled
+func (s *BackupService) Run(ctx context.Context) {
+	ticker := time.NewTicker(backupCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.backupIfDue(ctx); err != nil && ctx.Err() == nil {
+			fmt.Printf("Failed to back up: %v\n", err)
+			sentry.CaptureError(err, map[string]string{"job": "backups"})
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *BackupService) backupIfDue(ctx context.Context) error {
+	conn, err := s.db.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	var locked bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, backupLockKey).Scan(&locked); err != nil {
+		return fmt.Errorf("failed to take backup lock: %w", err)
+	}
+	if !locked {
+		return nil
+	}
+	defer conn.Exec(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, backupLockKey)
+
+	backups, err := s.List(ctx)
+	if err != nil {
+		return err
+	}
+	if len(backups) > 0 && time.Since(backups[0].CreatedAt) < s.interval {
+		return nil
+	}
+
+	_, err = s.Backup(ctx)
+	return err
+}
+
+// Backup snapshots BackupTables from one consistent transaction, then
+// drops backups beyond the retention count
+func (s *BackupService) Backup(ctx context.Context) (*Backup, error) {
+	now := time.Now().UTC()
+	backup := &Backup{ID: now.Format(backupIDLayout), CreatedAt: now}
+	folder := s.prefix + backup.ID + "/"
+
+	err := pgx.BeginTxFunc(ctx, s.db, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}, func(tx pgx.Tx) error {
+		for _, table := range BackupTables {
+			dumped, size, err := s.backupTable(ctx, tx, table, folder)
+			if err != nil {
+				return err
+			}
+			backup.Tables = append(backup.Tables, dumped)
+			backup.Size += size
+		}
+		return nil
+	})
+	if err == nil {
+		var manifest []byte
+		manifest, err = json.MarshalIndent(backup, "", "  ")
+		if err == nil {
+			err = s.store.PutObject(ctx, folder+backupManifestName, bytes.NewReader(manifest), int64(len(manifest)), "application/json")
+		}
+	}
+	if err != nil {
+		// Without a manifest the folder is ignored; remove what was uploaded
+		s.deleteFolder(context.WithoutCancel(ctx), folder)
+		return nil, fmt.Errorf("failed to back up: %w", err)
+	}
+
+	if err := s.prune(ctx); err != nil {
+		fmt.Printf("Failed to prune backups: %v\n", err)
+	}
+	return backup, nil
+}
+
+// backupTable dumps table as gzipped CSV into a temp file and uploads it;
+// S3 needs the size before the upload starts
+func (s *BackupService) backupTable(ctx context.Context, tx pgx.Tx, table, folder string) (*BackupTable, int64, error) {
+	columns, err := tableColumns(ctx, tx, table)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	file, err := os.CreateTemp("", "backup-"+table+"-*.csv.gz")
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	gz := gzip.NewWriter(file)
+	tag, err := tx.Conn().PgConn().CopyTo(ctx, gz,
+		`COPY (SELECT `+columnList(columns)+` FROM `+pgx.Identifier{table}.Sanitize()+`) TO STDOUT WITH (FORMAT csv, HEADER)`)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to dump %s: %w", table, err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, 0, fmt.Errorf("failed to compress %s: %w", table, err)
+	}
+
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read dump of %s: %w", table, err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, 0, fmt.Errorf("failed to read dump of %s: %w", table, err)
+	}
+	if err := s.store.PutObject(ctx, folder+table+".csv.gz", file, size, "application/gzip"); err != nil {
+		return nil, 0, fmt.Errorf("failed to upload %s: %w", table, err)
+	}
+
+	return &BackupTable{Name: table, Columns: columns, Rows: tag.RowsAffected()}, size, nil
+}
+
+// List returns complete backups, newest first
+func (s *BackupService) List(ctx context.Context) ([]*Backup, error) {
+	objects, err := s.store.ListObjects(ctx, s.prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	var backups []*Backup
+	sizes := make(map[string]int64)
+	for _, object := range objects {
+		id, name, ok := strings.Cut(strings.TrimPrefix(object.Key, s.prefix), "/")
+		if !ok {
+			continue
+		}
+		sizes[id] += object.Size
+		if name != backupManifestName {
+			continue
+		}
+		createdAt, err := time.Parse(backupIDLayout, id)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, &Backup{ID: id, CreatedAt: createdAt})
+	}
+	for _, backup := range backups {
+		backup.Size = sizes[backup.ID]
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+	return backups, nil
+}
+
+// Restore copies rows of backup id ("latest" for the newest) back into their
+// tables. Existing rows win: only rows missing from the database, such as
+// deleted ones, are inserted. It returns the number restored per table.
+func (s *BackupService) Restore(ctx context.Context, id string) (map[string]int64, error) {
+	if id == "latest" {
+		backups, err := s.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if len(backups) == 0 {
+			return nil, fmt.Errorf("backup not found")
+		}
+		id = backups[0].ID
+	}
+
+	if _, err := time.Parse(backupIDLayout, id); err != nil {
+		return nil, fmt.Errorf("backup not found")
+	}
+	backup, err := s.manifest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, table := range backup.Tables {
+		if !slices.Contains(BackupTables, table.Name) {
+			return nil, fmt.Errorf("backup has unexpected table %q", table.Name)
+		}
+	}
+
+	restored := make(map[string]int64)
+	err = pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		for _, table := range backup.Tables {
+			count, err := s.restoreTable(ctx, tx, s.prefix+id+"/", table)
+			if err != nil {
+				return err
+			}
+			restored[table.Name] = count
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
+
+func (s *BackupService) manifest(ctx context.Context, id string) (*Backup, error) {
+	body, err := s.store.GetObject(ctx, s.prefix+id+"/"+backupManifestName)
+	if errors.Is(err, s3.ErrNotFound) {
+		return nil, fmt.Errorf("backup not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get backup manifest: %w", err)
+	}
+	defer body.Close()
+
+	var backup Backup
+	if err := json.NewDecoder(body).Decode(&backup); err != nil {
+		return nil, fmt.Errorf("failed to read backup manifest: %w", err)
+	}
+	return &backup, nil
+}
+
+// restoreTable loads a dump into a temp table and inserts the rows whose
+// keys are missing from the real one
+func (s *BackupService) restoreTable(ctx context.Context, tx pgx.Tx, folder string, table *BackupTable) (int64, error) {
+	name := pgx.Identifier{table.Name}.Sanitize()
+	staging := pgx.Identifier{"restore_" + table.Name}.Sanitize()
+	columns := columnList(table.Columns)
+
+	_, err := tx.Exec(ctx, `CREATE TEMP TABLE `+staging+` ON COMMIT DROP AS SELECT `+columns+` FROM `+name+` WITH NO DATA`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare restore of %s: %w", table.Name, err)
+	}
+
+	body, err := s.store.GetObject(ctx, folder+table.Name+".csv.gz")
+	if err != nil {
+		return 0, fmt.Errorf("failed to download %s: %w", table.Name, err)
+	}
+	defer body.Close()
+	gz, err := gzip.NewReader(body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to decompress %s: %w", table.Name, err)
+	}
+
+	_, err = tx.Conn().PgConn().CopyFrom(ctx, gz, `COPY `+staging+` (`+columns+`) FROM STDIN WITH (FORMAT csv, HEADER)`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load %s: %w", table.Name, err)
+	}
+
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO `+name+` (`+columns+`)
+		SELECT `+columns+` FROM `+staging+`
+		ON CONFLICT DO NOTHING`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to restore %s: %w", table.Name, err)
+	}
+
+	// Restored serial values must not be handed out again
+	for _, column := range table.Columns {
+		_, err := tx.Exec(ctx, `
+			SELECT setval(seq, GREATEST((SELECT COALESCE(MAX(`+pgx.Identifier{column}.Sanitize()+`), 1) FROM `+name+`),
+			                            COALESCE(pg_sequence_last_value(seq), 1)))
+			FROM (SELECT pg_get_serial_sequence($1, $2)::regclass AS seq) s
+			WHERE seq IS NOT NULL`, table.Name, column)
+		if err != nil {
+			return 0, fmt.Errorf("failed to reset sequence of %s.%s: %w", table.Name, column, err)
+		}
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// prune deletes backups beyond the retention count
+func (s *BackupService) prune(ctx context.Context) error {
+	if s.retention <= 0 {
+		return nil
+	}
+	backups, err := s.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, backup := range backups[min(s.retention, len(backups)):] {
+		if err := s.deleteFolder(ctx, s.prefix+backup.ID+"/"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteFolder removes the manifest first so a partly deleted backup is
+// never listed
+func (s *BackupService) deleteFolder(ctx context.Context, folder string) error {
+	objects, err := s.store.ListObjects(ctx, folder)
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", folder, err)
+	}
+	sort.SliceStable(objects, func(i, j int) bool {
+		return strings.HasSuffix(objects[i].Key, "/"+backupManifestName)
+	})
+	for _, object := range objects {
+		if err := s.store.DeleteObject(ctx, object.Key); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", object.Key, err)
+		}
+	}
+	return nil
+}
+
+// tableColumns lists the columns of table that can be inserted into
+func tableColumns(ctx context.Context, tx pgx.Tx, table string) ([]string, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT column_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1 AND is_generated = 'NEVER'
+		ORDER BY ordinal_position`, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get columns of %s: %w", table, err)
+	}
+	columns, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to get columns of %s: %w", table, err)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("table %s not found", table)
+	}
+	return columns, nil
+}
+
+// columnList quotes columns for use in SQL
+func columnList(columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = pgx.Identifier{column}.Sanitize()
+	}
+	return strings.Join(quoted, ", ")
+}
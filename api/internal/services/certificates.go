@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"bailanysta/api/internal/pkg/database"
+	"bailanysta/api/internal/pkg/pdf"
+)
+
+// certificateCodeAlphabet leaves out 0/O and 1/I so codes can be typed
+// from a printout
+const certificateCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+type Certificate struct {
+	ID          uuid.UUID `json:"id"`
+	Code        string    `json:"code"`
+	UserID      uuid.UUID `json:"user_id"`
+	Username    string    `json:"username"`
+	CourseID    uuid.UUID `json:"course_id"`
+	CourseTitle string    `json:"course_title"`
+	IssuedAt    time.Time `json:"issued_at"`
+	VerifyURL   string    `json:"verify_url"`
+}
+
+type CertificateService struct {
+	db     *database.Pool
+	apiURL string
+}
+
+// NewCertificateService creates the service; verify links on certificates
+// point at apiURL
+func NewCertificateService(db *database.Pool, apiURL string) *CertificateService {
+	return &CertificateService{
+		db:     db,
+		apiURL: strings.TrimRight(apiURL, "/"),
+	}
+}
+
+// newCertificateCode returns a random code like 7KQX-M2PA-ZR4D-H9WC
+func newCertificateCode() (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for i, c := range raw {
+		if i > 0 && i%4 == 0 {
+			b.WriteByte('-')
+		}
+		b.WriteByte(certificateCodeAlphabet[int(c)%len(certificateCodeAlphabet)])
+	}
+	return b.String(), nil
+}
+
+// normalizeCertificateCode accepts a code as typed: in any case, with or
+// without the dashes, or with spaces instead
+func normalizeCertificateCode(code string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range strings.ToUpper(code) {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			continue
+		}
+		if n > 0 && n%4 == 0 {
+			b.WriteByte('-')
+		}
+		b.WriteRune(r)
+		n++
+	}
+	return b.String()
+}
+
+// issueCertificate gives userID a certificate for courseID, unless they
+// already have one
+func issueCertificate(ctx context.Context, tx pgx.Tx, userID, courseID uuid.UUID) error {
+	code, err := newCertificateCode()
+	if err != nil {
+		return fmt.Errorf("failed to generate certificate code: %w", err)
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO certificates (user_id, course_id, code) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, course_id) DO NOTHING`, userID, courseID, code)
+	if err != nil {
+		return fmt.Errorf("failed to issue certificate: %w", err)
+	}
+	return nil
+}
+
+// GetMyCertificates lists userID's certificates, newest first
+func (s *CertificateService) GetMyCertificates(ctx context.Context, userID uuid.UUID) ([]*Certificate, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT `+certificateColumns+`
+		FROM certificates cert
+		JOIN users u ON u.id = cert.user_id
+		JOIN courses c ON c.id = cert.course_id
+		WHERE cert.user_id = $1
+		ORDER BY cert.issued_at DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get certificates: %w", err)
+	}
+	return s.scanCertificates(rows)
+}
+
+// VerifyCertificate looks a certificate up by its code, for anyone holding
+// a printout. It fails with "certificate not found" for unknown codes.
+func (s *CertificateService) VerifyCertificate(ctx context.Context, code string) (*Certificate, error) {
+	rows, err := s.db.Reader().Query(ctx, `
+		SELECT `+certificateColumns+`
+		FROM certificates cert
+		JOIN users u ON u.id = cert.user_id
+		JOIN courses c ON c.id = cert.course_id
+		WHERE cert.code = $1`, normalizeCertificateCode(code))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get certificate: %w", err)
+	}
+	certificates, err := s.scanCertificates(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(certificates) == 0 {
+		return nil, fmt.Errorf("certificate not found")
+	}
+	return certificates[0], nil
+}
+
+// CertificatePDF renders one of userID's certificates as a PDF
+func (s *CertificateService) CertificatePDF(ctx context.Context, userID uuid.UUID, code string) ([]byte, *Certificate, error) {
+	certificate, err := s.VerifyCertificate(ctx, code)
+	if err != nil {
+		return nil, nil, err
+	}
+	if certificate.UserID != userID {
+		return nil, nil, fmt.Errorf("certificate not found")
+	}
+	return renderCertificate(certificate), certificate, nil
+}
+
+// renderCertificate lays a certificate out on a landscape A4 page
+func renderCertificate(certificate *Certificate) []byte {
+	page := pdf.NewPage(pdf.A4Height, pdf.A4Width)
+	center := pdf.A4Height / 2
+
+	page.Rect(30, 30, pdf.A4Height-60, pdf.A4Width-60, 3)
+	page.Rect(40, 40, pdf.A4Height-80, pdf.A4Width-80, 0.75)
+	page.Text(center, 440, 36, true, pdf.AlignCenter, "Certificate of Completion")
+	page.Text(center, 380, 14, false, pdf.AlignCenter, "This certifies that")
+	page.Text(center, 335, 28, true, pdf.AlignCenter, certificate.Username)
+	page.Text(center, 290, 14, false, pdf.AlignCenter, "has completed every module of the course")
+	page.Text(center, 250, 22, true, pdf.AlignCenter, truncateRunes(certificate.CourseTitle, 70))
+	page.Text(center, 190, 12, false, pdf.AlignCenter, "Issued "+certificate.IssuedAt.UTC().Format("2 January 2006")+" by Bailanysta")
+	page.Text(center, 95, 11, false, pdf.AlignCenter, "Certificate code: "+certificate.Code)
+	page.Text(center, 78, 9, false, pdf.AlignCenter, "Verify at "+certificate.VerifyURL)
+	return page.Bytes()
+}
+
+const certificateColumns = `cert.id, cert.code, cert.user_id, u.username, cert.course_id, c.title, cert.issued_at`
+
+func (s *CertificateService) scanCertificates(rows pgx.Rows) ([]*Certificate, error) {
+	defer rows.Close()
+
+	certificates := []*Certificate{}
+	for rows.Next() {
+		var certificate Certificate
+		err := rows.Scan(&certificate.ID, &certificate.Code, &certificate.UserID, &certificate.Username,
+			&certificate.CourseID, &certificate.CourseTitle, &certificate.IssuedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan certificate: %w", err)
+		}
+		certificate.VerifyURL = s.apiURL + "/api/v1/certificates/" + certificate.Code + "/verify"
+		certificates = append(certificates, &certificate)
+	}
+
+	return certificates, rows.Err()
+}
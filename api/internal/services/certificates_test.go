@@ -0,0 +1,53 @@
+package services
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCertificateCode(t *testing.T) {
+	code, err := newCertificateCode()
+	require.NoError(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^[A-HJ-NP-Z2-9]{4}(-[A-HJ-NP-Z2-9]{4}){3}$`), code)
+	assert.Equal(t, code, normalizeCertificateCode(code))
+}
+
+func TestNormalizeCertificateCode(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"7KQX-M2PA-ZR4D-H9WC", "7KQX-M2PA-ZR4D-H9WC"},
+		{"7kqx-m2pa-zr4d-h9wc", "7KQX-M2PA-ZR4D-H9WC"},
+		{"7KQXM2PAZR4DH9WC", "7KQX-M2PA-ZR4D-H9WC"},
+		{" 7KQX M2PA ZR4D H9WC ", "7KQX-M2PA-ZR4D-H9WC"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			assert.Equal(t, tt.want, normalizeCertificateCode(tt.in))
+		})
+	}
+}
+
+func TestRenderCertificate(t *testing.T) {
+	document := renderCertificate(&Certificate{
+		Code:        "7KQX-M2PA-ZR4D-H9WC",
+		Username:    "aigerim",
+		CourseTitle: "Алгоритмы (основы)",
+		IssuedAt:    time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+		VerifyURL:   "https://api.example.com/api/v1/certificates/7KQX-M2PA-ZR4D-H9WC/verify",
+	})
+
+	assert.True(t, bytes.HasPrefix(document, []byte("%PDF-1.4\n")))
+	assert.True(t, bytes.HasSuffix(document, []byte("%%EOF\n")))
+	assert.Contains(t, string(document), "(aigerim) Tj")
+	assert.Contains(t, string(document), `(Algoritmy \(osnovy\)) Tj`)
+	assert.Contains(t, string(document), "(Issued 1 March 2025 by Bailanysta) Tj")
+}
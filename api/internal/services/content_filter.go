@@ -0,0 +1,289 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"bailanysta/api/internal/pkg/metrics"
+)
+
+type FilterAction string
+
+const (
+	// FilterActionReject refuses the content outright
+	FilterActionReject FilterAction = "reject"
+	// FilterActionFlag publishes the content and records the hit without
+	// telling the author
+	FilterActionFlag FilterAction = "flag"
+	// FilterActionReview publishes the content and queues it for moderators
+	FilterActionReview FilterAction = "review"
+)
+
+const (
+	ContentTypePost    = "post"
+	ContentTypeComment = "comment"
+)
+
+const (
+	FilterRuleWordlist  = "wordlist"
+	FilterRuleLinks     = "links"
+	FilterRuleDuplicate = "duplicate"
+)
+
+var (
+	contentFilterChecks = metrics.NewCounterVec("content_filter_checks_total",
+		"Posts and comments checked by the content filter.", "content_type")
+	contentFilterHits = metrics.NewCounterVec("content_filter_hits_total",
+		"Content filter rule matches by rule and action.", "rule", "action")
+)
+
+// ContentFilterConfig sets up the rules; a rule with a zero limit or an empty
+// wordlist is off
+type ContentFilterConfig struct {
+	Words           []string
+	WordsAction     FilterAction
+	MaxLinks        int
+	LinksAction     FilterAction
+	DuplicateWindow time.Duration
+	DuplicateAction FilterAction
+}
+
+type FilterHit struct {
+	Rule   string
+	Action FilterAction
+}
+
+type ReviewItem struct {
+	ID          uuid.UUID `json:"id"`
+	ContentType string    `json:"content_type"`
+	ContentID   uuid.UUID `json:"content_id"`
+	UserID      uuid.UUID `json:"user_id"`
+	Username    string    `json:"username"`
+	Rule        string    `json:"rule"`
+	Text        string    `json:"text"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type ResolveReviewRequest struct {
+	Decision string `json:"decision" validate:"required,oneof=approve remove"`
+}
+
+type ContentFilterService struct {
+	db     *pgxpool.Pool
+	config ContentFilterConfig
+	words  []string
+}
+
+func NewContentFilterService(db *pgxpool.Pool, config ContentFilterConfig) *ContentFilterService {
+	var words []string
+	for _, word := range config.Words {
+		if normalized := strings.TrimSpace(normalizeWords(word)); normalized != "" {
+			words = append(words, normalized)
+		}
+	}
+
+	return &ContentFilterService{
+		db:     db,
+		config: config,
+		words:  words,
+	}
+}
+
+// Check runs every rule against text written by userID and returns the
+// matches. The caller rejects the content if any hit has FilterActionReject.
+func (s *ContentFilterService) Check(ctx context.Context, userID uuid.UUID, contentType, text string) ([]FilterHit, error) {
+	contentFilterChecks.Inc(contentType)
+
+	var hits []FilterHit
+	if matchesWordlist(text, s.words) {
+		hits = append(hits, FilterHit{Rule: FilterRuleWordlist, Action: s.config.WordsAction})
+	}
+	if s.config.MaxLinks > 0 && isLinkSpam(text, s.config.MaxLinks) {
+		hits = append(hits, FilterHit{Rule: FilterRuleLinks, Action: s.config.LinksAction})
+	}
+	if s.config.DuplicateWindow > 0 {
+		duplicate, err := s.isDuplicate(ctx, userID, contentType, text)
+		if err != nil {
+			return nil, err
+		}
+		if duplicate {
+			hits = append(hits, FilterHit{Rule: FilterRuleDuplicate, Action: s.config.DuplicateAction})
+		}
+	}
+
+	for _, hit := range hits {
+		contentFilterHits.Inc(hit.Rule, string(hit.Action))
+	}
+	return hits, nil
+}
+
+// isDuplicate reports whether the user posted the same text recently
+func (s *ContentFilterService) isDuplicate(ctx context.Context, userID uuid.UUID, contentType, text string) (bool, error) {
+	table := "posts"
+	if contentType == ContentTypeComment {
+		table = "comments"
+	}
+
+	var duplicate bool
+	err := s.db.QueryRow(ctx, `
+		SELECT EXISTS(
+		    SELECT 1 FROM `+table+`
+		    WHERE author_id = $1 AND text = $2 AND created_at > now() - make_interval(secs => $3::float8)
+		)`, userID, text, s.config.DuplicateWindow.Seconds()).Scan(&duplicate)
+	if err != nil {
+		return false, fmt.Errorf("failed to check duplicate content: %w", err)
+	}
+	return duplicate, nil
+}
+
+// recordFilterHits stores flag and review hits for newly created content
+func recordFilterHits(ctx context.Context, tx pgx.Tx, userID uuid.UUID, contentType string, contentID uuid.UUID, hits []FilterHit) error {
+	for _, hit := range hits {
+		if hit.Action == FilterActionReject {
+			continue
+		}
+
+		_, err := tx.Exec(ctx, `
+			INSERT INTO content_flags (content_type, content_id, user_id, rule, action)
+			VALUES ($1, $2, $3, $4, $5)`, contentType, contentID, userID, hit.Rule, string(hit.Action))
+		if err != nil {
+			return fmt.Errorf("failed to record content flag: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetReviewQueue lists orgID's unresolved review items, oldest first
+func (s *ContentFilterService) GetReviewQueue(ctx context.Context, orgID uuid.UUID, limit, offset int) ([]*ReviewItem, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT f.id, f.content_type, f.content_id, f.user_id, u.username, f.rule,
+		       COALESCE(p.text, c.text), f.created_at
+		FROM content_flags f
+		JOIN users u ON u.id = f.user_id
+		LEFT JOIN posts p ON f.content_type = 'post' AND p.id = f.content_id
+		LEFT JOIN comments c ON f.content_type = 'comment' AND c.id = f.content_id
+		WHERE f.action = 'review' AND f.resolved_at IS NULL AND u.org_id = $1
+		  AND (p.id IS NOT NULL OR c.id IS NOT NULL)
+		ORDER BY f.created_at
+		LIMIT $2 OFFSET $3`, orgID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get review queue: %w", err)
+	}
+	defer rows.Close()
+
+	items := []*ReviewItem{}
+	for rows.Next() {
+		var item ReviewItem
+		err := rows.Scan(&item.ID, &item.ContentType, &item.ContentID, &item.UserID, &item.Username,
+			&item.Rule, &item.Text, &item.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan review item: %w", err)
+		}
+		items = append(items, &item)
+	}
+
+	return items, rows.Err()
+}
+
+// ResolveReview closes a review item of orgID and every other open flag on
+// the same content. Removing deletes the post or comment.
+func (s *ContentFilterService) ResolveReview(ctx context.Context, orgID, moderatorID, flagID uuid.UUID, decision string) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		var contentType string
+		var contentID uuid.UUID
+		err := tx.QueryRow(ctx, `
+			SELECT f.content_type, f.content_id FROM content_flags f
+			JOIN users u ON u.id = f.user_id
+			WHERE f.id = $1 AND f.action = 'review' AND f.resolved_at IS NULL AND u.org_id = $2
+			FOR UPDATE OF f`, flagID, orgID).Scan(&contentType, &contentID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("review item not found")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get review item: %w", err)
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE content_flags
+			SET resolved_at = now(), resolved_by = $3, decision = $4
+			WHERE content_type = $1 AND content_id = $2 AND resolved_at IS NULL`,
+			contentType, contentID, moderatorID, decision)
+		if err != nil {
+			return fmt.Errorf("failed to resolve review item: %w", err)
+		}
+
+		if decision == "remove" {
+			query := `DELETE FROM posts WHERE id = $1`
+			if contentType == ContentTypeComment {
+				query = `DELETE FROM comments WHERE id = $1`
+			}
+			if _, err := tx.Exec(ctx, query, contentID); err != nil {
+				return fmt.Errorf("failed to remove content: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// rejected reports whether any hit rejects the content
+func rejected(hits []FilterHit) bool {
+	for _, hit := range hits {
+		if hit.Action == FilterActionReject {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeWords lowercases text and collapses everything but letters and
+// digits to single spaces, with a space at each end so whole words and
+// phrases can be matched with strings.Contains
+func normalizeWords(text string) string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return " " + strings.Join(fields, " ") + " "
+}
+
+// matchesWordlist reports whether text contains any of the normalized words
+// or phrases as whole words
+func matchesWordlist(text string, words []string) bool {
+	if len(words) == 0 {
+		return false
+	}
+
+	normalized := normalizeWords(text)
+	for _, word := range words {
+		if strings.Contains(normalized, " "+word+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// isLinkSpam reports whether text has more than maxLinks links or repeats
+// the same link
+func isLinkSpam(text string, maxLinks int) bool {
+	links := urlRe.FindAllString(text, -1)
+	if len(links) > maxLinks {
+		return true
+	}
+
+	seen := make(map[string]bool, len(links))
+	for _, link := range links {
+		link = strings.ToLower(strings.TrimRight(link, ".,;:!?)"))
+		if seen[link] {
+			return true
+		}
+		seen[link] = true
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchesWordlist(t *testing.T) {
+	filter := NewContentFilterService(nil, ContentFilterConfig{Words: []string{"Spam", "buy now", " "}})
+
+	tests := []struct {
+		name string
+		text string
+		want bool
+	}{
+		{"no match", "Lecture notes for week 3", false},
+		{"whole word", "this is spam", true},
+		{"case and punctuation", "SPAM!!!", true},
+		{"substring does not match", "spammer spamming", false},
+		{"phrase", "Buy   now, limited offer", true},
+		{"phrase split by punctuation", "buy-now", true},
+		{"partial phrase", "buy it now", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, matchesWordlist(tt.text, filter.words))
+		})
+	}
+
+	assert.False(t, matchesWordlist("spam", nil))
+}
+
+func TestIsLinkSpam(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want bool
+	}{
+		{"no links", "hello", false},
+		{"under the limit", "see https://a.kz and https://b.kz", false},
+		{"over the limit", "https://a.kz https://b.kz https://c.kz", true},
+		{"repeated link", "https://a.kz/x and again https://A.kz/x.", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isLinkSpam(tt.text, 2))
+		})
+	}
+}
+
+func TestRejected(t *testing.T) {
+	assert.False(t, rejected(nil))
+	assert.False(t, rejected([]FilterHit{{Rule: FilterRuleLinks, Action: FilterActionReview}}))
+	assert.True(t, rejected([]FilterHit{
+		{Rule: FilterRuleLinks, Action: FilterActionFlag},
+		{Rule: FilterRuleDuplicate, Action: FilterActionReject},
+	}))
+}
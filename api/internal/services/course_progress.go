@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"bailanysta/api/internal/pkg/database"
+)
+
+// CourseProgress is how far a student is through a course
+type CourseProgress struct {
+	Course
+	CompletedModules   int         `json:"completed_modules"`
+	TotalModules       int         `json:"total_modules"`
+	Percent            int         `json:"percent"`
+	CompletedModuleIDs []uuid.UUID `json:"completed_module_ids"`
+	// CompletedAt is when the student first finished every module
+	CompletedAt     *time.Time `json:"completed_at"`
+	CertificateCode *string    `json:"certificate_code"`
+}
+
+type CourseProgressService struct {
+	db                  *database.Pool
+	notificationService *NotificationService
+}
+
+func NewCourseProgressService(db *database.Pool, notificationService *NotificationService) *CourseProgressService {
+	return &CourseProgressService{
+		db:                  db,
+		notificationService: notificationService,
+	}
+}
+
+// progressPercent rounds down, so a course only shows 100% when every module
+// is done
+func progressPercent(completed, total int) int {
+	if total == 0 {
+		return 0
+	}
+	return completed * 100 / total
+}
+
+// CompleteModule marks a module of orgID complete for userID and returns the
+// course's progress. Finishing the last module records the course as
+// completed, issues a certificate and notifies the student, once.
+func (s *CourseProgressService) CompleteModule(ctx context.Context, userID, orgID, moduleID uuid.UUID) (*CourseProgress, error) {
+	var courseID uuid.UUID
+	var newlyCompleted bool
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, `
+			SELECT m.course_id FROM modules m
+			JOIN courses c ON c.id = m.course_id
+			WHERE m.id = $1 AND c.org_id = $2`, moduleID, orgID).Scan(&courseID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("module not found")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get module: %w", err)
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO module_progress (user_id, module_id) VALUES ($1, $2)
+			ON CONFLICT DO NOTHING`, userID, moduleID)
+		if err != nil {
+			return fmt.Errorf("failed to complete module: %w", err)
+		}
+
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO course_completions (user_id, course_id)
+			SELECT $1, $2
+			WHERE NOT EXISTS (
+			  SELECT 1 FROM modules m
+			  LEFT JOIN module_progress mp ON mp.module_id = m.id AND mp.user_id = $1
+			  WHERE m.course_id = $2 AND mp.module_id IS NULL
+			)
+			ON CONFLICT DO NOTHING`, userID, courseID)
+		if err != nil {
+			return fmt.Errorf("failed to record course completion: %w", err)
+		}
+		newlyCompleted = tag.RowsAffected() > 0
+		if newlyCompleted {
+			return issueCertificate(ctx, tx, userID, courseID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	progress, err := s.getProgress(ctx, userID, &courseID)
+	if err != nil {
+		return nil, err
+	}
+	if len(progress) == 0 {
+		return nil, fmt.Errorf("module not found")
+	}
+
+	if newlyCompleted {
+		if err := s.notificationService.NotifyCourseCompleted(ctx, userID, courseID, progress[0].Title); err != nil {
+			fmt.Printf("Failed to notify course completion for user %s: %v\n", userID, err)
+		}
+	}
+	return progress[0], nil
+}
+
+// UncompleteModule clears a module userID marked complete. A course already
+// completed stays completed.
+func (s *CourseProgressService) UncompleteModule(ctx context.Context, userID, moduleID uuid.UUID) error {
+	tag, err := s.db.Exec(ctx, `
+		DELETE FROM module_progress WHERE user_id = $1 AND module_id = $2`, userID, moduleID)
+	if err != nil {
+		return fmt.Errorf("failed to clear module progress: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("module not completed")
+	}
+	return nil
+}
+
+// GetMyCourses lists the courses userID has completed at least one module
+// of, with their progress
+func (s *CourseProgressService) GetMyCourses(ctx context.Context, userID uuid.UUID) ([]*CourseProgress, error) {
+	return s.getProgress(ctx, userID, nil)
+}
+
+// getProgress returns userID's progress in courseID, or in every course they
+// have started when courseID is nil
+func (s *CourseProgressService) getProgress(ctx context.Context, userID uuid.UUID, courseID *uuid.UUID) ([]*CourseProgress, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT c.id, c.title, COALESCE(c.description, ''),
+		       (SELECT COUNT(*) FROM modules WHERE course_id = c.id),
+		       COALESCE(array_agg(mp.module_id ORDER BY m."order") FILTER (WHERE mp.module_id IS NOT NULL), '{}'),
+		       cc.completed_at, cert.code
+		FROM courses c
+		JOIN modules m ON m.course_id = c.id
+		LEFT JOIN module_progress mp ON mp.module_id = m.id AND mp.user_id = $1
+		LEFT JOIN course_completions cc ON cc.course_id = c.id AND cc.user_id = $1
+		LEFT JOIN certificates cert ON cert.course_id = c.id AND cert.user_id = $1
+		WHERE ($2::uuid IS NULL OR c.id = $2)
+		GROUP BY c.id, cc.completed_at, cert.code
+		HAVING COUNT(mp.module_id) > 0 OR cc.completed_at IS NOT NULL
+		ORDER BY c.title`, userID, courseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get course progress: %w", err)
+	}
+	defer rows.Close()
+
+	courses := []*CourseProgress{}
+	for rows.Next() {
+		var progress CourseProgress
+		err := rows.Scan(&progress.ID, &progress.Title, &progress.Description, &progress.TotalModules,
+			&progress.CompletedModuleIDs, &progress.CompletedAt, &progress.CertificateCode)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan course progress: %w", err)
+		}
+		progress.CompletedModules = len(progress.CompletedModuleIDs)
+		progress.Percent = progressPercent(progress.CompletedModules, progress.TotalModules)
+		courses = append(courses, &progress)
+	}
+
+	return courses, rows.Err()
+}
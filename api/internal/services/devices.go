@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"bailanysta/api/internal/pkg/database"
+	"bailanysta/api/internal/pkg/geoip"
+	"bailanysta/api/internal/pkg/mailer"
+	"bailanysta/api/internal/pkg/useragent"
+)
+
+// newDeviceEmailTimeout bounds sending the new device warning
+const newDeviceEmailTimeout = 30 * time.Second
+
+// ClientInfo is what a request tells about the client that sent it
+type ClientInfo struct {
+	UserAgent string
+	IP        string
+}
+
+// SessionDevice describes the client a session was started or refreshed
+// from
+type SessionDevice struct {
+	UserAgent string
+	Agent     useragent.Agent
+	IP        string
+	Location  *geoip.Location // nil when unknown
+}
+
+// Where describes the location as "Almaty, Kazakhstan", or "" if unknown
+func (d SessionDevice) Where() string {
+	if d.Location == nil {
+		return ""
+	}
+	parts := []string{}
+	for _, part := range []string{d.Location.City, d.Location.Country} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// fingerprint identifies the device among a user's devices
+func (d SessionDevice) fingerprint() string {
+	return strings.ToLower(d.Agent.Browser + "|" + d.Agent.OS + "|" + d.Agent.Device)
+}
+
+// DeviceService records which devices users sign in from and warns them,
+// by notification and email, when an unfamiliar one appears
+type DeviceService struct {
+	db            *database.Pool
+	geo           *geoip.DB // nil without a GeoIP database
+	notifications *NotificationService
+	mailer        *mailer.Mailer // nil when email is not configured
+	appURL        string
+}
+
+func NewDeviceService(db *database.Pool, geo *geoip.DB, notifications *NotificationService, mailer *mailer.Mailer, appURL string) *DeviceService {
+	return &DeviceService{
+		db:            db,
+		geo:           geo,
+		notifications: notifications,
+		mailer:        mailer,
+		appURL:        strings.TrimRight(appURL, "/"),
+	}
+}
+
+// Describe parses client into a SessionDevice, locating its IP if there is
+// a GeoIP database
+func (s *DeviceService) Describe(client ClientInfo) SessionDevice {
+	device := SessionDevice{
+		UserAgent: truncateRunes(client.UserAgent, 500),
+		Agent:     useragent.Parse(client.UserAgent),
+		IP:        client.IP,
+	}
+	if s == nil || s.geo == nil {
+		return device
+	}
+	if ip := net.ParseIP(client.IP); ip != nil {
+		location, err := s.geo.Lookup(ip)
+		if err != nil {
+			fmt.Printf("Failed to look up IP location: %v\n", err)
+		}
+		device.Location = location
+	}
+	return device
+}
+
+// Seen records that userID signed in from device for sessionID. If the
+// user has signed in before but never from this device, they are warned.
+// The first device a user signs in on is not new.
+func (s *DeviceService) Seen(ctx context.Context, userID, sessionID uuid.UUID, device SessionDevice) error {
+	if s == nil {
+		return nil
+	}
+
+	var inserted, hadDevices bool
+	err := s.db.QueryRow(ctx, `
+		WITH known AS (SELECT EXISTS(SELECT 1 FROM user_devices WHERE user_id = $1) AS any)
+		INSERT INTO user_devices (user_id, fingerprint, device)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, fingerprint) DO UPDATE
+		SET last_seen_at = now(), device = EXCLUDED.device
+		RETURNING xmax = 0, (SELECT any FROM known)`,
+		userID, device.fingerprint(), device.Agent.String()).Scan(&inserted, &hadDevices)
+	if err != nil {
+		return fmt.Errorf("failed to record device: %w", err)
+	}
+	if !inserted || !hadDevices {
+		return nil
+	}
+
+	if s.notifications != nil {
+		if err := s.notifications.NotifyNewDeviceLogin(ctx, userID, sessionID, device.Agent.String(), device.Where()); err != nil {
+			fmt.Printf("Failed to create new device notification: %v\n", err)
+		}
+	}
+	if s.mailer != nil {
+		go s.sendNewDeviceEmail(context.WithoutCancel(ctx), userID, device)
+	}
+	return nil
+}
+
+// sendNewDeviceEmail emails the warning straight away rather than waiting
+// for the digest
+func (s *DeviceService) sendNewDeviceEmail(ctx context.Context, userID uuid.UUID, device SessionDevice) {
+	ctx, cancel := context.WithTimeout(ctx, newDeviceEmailTimeout)
+	defer cancel()
+
+	var email, username string
+	err := s.db.QueryRow(ctx, `SELECT email, username FROM users WHERE id = $1`, userID).Scan(&email, &username)
+	if err != nil {
+		fmt.Printf("Failed to get user for new device email: %v\n", err)
+		return
+	}
+
+	what := device.Agent.String()
+	if where := device.Where(); where != "" {
+		what += " near " + where
+	}
+	if device.IP != "" {
+		what += " (IP " + device.IP + ")"
+	}
+	sessionsURL := s.appURL + "/settings/sessions"
+
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "New sign-in to your Bailanysta account",
+		HTML: fmt.Sprintf(`<p>Hi %s,</p><p>Your account was just signed in to from a new device: %s.</p>`+
+			`<p>If this was you, there's nothing to do. If not, <a href="%s">sign out that session</a> and change your password.</p>`,
+			html.EscapeString(username), html.EscapeString(what), sessionsURL),
+		Text: fmt.Sprintf("Hi %s,\n\nYour account was just signed in to from a new device: %s.\n\n"+
+			"If this was you, there's nothing to do. If not, sign out that session and change your password: %s\n",
+			username, what, sessionsURL),
+	})
+	if err != nil {
+		fmt.Printf("Failed to send new device email: %v\n", err)
+	}
+}
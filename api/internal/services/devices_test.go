@@ -0,0 +1,52 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"bailanysta/api/internal/pkg/geoip"
+)
+
+func TestDescribeClient(t *testing.T) {
+	var devices *DeviceService // no GeoIP database
+
+	tests := []struct {
+		name      string
+		userAgent string
+		want      string
+		device    string
+	}{
+		{"chrome on windows", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36", "Chrome on Windows", "desktop"},
+		{"edge is not chrome", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36 Edg/126.0.0.0", "Edge on Windows", "desktop"},
+		{"safari on iphone", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1", "Safari on iOS", "mobile"},
+		{"android tablet", "Mozilla/5.0 (Linux; Android 14; SM-X710) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36", "Chrome on Android", "tablet"},
+		{"empty", "", "Unknown on Unknown", "other"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			device := devices.Describe(ClientInfo{UserAgent: tt.userAgent, IP: "203.0.113.7"})
+			assert.Equal(t, tt.want, device.Agent.String())
+			assert.Equal(t, tt.device, device.Agent.Device)
+			assert.Equal(t, "203.0.113.7", device.IP)
+			assert.Nil(t, device.Location)
+		})
+	}
+}
+
+func TestSessionDeviceFingerprintIgnoresVersions(t *testing.T) {
+	var devices *DeviceService
+	older := devices.Describe(ClientInfo{UserAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"})
+	newer := devices.Describe(ClientInfo{UserAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_5) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36"})
+	firefox := devices.Describe(ClientInfo{UserAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 14.5; rv:127.0) Gecko/20100101 Firefox/127.0"})
+
+	assert.Equal(t, older.fingerprint(), newer.fingerprint())
+	assert.NotEqual(t, older.fingerprint(), firefox.fingerprint())
+}
+
+func TestSessionDeviceWhere(t *testing.T) {
+	assert.Equal(t, "", SessionDevice{}.Where())
+	assert.Equal(t, "Kazakhstan", SessionDevice{Location: &geoip.Location{Country: "Kazakhstan"}}.Where())
+	assert.Equal(t, "Almaty, Kazakhstan", SessionDevice{Location: &geoip.Location{Country: "Kazakhstan", City: "Almaty"}}.Where())
+}
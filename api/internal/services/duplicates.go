@@ -0,0 +1,222 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"bailanysta/api/internal/pkg/metrics"
+)
+
+// minDuplicateWords is the fewest words a post needs to be fingerprinted;
+// shorter posts ("thanks!") repeat innocently
+const minDuplicateWords = 8
+
+var duplicateDetections = metrics.NewCounterVec("post_duplicates_detected_total",
+	"New posts found to be near-duplicates of a recent post.")
+
+// DuplicateConfig sets how far back new posts are compared and how many of
+// the 64 simhash bits may differ for two posts to count as duplicates
+type DuplicateConfig struct {
+	Window      time.Duration // 0 turns detection off
+	MaxDistance int
+}
+
+// DuplicateService finds near-duplicate posts, such as copy-paste spam with a
+// word or two changed, and keeps a report of them for moderators
+type DuplicateService struct {
+	db     *pgxpool.Pool
+	config DuplicateConfig
+}
+
+// DuplicatePost is an open report entry: a post and the earlier one it
+// copies
+type DuplicatePost struct {
+	PostID           uuid.UUID `json:"post_id"`
+	Text             string    `json:"text"`
+	AuthorID         uuid.UUID `json:"author_id"`
+	Username         string    `json:"username"`
+	OriginalID       uuid.UUID `json:"original_id"`
+	OriginalText     string    `json:"original_text"`
+	OriginalAuthorID uuid.UUID `json:"original_author_id"`
+	OriginalUsername string    `json:"original_username"`
+	Distance         int       `json:"distance"` // differing simhash bits
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+type ResolveDuplicateRequest struct {
+	// dismiss keeps both posts; merge moves the duplicate's likes and
+	// comments onto the original and deletes it
+	Decision string `json:"decision" validate:"required,oneof=dismiss merge"`
+}
+
+func NewDuplicateService(db *pgxpool.Pool, config DuplicateConfig) *DuplicateService {
+	return &DuplicateService{
+		db:     db,
+		config: config,
+	}
+}
+
+// findOriginal returns the earliest post in userID's organization within the
+// window whose simhash is near hash, and how many bits they differ in
+func (s *DuplicateService) findOriginal(ctx context.Context, tx pgx.Tx, userID uuid.UUID, hash int64) (*uuid.UUID, int, error) {
+	if s == nil || s.config.Window <= 0 {
+		return nil, 0, nil
+	}
+
+	var originalID uuid.UUID
+	var distance int
+	err := tx.QueryRow(ctx, `
+		SELECT id, bit_count((simhash # $2)::bit(64))
+		FROM posts
+		WHERE org_id = (SELECT org_id FROM users WHERE id = $1)
+		  AND simhash IS NOT NULL
+		  AND created_at > now() - make_interval(secs => $3::float8)
+		  AND bit_count((simhash # $2)::bit(64)) <= $4
+		ORDER BY created_at
+		LIMIT 1`, userID, hash, s.config.Window.Seconds(), s.config.MaxDistance).Scan(&originalID, &distance)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to check duplicate posts: %w", err)
+	}
+	return &originalID, distance, nil
+}
+
+// record adds postID to the duplicates report
+func (s *DuplicateService) record(ctx context.Context, tx pgx.Tx, postID, originalID uuid.UUID, distance int) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO post_duplicates (post_id, original_id, distance) VALUES ($1, $2, $3)`,
+		postID, originalID, distance)
+	if err != nil {
+		return fmt.Errorf("failed to record duplicate post: %w", err)
+	}
+	duplicateDetections.Inc()
+	return nil
+}
+
+// GetReport lists orgID's unresolved duplicates, oldest first
+func (s *DuplicateService) GetReport(ctx context.Context, orgID uuid.UUID, limit, offset int) ([]*DuplicatePost, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT d.post_id, p.text, p.author_id, u.username,
+		       d.original_id, o.text, o.author_id, ou.username,
+		       d.distance, d.created_at
+		FROM post_duplicates d
+		JOIN posts p ON p.id = d.post_id
+		JOIN users u ON u.id = p.author_id
+		JOIN posts o ON o.id = d.original_id
+		JOIN users ou ON ou.id = o.author_id
+		WHERE d.resolved_at IS NULL AND p.org_id = $1
+		ORDER BY d.created_at
+		LIMIT $2 OFFSET $3`, orgID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get duplicate posts: %w", err)
+	}
+	defer rows.Close()
+
+	duplicates := []*DuplicatePost{}
+	for rows.Next() {
+		var d DuplicatePost
+		err := rows.Scan(&d.PostID, &d.Text, &d.AuthorID, &d.Username,
+			&d.OriginalID, &d.OriginalText, &d.OriginalAuthorID, &d.OriginalUsername,
+			&d.Distance, &d.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan duplicate post: %w", err)
+		}
+		duplicates = append(duplicates, &d)
+	}
+	return duplicates, rows.Err()
+}
+
+// Resolve closes the report entry for postID in orgID. Merging moves its
+// likes (but not its author's own) and comments onto the original and
+// deletes it.
+func (s *DuplicateService) Resolve(ctx context.Context, orgID, moderatorID, postID uuid.UUID, decision string) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		var originalID uuid.UUID
+		err := tx.QueryRow(ctx, `
+			UPDATE post_duplicates d
+			SET resolved_at = now(), resolved_by = $2, decision = $3
+			FROM posts p
+			WHERE d.post_id = $1 AND d.resolved_at IS NULL AND p.id = d.post_id AND p.org_id = $4
+			RETURNING d.original_id`, postID, moderatorID, decision, orgID).Scan(&originalID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("duplicate post not found")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to resolve duplicate post: %w", err)
+		}
+		if decision != "merge" {
+			return nil
+		}
+
+		// Locking the original keeps like_count in step with the likes rows
+		var likeCount int
+		if err := lockPostLikeCount(ctx, tx, originalID, &likeCount); err != nil {
+			return err
+		}
+		result, err := tx.Exec(ctx, `
+			INSERT INTO likes (user_id, post_id, created_at)
+			SELECT l.user_id, $2, l.created_at
+			FROM likes l
+			JOIN posts o ON o.id = $2
+			WHERE l.post_id = $1 AND l.user_id <> o.author_id
+			ON CONFLICT (user_id, post_id) DO NOTHING`, postID, originalID)
+		if err != nil {
+			return fmt.Errorf("failed to merge likes: %w", err)
+		}
+		_, err = tx.Exec(ctx, `
+			UPDATE posts SET like_count = like_count + $2 WHERE id = $1`, originalID, result.RowsAffected())
+		if err != nil {
+			return fmt.Errorf("failed to update like count: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx, `UPDATE comments SET post_id = $2 WHERE post_id = $1`, postID, originalID); err != nil {
+			return fmt.Errorf("failed to merge comments: %w", err)
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM posts WHERE id = $1`, postID); err != nil {
+			return fmt.Errorf("failed to delete duplicate post: %w", err)
+		}
+		return nil
+	})
+}
+
+// simhash fingerprints text so that near-identical texts differ in few bits:
+// every word of the normalized text votes on each bit with its hash. ok is
+// false for texts shorter than minDuplicateWords.
+func simhash(text string) (hash int64, ok bool) {
+	words := strings.Fields(normalizeWords(text))
+	if len(words) < minDuplicateWords {
+		return 0, false
+	}
+
+	var votes [64]int
+	for _, word := range words {
+		h := fnv.New64a()
+		h.Write([]byte(word))
+		sum := h.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<bit) != 0 {
+				votes[bit]++
+			} else {
+				votes[bit]--
+			}
+		}
+	}
+
+	var fingerprint uint64
+	for bit, vote := range votes {
+		if vote > 0 {
+			fingerprint |= 1 << bit
+		}
+	}
+	return int64(fingerprint), true
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"math/bits"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func simhashDistance(t *testing.T, a, b string) int {
+	t.Helper()
+	ha, ok := simhash(a)
+	require.True(t, ok, a)
+	hb, ok := simhash(b)
+	require.True(t, ok, b)
+	return bits.OnesCount64(uint64(ha ^ hb))
+}
+
+func TestSimhash(t *testing.T) {
+	spam := "Earn money fast from home, join our channel today and get free crypto bonuses for every friend you invite"
+
+	t.Run("short texts are not fingerprinted", func(t *testing.T) {
+		_, ok := simhash("Thanks, see you in class tomorrow!")
+		assert.False(t, ok)
+	})
+
+	t.Run("case and punctuation are ignored", func(t *testing.T) {
+		assert.Equal(t, 0, simhashDistance(t, spam, "EARN money fast from home!!! Join our channel today, and get free crypto bonuses for every friend you invite."))
+	})
+
+	t.Run("a changed word keeps it close", func(t *testing.T) {
+		assert.LessOrEqual(t, simhashDistance(t, spam, "Earn money quick from home, join our channel today and get free crypto bonuses for every friend you invite"), 6)
+		assert.LessOrEqual(t, simhashDistance(t, spam, spam+" now"), 6)
+	})
+
+	t.Run("different texts are far apart", func(t *testing.T) {
+		other := "The lecture on graph algorithms moves to room 204 next week, bring your notes on Dijkstra and BFS"
+		assert.Greater(t, simhashDistance(t, spam, other), 16)
+	})
+}
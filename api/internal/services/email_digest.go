@@ -0,0 +1,348 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"bailanysta/api/internal/pkg/mailer"
+	"bailanysta/api/internal/pkg/sentry"
+)
+
+const (
+	digestBatchSize   = 200
+	digestItemLimit   = 10
+	digestSendTimeout = 30 * time.Second
+)
+
+var digestTemplate = template.Must(template.New("digest").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: Arial, sans-serif; color: #222; max-width: 560px; margin: 0 auto;">
+  <h2>Hi {{.Username}},</h2>
+  <p>You have {{.Total}} unread notification{{if ne .Total 1}}s{{end}} on Bailanysta.</p>
+  <ul>
+    {{range .Items}}<li style="margin-bottom: 8px;">{{.Summary}} <span style="color: #888;">{{.CreatedAt.Format "Jan 2, 15:04"}}</span></li>
+    {{end}}
+  </ul>
+  {{if gt .Total (len .Items)}}<p>…and {{.More}} more.</p>{{end}}
+  <p><a href="{{.AppURL}}/notifications" style="color: #2563eb;">Open Bailanysta</a></p>
+  <p style="color: #888; font-size: 12px;">
+    You receive this {{.Frequency}} digest because of your notification settings.
+    <a href="{{.UnsubscribeURL}}" style="color: #888;">Unsubscribe</a>
+  </p>
+</body>
+</html>`))
+
+type EmailDigestService struct {
+	db                   *pgxpool.Pool
+	notificationsService *NotificationService
+	mailer               *mailer.Mailer
+	secret               []byte
+	appURL               string
+	apiURL               string
+	interval             time.Duration
+	sendHour             int
+}
+
+type digestItem struct {
+	Summary   string
+	CreatedAt time.Time
+}
+
+type digestData struct {
+	Username       string
+	Frequency      string
+	Total          int
+	More           int
+	Items          []digestItem
+	AppURL         string
+	UnsubscribeURL string
+}
+
+// NewEmailDigestService creates the digest job; mailer may be nil, in which
+// case only unsubscribe links are handled. Digests go out from sendHour in
+// each user's time zone.
+func NewEmailDigestService(db *pgxpool.Pool, notificationsService *NotificationService, mailer *mailer.Mailer, secret, appURL, apiURL string, interval time.Duration, sendHour int) *EmailDigestService {
+	return &EmailDigestService{
+		db:                   db,
+		notificationsService: notificationsService,
+		mailer:               mailer,
+		secret:               []byte(secret),
+		appURL:               strings.TrimRight(appURL, "/"),
+		apiURL:               strings.TrimRight(apiURL, "/"),
+		interval:             interval,
+		sendHour:             sendHour,
+	}
+}
+
+// Run sends due digests every interval until ctx is cancelled
+func (s *EmailDigestService) Run(ctx context.Context) {
+	if s.mailer == nil {
+		return
+	}
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.SendDueDigests(ctx); err != nil {
+			fmt.Printf("Failed to send email digests: %v\n", err)
+			sentry.CaptureError(err, map[string]string{"job": "email-digest"})
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendDueDigests emails users whose daily or weekly digest is due and who have
+// unread notifications of types they receive by email. A digest is due once
+// the send hour has passed on a day, in the user's time zone, at least one
+// or seven days after the last one.
+func (s *EmailDigestService) SendDueDigests(ctx context.Context) (int, error) {
+	rows, err := s.db.Query(ctx, `
+		WITH local AS (
+		    SELECT u.id, u.username, u.email, u.email_digest, u.email_digest_sent_at, u.timezone,
+		           (now() AT TIME ZONE u.timezone)::date AS today,
+		           (u.email_digest_sent_at AT TIME ZONE u.timezone)::date AS sent_on
+		    FROM users u
+		    WHERE u.email_digest <> 'off'
+		      AND EXTRACT(HOUR FROM now() AT TIME ZONE u.timezone) >= $3
+		),
+		due AS (
+		    SELECT id, username, email, email_digest, timezone,
+		           COALESCE(email_digest_sent_at,
+		                    now() - CASE email_digest WHEN 'daily' THEN interval '1 day' ELSE interval '7 days' END) AS since
+		    FROM local
+		    WHERE sent_on IS NULL OR sent_on <= today - CASE email_digest WHEN 'daily' THEN 1 ELSE 7 END
+		)
+		SELECT due.id, due.username, due.email, due.email_digest, due.since, due.timezone
+		FROM due
+		WHERE EXISTS (
+		    SELECT 1 FROM notifications n
+		    LEFT JOIN notification_preferences np ON np.user_id = n.user_id AND np.type = n.type
+		    WHERE n.user_id = due.id AND n.read_at IS NULL AND n.created_at > due.since
+		      AND COALESCE(np.email, NOT (n.type = ANY($1)))
+		)
+		LIMIT $2`, emailDisabledByDefault(), digestBatchSize, s.sendHour)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get due digests: %w", err)
+	}
+
+	type recipient struct {
+		id        uuid.UUID
+		username  string
+		email     string
+		frequency string
+		since     time.Time
+		timezone  string
+	}
+	var recipients []recipient
+	for rows.Next() {
+		var r recipient
+		if err := rows.Scan(&r.id, &r.username, &r.email, &r.frequency, &r.since, &r.timezone); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan digest recipient: %w", err)
+		}
+		recipients = append(recipients, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read digest recipients: %w", err)
+	}
+
+	sent := 0
+	for _, r := range recipients {
+		if ctx.Err() != nil {
+			break
+		}
+
+		// An email already being sent is allowed to finish on shutdown
+		sendCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), digestSendTimeout)
+		err := s.sendDigest(sendCtx, r.id, r.username, r.email, r.frequency, r.since, userLocation(r.timezone))
+		cancel()
+		if err != nil {
+			// Retried on the next run
+			fmt.Printf("Failed to send email digest to user %s: %v\n", r.id, err)
+			continue
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+func (s *EmailDigestService) sendDigest(ctx context.Context, userID uuid.UUID, username, email, frequency string, since time.Time, location *time.Location) error {
+	rows, err := s.db.Query(ctx, `
+		SELECT n.id, n.user_id, n.type, n.entity_id, n.payload_json, n.read_at, n.created_at,
+		       COUNT(*) OVER () AS total
+		FROM notifications n
+		LEFT JOIN notification_preferences np ON np.user_id = n.user_id AND np.type = n.type
+		WHERE n.user_id = $1 AND n.read_at IS NULL AND n.created_at > $2
+		  AND COALESCE(np.email, NOT (n.type = ANY($3)))
+		ORDER BY n.created_at DESC
+		LIMIT $4`, userID, since, emailDisabledByDefault(), digestItemLimit)
+	if err != nil {
+		return fmt.Errorf("failed to get digest notifications: %w", err)
+	}
+
+	var notifications []*Notification
+	total := 0
+	for rows.Next() {
+		var notification Notification
+		var entityID pgtype.UUID
+		var payloadJSON []byte
+
+		err := rows.Scan(
+			&notification.ID, &notification.UserID, &notification.Type,
+			&entityID, &payloadJSON, &notification.ReadAt, &notification.CreatedAt, &total)
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan notification: %w", err)
+		}
+		if entityID.Valid {
+			entityUUID := uuid.UUID(entityID.Bytes)
+			notification.EntityID = &entityUUID
+		}
+		if err := json.Unmarshal(payloadJSON, &notification.Payload); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to unmarshal payload: %w", err)
+		}
+		notifications = append(notifications, &notification)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read digest notifications: %w", err)
+	}
+
+	if len(notifications) > 0 {
+		data := digestData{
+			Username:       username,
+			Frequency:      frequency,
+			Total:          total,
+			More:           total - len(notifications),
+			AppURL:         s.appURL,
+			UnsubscribeURL: s.UnsubscribeURL(userID),
+		}
+		for _, notification := range notifications {
+			if err := s.notificationsService.populateNotificationData(ctx, notification); err != nil {
+				fmt.Printf("Failed to populate digest notification: %v\n", err)
+			}
+			data.Items = append(data.Items, digestItem{
+				Summary:   notificationSummary(notification),
+				CreatedAt: notification.CreatedAt.In(location),
+			})
+		}
+
+		html, text, err := renderDigest(data)
+		if err != nil {
+			return err
+		}
+
+		err = s.mailer.Send(ctx, mailer.Message{
+			To:      email,
+			Subject: fmt.Sprintf("You have %d unread notifications on Bailanysta", total),
+			HTML:    html,
+			Text:    text,
+			Headers: map[string]string{
+				"List-Unsubscribe":      "<" + data.UnsubscribeURL + ">",
+				"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to send digest email: %w", err)
+		}
+	}
+
+	_, err = s.db.Exec(ctx, `UPDATE users SET email_digest_sent_at = now() WHERE id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to mark digest as sent: %w", err)
+	}
+	return nil
+}
+
+// UnsubscribeURL returns the signed one-click unsubscribe link for a user
+func (s *EmailDigestService) UnsubscribeURL(userID uuid.UUID) string {
+	return s.apiURL + "/api/v1/email/unsubscribe?token=" + url.QueryEscape(signUnsubscribeToken(userID, s.secret))
+}
+
+// Unsubscribe turns off email digests for the user the token was issued to
+func (s *EmailDigestService) Unsubscribe(ctx context.Context, token string) error {
+	userID, err := verifyUnsubscribeToken(token, s.secret)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(ctx, `UPDATE users SET email_digest = 'off' WHERE id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to unsubscribe: %w", err)
+	}
+	return nil
+}
+
+func renderDigest(data digestData) (string, string, error) {
+	var html bytes.Buffer
+	if err := digestTemplate.Execute(&html, data); err != nil {
+		return "", "", fmt.Errorf("failed to render digest: %w", err)
+	}
+
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("Hi %s,\n\nYou have %d unread notifications on Bailanysta.\n\n", data.Username, data.Total))
+	for _, item := range data.Items {
+		text.WriteString(fmt.Sprintf("- %s\n", item.Summary))
+	}
+	if data.More > 0 {
+		text.WriteString(fmt.Sprintf("...and %d more.\n", data.More))
+	}
+	text.WriteString(fmt.Sprintf("\nOpen Bailanysta: %s/notifications\n", data.AppURL))
+	text.WriteString(fmt.Sprintf("Unsubscribe: %s\n", data.UnsubscribeURL))
+
+	return html.String(), text.String(), nil
+}
+
+// emailDisabledByDefault lists the types left out of digests unless the user opts in
+func emailDisabledByDefault() []string {
+	types := []string{}
+	for _, pref := range notificationDefaults {
+		if !pref.Email {
+			types = append(types, string(pref.Type))
+		}
+	}
+	return types
+}
+
+func signUnsubscribeToken(userID uuid.UUID, secret []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("email-unsubscribe:" + userID.String()))
+	return userID.String() + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func verifyUnsubscribeToken(token string, secret []byte) (uuid.UUID, error) {
+	idPart, _, ok := strings.Cut(token, ".")
+	if !ok {
+		return uuid.Nil, fmt.Errorf("invalid token")
+	}
+	userID, err := uuid.Parse(idPart)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid token")
+	}
+	if !hmac.Equal([]byte(signUnsubscribeToken(userID, secret)), []byte(token)) {
+		return uuid.Nil, fmt.Errorf("invalid token")
+	}
+	return userID, nil
+}
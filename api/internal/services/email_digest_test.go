@@ -0,0 +1,62 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnsubscribeToken(t *testing.T) {
+	secret := []byte("test-secret")
+	userID := uuid.New()
+	token := signUnsubscribeToken(userID, secret)
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "valid token", token: token},
+		{name: "wrong secret", token: signUnsubscribeToken(userID, []byte("other")), wantErr: true},
+		{name: "other user", token: uuid.New().String() + token[36:], wantErr: true},
+		{name: "missing signature", token: userID.String(), wantErr: true},
+		{name: "garbage", token: "not-a-token.abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := verifyUnsubscribeToken(tt.token, secret)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, userID, got)
+		})
+	}
+}
+
+func TestRenderDigest(t *testing.T) {
+	html, text, err := renderDigest(digestData{
+		Username:  "dana",
+		Frequency: "daily",
+		Total:     3,
+		More:      2,
+		Items: []digestItem{
+			{Summary: "@aigerim commented: <b>great</b>", CreatedAt: time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)},
+		},
+		AppURL:         "https://bailanysta.kz",
+		UnsubscribeURL: "https://api.bailanysta.kz/api/v1/email/unsubscribe?token=abc",
+	})
+	require.NoError(t, err)
+
+	assert.Contains(t, html, "Hi dana,")
+	assert.Contains(t, html, "&lt;b&gt;great&lt;/b&gt;")
+	assert.Contains(t, html, "and 2 more")
+	assert.True(t, strings.Contains(text, "- @aigerim commented: <b>great</b>"))
+	assert.Contains(t, text, "Unsubscribe: https://api.bailanysta.kz/api/v1/email/unsubscribe?token=abc")
+}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"bailanysta/api/internal/pkg/ai"
+	"bailanysta/api/internal/pkg/sentry"
+)
+
+const (
+	embeddingBatchSize     = 32
+	embeddingMaxInputChars = 8000
+
+	// Semantic ranking blends cosine similarity with a recency decay
+	semanticCandidateLimit = 200
+	semanticSimilarityW    = 0.8
+	semanticRecencyW       = 0.2
+	semanticRecencyDays    = 7.0
+)
+
+type EmbeddingService struct {
+	db       *pgxpool.Pool
+	client   *ai.Client
+	model    string
+	interval time.Duration
+}
+
+type SemanticPost struct {
+	Post
+	Similarity float64 `json:"similarity"`
+	Score      float64 `json:"score"`
+}
+
+func NewEmbeddingService(db *pgxpool.Pool, client *ai.Client, model string, interval time.Duration) *EmbeddingService {
+	return &EmbeddingService{
+		db:       db,
+		client:   client,
+		model:    model,
+		interval: interval,
+	}
+}
+
+// Run embeds new and edited posts until ctx is cancelled
+func (s *EmbeddingService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			embedded, err := s.EmbedPendingPosts(ctx)
+			if err != nil {
+				fmt.Printf("Failed to embed posts: %v\n", err)
+				sentry.CaptureError(err, map[string]string{"job": "embeddings"})
+				break
+			}
+			// Keep draining while full batches come back (backfill)
+			if embedded < embeddingBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// EmbedPendingPosts embeds one batch of posts that have no embedding yet,
+// were edited since, or were embedded with a different model
+func (s *EmbeddingService) EmbedPendingPosts(ctx context.Context) (int, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT p.id, p.text
+		FROM posts p
+		LEFT JOIN post_embeddings e ON e.post_id = p.id
+		WHERE e.post_id IS NULL OR e.updated_at < p.updated_at OR e.model <> $1
+		ORDER BY p.created_at DESC
+		LIMIT $2`, s.model, embeddingBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get pending posts: %w", err)
+	}
+
+	var postIDs []uuid.UUID
+	var inputs []string
+	for rows.Next() {
+		var postID uuid.UUID
+		var text string
+		if err := rows.Scan(&postID, &text); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan pending post: %w", err)
+		}
+		if len(text) > embeddingMaxInputChars {
+			text = text[:embeddingMaxInputChars]
+		}
+		postIDs = append(postIDs, postID)
+		inputs = append(inputs, text)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read pending posts: %w", err)
+	}
+
+	if len(postIDs) == 0 {
+		return 0, nil
+	}
+
+	embeddings, err := s.client.CreateEmbeddings(ctx, s.model, inputs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create embeddings: %w", err)
+	}
+
+	for i, postID := range postIDs {
+		_, err := s.db.Exec(ctx, `
+			INSERT INTO post_embeddings (post_id, org_id, model, embedding, updated_at)
+			SELECT id, org_id, $2, $3::vector, now() FROM posts WHERE id = $1
+			ON CONFLICT (post_id) DO UPDATE
+			SET org_id = EXCLUDED.org_id, model = EXCLUDED.model, embedding = EXCLUDED.embedding, updated_at = EXCLUDED.updated_at`,
+			postID, s.model, formatVector(embeddings[i]))
+		if err != nil {
+			return i, fmt.Errorf("failed to store embedding: %w", err)
+		}
+	}
+
+	return len(postIDs), nil
+}
+
+// SemanticSearch ranks posts of orgID by cosine similarity to the query,
+// blended with recency
+func (s *EmbeddingService) SemanticSearch(ctx context.Context, query string, orgID, currentUserID uuid.UUID, limit, offset int) ([]*SemanticPost, error) {
+	embeddings, err := s.client.CreateEmbeddings(ctx, s.model, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+
+	// Nearest neighbours of orgID's posts come from the HNSW index, or for
+	// small organizations from a scan of their own embeddings; re-ranking
+	// happens on the candidates only.
+	rows, err := s.db.Query(ctx, `
+		WITH candidates AS (
+		    SELECT post_id, 1 - (embedding <=> $2::vector) AS similarity
+		    FROM post_embeddings
+		    WHERE model = $3 AND org_id = $10
+		    ORDER BY embedding <=> $2::vector
+		    LIMIT $4
+		)
+		SELECT p.id, p.author_id, p.text, p.course_id, p.module_id, p.is_ai_generated, p.edited_at IS NOT NULL, p.slug, p.created_at, p.updated_at,
+		       (SELECT COUNT(*) FROM likes l WHERE l.post_id = p.id) as like_count,
+		       (SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id) as comment_count,
+		       u.username, u.email, u.bio, u.avatar_url,
+		       EXISTS(SELECT 1 FROM likes ul WHERE ul.post_id = p.id AND ul.user_id = $1) as is_liked,
+		       cand.similarity,
+		       $5::float8 * cand.similarity +
+		       $6::float8 * exp(-EXTRACT(EPOCH FROM (now() - p.created_at))::float8 / 86400.0 / $7::float8) as score
+		FROM candidates cand
+		JOIN posts p ON p.id = cand.post_id
+		JOIN users u ON p.author_id = u.id
+		WHERE p.org_id = $10 AND p.group_id IS NULL AND (NOT u.shadow_banned OR u.id = $1)
+		  AND (NOT u.is_private OR u.id = $1 OR EXISTS (SELECT 1 FROM follows fv WHERE fv.follower_id = $1 AND fv.followee_id = u.id))
+		ORDER BY score DESC
+		LIMIT $8 OFFSET $9`,
+		currentUserID, formatVector(embeddings[0]), s.model, semanticCandidateLimit,
+		semanticSimilarityW, semanticRecencyW, semanticRecencyDays, limit, offset, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search posts: %w", err)
+	}
+	defer rows.Close()
+
+	var posts []*SemanticPost
+	for rows.Next() {
+		var post SemanticPost
+		var courseID, moduleID pgtype.UUID
+		var bio, avatarURL pgtype.Text
+
+		err := rows.Scan(
+			&post.ID, &post.AuthorID, &post.Text, &courseID, &moduleID, &post.IsAIGenerated, &post.Edited, &post.Slug,
+			&post.CreatedAt, &post.UpdatedAt, &post.LikeCount, &post.CommentCount,
+			&post.Author.Username, &post.Author.Email, &bio, &avatarURL, &post.IsLiked,
+			&post.Similarity, &post.Score)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan post: %w", err)
+		}
+
+		if courseID.Valid {
+			courseUUID := uuid.UUID(courseID.Bytes)
+			post.CourseID = &courseUUID
+		}
+		if moduleID.Valid {
+			moduleUUID := uuid.UUID(moduleID.Bytes)
+			post.ModuleID = &moduleUUID
+		}
+		post.TextHTML = RenderPostText(post.Slug, post.Text)
+		post.Author.ID = post.AuthorID
+		post.Author.Bio = getPgtypeTextValue(bio)
+		post.Author.AvatarURL = getPgtypeTextPtr(avatarURL)
+
+		posts = append(posts, &post)
+	}
+
+	return posts, nil
+}
+
+// formatVector renders an embedding as a pgvector text literal
+func formatVector(values []float32) string {
+	var b strings.Builder
+	b.Grow(len(values) * 10)
+	b.WriteByte('[')
+	for i, v := range values {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(v), 'f', -1, 32))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
@@ -0,0 +1,419 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"bailanysta/api/internal/pkg/database"
+	"bailanysta/api/internal/pkg/sentry"
+)
+
+// RSVP statuses
+const (
+	RSVPGoing    = "going"
+	RSVPMaybe    = "maybe"
+	RSVPDeclined = "declined"
+)
+
+// EventService manages course and group events and sends reminders to
+// their attendees shortly before they start
+type EventService struct {
+	db             *database.Pool
+	appURL         string
+	reminderLead   time.Duration
+	reminderPeriod time.Duration
+}
+
+type Event struct {
+	ID          uuid.UUID  `json:"id"`
+	CourseID    *uuid.UUID `json:"course_id,omitempty"`
+	GroupID     *uuid.UUID `json:"group_id,omitempty"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Location    string     `json:"location"`
+	StartsAt    time.Time  `json:"starts_at"`
+	EndsAt      time.Time  `json:"ends_at"`
+	CreatedBy   *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	GoingCount  int        `json:"going_count"`
+	MaybeCount  int        `json:"maybe_count"`
+	RSVP        string     `json:"rsvp,omitempty"` // the viewer's, if any
+}
+
+type EventAttendee struct {
+	User      UserResponse `json:"user"`
+	Status    string       `json:"status"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// CreateEventRequest needs exactly one of CourseID and GroupID
+type CreateEventRequest struct {
+	CourseID    *uuid.UUID `json:"course_id"`
+	GroupID     *uuid.UUID `json:"group_id"`
+	Title       string     `json:"title" validate:"required,max=200"`
+	Description string     `json:"description" validate:"max=2000"`
+	Location    string     `json:"location" validate:"max=200"`
+	StartsAt    time.Time  `json:"starts_at" validate:"required"`
+	EndsAt      time.Time  `json:"ends_at" validate:"required,gtfield=StartsAt"`
+}
+
+// EventFilter narrows GetEvents; events that ended before From are skipped
+type EventFilter struct {
+	CourseID *uuid.UUID
+	GroupID  *uuid.UUID
+	From     time.Time
+	Limit    int
+	Offset   int
+}
+
+func NewEventService(db *database.Pool, appURL string, reminderLead, reminderPeriod time.Duration) *EventService {
+	return &EventService{
+		db:             db,
+		appURL:         appURL,
+		reminderLead:   reminderLead,
+		reminderPeriod: reminderPeriod,
+	}
+}
+
+// eventsQuery selects the events of the viewer's ($1) organization that the
+// viewer can see, with RSVP counts and the viewer's RSVP. Group events follow
+// the group's read rules.
+const eventsQuery = `
+	SELECT e.id, e.course_id, e.group_id, e.title, e.description, e.location,
+	       e.starts_at, e.ends_at, e.created_by, e.created_at,
+	       (SELECT COUNT(*) FROM event_rsvps r WHERE r.event_id = e.id AND r.status = 'going'),
+	       (SELECT COUNT(*) FROM event_rsvps r WHERE r.event_id = e.id AND r.status = 'maybe'),
+	       er.status
+	FROM events e
+	LEFT JOIN groups g ON g.id = e.group_id
+	LEFT JOIN group_members gm ON gm.group_id = e.group_id AND gm.user_id = $1 AND gm.status = 'active'
+	LEFT JOIN event_rsvps er ON er.event_id = e.id AND er.user_id = $1
+	WHERE e.org_id = (SELECT org_id FROM users WHERE id = $1)
+	  AND (e.group_id IS NULL OR g.privacy = 'open' OR gm.user_id IS NOT NULL)`
+
+func scanEvent(row pgx.Row) (*Event, error) {
+	var event Event
+	var rsvp pgtype.Text
+	err := row.Scan(&event.ID, &event.CourseID, &event.GroupID, &event.Title, &event.Description, &event.Location,
+		&event.StartsAt, &event.EndsAt, &event.CreatedBy, &event.CreatedAt,
+		&event.GoingCount, &event.MaybeCount, &rsvp)
+	if err != nil {
+		return nil, err
+	}
+	event.RSVP = getPgtypeTextValue(rsvp)
+	return &event, nil
+}
+
+// CreateEvent schedules an event and RSVPs the creator as going. Course
+// events need the manage_courses permission, group events a group admin.
+func (s *EventService) CreateEvent(ctx context.Context, userID uuid.UUID, req CreateEventRequest) (*Event, error) {
+	if (req.CourseID == nil) == (req.GroupID == nil) {
+		return nil, fmt.Errorf("event needs a course or a group")
+	}
+
+	event := &Event{
+		CourseID:    req.CourseID,
+		GroupID:     req.GroupID,
+		Title:       req.Title,
+		Description: req.Description,
+		Location:    req.Location,
+		StartsAt:    req.StartsAt,
+		EndsAt:      req.EndsAt,
+		CreatedBy:   &userID,
+		GoingCount:  1,
+		RSVP:        RSVPGoing,
+	}
+
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		if req.GroupID != nil {
+			if err := requireGroupAdmin(ctx, tx, *req.GroupID, userID); err != nil {
+				return err
+			}
+		} else {
+			var role Role
+			var courseExists bool
+			err := tx.QueryRow(ctx, `
+				SELECT u.role, EXISTS(SELECT 1 FROM courses c WHERE c.id = $2 AND c.org_id = u.org_id)
+				FROM users u WHERE u.id = $1`, userID, *req.CourseID).Scan(&role, &courseExists)
+			if err != nil {
+				return fmt.Errorf("failed to check course: %w", err)
+			}
+			if !role.Can(PermissionManageCourses) {
+				return fmt.Errorf("access denied")
+			}
+			if !courseExists {
+				return fmt.Errorf("course not found")
+			}
+		}
+
+		err := tx.QueryRow(ctx, `
+			INSERT INTO events (org_id, course_id, group_id, title, description, location, starts_at, ends_at, created_by)
+			VALUES ((SELECT org_id FROM users WHERE id = $1), $2, $3, $4, $5, $6, $7, $8, $1)
+			RETURNING id, created_at`,
+			userID, req.CourseID, req.GroupID, req.Title, req.Description, req.Location,
+			req.StartsAt, req.EndsAt).Scan(&event.ID, &event.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to create event: %w", err)
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO event_rsvps (event_id, user_id, status) VALUES ($1, $2, 'going')`, event.ID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to add event RSVP: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// GetEvents lists the events the viewer can see, soonest first
+func (s *EventService) GetEvents(ctx context.Context, viewerID uuid.UUID, filter EventFilter) ([]*Event, error) {
+	rows, err := s.db.Query(ctx, eventsQuery+`
+		AND e.ends_at > $2
+		AND ($3::uuid IS NULL OR e.course_id = $3)
+		AND ($4::uuid IS NULL OR e.group_id = $4)
+		ORDER BY e.starts_at, e.id
+		LIMIT $5 OFFSET $6`,
+		viewerID, filter.From, filter.CourseID, filter.GroupID, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []*Event{}
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// GetEvent fails with "event not found" for events the viewer can't see
+func (s *EventService) GetEvent(ctx context.Context, eventID, viewerID uuid.UUID) (*Event, error) {
+	event, err := scanEvent(s.db.QueryRow(ctx, eventsQuery+`
+		AND e.id = $2`, viewerID, eventID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("event not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event: %w", err)
+	}
+	return event, nil
+}
+
+// SetRSVP records the user's answer to an event that hasn't ended yet
+func (s *EventService) SetRSVP(ctx context.Context, eventID, userID uuid.UUID, status string) error {
+	if status != RSVPGoing && status != RSVPMaybe && status != RSVPDeclined {
+		return fmt.Errorf("invalid rsvp status")
+	}
+
+	event, err := s.GetEvent(ctx, eventID, userID)
+	if err != nil {
+		return err
+	}
+	if !event.EndsAt.After(time.Now()) {
+		return fmt.Errorf("event has ended")
+	}
+
+	_, err = s.db.Exec(ctx, `
+		INSERT INTO event_rsvps (event_id, user_id, status) VALUES ($1, $2, $3)
+		ON CONFLICT (event_id, user_id) DO UPDATE SET status = EXCLUDED.status, created_at = now()`,
+		eventID, userID, status)
+	if err != nil {
+		return fmt.Errorf("failed to save RSVP: %w", err)
+	}
+	return nil
+}
+
+func (s *EventService) RemoveRSVP(ctx context.Context, eventID, userID uuid.UUID) error {
+	result, err := s.db.Exec(ctx, `
+		DELETE FROM event_rsvps WHERE event_id = $1 AND user_id = $2`, eventID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove RSVP: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("rsvp not found")
+	}
+	return nil
+}
+
+// GetEventAttendees lists the users who answered status to an event
+func (s *EventService) GetEventAttendees(ctx context.Context, eventID, viewerID uuid.UUID, status string, limit, offset int) ([]*EventAttendee, error) {
+	if status != RSVPGoing && status != RSVPMaybe && status != RSVPDeclined {
+		return nil, fmt.Errorf("invalid rsvp status")
+	}
+	if _, err := s.GetEvent(ctx, eventID, viewerID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT u.id, u.username, u.bio, u.avatar_url, r.status, r.created_at
+		FROM event_rsvps r
+		JOIN users u ON u.id = r.user_id
+		WHERE r.event_id = $1 AND r.status = $2
+		ORDER BY r.created_at, u.username
+		LIMIT $3 OFFSET $4`, eventID, status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event attendees: %w", err)
+	}
+	defer rows.Close()
+
+	attendees := []*EventAttendee{}
+	for rows.Next() {
+		var attendee EventAttendee
+		var bio, avatarURL pgtype.Text
+		err := rows.Scan(&attendee.User.ID, &attendee.User.Username, &bio, &avatarURL,
+			&attendee.Status, &attendee.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan event attendee: %w", err)
+		}
+		attendee.User.Bio = getPgtypeTextValue(bio)
+		attendee.User.AvatarURL = getPgtypeTextPtr(avatarURL)
+		attendees = append(attendees, &attendee)
+	}
+	return attendees, rows.Err()
+}
+
+// ICalendar renders events as an iCalendar (RFC 5545) document
+func (s *EventService) ICalendar(events []*Event) string {
+	return renderICalendar(events, s.appURL, time.Now())
+}
+
+// Run sends event reminders until ctx is cancelled
+func (s *EventService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.reminderPeriod)
+	defer ticker.Stop()
+
+	for {
+		if err := s.SendReminders(ctx); err != nil {
+			fmt.Printf("Failed to send event reminders: %v\n", err)
+			sentry.CaptureError(err, map[string]string{"job": "event-reminders"})
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendReminders claims the events starting within the reminder lead time
+// and queues one reminder per event; attendees are resolved when the
+// outbox is dispatched. Events that already started are never reminded.
+func (s *EventService) SendReminders(ctx context.Context) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			UPDATE events SET reminder_sent_at = now()
+			WHERE reminder_sent_at IS NULL
+			  AND starts_at > now()
+			  AND starts_at <= now() + make_interval(secs => $1::float8)
+			RETURNING id, COALESCE(created_by, '00000000-0000-0000-0000-000000000000')`,
+			s.reminderLead.Seconds())
+		if err != nil {
+			return fmt.Errorf("failed to claim event reminders: %w", err)
+		}
+
+		type reminder struct{ eventID, creatorID uuid.UUID }
+		var reminders []reminder
+		for rows.Next() {
+			var r reminder
+			if err := rows.Scan(&r.eventID, &r.creatorID); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan event reminder: %w", err)
+			}
+			reminders = append(reminders, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to claim event reminders: %w", err)
+		}
+
+		for _, r := range reminders {
+			err := enqueueNotification(ctx, tx, OutboxEvent{
+				Type:    NotificationTypeEventReminder,
+				ActorID: r.creatorID,
+				EventID: &r.eventID,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// renderICalendar writes one VEVENT per event with times in UTC
+func renderICalendar(events []*Event, appURL string, now time.Time) string {
+	const timeFormat = "20060102T150405Z"
+
+	var b strings.Builder
+	writeLine := func(line string) {
+		b.WriteString(foldICalLine(line))
+		b.WriteString("\r\n")
+	}
+
+	writeLine("BEGIN:VCALENDAR")
+	writeLine("VERSION:2.0")
+	writeLine("PRODID:-//Bailanysta//Events//EN")
+	writeLine("CALSCALE:GREGORIAN")
+	for _, event := range events {
+		writeLine("BEGIN:VEVENT")
+		writeLine("UID:" + event.ID.String() + "@bailanysta")
+		writeLine("DTSTAMP:" + now.UTC().Format(timeFormat))
+		writeLine("DTSTART:" + event.StartsAt.UTC().Format(timeFormat))
+		writeLine("DTEND:" + event.EndsAt.UTC().Format(timeFormat))
+		writeLine("SUMMARY:" + escapeICalText(event.Title))
+		if event.Description != "" {
+			writeLine("DESCRIPTION:" + escapeICalText(event.Description))
+		}
+		if event.Location != "" {
+			writeLine("LOCATION:" + escapeICalText(event.Location))
+		}
+		writeLine("URL:" + strings.TrimRight(appURL, "/") + "/events/" + event.ID.String())
+		writeLine("END:VEVENT")
+	}
+	writeLine("END:VCALENDAR")
+	return b.String()
+}
+
+var icalTextEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escapeICalText(text string) string {
+	return icalTextEscaper.Replace(text)
+}
+
+// foldICalLine splits lines longer than 75 octets, continuing them on lines
+// that start with a space, without breaking UTF-8 sequences
+func foldICalLine(line string) string {
+	const maxOctets = 75
+	if len(line) <= maxOctets {
+		return line
+	}
+
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > maxOctets {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	return b.String()
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderICalendar(t *testing.T) {
+	event := &Event{
+		ID:          uuid.MustParse("6f1c2b1e-9a7d-4a53-8f62-1d2c3b4a5e6f"),
+		Title:       "Calculus; review, part 2",
+		Description: "Bring notes\nand questions",
+		StartsAt:    time.Date(2026, 3, 2, 15, 0, 0, 0, time.FixedZone("ALMT", 5*60*60)),
+		EndsAt:      time.Date(2026, 3, 2, 16, 30, 0, 0, time.FixedZone("ALMT", 5*60*60)),
+	}
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	ical := renderICalendar([]*Event{event}, "https://bailanysta.kz/", now)
+
+	assert.True(t, strings.HasPrefix(ical, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.True(t, strings.HasSuffix(ical, "END:VEVENT\r\nEND:VCALENDAR\r\n"))
+	assert.Contains(t, ical, "\r\nUID:6f1c2b1e-9a7d-4a53-8f62-1d2c3b4a5e6f@bailanysta\r\n")
+	assert.Contains(t, ical, "\r\nDTSTAMP:20260301T090000Z\r\n")
+	assert.Contains(t, ical, "\r\nDTSTART:20260302T100000Z\r\n")
+	assert.Contains(t, ical, "\r\nDTEND:20260302T113000Z\r\n")
+	assert.Contains(t, ical, "\r\nSUMMARY:Calculus\\; review\\, part 2\r\n")
+	assert.Contains(t, ical, "\r\nDESCRIPTION:Bring notes\\nand questions\r\n")
+	assert.Contains(t, ical, "\r\nURL:https://bailanysta.kz/events/6f1c2b1e-9a7d-4a53-8f62-1d2c3b4a5e6f\r\n")
+	assert.NotContains(t, ical, "LOCATION:")
+}
+
+func TestFoldICalLine(t *testing.T) {
+	t.Run("short line is unchanged", func(t *testing.T) {
+		assert.Equal(t, "SUMMARY:Office hours", foldICalLine("SUMMARY:Office hours"))
+	})
+
+	t.Run("long line is folded at 75 octets", func(t *testing.T) {
+		line := "DESCRIPTION:" + strings.Repeat("a", 100)
+		folded := foldICalLine(line)
+
+		parts := strings.Split(folded, "\r\n")
+		assert.Len(t, parts, 2)
+		assert.Len(t, parts[0], 75)
+		assert.True(t, strings.HasPrefix(parts[1], " "))
+		assert.Equal(t, line, parts[0]+parts[1][1:])
+	})
+
+	t.Run("multi-byte characters are not split", func(t *testing.T) {
+		folded := foldICalLine("SUMMARY:" + strings.Repeat("қ", 60))
+
+		for _, part := range strings.Split(folded, "\r\n") {
+			assert.LessOrEqual(t, len(part), 75)
+			assert.True(t, strings.ToValidUTF8(part, "?") == part)
+		}
+	})
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"bailanysta/api/internal/pkg/database"
+)
+
+const exportChunkSize = 1000
+
+// ExportFields are the post fields an export can select, in their default
+// order. seq orders the export and resumes it via ExportFilter.After.
+var ExportFields = []string{
+	"seq", "id", "author_id", "created_at", "text", "course_id", "language",
+	"is_ai_generated", "like_count", "comment_count", "view_count", "hashtags",
+}
+
+// ExportService streams posts of an organization for offline analysis
+type ExportService struct {
+	db *database.Pool
+}
+
+// ExportPost is a post with its engagement counts as exported
+type ExportPost struct {
+	Seq           int64
+	ID            uuid.UUID
+	AuthorID      uuid.UUID
+	CreatedAt     time.Time
+	Text          string
+	CourseID      *uuid.UUID
+	Language      *string
+	IsAIGenerated bool
+	LikeCount     int64
+	CommentCount  int64
+	ViewCount     int64
+	Hashtags      []string
+}
+
+// ExportFilter narrows an export; zero values don't filter. Limit caps the
+// number of posts, After skips posts up to and including that seq.
+type ExportFilter struct {
+	From  *time.Time
+	To    *time.Time
+	After int64
+	Limit int
+}
+
+func NewExportService(db *database.Pool) *ExportService {
+	return &ExportService{db: db}
+}
+
+// ParseExportFields parses a comma-separated field list; an empty list
+// selects every field
+func ParseExportFields(param string) ([]string, error) {
+	if strings.TrimSpace(param) == "" {
+		return ExportFields, nil
+	}
+
+	var fields []string
+	seen := make(map[string]bool)
+	for _, field := range strings.Split(param, ",") {
+		field = strings.TrimSpace(field)
+		if !validExportField(field) {
+			return nil, fmt.Errorf("invalid export field")
+		}
+		if !seen[field] {
+			seen[field] = true
+			fields = append(fields, field)
+		}
+	}
+	return fields, nil
+}
+
+func validExportField(field string) bool {
+	for _, f := range ExportFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Value returns field of p as it is encoded in JSON
+func (p *ExportPost) Value(field string) interface{} {
+	switch field {
+	case "seq":
+		return p.Seq
+	case "id":
+		return p.ID
+	case "author_id":
+		return p.AuthorID
+	case "created_at":
+		return p.CreatedAt.UTC()
+	case "text":
+		return p.Text
+	case "course_id":
+		return p.CourseID
+	case "language":
+		return p.Language
+	case "is_ai_generated":
+		return p.IsAIGenerated
+	case "like_count":
+		return p.LikeCount
+	case "comment_count":
+		return p.CommentCount
+	case "view_count":
+		return p.ViewCount
+	case "hashtags":
+		return p.Hashtags
+	}
+	return nil
+}
+
+// CSVValue returns field of p as a CSV cell; nulls are empty and hashtags
+// are joined with spaces
+func (p *ExportPost) CSVValue(field string) string {
+	switch field {
+	case "seq":
+		return strconv.FormatInt(p.Seq, 10)
+	case "id":
+		return p.ID.String()
+	case "author_id":
+		return p.AuthorID.String()
+	case "created_at":
+		return p.CreatedAt.UTC().Format(time.RFC3339)
+	case "text":
+		return p.Text
+	case "course_id":
+		if p.CourseID == nil {
+			return ""
+		}
+		return p.CourseID.String()
+	case "language":
+		if p.Language == nil {
+			return ""
+		}
+		return *p.Language
+	case "is_ai_generated":
+		return strconv.FormatBool(p.IsAIGenerated)
+	case "like_count":
+		return strconv.FormatInt(p.LikeCount, 10)
+	case "comment_count":
+		return strconv.FormatInt(p.CommentCount, 10)
+	case "view_count":
+		return strconv.FormatInt(p.ViewCount, 10)
+	case "hashtags":
+		return strings.Join(p.Hashtags, " ")
+	}
+	return ""
+}
+
+// ExportPosts passes orgID's posts to emit in chunks, oldest first, until the
+// filter is exhausted or emit fails. Group posts and posts by private or
+// shadow-banned users are left out.
+func (s *ExportService) ExportPosts(ctx context.Context, orgID uuid.UUID, filter ExportFilter, emit func([]*ExportPost) error) error {
+	after := filter.After
+	remaining := filter.Limit
+
+	for {
+		chunk := exportChunkSize
+		if filter.Limit > 0 {
+			chunk = min(chunk, remaining)
+		}
+		if chunk == 0 {
+			return nil
+		}
+
+		posts, err := s.exportChunk(ctx, orgID, filter, after, chunk)
+		if err != nil {
+			return err
+		}
+		if len(posts) == 0 {
+			return nil
+		}
+		if err := emit(posts); err != nil {
+			return err
+		}
+		if len(posts) < chunk {
+			return nil
+		}
+
+		after = posts[len(posts)-1].Seq
+		remaining -= len(posts)
+	}
+}
+
+func (s *ExportService) exportChunk(ctx context.Context, orgID uuid.UUID, filter ExportFilter, after int64, limit int) ([]*ExportPost, error) {
+	rows, err := s.db.Reader().Query(ctx, `
+		SELECT p.seq, p.id, p.author_id, p.created_at, p.text, p.course_id, p.language, p.is_ai_generated,
+		       (SELECT COUNT(*) FROM likes l WHERE l.post_id = p.id),
+		       (SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id),
+		       p.view_count,
+		       COALESCE((SELECT array_agg(h.display ORDER BY h.tag)
+		                 FROM post_hashtags ph JOIN hashtags h ON h.id = ph.hashtag_id
+		                 WHERE ph.post_id = p.id), '{}')
+		FROM posts p
+		JOIN users u ON u.id = p.author_id
+		WHERE p.org_id = $1 AND p.seq > $2
+		  AND p.group_id IS NULL AND NOT u.is_private AND NOT u.shadow_banned
+		  AND ($3::timestamptz IS NULL OR p.created_at >= $3)
+		  AND ($4::timestamptz IS NULL OR p.created_at < $4)
+		ORDER BY p.seq
+		LIMIT $5`, orgID, after, filter.From, filter.To, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export posts: %w", err)
+	}
+	defer rows.Close()
+
+	var posts []*ExportPost
+	for rows.Next() {
+		var p ExportPost
+		err := rows.Scan(&p.Seq, &p.ID, &p.AuthorID, &p.CreatedAt, &p.Text, &p.CourseID, &p.Language, &p.IsAIGenerated,
+			&p.LikeCount, &p.CommentCount, &p.ViewCount, &p.Hashtags)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan exported post: %w", err)
+		}
+		posts = append(posts, &p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to export posts: %w", err)
+	}
+	return posts, nil
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseExportFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		param   string
+		want    []string
+		wantErr bool
+	}{
+		{"empty selects all", "", ExportFields, false},
+		{"subset keeps order", "text, seq", []string{"text", "seq"}, false},
+		{"duplicates dropped", "id,id,hashtags", []string{"id", "hashtags"}, false},
+		{"unknown field", "id,email", nil, true},
+		{"empty entry", "id,,text", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseExportFields(tt.param)
+			if tt.wantErr {
+				assert.EqualError(t, err, "invalid export field")
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestExportPostCSVValue(t *testing.T) {
+	language := "kk"
+	post := &ExportPost{
+		Seq:       42,
+		ID:        uuid.MustParse("11111111-1111-1111-1111-111111111111"),
+		CreatedAt: time.Date(2026, 5, 1, 14, 30, 0, 0, time.FixedZone("ALMT", 5*60*60)),
+		Text:      "Сәлем, \"әлем\"",
+		Language:  &language,
+		LikeCount: 3,
+		Hashtags:  []string{"go", "kbtu"},
+	}
+
+	assert.Equal(t, "42", post.CSVValue("seq"))
+	assert.Equal(t, "11111111-1111-1111-1111-111111111111", post.CSVValue("id"))
+	assert.Equal(t, "2026-05-01T09:30:00Z", post.CSVValue("created_at"))
+	assert.Equal(t, "Сәлем, \"әлем\"", post.CSVValue("text"))
+	assert.Equal(t, "", post.CSVValue("course_id"))
+	assert.Equal(t, "kk", post.CSVValue("language"))
+	assert.Equal(t, "false", post.CSVValue("is_ai_generated"))
+	assert.Equal(t, "3", post.CSVValue("like_count"))
+	assert.Equal(t, "go kbtu", post.CSVValue("hashtags"))
+}
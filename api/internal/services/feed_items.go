@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"bailanysta/api/internal/pkg/sentry"
+)
+
+const (
+	// Posts copied into a feed by the backfill or a new follow; older posts
+	// drop out of the materialized feed
+	feedBackfillPosts     = 1000
+	feedBackfillBatchSize = 100
+	feedBackfillInterval  = time.Minute
+)
+
+// materializedFeedQuery reads a page of the viewer's feed_items. Filters match
+// GetFeed so both paths show the same posts.
+const materializedFeedQuery = `
+	SELECT p.id, p.author_id, p.text, p.course_id, p.module_id, p.is_ai_generated, p.edited_at IS NOT NULL, p.slug, p.created_at, p.updated_at,
+	       p.like_count,
+	       (SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id) AS comment_count,
+	       u.username, u.email, u.bio, u.avatar_url,
+	       EXISTS(SELECT 1 FROM likes ul WHERE ul.post_id = p.id AND ul.user_id = $1) AS is_liked
+	FROM feed_items fi
+	JOIN posts p ON p.id = fi.post_id
+	JOIN users u ON p.author_id = u.id
+	WHERE fi.user_id = $1
+	AND (NOT u.shadow_banned OR u.id = $1)
+	AND (NOT p.is_ai_generated OR p.author_id = $1
+	     OR (SELECT ai_content FROM users WHERE id = $1) <> 'hide')
+	AND (p.language IS NULL OR p.author_id = $1
+	     OR (SELECT feed_languages = '{}' OR p.language = ANY(feed_languages) FROM users WHERE id = $1))
+	AND ($4 = '' OR p.post_type = 'question' AND ($4 = 'questions' OR p.accepted_comment_id IS NULL))
+	ORDER BY fi.created_at DESC
+	LIMIT $2 OFFSET $3`
+
+// fanOutPost adds a new post to its author's and followers' feeds
+func fanOutPost(ctx context.Context, tx pgx.Tx, postID, authorID uuid.UUID, createdAt time.Time) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO feed_items (user_id, post_id, author_id, created_at)
+		SELECT follower_id, $1, $2, $3 FROM follows WHERE followee_id = $2
+		UNION
+		SELECT $2, $1, $2, $3
+		ON CONFLICT DO NOTHING`, postID, authorID, createdAt)
+	if err != nil {
+		return fmt.Errorf("failed to fan out post: %w", err)
+	}
+	return nil
+}
+
+// addAuthorToFeed copies followeeID's recent posts into followerID's feed
+// after a follow
+func addAuthorToFeed(ctx context.Context, tx pgx.Tx, followerID, followeeID uuid.UUID) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO feed_items (user_id, post_id, author_id, created_at)
+		SELECT $1, id, author_id, created_at FROM posts
+		WHERE author_id = $2 AND group_id IS NULL
+		ORDER BY created_at DESC
+		LIMIT $3
+		ON CONFLICT DO NOTHING`, followerID, followeeID, feedBackfillPosts)
+	if err != nil {
+		return fmt.Errorf("failed to add posts to feed: %w", err)
+	}
+	return nil
+}
+
+// removeAuthorFromFeed drops followeeID's posts from followerID's feed after
+// an unfollow
+func removeAuthorFromFeed(ctx context.Context, tx pgx.Tx, followerID, followeeID uuid.UUID) error {
+	_, err := tx.Exec(ctx, `
+		DELETE FROM feed_items WHERE user_id = $1 AND author_id = $2`, followerID, followeeID)
+	if err != nil {
+		return fmt.Errorf("failed to remove posts from feed: %w", err)
+	}
+	return nil
+}
+
+// hasMaterializedFeed reports whether userID's feed can be read from
+// feed_items
+func (s *SocialService) hasMaterializedFeed(ctx context.Context, userID uuid.UUID) (bool, error) {
+	if !s.feedFanout {
+		return false, nil
+	}
+
+	var backfilled bool
+	err := s.db.Reader().QueryRow(ctx, `
+		SELECT feed_backfilled_at IS NOT NULL FROM users WHERE id = $1`, userID).Scan(&backfilled)
+	if err != nil {
+		return false, fmt.Errorf("failed to check feed backfill: %w", err)
+	}
+	return backfilled, nil
+}
+
+// RunFeedBackfill fills feed_items for users who don't have a materialized
+// feed yet (existing users when fan-out is first enabled, then new sign-ups)
+// until ctx is cancelled
+func (s *SocialService) RunFeedBackfill(ctx context.Context) {
+	ticker := time.NewTicker(feedBackfillInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			filled, err := s.BackfillFeeds(ctx)
+			if err != nil {
+				fmt.Printf("Failed to backfill feeds: %v\n", err)
+				sentry.CaptureError(err, map[string]string{"job": "feed-backfill"})
+				break
+			}
+			if filled < feedBackfillBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// BackfillFeeds materializes the feeds of one batch of users and returns how
+// many it filled. Each user is filled in its own transaction, so posts and
+// follows written meanwhile are fanned out as usual.
+func (s *SocialService) BackfillFeeds(ctx context.Context) (int, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT id FROM users
+		WHERE feed_backfilled_at IS NULL
+		ORDER BY id
+		LIMIT $1`, feedBackfillBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get users to backfill: %w", err)
+	}
+	userIDs, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return 0, fmt.Errorf("failed to get users to backfill: %w", err)
+	}
+
+	for i, userID := range userIDs {
+		err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+			_, err := tx.Exec(ctx, `
+				INSERT INTO feed_items (user_id, post_id, author_id, created_at)
+				SELECT $1, id, author_id, created_at FROM posts
+				WHERE author_id IN (
+				    SELECT followee_id FROM follows WHERE follower_id = $1
+				    UNION
+				    SELECT $1
+				) AND group_id IS NULL
+				ORDER BY created_at DESC
+				LIMIT $2
+				ON CONFLICT DO NOTHING`, userID, feedBackfillPosts)
+			if err != nil {
+				return fmt.Errorf("failed to backfill feed: %w", err)
+			}
+
+			_, err = tx.Exec(ctx, `UPDATE users SET feed_backfilled_at = now() WHERE id = $1`, userID)
+			if err != nil {
+				return fmt.Errorf("failed to mark feed backfilled: %w", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return i, err
+		}
+	}
+
+	return len(userIDs), nil
+}
+
+// ResetMaterializedFeeds discards feed_items. Follows and posts aren't fanned
+// out while FEED_FANOUT_ENABLED is off, so feeds built earlier can't be
+// trusted if it is turned back on; the backfill rebuilds them.
+func (s *SocialService) ResetMaterializedFeeds(ctx context.Context) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			UPDATE users SET feed_backfilled_at = NULL WHERE feed_backfilled_at IS NOT NULL`)
+		if err != nil {
+			return fmt.Errorf("failed to reset feed backfill: %w", err)
+		}
+		if result.RowsAffected() == 0 {
+			return nil
+		}
+
+		if _, err := tx.Exec(ctx, `TRUNCATE feed_items`); err != nil {
+			return fmt.Errorf("failed to clear feed items: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,226 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"bailanysta/api/internal/pkg/database"
+)
+
+const (
+	// flashcardMinEase is the lowest SM-2 ease factor, so a hard card still
+	// spaces out a little
+	flashcardMinEase = 1.3
+	// flashcardPassGrade is the lowest grade that counts as remembered
+	flashcardPassGrade = 3
+)
+
+type Flashcard struct {
+	ID           uuid.UUID  `json:"id"`
+	Front        string     `json:"front"`
+	Back         string     `json:"back"`
+	Topic        string     `json:"topic,omitempty"`
+	SourcePostID *uuid.UUID `json:"source_post_id,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	FlashcardSchedule
+	LastReviewedAt *time.Time `json:"last_reviewed_at"`
+}
+
+// FlashcardSchedule is a card's SM-2 state
+type FlashcardSchedule struct {
+	EaseFactor   float64   `json:"ease_factor"`
+	IntervalDays int       `json:"interval_days"`
+	Repetitions  int       `json:"repetitions"` // successful reviews in a row
+	DueAt        time.Time `json:"due_at"`
+}
+
+type ReviewFlashcardRequest struct {
+	// 0-5: 0-2 forgotten, 3 hard, 4 good, 5 easy
+	Grade *int `json:"grade" validate:"required,min=0,max=5"`
+}
+
+// scheduleReview applies an SM-2 review graded grade (0-5) at now. A pass
+// grows the interval, 1 day then 6 then by the ease factor; a lapse starts
+// the card over tomorrow. The ease factor moves with every grade.
+func scheduleReview(state FlashcardSchedule, grade int, now time.Time) FlashcardSchedule {
+	next := state
+	if grade >= flashcardPassGrade {
+		switch state.Repetitions {
+		case 0:
+			next.IntervalDays = 1
+		case 1:
+			next.IntervalDays = 6
+		default:
+			next.IntervalDays = int(math.Round(float64(state.IntervalDays) * state.EaseFactor))
+		}
+		next.Repetitions++
+	} else {
+		next.Repetitions = 0
+		next.IntervalDays = 1
+	}
+
+	miss := float64(5 - grade)
+	next.EaseFactor = math.Max(flashcardMinEase, state.EaseFactor+0.1-miss*(0.08+miss*0.02))
+	next.DueAt = now.AddDate(0, 0, next.IntervalDays)
+	return next
+}
+
+// FlashcardService keeps the study cards users generate
+type FlashcardService struct {
+	db *database.Pool
+}
+
+func NewFlashcardService(db *database.Pool) *FlashcardService {
+	return &FlashcardService{db: db}
+}
+
+// CreateFlashcards saves generated cards for userID, in the order given
+func (s *FlashcardService) CreateFlashcards(ctx context.Context, userID uuid.UUID, topic string, sourcePostID *uuid.UUID, drafts []FlashcardDraft) ([]*Flashcard, error) {
+	cards := make([]*Flashcard, 0, len(drafts))
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		for _, draft := range drafts {
+			card := &Flashcard{Front: draft.Front, Back: draft.Back, Topic: topic, SourcePostID: sourcePostID}
+			err := tx.QueryRow(ctx, `
+				INSERT INTO flashcards (user_id, front, back, topic, source_post_id)
+				VALUES ($1, $2, $3, $4, $5)
+				RETURNING id, created_at, ease_factor, interval_days, repetitions, due_at`,
+				userID, draft.Front, draft.Back, topic, sourcePostID).Scan(&card.ID, &card.CreatedAt,
+				&card.EaseFactor, &card.IntervalDays, &card.Repetitions, &card.DueAt)
+			if err != nil {
+				return fmt.Errorf("failed to save flashcard: %w", err)
+			}
+			cards = append(cards, card)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cards, nil
+}
+
+// GetFlashcards lists userID's cards, newest first. A non-nil sourcePostID
+// limits them to cards made from that post.
+func (s *FlashcardService) GetFlashcards(ctx context.Context, userID uuid.UUID, sourcePostID *uuid.UUID, limit, offset int) ([]*Flashcard, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT `+flashcardColumns+`
+		FROM flashcards
+		WHERE user_id = $1 AND ($2::uuid IS NULL OR source_post_id = $2)
+		ORDER BY created_at DESC, id
+		LIMIT $3 OFFSET $4`, userID, sourcePostID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get flashcards: %w", err)
+	}
+	return scanFlashcards(rows)
+}
+
+// GetDueFlashcards lists userID's cards due for review, most overdue first,
+// and how many are due in all
+func (s *FlashcardService) GetDueFlashcards(ctx context.Context, userID uuid.UUID, limit int) ([]*Flashcard, int, error) {
+	var total int
+	err := s.db.QueryRow(ctx, `
+		SELECT COUNT(*) FROM flashcards WHERE user_id = $1 AND due_at <= now()`, userID).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count due flashcards: %w", err)
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT `+flashcardColumns+`
+		FROM flashcards
+		WHERE user_id = $1 AND due_at <= now()
+		ORDER BY due_at, id
+		LIMIT $2`, userID, limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get due flashcards: %w", err)
+	}
+	cards, err := scanFlashcards(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	return cards, total, nil
+}
+
+// ReviewFlashcard records userID's grade for a card and schedules its next
+// review
+func (s *FlashcardService) ReviewFlashcard(ctx context.Context, userID, cardID uuid.UUID, grade int) (*Flashcard, error) {
+	var card *Flashcard
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT `+flashcardColumns+`
+			FROM flashcards
+			WHERE id = $1 AND user_id = $2
+			FOR UPDATE`, cardID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to get flashcard: %w", err)
+		}
+		cards, err := scanFlashcards(rows)
+		if err != nil {
+			return err
+		}
+		if len(cards) == 0 {
+			return fmt.Errorf("flashcard not found")
+		}
+		card = cards[0]
+
+		now := time.Now()
+		card.FlashcardSchedule = scheduleReview(card.FlashcardSchedule, grade, now)
+		card.LastReviewedAt = &now
+
+		_, err = tx.Exec(ctx, `
+			UPDATE flashcards
+			SET ease_factor = $2, interval_days = $3, repetitions = $4, due_at = $5, last_reviewed_at = $6
+			WHERE id = $1`, cardID, card.EaseFactor, card.IntervalDays, card.Repetitions, card.DueAt, now)
+		if err != nil {
+			return fmt.Errorf("failed to schedule flashcard: %w", err)
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO flashcard_reviews (flashcard_id, user_id, grade, reviewed_at)
+			VALUES ($1, $2, $3, $4)`, cardID, userID, grade, now)
+		if err != nil {
+			return fmt.Errorf("failed to record flashcard review: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return card, nil
+}
+
+const flashcardColumns = `id, front, back, topic, source_post_id, created_at,
+		ease_factor, interval_days, repetitions, due_at, last_reviewed_at`
+
+func scanFlashcards(rows pgx.Rows) ([]*Flashcard, error) {
+	defer rows.Close()
+
+	cards := []*Flashcard{}
+	for rows.Next() {
+		var card Flashcard
+		err := rows.Scan(&card.ID, &card.Front, &card.Back, &card.Topic, &card.SourcePostID, &card.CreatedAt,
+			&card.EaseFactor, &card.IntervalDays, &card.Repetitions, &card.DueAt, &card.LastReviewedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan flashcard: %w", err)
+		}
+		cards = append(cards, &card)
+	}
+
+	return cards, rows.Err()
+}
+
+// DeleteFlashcard deletes one of userID's cards
+func (s *FlashcardService) DeleteFlashcard(ctx context.Context, userID, cardID uuid.UUID) error {
+	tag, err := s.db.Exec(ctx, `DELETE FROM flashcards WHERE id = $1 AND user_id = $2`, cardID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete flashcard: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("flashcard not found")
+	}
+	return nil
+}
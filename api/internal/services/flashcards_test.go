@@ -0,0 +1,39 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduleReview(t *testing.T) {
+	now := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	fresh := FlashcardSchedule{EaseFactor: 2.5, DueAt: now}
+
+	tests := []struct {
+		name         string
+		state        FlashcardSchedule
+		grade        int
+		wantInterval int
+		wantReps     int
+		wantEase     float64
+	}{
+		{"first pass", fresh, 4, 1, 1, 2.5},
+		{"second pass", FlashcardSchedule{EaseFactor: 2.5, IntervalDays: 1, Repetitions: 1}, 5, 6, 2, 2.6},
+		{"later pass grows by ease", FlashcardSchedule{EaseFactor: 2.5, IntervalDays: 6, Repetitions: 2}, 4, 15, 3, 2.5},
+		{"hard pass lowers ease", FlashcardSchedule{EaseFactor: 2.5, IntervalDays: 6, Repetitions: 2}, 3, 15, 3, 2.36},
+		{"lapse starts over", FlashcardSchedule{EaseFactor: 2.5, IntervalDays: 15, Repetitions: 3}, 1, 1, 0, 1.96},
+		{"ease has a floor", FlashcardSchedule{EaseFactor: 1.4, IntervalDays: 1, Repetitions: 0}, 0, 1, 0, flashcardMinEase},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := scheduleReview(tt.state, tt.grade, now)
+			assert.Equal(t, tt.wantInterval, got.IntervalDays)
+			assert.Equal(t, tt.wantReps, got.Repetitions)
+			assert.InDelta(t, tt.wantEase, got.EaseFactor, 1e-9)
+			assert.Equal(t, now.AddDate(0, 0, tt.wantInterval), got.DueAt)
+		})
+	}
+}
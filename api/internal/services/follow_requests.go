@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type PendingFollowRequest struct {
+	User      UserResponse `json:"user"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// requestFollow records a pending follow of a private account and notifies
+// its owner
+func (s *SocialService) requestFollow(ctx context.Context, requesterID, targetID uuid.UUID) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		// Following a private account needs an approved request, so checking
+		// before inserting is enough here
+		var following bool
+		err := tx.QueryRow(ctx, `
+			SELECT EXISTS(SELECT 1 FROM follows WHERE follower_id = $1 AND followee_id = $2)`,
+			requesterID, targetID).Scan(&following)
+		if err != nil {
+			return fmt.Errorf("failed to check follow status: %w", err)
+		}
+		if following {
+			return fmt.Errorf("already following this user")
+		}
+
+		result, err := tx.Exec(ctx, `
+			INSERT INTO follow_requests (requester_id, target_id)
+			VALUES ($1, $2)
+			ON CONFLICT (requester_id, target_id) DO NOTHING`, requesterID, targetID)
+		if err != nil {
+			return fmt.Errorf("failed to create follow request: %w", err)
+		}
+		if result.RowsAffected() == 0 {
+			return fmt.Errorf("follow request already sent")
+		}
+
+		if s.notificationsService == nil {
+			return nil
+		}
+		return enqueueNotification(ctx, tx, OutboxEvent{
+			Type:     NotificationTypeFollowRequest,
+			ActorID:  requesterID,
+			TargetID: &targetID,
+		})
+	})
+}
+
+// GetFollowRequests lists pending requests to follow userID, newest first
+func (s *SocialService) GetFollowRequests(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*PendingFollowRequest, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT u.id, u.username, u.email, u.bio, u.avatar_url, fr.created_at
+		FROM follow_requests fr
+		JOIN users u ON fr.requester_id = u.id
+		WHERE fr.target_id = $1
+		ORDER BY fr.created_at DESC
+		LIMIT $2 OFFSET $3`, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get follow requests: %w", err)
+	}
+	defer rows.Close()
+
+	requests := []*PendingFollowRequest{}
+	for rows.Next() {
+		var request PendingFollowRequest
+		var bio, avatarURL pgtype.Text
+
+		err := rows.Scan(&request.User.ID, &request.User.Username, &request.User.Email, &bio, &avatarURL, &request.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan follow request: %w", err)
+		}
+
+		request.User.Bio = getPgtypeTextValue(bio)
+		request.User.AvatarURL = getPgtypeTextPtr(avatarURL)
+		requests = append(requests, &request)
+	}
+
+	return requests, rows.Err()
+}
+
+// ApproveFollowRequest turns requesterID's pending request into a follow
+func (s *SocialService) ApproveFollowRequest(ctx context.Context, userID, requesterID uuid.UUID) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			DELETE FROM follow_requests
+			WHERE requester_id = $1 AND target_id = $2`, requesterID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to approve follow request: %w", err)
+		}
+		if result.RowsAffected() == 0 {
+			return fmt.Errorf("follow request not found")
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO follows (follower_id, followee_id)
+			VALUES ($1, $2)
+			ON CONFLICT DO NOTHING`, requesterID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to follow user: %w", err)
+		}
+
+		if s.feedFanout {
+			if err := addAuthorToFeed(ctx, tx, requesterID, userID); err != nil {
+				return err
+			}
+		}
+
+		if s.notificationsService == nil {
+			return nil
+		}
+		return enqueueNotification(ctx, tx, OutboxEvent{
+			Type:     NotificationTypeFollowAccepted,
+			ActorID:  userID,
+			TargetID: &requesterID,
+		})
+	})
+}
+
+// DeclineFollowRequest discards requesterID's pending request. The requester
+// is not told.
+func (s *SocialService) DeclineFollowRequest(ctx context.Context, userID, requesterID uuid.UUID) error {
+	result, err := s.db.Exec(ctx, `
+		DELETE FROM follow_requests
+		WHERE requester_id = $1 AND target_id = $2`, requesterID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to decline follow request: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("follow request not found")
+	}
+	return nil
+}
+
+// SetPrivate switches the account between public and private. Making the
+// account public approves every pending request.
+func (s *SocialService) SetPrivate(ctx context.Context, userID uuid.UUID, private bool) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `UPDATE users SET is_private = $2 WHERE id = $1`, userID, private)
+		if err != nil {
+			return fmt.Errorf("failed to update privacy: %w", err)
+		}
+		if private {
+			return nil
+		}
+
+		rows, err := tx.Query(ctx, `
+			WITH approved AS (
+			    DELETE FROM follow_requests WHERE target_id = $1
+			    RETURNING requester_id
+			)
+			INSERT INTO follows (follower_id, followee_id)
+			SELECT requester_id, $1 FROM approved
+			ON CONFLICT DO NOTHING
+			RETURNING follower_id`, userID)
+		if err != nil {
+			return fmt.Errorf("failed to approve follow requests: %w", err)
+		}
+		followerIDs, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+		if err != nil {
+			return fmt.Errorf("failed to approve follow requests: %w", err)
+		}
+
+		if s.feedFanout {
+			for _, followerID := range followerIDs {
+				if err := addAuthorToFeed(ctx, tx, followerID, userID); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"bailanysta/api/internal/pkg/coalesce"
+	"bailanysta/api/internal/pkg/gifs"
+)
+
+// GIF is a GIF from the configured provider. ID is "provider:id", which is
+// what posts and comments attach.
+type GIF struct {
+	ID         string `json:"id"`
+	Title      string `json:"title,omitempty"`
+	URL        string `json:"url"`
+	PreviewURL string `json:"preview_url"`
+	Width      int    `json:"width,omitempty"`
+	Height     int    `json:"height,omitempty"`
+}
+
+// GIFService proxies GIF search so the provider's API key stays on the
+// server. Searches and lookups are cached for cacheTTL.
+type GIFService struct {
+	client   *gifs.Client
+	searches *coalesce.Group[[]*GIF]
+	lookups  *coalesce.Group[*GIF]
+}
+
+// NewGIFService creates the service; with a nil client GIFs are disabled
+func NewGIFService(client *gifs.Client, cacheTTL time.Duration) *GIFService {
+	return &GIFService{
+		client:   client,
+		searches: coalesce.New[[]*GIF]("gif_search", cacheTTL),
+		lookups:  coalesce.New[*GIF]("gif_lookup", cacheTTL),
+	}
+}
+
+// Enabled reports whether a provider is configured
+func (s *GIFService) Enabled() bool {
+	return s != nil && s.client != nil
+}
+
+// Search returns up to limit GIFs matching query, or trending ones for an
+// empty query
+func (s *GIFService) Search(ctx context.Context, query string, limit int) ([]*GIF, error) {
+	if !s.Enabled() {
+		return nil, fmt.Errorf("GIFs are not enabled")
+	}
+	query = strings.ToLower(strings.TrimSpace(query))
+
+	key := fmt.Sprintf("%d:%s", limit, query)
+	return s.searches.Do(ctx, key, func(ctx context.Context) ([]*GIF, error) {
+		results, err := s.client.Search(ctx, query, limit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search GIFs: %w", err)
+		}
+		found := make([]*GIF, len(results))
+		for i, result := range results {
+			found[i] = s.fromProvider(result)
+		}
+		return found, nil
+	})
+}
+
+// Get looks a GIF up by the ID Search returned. IDs of another provider, or
+// ones the provider doesn't know, fail with "GIF not found".
+func (s *GIFService) Get(ctx context.Context, id string) (*GIF, error) {
+	if !s.Enabled() {
+		return nil, fmt.Errorf("GIFs are not enabled")
+	}
+	providerID, ok := strings.CutPrefix(id, s.client.Provider()+":")
+	if !ok || providerID == "" {
+		return nil, fmt.Errorf("GIF not found")
+	}
+
+	return s.lookups.Do(ctx, id, func(ctx context.Context) (*GIF, error) {
+		result, err := s.client.Get(ctx, providerID)
+		if errors.Is(err, gifs.ErrNotFound) {
+			return nil, fmt.Errorf("GIF not found")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get GIF: %w", err)
+		}
+		return s.fromProvider(*result), nil
+	})
+}
+
+func (s *GIFService) fromProvider(gif gifs.GIF) *GIF {
+	return &GIF{
+		ID:         s.client.Provider() + ":" + gif.ID,
+		Title:      truncateRunes(gif.Title, 200),
+		URL:        gif.URL,
+		PreviewURL: gif.PreviewURL,
+		Width:      gif.Width,
+		Height:     gif.Height,
+	}
+}
+
+// resolveGIF looks up the GIF a new post or comment attaches, if any
+func (s *PostsService) resolveGIF(ctx context.Context, id string) (*GIF, error) {
+	if id == "" {
+		return nil, nil
+	}
+	return s.gifs.Get(ctx, id)
+}
+
+// saveGIF stores gif's metadata within tx so it can be shown without asking
+// the provider again
+func saveGIF(ctx context.Context, tx pgx.Tx, gif *GIF) error {
+	provider, _, _ := strings.Cut(gif.ID, ":")
+	_, err := tx.Exec(ctx, `
+		INSERT INTO gifs (id, provider, title, url, preview_url, width, height)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (id) DO UPDATE
+		SET title = EXCLUDED.title, url = EXCLUDED.url, preview_url = EXCLUDED.preview_url,
+		    width = EXCLUDED.width, height = EXCLUDED.height, fetched_at = now()`,
+		gif.ID, provider, gif.Title, gif.URL, gif.PreviewURL, gif.Width, gif.Height)
+	if err != nil {
+		return fmt.Errorf("failed to save GIF: %w", err)
+	}
+	return nil
+}
+
+const postGIFsQuery = `
+	SELECT p.id, g.id, g.title, g.url, g.preview_url, g.width, g.height
+	FROM posts p
+	JOIN gifs g ON g.id = p.gif_id
+	WHERE p.id = ANY($1)`
+
+// getPostGIFs loads the GIFs attached to postIDs, keyed by post
+func getPostGIFs(ctx context.Context, db *pgxpool.Pool, postIDs []uuid.UUID) (map[uuid.UUID]*GIF, error) {
+	if len(postIDs) == 0 {
+		return map[uuid.UUID]*GIF{}, nil
+	}
+
+	rows, err := db.Query(ctx, postGIFsQuery, postIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GIFs: %w", err)
+	}
+	return scanPostGIFs(rows)
+}
+
+func scanPostGIFs(rows pgx.Rows) (map[uuid.UUID]*GIF, error) {
+	defer rows.Close()
+
+	byPost := make(map[uuid.UUID]*GIF)
+	for rows.Next() {
+		var postID uuid.UUID
+		var gif GIF
+		err := rows.Scan(&postID, &gif.ID, &gif.Title, &gif.URL, &gif.PreviewURL, &gif.Width, &gif.Height)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan GIF: %w", err)
+		}
+		byPost[postID] = &gif
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read GIFs: %w", err)
+	}
+	return byPost, nil
+}
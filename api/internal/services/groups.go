@@ -0,0 +1,489 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// GroupPrivacy decides who can see and join a study group
+type GroupPrivacy string
+
+const (
+	GroupPrivacyOpen    GroupPrivacy = "open"    // anyone in the organization can read and join
+	GroupPrivacyRequest GroupPrivacy = "request" // listed, but joining needs a group admin's approval
+	GroupPrivacyInvite  GroupPrivacy = "invite"  // hidden from everyone but members and invitees
+)
+
+type GroupRole string
+
+const (
+	GroupRoleOwner  GroupRole = "owner"
+	GroupRoleAdmin  GroupRole = "admin"
+	GroupRoleMember GroupRole = "member"
+)
+
+// Membership statuses; only active members count as members
+const (
+	GroupMemberActive    = "active"
+	GroupMemberRequested = "requested"
+	GroupMemberInvited   = "invited"
+)
+
+type Group struct {
+	ID          uuid.UUID        `json:"id"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Privacy     GroupPrivacy     `json:"privacy"`
+	MemberCount int              `json:"member_count"`
+	CreatedAt   time.Time        `json:"created_at"`
+	Membership  *GroupMembership `json:"membership,omitempty"` // the viewer's, if any
+}
+
+type GroupMembership struct {
+	Role   GroupRole `json:"role"`
+	Status string    `json:"status"`
+}
+
+type GroupMember struct {
+	User      UserResponse `json:"user"`
+	Role      GroupRole    `json:"role"`
+	Status    string       `json:"status"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type CreateGroupRequest struct {
+	Name        string       `json:"name" validate:"required,max=100"`
+	Description string       `json:"description" validate:"max=1000"`
+	Privacy     GroupPrivacy `json:"privacy" validate:"required,oneof=open request invite"`
+}
+
+func (m *GroupMembership) active() bool {
+	return m != nil && m.Status == GroupMemberActive
+}
+
+func (m *GroupMembership) isAdmin() bool {
+	return m.active() && (m.Role == GroupRoleOwner || m.Role == GroupRoleAdmin)
+}
+
+// canRead reports whether the viewer may see the group's posts and members
+func (g *Group) canRead() bool {
+	return g.Privacy == GroupPrivacyOpen || g.Membership.active()
+}
+
+// groupsQuery selects the groups of the viewer's ($1) organization that the
+// viewer can see, with the viewer's membership
+const groupsQuery = `
+	SELECT g.id, g.name, g.description, g.privacy, g.created_at,
+	       (SELECT COUNT(*) FROM group_members m WHERE m.group_id = g.id AND m.status = 'active'),
+	       gm.role, gm.status
+	FROM groups g
+	LEFT JOIN group_members gm ON gm.group_id = g.id AND gm.user_id = $1
+	WHERE g.org_id = (SELECT org_id FROM users WHERE id = $1)
+	  AND (g.privacy <> 'invite' OR gm.user_id IS NOT NULL)`
+
+func scanGroup(row pgx.Row) (*Group, error) {
+	var group Group
+	var role, status pgtype.Text
+	err := row.Scan(&group.ID, &group.Name, &group.Description, &group.Privacy, &group.CreatedAt,
+		&group.MemberCount, &role, &status)
+	if err != nil {
+		return nil, err
+	}
+	if status.Valid {
+		group.Membership = &GroupMembership{Role: GroupRole(role.String), Status: status.String}
+	}
+	return &group, nil
+}
+
+// CreateGroup creates a group in the user's organization with the user as
+// its owner
+func (s *SocialService) CreateGroup(ctx context.Context, userID uuid.UUID, req CreateGroupRequest) (*Group, error) {
+	group := &Group{
+		Name:        req.Name,
+		Description: req.Description,
+		Privacy:     req.Privacy,
+		MemberCount: 1,
+		Membership:  &GroupMembership{Role: GroupRoleOwner, Status: GroupMemberActive},
+	}
+
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, `
+			INSERT INTO groups (org_id, name, description, privacy, created_by)
+			VALUES ((SELECT org_id FROM users WHERE id = $1), $2, $3, $4, $1)
+			RETURNING id, created_at`, userID, req.Name, req.Description, req.Privacy).Scan(&group.ID, &group.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to create group: %w", err)
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO group_members (group_id, user_id, role, status)
+			VALUES ($1, $2, 'owner', 'active')`, group.ID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to add group owner: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// GetGroups lists the groups the viewer can see, by name
+func (s *SocialService) GetGroups(ctx context.Context, viewerID uuid.UUID) ([]*Group, error) {
+	rows, err := s.db.Query(ctx, groupsQuery+`
+		ORDER BY g.name`, viewerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get groups: %w", err)
+	}
+	defer rows.Close()
+
+	groups := []*Group{}
+	for rows.Next() {
+		group, err := scanGroup(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan group: %w", err)
+		}
+		groups = append(groups, group)
+	}
+	return groups, rows.Err()
+}
+
+// GetGroup fails with "group not found" for groups the viewer can't see
+func (s *SocialService) GetGroup(ctx context.Context, groupID, viewerID uuid.UUID) (*Group, error) {
+	group, err := scanGroup(s.db.QueryRow(ctx, groupsQuery+`
+		AND g.id = $2`, viewerID, groupID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("group not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group: %w", err)
+	}
+	return group, nil
+}
+
+// JoinGroup joins an open group, accepts an invitation or asks to join a
+// request-to-join group, and returns the resulting membership
+func (s *SocialService) JoinGroup(ctx context.Context, groupID, userID uuid.UUID) (*GroupMembership, error) {
+	group, err := s.GetGroup(ctx, groupID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	membership := group.Membership
+	switch {
+	case membership != nil && membership.Status != GroupMemberInvited:
+		// Already a member or already asked
+		return membership, nil
+
+	case membership != nil:
+		_, err = s.db.Exec(ctx, `
+			UPDATE group_members SET status = 'active', created_at = now()
+			WHERE group_id = $1 AND user_id = $2 AND status = 'invited'`, groupID, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to accept invitation: %w", err)
+		}
+		return &GroupMembership{Role: membership.Role, Status: GroupMemberActive}, nil
+
+	case group.Privacy == GroupPrivacyOpen:
+		_, err = s.db.Exec(ctx, `
+			INSERT INTO group_members (group_id, user_id) VALUES ($1, $2)
+			ON CONFLICT DO NOTHING`, groupID, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to join group: %w", err)
+		}
+		return &GroupMembership{Role: GroupRoleMember, Status: GroupMemberActive}, nil
+
+	case group.Privacy == GroupPrivacyRequest:
+		err = pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+			result, err := tx.Exec(ctx, `
+				INSERT INTO group_members (group_id, user_id, status) VALUES ($1, $2, 'requested')
+				ON CONFLICT DO NOTHING`, groupID, userID)
+			if err != nil {
+				return fmt.Errorf("failed to request to join group: %w", err)
+			}
+			if result.RowsAffected() == 0 || s.notificationsService == nil {
+				return nil
+			}
+			return enqueueNotification(ctx, tx, OutboxEvent{
+				Type:    NotificationTypeGroupJoinRequest,
+				ActorID: userID,
+				GroupID: &groupID,
+			})
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &GroupMembership{Role: GroupRoleMember, Status: GroupMemberRequested}, nil
+	}
+
+	return nil, fmt.Errorf("invitation required")
+}
+
+// LeaveGroup leaves a group, withdraws a join request or declines an
+// invitation. Owners can't leave their group.
+func (s *SocialService) LeaveGroup(ctx context.Context, groupID, userID uuid.UUID) error {
+	var role GroupRole
+	err := s.db.QueryRow(ctx, `
+		DELETE FROM group_members
+		WHERE group_id = $1 AND user_id = $2 AND role <> 'owner'
+		RETURNING role`, groupID, userID).Scan(&role)
+	if !errors.Is(err, pgx.ErrNoRows) {
+		if err != nil {
+			return fmt.Errorf("failed to leave group: %w", err)
+		}
+		return nil
+	}
+
+	var isOwner bool
+	err = s.db.QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM group_members WHERE group_id = $1 AND user_id = $2)`,
+		groupID, userID).Scan(&isOwner)
+	if err != nil {
+		return fmt.Errorf("failed to leave group: %w", err)
+	}
+	if isOwner {
+		return fmt.Errorf("group owner cannot leave")
+	}
+	return fmt.Errorf("not a group member")
+}
+
+// InviteToGroup invites a user of the group's organization. Inviting a user
+// who asked to join approves the request instead.
+func (s *SocialService) InviteToGroup(ctx context.Context, groupID, adminID, inviteeID uuid.UUID) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		if err := requireGroupAdmin(ctx, tx, groupID, adminID); err != nil {
+			return err
+		}
+
+		var sameOrg bool
+		err := tx.QueryRow(ctx, `
+			SELECT EXISTS(
+			    SELECT 1 FROM users u JOIN groups g ON g.org_id = u.org_id
+			    WHERE u.id = $1 AND g.id = $2
+			)`, inviteeID, groupID).Scan(&sameOrg)
+		if err != nil {
+			return fmt.Errorf("failed to check user: %w", err)
+		}
+		if !sameOrg {
+			return fmt.Errorf("user not found")
+		}
+
+		var status string
+		err = tx.QueryRow(ctx, `
+			INSERT INTO group_members (group_id, user_id, status) VALUES ($1, $2, 'invited')
+			ON CONFLICT (group_id, user_id) DO UPDATE SET status = 'active', created_at = now()
+			    WHERE group_members.status = 'requested'
+			RETURNING status`, groupID, inviteeID).Scan(&status)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("already invited")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to invite to group: %w", err)
+		}
+
+		if s.notificationsService == nil {
+			return nil
+		}
+		notificationType := NotificationTypeGroupInvite
+		if status == GroupMemberActive {
+			notificationType = NotificationTypeGroupJoinApproved
+		}
+		return enqueueNotification(ctx, tx, OutboxEvent{
+			Type:     notificationType,
+			ActorID:  adminID,
+			TargetID: &inviteeID,
+			GroupID:  &groupID,
+		})
+	})
+}
+
+// ApproveJoinRequest makes a user who asked to join a member
+func (s *SocialService) ApproveJoinRequest(ctx context.Context, groupID, adminID, userID uuid.UUID) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		if err := requireGroupAdmin(ctx, tx, groupID, adminID); err != nil {
+			return err
+		}
+
+		result, err := tx.Exec(ctx, `
+			UPDATE group_members SET status = 'active', created_at = now()
+			WHERE group_id = $1 AND user_id = $2 AND status = 'requested'`, groupID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to approve join request: %w", err)
+		}
+		if result.RowsAffected() == 0 {
+			return fmt.Errorf("join request not found")
+		}
+
+		if s.notificationsService == nil {
+			return nil
+		}
+		return enqueueNotification(ctx, tx, OutboxEvent{
+			Type:     NotificationTypeGroupJoinApproved,
+			ActorID:  adminID,
+			TargetID: &userID,
+			GroupID:  &groupID,
+		})
+	})
+}
+
+// RemoveGroupMember removes a member, declines a join request or withdraws
+// an invitation. The owner can't be removed.
+func (s *SocialService) RemoveGroupMember(ctx context.Context, groupID, adminID, userID uuid.UUID) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		if err := requireGroupAdmin(ctx, tx, groupID, adminID); err != nil {
+			return err
+		}
+
+		result, err := tx.Exec(ctx, `
+			DELETE FROM group_members
+			WHERE group_id = $1 AND user_id = $2 AND role <> 'owner'`, groupID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to remove group member: %w", err)
+		}
+		if result.RowsAffected() == 0 {
+			return fmt.Errorf("member not found")
+		}
+		return nil
+	})
+}
+
+// SetGroupMemberRole makes a member an admin or back; only the owner may
+func (s *SocialService) SetGroupMemberRole(ctx context.Context, groupID, ownerID, userID uuid.UUID, role GroupRole) error {
+	if role != GroupRoleAdmin && role != GroupRoleMember {
+		return fmt.Errorf("invalid group role")
+	}
+
+	var isOwner bool
+	err := s.db.QueryRow(ctx, `
+		SELECT EXISTS(
+		    SELECT 1 FROM group_members
+		    WHERE group_id = $1 AND user_id = $2 AND role = 'owner'
+		)`, groupID, ownerID).Scan(&isOwner)
+	if err != nil {
+		return fmt.Errorf("failed to check group role: %w", err)
+	}
+	if !isOwner {
+		return fmt.Errorf("access denied")
+	}
+
+	result, err := s.db.Exec(ctx, `
+		UPDATE group_members SET role = $3
+		WHERE group_id = $1 AND user_id = $2 AND status = 'active' AND role <> 'owner'`, groupID, userID, role)
+	if err != nil {
+		return fmt.Errorf("failed to update group role: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("member not found")
+	}
+	return nil
+}
+
+// GetGroupMembers lists a group's members, or with status requested or
+// invited its pending requests and invitations, which only group admins
+// may see
+func (s *SocialService) GetGroupMembers(ctx context.Context, groupID, viewerID uuid.UUID, status string, limit, offset int) ([]*GroupMember, error) {
+	group, err := s.GetGroup(ctx, groupID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	switch status {
+	case GroupMemberActive:
+		if !group.canRead() {
+			return nil, fmt.Errorf("access denied")
+		}
+	case GroupMemberRequested, GroupMemberInvited:
+		if !group.Membership.isAdmin() {
+			return nil, fmt.Errorf("access denied")
+		}
+	default:
+		return nil, fmt.Errorf("invalid member status")
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT u.id, u.username, u.bio, u.avatar_url, gm.role, gm.status, gm.created_at
+		FROM group_members gm
+		JOIN users u ON u.id = gm.user_id
+		WHERE gm.group_id = $1 AND gm.status = $2
+		ORDER BY gm.role = 'member', u.username
+		LIMIT $3 OFFSET $4`, groupID, status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []*GroupMember{}
+	for rows.Next() {
+		var member GroupMember
+		var bio, avatarURL pgtype.Text
+		err := rows.Scan(&member.User.ID, &member.User.Username, &bio, &avatarURL,
+			&member.Role, &member.Status, &member.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan group member: %w", err)
+		}
+		member.User.Bio = getPgtypeTextValue(bio)
+		member.User.AvatarURL = getPgtypeTextPtr(avatarURL)
+		members = append(members, &member)
+	}
+	return members, rows.Err()
+}
+
+// GetGroupFeed lists a group's posts, newest first
+func (s *SocialService) GetGroupFeed(ctx context.Context, groupID, viewerID uuid.UUID, limit, offset int) ([]*FeedPost, error) {
+	group, err := s.GetGroup(ctx, groupID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if !group.canRead() {
+		return nil, fmt.Errorf("access denied")
+	}
+
+	rows, err := s.db.Reader().Query(ctx, `
+		SELECT p.id, p.author_id, p.text, p.course_id, p.module_id, p.is_ai_generated, p.edited_at IS NOT NULL, p.slug, p.created_at, p.updated_at,
+		       p.like_count,
+		       (SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id) AS comment_count,
+		       u.username, u.email, u.bio, u.avatar_url,
+		       EXISTS(SELECT 1 FROM likes ul WHERE ul.post_id = p.id AND ul.user_id = $1) AS is_liked
+		FROM posts p
+		JOIN users u ON p.author_id = u.id
+		WHERE p.group_id = $2 AND (NOT u.shadow_banned OR u.id = $1)
+		ORDER BY p.created_at DESC
+		LIMIT $3 OFFSET $4`, viewerID, groupID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group feed: %w", err)
+	}
+	posts, err := scanFeedPosts(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.attachPostExtras(ctx, posts, viewerID); err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+// requireGroupAdmin fails with "access denied" unless userID is an active
+// owner or admin of the group
+func requireGroupAdmin(ctx context.Context, tx pgx.Tx, groupID, userID uuid.UUID) error {
+	membership := &GroupMembership{}
+	err := tx.QueryRow(ctx, `
+		SELECT role, status FROM group_members WHERE group_id = $1 AND user_id = $2`,
+		groupID, userID).Scan(&membership.Role, &membership.Status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("access denied")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check group role: %w", err)
+	}
+	if !membership.isAdmin() {
+		return fmt.Errorf("access denied")
+	}
+	return nil
+}
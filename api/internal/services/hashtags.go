@@ -0,0 +1,56 @@
+package services
+
+import (
+	"regexp"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxHashtagLength is how many characters of a hashtag are kept; longer
+// ones are cut
+const MaxHashtagLength = 64
+
+// hashtagRe matches # then letters in any script with their combining marks,
+// digits and underscores
+var hashtagRe = regexp.MustCompile(`#([\p{L}\p{M}\p{N}_]+)`)
+
+// extractHashtags returns the hashtags in text as written, NFC normalized
+// and cut to MaxHashtagLength. Repeats, in any case, are left out.
+func extractHashtags(text string) []string {
+	var hashtags []string
+	seen := make(map[string]bool)
+	for _, match := range hashtagRe.FindAllStringSubmatch(norm.NFC.String(text), -1) {
+		display := truncateHashtag(match[1])
+		canonical := CanonicalHashtag(display)
+		if seen[canonical] {
+			continue
+		}
+		seen[canonical] = true
+		hashtags = append(hashtags, display)
+	}
+	return hashtags
+}
+
+// CanonicalHashtag is the form a hashtag is stored and looked up under, so
+// #Go, #go and #GO are one tag: NFC normalized, case folded and cut to
+// MaxHashtagLength
+func CanonicalHashtag(tag string) string {
+	// A Caser keeps state, so each call gets its own
+	return truncateHashtag(norm.NFC.String(cases.Fold().String(norm.NFC.String(tag))))
+}
+
+// ValidHashtag reports whether tag, without its #, could have been written
+// in a post
+func ValidHashtag(tag string) bool {
+	match := hashtagRe.FindStringSubmatch("#" + tag)
+	return match != nil && match[1] == tag
+}
+
+func truncateHashtag(tag string) string {
+	runes := []rune(tag)
+	if len(runes) <= MaxHashtagLength {
+		return tag
+	}
+	return string(runes[:MaxHashtagLength])
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"bailanysta/api/internal/pkg/auth"
+	"bailanysta/api/internal/pkg/database"
+)
+
+// ImpersonationService lets support staff act as a user to debug problems
+// only that user sees. Sessions are short, read-only unless asked otherwise,
+// and every request made in one is logged.
+type ImpersonationService struct {
+	db         *database.Pool
+	jwtManager *auth.JWTManager
+	ttl        time.Duration
+}
+
+type ImpersonateRequest struct {
+	Reason string `json:"reason" validate:"required,min=5,max=500"`
+	Write  bool   `json:"write"` // allow changes, not just reads
+}
+
+type ImpersonationToken struct {
+	AccessToken string    `json:"access_token"`
+	SessionID   uuid.UUID `json:"session_id"`
+	UserID      uuid.UUID `json:"user_id"`
+	Username    string    `json:"username"`
+	CanWrite    bool      `json:"can_write"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// ImpersonationInfo describes the session a request is made in, so clients
+// can show a banner
+type ImpersonationInfo struct {
+	SessionID      uuid.UUID `json:"session_id"`
+	ImpersonatorID uuid.UUID `json:"impersonator_id"`
+	CanWrite       bool      `json:"can_write"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+type ImpersonationSession struct {
+	ID                   uuid.UUID              `json:"id"`
+	ImpersonatorID       uuid.UUID              `json:"impersonator_id"`
+	ImpersonatorUsername string                 `json:"impersonator_username"`
+	UserID               uuid.UUID              `json:"user_id"`
+	Username             string                 `json:"username"`
+	Reason               string                 `json:"reason"`
+	CanWrite             bool                   `json:"can_write"`
+	CreatedAt            time.Time              `json:"created_at"`
+	ExpiresAt            time.Time              `json:"expires_at"`
+	Actions              []*ImpersonationAction `json:"actions"`
+}
+
+type ImpersonationAction struct {
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Status    int       `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewImpersonationService(db *database.Pool, jwtManager *auth.JWTManager, ttl time.Duration) *ImpersonationService {
+	return &ImpersonationService{
+		db:         db,
+		jwtManager: jwtManager,
+		ttl:        ttl,
+	}
+}
+
+// ImpersonationFromClaims returns the session an impersonation token belongs
+// to, or nil for ordinary tokens
+func ImpersonationFromClaims(claims *auth.Claims) *ImpersonationInfo {
+	if claims.ImpersonatorID == nil {
+		return nil
+	}
+	sessionID, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return nil
+	}
+	info := &ImpersonationInfo{
+		SessionID:      sessionID,
+		ImpersonatorID: *claims.ImpersonatorID,
+		CanWrite:       claims.HasScope(auth.ScopeWrite),
+	}
+	if claims.ExpiresAt != nil {
+		info.ExpiresAt = claims.ExpiresAt.Time
+	}
+	return info
+}
+
+// Start opens a session in which adminID acts as userID. Admins can't be
+// impersonated, so a session never grants more than the staff member has.
+func (s *ImpersonationService) Start(ctx context.Context, orgID, adminID, userID uuid.UUID, req ImpersonateRequest) (*ImpersonationToken, error) {
+	if userID == adminID {
+		return nil, fmt.Errorf("cannot impersonate yourself")
+	}
+
+	var username string
+	var role Role
+	err := s.db.QueryRow(ctx, `
+		SELECT username, role FROM users WHERE id = $1 AND org_id = $2`, userID, orgID).Scan(&username, &role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("user not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if role == RoleAdmin || role == RoleSuperadmin {
+		return nil, fmt.Errorf("cannot impersonate an admin")
+	}
+
+	token := &ImpersonationToken{
+		UserID:   userID,
+		Username: username,
+		CanWrite: req.Write,
+	}
+	err = s.db.QueryRow(ctx, `
+		INSERT INTO impersonation_sessions (impersonator_id, user_id, reason, can_write, expires_at)
+		VALUES ($1, $2, $3, $4, now() + make_interval(secs => $5::float8))
+		RETURNING id, expires_at`,
+		adminID, userID, req.Reason, req.Write, s.ttl.Seconds(),
+	).Scan(&token.SessionID, &token.ExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start impersonation: %w", err)
+	}
+
+	scopes := []string{auth.ScopeRead}
+	if req.Write {
+		scopes = auth.SessionScopes
+	}
+	token.AccessToken, err = s.jwtManager.GenerateImpersonationToken(userID, orgID, string(role), scopes, adminID, token.SessionID, token.ExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	return token, nil
+}
+
+// LogAction records a request made during a session
+func (s *ImpersonationService) LogAction(ctx context.Context, sessionID uuid.UUID, method, path string, status int) error {
+	_, err := s.db.Exec(ctx, `
+		INSERT INTO impersonation_actions (session_id, method, path, status)
+		VALUES ($1, $2, $3, $4)`, sessionID, method, path, status)
+	if err != nil {
+		return fmt.Errorf("failed to log impersonated action: %w", err)
+	}
+	return nil
+}
+
+// GetSessions returns the newest sessions of orgID with their actions,
+// optionally only those impersonating userID
+func (s *ImpersonationService) GetSessions(ctx context.Context, orgID uuid.UUID, userID *uuid.UUID, limit int) ([]*ImpersonationSession, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT s.id, s.impersonator_id, a.username, s.user_id, u.username, s.reason, s.can_write, s.created_at, s.expires_at
+		FROM impersonation_sessions s
+		JOIN users u ON u.id = s.user_id
+		JOIN users a ON a.id = s.impersonator_id
+		WHERE u.org_id = $1 AND ($2::uuid IS NULL OR s.user_id = $2)
+		ORDER BY s.created_at DESC
+		LIMIT $3`, orgID, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get impersonation sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []*ImpersonationSession{}
+	byID := make(map[uuid.UUID]*ImpersonationSession)
+	for rows.Next() {
+		session := &ImpersonationSession{Actions: []*ImpersonationAction{}}
+		err := rows.Scan(&session.ID, &session.ImpersonatorID, &session.ImpersonatorUsername, &session.UserID, &session.Username,
+			&session.Reason, &session.CanWrite, &session.CreatedAt, &session.ExpiresAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan impersonation session: %w", err)
+		}
+		sessions = append(sessions, session)
+		byID[session.ID] = session
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get impersonation sessions: %w", err)
+	}
+	if len(sessions) == 0 {
+		return sessions, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(sessions))
+	for _, session := range sessions {
+		ids = append(ids, session.ID)
+	}
+	actionRows, err := s.db.Query(ctx, `
+		SELECT session_id, method, path, status, created_at
+		FROM impersonation_actions
+		WHERE session_id = ANY($1)
+		ORDER BY created_at, id`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get impersonated actions: %w", err)
+	}
+	defer actionRows.Close()
+
+	for actionRows.Next() {
+		var sessionID uuid.UUID
+		var action ImpersonationAction
+		if err := actionRows.Scan(&sessionID, &action.Method, &action.Path, &action.Status, &action.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan impersonated action: %w", err)
+		}
+		byID[sessionID].Actions = append(byID[sessionID].Actions, &action)
+	}
+	return sessions, actionRows.Err()
+}
@@ -0,0 +1,287 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+
+	"bailanysta/api/internal/pkg/database"
+	"bailanysta/api/internal/pkg/geoip"
+	"bailanysta/api/internal/pkg/sentry"
+)
+
+// ipBlocklistRefreshInterval is how often each replica reloads the blocklist,
+// so blocks added elsewhere take effect within it
+const ipBlocklistRefreshInterval = 30 * time.Second
+
+// IP block sources
+const (
+	IPBlockSourceAdmin = "admin"
+	IPBlockSourceAbuse = "abuse"
+)
+
+type IPBlock struct {
+	ID        uuid.UUID  `json:"id"`
+	CIDR      *string    `json:"cidr,omitempty"`
+	ASN       *int64     `json:"asn,omitempty"`
+	Reason    string     `json:"reason"`
+	Source    string     `json:"source"`
+	CreatedBy *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// CreateIPBlockRequest blocks either an IP address or CIDR range, or an
+// autonomous system; TTLSeconds 0 blocks until removed
+type CreateIPBlockRequest struct {
+	CIDR       string `json:"cidr,omitempty"`
+	ASN        int64  `json:"asn,omitempty" validate:"min=0,max=4294967295"`
+	Reason     string `json:"reason" validate:"max=500"`
+	TTLSeconds int64  `json:"ttl_seconds,omitempty" validate:"min=0"`
+}
+
+// ipBlockSet is an in-memory snapshot of the blocks in force
+type ipBlockSet struct {
+	prefixes []blockedPrefix
+	asns     map[uint]*time.Time // expiry, nil for none
+}
+
+type blockedPrefix struct {
+	prefix    netip.Prefix
+	expiresAt *time.Time
+}
+
+// blocked reports whether addr, in autonomous system asn (0 if unknown), is
+// blocked at now
+func (b *ipBlockSet) blocked(addr netip.Addr, asn uint, now time.Time) bool {
+	addr = addr.Unmap()
+	for _, p := range b.prefixes {
+		if p.prefix.Contains(addr) && (p.expiresAt == nil || now.Before(*p.expiresAt)) {
+			return true
+		}
+	}
+	if asn == 0 {
+		return false
+	}
+	expiresAt, ok := b.asns[asn]
+	return ok && (expiresAt == nil || now.Before(*expiresAt))
+}
+
+// IPBlocklistService refuses requests from blocked IP ranges and autonomous
+// systems. Blocks live in the database; each replica matches against a
+// snapshot it reloads every ipBlocklistRefreshInterval.
+type IPBlocklistService struct {
+	db    *database.Pool
+	asnDB *geoip.DB // nil without a GeoLite2 ASN database
+	set   atomic.Pointer[ipBlockSet]
+}
+
+func NewIPBlocklistService(db *database.Pool, asnDB *geoip.DB) *IPBlocklistService {
+	s := &IPBlocklistService{
+		db:    db,
+		asnDB: asnDB,
+	}
+	s.set.Store(&ipBlockSet{})
+	return s
+}
+
+// Blocked reports whether requests from ip are refused
+func (s *IPBlocklistService) Blocked(ip string) bool {
+	if s == nil {
+		return false
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+
+	set := s.set.Load()
+	var asn uint
+	if len(set.asns) > 0 && s.asnDB != nil {
+		asn, err = s.asnDB.ASN(net.IP(addr.AsSlice()))
+		if err != nil {
+			fmt.Printf("Failed to look up ASN: %v\n", err)
+		}
+	}
+	return set.blocked(addr, asn, time.Now())
+}
+
+// Refresh reloads the blocks in force from the database
+func (s *IPBlocklistService) Refresh(ctx context.Context) error {
+	blocks, err := s.query(ctx, `WHERE expires_at IS NULL OR expires_at > now()`)
+	if err != nil {
+		return err
+	}
+
+	set := &ipBlockSet{asns: make(map[uint]*time.Time)}
+	for _, block := range blocks {
+		if block.ASN != nil {
+			set.asns[uint(*block.ASN)] = block.ExpiresAt
+			continue
+		}
+		prefix, err := netip.ParsePrefix(*block.CIDR)
+		if err != nil {
+			fmt.Printf("Skipping invalid IP block %s: %v\n", block.ID, err)
+			continue
+		}
+		set.prefixes = append(set.prefixes, blockedPrefix{prefix: prefix, expiresAt: block.ExpiresAt})
+	}
+	s.set.Store(set)
+	return nil
+}
+
+// List returns the blocks in force, newest first
+func (s *IPBlocklistService) List(ctx context.Context) ([]*IPBlock, error) {
+	return s.query(ctx, `WHERE expires_at IS NULL OR expires_at > now() ORDER BY created_at DESC`)
+}
+
+// Create adds a block from an admin
+func (s *IPBlocklistService) Create(ctx context.Context, adminID uuid.UUID, req CreateIPBlockRequest) (*IPBlock, error) {
+	if (req.CIDR == "") == (req.ASN == 0) {
+		return nil, fmt.Errorf("cidr or asn is required")
+	}
+	var cidr *string
+	var asn *int64
+	if req.CIDR != "" {
+		prefix, err := parseBlockedRange(req.CIDR)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cidr")
+		}
+		normalized := prefix.String()
+		cidr = &normalized
+	} else {
+		if s.asnDB == nil {
+			return nil, fmt.Errorf("asn lookups are not configured")
+		}
+		asn = &req.ASN
+	}
+
+	var ttl time.Duration
+	if req.TTLSeconds > 0 {
+		ttl = time.Duration(req.TTLSeconds) * time.Second
+	}
+	return s.insert(ctx, cidr, asn, req.Reason, IPBlockSourceAdmin, &adminID, ttl)
+}
+
+// BlockTemporarily blocks a single IP for ttl; abuse heuristics use it
+func (s *IPBlocklistService) BlockTemporarily(ctx context.Context, ip, reason string, ttl time.Duration) error {
+	prefix, err := parseBlockedRange(ip)
+	if err != nil {
+		return fmt.Errorf("invalid ip: %w", err)
+	}
+	cidr := prefix.String()
+	_, err = s.insert(ctx, &cidr, nil, reason, IPBlockSourceAbuse, nil, ttl)
+	return err
+}
+
+// Delete removes a block
+func (s *IPBlocklistService) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := s.db.Exec(ctx, `DELETE FROM ip_blocks WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete ip block: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("ip block not found")
+	}
+	if err := s.Refresh(ctx); err != nil {
+		fmt.Printf("Failed to refresh IP blocklist: %v\n", err)
+	}
+	return nil
+}
+
+// insert stores a block (ttl 0 for none) and applies it on this replica
+// straight away
+func (s *IPBlocklistService) insert(ctx context.Context, cidr *string, asn *int64, reason, source string, createdBy *uuid.UUID, ttl time.Duration) (*IPBlock, error) {
+	var expiresAt *time.Time
+	if ttl > 0 {
+		t := time.Now().Add(ttl)
+		expiresAt = &t
+	}
+
+	block := &IPBlock{CIDR: cidr, ASN: asn, Reason: reason, Source: source, CreatedBy: createdBy, ExpiresAt: expiresAt}
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO ip_blocks (cidr, asn, reason, source, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`,
+		cidr, asn, reason, source, createdBy, expiresAt).Scan(&block.ID, &block.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ip block: %w", err)
+	}
+
+	if err := s.Refresh(ctx); err != nil {
+		fmt.Printf("Failed to refresh IP blocklist: %v\n", err)
+	}
+	return block, nil
+}
+
+func (s *IPBlocklistService) query(ctx context.Context, where string) ([]*IPBlock, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT id, cidr, asn, reason, source, created_by, created_at, expires_at
+		FROM ip_blocks `+where)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ip blocks: %w", err)
+	}
+	defer rows.Close()
+
+	blocks := []*IPBlock{}
+	for rows.Next() {
+		var block IPBlock
+		err := rows.Scan(&block.ID, &block.CIDR, &block.ASN, &block.Reason, &block.Source,
+			&block.CreatedBy, &block.CreatedAt, &block.ExpiresAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan ip block: %w", err)
+		}
+		blocks = append(blocks, &block)
+	}
+	return blocks, rows.Err()
+}
+
+// Run reloads the blocklist and deletes expired blocks until ctx is
+// cancelled
+func (s *IPBlocklistService) Run(ctx context.Context) {
+	ticker := time.NewTicker(ipBlocklistRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Refresh(ctx); err != nil {
+			fmt.Printf("Failed to refresh IP blocklist: %v\n", err)
+			sentry.CaptureError(err, map[string]string{"job": "ip-blocklist"})
+		}
+		if _, err := s.db.Exec(ctx, `DELETE FROM ip_blocks WHERE expires_at <= now()`); err != nil {
+			fmt.Printf("Failed to delete expired IP blocks: %v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// parseBlockedRange accepts an address, blocked alone, or a CIDR range
+func parseBlockedRange(value string) (netip.Prefix, error) {
+	value = strings.TrimSpace(value)
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBlockedRange(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"203.0.113.7", "203.0.113.7/32"},
+		{" 203.0.113.7 ", "203.0.113.7/32"},
+		{"203.0.113.7/24", "203.0.113.0/24"},
+		{"::ffff:203.0.113.7", "203.0.113.7/32"},
+		{"2001:db8::1", "2001:db8::1/128"},
+		{"2001:db8::/32", "2001:db8::/32"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			prefix, err := parseBlockedRange(tt.value)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, prefix.String())
+		})
+	}
+
+	for _, value := range []string{"", "example.com", "203.0.113.0/33"} {
+		_, err := parseBlockedRange(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestIPBlockSetBlocked(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Minute)
+	set := &ipBlockSet{
+		prefixes: []blockedPrefix{
+			{prefix: netip.MustParsePrefix("203.0.113.0/24")},
+			{prefix: netip.MustParsePrefix("198.51.100.7/32"), expiresAt: &past},
+			{prefix: netip.MustParsePrefix("198.51.100.8/32"), expiresAt: &future},
+		},
+		asns: map[uint]*time.Time{64500: nil, 64501: &past},
+	}
+
+	tests := []struct {
+		name string
+		addr string
+		asn  uint
+		want bool
+	}{
+		{"in range", "203.0.113.200", 0, true},
+		{"mapped address in range", "::ffff:203.0.113.200", 0, true},
+		{"outside range", "203.0.114.1", 0, false},
+		{"expired block", "198.51.100.7", 0, false},
+		{"temporary block", "198.51.100.8", 0, true},
+		{"blocked asn", "192.0.2.1", 64500, true},
+		{"expired asn block", "192.0.2.1", 64501, false},
+		{"unknown asn", "192.0.2.1", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, set.blocked(netip.MustParseAddr(tt.addr), tt.asn, now))
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"bailanysta/api/internal/pkg/i18n"
+)
+
+// kazakhLetters are the letters Kazakh adds to Cyrillic and to the 2021
+// Latin alphabet; Russian and English text doesn't use them
+const kazakhLetters = "әғқңөұүһіӘҒҚҢӨҰҮҺІáǵńóúışÁǴŃÓÚİŞ"
+
+// kazakhShare is the share of Kazakh-specific letters that marks Kazakh;
+// ordinary Kazakh text sits well above it
+const kazakhShare = 0.02
+
+var languageNoiseRegex = regexp.MustCompile(`https?://\S+|[@#][\p{L}\p{N}_]+`)
+
+// detectPostLanguage returns the dominant language of text as en, kk or ru,
+// or "" when it has no letters to go by. URLs, mentions and hashtags are
+// ignored.
+func detectPostLanguage(text string) string {
+	text = languageNoiseRegex.ReplaceAllString(text, " ")
+
+	var latin, cyrillic, kazakh int
+	for _, r := range text {
+		switch {
+		case strings.ContainsRune(kazakhLetters, r):
+			kazakh++
+			if unicode.Is(unicode.Cyrillic, r) {
+				cyrillic++
+			} else {
+				latin++
+			}
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		}
+	}
+
+	letters := latin + cyrillic
+	switch {
+	case letters == 0:
+		return ""
+	case float64(kazakh)/float64(letters) >= kazakhShare:
+		return i18n.Kazakh
+	case cyrillic > latin:
+		return i18n.Russian
+	default:
+		return i18n.English
+	}
+}
+
+// normalizeFeedLanguages validates and de-duplicates a feed language list
+func normalizeFeedLanguages(languages []string) ([]string, error) {
+	normalized := make([]string, 0, len(languages))
+	seen := make(map[string]bool, len(languages))
+	for _, lang := range languages {
+		lang = strings.ToLower(strings.TrimSpace(lang))
+		if !i18n.Supported(lang) {
+			return nil, fmt.Errorf("invalid feed language")
+		}
+		if !seen[lang] {
+			seen[lang] = true
+			normalized = append(normalized, lang)
+		}
+	}
+	return normalized, nil
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectPostLanguage(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"english", "Does anyone have notes from today's lecture?", "en"},
+		{"russian", "Кто-нибудь записал сегодняшнюю лекцию?", "ru"},
+		{"kazakh cyrillic", "Бүгінгі дәрісті біреу жазып алды ма?", "kk"},
+		{"kazakh latin", "Búgingi dárisi bireý jazyp aldy ma?", "kk"},
+		{"russian with an english term", "Кто объяснит, как работает garbage collector в Go?", "ru"},
+		{"urls and mentions are ignored", "@alice https://example.com/lecture смотри", "ru"},
+		{"no letters", "123 :) 🎉", ""},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, detectPostLanguage(tt.text))
+		})
+	}
+}
+
+func TestNormalizeFeedLanguages(t *testing.T) {
+	languages, err := normalizeFeedLanguages([]string{"RU", "kk", "ru "})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ru", "kk"}, languages)
+
+	languages, err = normalizeFeedLanguages(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, languages)
+
+	_, err = normalizeFeedLanguages([]string{"de"})
+	assert.EqualError(t, err, "invalid feed language")
+}
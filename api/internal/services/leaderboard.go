@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"bailanysta/api/internal/pkg/database"
+	"bailanysta/api/internal/pkg/sentry"
+)
+
+// Leaderboard windows
+const (
+	LeaderboardDay  = "day"
+	LeaderboardWeek = "week"
+	LeaderboardAll  = "all"
+)
+
+// LeaderboardService ranks users by engagement points from the
+// leaderboard_points materialized view, which Run refreshes periodically
+type LeaderboardService struct {
+	db       *database.Pool
+	interval time.Duration
+}
+
+type LeaderboardEntry struct {
+	Rank   int          `json:"rank"`
+	User   UserResponse `json:"user"`
+	Points int          `json:"points"`
+}
+
+// LeaderboardScope is the whole organization, or one course with CourseID set
+type LeaderboardScope struct {
+	CourseID *uuid.UUID
+}
+
+func NewLeaderboardService(db *database.Pool, interval time.Duration) *LeaderboardService {
+	return &LeaderboardService{db: db, interval: interval}
+}
+
+// ParseLeaderboardScope parses "org" (or "") and "course:{id}"
+func ParseLeaderboardScope(scope string) (LeaderboardScope, error) {
+	if scope == "" || scope == "org" {
+		return LeaderboardScope{}, nil
+	}
+	if raw, ok := strings.CutPrefix(scope, "course:"); ok {
+		courseID, err := uuid.Parse(raw)
+		if err != nil {
+			return LeaderboardScope{}, fmt.Errorf("invalid leaderboard scope")
+		}
+		return LeaderboardScope{CourseID: &courseID}, nil
+	}
+	return LeaderboardScope{}, fmt.Errorf("invalid leaderboard scope")
+}
+
+// leaderboardSince returns the first UTC day counted by period, or nil for
+// all time. A week is today plus the six days before.
+func leaderboardSince(period string, now time.Time) (*time.Time, error) {
+	today := now.UTC().Truncate(24 * time.Hour)
+	switch period {
+	case LeaderboardDay:
+		return &today, nil
+	case LeaderboardWeek:
+		since := today.AddDate(0, 0, -6)
+		return &since, nil
+	case LeaderboardAll:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("invalid leaderboard period")
+}
+
+// GetLeaderboard returns the top users of orgID by points over period.
+// Shadow-banned users are left out; tied users share a rank.
+func (s *LeaderboardService) GetLeaderboard(ctx context.Context, orgID uuid.UUID, scope LeaderboardScope, period string, limit int) ([]*LeaderboardEntry, error) {
+	since, err := leaderboardSince(period, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if scope.CourseID != nil {
+		var exists bool
+		err := s.db.Reader().QueryRow(ctx, `
+			SELECT EXISTS(SELECT 1 FROM courses WHERE id = $1 AND org_id = $2)`,
+			*scope.CourseID, orgID).Scan(&exists)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check course: %w", err)
+		}
+		if !exists {
+			return nil, fmt.Errorf("course not found")
+		}
+	}
+
+	rows, err := s.db.Reader().Query(ctx, `
+		SELECT RANK() OVER (ORDER BY SUM(lp.points) DESC),
+		       u.id, u.username, u.bio, u.avatar_url, SUM(lp.points)::int
+		FROM leaderboard_points lp
+		JOIN users u ON u.id = lp.user_id
+		WHERE lp.org_id = $1
+		  AND ($2::uuid IS NULL OR lp.course_id = $2)
+		  AND ($3::date IS NULL OR lp.day >= $3)
+		  AND NOT u.shadow_banned
+		GROUP BY u.id
+		ORDER BY SUM(lp.points) DESC, u.username
+		LIMIT $4`, orgID, scope.CourseID, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get leaderboard: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []*LeaderboardEntry{}
+	for rows.Next() {
+		var entry LeaderboardEntry
+		var bio, avatarURL pgtype.Text
+		err := rows.Scan(&entry.Rank, &entry.User.ID, &entry.User.Username, &bio, &avatarURL, &entry.Points)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan leaderboard entry: %w", err)
+		}
+		entry.User.Bio = getPgtypeTextValue(bio)
+		entry.User.AvatarURL = getPgtypeTextPtr(avatarURL)
+		entries = append(entries, &entry)
+	}
+	return entries, rows.Err()
+}
+
+// Refresh recomputes leaderboard_points without blocking readers
+func (s *LeaderboardService) Refresh(ctx context.Context) error {
+	_, err := s.db.Exec(ctx, `REFRESH MATERIALIZED VIEW CONCURRENTLY leaderboard_points`)
+	if err != nil {
+		return fmt.Errorf("failed to refresh leaderboard: %w", err)
+	}
+	return nil
+}
+
+// Run refreshes the leaderboard until ctx is cancelled
+func (s *LeaderboardService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Refresh(ctx); err != nil {
+			fmt.Printf("Failed to refresh leaderboard: %v\n", err)
+			sentry.CaptureError(err, map[string]string{"job": "leaderboard-refresh"})
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
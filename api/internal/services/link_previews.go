@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"bailanysta/api/internal/pkg/linkpreview"
+	"bailanysta/api/internal/pkg/sentry"
+)
+
+const (
+	maxLinksPerPost       = 3
+	maxLinkLength         = 2048
+	linkPreviewBatchSize  = 20
+	linkPreviewRetryAfter = time.Hour
+)
+
+var urlRe = regexp.MustCompile(`https?://[^\s<>"'` + "`" + `]+`)
+
+type LinkPreview struct {
+	URL         string `json:"url"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	ImageURL    string `json:"image_url,omitempty"`
+	SiteName    string `json:"site_name,omitempty"`
+}
+
+type LinkPreviewService struct {
+	db       *pgxpool.Pool
+	fetcher  *linkpreview.Fetcher
+	ttl      time.Duration
+	interval time.Duration
+}
+
+func NewLinkPreviewService(db *pgxpool.Pool, fetcher *linkpreview.Fetcher, ttl, interval time.Duration) *LinkPreviewService {
+	return &LinkPreviewService{
+		db:       db,
+		fetcher:  fetcher,
+		ttl:      ttl,
+		interval: interval,
+	}
+}
+
+// Run fetches new and expired previews until ctx is cancelled
+func (s *LinkPreviewService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			fetched, err := s.FetchDuePreviews(ctx)
+			if err != nil {
+				fmt.Printf("Failed to fetch link previews: %v\n", err)
+				sentry.CaptureError(err, map[string]string{"job": "link-previews"})
+				break
+			}
+			if fetched < linkPreviewBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// FetchDuePreviews fetches one batch of previews that are pending or expired
+// and still linked from a post. Failed fetches are retried after an hour.
+func (s *LinkPreviewService) FetchDuePreviews(ctx context.Context) (int, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT lp.url
+		FROM link_previews lp
+		WHERE (lp.status = 'pending' OR lp.expires_at <= now())
+		  AND EXISTS (SELECT 1 FROM post_links pl WHERE pl.url = lp.url)
+		ORDER BY lp.created_at
+		LIMIT $1`, linkPreviewBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get due link previews: %w", err)
+	}
+	urls, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return 0, fmt.Errorf("failed to read due link previews: %w", err)
+	}
+
+	for _, rawURL := range urls {
+		if ctx.Err() != nil {
+			break
+		}
+
+		preview, err := s.fetcher.Fetch(ctx, rawURL)
+		if err != nil {
+			_, err = s.db.Exec(ctx, `
+				UPDATE link_previews
+				SET status = 'failed', fetched_at = now(), expires_at = now() + make_interval(secs => $2::float8)
+				WHERE url = $1`, rawURL, min(s.ttl, linkPreviewRetryAfter).Seconds())
+		} else {
+			_, err = s.db.Exec(ctx, `
+				UPDATE link_previews
+				SET status = 'ok', title = $2, description = $3, image_url = $4, site_name = $5,
+				    fetched_at = now(), expires_at = now() + make_interval(secs => $6::float8)
+				WHERE url = $1`,
+				rawURL, preview.Title, preview.Description, preview.ImageURL, preview.SiteName, s.ttl.Seconds())
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to save link preview: %w", err)
+		}
+	}
+
+	return len(urls), nil
+}
+
+// savePostLinks replaces the links recorded for a post and queues previews
+// for URLs not seen before
+func savePostLinks(ctx context.Context, tx pgx.Tx, postID uuid.UUID, text string) error {
+	_, err := tx.Exec(ctx, `DELETE FROM post_links WHERE post_id = $1`, postID)
+	if err != nil {
+		return fmt.Errorf("failed to clear post links: %w", err)
+	}
+
+	for i, link := range extractURLs(text) {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO link_previews (url) VALUES ($1)
+			ON CONFLICT (url) DO NOTHING`, link)
+		if err != nil {
+			return fmt.Errorf("failed to queue link preview: %w", err)
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO post_links (post_id, url, position) VALUES ($1, $2, $3)`, postID, link, i)
+		if err != nil {
+			return fmt.Errorf("failed to save post link: %w", err)
+		}
+	}
+	return nil
+}
+
+const linkPreviewsQuery = `
+	SELECT pl.post_id, lp.url, lp.title, lp.description, lp.image_url, lp.site_name
+	FROM post_links pl
+	JOIN link_previews lp ON lp.url = pl.url
+	WHERE pl.post_id = ANY($1) AND lp.status = 'ok'
+	ORDER BY pl.post_id, pl.position`
+
+// getLinkPreviews loads fetched previews for postIDs, keyed by post
+func getLinkPreviews(ctx context.Context, db *pgxpool.Pool, postIDs []uuid.UUID) (map[uuid.UUID][]*LinkPreview, error) {
+	if len(postIDs) == 0 {
+		return map[uuid.UUID][]*LinkPreview{}, nil
+	}
+
+	rows, err := db.Query(ctx, linkPreviewsQuery, postIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get link previews: %w", err)
+	}
+	return scanLinkPreviews(rows)
+}
+
+func scanLinkPreviews(rows pgx.Rows) (map[uuid.UUID][]*LinkPreview, error) {
+	defer rows.Close()
+
+	previews := make(map[uuid.UUID][]*LinkPreview)
+	for rows.Next() {
+		var postID uuid.UUID
+		var preview LinkPreview
+		var title, description, imageURL, siteName pgtype.Text
+		if err := rows.Scan(&postID, &preview.URL, &title, &description, &imageURL, &siteName); err != nil {
+			return nil, fmt.Errorf("failed to scan link preview: %w", err)
+		}
+
+		preview.Title = getPgtypeTextValue(title)
+		preview.Description = getPgtypeTextValue(description)
+		preview.ImageURL = getPgtypeTextValue(imageURL)
+		preview.SiteName = getPgtypeTextValue(siteName)
+		previews[postID] = append(previews[postID], &preview)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read link previews: %w", err)
+	}
+	return previews, nil
+}
+
+// extractURLs returns the first few distinct http(s) URLs in text, without
+// trailing punctuation
+func extractURLs(text string) []string {
+	var urls []string
+	seen := make(map[string]bool)
+	for _, match := range urlRe.FindAllString(text, -1) {
+		link := strings.TrimRight(match, ".,;:!?")
+		// Drop an unbalanced closing parenthesis, e.g. "(see https://x.kz/a)"
+		if strings.HasSuffix(link, ")") && strings.Count(link, "(") < strings.Count(link, ")") {
+			link = strings.TrimRight(strings.TrimSuffix(link, ")"), ".,;:!?")
+		}
+		if len(link) > maxLinkLength || seen[link] {
+			continue
+		}
+		if u, err := url.Parse(link); err != nil || u.Hostname() == "" {
+			continue
+		}
+
+		seen[link] = true
+		urls = append(urls, link)
+		if len(urls) == maxLinksPerPost {
+			break
+		}
+	}
+	return urls
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"bailanysta/api/internal/pkg/metrics"
+	"bailanysta/api/internal/pkg/sentry"
+)
+
+const (
+	NotificationRetentionDelete  = "delete"
+	NotificationRetentionArchive = "archive"
+
+	notificationCleanupBatchSize = 1000
+)
+
+var notificationsPurged = metrics.NewCounterVec("notifications_purged_total",
+	"Read notifications removed by the retention job, by action.", "action")
+
+// NotificationCleanupService enforces the notification retention policy:
+// read notifications older than maxAge are deleted or moved to
+// notifications_archive. Unread notifications are kept regardless of age.
+type NotificationCleanupService struct {
+	db       *pgxpool.Pool
+	maxAge   time.Duration
+	action   string
+	interval time.Duration
+}
+
+func NewNotificationCleanupService(db *pgxpool.Pool, maxAge time.Duration, action string, interval time.Duration) *NotificationCleanupService {
+	return &NotificationCleanupService{
+		db:       db,
+		maxAge:   maxAge,
+		action:   action,
+		interval: interval,
+	}
+}
+
+// Run purges expired notifications until ctx is cancelled
+func (s *NotificationCleanupService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			purged, err := s.PurgeExpired(ctx)
+			if err != nil {
+				fmt.Printf("Failed to purge notifications: %v\n", err)
+				sentry.CaptureError(err, map[string]string{"job": "notification-cleanup"})
+				break
+			}
+			if purged < notificationCleanupBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeExpired removes one batch of expired notifications and returns how
+// many it removed. Batches keep each statement's locks and WAL short.
+func (s *NotificationCleanupService) PurgeExpired(ctx context.Context) (int, error) {
+	query := `
+		DELETE FROM notifications
+		WHERE id IN (
+		    SELECT id FROM notifications
+		    WHERE read_at IS NOT NULL
+		      AND created_at < now() - make_interval(secs => $1::float8)
+		    LIMIT $2
+		    FOR UPDATE SKIP LOCKED
+		)`
+	if s.action == NotificationRetentionArchive {
+		query = `
+			WITH moved AS (
+			    DELETE FROM notifications
+			    WHERE id IN (
+			        SELECT id FROM notifications
+			        WHERE read_at IS NOT NULL
+			          AND created_at < now() - make_interval(secs => $1::float8)
+			        LIMIT $2
+			        FOR UPDATE SKIP LOCKED
+			    )
+			    RETURNING id, user_id, type, entity_id, payload_json, read_at, created_at
+			)
+			INSERT INTO notifications_archive (id, user_id, type, entity_id, payload_json, read_at, created_at)
+			SELECT id, user_id, type, entity_id, payload_json, read_at, created_at FROM moved`
+	}
+
+	result, err := s.db.Exec(ctx, query, s.maxAge.Seconds(), notificationCleanupBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to %s notifications: %w", s.action, err)
+	}
+
+	purged := int(result.RowsAffected())
+	notificationsPurged.Add(float64(purged), s.action)
+	return purged, nil
+}
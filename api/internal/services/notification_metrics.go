@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"bailanysta/api/internal/pkg/metrics"
+	"bailanysta/api/internal/pkg/sentry"
+)
+
+// How often the unread backlog gauge is recomputed
+const notificationBacklogInterval = time.Minute
+
+var (
+	notificationsCreated = metrics.NewCounterVec("notifications_created_total",
+		"Notifications created, by type.", "type")
+	notificationsRead = metrics.NewCounterVec("notifications_read_total",
+		"Notifications marked read, by type.", "type")
+	notificationsDeleted = metrics.NewCounterVec("notifications_deleted_total",
+		"Notifications deleted by their recipient, by type; see notifications_purged_total for retention.", "type")
+	notificationFanout = metrics.NewHistogramVec("notification_fanout_recipients",
+		"Recipients of events that notify many users at once, by type.",
+		[]float64{1, 5, 10, 50, 100, 500, 1000, 5000}, "type")
+	notificationBacklog = metrics.NewGaugeVec("notifications_unread",
+		"Unread notifications by age: under an hour, a day, a week, or older.", "age")
+)
+
+// notificationBacklogAges label the backlog gauge, youngest first
+var notificationBacklogAges = []string{"1h", "1d", "7d", "older"}
+
+// RunBacklogMetrics keeps the unread backlog gauge current until ctx is
+// cancelled
+func (s *NotificationService) RunBacklogMetrics(ctx context.Context) {
+	ticker := time.NewTicker(notificationBacklogInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.updateBacklogMetrics(ctx); err != nil && ctx.Err() == nil {
+			fmt.Printf("Failed to update notification backlog metrics: %v\n", err)
+			sentry.CaptureError(err, map[string]string{"job": "notification-metrics"})
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *NotificationService) updateBacklogMetrics(ctx context.Context) error {
+	counts := make([]int64, len(notificationBacklogAges))
+	err := s.db.Reader().QueryRow(ctx, `
+		SELECT
+		  COUNT(*) FILTER (WHERE created_at > now() - interval '1 hour'),
+		  COUNT(*) FILTER (WHERE created_at <= now() - interval '1 hour' AND created_at > now() - interval '1 day'),
+		  COUNT(*) FILTER (WHERE created_at <= now() - interval '1 day' AND created_at > now() - interval '7 days'),
+		  COUNT(*) FILTER (WHERE created_at <= now() - interval '7 days')
+		FROM notifications
+		WHERE read_at IS NULL`).Scan(&counts[0], &counts[1], &counts[2], &counts[3])
+	if err != nil {
+		return fmt.Errorf("failed to count unread notifications: %w", err)
+	}
+
+	for i, age := range notificationBacklogAges {
+		notificationBacklog.Set(float64(counts[i]), age)
+	}
+	return nil
+}
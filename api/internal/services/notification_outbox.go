@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"bailanysta/api/internal/pkg/sentry"
+)
+
+const (
+	outboxPollInterval = time.Second
+	outboxBatchSize    = 100
+	outboxMaxAttempts  = 10
+	outboxRetryBase    = 5 * time.Second
+)
+
+// OutboxEvent is a notification trigger recorded in the same transaction as
+// the write that caused it. Which IDs are set depends on Type.
+type OutboxEvent struct {
+	Type      NotificationType `json:"type"`
+	ActorID   uuid.UUID        `json:"actor_id"`
+	TargetID  *uuid.UUID       `json:"target_id,omitempty"`
+	PostID    *uuid.UUID       `json:"post_id,omitempty"`
+	CommentID *uuid.UUID       `json:"comment_id,omitempty"`
+	GroupID   *uuid.UUID       `json:"group_id,omitempty"`
+	EventID   *uuid.UUID       `json:"event_id,omitempty"`
+	Text      string           `json:"text,omitempty"`
+	Amount    int              `json:"amount,omitempty"` // bounty of an accepted answer
+}
+
+// enqueueNotification writes event to the outbox within tx, so the
+// notification is sent if and only if tx commits
+func enqueueNotification(ctx context.Context, tx pgx.Tx, event OutboxEvent) error {
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox event: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `INSERT INTO notification_outbox (event) VALUES ($1)`, eventJSON)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue notification: %w", err)
+	}
+	return nil
+}
+
+// RunOutboxDispatcher turns outbox events into notifications until ctx is
+// cancelled. Delivery is at least once: an event is deleted only after it
+// has been handled, so a crash in between repeats it.
+func (s *NotificationService) RunOutboxDispatcher(ctx context.Context) {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Keep going while full batches come back so a backlog clears quickly
+			for ctx.Err() == nil {
+				n, err := s.dispatchOutbox(ctx)
+				if err != nil {
+					fmt.Printf("Failed to dispatch notification outbox: %v\n", err)
+					sentry.CaptureError(err, map[string]string{"job": "notification-outbox"})
+					break
+				}
+				if n < outboxBatchSize {
+					break
+				}
+			}
+		}
+	}
+}
+
+// dispatchOutbox handles one batch of due events and returns how many it
+// claimed. Rows are locked with SKIP LOCKED so several API instances can run
+// dispatchers side by side.
+func (s *NotificationService) dispatchOutbox(ctx context.Context) (int, error) {
+	claimed := 0
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT id, event, attempts FROM notification_outbox
+			WHERE available_at <= now()
+			ORDER BY created_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED`, outboxBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to claim outbox events: %w", err)
+		}
+
+		type outboxRow struct {
+			id       uuid.UUID
+			event    []byte
+			attempts int
+		}
+		var batch []outboxRow
+		for rows.Next() {
+			var row outboxRow
+			if err := rows.Scan(&row.id, &row.event, &row.attempts); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan outbox event: %w", err)
+			}
+			batch = append(batch, row)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to claim outbox events: %w", err)
+		}
+		claimed = len(batch)
+
+		for _, row := range batch {
+			var event OutboxEvent
+			err := json.Unmarshal(row.event, &event)
+			if err == nil {
+				err = s.handleOutboxEvent(ctx, event)
+			}
+
+			if err == nil || row.attempts+1 >= outboxMaxAttempts {
+				if err != nil {
+					fmt.Printf("Dropping notification outbox event %s after %d attempts: %v\n", row.id, row.attempts+1, err)
+					sentry.CaptureError(err, map[string]string{"job": "notification-outbox"})
+				}
+				_, err = tx.Exec(ctx, `DELETE FROM notification_outbox WHERE id = $1`, row.id)
+			} else {
+				_, err = tx.Exec(ctx, `
+					UPDATE notification_outbox
+					SET attempts = attempts + 1, last_error = $2,
+					    available_at = now() + make_interval(secs => $3::float8)
+					WHERE id = $1`, row.id, err.Error(), outboxRetryDelay(row.attempts+1).Seconds())
+			}
+			if err != nil {
+				return fmt.Errorf("failed to update outbox event: %w", err)
+			}
+		}
+		return nil
+	})
+	return claimed, err
+}
+
+func (s *NotificationService) handleOutboxEvent(ctx context.Context, event OutboxEvent) error {
+	switch event.Type {
+	case NotificationTypeLike:
+		if event.PostID == nil {
+			break
+		}
+		return s.NotifyLike(ctx, event.ActorID, *event.PostID)
+	case NotificationTypeComment:
+		if event.PostID == nil || event.CommentID == nil {
+			break
+		}
+		return s.NotifyComment(ctx, event.ActorID, *event.PostID, *event.CommentID, event.Text)
+	case NotificationTypeVerifiedAnswer:
+		if event.TargetID == nil || event.PostID == nil || event.CommentID == nil {
+			break
+		}
+		return s.NotifyVerifiedAnswer(ctx, event.ActorID, *event.TargetID, *event.PostID, *event.CommentID, event.Text)
+	case NotificationTypeAnswerAccepted:
+		if event.TargetID == nil || event.PostID == nil || event.CommentID == nil {
+			break
+		}
+		return s.NotifyAnswerAccepted(ctx, event.ActorID, *event.TargetID, *event.PostID, *event.CommentID, event.Text, event.Amount)
+	case NotificationTypeGroupInvite:
+		if event.TargetID == nil || event.GroupID == nil {
+			break
+		}
+		return s.NotifyGroupInvite(ctx, event.ActorID, *event.TargetID, *event.GroupID)
+	case NotificationTypeGroupJoinRequest:
+		if event.GroupID == nil {
+			break
+		}
+		return s.NotifyGroupJoinRequest(ctx, event.ActorID, *event.GroupID)
+	case NotificationTypeGroupJoinApproved:
+		if event.TargetID == nil || event.GroupID == nil {
+			break
+		}
+		return s.NotifyGroupJoinApproved(ctx, event.ActorID, *event.TargetID, *event.GroupID)
+	case NotificationTypeEventReminder:
+		if event.EventID == nil {
+			break
+		}
+		return s.NotifyEventReminder(ctx, *event.EventID)
+	case NotificationTypeNewPost:
+		if event.PostID == nil {
+			break
+		}
+		return s.NotifyNewPost(ctx, event.ActorID, *event.PostID, event.Text)
+	case NotificationTypeFollow:
+		if event.TargetID == nil {
+			break
+		}
+		return s.NotifyFollow(ctx, event.ActorID, *event.TargetID)
+	case NotificationTypeFollowRequest:
+		if event.TargetID == nil {
+			break
+		}
+		return s.NotifyFollowRequest(ctx, event.ActorID, *event.TargetID)
+	case NotificationTypeFollowAccepted:
+		if event.TargetID == nil {
+			break
+		}
+		return s.NotifyFollowAccepted(ctx, event.ActorID, *event.TargetID)
+	}
+	return fmt.Errorf("invalid outbox event %q", event.Type)
+}
+
+// outboxRetryDelay backs off exponentially from outboxRetryBase, capped at
+// an hour
+func outboxRetryDelay(attempts int) time.Duration {
+	delay := outboxRetryBase
+	for i := 1; i < attempts && delay < time.Hour; i++ {
+		delay *= 2
+	}
+	return min(delay, time.Hour)
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// notificationDefaults lists every configurable notification type with the
+// delivery channels enabled until the user changes them
+var notificationDefaults = []NotificationPreference{
+	{Type: NotificationTypeComment, Push: true, Email: true},
+	{Type: NotificationTypeFollow, Push: true, Email: true},
+	{Type: NotificationTypeFollowRequest, Push: true, Email: true},
+	{Type: NotificationTypeFollowAccepted, Push: true, Email: false},
+	{Type: NotificationTypeMention, Push: true, Email: true},
+	{Type: NotificationTypeVerifiedAnswer, Push: true, Email: true},
+	{Type: NotificationTypeAnswerAccepted, Push: true, Email: true},
+	{Type: NotificationTypeGroupInvite, Push: true, Email: true},
+	{Type: NotificationTypeGroupJoinRequest, Push: true, Email: false},
+	{Type: NotificationTypeGroupJoinApproved, Push: true, Email: false},
+	{Type: NotificationTypeEventReminder, Push: true, Email: false},
+	{Type: NotificationTypeStreakReminder, Push: true, Email: false},
+	{Type: NotificationTypeCourseCompleted, Push: true, Email: false},
+	{Type: NotificationTypeAssignmentDue, Push: true, Email: true},
+	// Emailed straight away by DeviceService, so left out of digests
+	{Type: NotificationTypeNewDeviceLogin, Push: true, Email: false},
+	{Type: NotificationTypeLike, Push: false, Email: true},
+	{Type: NotificationTypeNewPost, Push: false, Email: true},
+}
+
+type NotificationPreference struct {
+	Type  NotificationType `json:"type"`
+	Push  bool             `json:"push"`
+	Email bool             `json:"email"`
+}
+
+type NotificationSettings struct {
+	Preferences []NotificationPreference `json:"preferences"`
+	EmailDigest string                   `json:"email_digest"`
+}
+
+// NotificationPreferenceUpdate changes only the channels that are set
+type NotificationPreferenceUpdate struct {
+	Type  NotificationType `json:"type" validate:"required"`
+	Push  *bool            `json:"push"`
+	Email *bool            `json:"email"`
+}
+
+type UpdateNotificationPreferencesRequest struct {
+	Preferences []NotificationPreferenceUpdate `json:"preferences" validate:"omitempty,max=20,dive"`
+	EmailDigest *string                        `json:"email_digest" validate:"omitempty,oneof=off daily weekly"`
+}
+
+func defaultPreference(notificationType NotificationType) (NotificationPreference, bool) {
+	for _, pref := range notificationDefaults {
+		if pref.Type == notificationType {
+			return pref, true
+		}
+	}
+	return NotificationPreference{Type: notificationType}, false
+}
+
+// GetNotificationSettings returns the effective settings for every notification type
+func (s *NotificationService) GetNotificationSettings(ctx context.Context, userID uuid.UUID) (*NotificationSettings, error) {
+	settings := &NotificationSettings{}
+	err := s.db.QueryRow(ctx, `SELECT email_digest FROM users WHERE id = $1`, userID).Scan(&settings.EmailDigest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get email digest setting: %w", err)
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT type, push, email FROM notification_preferences WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+	defer rows.Close()
+
+	stored := make(map[NotificationType]storedPreference)
+	for rows.Next() {
+		var notificationType NotificationType
+		var pref storedPreference
+		if err := rows.Scan(&notificationType, &pref.push, &pref.email); err != nil {
+			return nil, fmt.Errorf("failed to scan notification preference: %w", err)
+		}
+		stored[notificationType] = pref
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read notification preferences: %w", err)
+	}
+
+	settings.Preferences = mergePreferences(stored)
+	return settings, nil
+}
+
+func (s *NotificationService) UpdateNotificationSettings(ctx context.Context, userID uuid.UUID, req UpdateNotificationPreferencesRequest) error {
+	for _, pref := range req.Preferences {
+		if _, ok := defaultPreference(pref.Type); !ok {
+			return fmt.Errorf("unknown notification type: %s", pref.Type)
+		}
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, pref := range req.Preferences {
+		defaults, _ := defaultPreference(pref.Type)
+		_, err := tx.Exec(ctx, `
+			INSERT INTO notification_preferences (user_id, type, push, email)
+			VALUES ($1, $2, COALESCE($3::boolean, $5::boolean), $4::boolean)
+			ON CONFLICT (user_id, type) DO UPDATE
+			SET push = COALESCE($3, notification_preferences.push),
+			    email = COALESCE($4, notification_preferences.email)`,
+			userID, pref.Type, pref.Push, pref.Email, defaults.Push)
+		if err != nil {
+			return fmt.Errorf("failed to update notification preferences: %w", err)
+		}
+	}
+
+	if req.EmailDigest != nil {
+		_, err := tx.Exec(ctx, `UPDATE users SET email_digest = $2 WHERE id = $1`, userID, *req.EmailDigest)
+		if err != nil {
+			return fmt.Errorf("failed to update email digest setting: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (s *NotificationService) getNotificationPreference(ctx context.Context, userID uuid.UUID, notificationType NotificationType) (NotificationPreference, error) {
+	var stored storedPreference
+	err := s.db.QueryRow(ctx, `
+		SELECT push, email FROM notification_preferences WHERE user_id = $1 AND type = $2`,
+		userID, notificationType).Scan(&stored.push, &stored.email)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return NotificationPreference{}, err
+	}
+
+	pref, _ := defaultPreference(notificationType)
+	if err == nil {
+		pref = stored.apply(pref)
+	}
+	return pref, nil
+}
+
+// storedPreference is a notification_preferences row; a NULL email column
+// means the type default applies
+type storedPreference struct {
+	push  bool
+	email *bool
+}
+
+func (p storedPreference) apply(pref NotificationPreference) NotificationPreference {
+	pref.Push = p.push
+	if p.email != nil {
+		pref.Email = *p.email
+	}
+	return pref
+}
+
+// mergePreferences fills in defaults for types the user never configured
+func mergePreferences(stored map[NotificationType]storedPreference) []NotificationPreference {
+	prefs := make([]NotificationPreference, 0, len(notificationDefaults))
+	for _, pref := range notificationDefaults {
+		if row, ok := stored[pref.Type]; ok {
+			pref = row.apply(pref)
+		}
+		prefs = append(prefs, pref)
+	}
+	return prefs
+}
@@ -0,0 +1,26 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergePreferences(t *testing.T) {
+	disabled := false
+	prefs := mergePreferences(map[NotificationType]storedPreference{
+		NotificationTypeComment: {push: false, email: &disabled},
+		NotificationTypeLike:    {push: true},
+	})
+
+	byType := make(map[NotificationType]NotificationPreference)
+	for _, pref := range prefs {
+		byType[pref.Type] = pref
+	}
+
+	assert.Len(t, prefs, len(notificationDefaults))
+	assert.Equal(t, NotificationPreference{Type: NotificationTypeComment, Push: false, Email: false}, byType[NotificationTypeComment])
+	assert.Equal(t, NotificationPreference{Type: NotificationTypeLike, Push: true, Email: true}, byType[NotificationTypeLike])
+	assert.Equal(t, NotificationPreference{Type: NotificationTypeFollow, Push: true, Email: true}, byType[NotificationTypeFollow])
+	assert.Equal(t, NotificationPreference{Type: NotificationTypeNewPost, Push: false, Email: true}, byType[NotificationTypeNewPost])
+}
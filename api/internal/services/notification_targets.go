@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	NotificationTargetPost       = "post"
+	NotificationTargetUser       = "user"
+	NotificationTargetGroup      = "group"
+	NotificationTargetEvent      = "event"
+	NotificationTargetCourse     = "course"
+	NotificationTargetAssignment = "assignment"
+	NotificationTargetSession    = "session"
+)
+
+// NotificationTarget is where a client should navigate for a notification.
+// Path is the web app route, with a #comment- anchor for comments.
+type NotificationTarget struct {
+	Kind         string     `json:"kind"`
+	PostID       *uuid.UUID `json:"post_id,omitempty"`
+	CommentID    *uuid.UUID `json:"comment_id,omitempty"`
+	UserID       *uuid.UUID `json:"user_id,omitempty"`
+	Username     string     `json:"username,omitempty"`
+	GroupID      *uuid.UUID `json:"group_id,omitempty"`
+	EventID      *uuid.UUID `json:"event_id,omitempty"`
+	CourseID     *uuid.UUID `json:"course_id,omitempty"`
+	AssignmentID *uuid.UUID `json:"assignment_id,omitempty"`
+	Path         string     `json:"path"`
+}
+
+// ResolveTarget returns the resource a notification points to. With
+// markRead the notification is marked read by the same statement that
+// loads it.
+func (s *NotificationService) ResolveTarget(ctx context.Context, notificationID, userID uuid.UUID, markRead bool) (*NotificationTarget, error) {
+	query := `
+		SELECT type, entity_id, payload_json, false FROM notifications
+		WHERE id = $1 AND user_id = $2`
+	if markRead {
+		// The subquery sees the row before the update, telling whether
+		// this statement is what read it
+		query = `
+			UPDATE notifications n SET read_at = COALESCE(n.read_at, now())
+			FROM (SELECT id, read_at FROM notifications WHERE id = $1 AND user_id = $2) old
+			WHERE n.id = old.id
+			RETURNING n.type, n.entity_id, n.payload_json, old.read_at IS NULL`
+	}
+
+	var notification Notification
+	var newlyRead bool
+	err := s.db.QueryRow(ctx, query, notificationID, userID).Scan(
+		&notification.Type, &notification.EntityID, &notification.Payload, &newlyRead)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("notification not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification: %w", err)
+	}
+	if newlyRead {
+		notificationsRead.Inc(string(notification.Type))
+		s.unreadCounts.Forget(userID.String())
+	}
+
+	target, err := notificationTarget(&notification)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the target still exists; user paths need the username
+	switch target.Kind {
+	case NotificationTargetPost:
+		var exists bool
+		err = s.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM posts WHERE id = $1)`, *target.PostID).Scan(&exists)
+		if err == nil && !exists {
+			return nil, fmt.Errorf("notification target not found")
+		}
+	case NotificationTargetUser:
+		err = s.db.QueryRow(ctx, `SELECT username FROM users WHERE id = $1`, *target.UserID).Scan(&target.Username)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("notification target not found")
+		}
+		target.Path = "/profile/" + target.Username
+	case NotificationTargetGroup:
+		var exists bool
+		err = s.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM groups WHERE id = $1)`, *target.GroupID).Scan(&exists)
+		if err == nil && !exists {
+			return nil, fmt.Errorf("notification target not found")
+		}
+	case NotificationTargetEvent:
+		var exists bool
+		err = s.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM events WHERE id = $1)`, *target.EventID).Scan(&exists)
+		if err == nil && !exists {
+			return nil, fmt.Errorf("notification target not found")
+		}
+	case NotificationTargetCourse:
+		var exists bool
+		err = s.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM courses WHERE id = $1)`, *target.CourseID).Scan(&exists)
+		if err == nil && !exists {
+			return nil, fmt.Errorf("notification target not found")
+		}
+	case NotificationTargetAssignment:
+		var exists bool
+		err = s.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM assignments WHERE id = $1)`, *target.AssignmentID).Scan(&exists)
+		if err == nil && !exists {
+			return nil, fmt.Errorf("notification target not found")
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve notification target: %w", err)
+	}
+
+	return target, nil
+}
+
+// notificationTarget maps a notification to its target from the type, entity
+// and payload alone
+func notificationTarget(notification *Notification) (*NotificationTarget, error) {
+	if notification.EntityID == nil {
+		return nil, fmt.Errorf("notification target not found")
+	}
+	entityID := *notification.EntityID
+
+	switch notification.Type {
+	case NotificationTypeLike, NotificationTypeNewPost, NotificationTypeMention:
+		return &NotificationTarget{
+			Kind:   NotificationTargetPost,
+			PostID: &entityID,
+			Path:   "/post/" + entityID.String(),
+		}, nil
+
+	case NotificationTypeComment, NotificationTypeVerifiedAnswer, NotificationTypeAnswerAccepted:
+		target := &NotificationTarget{
+			Kind:   NotificationTargetPost,
+			PostID: &entityID,
+			Path:   "/post/" + entityID.String(),
+		}
+		// Comment notifications from before comment_id was recorded link to
+		// the post only
+		if raw, ok := notification.Payload["comment_id"].(string); ok {
+			if commentID, err := uuid.Parse(raw); err == nil {
+				target.CommentID = &commentID
+				target.Path += "#comment-" + commentID.String()
+			}
+		}
+		return target, nil
+
+	case NotificationTypeFollow, NotificationTypeFollowRequest, NotificationTypeFollowAccepted, NotificationTypeStreakReminder:
+		return &NotificationTarget{
+			Kind:   NotificationTargetUser,
+			UserID: &entityID,
+		}, nil
+
+	case NotificationTypeGroupInvite, NotificationTypeGroupJoinRequest, NotificationTypeGroupJoinApproved:
+		return &NotificationTarget{
+			Kind:    NotificationTargetGroup,
+			GroupID: &entityID,
+			Path:    "/groups/" + entityID.String(),
+		}, nil
+
+	case NotificationTypeEventReminder:
+		return &NotificationTarget{
+			Kind:    NotificationTargetEvent,
+			EventID: &entityID,
+			Path:    "/events/" + entityID.String(),
+		}, nil
+
+	case NotificationTypeCourseCompleted:
+		return &NotificationTarget{
+			Kind:     NotificationTargetCourse,
+			CourseID: &entityID,
+			Path:     "/courses/" + entityID.String(),
+		}, nil
+
+	case NotificationTypeAssignmentDue:
+		return &NotificationTarget{
+			Kind:         NotificationTargetAssignment,
+			AssignmentID: &entityID,
+			Path:         "/assignments/" + entityID.String(),
+		}, nil
+
+	case NotificationTypeNewDeviceLogin:
+		return &NotificationTarget{
+			Kind: NotificationTargetSession,
+			Path: "/settings/sessions",
+		}, nil
+	}
+
+	return nil, fmt.Errorf("notification target not found")
+}
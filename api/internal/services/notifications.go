@@ -3,26 +3,49 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
-	"github.com/jackc/pgx/v5/pgxpool"
+
+	"bailanysta/api/internal/pkg/coalesce"
+	"bailanysta/api/internal/pkg/database"
+	"bailanysta/api/internal/pkg/markdown"
+	"bailanysta/api/internal/pkg/webpush"
 )
 
 type NotificationType string
 
 const (
-	NotificationTypeLike    NotificationType = "like"
-	NotificationTypeComment NotificationType = "comment"
-	NotificationTypeFollow  NotificationType = "follow"
-	NotificationTypeMention NotificationType = "mention"
-	NotificationTypeNewPost NotificationType = "new_post"
+	NotificationTypeLike           NotificationType = "like"
+	NotificationTypeComment        NotificationType = "comment"
+	NotificationTypeFollow         NotificationType = "follow"
+	NotificationTypeFollowRequest  NotificationType = "follow_request"
+	NotificationTypeFollowAccepted NotificationType = "follow_accepted"
+	NotificationTypeMention        NotificationType = "mention"
+	NotificationTypeNewPost        NotificationType = "new_post"
+	NotificationTypeVerifiedAnswer NotificationType = "verified_answer"
+	NotificationTypeAnswerAccepted NotificationType = "answer_accepted"
+	// Group notifications have the group as their entity
+	NotificationTypeGroupInvite       NotificationType = "group_invite"
+	NotificationTypeGroupJoinRequest  NotificationType = "group_join_request"
+	NotificationTypeGroupJoinApproved NotificationType = "group_join_approved"
+	NotificationTypeEventReminder     NotificationType = "event_reminder"
+	NotificationTypeStreakReminder    NotificationType = "streak_reminder"
+	NotificationTypeCourseCompleted   NotificationType = "course_completed"
+	NotificationTypeAssignmentDue     NotificationType = "assignment_due"
+	// The entity is the session that signed in
+	NotificationTypeNewDeviceLogin NotificationType = "new_device_login"
 )
 
 type NotificationService struct {
-	db *pgxpool.Pool
+	db           *database.Pool
+	push         *webpush.Client
+	pushQueue    chan *Notification
+	unreadCounts *coalesce.Group[int]
 }
 
 type Notification struct {
@@ -46,8 +69,15 @@ type CreateNotificationRequest struct {
 	Payload  map[string]interface{} `json:"payload"`
 }
 
-func NewNotificationService(db *pgxpool.Pool) *NotificationService {
-	return &NotificationService{db: db}
+// NewNotificationService creates the service; push may be nil when Web Push
+// is not configured. Unread counts are cached for unreadCacheTTL.
+func NewNotificationService(db *database.Pool, push *webpush.Client, unreadCacheTTL time.Duration) *NotificationService {
+	return &NotificationService{
+		db:           db,
+		push:         push,
+		pushQueue:    make(chan *Notification, pushQueueSize),
+		unreadCounts: coalesce.New[int]("unread_count", unreadCacheTTL),
+	}
 }
 
 func (s *NotificationService) CreateNotification(ctx context.Context, req CreateNotificationRequest) (*Notification, error) {
@@ -74,6 +104,8 @@ func (s *NotificationService) CreateNotification(ctx context.Context, req Create
 	if err != nil {
 		return nil, fmt.Errorf("failed to create notification: %w", err)
 	}
+	notificationsCreated.Inc(string(notification.Type))
+	s.unreadCounts.Forget(req.UserID.String())
 
 	if entityID.Valid {
 		entityUUID := uuid.UUID(entityID.Bytes)
@@ -85,11 +117,16 @@ func (s *NotificationService) CreateNotification(ctx context.Context, req Create
 		return nil, fmt.Errorf("failed to unmarshal payload: %w", err)
 	}
 
+	pushed := notification
+	s.enqueuePush(&pushed)
+
 	return &notification, nil
 }
 
+// GetUserNotifications lists notifications from the read replica when one is
+// configured, so one that was just created may be missing for a moment
 func (s *NotificationService) GetUserNotifications(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Notification, error) {
-	rows, err := s.db.Query(ctx, `
+	rows, err := s.db.Reader().Query(ctx, `
 		SELECT n.id, n.user_id, n.type, n.entity_id, n.payload_json, n.read_at, n.created_at
 		FROM notifications n
 		WHERE n.user_id = $1
@@ -136,65 +173,96 @@ func (s *NotificationService) GetUserNotifications(ctx context.Context, userID u
 }
 
 func (s *NotificationService) MarkAsRead(ctx context.Context, notificationID, userID uuid.UUID) error {
-	result, err := s.db.Exec(ctx, `
+	var notificationType NotificationType
+	err := s.db.QueryRow(ctx, `
 		UPDATE notifications
 		SET read_at = now()
-		WHERE id = $1 AND user_id = $2 AND read_at IS NULL`, notificationID, userID)
+		WHERE id = $1 AND user_id = $2 AND read_at IS NULL
+		RETURNING type`, notificationID, userID).Scan(&notificationType)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("notification not found or already read")
+	}
 	if err != nil {
 		return fmt.Errorf("failed to mark notification as read: %w", err)
 	}
 
-	rowsAffected := result.RowsAffected()
-	if rowsAffected == 0 {
-		return fmt.Errorf("notification not found or already read")
-	}
-
+	notificationsRead.Inc(string(notificationType))
+	s.unreadCounts.Forget(userID.String())
 	return nil
 }
 
 func (s *NotificationService) MarkAllAsRead(ctx context.Context, userID uuid.UUID) error {
-	_, err := s.db.Exec(ctx, `
-		UPDATE notifications
-		SET read_at = now()
-		WHERE user_id = $1 AND read_at IS NULL`, userID)
+	rows, err := s.db.Query(ctx, `
+		WITH updated AS (
+			UPDATE notifications
+			SET read_at = now()
+			WHERE user_id = $1 AND read_at IS NULL
+			RETURNING type
+		)
+		SELECT type, COUNT(*) FROM updated GROUP BY type`, userID)
 	if err != nil {
 		return fmt.Errorf("failed to mark all notifications as read: %w", err)
 	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var notificationType NotificationType
+		var count int
+		if err := rows.Scan(&notificationType, &count); err != nil {
+			return fmt.Errorf("failed to mark all notifications as read: %w", err)
+		}
+		notificationsRead.Add(float64(count), string(notificationType))
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to mark all notifications as read: %w", err)
+	}
+	s.unreadCounts.Forget(userID.String())
 
 	return nil
 }
 
+// GetUnreadCount is polled by every open client, so concurrent calls for a
+// user share one query and the result is cached briefly. New notifications
+// and the user's own reads and deletes drop it.
 func (s *NotificationService) GetUnreadCount(ctx context.Context, userID uuid.UUID) (int, error) {
-	var count int
-	err := s.db.QueryRow(ctx, `
-		SELECT COUNT(*) FROM notifications
-		WHERE user_id = $1 AND read_at IS NULL`, userID).Scan(&count)
-	if err != nil {
-		return 0, fmt.Errorf("failed to get unread count: %w", err)
-	}
-
-	return count, nil
+	return s.unreadCounts.Do(ctx, userID.String(), func(ctx context.Context) (int, error) {
+		var count int
+		err := s.db.QueryRow(ctx, `
+			SELECT COUNT(*) FROM notifications
+			WHERE user_id = $1 AND read_at IS NULL`, userID).Scan(&count)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get unread count: %w", err)
+		}
+		return count, nil
+	})
 }
 
 func (s *NotificationService) DeleteNotification(ctx context.Context, notificationID, userID uuid.UUID) error {
-	result, err := s.db.Exec(ctx, `
+	var notificationType NotificationType
+	err := s.db.QueryRow(ctx, `
 		DELETE FROM notifications
-		WHERE id = $1 AND user_id = $2`, notificationID, userID)
+		WHERE id = $1 AND user_id = $2
+		RETURNING type`, notificationID, userID).Scan(&notificationType)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("notification not found")
+	}
 	if err != nil {
 		return fmt.Errorf("failed to delete notification: %w", err)
 	}
 
-	rowsAffected := result.RowsAffected()
-	if rowsAffected == 0 {
-		return fmt.Errorf("notification not found")
-	}
-
+	notificationsDeleted.Inc(string(notificationType))
+	s.unreadCounts.Forget(userID.String())
 	return nil
 }
 
-// Notification triggers - called when certain actions happen
+// Notification triggers - called by the outbox dispatcher for events written
+// alongside the action. Actions by shadow-banned users notify no one.
 
 func (s *NotificationService) NotifyLike(ctx context.Context, likerID, postID uuid.UUID) error {
+	if banned, err := s.isShadowBanned(ctx, likerID); err != nil || banned {
+		return err
+	}
+
 	// Get post author
 	var postAuthorID uuid.UUID
 	var postText string
@@ -225,7 +293,11 @@ func (s *NotificationService) NotifyLike(ctx context.Context, likerID, postID uu
 	return err
 }
 
-func (s *NotificationService) NotifyComment(ctx context.Context, commenterID, postID uuid.UUID, commentText string) error {
+func (s *NotificationService) NotifyComment(ctx context.Context, commenterID, postID, commentID uuid.UUID, commentText string) error {
+	if banned, err := s.isShadowBanned(ctx, commenterID); err != nil || banned {
+		return err
+	}
+
 	// Get post author
 	var postAuthorID uuid.UUID
 	var postText string
@@ -243,6 +315,7 @@ func (s *NotificationService) NotifyComment(ctx context.Context, commenterID, po
 	payload := map[string]interface{}{
 		"commenter_id": commenterID,
 		"post_id":      postID,
+		"comment_id":   commentID,
 		"comment_text": truncateText(commentText, 100),
 		"post_text":    truncateText(postText, 100),
 	}
@@ -257,7 +330,255 @@ func (s *NotificationService) NotifyComment(ctx context.Context, commenterID, po
 	return err
 }
 
+// NotifyVerifiedAnswer tells authorID a teacher marked their comment as the
+// verified answer
+func (s *NotificationService) NotifyVerifiedAnswer(ctx context.Context, teacherID, authorID, postID, commentID uuid.UUID, commentText string) error {
+	payload := map[string]interface{}{
+		"teacher_id":   teacherID,
+		"post_id":      postID,
+		"comment_id":   commentID,
+		"comment_text": truncateText(commentText, 100),
+	}
+
+	_, err := s.CreateNotification(ctx, CreateNotificationRequest{
+		UserID:   authorID,
+		Type:     NotificationTypeVerifiedAnswer,
+		EntityID: &postID,
+		Payload:  payload,
+	})
+
+	return err
+}
+
+// NotifyAnswerAccepted tells authorID the asker accepted their comment as
+// the answer to a question; bounty is what it earned them, if anything
+func (s *NotificationService) NotifyAnswerAccepted(ctx context.Context, askerID, authorID, postID, commentID uuid.UUID, commentText string, bounty int) error {
+	payload := map[string]interface{}{
+		"asker_id":     askerID,
+		"post_id":      postID,
+		"comment_id":   commentID,
+		"comment_text": truncateText(commentText, 100),
+		"bounty":       bounty,
+	}
+
+	_, err := s.CreateNotification(ctx, CreateNotificationRequest{
+		UserID:   authorID,
+		Type:     NotificationTypeAnswerAccepted,
+		EntityID: &postID,
+		Payload:  payload,
+	})
+
+	return err
+}
+
+func (s *NotificationService) NotifyGroupInvite(ctx context.Context, inviterID, inviteeID, groupID uuid.UUID) error {
+	return s.notifyGroup(ctx, NotificationTypeGroupInvite, "inviter_id", inviterID, inviteeID, groupID)
+}
+
+func (s *NotificationService) NotifyGroupJoinApproved(ctx context.Context, approverID, userID, groupID uuid.UUID) error {
+	return s.notifyGroup(ctx, NotificationTypeGroupJoinApproved, "approver_id", approverID, userID, groupID)
+}
+
+// NotifyGroupJoinRequest tells the group's owner and admins that requesterID
+// asked to join
+func (s *NotificationService) NotifyGroupJoinRequest(ctx context.Context, requesterID, groupID uuid.UUID) error {
+	if banned, err := s.isShadowBanned(ctx, requesterID); err != nil || banned {
+		return err
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT user_id FROM group_members
+		WHERE group_id = $1 AND status = 'active' AND role IN ('owner', 'admin')`, groupID)
+	if err != nil {
+		return fmt.Errorf("failed to get group admins: %w", err)
+	}
+	adminIDs, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return fmt.Errorf("failed to get group admins: %w", err)
+	}
+
+	notificationFanout.Observe(float64(len(adminIDs)), string(NotificationTypeGroupJoinRequest))
+	for _, adminID := range adminIDs {
+		if err := s.notifyGroup(ctx, NotificationTypeGroupJoinRequest, "requester_id", requesterID, adminID, groupID); err != nil {
+			// Log error but continue with other notifications
+			fmt.Printf("Failed to create group join request notification for user %s: %v\n", adminID, err)
+		}
+	}
+	return nil
+}
+
+// notifyGroup notifies userID of a group event; the actor's ID is stored in
+// the payload under actorKey
+func (s *NotificationService) notifyGroup(ctx context.Context, notificationType NotificationType, actorKey string, actorID, userID, groupID uuid.UUID) error {
+	var groupName string
+	err := s.db.QueryRow(ctx, `SELECT name FROM groups WHERE id = $1`, groupID).Scan(&groupName)
+	if err != nil {
+		return fmt.Errorf("failed to get group: %w", err)
+	}
+
+	_, err = s.CreateNotification(ctx, CreateNotificationRequest{
+		UserID:   userID,
+		Type:     notificationType,
+		EntityID: &groupID,
+		Payload: map[string]interface{}{
+			actorKey:     actorID,
+			"group_id":   groupID,
+			"group_name": groupName,
+		},
+	})
+
+	return err
+}
+
+// NotifyEventReminder tells everyone going to, or maybe going to, an event
+// that it starts soon
+func (s *NotificationService) NotifyEventReminder(ctx context.Context, eventID uuid.UUID) error {
+	var title string
+	var startsAt time.Time
+	err := s.db.QueryRow(ctx, `SELECT title, starts_at FROM events WHERE id = $1`, eventID).Scan(&title, &startsAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get event: %w", err)
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT user_id FROM event_rsvps
+		WHERE event_id = $1 AND status IN ('going', 'maybe')`, eventID)
+	if err != nil {
+		return fmt.Errorf("failed to get event attendees: %w", err)
+	}
+	attendeeIDs, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return fmt.Errorf("failed to get event attendees: %w", err)
+	}
+
+	notificationFanout.Observe(float64(len(attendeeIDs)), string(NotificationTypeEventReminder))
+	for _, attendeeID := range attendeeIDs {
+		_, err := s.CreateNotification(ctx, CreateNotificationRequest{
+			UserID:   attendeeID,
+			Type:     NotificationTypeEventReminder,
+			EntityID: &eventID,
+			Payload: map[string]interface{}{
+				"event_id":    eventID,
+				"event_title": title,
+				"starts_at":   startsAt,
+			},
+		})
+		if err != nil {
+			// Log error but continue with other notifications
+			fmt.Printf("Failed to create event reminder for user %s: %v\n", attendeeID, err)
+		}
+	}
+	return nil
+}
+
+// NotifyStreakRisk reminds userID that their streak of the given length ends
+// unless they are active today
+func (s *NotificationService) NotifyStreakRisk(ctx context.Context, userID uuid.UUID, streak int) error {
+	_, err := s.CreateNotification(ctx, CreateNotificationRequest{
+		UserID:   userID,
+		Type:     NotificationTypeStreakReminder,
+		EntityID: &userID,
+		Payload: map[string]interface{}{
+			"streak": streak,
+		},
+	})
+
+	return err
+}
+
+// NotifyCourseCompleted congratulates userID on finishing every module of a
+// course
+func (s *NotificationService) NotifyCourseCompleted(ctx context.Context, userID, courseID uuid.UUID, courseTitle string) error {
+	_, err := s.CreateNotification(ctx, CreateNotificationRequest{
+		UserID:   userID,
+		Type:     NotificationTypeCourseCompleted,
+		EntityID: &courseID,
+		Payload: map[string]interface{}{
+			"course_title": courseTitle,
+		},
+	})
+
+	return err
+}
+
+// NotifyNewDeviceLogin warns userID that sessionID signed in from a device
+// they haven't used before; location may be empty
+func (s *NotificationService) NotifyNewDeviceLogin(ctx context.Context, userID, sessionID uuid.UUID, device, location string) error {
+	_, err := s.CreateNotification(ctx, CreateNotificationRequest{
+		UserID:   userID,
+		Type:     NotificationTypeNewDeviceLogin,
+		EntityID: &sessionID,
+		Payload: map[string]interface{}{
+			"device":   device,
+			"location": location,
+		},
+	})
+
+	return err
+}
+
+// NotifyAssignmentDue reminds the students who started an assignment's
+// course, and haven't handed anything in, that it is due soon
+func (s *NotificationService) NotifyAssignmentDue(ctx context.Context, assignmentID uuid.UUID) error {
+	var title string
+	var courseID uuid.UUID
+	var dueAt *time.Time
+	err := s.db.QueryRow(ctx, `
+		SELECT a.title, m.course_id, a.due_at FROM assignments a
+		JOIN modules m ON m.id = a.module_id
+		WHERE a.id = $1`, assignmentID).Scan(&title, &courseID, &dueAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get assignment: %w", err)
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT DISTINCT mp.user_id FROM module_progress mp
+		JOIN modules m ON m.id = mp.module_id
+		WHERE m.course_id = $1
+		  AND NOT EXISTS (
+		    SELECT 1 FROM assignment_submissions sub
+		    WHERE sub.assignment_id = $2 AND sub.user_id = mp.user_id
+		  )`, courseID, assignmentID)
+	if err != nil {
+		return fmt.Errorf("failed to get assignment students: %w", err)
+	}
+	studentIDs, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return fmt.Errorf("failed to get assignment students: %w", err)
+	}
+
+	notificationFanout.Observe(float64(len(studentIDs)), string(NotificationTypeAssignmentDue))
+	for _, studentID := range studentIDs {
+		_, err := s.CreateNotification(ctx, CreateNotificationRequest{
+			UserID:   studentID,
+			Type:     NotificationTypeAssignmentDue,
+			EntityID: &assignmentID,
+			Payload: map[string]interface{}{
+				"assignment_id":    assignmentID,
+				"assignment_title": title,
+				"course_id":        courseID,
+				"due_at":           dueAt,
+			},
+		})
+		if err != nil {
+			// Log error but continue with other notifications
+			fmt.Printf("Failed to create assignment reminder for user %s: %v\n", studentID, err)
+		}
+	}
+	return nil
+}
+
 func (s *NotificationService) NotifyFollow(ctx context.Context, followerID, followeeID uuid.UUID) error {
+	if banned, err := s.isShadowBanned(ctx, followerID); err != nil || banned {
+		return err
+	}
+
 	payload := map[string]interface{}{
 		"follower_id": followerID,
 	}
@@ -272,7 +593,41 @@ func (s *NotificationService) NotifyFollow(ctx context.Context, followerID, foll
 	return err
 }
 
+func (s *NotificationService) NotifyFollowRequest(ctx context.Context, requesterID, targetID uuid.UUID) error {
+	if banned, err := s.isShadowBanned(ctx, requesterID); err != nil || banned {
+		return err
+	}
+
+	_, err := s.CreateNotification(ctx, CreateNotificationRequest{
+		UserID:   targetID,
+		Type:     NotificationTypeFollowRequest,
+		EntityID: &requesterID,
+		Payload: map[string]interface{}{
+			"requester_id": requesterID,
+		},
+	})
+
+	return err
+}
+
+func (s *NotificationService) NotifyFollowAccepted(ctx context.Context, accepterID, requesterID uuid.UUID) error {
+	_, err := s.CreateNotification(ctx, CreateNotificationRequest{
+		UserID:   requesterID,
+		Type:     NotificationTypeFollowAccepted,
+		EntityID: &accepterID,
+		Payload: map[string]interface{}{
+			"accepter_id": accepterID,
+		},
+	})
+
+	return err
+}
+
 func (s *NotificationService) NotifyNewPost(ctx context.Context, authorID, postID uuid.UUID, postText string) error {
+	if banned, err := s.isShadowBanned(ctx, authorID); err != nil || banned {
+		return err
+	}
+
 	// Get all followers of the author
 	rows, err := s.db.Query(ctx, `
 		SELECT follower_id FROM follows WHERE followee_id = $1`, authorID)
@@ -291,6 +646,7 @@ func (s *NotificationService) NotifyNewPost(ctx context.Context, authorID, postI
 	}
 
 	// Create notifications for all followers
+	notificationFanout.Observe(float64(len(followerIDs)), string(NotificationTypeNewPost))
 	for _, followerID := range followerIDs {
 		payload := map[string]interface{}{
 			"author_id": authorID,
@@ -315,16 +671,36 @@ func (s *NotificationService) NotifyNewPost(ctx context.Context, authorID, postI
 
 // Helper methods
 
+func (s *NotificationService) isShadowBanned(ctx context.Context, userID uuid.UUID) (bool, error) {
+	var banned bool
+	err := s.db.QueryRow(ctx, `
+		SELECT shadow_banned FROM users WHERE id = $1`, userID).Scan(&banned)
+	if err != nil {
+		return false, fmt.Errorf("failed to check shadow ban: %w", err)
+	}
+	return banned, nil
+}
+
 func (s *NotificationService) populateNotificationData(ctx context.Context, notification *Notification) error {
 	switch notification.Type {
 	case NotificationTypeLike:
 		return s.populateLikeData(ctx, notification)
 	case NotificationTypeComment:
-		return s.populateCommentData(ctx, notification)
-	case NotificationTypeFollow:
+		return s.populateCommentData(ctx, notification, "commenter_id")
+	case NotificationTypeVerifiedAnswer:
+		return s.populateCommentData(ctx, notification, "teacher_id")
+	case NotificationTypeAnswerAccepted:
+		return s.populateCommentData(ctx, notification, "asker_id")
+	case NotificationTypeFollow, NotificationTypeFollowRequest, NotificationTypeFollowAccepted:
 		return s.populateFollowData(ctx, notification)
 	case NotificationTypeNewPost:
 		return s.populateNewPostData(ctx, notification)
+	case NotificationTypeGroupInvite:
+		return s.populateActor(ctx, notification, "inviter_id")
+	case NotificationTypeGroupJoinRequest:
+		return s.populateActor(ctx, notification, "requester_id")
+	case NotificationTypeGroupJoinApproved:
+		return s.populateActor(ctx, notification, "approver_id")
 	}
 	return nil
 }
@@ -366,12 +742,12 @@ func (s *NotificationService) populateLikeData(ctx context.Context, notification
 	var courseID, moduleID pgtype.UUID
 	var postBio, postAvatarURL pgtype.Text
 	err = s.db.QueryRow(ctx, `
-		SELECT p.id, p.author_id, p.text, p.course_id, p.module_id, p.created_at, p.updated_at,
+		SELECT p.id, p.author_id, p.text, p.course_id, p.module_id, p.is_ai_generated, p.edited_at IS NOT NULL, p.slug, p.created_at, p.updated_at,
 		       u.username, u.email, u.bio, u.avatar_url
 		FROM posts p
 		JOIN users u ON p.author_id = u.id
 		WHERE p.id = $1`, *notification.EntityID).Scan(
-		&post.ID, &post.AuthorID, &post.Text, &courseID, &moduleID, &post.CreatedAt, &post.UpdatedAt,
+		&post.ID, &post.AuthorID, &post.Text, &courseID, &moduleID, &post.IsAIGenerated, &post.Edited, &post.Slug, &post.CreatedAt, &post.UpdatedAt,
 		&post.Author.Username, &post.Author.Email, &postBio, &postAvatarURL)
 	if err != nil {
 		return err
@@ -385,6 +761,7 @@ func (s *NotificationService) populateLikeData(ctx context.Context, notification
 		moduleUUID := uuid.UUID(moduleID.Bytes)
 		post.ModuleID = &moduleUUID
 	}
+	post.TextHTML = RenderPostText(post.Slug, post.Text)
 	post.Author.Bio = getPgtypeTextValue(postBio)
 	post.Author.AvatarURL = getPgtypeTextPtr(postAvatarURL)
 
@@ -393,12 +770,14 @@ func (s *NotificationService) populateLikeData(ctx context.Context, notification
 	return nil
 }
 
-func (s *NotificationService) populateCommentData(ctx context.Context, notification *Notification) error {
+// populateCommentData loads the post and, as the actor, the user whose ID
+// is in the payload under actorKey
+func (s *NotificationService) populateCommentData(ctx context.Context, notification *Notification, actorKey string) error {
 	if notification.EntityID == nil {
 		return nil
 	}
 
-	commenterID, ok := notification.Payload["commenter_id"].(string)
+	commenterID, ok := notification.Payload[actorKey].(string)
 	if !ok {
 		return nil
 	}
@@ -454,6 +833,37 @@ func (s *NotificationService) populateFollowData(ctx context.Context, notificati
 	return nil
 }
 
+// populateActor loads the user whose ID is in the payload under actorKey
+func (s *NotificationService) populateActor(ctx context.Context, notification *Notification, actorKey string) error {
+	actorID, ok := notification.Payload[actorKey].(string)
+	if !ok {
+		return nil
+	}
+
+	actorUUID, err := uuid.Parse(actorID)
+	if err != nil {
+		return err
+	}
+
+	var actor UserResponse
+	var bio, avatarURL pgtype.Text
+	err = s.db.QueryRow(ctx, `
+		SELECT username, email, bio, avatar_url
+		FROM users WHERE id = $1`, actorUUID).Scan(
+		&actor.Username, &actor.Email, &bio, &avatarURL)
+	if err != nil {
+		return err
+	}
+
+	actor.ID = actorUUID
+	actor.Bio = getPgtypeTextValue(bio)
+	actor.AvatarURL = getPgtypeTextPtr(avatarURL)
+
+	notification.Actor = &actor
+
+	return nil
+}
+
 func (s *NotificationService) populateNewPostData(ctx context.Context, notification *Notification) error {
 	if notification.EntityID == nil {
 		return nil
@@ -474,6 +884,8 @@ func (s *NotificationService) populateNewPostData(ctx context.Context, notificat
 		return fmt.Errorf("failed to get post info: %w", err)
 	}
 
+	post.TextHTML = markdown.Render(post.Text)
+
 	// Convert pgtype to regular types
 	post.Author.Bio = getPgtypeTextValue(bio)
 	post.Author.AvatarURL = getPgtypeTextPtr(avatarURL)
@@ -484,6 +896,63 @@ func (s *NotificationService) populateNewPostData(ctx context.Context, notificat
 	return nil
 }
 
+// notificationSummary renders a one-line description of a populated notification
+func notificationSummary(notification *Notification) string {
+	actor := "Someone"
+	if notification.Actor != nil && notification.Actor.Username != "" {
+		actor = "@" + notification.Actor.Username
+	}
+	text := func(key string) string {
+		value, _ := notification.Payload[key].(string)
+		return value
+	}
+
+	switch notification.Type {
+	case NotificationTypeLike:
+		return actor + " liked your post"
+	case NotificationTypeComment:
+		return actor + " commented: " + text("comment_text")
+	case NotificationTypeFollow:
+		return actor + " started following you"
+	case NotificationTypeFollowRequest:
+		return actor + " requested to follow you"
+	case NotificationTypeFollowAccepted:
+		return actor + " accepted your follow request"
+	case NotificationTypeMention:
+		return actor + " mentioned you"
+	case NotificationTypeNewPost:
+		return actor + " published a new post: " + text("post_text")
+	case NotificationTypeVerifiedAnswer:
+		return actor + " marked your comment as the verified answer"
+	case NotificationTypeAnswerAccepted:
+		if bounty, ok := notification.Payload["bounty"].(float64); ok && bounty > 0 {
+			return fmt.Sprintf("%s accepted your answer; you earned a %v-point bounty", actor, bounty)
+		}
+		return actor + " accepted your answer"
+	case NotificationTypeGroupInvite:
+		return actor + " invited you to " + text("group_name")
+	case NotificationTypeGroupJoinRequest:
+		return actor + " asked to join " + text("group_name")
+	case NotificationTypeGroupJoinApproved:
+		return actor + " added you to " + text("group_name")
+	case NotificationTypeEventReminder:
+		return text("event_title") + " starts soon"
+	case NotificationTypeStreakReminder:
+		return fmt.Sprintf("Post or comment today to keep your %v-day streak", notification.Payload["streak"])
+	case NotificationTypeCourseCompleted:
+		return "You completed " + text("course_title")
+	case NotificationTypeAssignmentDue:
+		return text("assignment_title") + " is due soon"
+	case NotificationTypeNewDeviceLogin:
+		if location := text("location"); location != "" {
+			return "New sign-in from " + text("device") + " near " + location
+		}
+		return "New sign-in from " + text("device")
+	default:
+		return "You have a new notification"
+	}
+}
+
 // Utility functions
 func truncateText(text string, maxLength int) string {
 	if len(text) <= maxLength {
@@ -1,9 +1,13 @@
 package services
 
 import (
+	"context"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestTruncateText(t *testing.T) {
@@ -88,3 +92,82 @@ func TestNotificationTypes(t *testing.T) {
 		})
 	}
 }
+
+func TestNotificationTarget(t *testing.T) {
+	postID := uuid.New()
+	commentID := uuid.New()
+	userID := uuid.New()
+
+	t.Run("like links to post", func(t *testing.T) {
+		target, err := notificationTarget(&Notification{Type: NotificationTypeLike, EntityID: &postID})
+		require.NoError(t, err)
+		assert.Equal(t, NotificationTargetPost, target.Kind)
+		assert.Equal(t, "/post/"+postID.String(), target.Path)
+		assert.Nil(t, target.CommentID)
+	})
+
+	t.Run("comment anchors the comment", func(t *testing.T) {
+		target, err := notificationTarget(&Notification{
+			Type:     NotificationTypeComment,
+			EntityID: &postID,
+			Payload:  map[string]interface{}{"comment_id": commentID.String()},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, &commentID, target.CommentID)
+		assert.Equal(t, "/post/"+postID.String()+"#comment-"+commentID.String(), target.Path)
+	})
+
+	t.Run("old comment without id", func(t *testing.T) {
+		target, err := notificationTarget(&Notification{Type: NotificationTypeComment, EntityID: &postID})
+		require.NoError(t, err)
+		assert.Nil(t, target.CommentID)
+		assert.Equal(t, "/post/"+postID.String(), target.Path)
+	})
+
+	t.Run("follow links to user", func(t *testing.T) {
+		target, err := notificationTarget(&Notification{Type: NotificationTypeFollow, EntityID: &userID})
+		require.NoError(t, err)
+		assert.Equal(t, NotificationTargetUser, target.Kind)
+		assert.Equal(t, &userID, target.UserID)
+	})
+
+	t.Run("new device links to sessions", func(t *testing.T) {
+		sessionID := uuid.New()
+		target, err := notificationTarget(&Notification{Type: NotificationTypeNewDeviceLogin, EntityID: &sessionID})
+		require.NoError(t, err)
+		assert.Equal(t, NotificationTargetSession, target.Kind)
+		assert.Equal(t, "/settings/sessions", target.Path)
+	})
+
+	t.Run("missing entity", func(t *testing.T) {
+		_, err := notificationTarget(&Notification{Type: NotificationTypeLike})
+		assert.Error(t, err)
+	})
+
+	t.Run("unknown type", func(t *testing.T) {
+		_, err := notificationTarget(&Notification{Type: NotificationType("digest"), EntityID: &postID})
+		assert.Error(t, err)
+	})
+}
+
+func TestOutboxRetryDelay(t *testing.T) {
+	assert.Equal(t, 5*time.Second, outboxRetryDelay(1))
+	assert.Equal(t, 10*time.Second, outboxRetryDelay(2))
+	assert.Equal(t, 40*time.Second, outboxRetryDelay(4))
+	assert.Equal(t, time.Hour, outboxRetryDelay(outboxMaxAttempts+20))
+}
+
+func TestHandleOutboxEventRejectsIncompleteEvents(t *testing.T) {
+	s := &NotificationService{}
+	actorID := uuid.New()
+
+	for _, event := range []OutboxEvent{
+		{Type: NotificationTypeLike, ActorID: actorID},
+		{Type: NotificationTypeComment, ActorID: actorID, PostID: &actorID},
+		{Type: NotificationTypeAnswerAccepted, ActorID: actorID, PostID: &actorID, CommentID: &actorID},
+		{Type: NotificationTypeFollow, ActorID: actorID},
+		{Type: NotificationType("digest"), ActorID: actorID},
+	} {
+		assert.Error(t, s.handleOutboxEvent(context.Background(), event), event.Type)
+	}
+}
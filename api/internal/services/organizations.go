@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// DefaultOrganizationID is the organization created by the migration that
+// introduced them. Existing data and sign-ups that don't name an
+// organization belong to it.
+var DefaultOrganizationID = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+// orgSlugRe matches organization slugs, which clients send in the
+// X-Organization header
+var orgSlugRe = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,49}$`)
+
+// Organization is a school; users only see users, posts and courses of
+// their own organization
+type Organization struct {
+	ID        uuid.UUID `json:"id"`
+	Slug      string    `json:"slug"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateOrganizationRequest struct {
+	Slug string `json:"slug" validate:"required,min=2,max=50"`
+	Name string `json:"name" validate:"required,max=200"`
+}
+
+// ValidOrganizationSlug reports whether slug can name an organization
+func ValidOrganizationSlug(slug string) bool {
+	return orgSlugRe.MatchString(slug)
+}
+
+// GetOrganizationBySlug fails with "organization not found" for unknown slugs
+func (s *AuthService) GetOrganizationBySlug(ctx context.Context, slug string) (*Organization, error) {
+	var org Organization
+	err := s.db.QueryRow(ctx, `
+		SELECT id, slug, name, created_at FROM organizations WHERE slug = $1`, slug).Scan(
+		&org.ID, &org.Slug, &org.Name, &org.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("organization not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+	return &org, nil
+}
+
+func (s *AuthService) GetOrganizations(ctx context.Context) ([]*Organization, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT id, slug, name, created_at FROM organizations ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organizations: %w", err)
+	}
+	defer rows.Close()
+
+	var orgs []*Organization
+	for rows.Next() {
+		var org Organization
+		if err := rows.Scan(&org.ID, &org.Slug, &org.Name, &org.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan organization: %w", err)
+		}
+		orgs = append(orgs, &org)
+	}
+	return orgs, rows.Err()
+}
+
+func (s *AuthService) CreateOrganization(ctx context.Context, req CreateOrganizationRequest) (*Organization, error) {
+	if !ValidOrganizationSlug(req.Slug) {
+		return nil, fmt.Errorf("invalid organization slug")
+	}
+
+	var org Organization
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO organizations (slug, name) VALUES ($1, $2)
+		RETURNING id, slug, name, created_at`, req.Slug, req.Name).Scan(
+		&org.ID, &org.Slug, &org.Name, &org.CreatedAt)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return nil, fmt.Errorf("organization already exists")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create organization: %w", err)
+	}
+	return &org, nil
+}
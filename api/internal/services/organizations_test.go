@@ -0,0 +1,27 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidOrganizationSlug(t *testing.T) {
+	tests := []struct {
+		slug string
+		want bool
+	}{
+		{"default", true},
+		{"school-42", true},
+		{"a", false},
+		{"-school", false},
+		{"School", false},
+		{"my_school", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.slug, func(t *testing.T) {
+			assert.Equal(t, tt.want, ValidOrganizationSlug(tt.slug))
+		})
+	}
+}
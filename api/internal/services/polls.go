@@ -0,0 +1,224 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const maxPollDuration = 30 * 24 * time.Hour
+
+type CreatePollRequest struct {
+	Options   []string   `json:"options" validate:"required,min=2,max=10,dive,required,min=1,max=100"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // open until deleted when nil
+}
+
+type VotePollRequest struct {
+	OptionID uuid.UUID `json:"option_id" validate:"required"`
+}
+
+type Poll struct {
+	Options       []*PollOption `json:"options"`
+	ExpiresAt     *time.Time    `json:"expires_at,omitempty"`
+	Closed        bool          `json:"closed"`
+	TotalVotes    int           `json:"total_votes"`
+	VotedOptionID *uuid.UUID    `json:"voted_option_id,omitempty"`
+}
+
+type PollOption struct {
+	ID      uuid.UUID `json:"id"`
+	Text    string    `json:"text"`
+	Votes   int       `json:"votes"`
+	Percent int       `json:"percent"`
+}
+
+func validatePollExpiry(expiresAt *time.Time, now time.Time) error {
+	if expiresAt == nil {
+		return nil
+	}
+	if !expiresAt.After(now) || expiresAt.Sub(now) > maxPollDuration {
+		return fmt.Errorf("invalid poll expiry")
+	}
+	return nil
+}
+
+// createPoll inserts the poll for a new post inside the post's transaction
+func createPoll(ctx context.Context, tx pgx.Tx, postID uuid.UUID, req CreatePollRequest) (*Poll, error) {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO polls (post_id, expires_at) VALUES ($1, $2)`, postID, req.ExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create poll: %w", err)
+	}
+
+	poll := &Poll{ExpiresAt: req.ExpiresAt, Options: make([]*PollOption, 0, len(req.Options))}
+	for i, text := range req.Options {
+		option := &PollOption{Text: text}
+		err := tx.QueryRow(ctx, `
+			INSERT INTO poll_options (post_id, position, text)
+			VALUES ($1, $2, $3)
+			RETURNING id`, postID, i, text).Scan(&option.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create poll option: %w", err)
+		}
+		poll.Options = append(poll.Options, option)
+	}
+
+	return poll, nil
+}
+
+// VotePoll records the user's vote and returns the updated results. Each
+// user votes once; votes cannot be changed.
+func (s *PostsService) VotePoll(ctx context.Context, userID, postID, optionID uuid.UUID) (*Poll, error) {
+	if err := s.checkPostVisible(ctx, postID, userID); err != nil {
+		return nil, err
+	}
+
+	var expiresAt *time.Time
+	var validOption bool
+	err := s.db.QueryRow(ctx, `
+		SELECT pl.expires_at,
+		       EXISTS(SELECT 1 FROM poll_options o WHERE o.id = $2 AND o.post_id = pl.post_id)
+		FROM polls pl
+		WHERE pl.post_id = $1`, postID, optionID).Scan(&expiresAt, &validOption)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("poll not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get poll: %w", err)
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("poll closed")
+	}
+	if !validOption {
+		return nil, fmt.Errorf("invalid poll option")
+	}
+
+	result, err := s.db.Exec(ctx, `
+		INSERT INTO poll_votes (post_id, user_id, option_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (post_id, user_id) DO NOTHING`, postID, userID, optionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to vote: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return nil, fmt.Errorf("already voted")
+	}
+
+	polls, err := getPolls(ctx, s.db, []uuid.UUID{postID}, userID)
+	if err != nil {
+		return nil, err
+	}
+	return polls[postID], nil
+}
+
+const pollsQuery = `
+	SELECT o.post_id, pl.expires_at, o.id, o.text, COUNT(v.user_id) AS votes,
+	       (SELECT mv.option_id FROM poll_votes mv WHERE mv.post_id = o.post_id AND mv.user_id = $2) AS voted
+	FROM poll_options o
+	JOIN polls pl ON pl.post_id = o.post_id
+	LEFT JOIN poll_votes v ON v.option_id = o.id
+	WHERE o.post_id = ANY($1)
+	GROUP BY o.post_id, pl.expires_at, o.id, o.position, o.text
+	ORDER BY o.post_id, o.position`
+
+// getPolls loads results for the polls attached to postIDs, keyed by post.
+// viewerID may be uuid.Nil, in which case VotedOptionID is never set.
+func getPolls(ctx context.Context, db *pgxpool.Pool, postIDs []uuid.UUID, viewerID uuid.UUID) (map[uuid.UUID]*Poll, error) {
+	if len(postIDs) == 0 {
+		return map[uuid.UUID]*Poll{}, nil
+	}
+
+	rows, err := db.Query(ctx, pollsQuery, postIDs, viewerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get polls: %w", err)
+	}
+	return scanPolls(rows)
+}
+
+func scanPolls(rows pgx.Rows) (map[uuid.UUID]*Poll, error) {
+	defer rows.Close()
+
+	polls := make(map[uuid.UUID]*Poll)
+	for rows.Next() {
+		var postID uuid.UUID
+		var expiresAt *time.Time
+		var voted pgtype.UUID
+		option := &PollOption{}
+		if err := rows.Scan(&postID, &expiresAt, &option.ID, &option.Text, &option.Votes, &voted); err != nil {
+			return nil, fmt.Errorf("failed to scan poll option: %w", err)
+		}
+
+		poll, ok := polls[postID]
+		if !ok {
+			poll = &Poll{ExpiresAt: expiresAt}
+			if voted.Valid {
+				votedID := uuid.UUID(voted.Bytes)
+				poll.VotedOptionID = &votedID
+			}
+			polls[postID] = poll
+		}
+		poll.Options = append(poll.Options, option)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read polls: %w", err)
+	}
+
+	now := time.Now()
+	for _, poll := range polls {
+		poll.tally(now)
+	}
+	return polls, nil
+}
+
+// tally fills in totals, percentages and whether the poll has closed
+func (p *Poll) tally(now time.Time) {
+	votes := make([]int, len(p.Options))
+	p.TotalVotes = 0
+	for i, option := range p.Options {
+		votes[i] = option.Votes
+		p.TotalVotes += option.Votes
+	}
+	for i, percent := range pollPercentages(votes) {
+		p.Options[i].Percent = percent
+	}
+	p.Closed = p.ExpiresAt != nil && !p.ExpiresAt.After(now)
+}
+
+// pollPercentages converts vote counts to whole percentages that add up to
+// 100, giving leftover points to the options with the largest remainders
+func pollPercentages(votes []int) []int {
+	percents := make([]int, len(votes))
+	total := 0
+	for _, v := range votes {
+		total += v
+	}
+	if total == 0 {
+		return percents
+	}
+
+	remainders := make([]int, len(votes))
+	assigned := 0
+	for i, v := range votes {
+		percents[i] = v * 100 / total
+		remainders[i] = v * 100 % total
+		assigned += percents[i]
+	}
+
+	for ; assigned < 100; assigned++ {
+		best := -1
+		for i := range votes {
+			if best == -1 || remainders[i] > remainders[best] {
+				best = i
+			}
+		}
+		percents[best]++
+		remainders[best] = -1
+	}
+	return percents
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPollPercentages(t *testing.T) {
+	tests := []struct {
+		name  string
+		votes []int
+		want  []int
+	}{
+		{"no votes", []int{0, 0, 0}, []int{0, 0, 0}},
+		{"even split", []int{1, 1}, []int{50, 50}},
+		{"thirds round to 100", []int{1, 1, 1}, []int{34, 33, 33}},
+		{"largest remainder wins", []int{2, 1, 0}, []int{67, 33, 0}},
+		{"single option", []int{5}, []int{100}},
+		{"uneven", []int{7, 2, 1}, []int{70, 20, 10}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, pollPercentages(tt.votes))
+		})
+	}
+}
+
+func TestValidatePollExpiry(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+
+	assert.NoError(t, validatePollExpiry(nil, now))
+	assert.NoError(t, validatePollExpiry(at(time.Hour), now))
+	assert.NoError(t, validatePollExpiry(at(maxPollDuration), now))
+	assert.Error(t, validatePollExpiry(at(0), now))
+	assert.Error(t, validatePollExpiry(at(-time.Hour), now))
+	assert.Error(t, validatePollExpiry(at(maxPollDuration+time.Second), now))
+}
+
+func TestPollTally(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Minute)
+
+	poll := &Poll{
+		ExpiresAt: &past,
+		Options:   []*PollOption{{Text: "a", Votes: 3}, {Text: "b", Votes: 1}},
+	}
+	poll.tally(now)
+
+	assert.Equal(t, 4, poll.TotalVotes)
+	assert.True(t, poll.Closed)
+	assert.Equal(t, 75, poll.Options[0].Percent)
+	assert.Equal(t, 25, poll.Options[1].Percent)
+}
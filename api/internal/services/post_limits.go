@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// MaxPostChars caps every post length limit, configured or not
+const MaxPostChars = 50000
+
+// PostLimits bound a post's length in characters (runes, not bytes) and
+// words; MaxWords 0 means no word limit
+type PostLimits struct {
+	MaxChars int `json:"max_chars"`
+	MaxWords int `json:"max_words"`
+}
+
+// PostLimitRule is a limit an organization set, for one course, one post
+// type, both or neither
+type PostLimitRule struct {
+	ID        uuid.UUID  `json:"id"`
+	CourseID  *uuid.UUID `json:"course_id,omitempty"`
+	PostType  *string    `json:"post_type,omitempty"`
+	MaxChars  int        `json:"max_chars"`
+	MaxWords  int        `json:"max_words"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+type SetPostLimitRequest struct {
+	CourseID *uuid.UUID `json:"course_id,omitempty"`
+	PostType string     `json:"post_type,omitempty" validate:"omitempty,oneof=post question"`
+	MaxChars int        `json:"max_chars" validate:"required,min=1,max=50000"`
+	MaxWords int        `json:"max_words,omitempty" validate:"min=0,max=50000"`
+}
+
+// PostLimitsService decides how long posts may be. Organizations override
+// the server defaults per course and post type; the most specific rule wins.
+type PostLimitsService struct {
+	db       *pgxpool.Pool
+	defaults PostLimits
+}
+
+func NewPostLimitsService(db *pgxpool.Pool, defaults PostLimits) *PostLimitsService {
+	return &PostLimitsService{
+		db:       db,
+		defaults: defaults,
+	}
+}
+
+// Resolve returns the limits for a post of postType by userID, in courseID
+// if not nil
+func (s *PostLimitsService) Resolve(ctx context.Context, userID uuid.UUID, courseID *uuid.UUID, postType string) (PostLimits, error) {
+	if s == nil {
+		return PostLimits{MaxChars: MaxPostChars}, nil
+	}
+
+	limits := s.defaults
+	err := s.db.QueryRow(ctx, `
+		SELECT max_chars, max_words FROM post_limits
+		WHERE org_id = (SELECT org_id FROM users WHERE id = $1)
+		  AND (course_id IS NULL OR course_id = $2)
+		  AND (post_type IS NULL OR post_type = $3)
+		ORDER BY course_id IS NULL, post_type IS NULL
+		LIMIT 1`, userID, courseID, postType).Scan(&limits.MaxChars, &limits.MaxWords)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return PostLimits{}, fmt.Errorf("failed to get post limits: %w", err)
+	}
+	return limits, nil
+}
+
+// Capabilities returns the limits for each post type, for clients to show
+// counters
+func (s *PostLimitsService) Capabilities(ctx context.Context, userID uuid.UUID, courseID *uuid.UUID) (map[string]PostLimits, error) {
+	capabilities := make(map[string]PostLimits)
+	for _, postType := range []string{PostTypePost, PostTypeQuestion} {
+		limits, err := s.Resolve(ctx, userID, courseID, postType)
+		if err != nil {
+			return nil, err
+		}
+		capabilities[postType] = limits
+	}
+	return capabilities, nil
+}
+
+// List returns orgID's rules
+func (s *PostLimitsService) List(ctx context.Context, orgID uuid.UUID) ([]*PostLimitRule, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT id, course_id, post_type, max_chars, max_words, updated_at
+		FROM post_limits
+		WHERE org_id = $1
+		ORDER BY course_id NULLS FIRST, post_type NULLS FIRST`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post limits: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []*PostLimitRule{}
+	for rows.Next() {
+		var rule PostLimitRule
+		if err := rows.Scan(&rule.ID, &rule.CourseID, &rule.PostType, &rule.MaxChars, &rule.MaxWords, &rule.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan post limit: %w", err)
+		}
+		rules = append(rules, &rule)
+	}
+	return rules, rows.Err()
+}
+
+// Set creates or replaces orgID's rule for the request's course and post
+// type. The course must belong to orgID.
+func (s *PostLimitsService) Set(ctx context.Context, orgID uuid.UUID, req SetPostLimitRequest) (*PostLimitRule, error) {
+	if req.CourseID != nil {
+		var ok bool
+		err := s.db.QueryRow(ctx, `
+			SELECT EXISTS(SELECT 1 FROM courses WHERE id = $1 AND org_id = $2)`, *req.CourseID, orgID).Scan(&ok)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check course: %w", err)
+		}
+		if !ok {
+			return nil, fmt.Errorf("course not found")
+		}
+	}
+
+	var postType *string
+	if req.PostType != "" {
+		postType = &req.PostType
+	}
+	rule := PostLimitRule{CourseID: req.CourseID, PostType: postType}
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO post_limits (org_id, course_id, post_type, max_chars, max_words)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (org_id, course_id, post_type)
+		DO UPDATE SET max_chars = EXCLUDED.max_chars, max_words = EXCLUDED.max_words, updated_at = now()
+		RETURNING id, max_chars, max_words, updated_at`,
+		orgID, req.CourseID, postType, req.MaxChars, req.MaxWords).Scan(
+		&rule.ID, &rule.MaxChars, &rule.MaxWords, &rule.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set post limit: %w", err)
+	}
+	return &rule, nil
+}
+
+// Delete removes one of orgID's rules
+func (s *PostLimitsService) Delete(ctx context.Context, orgID, ruleID uuid.UUID) error {
+	result, err := s.db.Exec(ctx, `DELETE FROM post_limits WHERE id = $1 AND org_id = $2`, ruleID, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to delete post limit: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("post limit not found")
+	}
+	return nil
+}
+
+// check fails with "post is too long" or "post has too many words" if text
+// is over limits
+func (l PostLimits) check(text string) error {
+	if utf8.RuneCountInString(text) > l.MaxChars {
+		return fmt.Errorf("post is too long")
+	}
+	if l.MaxWords > 0 && len(strings.Fields(text)) > l.MaxWords {
+		return fmt.Errorf("post has too many words")
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostLimitsCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits PostLimits
+		text   string
+		want   string
+	}{
+		{"within limit", PostLimits{MaxChars: 20}, "hello world", ""},
+		{"counts runes, not bytes", PostLimits{MaxChars: 6}, "Сәлем!", ""},
+		{"too long", PostLimits{MaxChars: 5}, "Сәлем!", "post is too long"},
+		{"no word limit", PostLimits{MaxChars: 100}, "one two three four", ""},
+		{"within word limit", PostLimits{MaxChars: 100, MaxWords: 4}, "one two  three\nfour", ""},
+		{"too many words", PostLimits{MaxChars: 100, MaxWords: 3}, "one two three four", "post has too many words"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.limits.check(tt.text)
+			if tt.want == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostLimitsResolveWithoutService(t *testing.T) {
+	var s *PostLimitsService
+	limits, err := s.Resolve(context.Background(), uuid.New(), nil, PostTypePost)
+	require.NoError(t, err)
+	assert.Equal(t, PostLimits{MaxChars: MaxPostChars}, limits)
+}
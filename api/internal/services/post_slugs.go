@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	base62Alphabet = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+	postSlugWords   = 6
+	postSlugMaxText = 60
+)
+
+// postSlug builds a post's slug from its sequence number and the first words
+// of its text, e.g. "4c-intro-to-go". Only the base62 part identifies the
+// post.
+func postSlug(seq int64, text string) string {
+	slug := encodeBase62(seq)
+
+	text = urlRe.ReplaceAllString(text, " ")
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	length := 0
+	for i, word := range words {
+		if i == postSlugWords || length+len(word) > postSlugMaxText {
+			break
+		}
+		slug += "-" + word
+		length += len(word) + 1
+	}
+	return slug
+}
+
+// parsePostSlug returns the sequence number encoded at the start of a slug
+func parsePostSlug(slug string) (int64, bool) {
+	id, _, _ := strings.Cut(slug, "-")
+	return decodeBase62(id)
+}
+
+func encodeBase62(n int64) string {
+	if n <= 0 {
+		return "0"
+	}
+
+	var buf [11]byte
+	i := len(buf)
+	for n > 0 {
+		i--
+		buf[i] = base62Alphabet[n%62]
+		n /= 62
+	}
+	return string(buf[i:])
+}
+
+func decodeBase62(s string) (int64, bool) {
+	if s == "" {
+		return 0, false
+	}
+
+	var n int64
+	for _, c := range []byte(s) {
+		digit := strings.IndexByte(base62Alphabet, c)
+		if digit < 0 || n > (math.MaxInt64-int64(digit))/62 {
+			return 0, false
+		}
+		n = n*62 + int64(digit)
+	}
+	return n, true
+}
+
+// ResolvePostSlug returns the ID of the post a slug points to. The words
+// after the base62 part are ignored, so links keep working after edits.
+func (s *PostsService) ResolvePostSlug(ctx context.Context, slug string) (uuid.UUID, error) {
+	seq, ok := parsePostSlug(slug)
+	if !ok {
+		return uuid.Nil, fmt.Errorf("post not found")
+	}
+
+	var postID uuid.UUID
+	err := s.db.QueryRow(ctx, `SELECT id FROM posts WHERE seq = $1`, seq).Scan(&postID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, fmt.Errorf("post not found")
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to resolve post slug: %w", err)
+	}
+	return postID, nil
+}
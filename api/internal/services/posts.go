@@ -2,33 +2,60 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"regexp"
-	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"bailanysta/api/internal/pkg/coalesce"
+	"bailanysta/api/internal/pkg/markdown"
 )
 
 type PostsService struct {
 	db                   *pgxpool.Pool
 	notificationsService *NotificationService
+	contentFilter        *ContentFilterService
+	gifs                 *GIFService
+	abuse                *AbuseService
+	duplicates           *DuplicateService
+	postLimits           *PostLimitsService
+	detailComments       int
+	feedFanout           bool
+	postDetails          *coalesce.Group[*Post]
 }
 
 type Post struct {
-	ID           uuid.UUID    `json:"id"`
-	AuthorID     uuid.UUID    `json:"author_id"`
-	Text         string       `json:"text"`
-	CourseID     *uuid.UUID   `json:"course_id,omitempty"`
-	ModuleID     *uuid.UUID   `json:"module_id,omitempty"`
-	CreatedAt    time.Time    `json:"created_at"`
-	UpdatedAt    time.Time    `json:"updated_at"`
-	LikeCount    int          `json:"like_count"`
-	CommentCount int          `json:"comment_count"`
-	Author       UserResponse `json:"author,omitempty"`
-	IsLiked      bool         `json:"is_liked"`
+	ID            uuid.UUID      `json:"id"`
+	AuthorID      uuid.UUID      `json:"author_id"`
+	Text          string         `json:"text"`
+	TextHTML      string         `json:"text_html"`
+	CourseID      *uuid.UUID     `json:"course_id,omitempty"`
+	ModuleID      *uuid.UUID     `json:"module_id,omitempty"`
+	GroupID       *uuid.UUID     `json:"group_id,omitempty"`
+	IsAIGenerated bool           `json:"is_ai_generated"`
+	Edited        bool           `json:"edited"`             // text changed since posting; see GetPostHistory
+	Slug          string         `json:"slug"`               // resolves like the ID in GET /posts/{id}
+	Language      *string        `json:"language,omitempty"` // detected en, kk or ru
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	LikeCount     int            `json:"like_count"`
+	CommentCount  int            `json:"comment_count"`
+	Author        UserResponse   `json:"author,omitempty"`
+	IsLiked       bool           `json:"is_liked"`
+	ViewCount     *int64         `json:"view_count,omitempty"` // only exposed to the author
+	Hashtags      []string       `json:"hashtags,omitempty"`
+	Comments      []*Comment     `json:"comments,omitempty"` // first comments, on the detail view only
+	Poll          *Poll          `json:"poll,omitempty"`
+	LinkPreviews  []*LinkPreview `json:"link_previews,omitempty"`
+	Question      *Question      `json:"question,omitempty"`
+	GIF           *GIF           `json:"gif,omitempty"`
+	// DuplicateOf is the earlier post a newly created one nearly copies, to
+	// warn its author; it is only set in the create response
+	DuplicateOf *uuid.UUID `json:"duplicate_of,omitempty"`
 }
 
 type Comment struct {
@@ -36,8 +63,27 @@ type Comment struct {
 	PostID    uuid.UUID    `json:"post_id"`
 	AuthorID  uuid.UUID    `json:"author_id"`
 	Text      string       `json:"text"`
+	TextHTML  string       `json:"text_html"`
 	CreatedAt time.Time    `json:"created_at"`
 	Author    UserResponse `json:"author,omitempty"`
+	// VerifiedAnswer is set on the comment a teacher marked as answering a
+	// course post
+	VerifiedAnswer bool `json:"verified_answer"`
+	GIF            *GIF `json:"gif,omitempty"`
+}
+
+// PostRevision is a post's text as it was until an edit replaced it
+type PostRevision struct {
+	ID         uuid.UUID `json:"id"`
+	Text       string    `json:"text"`
+	TextHTML   string    `json:"text_html"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
+
+// LikeState is a post's like count and whether the current user likes it
+type LikeState struct {
+	LikeCount int  `json:"like_count"`
+	IsLiked   bool `json:"is_liked"`
 }
 
 type Like struct {
@@ -46,31 +92,97 @@ type Like struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// Post text is checked against PostLimitsService in the service; the tags
+// only cap it at MaxPostChars
 type CreatePostRequest struct {
-	Text     string     `json:"text" validate:"required,min=1,max=5000"`
-	CourseID *uuid.UUID `json:"course_id,omitempty"`
-	ModuleID *uuid.UUID `json:"module_id,omitempty"`
+	Text     string             `json:"text" validate:"required,min=1,max=50000"`
+	CourseID *uuid.UUID         `json:"course_id,omitempty"`
+	ModuleID *uuid.UUID         `json:"module_id,omitempty"`
+	GroupID  *uuid.UUID         `json:"group_id,omitempty"` // such posts only appear in the group's feed
+	Poll     *CreatePollRequest `json:"poll,omitempty"`
+	// Type is post (the default) or question; only questions take a bounty
+	Type   string `json:"type,omitempty" validate:"omitempty,oneof=post question"`
+	Bounty int    `json:"bounty,omitempty" validate:"min=0,max=500"`
+	// GIFID is the id of a GET /gifs/search result
+	GIFID string `json:"gif_id,omitempty" validate:"omitempty,max=100"`
+	// AIGenerationID is the generation_id returned by /ai/generate-post
+	AIGenerationID *uuid.UUID `json:"ai_generation_id,omitempty"`
 }
 
 type UpdatePostRequest struct {
-	Text     string     `json:"text" validate:"required,min=1,max=5000"`
+	Text     string     `json:"text" validate:"required,min=1,max=50000"`
 	CourseID *uuid.UUID `json:"course_id,omitempty"`
 	ModuleID *uuid.UUID `json:"module_id,omitempty"`
 }
 
 type CreateCommentRequest struct {
-	Text string `json:"text" validate:"required,min=1,max=1000"`
+	Text  string `json:"text" validate:"required,min=1,max=1000"`
+	GIFID string `json:"gif_id,omitempty" validate:"omitempty,max=100"`
 }
 
-func NewPostsService(db *pgxpool.Pool, notificationsService *NotificationService) *PostsService {
+type RecordImpressionsRequest struct {
+	PostIDs []uuid.UUID `json:"post_ids" validate:"required,min=1,max=100"`
+}
+
+// ErrPostNotFound is returned for posts that don't exist and for posts the
+// viewer may not see, which must look the same
+var ErrPostNotFound = errors.New("post not found")
+
+type BatchPostsRequest struct {
+	PostIDs []uuid.UUID `json:"post_ids" validate:"required,min=1,max=100"`
+}
+
+// NewPostsService creates the service; detailComments is how many comments
+// GetPostByID includes (0 for none). contentFilter, gifService, duplicates
+// and postLimits may be nil; without postLimits posts are only held to
+// MaxPostChars. With
+// feedFanout, new posts are written to followers' feed_items. Post details
+// are cached for detailCacheTTL.
+func NewPostsService(db *pgxpool.Pool, notificationsService *NotificationService, contentFilter *ContentFilterService, gifService *GIFService, abuse *AbuseService, duplicates *DuplicateService, postLimits *PostLimitsService, detailComments int, feedFanout bool, detailCacheTTL time.Duration) *PostsService {
 	return &PostsService{
 		db:                   db,
 		notificationsService: notificationsService,
+		contentFilter:        contentFilter,
+		gifs:                 gifService,
+		abuse:                abuse,
+		duplicates:           duplicates,
+		postLimits:           postLimits,
+		detailComments:       detailComments,
+		feedFanout:           feedFanout,
+		postDetails:          coalesce.New[*Post]("post_detail", detailCacheTTL),
 	}
 }
 
 func (s *PostsService) CreatePost(ctx context.Context, userID uuid.UUID, req CreatePostRequest) (*Post, error) {
 	var post Post
+	var duplicateDistance int
+
+	postType := req.Type
+	if postType == "" {
+		postType = PostTypePost
+	}
+	limits, err := s.postLimits.Resolve(ctx, userID, req.CourseID, postType)
+	if err != nil {
+		return nil, err
+	}
+	if err := limits.check(req.Text); err != nil {
+		return nil, err
+	}
+
+	if req.Poll != nil {
+		if err := validatePollExpiry(req.Poll.ExpiresAt, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	hits, err := s.filterContent(ctx, userID, ContentTypePost, req.Text)
+	if err != nil {
+		return nil, err
+	}
+	gif, err := s.resolveGIF(ctx, req.GIFID)
+	if err != nil {
+		return nil, err
+	}
 
 	// Extract hashtags from text
 	hashtags := extractHashtags(req.Text)
@@ -82,26 +194,97 @@ func (s *PostsService) CreatePost(ctx context.Context, userID uuid.UUID, req Cre
 	}
 	defer tx.Rollback(ctx)
 
+	// A generation labels one post; it is used up here
+	aiGenerated := false
+	if req.AIGenerationID != nil {
+		result, err := tx.Exec(ctx, `
+			DELETE FROM ai_post_generations WHERE id = $1 AND user_id = $2`, *req.AIGenerationID, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to use AI generation: %w", err)
+		}
+		if result.RowsAffected() == 0 {
+			return nil, fmt.Errorf("AI generation not found")
+		}
+		aiGenerated = true
+	}
+
+	if req.CourseID != nil {
+		if err := checkPostCourse(ctx, tx, *req.CourseID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.GroupID != nil {
+		var member bool
+		err = tx.QueryRow(ctx, `
+			SELECT EXISTS(
+			    SELECT 1 FROM group_members
+			    WHERE group_id = $1 AND user_id = $2 AND status = 'active'
+			)`, *req.GroupID, userID).Scan(&member)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check group membership: %w", err)
+		}
+		if !member {
+			return nil, fmt.Errorf("not a group member")
+		}
+	}
+
+	if req.Bounty > 0 {
+		if postType != PostTypeQuestion {
+			return nil, fmt.Errorf("only questions can have a bounty")
+		}
+		if err = reserveBounty(ctx, tx, userID, req.Bounty); err != nil {
+			return nil, err
+		}
+	}
+
+	if gif != nil {
+		if err = saveGIF(ctx, tx, gif); err != nil {
+			return nil, err
+		}
+	}
+
+	var fingerprint *int64
+	if hash, ok := simhash(req.Text); ok {
+		fingerprint = &hash
+		post.DuplicateOf, duplicateDistance, err = s.duplicates.findOriginal(ctx, tx, userID, hash)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The slug embeds seq, so take it from the sequence first
+	var seq int64
+	err = tx.QueryRow(ctx, `SELECT nextval(pg_get_serial_sequence('posts', 'seq'))`).Scan(&seq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create post: %w", err)
+	}
+
 	// Create post
 	err = tx.QueryRow(ctx, `
-		INSERT INTO posts (author_id, text, course_id, module_id)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, author_id, text, course_id, module_id, created_at, updated_at`,
-		userID, req.Text, req.CourseID, req.ModuleID).Scan(
-		&post.ID, &post.AuthorID, &post.Text, &post.CourseID, &post.ModuleID, &post.CreatedAt, &post.UpdatedAt)
+		INSERT INTO posts (author_id, text, course_id, module_id, is_ai_generated, seq, slug, org_id, group_id, language, post_type, bounty, gif_id, simhash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, (SELECT org_id FROM users WHERE id = $1), $8, NULLIF($9, ''), $10, $11, NULLIF($12, ''), $13)
+		RETURNING id, author_id, text, course_id, module_id, group_id, is_ai_generated, edited_at IS NOT NULL, slug, language, created_at, updated_at`,
+		userID, req.Text, req.CourseID, req.ModuleID, aiGenerated, seq, postSlug(seq, req.Text), req.GroupID, detectPostLanguage(req.Text), postType, req.Bounty, req.GIFID, fingerprint).Scan(
+		&post.ID, &post.AuthorID, &post.Text, &post.CourseID, &post.ModuleID, &post.GroupID, &post.IsAIGenerated, &post.Edited, &post.Slug, &post.Language, &post.CreatedAt, &post.UpdatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create post: %w", err)
 	}
+	post.TextHTML = RenderPostText(post.Slug, post.Text)
+	if postType == PostTypeQuestion {
+		post.Question = &Question{Bounty: req.Bounty}
+	}
+	post.GIF = gif
 
 	// Add hashtags
 	for _, hashtag := range hashtags {
 		// Insert or get hashtag
 		var hashtagID uuid.UUID
 		err = tx.QueryRow(ctx, `
-			INSERT INTO hashtags (tag)
-			VALUES ($1)
+			INSERT INTO hashtags (tag, display)
+			VALUES ($1, $2)
 			ON CONFLICT (tag) DO UPDATE SET tag = EXCLUDED.tag
-			RETURNING id`, hashtag).Scan(&hashtagID)
+			RETURNING id`, CanonicalHashtag(hashtag), hashtag).Scan(&hashtagID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create hashtag: %w", err)
 		}
@@ -115,6 +298,52 @@ func (s *PostsService) CreatePost(ctx context.Context, userID uuid.UUID, req Cre
 		}
 	}
 
+	if err = savePostLinks(ctx, tx, post.ID, req.Text); err != nil {
+		return nil, err
+	}
+
+	if post.DuplicateOf != nil {
+		if err = s.duplicates.record(ctx, tx, post.ID, *post.DuplicateOf, duplicateDistance); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = recordFilterHits(ctx, tx, userID, ContentTypePost, post.ID, hits); err != nil {
+		return nil, err
+	}
+
+	if err = recordActivity(ctx, tx, userID, ActivityPost); err != nil {
+		return nil, err
+	}
+
+	if req.Poll != nil {
+		post.Poll, err = createPoll(ctx, tx, post.ID, *req.Poll)
+		if err != nil {
+			return nil, err
+		}
+		post.Poll.tally(time.Now())
+	}
+
+	// Group posts stay out of home feeds
+	if s.feedFanout && req.GroupID == nil {
+		if err = fanOutPost(ctx, tx, post.ID, userID, post.CreatedAt); err != nil {
+			return nil, err
+		}
+	}
+
+	// Notify followers once the post is committed
+	if s.notificationsService != nil && req.GroupID == nil {
+		err = enqueueNotification(ctx, tx, OutboxEvent{
+			Type:    NotificationTypeNewPost,
+			ActorID: userID,
+			PostID:  &post.ID,
+			Text:    post.Text,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Commit transaction
 	if err = tx.Commit(ctx); err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
@@ -123,39 +352,145 @@ func (s *PostsService) CreatePost(ctx context.Context, userID uuid.UUID, req Cre
 	post.LikeCount = 0
 	post.CommentCount = 0
 
-	// Create notifications for followers
-	if s.notificationsService != nil {
-		err = s.notificationsService.NotifyNewPost(ctx, userID, post.ID, post.Text)
-		if err != nil {
-			// Log error but don't fail the operation
-			fmt.Printf("Failed to create new post notifications: %v\n", err)
-		}
+	return &post, nil
+}
+
+// postVisibleQuery tells whether post $1 exists and viewer $2 may see it:
+// it is in the viewer's organization, its author is neither shadow-banned
+// nor private to the viewer, and it is not in a group the viewer can't read
+const postVisibleQuery = `
+	SELECT EXISTS (
+	  SELECT 1 FROM posts p
+	  JOIN users u ON p.author_id = u.id
+	  WHERE p.id = $1 AND (NOT u.shadow_banned OR u.id = $2)
+	    AND (NOT u.is_private OR u.id = $2 OR EXISTS (SELECT 1 FROM follows fv WHERE fv.follower_id = $2 AND fv.followee_id = u.id))
+	    AND p.org_id = (SELECT org_id FROM users WHERE id = $2)
+	    AND (p.group_id IS NULL OR EXISTS (
+	        SELECT 1 FROM groups g
+	        LEFT JOIN group_members gm ON gm.group_id = g.id AND gm.user_id = $2
+	        WHERE g.id = p.group_id AND (g.privacy = 'open' OR gm.status = 'active')
+	    ))
+	)`
+
+// checkPostVisible fails with "post not found" unless viewerID may see
+// postID, so posts the viewer can't see can't be commented on, liked or
+// voted on either
+func (s *PostsService) checkPostVisible(ctx context.Context, postID, viewerID uuid.UUID) error {
+	var visible bool
+	if err := s.db.QueryRow(ctx, postVisibleQuery, postID, viewerID).Scan(&visible); err != nil {
+		return fmt.Errorf("failed to get post: %w", err)
+	}
+	if !visible {
+		return ErrPostNotFound
 	}
+	return nil
+}
 
+// GetPostByID returns a post with its hashtags, first comments, link previews
+// and poll results as seen by viewerID. Posts outside the viewer's
+// organization are not found. What every viewer sees alike comes from
+// postDetails; the viewer's own part is sent as one batch.
+func (s *PostsService) GetPostByID(ctx context.Context, postID, viewerID uuid.UUID) (*Post, error) {
+	shared, err := s.postDetails.Do(ctx, postID.String(), func(ctx context.Context) (*Post, error) {
+		return s.getPostDetail(ctx, postID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	post := *shared
+
+	var visible bool
+	batch := &pgx.Batch{}
+	batch.Queue(postVisibleQuery, postID, viewerID).QueryRow(func(row pgx.Row) error {
+		return row.Scan(&visible)
+	})
+	batch.Queue(pollsQuery, []uuid.UUID{postID}, viewerID).Query(func(rows pgx.Rows) error {
+		polls, err := scanPolls(rows)
+		post.Poll = polls[postID]
+		return err
+	})
+	if s.detailComments > 0 {
+		batch.Queue(commentsQuery(CommentSortOldest), postID, s.detailComments, 0, viewerID).Query(func(rows pgx.Rows) error {
+			comments, err := scanComments(rows)
+			post.Comments = comments
+			return err
+		})
+	}
+
+	if err := s.db.SendBatch(ctx, batch).Close(); err != nil {
+		return nil, fmt.Errorf("failed to get post: %w", err)
+	}
+	if !visible {
+		return nil, ErrPostNotFound
+	}
 	return &post, nil
 }
 
-func (s *PostsService) GetPostByID(ctx context.Context, postID uuid.UUID) (*Post, error) {
+// getPostDetail loads the part of a post's detail view that is the same for
+// every viewer: the post, its author and counts, hashtags, link previews,
+// GIF and question state.
+// It does not check whether anyone may see the post.
+func (s *PostsService) getPostDetail(ctx context.Context, postID uuid.UUID) (*Post, error) {
 	var post Post
 	var courseID, moduleID pgtype.UUID
 	var bio, avatarURL pgtype.Text
+	var viewCount int64
 
-	err := s.db.QueryRow(ctx, `
-		SELECT p.id, p.author_id, p.text, p.course_id, p.module_id, p.created_at, p.updated_at,
+	batch := &pgx.Batch{}
+	batch.Queue(`
+		SELECT p.id, p.author_id, p.text, p.course_id, p.module_id, p.group_id, p.is_ai_generated, p.edited_at IS NOT NULL, p.slug, p.language, p.created_at, p.updated_at,
 		       COUNT(DISTINCT l.user_id) as like_count,
 		       COUNT(DISTINCT c.id) as comment_count,
+		       p.view_count,
 		       u.username, u.email, u.bio, u.avatar_url
 		FROM posts p
 		JOIN users u ON p.author_id = u.id
 		LEFT JOIN likes l ON p.id = l.post_id
 		LEFT JOIN comments c ON p.id = c.post_id
 		WHERE p.id = $1
-		GROUP BY p.id, u.username, u.email, u.bio, u.avatar_url`, postID).Scan(
-		&post.ID, &post.AuthorID, &post.Text, &courseID, &moduleID, &post.CreatedAt, &post.UpdatedAt,
-		&post.LikeCount, &post.CommentCount,
-		&post.Author.Username, &post.Author.Email, &bio, &avatarURL)
+		GROUP BY p.id, u.username, u.email, u.bio, u.avatar_url`, postID).QueryRow(func(row pgx.Row) error {
+		return row.Scan(
+			&post.ID, &post.AuthorID, &post.Text, &courseID, &moduleID, &post.GroupID, &post.IsAIGenerated, &post.Edited, &post.Slug, &post.Language, &post.CreatedAt, &post.UpdatedAt,
+			&post.LikeCount, &post.CommentCount, &viewCount,
+			&post.Author.Username, &post.Author.Email, &bio, &avatarURL)
+	})
+	batch.Queue(`
+		SELECT h.display
+		FROM post_hashtags ph
+		JOIN hashtags h ON ph.hashtag_id = h.id
+		WHERE ph.post_id = $1
+		ORDER BY h.tag`, postID).Query(func(rows pgx.Rows) error {
+		tags, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		post.Hashtags = tags
+		return err
+	})
+	batch.Queue(linkPreviewsQuery, []uuid.UUID{postID}).Query(func(rows pgx.Rows) error {
+		previews, err := scanLinkPreviews(rows)
+		post.LinkPreviews = previews[postID]
+		return err
+	})
+	batch.Queue(questionsQuery, []uuid.UUID{postID}).Query(func(rows pgx.Rows) error {
+		questions, err := scanQuestions(rows)
+		post.Question = questions[postID]
+		return err
+	})
+	batch.Queue(postGIFsQuery, []uuid.UUID{postID}).Query(func(rows pgx.Rows) error {
+		gifs, err := scanPostGIFs(rows)
+		post.GIF = gifs[postID]
+		return err
+	})
+
+	err := s.db.SendBatch(ctx, batch).Close()
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrPostNotFound
+	}
 	if err != nil {
-		return nil, fmt.Errorf("post not found: %w", err)
+		return nil, fmt.Errorf("failed to get post: %w", err)
+	}
+	post.ViewCount = &viewCount
+	post.TextHTML = RenderPostText(post.Slug, post.Text)
+	if post.Hashtags == nil {
+		post.Hashtags = []string{}
 	}
 
 	// Convert pgtype to regular types
@@ -173,10 +508,111 @@ func (s *PostsService) GetPostByID(ctx context.Context, postID uuid.UUID) (*Post
 	return &post, nil
 }
 
+// GetPostsByIDs hydrates many posts at once for clients that hold only IDs.
+// Posts are returned in the order requested; missing posts and posts the
+// viewer may not see are left out.
+func (s *PostsService) GetPostsByIDs(ctx context.Context, postIDs []uuid.UUID, viewerID uuid.UUID) ([]*Post, error) {
+	byID := make(map[uuid.UUID]*Post, len(postIDs))
+	var polls map[uuid.UUID]*Poll
+	var previews map[uuid.UUID][]*LinkPreview
+	var questions map[uuid.UUID]*Question
+	var gifs map[uuid.UUID]*GIF
+
+	batch := &pgx.Batch{}
+	batch.Queue(`
+		SELECT p.id, p.author_id, p.text, p.course_id, p.module_id, p.is_ai_generated, p.edited_at IS NOT NULL, p.slug, p.created_at, p.updated_at,
+		       p.like_count,
+		       (SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id) AS comment_count,
+		       u.username, u.email, u.bio, u.avatar_url,
+		       EXISTS(SELECT 1 FROM likes ul WHERE ul.post_id = p.id AND ul.user_id = $2) AS is_liked
+		FROM posts p
+		JOIN users u ON p.author_id = u.id
+		WHERE p.id = ANY($1) AND (NOT u.shadow_banned OR u.id = $2)
+		  AND (NOT u.is_private OR u.id = $2 OR EXISTS (SELECT 1 FROM follows fv WHERE fv.follower_id = $2 AND fv.followee_id = u.id))
+		  AND p.org_id = (SELECT org_id FROM users WHERE id = $2)
+		  AND (p.group_id IS NULL OR EXISTS (
+		      SELECT 1 FROM groups g
+		      LEFT JOIN group_members gm ON gm.group_id = g.id AND gm.user_id = $2
+		      WHERE g.id = p.group_id AND (g.privacy = 'open' OR gm.status = 'active')
+		  ))`,
+		postIDs, viewerID).Query(func(rows pgx.Rows) error {
+		for rows.Next() {
+			var post Post
+			var courseID, moduleID pgtype.UUID
+			var bio, avatarURL pgtype.Text
+
+			err := rows.Scan(
+				&post.ID, &post.AuthorID, &post.Text, &courseID, &moduleID, &post.IsAIGenerated, &post.Edited, &post.Slug, &post.CreatedAt, &post.UpdatedAt,
+				&post.LikeCount, &post.CommentCount,
+				&post.Author.Username, &post.Author.Email, &bio, &avatarURL, &post.IsLiked)
+			if err != nil {
+				return fmt.Errorf("failed to scan post: %w", err)
+			}
+
+			if courseID.Valid {
+				courseUUID := uuid.UUID(courseID.Bytes)
+				post.CourseID = &courseUUID
+			}
+			if moduleID.Valid {
+				moduleUUID := uuid.UUID(moduleID.Bytes)
+				post.ModuleID = &moduleUUID
+			}
+			post.TextHTML = RenderPostText(post.Slug, post.Text)
+			post.Author.Bio = getPgtypeTextValue(bio)
+			post.Author.AvatarURL = getPgtypeTextPtr(avatarURL)
+			byID[post.ID] = &post
+		}
+		return rows.Err()
+	})
+	batch.Queue(pollsQuery, postIDs, viewerID).Query(func(rows pgx.Rows) error {
+		var err error
+		polls, err = scanPolls(rows)
+		return err
+	})
+	batch.Queue(linkPreviewsQuery, postIDs).Query(func(rows pgx.Rows) error {
+		var err error
+		previews, err = scanLinkPreviews(rows)
+		return err
+	})
+	batch.Queue(questionsQuery, postIDs).Query(func(rows pgx.Rows) error {
+		var err error
+		questions, err = scanQuestions(rows)
+		return err
+	})
+	batch.Queue(postGIFsQuery, postIDs).Query(func(rows pgx.Rows) error {
+		var err error
+		gifs, err = scanPostGIFs(rows)
+		return err
+	})
+
+	if err := s.db.SendBatch(ctx, batch).Close(); err != nil {
+		return nil, fmt.Errorf("failed to get posts: %w", err)
+	}
+
+	posts := make([]*Post, 0, len(byID))
+	for _, id := range postIDs {
+		post, ok := byID[id]
+		if !ok {
+			continue
+		}
+		// Each post once, even if its ID was sent twice
+		delete(byID, id)
+
+		post.Poll = polls[id]
+		post.LinkPreviews = previews[id]
+		post.Question = questions[id]
+		post.GIF = gifs[id]
+		posts = append(posts, post)
+	}
+
+	return posts, nil
+}
+
 func (s *PostsService) UpdatePost(ctx context.Context, userID, postID uuid.UUID, req UpdatePostRequest) (*Post, error) {
 	// Check if user owns the post
 	var authorID uuid.UUID
-	err := s.db.QueryRow(ctx, "SELECT author_id FROM posts WHERE id = $1", postID).Scan(&authorID)
+	var postType string
+	err := s.db.QueryRow(ctx, "SELECT author_id, post_type FROM posts WHERE id = $1", postID).Scan(&authorID, &postType)
 	if err != nil {
 		return nil, fmt.Errorf("post not found: %w", err)
 	}
@@ -184,6 +620,14 @@ func (s *PostsService) UpdatePost(ctx context.Context, userID, postID uuid.UUID,
 		return nil, fmt.Errorf("access denied")
 	}
 
+	limits, err := s.postLimits.Resolve(ctx, userID, req.CourseID, postType)
+	if err != nil {
+		return nil, err
+	}
+	if err := limits.check(req.Text); err != nil {
+		return nil, err
+	}
+
 	// Update post
 	var courseID, moduleID uuid.NullUUID
 	if req.CourseID != nil {
@@ -193,17 +637,48 @@ func (s *PostsService) UpdatePost(ctx context.Context, userID, postID uuid.UUID,
 		moduleID = uuid.NullUUID{UUID: *req.ModuleID, Valid: true}
 	}
 
+	var fingerprint *int64
+	if hash, ok := simhash(req.Text); ok {
+		fingerprint = &hash
+	}
+
 	var post Post
-	err = s.db.QueryRow(ctx, `
-		UPDATE posts
-		SET text = $1, course_id = $2, module_id = $3, updated_at = now()
-		WHERE id = $4 AND author_id = $5
-		RETURNING id, author_id, text, course_id, module_id, created_at, updated_at`,
-		req.Text, courseID, moduleID, postID, userID).Scan(
-		&post.ID, &post.AuthorID, &post.Text, &post.CourseID, &post.ModuleID, &post.CreatedAt, &post.UpdatedAt)
+	err = pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		if req.CourseID != nil {
+			if err := checkPostCourse(ctx, tx, *req.CourseID, userID); err != nil {
+				return err
+			}
+		}
+
+		// Keep the old text when it changes; the row lock stops concurrent
+		// edits from losing a revision
+		_, err := tx.Exec(ctx, `
+			INSERT INTO post_revisions (post_id, text)
+			SELECT id, text FROM posts
+			WHERE id = $1 AND text <> $2
+			FOR UPDATE`, postID, req.Text)
+		if err != nil {
+			return fmt.Errorf("failed to save post revision: %w", err)
+		}
+
+		err = tx.QueryRow(ctx, `
+			UPDATE posts
+			SET text = $1, course_id = $2, module_id = $3, language = NULLIF($6, ''), simhash = $7, updated_at = now(),
+			    edited_at = CASE WHEN text <> $1 THEN now() ELSE edited_at END
+			WHERE id = $4 AND author_id = $5
+			RETURNING id, author_id, text, course_id, module_id, is_ai_generated, edited_at IS NOT NULL, slug, language, created_at, updated_at`,
+			req.Text, courseID, moduleID, postID, userID, detectPostLanguage(req.Text), fingerprint).Scan(
+			&post.ID, &post.AuthorID, &post.Text, &post.CourseID, &post.ModuleID, &post.IsAIGenerated, &post.Edited, &post.Slug, &post.Language, &post.CreatedAt, &post.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to update post: %w", err)
+		}
+
+		return savePostLinks(ctx, tx, postID, req.Text)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to update post: %w", err)
+		return nil, err
 	}
+	post.TextHTML = RenderPostText(post.Slug, post.Text)
 
 	// Get counts
 	err = s.db.QueryRow(ctx, `
@@ -216,10 +691,78 @@ func (s *PostsService) UpdatePost(ctx context.Context, userID, postID uuid.UUID,
 	if err != nil {
 		return nil, fmt.Errorf("failed to get counts: %w", err)
 	}
+	s.postDetails.Forget(postID.String())
 
 	return &post, nil
 }
 
+// checkPostCourse fails with "course not found" unless courseID belongs to
+// userID's organization; posts may only be tagged with those courses
+func checkPostCourse(ctx context.Context, tx pgx.Tx, courseID, userID uuid.UUID) error {
+	var ok bool
+	err := tx.QueryRow(ctx, `
+		SELECT EXISTS(
+		    SELECT 1 FROM courses c JOIN users u ON u.org_id = c.org_id
+		    WHERE c.id = $1 AND u.id = $2
+		)`, courseID, userID).Scan(&ok)
+	if err != nil {
+		return fmt.Errorf("failed to check course: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("course not found")
+	}
+	return nil
+}
+
+// GetPostHistory lists a post's earlier texts, newest first. Only the author
+// and moderators of the post's organization may see it.
+func (s *PostsService) GetPostHistory(ctx context.Context, postID, viewerID uuid.UUID) ([]*PostRevision, error) {
+	var authorID uuid.UUID
+	var viewerRole Role
+	var sameOrg bool
+	err := s.db.QueryRow(ctx, `
+		SELECT p.author_id, COALESCE(v.role, ''), COALESCE(p.org_id = v.org_id, false)
+		FROM posts p
+		LEFT JOIN users v ON v.id = $2
+		WHERE p.id = $1`, postID, viewerID).Scan(&authorID, &viewerRole, &sameOrg)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("post not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post: %w", err)
+	}
+	if authorID != viewerID {
+		// Moderators only see the history of their own organization's posts
+		if !sameOrg {
+			return nil, fmt.Errorf("post not found")
+		}
+		if !viewerRole.Can(PermissionModerate) {
+			return nil, fmt.Errorf("access denied")
+		}
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT id, text, replaced_at FROM post_revisions
+		WHERE post_id = $1
+		ORDER BY replaced_at DESC`, postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post history: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []*PostRevision{}
+	for rows.Next() {
+		var revision PostRevision
+		if err := rows.Scan(&revision.ID, &revision.Text, &revision.ReplacedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan post revision: %w", err)
+		}
+		revision.TextHTML = markdown.Render(revision.Text)
+		revisions = append(revisions, &revision)
+	}
+
+	return revisions, rows.Err()
+}
+
 func (s *PostsService) DeletePost(ctx context.Context, userID, postID uuid.UUID) error {
 	// Check if user owns the post
 	var authorID uuid.UUID
@@ -236,13 +779,14 @@ func (s *PostsService) DeletePost(ctx context.Context, userID, postID uuid.UUID)
 	if err != nil {
 		return fmt.Errorf("failed to delete post: %w", err)
 	}
+	s.postDetails.Forget(postID.String())
 
 	return nil
 }
 
 func (s *PostsService) GetUserPosts(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Post, error) {
 	rows, err := s.db.Query(ctx, `
-		SELECT p.id, p.author_id, p.text, p.course_id, p.module_id, p.created_at, p.updated_at,
+		SELECT p.id, p.author_id, p.text, p.course_id, p.module_id, p.is_ai_generated, p.edited_at IS NOT NULL, p.slug, p.created_at, p.updated_at,
 		       COUNT(DISTINCT l.user_id) as like_count,
 		       COUNT(DISTINCT c.id) as comment_count,
 		       u.username, u.email, u.bio, u.avatar_url
@@ -250,7 +794,7 @@ func (s *PostsService) GetUserPosts(ctx context.Context, userID uuid.UUID, limit
 		JOIN users u ON p.author_id = u.id
 		LEFT JOIN likes l ON p.id = l.post_id
 		LEFT JOIN comments c ON p.id = c.post_id
-		WHERE p.author_id = $1
+		WHERE p.author_id = $1 AND p.group_id IS NULL
 		GROUP BY p.id, u.username, u.email, u.bio, u.avatar_url
 		ORDER BY p.created_at DESC
 		LIMIT $2 OFFSET $3`, userID, limit, offset)
@@ -263,12 +807,13 @@ func (s *PostsService) GetUserPosts(ctx context.Context, userID uuid.UUID, limit
 	for rows.Next() {
 		var post Post
 		err := rows.Scan(
-			&post.ID, &post.AuthorID, &post.Text, &post.CourseID, &post.ModuleID, &post.CreatedAt, &post.UpdatedAt,
+			&post.ID, &post.AuthorID, &post.Text, &post.CourseID, &post.ModuleID, &post.IsAIGenerated, &post.Edited, &post.Slug, &post.CreatedAt, &post.UpdatedAt,
 			&post.LikeCount, &post.CommentCount,
 			&post.Author.Username, &post.Author.Email, &post.Author.Bio, &post.Author.AvatarURL)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan post: %w", err)
 		}
+		post.TextHTML = RenderPostText(post.Slug, post.Text)
 		posts = append(posts, &post)
 	}
 
@@ -276,16 +821,57 @@ func (s *PostsService) GetUserPosts(ctx context.Context, userID uuid.UUID, limit
 }
 
 func (s *PostsService) CreateComment(ctx context.Context, userID, postID uuid.UUID, req CreateCommentRequest) (*Comment, error) {
+	if err := s.checkPostVisible(ctx, postID, userID); err != nil {
+		return nil, err
+	}
+	hits, err := s.filterContent(ctx, userID, ContentTypeComment, req.Text)
+	if err != nil {
+		return nil, err
+	}
+	gif, err := s.resolveGIF(ctx, req.GIFID)
+	if err != nil {
+		return nil, err
+	}
+
 	var comment Comment
-	err := s.db.QueryRow(ctx, `
-		INSERT INTO comments (post_id, author_id, text)
-		VALUES ($1, $2, $3)
-		RETURNING id, post_id, author_id, text, created_at`,
-		postID, userID, req.Text).Scan(
-		&comment.ID, &comment.PostID, &comment.AuthorID, &comment.Text, &comment.CreatedAt)
+	err = pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		if gif != nil {
+			if err := saveGIF(ctx, tx, gif); err != nil {
+				return err
+			}
+		}
+		err := tx.QueryRow(ctx, `
+			INSERT INTO comments (post_id, author_id, text, gif_id)
+			VALUES ($1, $2, $3, NULLIF($4, ''))
+			RETURNING id, post_id, author_id, text, created_at`,
+			postID, userID, req.Text, req.GIFID).Scan(
+			&comment.ID, &comment.PostID, &comment.AuthorID, &comment.Text, &comment.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to create comment: %w", err)
+		}
+		if err := recordFilterHits(ctx, tx, userID, ContentTypeComment, comment.ID, hits); err != nil {
+			return err
+		}
+		if err := recordActivity(ctx, tx, userID, ActivityComment); err != nil {
+			return err
+		}
+
+		if s.notificationsService == nil {
+			return nil
+		}
+		return enqueueNotification(ctx, tx, OutboxEvent{
+			Type:      NotificationTypeComment,
+			ActorID:   userID,
+			PostID:    &postID,
+			CommentID: &comment.ID,
+			Text:      req.Text,
+		})
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create comment: %w", err)
+		return nil, err
 	}
+	comment.TextHTML = markdown.Render(comment.Text)
+	comment.GIF = gif
 
 	// Get author info
 	var bio, avatarURL pgtype.Text
@@ -301,30 +887,150 @@ func (s *PostsService) CreateComment(ctx context.Context, userID, postID uuid.UU
 	comment.Author.Bio = getPgtypeTextValue(bio)
 	comment.Author.AvatarURL = getPgtypeTextPtr(avatarURL)
 
-	// Create notification
-	if s.notificationsService != nil {
-		err = s.notificationsService.NotifyComment(ctx, userID, postID, req.Text)
-		if err != nil {
-			// Log error but don't fail the operation
-			fmt.Printf("Failed to create comment notification: %v\n", err)
-		}
+	return &comment, nil
+}
+
+const (
+	CommentSortOldest = "oldest"
+	CommentSortNewest = "newest"
+	// CommentSortTop orders by likes once comments can be liked; until then
+	// it is the same as oldest
+	CommentSortTop = "top"
+)
+
+// commentOrders maps each sort to its ORDER BY. The verified answer is
+// pinned first; id breaks ties so pages and context offsets agree.
+var commentOrders = map[string]string{
+	CommentSortOldest: "c.verified_at IS NULL, c.created_at ASC, c.id ASC",
+	CommentSortNewest: "c.verified_at IS NULL, c.created_at DESC, c.id DESC",
+	CommentSortTop:    "c.verified_at IS NULL, c.created_at ASC, c.id ASC",
+}
+
+// ValidCommentSort reports whether sort is a supported comment order
+func ValidCommentSort(sort string) bool {
+	_, ok := commentOrders[sort]
+	return ok
+}
+
+// commentGIFColumn selects a comment's GIF as JSON, or NULL without one
+const commentGIFColumn = `(SELECT json_build_object('id', g.id, 'title', g.title, 'url', g.url, 'preview_url', g.preview_url,
+	                          'width', g.width, 'height', g.height)
+	        FROM gifs g WHERE g.id = c.gif_id)`
+
+func commentsQuery(sort string) string {
+	return `
+	SELECT c.id, c.post_id, c.author_id, c.text, c.created_at, c.verified_at IS NOT NULL,
+	       u.username, u.email, u.bio, u.avatar_url, ` + commentGIFColumn + `
+	FROM comments c
+	JOIN users u ON c.author_id = u.id
+	WHERE c.post_id = $1 AND (NOT u.shadow_banned OR u.id = $4)
+	ORDER BY ` + commentOrders[sort] + `
+	LIMIT $2 OFFSET $3`
+}
+
+// GetComments lists the comments on a post the viewer may see, in the given
+// sort order. Comments by shadow-banned users are only shown to their
+// authors.
+func (s *PostsService) GetComments(ctx context.Context, postID, viewerID uuid.UUID, sort string, limit, offset int) ([]*Comment, error) {
+	if !ValidCommentSort(sort) {
+		return nil, fmt.Errorf("invalid comment sort")
+	}
+	if err := s.checkPostVisible(ctx, postID, viewerID); err != nil {
+		return nil, err
 	}
 
-	return &comment, nil
+	rows, err := s.db.Query(ctx, commentsQuery(sort), postID, limit, offset, viewerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comments: %w", err)
+	}
+	return scanComments(rows)
 }
 
-func (s *PostsService) GetComments(ctx context.Context, postID uuid.UUID, limit, offset int) ([]*Comment, error) {
+// GetFirstComments returns up to limit of each post's oldest comments, keyed
+// by post ID, in one query
+func (s *PostsService) GetFirstComments(ctx context.Context, postIDs []uuid.UUID, viewerID uuid.UUID, limit int) (map[uuid.UUID][]*Comment, error) {
 	rows, err := s.db.Query(ctx, `
-		SELECT c.id, c.post_id, c.author_id, c.text, c.created_at,
-		       u.username, u.email, u.bio, u.avatar_url
+		SELECT id, post_id, author_id, text, created_at, verified, username, email, bio, avatar_url, gif
+		FROM (
+		    SELECT c.id, c.post_id, c.author_id, c.text, c.created_at, c.verified_at IS NOT NULL AS verified,
+		           u.username, u.email, u.bio, u.avatar_url, `+commentGIFColumn+` AS gif,
+		           ROW_NUMBER() OVER (PARTITION BY c.post_id ORDER BY `+commentOrders[CommentSortOldest]+`) AS n
+		    FROM comments c
+		    JOIN users u ON c.author_id = u.id
+		    WHERE c.post_id = ANY($1) AND (NOT u.shadow_banned OR u.id = $3)
+		) c
+		WHERE n <= $2
+		ORDER BY post_id, n`, postIDs, limit, viewerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comments: %w", err)
+	}
+	comments, err := scanComments(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	byPost := make(map[uuid.UUID][]*Comment, len(postIDs))
+	for _, comment := range comments {
+		byPost[comment.PostID] = append(byPost[comment.PostID], comment)
+	}
+	return byPost, nil
+}
+
+// CountComments returns how many of a post's comments the viewer can see
+func (s *PostsService) CountComments(ctx context.Context, postID, viewerID uuid.UUID) (int, error) {
+	var total int
+	err := s.db.QueryRow(ctx, `
+		SELECT COUNT(*)
 		FROM comments c
 		JOIN users u ON c.author_id = u.id
-		WHERE c.post_id = $1
-		ORDER BY c.created_at ASC
-		LIMIT $2 OFFSET $3`, postID, limit, offset)
+		WHERE c.post_id = $1 AND (NOT u.shadow_banned OR u.id = $2)`, postID, viewerID).Scan(&total)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get comments: %w", err)
+		return 0, fmt.Errorf("failed to count comments: %w", err)
 	}
+	return total, nil
+}
+
+// CommentPageOffset returns the offset of the page of size limit that
+// contains commentID in the given sort order, for deep links to a comment
+func (s *PostsService) CommentPageOffset(ctx context.Context, postID, commentID, viewerID uuid.UUID, sort string, limit int) (int, error) {
+	if !ValidCommentSort(sort) {
+		return 0, fmt.Errorf("invalid comment sort")
+	}
+
+	before := "(c.created_at, c.id) < (t.created_at, t.id)"
+	if sort == CommentSortNewest {
+		before = "(c.created_at, c.id) > (t.created_at, t.id)"
+	}
+	// The verified answer is pinned ahead of everything else
+	before = "(c.verified_at IS NOT NULL AND t.verified_at IS NULL) OR " +
+		"((c.verified_at IS NULL) = (t.verified_at IS NULL) AND " + before + ")"
+
+	var position int
+	err := s.db.QueryRow(ctx, `
+		SELECT (
+		    SELECT COUNT(*)
+		    FROM comments c
+		    JOIN users u ON c.author_id = u.id
+		    WHERE c.post_id = $1 AND (NOT u.shadow_banned OR u.id = $3)
+		      AND (`+before+`)
+		)
+		FROM comments t
+		WHERE t.id = $2 AND t.post_id = $1`, postID, commentID, viewerID).Scan(&position)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("comment not found")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to locate comment: %w", err)
+	}
+	return pageOffset(position, limit), nil
+}
+
+// pageOffset returns the offset of the page that holds the item at position
+func pageOffset(position, limit int) int {
+	return position / limit * limit
+}
+
+func scanComments(rows pgx.Rows) ([]*Comment, error) {
 	defer rows.Close()
 
 	var comments []*Comment
@@ -332,12 +1038,14 @@ func (s *PostsService) GetComments(ctx context.Context, postID uuid.UUID, limit,
 		var comment Comment
 		var bio, avatarURL pgtype.Text
 		err := rows.Scan(
-			&comment.ID, &comment.PostID, &comment.AuthorID, &comment.Text, &comment.CreatedAt,
-			&comment.Author.Username, &comment.Author.Email, &bio, &avatarURL)
+			&comment.ID, &comment.PostID, &comment.AuthorID, &comment.Text, &comment.CreatedAt, &comment.VerifiedAnswer,
+			&comment.Author.Username, &comment.Author.Email, &bio, &avatarURL, &comment.GIF)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan comment: %w", err)
 		}
 
+		comment.TextHTML = markdown.Render(comment.Text)
+
 		// Convert pgtype to regular types
 		comment.Author.Bio = getPgtypeTextValue(bio)
 		comment.Author.AvatarURL = getPgtypeTextPtr(avatarURL)
@@ -345,37 +1053,249 @@ func (s *PostsService) GetComments(ctx context.Context, postID uuid.UUID, limit,
 		comments = append(comments, &comment)
 	}
 
-	return comments, nil
+	return comments, rows.Err()
 }
 
-func (s *PostsService) LikePost(ctx context.Context, userID, postID uuid.UUID) error {
-	_, err := s.db.Exec(ctx, `
-		INSERT INTO likes (user_id, post_id)
-		VALUES ($1, $2)
-		ON CONFLICT (user_id, post_id) DO NOTHING`, userID, postID)
+// SetVerifiedAnswer marks or unmarks a comment as the verified answer of its
+// post. Only course posts of the teacher's organization can have one, and
+// marking a comment replaces the previous answer.
+func (s *PostsService) SetVerifiedAnswer(ctx context.Context, teacherID, postID, commentID uuid.UUID, verified bool) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		var authorID uuid.UUID
+		var text string
+		var courseID pgtype.UUID
+		var wasVerified bool
+		// Locking the post serializes markings of its comments
+		err := tx.QueryRow(ctx, `
+			SELECT c.author_id, c.text, p.course_id, c.verified_at IS NOT NULL
+			FROM comments c
+			JOIN posts p ON p.id = c.post_id
+			WHERE c.id = $1 AND c.post_id = $2
+			  AND p.org_id = (SELECT org_id FROM users WHERE id = $3)
+			FOR UPDATE OF p`, commentID, postID, teacherID).Scan(&authorID, &text, &courseID, &wasVerified)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("comment not found")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get comment: %w", err)
+		}
+		if !courseID.Valid {
+			return fmt.Errorf("post has no course")
+		}
+		if verified == wasVerified {
+			return nil
+		}
+
+		if !verified {
+			_, err = tx.Exec(ctx, `
+				UPDATE comments SET verified_by = NULL, verified_at = NULL WHERE id = $1`, commentID)
+			if err != nil {
+				return fmt.Errorf("failed to unverify comment: %w", err)
+			}
+			return nil
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE comments SET verified_by = NULL, verified_at = NULL
+			WHERE post_id = $1 AND verified_at IS NOT NULL`, postID)
+		if err != nil {
+			return fmt.Errorf("failed to unverify comment: %w", err)
+		}
+		_, err = tx.Exec(ctx, `
+			UPDATE comments SET verified_by = $2, verified_at = now() WHERE id = $1`, commentID, teacherID)
+		if err != nil {
+			return fmt.Errorf("failed to verify comment: %w", err)
+		}
+
+		if s.notificationsService == nil || authorID == teacherID {
+			return nil
+		}
+		return enqueueNotification(ctx, tx, OutboxEvent{
+			Type:      NotificationTypeVerifiedAnswer,
+			ActorID:   teacherID,
+			TargetID:  &authorID,
+			PostID:    &postID,
+			CommentID: &commentID,
+			Text:      text,
+		})
+	})
+}
+
+// LikePost likes a post and returns its new like state. Liking twice is a
+// no-op.
+func (s *PostsService) LikePost(ctx context.Context, userID, postID uuid.UUID) (*LikeState, error) {
+	if err := s.abuse.Allow(ctx, userID, AbuseActionLike); err != nil {
+		return nil, err
+	}
+	if err := s.checkPostVisible(ctx, postID, userID); err != nil {
+		return nil, err
+	}
+
+	state := &LikeState{IsLiked: true}
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		// Locking the post keeps like_count in step with the likes rows
+		if err := lockPostLikeCount(ctx, tx, postID, &state.LikeCount); err != nil {
+			return err
+		}
+
+		result, err := tx.Exec(ctx, `
+			INSERT INTO likes (user_id, post_id)
+			VALUES ($1, $2)
+			ON CONFLICT (user_id, post_id) DO NOTHING`, userID, postID)
+		if err != nil {
+			return fmt.Errorf("failed to like post: %w", err)
+		}
+		if result.RowsAffected() == 0 {
+			return nil
+		}
+
+		err = tx.QueryRow(ctx, `
+			UPDATE posts SET like_count = like_count + 1 WHERE id = $1
+			RETURNING like_count`, postID).Scan(&state.LikeCount)
+		if err != nil {
+			return fmt.Errorf("failed to update like count: %w", err)
+		}
+
+		if s.notificationsService == nil {
+			return nil
+		}
+		return enqueueNotification(ctx, tx, OutboxEvent{
+			Type:    NotificationTypeLike,
+			ActorID: userID,
+			PostID:  &postID,
+		})
+	})
 	if err != nil {
-		return fmt.Errorf("failed to like post: %w", err)
+		return nil, err
 	}
+	return state, nil
+}
+
+// UnlikePost removes the user's like and returns the new like state.
+// Unliking a post that isn't liked is a no-op.
+func (s *PostsService) UnlikePost(ctx context.Context, userID, postID uuid.UUID) (*LikeState, error) {
+	state := &LikeState{}
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		if err := lockPostLikeCount(ctx, tx, postID, &state.LikeCount); err != nil {
+			return err
+		}
 
-	// Create notification (will be handled by trigger in production)
-	if s.notificationsService != nil {
-		err = s.notificationsService.NotifyLike(ctx, userID, postID)
+		result, err := tx.Exec(ctx, `
+			DELETE FROM likes WHERE user_id = $1 AND post_id = $2`, userID, postID)
 		if err != nil {
-			// Log error but don't fail the operation
-			fmt.Printf("Failed to create like notification: %v\n", err)
+			return fmt.Errorf("failed to unlike post: %w", err)
 		}
+		if result.RowsAffected() == 0 {
+			return nil
+		}
+
+		err = tx.QueryRow(ctx, `
+			UPDATE posts SET like_count = GREATEST(like_count - 1, 0) WHERE id = $1
+			RETURNING like_count`, postID).Scan(&state.LikeCount)
+		if err != nil {
+			return fmt.Errorf("failed to update like count: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return state, nil
+}
 
+func lockPostLikeCount(ctx context.Context, tx pgx.Tx, postID uuid.UUID, likeCount *int) error {
+	err := tx.QueryRow(ctx, `
+		SELECT like_count FROM posts WHERE id = $1 FOR UPDATE`, postID).Scan(likeCount)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("post not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get post: %w", err)
+	}
 	return nil
 }
 
-func (s *PostsService) UnlikePost(ctx context.Context, userID, postID uuid.UUID) error {
-	_, err := s.db.Exec(ctx, `
-		DELETE FROM likes WHERE user_id = $1 AND post_id = $2`, userID, postID)
+// GetPostLikes lists users who liked a post, most recent first, flagging the
+// ones the viewer follows. Users blocked either way by the viewer and
+// shadow-banned users are left out. It also returns the total number of likes.
+func (s *PostsService) GetPostLikes(ctx context.Context, postID, viewerID uuid.UUID, limit, offset int) ([]*UserResponse, int, error) {
+	if err := s.checkPostVisible(ctx, postID, viewerID); err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	err := s.db.QueryRow(ctx, `
+		SELECT COUNT(*) FROM likes WHERE post_id = $1`, postID).Scan(&total)
 	if err != nil {
-		return fmt.Errorf("failed to unlike post: %w", err)
+		return nil, 0, fmt.Errorf("failed to count likes: %w", err)
 	}
-	return nil
+
+	rows, err := s.db.Query(ctx, `
+		SELECT u.id, u.username, u.email, u.bio, u.avatar_url,
+		       EXISTS(SELECT 1 FROM follows f WHERE f.follower_id = $2 AND f.followee_id = u.id) AS is_following
+		FROM likes l
+		JOIN users u ON l.user_id = u.id
+		WHERE l.post_id = $1
+		  AND (NOT u.shadow_banned OR u.id = $2)
+		  AND NOT EXISTS (
+		      SELECT 1 FROM user_blocks b
+		      WHERE (b.blocker_id = $2 AND b.blocked_id = u.id)
+		         OR (b.blocker_id = u.id AND b.blocked_id = $2)
+		  )
+		ORDER BY l.created_at DESC, u.id
+		LIMIT $3 OFFSET $4`, postID, viewerID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get likes: %w", err)
+	}
+	defer rows.Close()
+
+	var users []*UserResponse
+	for rows.Next() {
+		var user UserResponse
+		var bio, avatarURL pgtype.Text
+
+		err := rows.Scan(&user.ID, &user.Username, &user.Email, &bio, &avatarURL, &user.IsFollowing)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan like: %w", err)
+		}
+
+		user.Bio = getPgtypeTextValue(bio)
+		user.AvatarURL = getPgtypeTextPtr(avatarURL)
+		users = append(users, &user)
+	}
+
+	return users, total, nil
+}
+
+// RecordImpressions counts one view per viewer per post per day. Views of
+// the viewer's own posts and unknown post IDs are ignored. It returns the
+// number of newly counted views.
+func (s *PostsService) RecordImpressions(ctx context.Context, viewerID uuid.UUID, postIDs []uuid.UUID) (int64, error) {
+	seen := make(map[uuid.UUID]bool, len(postIDs))
+	unique := make([]uuid.UUID, 0, len(postIDs))
+	for _, id := range postIDs {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	result, err := s.db.Exec(ctx, `
+		WITH inserted AS (
+		    INSERT INTO post_impressions (post_id, viewer_id, viewed_on)
+		    SELECT p.id, $2, current_date
+		    FROM posts p
+		    WHERE p.id = ANY($1) AND p.author_id <> $2
+		    ON CONFLICT (post_id, viewer_id, viewed_on) DO NOTHING
+		    RETURNING post_id
+		)
+		UPDATE posts SET view_count = view_count + 1
+		WHERE id IN (SELECT post_id FROM inserted)`, unique, viewerID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record impressions: %w", err)
+	}
+
+	return result.RowsAffected(), nil
 }
 
 func (s *PostsService) IsPostLiked(ctx context.Context, userID, postID uuid.UUID) (bool, error) {
@@ -389,16 +1309,6 @@ func (s *PostsService) IsPostLiked(ctx context.Context, userID, postID uuid.UUID
 }
 
 // Helper functions
-func extractHashtags(text string) []string {
-	re := regexp.MustCompile(`#\w+`)
-	matches := re.FindAllString(text, -1)
-	var hashtags []string
-	for _, match := range matches {
-		hashtags = append(hashtags, strings.TrimPrefix(match, "#"))
-	}
-	return hashtags
-}
-
 func getPgtypeTextValue(pt pgtype.Text) string {
 	if pt.Valid {
 		return pt.String
@@ -412,3 +1322,20 @@ func getPgtypeTextPtr(pt pgtype.Text) *string {
 	}
 	return nil
 }
+
+// filterContent runs the content filter, if any, and fails with
+// "content rejected" when a rule rejects the text
+func (s *PostsService) filterContent(ctx context.Context, userID uuid.UUID, contentType, text string) ([]FilterHit, error) {
+	if s.contentFilter == nil {
+		return nil, nil
+	}
+
+	hits, err := s.contentFilter.Check(ctx, userID, contentType, text)
+	if err != nil {
+		return nil, err
+	}
+	if rejected(hits) {
+		return nil, fmt.Errorf("content rejected")
+	}
+	return hits, nil
+}
@@ -1,6 +1,8 @@
 package services
 
 import (
+	"math"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -37,6 +39,26 @@ func TestExtractHashtags(t *testing.T) {
 			text:     "Version #v2.0 and #test123",
 			expected: []string{"v2", "test123"},
 		},
+		{
+			name:     "cyrillic and kazakh letters",
+			text:     "Жаңа пост #қазақша про #МашинноеОбучение",
+			expected: []string{"қазақша", "МашинноеОбучение"},
+		},
+		{
+			name:     "repeats in another case are dropped",
+			text:     "#Go is fun, #go #GO",
+			expected: []string{"Go"},
+		},
+		{
+			name:     "combining marks are normalized",
+			text:     "#cafe\u0301",
+			expected: []string{"caf\u00e9"},
+		},
+		{
+			name:     "long hashtags are cut",
+			text:     "#" + strings.Repeat("ә", MaxHashtagLength+10),
+			expected: []string{strings.Repeat("ә", MaxHashtagLength)},
+		},
 	}
 
 	for _, tt := range tests {
@@ -46,3 +68,108 @@ func TestExtractHashtags(t *testing.T) {
 		})
 	}
 }
+
+func TestCanonicalHashtag(t *testing.T) {
+	assert.Equal(t, "go", CanonicalHashtag("Go"))
+	assert.Equal(t, "машинноеобучение", CanonicalHashtag("МашинноеОбучение"))
+	assert.Equal(t, "қазақша", CanonicalHashtag("ҚАЗАҚША"))
+	assert.Equal(t, CanonicalHashtag("caf\u00e9"), CanonicalHashtag("CAFE\u0301"))
+	assert.Len(t, []rune(CanonicalHashtag(strings.Repeat("A", 100))), MaxHashtagLength)
+}
+
+func TestValidHashtag(t *testing.T) {
+	for _, tag := range []string{"go", "қазақша", "deep_learning", "v2"} {
+		assert.True(t, ValidHashtag(tag), tag)
+	}
+	for _, tag := range []string{"", "machine-learning", "two words", "#go"} {
+		assert.False(t, ValidHashtag(tag), tag)
+	}
+}
+
+func TestPageOffset(t *testing.T) {
+	tests := []struct {
+		position int
+		limit    int
+		want     int
+	}{
+		{0, 20, 0},
+		{19, 20, 0},
+		{20, 20, 20},
+		{45, 20, 40},
+		{7, 1, 7},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, pageOffset(tt.position, tt.limit))
+	}
+}
+
+func TestValidCommentSort(t *testing.T) {
+	assert.True(t, ValidCommentSort(CommentSortOldest))
+	assert.True(t, ValidCommentSort(CommentSortNewest))
+	assert.True(t, ValidCommentSort(CommentSortTop))
+	assert.False(t, ValidCommentSort(""))
+	assert.False(t, ValidCommentSort("random"))
+}
+
+func TestPostSlug(t *testing.T) {
+	tests := []struct {
+		name string
+		seq  int64
+		text string
+		want string
+	}{
+		{"words", 1, "Intro to Go: part 1!", "1-intro-to-go-part-1"},
+		{"base62", 3843, "Hello", "ZZ-hello"},
+		{"word limit", 62, "one two three four five six seven", "10-one-two-three-four-five-six"},
+		{"urls dropped", 5, "See https://example.com/page for #golang", "5-see-for-golang"},
+		{"cyrillic", 7, "Сәлем, әлем", "7-сәлем-әлем"},
+		{"no words", 9, "!!! ???", "9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, postSlug(tt.seq, tt.text))
+		})
+	}
+}
+
+func TestParsePostSlug(t *testing.T) {
+	for _, seq := range []int64{1, 61, 62, 3843, 1 << 40, math.MaxInt64} {
+		got, ok := parsePostSlug(postSlug(seq, "some words"))
+		assert.True(t, ok)
+		assert.Equal(t, seq, got)
+	}
+
+	for _, slug := range []string{"", "-words", "a_b", "zzzzzzzzzzzzzz"} {
+		_, ok := parsePostSlug(slug)
+		assert.False(t, ok, slug)
+	}
+}
+
+func TestParseFeedFilter(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    FeedFilter
+		wantErr bool
+	}{
+		{"", FeedFilterAll, false},
+		{"all", FeedFilterAll, false},
+		{"questions", FeedFilterQuestions, false},
+		{"unanswered", FeedFilterUnanswered, false},
+		{"question", FeedFilterAll, true},
+		{"UNANSWERED", FeedFilterAll, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseFeedFilter(tt.in)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"bailanysta/api/internal/pkg/database"
+	"bailanysta/api/internal/pkg/sentry"
+)
+
+// Presence statuses
+const (
+	PresenceOnline         = "online"
+	PresenceRecentlyActive = "recently_active"
+	PresenceOffline        = "offline"
+)
+
+const (
+	presenceOnlineWindow = 5 * time.Minute
+	presenceRecentWindow = 24 * time.Hour
+)
+
+// Presence is what others see of when a user was last active
+type Presence struct {
+	Status       string     `json:"status"`
+	LastActiveAt *time.Time `json:"last_active_at,omitempty"` // to the minute
+}
+
+// PresenceOf describes a user last active at lastActive, or returns nil when
+// they hide their presence
+func PresenceOf(lastActive *time.Time, show bool, now time.Time) *Presence {
+	if !show {
+		return nil
+	}
+	if lastActive == nil {
+		return &Presence{Status: PresenceOffline}
+	}
+
+	at := lastActive.Truncate(time.Minute)
+	presence := &Presence{Status: PresenceOffline, LastActiveAt: &at}
+	switch since := now.Sub(*lastActive); {
+	case since < presenceOnlineWindow:
+		presence.Status = PresenceOnline
+	case since < presenceRecentWindow:
+		presence.Status = PresenceRecentlyActive
+	}
+	return presence
+}
+
+// PresenceService records when users were last active. Touch only marks a
+// user in memory; Run writes the marks in one batch per interval, so a user
+// costs at most one write per interval however many requests they make.
+type PresenceService struct {
+	db       *database.Pool
+	interval time.Duration
+
+	mu      sync.Mutex
+	pending map[uuid.UUID]time.Time // activity not written yet
+	written map[uuid.UUID]time.Time // when each user's activity was last queued
+}
+
+func NewPresenceService(db *database.Pool, interval time.Duration) *PresenceService {
+	return &PresenceService{
+		db:       db,
+		interval: interval,
+		pending:  make(map[uuid.UUID]time.Time),
+		written:  make(map[uuid.UUID]time.Time),
+	}
+}
+
+// Touch records that userID is active now
+func (s *PresenceService) Touch(userID uuid.UUID) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if last, ok := s.written[userID]; ok && now.Sub(last) < s.interval {
+		return
+	}
+	s.written[userID] = now
+	s.pending[userID] = now
+}
+
+// Run writes recorded activity every interval until ctx is cancelled, then
+// writes what is left
+func (s *PresenceService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := s.flush(flushCtx); err != nil {
+				fmt.Printf("Failed to write presence: %v\n", err)
+			}
+			return
+		case <-ticker.C:
+			if err := s.flush(ctx); err != nil {
+				fmt.Printf("Failed to write presence: %v\n", err)
+				sentry.CaptureError(err, map[string]string{"job": "presence"})
+			}
+		}
+	}
+}
+
+func (s *PresenceService) flush(ctx context.Context) error {
+	now := time.Now()
+
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = make(map[uuid.UUID]time.Time)
+	for userID, at := range s.written {
+		if now.Sub(at) >= s.interval {
+			delete(s.written, userID)
+		}
+	}
+	s.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+	userIDs := make([]uuid.UUID, 0, len(pending))
+	times := make([]time.Time, 0, len(pending))
+	for userID, at := range pending {
+		userIDs = append(userIDs, userID)
+		times = append(times, at)
+	}
+
+	_, err := s.db.Exec(ctx, `
+		UPDATE users u SET last_active_at = a.at
+		FROM unnest($1::uuid[], $2::timestamptz[]) AS a(id, at)
+		WHERE u.id = a.id AND (u.last_active_at IS NULL OR u.last_active_at < a.at)`, userIDs, times)
+	if err != nil {
+		return fmt.Errorf("failed to write last active times: %w", err)
+	}
+	return nil
+}
+
+// SetShowPresence sets whether others see when userID was last active
+func (s *SocialService) SetShowPresence(ctx context.Context, userID uuid.UUID, show bool) error {
+	_, err := s.db.Exec(ctx, `UPDATE users SET show_presence = $2 WHERE id = $1`, userID, show)
+	if err != nil {
+		return fmt.Errorf("failed to update presence visibility: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPresenceOf(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 30, 0, time.UTC)
+	at := func(ago time.Duration) *time.Time {
+		t := now.Add(-ago)
+		return &t
+	}
+
+	tests := []struct {
+		name       string
+		lastActive *time.Time
+		show       bool
+		want       string
+	}{
+		{"just now", at(time.Minute), true, PresenceOnline},
+		{"an hour ago", at(time.Hour), true, PresenceRecentlyActive},
+		{"last week", at(7 * 24 * time.Hour), true, PresenceOffline},
+		{"never", nil, true, PresenceOffline},
+		{"hidden", at(time.Minute), false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PresenceOf(tt.lastActive, tt.show, now)
+			if tt.want == "" {
+				assert.Nil(t, got)
+				return
+			}
+			require.NotNil(t, got)
+			assert.Equal(t, tt.want, got.Status)
+			if tt.lastActive == nil {
+				assert.Nil(t, got.LastActiveAt)
+			} else {
+				assert.Equal(t, tt.lastActive.Truncate(time.Minute), *got.LastActiveAt)
+			}
+		})
+	}
+}
+
+func TestPresenceTouchThrottles(t *testing.T) {
+	s := NewPresenceService(nil, time.Minute)
+	userID := uuid.New()
+
+	s.Touch(userID)
+	first := s.pending[userID]
+	s.Touch(userID)
+
+	assert.Len(t, s.pending, 1)
+	assert.Equal(t, first, s.pending[userID])
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"bailanysta/api/internal/pkg/database"
+	"bailanysta/api/internal/pkg/sentry"
+)
+
+const (
+	profileViewCleanupInterval = time.Hour
+	// maxProfileViewers bounds the named viewers GetProfileViews lists
+	maxProfileViewers = 50
+)
+
+// ProfileViewService records who views the profiles of users that opted in.
+// Owners see how many people viewed them each day; names are only shown
+// when both the owner and the viewer share theirs. Views are kept for
+// retentionDays.
+type ProfileViewService struct {
+	db            *database.Pool
+	retentionDays int
+}
+
+type ProfileViewSettings struct {
+	Enabled bool `json:"enabled"`
+	// ShareIdentity shows the user's name to profiles they view, and names
+	// viewers who share theirs in return
+	ShareIdentity bool `json:"share_identity"`
+}
+
+type ProfileViewDay struct {
+	Day     string `json:"day"` // YYYY-MM-DD, UTC
+	Viewers int    `json:"viewers"`
+}
+
+type ProfileViewer struct {
+	User         UserResponse `json:"user"`
+	LastViewedOn string       `json:"last_viewed_on"`
+}
+
+type ProfileViewStats struct {
+	ProfileViewSettings
+	Days         []ProfileViewDay `json:"days"`
+	TotalViewers int              `json:"total_viewers"`
+	// Viewers is only set when the owner shares their identity
+	Viewers []*ProfileViewer `json:"viewers,omitempty"`
+}
+
+func NewProfileViewService(db *database.Pool, retentionDays int) *ProfileViewService {
+	return &ProfileViewService{db: db, retentionDays: retentionDays}
+}
+
+// RecordView notes that viewerID looked at profileID today, if profileID
+// tracks views. Repeat views on the same day are not stored.
+func (s *ProfileViewService) RecordView(ctx context.Context, profileID, viewerID uuid.UUID) error {
+	if profileID == viewerID {
+		return nil
+	}
+	_, err := s.db.Exec(ctx, `
+		INSERT INTO profile_views (profile_id, viewer_id, day, viewer_shared)
+		SELECT p.id, v.id, (now() AT TIME ZONE 'UTC')::date, v.share_profile_views
+		FROM users p, users v
+		WHERE p.id = $1 AND v.id = $2 AND p.profile_views_enabled
+		ON CONFLICT DO NOTHING`, profileID, viewerID)
+	if err != nil {
+		return fmt.Errorf("failed to record profile view: %w", err)
+	}
+	return nil
+}
+
+// SetSettings updates userID's profile view settings. Turning tracking off
+// deletes the views recorded so far.
+func (s *ProfileViewService) SetSettings(ctx context.Context, userID uuid.UUID, enabled, shareIdentity *bool) (*ProfileViewSettings, error) {
+	var settings ProfileViewSettings
+	err := s.db.QueryRow(ctx, `
+		UPDATE users
+		SET profile_views_enabled = COALESCE($2, profile_views_enabled),
+		    share_profile_views = COALESCE($3, share_profile_views)
+		WHERE id = $1
+		RETURNING profile_views_enabled, share_profile_views`, userID, enabled, shareIdentity).Scan(
+		&settings.Enabled, &settings.ShareIdentity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update profile view settings: %w", err)
+	}
+
+	if !settings.Enabled {
+		if _, err := s.db.Exec(ctx, `DELETE FROM profile_views WHERE profile_id = $1`, userID); err != nil {
+			return nil, fmt.Errorf("failed to delete profile views: %w", err)
+		}
+	}
+	return &settings, nil
+}
+
+// GetProfileViews summarizes the views of userID's profile over the last
+// days days, counting each viewer once per day
+func (s *ProfileViewService) GetProfileViews(ctx context.Context, userID uuid.UUID, days int) (*ProfileViewStats, error) {
+	stats := &ProfileViewStats{Days: []ProfileViewDay{}}
+	err := s.db.Reader().QueryRow(ctx, `
+		SELECT profile_views_enabled, share_profile_views FROM users WHERE id = $1`, userID).Scan(
+		&stats.Enabled, &stats.ShareIdentity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profile view settings: %w", err)
+	}
+	if !stats.Enabled {
+		return stats, nil
+	}
+
+	since := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1-days)
+	rows, err := s.db.Reader().Query(ctx, `
+		SELECT to_char(day, 'YYYY-MM-DD'), COUNT(*)
+		FROM profile_views
+		WHERE profile_id = $1 AND day >= $2
+		GROUP BY day
+		ORDER BY day`, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profile views: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var day ProfileViewDay
+		if err := rows.Scan(&day.Day, &day.Viewers); err != nil {
+			return nil, fmt.Errorf("failed to scan profile views: %w", err)
+		}
+		stats.Days = append(stats.Days, day)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read profile views: %w", err)
+	}
+
+	err = s.db.Reader().QueryRow(ctx, `
+		SELECT COUNT(DISTINCT viewer_id) FROM profile_views
+		WHERE profile_id = $1 AND day >= $2`, userID, since).Scan(&stats.TotalViewers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count profile viewers: %w", err)
+	}
+
+	if !stats.ShareIdentity {
+		return stats, nil
+	}
+	stats.Viewers, err = s.getSharedViewers(ctx, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// getSharedViewers lists viewers who shared their identity when they viewed
+// and still do, most recent first
+func (s *ProfileViewService) getSharedViewers(ctx context.Context, userID uuid.UUID, since time.Time) ([]*ProfileViewer, error) {
+	rows, err := s.db.Reader().Query(ctx, `
+		SELECT u.id, u.username, u.bio, u.avatar_url, to_char(MAX(pv.day), 'YYYY-MM-DD')
+		FROM profile_views pv
+		JOIN users u ON u.id = pv.viewer_id
+		WHERE pv.profile_id = $1 AND pv.day >= $2
+		  AND pv.viewer_shared AND u.share_profile_views AND NOT u.shadow_banned
+		GROUP BY u.id
+		ORDER BY MAX(pv.day) DESC, u.username
+		LIMIT $3`, userID, since, maxProfileViewers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profile viewers: %w", err)
+	}
+	defer rows.Close()
+
+	viewers := []*ProfileViewer{}
+	for rows.Next() {
+		var viewer ProfileViewer
+		var bio, avatarURL pgtype.Text
+		err := rows.Scan(&viewer.User.ID, &viewer.User.Username, &bio, &avatarURL, &viewer.LastViewedOn)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan profile viewer: %w", err)
+		}
+		viewer.User.Bio = getPgtypeTextValue(bio)
+		viewer.User.AvatarURL = getPgtypeTextPtr(avatarURL)
+		viewers = append(viewers, &viewer)
+	}
+	return viewers, rows.Err()
+}
+
+// RetentionDays is how many days of views are kept
+func (s *ProfileViewService) RetentionDays() int {
+	return s.retentionDays
+}
+
+// PurgeExpired deletes views older than the retention period
+func (s *ProfileViewService) PurgeExpired(ctx context.Context) (int64, error) {
+	result, err := s.db.Exec(ctx, `
+		DELETE FROM profile_views
+		WHERE day < (now() AT TIME ZONE 'UTC')::date - $1::int`, s.retentionDays)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge profile views: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+// Run purges expired views until ctx is cancelled
+func (s *ProfileViewService) Run(ctx context.Context) {
+	ticker := time.NewTicker(profileViewCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.PurgeExpired(ctx); err != nil {
+			fmt.Printf("Failed to purge profile views: %v\n", err)
+			sentry.CaptureError(err, map[string]string{"job": "profile-view-cleanup"})
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
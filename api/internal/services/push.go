@@ -0,0 +1,203 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"bailanysta/api/internal/pkg/webpush"
+)
+
+const (
+	pushQueueSize     = 256
+	pushSendTimeout   = 20 * time.Second
+	pushPruneInterval = time.Hour
+	pushDrainTimeout  = 10 * time.Second
+)
+
+type PushSubscriptionRequest struct {
+	Endpoint       string `json:"endpoint" validate:"required,url,startswith=https://,max=2048"`
+	ExpirationTime *int64 `json:"expirationTime"` // ms since epoch, as reported by the browser
+	Keys           struct {
+		P256dh string `json:"p256dh" validate:"required,max=256"`
+		Auth   string `json:"auth" validate:"required,max=64"`
+	} `json:"keys"`
+}
+
+type pushMessage struct {
+	Title          string           `json:"title"`
+	Body           string           `json:"body"`
+	Type           NotificationType `json:"type"`
+	NotificationID uuid.UUID        `json:"notification_id"`
+	EntityID       *uuid.UUID       `json:"entity_id,omitempty"`
+}
+
+// PushEnabled reports whether a VAPID key is configured
+func (s *NotificationService) PushEnabled() bool {
+	return s.push != nil
+}
+
+// VAPIDPublicKey returns the application server key browsers subscribe with
+func (s *NotificationService) VAPIDPublicKey() string {
+	if s.push == nil {
+		return ""
+	}
+	return s.push.PublicKey()
+}
+
+func (s *NotificationService) SavePushSubscription(ctx context.Context, userID uuid.UUID, req PushSubscriptionRequest) error {
+	var expiresAt *time.Time
+	if req.ExpirationTime != nil {
+		t := time.UnixMilli(*req.ExpirationTime)
+		expiresAt = &t
+	}
+
+	// Endpoints are unique per browser; re-subscribing moves it to the current user
+	_, err := s.db.Exec(ctx, `
+		INSERT INTO push_subscriptions (user_id, endpoint, p256dh, auth, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (endpoint) DO UPDATE
+		SET user_id = EXCLUDED.user_id, p256dh = EXCLUDED.p256dh,
+		    auth = EXCLUDED.auth, expires_at = EXCLUDED.expires_at`,
+		userID, req.Endpoint, req.Keys.P256dh, req.Keys.Auth, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to save push subscription: %w", err)
+	}
+	return nil
+}
+
+func (s *NotificationService) DeletePushSubscription(ctx context.Context, userID uuid.UUID, endpoint string) error {
+	result, err := s.db.Exec(ctx, `
+		DELETE FROM push_subscriptions WHERE user_id = $1 AND endpoint = $2`, userID, endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to delete push subscription: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("subscription not found")
+	}
+	return nil
+}
+
+// RunPushDispatcher delivers queued notifications as Web Push messages and
+// periodically prunes expired subscriptions. When ctx is cancelled it drains
+// the queue for up to pushDrainTimeout before returning.
+func (s *NotificationService) RunPushDispatcher(ctx context.Context) {
+	if s.push == nil {
+		return
+	}
+
+	ticker := time.NewTicker(pushPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.drainPushQueue()
+			return
+		case notification := <-s.pushQueue:
+			sendCtx, cancel := context.WithTimeout(ctx, pushSendTimeout)
+			s.deliverPush(sendCtx, notification)
+			cancel()
+		case <-ticker.C:
+			if _, err := s.db.Exec(ctx, `DELETE FROM push_subscriptions WHERE expires_at < now()`); err != nil {
+				fmt.Printf("Failed to prune push subscriptions: %v\n", err)
+			}
+		}
+	}
+}
+
+func (s *NotificationService) drainPushQueue() {
+	drainCtx, cancel := context.WithTimeout(context.Background(), pushDrainTimeout)
+	defer cancel()
+
+	for drainCtx.Err() == nil {
+		select {
+		case notification := <-s.pushQueue:
+			s.deliverPush(drainCtx, notification)
+		default:
+			return
+		}
+	}
+}
+
+// enqueuePush hands a notification to the dispatcher without blocking the caller
+func (s *NotificationService) enqueuePush(notification *Notification) {
+	if s.push == nil {
+		return
+	}
+
+	select {
+	case s.pushQueue <- notification:
+	default:
+		fmt.Printf("Push queue full, dropping notification %s\n", notification.ID)
+	}
+}
+
+func (s *NotificationService) deliverPush(ctx context.Context, notification *Notification) {
+	pref, err := s.getNotificationPreference(ctx, notification.UserID, notification.Type)
+	if err != nil {
+		fmt.Printf("Failed to get push preference: %v\n", err)
+		return
+	}
+	if !pref.Push {
+		return
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT endpoint, p256dh, auth FROM push_subscriptions
+		WHERE user_id = $1 AND (expires_at IS NULL OR expires_at > now())`, notification.UserID)
+	if err != nil {
+		fmt.Printf("Failed to get push subscriptions: %v\n", err)
+		return
+	}
+	var subscriptions []webpush.Subscription
+	for rows.Next() {
+		var sub webpush.Subscription
+		if err := rows.Scan(&sub.Endpoint, &sub.P256dh, &sub.Auth); err != nil {
+			rows.Close()
+			fmt.Printf("Failed to scan push subscription: %v\n", err)
+			return
+		}
+		subscriptions = append(subscriptions, sub)
+	}
+	rows.Close()
+	if len(subscriptions) == 0 {
+		return
+	}
+
+	if err := s.populateNotificationData(ctx, notification); err != nil {
+		fmt.Printf("Failed to populate push notification: %v\n", err)
+	}
+	payload, err := json.Marshal(buildPushMessage(notification))
+	if err != nil {
+		fmt.Printf("Failed to marshal push message: %v\n", err)
+		return
+	}
+
+	for _, sub := range subscriptions {
+		err := s.push.Send(ctx, sub, payload, webpush.Options{TTL: 24 * time.Hour, Urgency: "high"})
+		if errors.Is(err, webpush.ErrSubscriptionGone) {
+			if _, err := s.db.Exec(ctx, `DELETE FROM push_subscriptions WHERE endpoint = $1`, sub.Endpoint); err != nil {
+				fmt.Printf("Failed to delete expired push subscription: %v\n", err)
+			}
+			continue
+		}
+		if err != nil {
+			fmt.Printf("Failed to send push message: %v\n", err)
+		}
+	}
+}
+
+func buildPushMessage(notification *Notification) pushMessage {
+	return pushMessage{
+		Title:          "Bailanysta",
+		Body:           notificationSummary(notification),
+		Type:           notification.Type,
+		NotificationID: notification.ID,
+		EntityID:       notification.EntityID,
+	}
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildPushMessage(t *testing.T) {
+	tests := []struct {
+		name         string
+		notification *Notification
+		expected     string
+	}{
+		{
+			name: "comment with actor",
+			notification: &Notification{
+				Type:    NotificationTypeComment,
+				Payload: map[string]interface{}{"comment_text": "Nice notes!"},
+				Actor:   &UserResponse{Username: "aigerim"},
+			},
+			expected: "@aigerim commented: Nice notes!",
+		},
+		{
+			name:         "follow without actor",
+			notification: &Notification{Type: NotificationTypeFollow},
+			expected:     "Someone started following you",
+		},
+		{
+			name: "follow request with actor",
+			notification: &Notification{
+				Type:  NotificationTypeFollowRequest,
+				Actor: &UserResponse{Username: "daniyar"},
+			},
+			expected: "@daniyar requested to follow you",
+		},
+		{
+			name:         "unknown type",
+			notification: &Notification{Type: NotificationType("digest")},
+			expected:     "You have a new notification",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.notification.ID = uuid.New()
+			message := buildPushMessage(tt.notification)
+			assert.Equal(t, tt.expected, message.Body)
+			assert.Equal(t, tt.notification.ID, message.NotificationID)
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Post types
+const (
+	PostTypePost     = "post"
+	PostTypeQuestion = "question"
+)
+
+// Question is what a question post carries beyond a plain post. Posts that
+// aren't questions have none.
+type Question struct {
+	AcceptedCommentID *uuid.UUID `json:"accepted_comment_id,omitempty"`
+	AcceptedAt        *time.Time `json:"accepted_at,omitempty"`
+	Bounty            int        `json:"bounty"`
+	// BountyAwardedTo is the answerer who earned the bounty
+	BountyAwardedTo *uuid.UUID `json:"bounty_awarded_to,omitempty"`
+}
+
+// FeedFilter narrows the feed to questions
+type FeedFilter string
+
+const (
+	FeedFilterAll        FeedFilter = ""
+	FeedFilterQuestions  FeedFilter = "questions"
+	FeedFilterUnanswered FeedFilter = "unanswered"
+)
+
+// ParseFeedFilter parses the feed's ?type=: "" or "all", "questions" or
+// "unanswered"
+func ParseFeedFilter(value string) (FeedFilter, error) {
+	switch value {
+	case "", "all":
+		return FeedFilterAll, nil
+	case string(FeedFilterQuestions), string(FeedFilterUnanswered):
+		return FeedFilter(value), nil
+	}
+	return FeedFilterAll, fmt.Errorf("invalid feed filter")
+}
+
+const questionsQuery = `
+	SELECT id, accepted_comment_id, accepted_at, bounty, bounty_awarded_to
+	FROM posts
+	WHERE id = ANY($1) AND post_type = 'question'`
+
+// getQuestions loads the question state of those of postIDs that are
+// questions, keyed by post
+func getQuestions(ctx context.Context, db *pgxpool.Pool, postIDs []uuid.UUID) (map[uuid.UUID]*Question, error) {
+	if len(postIDs) == 0 {
+		return map[uuid.UUID]*Question{}, nil
+	}
+
+	rows, err := db.Query(ctx, questionsQuery, postIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get questions: %w", err)
+	}
+	return scanQuestions(rows)
+}
+
+func scanQuestions(rows pgx.Rows) (map[uuid.UUID]*Question, error) {
+	defer rows.Close()
+
+	questions := make(map[uuid.UUID]*Question)
+	for rows.Next() {
+		var postID uuid.UUID
+		var question Question
+		err := rows.Scan(&postID, &question.AcceptedCommentID, &question.AcceptedAt, &question.Bounty, &question.BountyAwardedTo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan question: %w", err)
+		}
+		questions[postID] = &question
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read questions: %w", err)
+	}
+	return questions, nil
+}
+
+// Reputation is a user's leaderboard points over all time. Reserved is what
+// their open questions put up as bounties, which they can't offer again.
+type Reputation struct {
+	Points    int `json:"points"`
+	Reserved  int `json:"reserved"`
+	Available int `json:"available"`
+}
+
+const reputationQuery = `
+	SELECT (SELECT COALESCE(SUM(points), 0) FROM leaderboard_points WHERE user_id = $1),
+	       (SELECT COALESCE(SUM(bounty), 0) FROM posts
+	        WHERE author_id = $1 AND bounty > 0 AND bounty_awarded_at IS NULL AND accepted_at IS NULL)`
+
+// GetReputation returns userID's reputation as of the last leaderboard
+// refresh
+func (s *LeaderboardService) GetReputation(ctx context.Context, userID uuid.UUID) (*Reputation, error) {
+	var reputation Reputation
+	err := s.db.Reader().QueryRow(ctx, reputationQuery, userID).Scan(&reputation.Points, &reputation.Reserved)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reputation: %w", err)
+	}
+	reputation.Available = max(reputation.Points-reputation.Reserved, 0)
+	return &reputation, nil
+}
+
+// reserveBounty checks within tx that userID has the reputation to put up
+// bounty on a new question. The user row is locked so two questions can't
+// spend the same points.
+func reserveBounty(ctx context.Context, tx pgx.Tx, userID uuid.UUID, bounty int) error {
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, userID); err != nil {
+		return fmt.Errorf("failed to lock user: %w", err)
+	}
+
+	var points, reserved int
+	if err := tx.QueryRow(ctx, reputationQuery, userID).Scan(&points, &reserved); err != nil {
+		return fmt.Errorf("failed to get reputation: %w", err)
+	}
+	if bounty > points-reserved {
+		return fmt.Errorf("insufficient reputation")
+	}
+	return nil
+}
+
+// AcceptAnswer marks commentID as the answer to the question postID; only
+// the asker may. Accepting another comment replaces the answer. The bounty,
+// if any, goes to the first accepted answer written by someone else and is
+// not taken back when the answer changes.
+func (s *PostsService) AcceptAnswer(ctx context.Context, userID, postID, commentID uuid.UUID) (*Question, error) {
+	var question Question
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		var askerID, answererID uuid.UUID
+		var postType, text string
+		err := tx.QueryRow(ctx, `
+			SELECT p.author_id, p.post_type, c.author_id, c.text
+			FROM comments c
+			JOIN posts p ON p.id = c.post_id
+			WHERE c.id = $1 AND c.post_id = $2
+			FOR UPDATE OF p`, commentID, postID).Scan(&askerID, &postType, &answererID, &text)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("comment not found")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get comment: %w", err)
+		}
+		if postType != PostTypeQuestion {
+			return fmt.Errorf("post is not a question")
+		}
+		if askerID != userID {
+			return fmt.Errorf("only the asker can accept an answer")
+		}
+
+		err = tx.QueryRow(ctx, `
+			UPDATE posts
+			SET accepted_comment_id = $2, accepted_at = now(),
+			    bounty_awarded_to = CASE WHEN bounty > 0 AND bounty_awarded_at IS NULL AND $3 <> author_id
+			                             THEN $3 ELSE bounty_awarded_to END,
+			    bounty_awarded_at = CASE WHEN bounty > 0 AND bounty_awarded_at IS NULL AND $3 <> author_id
+			                             THEN now() ELSE bounty_awarded_at END
+			WHERE id = $1
+			RETURNING accepted_comment_id, accepted_at, bounty, bounty_awarded_to`,
+			postID, commentID, answererID).Scan(
+			&question.AcceptedCommentID, &question.AcceptedAt, &question.Bounty, &question.BountyAwardedTo)
+		if err != nil {
+			return fmt.Errorf("failed to accept answer: %w", err)
+		}
+
+		if s.notificationsService == nil || answererID == userID {
+			return nil
+		}
+		event := OutboxEvent{
+			Type:      NotificationTypeAnswerAccepted,
+			ActorID:   userID,
+			TargetID:  &answererID,
+			PostID:    &postID,
+			CommentID: &commentID,
+			Text:      text,
+		}
+		if question.BountyAwardedTo != nil && *question.BountyAwardedTo == answererID {
+			event.Amount = question.Bounty
+		}
+		return enqueueNotification(ctx, tx, event)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.postDetails.Forget(postID.String())
+	return &question, nil
+}
+
+// UnacceptAnswer withdraws commentID as the answer to the question postID.
+// It is a no-op if another comment, or none, is accepted.
+func (s *PostsService) UnacceptAnswer(ctx context.Context, userID, postID, commentID uuid.UUID) (*Question, error) {
+	var askerID uuid.UUID
+	var postType string
+	err := s.db.QueryRow(ctx, `
+		SELECT author_id, post_type FROM posts WHERE id = $1`, postID).Scan(&askerID, &postType)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("post not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post: %w", err)
+	}
+	if postType != PostTypeQuestion {
+		return nil, fmt.Errorf("post is not a question")
+	}
+	if askerID != userID {
+		return nil, fmt.Errorf("only the asker can accept an answer")
+	}
+
+	var question Question
+	err = s.db.QueryRow(ctx, `
+		UPDATE posts
+		SET accepted_comment_id = CASE WHEN accepted_comment_id = $2 THEN NULL ELSE accepted_comment_id END,
+		    accepted_at = CASE WHEN accepted_comment_id = $2 THEN NULL ELSE accepted_at END
+		WHERE id = $1
+		RETURNING accepted_comment_id, accepted_at, bounty, bounty_awarded_to`, postID, commentID).Scan(
+		&question.AcceptedCommentID, &question.AcceptedAt, &question.Bounty, &question.BountyAwardedTo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unaccept answer: %w", err)
+	}
+	s.postDetails.Forget(postID.String())
+	return &question, nil
+}
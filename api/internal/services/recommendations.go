@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Signal weights used to rank follow recommendations
+const (
+	recommendMutualWeight = 3
+	recommendCourseWeight = 2
+	recommendTagWeight    = 1
+)
+
+type UserRecommendation struct {
+	User           UserResponse `json:"user"`
+	Reason         string       `json:"reason"`
+	MutualFollows  int          `json:"mutual_follows"`
+	SharedCourses  int          `json:"shared_courses"`
+	SharedHashtags int          `json:"shared_hashtags"`
+}
+
+// GetRecommendedUsers suggests accounts followed by people the user follows,
+// active in the same courses, or posting under the same hashtags. Course
+// participation is derived from course-tagged posts. Followed, blocked (in
+// either direction) and shadow-banned users are excluded, as is anyone
+// outside the user's organization.
+func (s *SocialService) GetRecommendedUsers(ctx context.Context, userID uuid.UUID, limit int) ([]*UserRecommendation, error) {
+	rows, err := s.db.Query(ctx, `
+		WITH excluded AS (
+		    SELECT $1::uuid AS id
+		    UNION SELECT followee_id FROM follows WHERE follower_id = $1
+		    UNION SELECT blocked_id FROM user_blocks WHERE blocker_id = $1
+		    UNION SELECT blocker_id FROM user_blocks WHERE blocked_id = $1
+		),
+		fof AS (
+		    SELECT f2.followee_id AS user_id, COUNT(*) AS mutuals
+		    FROM follows f1
+		    JOIN follows f2 ON f2.follower_id = f1.followee_id
+		    WHERE f1.follower_id = $1
+		    GROUP BY f2.followee_id
+		),
+		my_courses AS (
+		    SELECT DISTINCT course_id FROM posts
+		    WHERE author_id = $1 AND course_id IS NOT NULL
+		),
+		course_peers AS (
+		    SELECT p.author_id AS user_id, COUNT(DISTINCT p.course_id) AS shared_courses
+		    FROM posts p
+		    JOIN my_courses mc ON mc.course_id = p.course_id
+		    GROUP BY p.author_id
+		),
+		my_tags AS (
+		    SELECT DISTINCT ph.hashtag_id
+		    FROM post_hashtags ph
+		    JOIN posts p ON p.id = ph.post_id
+		    WHERE p.author_id = $1
+		),
+		tag_peers AS (
+		    SELECT p.author_id AS user_id, COUNT(DISTINCT ph.hashtag_id) AS shared_tags
+		    FROM posts p
+		    JOIN post_hashtags ph ON ph.post_id = p.id
+		    JOIN my_tags mt ON mt.hashtag_id = ph.hashtag_id
+		    GROUP BY p.author_id
+		),
+		candidates AS (
+		    SELECT user_id FROM fof
+		    UNION SELECT user_id FROM course_peers
+		    UNION SELECT user_id FROM tag_peers
+		)
+		SELECT u.id, u.username, u.email, u.bio, u.avatar_url,
+		       COALESCE(fof.mutuals, 0), COALESCE(cp.shared_courses, 0), COALESCE(tp.shared_tags, 0)
+		FROM candidates c
+		JOIN users u ON u.id = c.user_id
+		LEFT JOIN fof ON fof.user_id = c.user_id
+		LEFT JOIN course_peers cp ON cp.user_id = c.user_id
+		LEFT JOIN tag_peers tp ON tp.user_id = c.user_id
+		WHERE c.user_id NOT IN (SELECT id FROM excluded) AND NOT u.shadow_banned
+		  AND u.org_id = (SELECT org_id FROM users WHERE id = $1)
+		ORDER BY $3 * COALESCE(fof.mutuals, 0) + $4 * COALESCE(cp.shared_courses, 0) + $5 * COALESCE(tp.shared_tags, 0) DESC,
+		         u.username
+		LIMIT $2`,
+		userID, limit, recommendMutualWeight, recommendCourseWeight, recommendTagWeight)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recommendations: %w", err)
+	}
+	defer rows.Close()
+
+	var recommendations []*UserRecommendation
+	for rows.Next() {
+		var rec UserRecommendation
+		var bio, avatarURL pgtype.Text
+
+		err := rows.Scan(
+			&rec.User.ID, &rec.User.Username, &rec.User.Email, &bio, &avatarURL,
+			&rec.MutualFollows, &rec.SharedCourses, &rec.SharedHashtags)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan recommendation: %w", err)
+		}
+
+		rec.User.Bio = getPgtypeTextValue(bio)
+		rec.User.AvatarURL = getPgtypeTextPtr(avatarURL)
+		rec.Reason = recommendationReason(rec.MutualFollows, rec.SharedCourses, rec.SharedHashtags)
+
+		recommendations = append(recommendations, &rec)
+	}
+
+	return recommendations, nil
+}
+
+// recommendationReason explains a suggestion by its strongest weighted signal
+func recommendationReason(mutuals, courses, tags int) string {
+	mutualScore := mutuals * recommendMutualWeight
+	courseScore := courses * recommendCourseWeight
+	tagScore := tags * recommendTagWeight
+
+	switch {
+	case mutuals > 0 && mutualScore >= courseScore && mutualScore >= tagScore:
+		if mutuals == 1 {
+			return "Followed by someone you follow"
+		}
+		return fmt.Sprintf("Followed by %d people you follow", mutuals)
+	case courses > 0 && courseScore >= tagScore:
+		if courses == 1 {
+			return "Active in a course you post in"
+		}
+		return fmt.Sprintf("Active in %d of your courses", courses)
+	case tags > 0:
+		if tags == 1 {
+			return "Posts about a hashtag you use"
+		}
+		return fmt.Sprintf("Posts about %d hashtags you use", tags)
+	default:
+		return "Suggested for you"
+	}
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecommendationReason(t *testing.T) {
+	tests := []struct {
+		name     string
+		mutuals  int
+		courses  int
+		tags     int
+		expected string
+	}{
+		{
+			name:     "single mutual",
+			mutuals:  1,
+			expected: "Followed by someone you follow",
+		},
+		{
+			name:     "mutuals outweigh tags",
+			mutuals:  2,
+			tags:     5,
+			expected: "Followed by 2 people you follow",
+		},
+		{
+			name:     "courses outweigh single mutual",
+			mutuals:  1,
+			courses:  2,
+			expected: "Active in 2 of your courses",
+		},
+		{
+			name:     "tags only",
+			tags:     3,
+			expected: "Posts about 3 hashtags you use",
+		},
+		{
+			name:     "no signals",
+			expected: "Suggested for you",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, recommendationReason(tt.mutuals, tt.courses, tt.tags))
+		})
+	}
+}
@@ -0,0 +1,469 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"bailanysta/api/internal/pkg/database"
+	"bailanysta/api/internal/pkg/mailer"
+)
+
+// RecoveryCodeCount is how many codes each generation issues
+const RecoveryCodeCount = 10
+
+// recoveryCodeAlphabet leaves out characters that are easy to misread
+const recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// Recovery token purposes
+const (
+	recoveryPurposeVerify  = "verify_backup_email"
+	recoveryPurposeRecover = "recover"
+)
+
+// Account recovery audit events
+const (
+	RecoveryEventBackupEmailSet      = "backup_email_set"
+	RecoveryEventBackupEmailVerified = "backup_email_verified"
+	RecoveryEventBackupEmailRemoved  = "backup_email_removed"
+	RecoveryEventCodesGenerated      = "codes_generated"
+	RecoveryEventRecoveryEmailSent   = "recovery_email_sent"
+	RecoveryEventRecoveredWithEmail  = "recovered_with_email"
+	RecoveryEventRecoveredWithCode   = "recovered_with_code"
+)
+
+// RecoveryService lets users back into their account without its password,
+// through a verified backup email or a one-time recovery code. Every step
+// is recorded in the account's recovery audit.
+type RecoveryService struct {
+	db       *database.Pool
+	auth     *AuthService
+	mailer   *mailer.Mailer // nil when email is not configured
+	appURL   string
+	tokenTTL time.Duration
+}
+
+type RecoveryEvent struct {
+	Event     string    `json:"event"`
+	IPAddress string    `json:"ip_address"`
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RecoveryStatus is what a user has set up to recover their account
+type RecoveryStatus struct {
+	BackupEmail         *string          `json:"backup_email"`
+	BackupEmailVerified bool             `json:"backup_email_verified"`
+	CodesRemaining      int              `json:"codes_remaining"`
+	CodesGeneratedAt    *time.Time       `json:"codes_generated_at,omitempty"`
+	Events              []*RecoveryEvent `json:"events"` // latest 20
+}
+
+// RecoverAccountRequest proves ownership with either the token from a
+// recovery email or the account email and one of its recovery codes
+type RecoverAccountRequest struct {
+	Token       string `json:"token,omitempty"`
+	Email       string `json:"email,omitempty" validate:"omitempty,email"`
+	Code        string `json:"code,omitempty"`
+	NewPassword string `json:"new_password" validate:"required,min=6"`
+}
+
+func NewRecoveryService(db *database.Pool, auth *AuthService, mailer *mailer.Mailer, appURL string, tokenTTL time.Duration) *RecoveryService {
+	return &RecoveryService{
+		db:       db,
+		auth:     auth,
+		mailer:   mailer,
+		appURL:   strings.TrimRight(appURL, "/"),
+		tokenTTL: tokenTTL,
+	}
+}
+
+// Status returns userID's recovery options and recent recovery activity
+func (s *RecoveryService) Status(ctx context.Context, userID uuid.UUID) (*RecoveryStatus, error) {
+	var status RecoveryStatus
+	var verifiedAt *time.Time
+	err := s.db.QueryRow(ctx, `
+		SELECT u.backup_email, u.backup_email_verified_at,
+		       (SELECT count(*) FROM recovery_codes WHERE user_id = u.id AND used_at IS NULL),
+		       (SELECT max(created_at) FROM recovery_codes WHERE user_id = u.id)
+		FROM users u WHERE u.id = $1`, userID).Scan(
+		&status.BackupEmail, &verifiedAt, &status.CodesRemaining, &status.CodesGeneratedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("user not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recovery status: %w", err)
+	}
+	status.BackupEmailVerified = verifiedAt != nil
+
+	rows, err := s.db.Query(ctx, `
+		SELECT event, ip_address, user_agent, created_at
+		FROM account_recovery_events
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT 20`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recovery events: %w", err)
+	}
+	defer rows.Close()
+
+	status.Events = []*RecoveryEvent{}
+	for rows.Next() {
+		var event RecoveryEvent
+		if err := rows.Scan(&event.Event, &event.IPAddress, &event.UserAgent, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan recovery event: %w", err)
+		}
+		status.Events = append(status.Events, &event)
+	}
+	return &status, rows.Err()
+}
+
+// SetBackupEmail replaces userID's backup email with an unverified one and
+// emails it a verification link. It can't be used for recovery until
+// verified. Like new recovery codes it needs the current password, since a
+// backup email is a way into the account.
+func (s *RecoveryService) SetBackupEmail(ctx context.Context, userID uuid.UUID, password, email string, client ClientInfo) error {
+	if s.mailer == nil {
+		return fmt.Errorf("email is not configured")
+	}
+	if err := s.checkPassword(ctx, userID, password); err != nil {
+		return err
+	}
+	email = strings.TrimSpace(email)
+
+	token, err := generateInviteToken()
+	if err != nil {
+		return fmt.Errorf("failed to generate token: %w", err)
+	}
+	err = pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			UPDATE users SET backup_email = $2, backup_email_verified_at = NULL
+			WHERE id = $1 AND lower(email) <> lower($2)`, userID, email)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			return fmt.Errorf("backup email must differ from account email")
+		}
+		// Earlier links, including recovery ones sent to the old address, stop working
+		if _, err := tx.Exec(ctx, `DELETE FROM recovery_tokens WHERE user_id = $1`, userID); err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `
+			INSERT INTO recovery_tokens (token_hash, user_id, purpose, email, expires_at)
+			VALUES ($1, $2, $3, $4, $5)`,
+			hashToken(token), userID, recoveryPurposeVerify, email, time.Now().Add(s.tokenTTL))
+		if err != nil {
+			return err
+		}
+		return logRecoveryEvent(ctx, tx, userID, RecoveryEventBackupEmailSet, client)
+	})
+	if err != nil {
+		if err.Error() == "backup email must differ from account email" {
+			return err
+		}
+		return fmt.Errorf("failed to set backup email: %w", err)
+	}
+
+	link := s.appURL + "/settings/recovery/verify?token=" + url.QueryEscape(token)
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Confirm your Bailanysta backup email",
+		HTML: fmt.Sprintf(`<p>This address was added as the backup email of a Bailanysta account.</p>`+
+			`<p><a href="%s">Confirm it</a> to use it if you ever lose access to your account.</p>`+
+			`<p>If you didn't add it, ignore this email.</p>`, link),
+		Text: fmt.Sprintf("This address was added as the backup email of a Bailanysta account.\n\n"+
+			"Confirm it to use it if you ever lose access to your account: %s\n\n"+
+			"If you didn't add it, ignore this email.\n", link),
+	})
+}
+
+// VerifyBackupEmail confirms the backup email a verification token was
+// sent to, if it is still the account's backup email
+func (s *RecoveryService) VerifyBackupEmail(ctx context.Context, token string, client ClientInfo) error {
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		var userID uuid.UUID
+		err := tx.QueryRow(ctx, `
+			DELETE FROM recovery_tokens t
+			USING users u
+			WHERE t.token_hash = $1 AND t.purpose = $2 AND t.expires_at > now()
+			  AND u.id = t.user_id AND u.backup_email = t.email
+			RETURNING t.user_id`, hashToken(token), recoveryPurposeVerify).Scan(&userID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("invalid token")
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `UPDATE users SET backup_email_verified_at = now() WHERE id = $1`, userID); err != nil {
+			return err
+		}
+		return logRecoveryEvent(ctx, tx, userID, RecoveryEventBackupEmailVerified, client)
+	})
+	if err != nil {
+		if err.Error() == "invalid token" {
+			return err
+		}
+		return fmt.Errorf("failed to verify backup email: %w", err)
+	}
+	return nil
+}
+
+// RemoveBackupEmail removes userID's backup email and any links sent to it
+func (s *RecoveryService) RemoveBackupEmail(ctx context.Context, userID uuid.UUID, client ClientInfo) error {
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			UPDATE users SET backup_email = NULL, backup_email_verified_at = NULL
+			WHERE id = $1 AND backup_email IS NOT NULL`, userID)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			return fmt.Errorf("no backup email")
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM recovery_tokens WHERE user_id = $1`, userID); err != nil {
+			return err
+		}
+		return logRecoveryEvent(ctx, tx, userID, RecoveryEventBackupEmailRemoved, client)
+	})
+	if err != nil {
+		if err.Error() == "no backup email" {
+			return err
+		}
+		return fmt.Errorf("failed to remove backup email: %w", err)
+	}
+	return nil
+}
+
+// RegenerateCodes replaces all of userID's recovery codes with
+// RecoveryCodeCount new ones, which are only ever returned here. The
+// current password is required so a stolen session can't take them.
+func (s *RecoveryService) RegenerateCodes(ctx context.Context, userID uuid.UUID, password string, client ClientInfo) ([]string, error) {
+	if err := s.checkPassword(ctx, userID, password); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, RecoveryCodeCount)
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		codes[i] = code
+	}
+
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+			return err
+		}
+		for _, code := range codes {
+			_, err := tx.Exec(ctx, `INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)`,
+				userID, hashToken(normalizeRecoveryCode(code)))
+			if err != nil {
+				return err
+			}
+		}
+		return logRecoveryEvent(ctx, tx, userID, RecoveryEventCodesGenerated, client)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store recovery codes: %w", err)
+	}
+	return codes, nil
+}
+
+// checkPassword fails with "invalid password" unless password is userID's
+// current password
+func (s *RecoveryService) checkPassword(ctx context.Context, userID uuid.UUID, password string) error {
+	var passwordHash string
+	err := s.db.QueryRow(ctx, `SELECT password_hash FROM users WHERE id = $1`, userID).Scan(&passwordHash)
+	if err != nil {
+		return fmt.Errorf("user not found")
+	}
+	if !checkPasswordHash(password, passwordHash) {
+		return fmt.Errorf("invalid password")
+	}
+	return nil
+}
+
+// StartRecovery emails a recovery link to the verified backup email of the
+// account with email. Unknown accounts and ones without a verified backup
+// email are not an error, so the response doesn't reveal either.
+func (s *RecoveryService) StartRecovery(ctx context.Context, email string, client ClientInfo) error {
+	if s.mailer == nil {
+		return fmt.Errorf("email is not configured")
+	}
+
+	var userID uuid.UUID
+	var backupEmail string
+	err := s.db.QueryRow(ctx, `
+		SELECT id, backup_email FROM users
+		WHERE email = $1 AND backup_email IS NOT NULL AND backup_email_verified_at IS NOT NULL`,
+		strings.TrimSpace(email)).Scan(&userID, &backupEmail)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	token, err := generateInviteToken()
+	if err != nil {
+		return fmt.Errorf("failed to generate token: %w", err)
+	}
+	err = pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		// Only the latest link works
+		_, err := tx.Exec(ctx, `DELETE FROM recovery_tokens WHERE user_id = $1 AND purpose = $2`, userID, recoveryPurposeRecover)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `
+			INSERT INTO recovery_tokens (token_hash, user_id, purpose, email, expires_at)
+			VALUES ($1, $2, $3, $4, $5)`,
+			hashToken(token), userID, recoveryPurposeRecover, backupEmail, time.Now().Add(s.tokenTTL))
+		if err != nil {
+			return err
+		}
+		return logRecoveryEvent(ctx, tx, userID, RecoveryEventRecoveryEmailSent, client)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start recovery: %w", err)
+	}
+
+	link := s.appURL + "/recover?token=" + url.QueryEscape(token)
+	minutes := int(s.tokenTTL.Minutes())
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      backupEmail,
+		Subject: "Recover your Bailanysta account",
+		HTML: fmt.Sprintf(`<p>Someone asked to recover the Bailanysta account this is the backup email of.</p>`+
+			`<p><a href="%s">Choose a new password</a>. The link works once, for %d minutes.</p>`+
+			`<p>If this wasn't you, ignore this email; your password stays the same.</p>`, link, minutes),
+		Text: fmt.Sprintf("Someone asked to recover the Bailanysta account this is the backup email of.\n\n"+
+			"Choose a new password: %s\nThe link works once, for %d minutes.\n\n"+
+			"If this wasn't you, ignore this email; your password stays the same.\n", link, minutes),
+	})
+}
+
+// Recover sets a new password using a recovery email token or a recovery
+// code, which are used up, signs the account out everywhere and starts a
+// session for client
+func (s *RecoveryService) Recover(ctx context.Context, req RecoverAccountRequest, client ClientInfo) (*AuthResponse, error) {
+	withToken := req.Token != ""
+	if withToken == (req.Email != "" || req.Code != "") || (!withToken && (req.Email == "" || req.Code == "")) {
+		return nil, fmt.Errorf("token or email and code is required")
+	}
+
+	passwordHash, err := hashPassword(req.NewPassword)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	var userID uuid.UUID
+	err = pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		event := RecoveryEventRecoveredWithEmail
+		var err error
+		if withToken {
+			err = tx.QueryRow(ctx, `
+				DELETE FROM recovery_tokens
+				WHERE token_hash = $1 AND purpose = $2 AND expires_at > now()
+				RETURNING user_id`, hashToken(req.Token), recoveryPurposeRecover).Scan(&userID)
+		} else {
+			event = RecoveryEventRecoveredWithCode
+			err = tx.QueryRow(ctx, `
+				UPDATE recovery_codes SET used_at = now()
+				WHERE code_hash = $1 AND used_at IS NULL
+				  AND user_id = (SELECT id FROM users WHERE email = $2)
+				RETURNING user_id`, hashToken(normalizeRecoveryCode(req.Code)), strings.TrimSpace(req.Email)).Scan(&userID)
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("invalid recovery credentials")
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, `UPDATE users SET password_hash = $2 WHERE id = $1`, userID, passwordHash); err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `UPDATE refresh_tokens SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL`, userID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `DELETE FROM recovery_tokens WHERE user_id = $1 AND purpose = $2`, userID, recoveryPurposeRecover)
+		if err != nil {
+			return err
+		}
+		return logRecoveryEvent(ctx, tx, userID, event, client)
+	})
+	if err != nil {
+		if err.Error() == "invalid recovery credentials" {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to recover account: %w", err)
+	}
+
+	var user User
+	var role Role
+	err = s.db.QueryRow(ctx, `
+		SELECT id, username, email, bio, avatar_url, org_id, role
+		FROM users WHERE id = $1`, userID).Scan(
+		&user.ID, &user.Username, &user.Email, &user.Bio, &user.AvatarURL, &user.OrgID, &role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	tokens, err := s.auth.startSession(ctx, user.ID, user.OrgID, role, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate tokens: %w", err)
+	}
+
+	return &AuthResponse{
+		User: UserResponse{
+			ID:        user.ID,
+			Username:  user.Username,
+			Email:     user.Email,
+			Bio:       getNullStringValue(user.Bio),
+			AvatarURL: getNullStringPtr(user.AvatarURL),
+		},
+		Tokens: *tokens,
+	}, nil
+}
+
+func logRecoveryEvent(ctx context.Context, db execer, userID uuid.UUID, event string, client ClientInfo) error {
+	_, err := db.Exec(ctx, `
+		INSERT INTO account_recovery_events (user_id, event, ip_address, user_agent)
+		VALUES ($1, $2, $3, $4)`, userID, event, client.IP, truncateRunes(client.UserAgent, 500))
+	return err
+}
+
+// generateRecoveryCode returns a code like "k7m2p-x9qre", about 49 bits
+func generateRecoveryCode() (string, error) {
+	bytes := make([]byte, 10)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	code := make([]byte, 0, 11)
+	for i, b := range bytes {
+		if i == 5 {
+			code = append(code, '-')
+		}
+		// 256 isn't a multiple of the alphabet size; the bias is negligible here
+		code = append(code, recoveryCodeAlphabet[int(b)%len(recoveryCodeAlphabet)])
+	}
+	return string(code), nil
+}
+
+// normalizeRecoveryCode ignores case, spaces and dashes in typed codes
+func normalizeRecoveryCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(code)))
+}
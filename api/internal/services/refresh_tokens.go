@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"bailanysta/api/internal/pkg/auth"
+)
+
+// Session is a signed-in device, as of its latest refresh
+type Session struct {
+	ID         uuid.UUID `json:"id"`
+	Device     string    `json:"device"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	Country    string    `json:"country,omitempty"`
+	City       string    `json:"city,omitempty"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// issueTokens generates a token pair for a session and stores its refresh
+// token with the device it went to, dropping the user's expired ones. It
+// returns the refresh token's ID.
+func (s *AuthService) issueTokens(ctx context.Context, userID, orgID uuid.UUID, role Role, device SessionDevice) (*auth.TokenPair, uuid.UUID, error) {
+	tokens, err := s.jwtManager.GenerateTokenPair(userID, orgID, string(role), auth.SessionScopes)
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+
+	var country, city string
+	if device.Location != nil {
+		country, city = device.Location.Country, device.Location.City
+	}
+	var sessionID uuid.UUID
+	err = s.db.QueryRow(ctx, `
+		INSERT INTO refresh_tokens (user_id, token_hash, expires_at, user_agent, device, ip_address, country, city)
+		VALUES ($1, $2, now() + make_interval(secs => $3::float8), $4, $5, $6, $7, $8)
+		RETURNING id`,
+		userID, hashToken(tokens.RefreshToken), s.jwtManager.RefreshExpiry().Seconds(),
+		device.UserAgent, device.Agent.String(), device.IP, country, city).Scan(&sessionID)
+	if err != nil {
+		return nil, uuid.Nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	if _, err := s.db.Exec(ctx, `DELETE FROM refresh_tokens WHERE user_id = $1 AND expires_at <= now()`, userID); err != nil {
+		fmt.Printf("Failed to delete expired refresh tokens: %v\n", err)
+	}
+
+	return tokens, sessionID, nil
+}
+
+// startSession issues tokens to client and records its device, warning
+// the user if it is new to them
+func (s *AuthService) startSession(ctx context.Context, userID, orgID uuid.UUID, role Role, client ClientInfo) (*auth.TokenPair, error) {
+	device := s.devices.Describe(client)
+	tokens, sessionID, err := s.issueTokens(ctx, userID, orgID, role, device)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.devices.Seen(ctx, userID, sessionID, device); err != nil {
+		fmt.Printf("Failed to record session device: %v\n", err)
+	}
+	return tokens, nil
+}
+
+// RefreshToken exchanges a refresh token for a new pair. The old token is
+// revoked, so each one works once.
+func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string, client ClientInfo) (*auth.TokenPair, error) {
+	if err := s.jwtManager.ValidateRefreshToken(refreshToken); err != nil {
+		return nil, fmt.Errorf("invalid refresh token")
+	}
+
+	var userID, orgID uuid.UUID
+	var role Role
+	err := s.db.QueryRow(ctx, `
+		UPDATE refresh_tokens t SET revoked_at = now()
+		FROM users u
+		WHERE t.token_hash = $1 AND t.revoked_at IS NULL AND t.expires_at > now() AND u.id = t.user_id
+		RETURNING u.id, u.org_id, u.role`, hashToken(refreshToken),
+	).Scan(&userID, &orgID, &role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("invalid refresh token")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh token: %w", err)
+	}
+
+	tokens, err := s.startSession(ctx, userID, orgID, role, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// RevokeRefreshToken ends the session of a refresh token; unknown tokens
+// are ignored
+func (s *AuthService) RevokeRefreshToken(ctx context.Context, refreshToken string) error {
+	_, err := s.db.Exec(ctx, `
+		UPDATE refresh_tokens SET revoked_at = now()
+		WHERE token_hash = $1 AND revoked_at IS NULL`, hashToken(refreshToken))
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+	return nil
+}
+
+// GetSessions lists userID's signed-in devices, most recently used first
+func (s *AuthService) GetSessions(ctx context.Context, userID uuid.UUID) ([]*Session, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT id, device, user_agent, ip_address, country, city, created_at, expires_at
+		FROM refresh_tokens
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > now()
+		ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []*Session{}
+	for rows.Next() {
+		var session Session
+		err := rows.Scan(&session.ID, &session.Device, &session.UserAgent, &session.IPAddress,
+			&session.Country, &session.City, &session.LastUsedAt, &session.ExpiresAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, &session)
+	}
+	return sessions, rows.Err()
+}
+
+// RevokeSession signs userID out of one of their sessions. Its access
+// token stays valid until it expires.
+func (s *AuthService) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	result, err := s.db.Exec(ctx, `
+		UPDATE refresh_tokens SET revoked_at = now()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`, sessionID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("session not found")
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Role is what a user is at their school; it decides their permissions
+type Role string
+
+const (
+	RoleStudent   Role = "student"
+	RoleTeacher   Role = "teacher"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+	// RoleSuperadmin runs the deployment rather than a school. It is only
+	// granted with the admin command, never through SetRole.
+	RoleSuperadmin Role = "superadmin"
+)
+
+// Permission is an action that only some roles may take
+type Permission string
+
+const (
+	PermissionManageCourses Permission = "manage_courses" // create courses and modules
+	PermissionVerifyAnswers Permission = "verify_answers" // mark comments on course posts as answers
+	PermissionModerate      Permission = "moderate"       // review queue, shadow bans, post history
+	PermissionManageRoles   Permission = "manage_roles"
+	PermissionManageSystem  Permission = "manage_system" // log levels, organizations; superadmins only
+	PermissionExportData    Permission = "export_data"   // bulk post exports for research
+	PermissionImpersonate   Permission = "impersonate"   // act as a user for support
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleTeacher:    {PermissionManageCourses, PermissionVerifyAnswers},
+	RoleModerator:  {PermissionModerate},
+	RoleAdmin:      {PermissionManageCourses, PermissionVerifyAnswers, PermissionModerate, PermissionManageRoles, PermissionExportData, PermissionImpersonate},
+	RoleSuperadmin: {PermissionManageCourses, PermissionVerifyAnswers, PermissionModerate, PermissionManageRoles, PermissionManageSystem, PermissionExportData, PermissionImpersonate},
+}
+
+// ValidRole reports whether role is one of the roles an organization's
+// admins may assign
+func ValidRole(role Role) bool {
+	switch role {
+	case RoleStudent, RoleTeacher, RoleModerator, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+// Can reports whether the role grants permission
+func (r Role) Can(permission Permission) bool {
+	for _, p := range rolePermissions[r] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// GetRole fails with "user not found" for unknown users
+func (s *AuthService) GetRole(ctx context.Context, userID uuid.UUID) (Role, error) {
+	var role Role
+	err := s.db.QueryRow(ctx, `SELECT role FROM users WHERE id = $1`, userID).Scan(&role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", fmt.Errorf("user not found")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get role: %w", err)
+	}
+	return role, nil
+}
+
+// HasPermission reports whether the user's role grants permission
+func (s *AuthService) HasPermission(ctx context.Context, userID uuid.UUID, permission Permission) (bool, error) {
+	role, err := s.GetRole(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return role.Can(permission), nil
+}
+
+// SetRole assigns a role to a user of orgID; admins can't demote themselves
+// so an organization is never left without one by accident. Superadmins
+// are left alone: only SetSuperadmin changes them.
+func (s *AuthService) SetRole(ctx context.Context, orgID, adminID, userID uuid.UUID, role Role) error {
+	if !ValidRole(role) {
+		return fmt.Errorf("invalid role")
+	}
+	if userID == adminID && role != RoleAdmin {
+		return fmt.Errorf("cannot change own role")
+	}
+
+	result, err := s.db.Exec(ctx, `
+		UPDATE users SET role = $3 WHERE id = $1 AND org_id = $2 AND role <> $4`,
+		userID, orgID, role, RoleSuperadmin)
+	if err != nil {
+		return fmt.Errorf("failed to update role: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		var superadmin bool
+		err := s.db.QueryRow(ctx, `
+			SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND org_id = $2 AND role = $3)`,
+			userID, orgID, RoleSuperadmin).Scan(&superadmin)
+		if err == nil && superadmin {
+			return fmt.Errorf("cannot change a superadmin's role")
+		}
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
+
+// SetSuperadmin makes the user with email a superadmin, or a superadmin an
+// ordinary admin of their organization again. It is for the admin command,
+// which only whoever runs the deployment can use.
+func (s *AuthService) SetSuperadmin(ctx context.Context, email string, superadmin bool) error {
+	role, query := RoleSuperadmin, `UPDATE users SET role = $2 WHERE email = $1`
+	if !superadmin {
+		role, query = RoleAdmin, query+` AND role = 'superadmin'`
+	}
+	result, err := s.db.Exec(ctx, query, normalizeEmail(email), role)
+	if err != nil {
+		return fmt.Errorf("failed to update role: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
+
+// GetUsersByRole lists the users of orgID that have role
+func (s *AuthService) GetUsersByRole(ctx context.Context, orgID uuid.UUID, role Role) ([]*UserResponse, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT id, username, role FROM users
+		WHERE org_id = $1 AND role = $2
+		ORDER BY username`, orgID, role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []*UserResponse{}
+	for rows.Next() {
+		var user UserResponse
+		if err := rows.Scan(&user.ID, &user.Username, &user.Role); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, &user)
+	}
+	return users, rows.Err()
+}
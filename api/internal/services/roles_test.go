@@ -0,0 +1,41 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoleCan(t *testing.T) {
+	tests := []struct {
+		role       Role
+		permission Permission
+		want       bool
+	}{
+		{RoleStudent, PermissionManageCourses, false},
+		{RoleTeacher, PermissionManageCourses, true},
+		{RoleTeacher, PermissionModerate, false},
+		{RoleModerator, PermissionModerate, true},
+		{RoleModerator, PermissionManageRoles, false},
+		{RoleAdmin, PermissionManageSystem, false},
+		{RoleSuperadmin, PermissionManageSystem, true},
+		{RoleSuperadmin, PermissionManageRoles, true},
+		{RoleAdmin, PermissionExportData, true},
+		{RoleModerator, PermissionExportData, false},
+		{RoleAdmin, PermissionImpersonate, true},
+		{RoleModerator, PermissionImpersonate, false},
+		{Role("owner"), PermissionModerate, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.role)+"/"+string(tt.permission), func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.role.Can(tt.permission))
+		})
+	}
+}
+
+func TestValidRole(t *testing.T) {
+	assert.True(t, ValidRole(RoleAdmin))
+	assert.False(t, ValidRole(RoleSuperadmin), "superadmins are not assigned through SetRole")
+	assert.False(t, ValidRole(Role("owner")))
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"bailanysta/api/internal/pkg/database"
+	"bailanysta/api/internal/pkg/qr"
+	"bailanysta/api/internal/pkg/sharecard"
+)
+
+const (
+	// qrScale is the size of a QR code module in pixels; a typical post
+	// link makes a code of about 330px
+	qrScale = 8
+
+	metaDescriptionChars = 200
+)
+
+// markdownMarks are left out of the text on share cards
+var markdownMarks = strings.NewReplacer("**", "", "~~", "", "```", "", "`", "")
+
+// SharingService provides what social sites and printouts show for a post:
+// link unfurling metadata, a preview card and a QR code of its link. Like
+// feeds, these only exist for public posts: not in a group, by an account
+// that is neither private nor shadow-banned.
+type SharingService struct {
+	db     *database.Pool
+	appURL string
+	apiURL string
+}
+
+// PostMeta is what a server-side renderer needs for a post's Open Graph and
+// Twitter card tags
+type PostMeta struct {
+	Title        string    `json:"title"`
+	Description  string    `json:"description"`
+	Image        string    `json:"image"`
+	ImageWidth   int       `json:"image_width"`
+	ImageHeight  int       `json:"image_height"`
+	CanonicalURL string    `json:"canonical_url"`
+	SiteName     string    `json:"site_name"`
+	Author       string    `json:"author"`
+	PublishedAt  time.Time `json:"published_at"`
+	ModifiedAt   time.Time `json:"modified_at"`
+}
+
+type sharedPost struct {
+	id        uuid.UUID
+	slug      string
+	text      string
+	username  string
+	createdAt time.Time
+	updatedAt time.Time
+}
+
+// NewSharingService creates the service; links to posts point at appURL and
+// image links at apiURL
+func NewSharingService(db *database.Pool, appURL, apiURL string) *SharingService {
+	return &SharingService{
+		db:     db,
+		appURL: strings.TrimRight(appURL, "/"),
+		apiURL: strings.TrimRight(apiURL, "/"),
+	}
+}
+
+// publicPost looks a post up by ID or slug. It fails with "post not found"
+// unless the post is public in orgID.
+func (s *SharingService) publicPost(ctx context.Context, orgID uuid.UUID, idOrSlug string) (*sharedPost, error) {
+	var postID *uuid.UUID
+	var seq *int64
+	if id, err := uuid.Parse(idOrSlug); err == nil {
+		postID = &id
+	} else if n, ok := parsePostSlug(idOrSlug); ok {
+		seq = &n
+	} else {
+		return nil, fmt.Errorf("post not found")
+	}
+
+	var post sharedPost
+	err := s.db.Reader().QueryRow(ctx, `
+		SELECT p.id, p.slug, p.text, u.username, p.created_at, p.updated_at
+		FROM posts p
+		JOIN users u ON p.author_id = u.id
+		WHERE (p.id = $1 OR p.seq = $2) AND p.org_id = $3
+		  AND p.group_id IS NULL AND NOT u.is_private AND NOT u.shadow_banned`,
+		postID, seq, orgID).Scan(&post.id, &post.slug, &post.text, &post.username, &post.createdAt, &post.updatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("post not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post: %w", err)
+	}
+	return &post, nil
+}
+
+// postURL is the post's page in the web app
+func (s *SharingService) postURL(slug string) string {
+	return s.appURL + "/post/" + url.PathEscape(slug)
+}
+
+// Meta returns the unfurling metadata of a public post. The title is the
+// post's first line, the description the start of its text and the image
+// its share card.
+func (s *SharingService) Meta(ctx context.Context, orgID uuid.UUID, idOrSlug string) (*PostMeta, error) {
+	post, err := s.publicPost(ctx, orgID, idOrSlug)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PostMeta{
+		Title:        syndicationTitle(markdownMarks.Replace(post.text)),
+		Description:  metaDescription(post.text),
+		Image:        s.apiURL + "/api/v1/posts/" + url.PathEscape(post.slug) + "/share-card.png",
+		ImageWidth:   sharecard.Width,
+		ImageHeight:  sharecard.Height,
+		CanonicalURL: s.postURL(post.slug),
+		SiteName:     "Bailanysta",
+		Author:       "@" + post.username,
+		PublishedAt:  post.createdAt,
+		ModifiedAt:   post.updatedAt,
+	}, nil
+}
+
+// metaDescription is text as one line without Markdown marks, cut to
+// metaDescriptionChars characters
+func metaDescription(text string) string {
+	description := strings.Join(strings.Fields(markdownMarks.Replace(text)), " ")
+	if utf8.RuneCountInString(description) <= metaDescriptionChars {
+		return description
+	}
+
+	runes := []rune(description)
+	return strings.TrimSpace(string(runes[:metaDescriptionChars-1])) + "…"
+}
+
+// ShareCard renders the preview card of a public post as PNG
+func (s *SharingService) ShareCard(ctx context.Context, orgID uuid.UUID, idOrSlug string) ([]byte, error) {
+	post, err := s.publicPost(ctx, orgID, idOrSlug)
+	if err != nil {
+		return nil, err
+	}
+
+	site := s.appURL
+	if u, err := url.Parse(s.appURL); err == nil && u.Host != "" {
+		site = u.Host
+	}
+	return encodePNG(sharecard.Render(sharecard.Card{
+		Username: post.username,
+		Text:     markdownMarks.Replace(post.text),
+		Site:     site,
+	}))
+}
+
+// QRCode renders a QR code of a public post's link as PNG
+func (s *SharingService) QRCode(ctx context.Context, orgID uuid.UUID, idOrSlug string) ([]byte, error) {
+	post, err := s.publicPost(ctx, orgID, idOrSlug)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := qr.Encode(s.postURL(post.slug))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode QR code: %w", err)
+	}
+	return encodePNG(code.Image(qrScale))
+}
+
+func encodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+	return buf.Bytes(), nil
+}
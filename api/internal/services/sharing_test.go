@@ -0,0 +1,19 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetaDescription(t *testing.T) {
+	assert.Equal(t, "Notes on channels: use select for timeouts", metaDescription("Notes on **channels**:\n\n  use `select` for timeouts"))
+	assert.Equal(t, "", metaDescription("  \n "))
+
+	long := metaDescription(strings.Repeat("сөз ", 100))
+	assert.LessOrEqual(t, utf8.RuneCountInString(long), metaDescriptionChars)
+	assert.True(t, strings.HasSuffix(long, "…"), long)
+	assert.False(t, strings.HasSuffix(long, " …"), long)
+}
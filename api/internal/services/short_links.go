@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"bailanysta/api/internal/pkg/markdown"
+	"bailanysta/api/internal/pkg/metrics"
+	"bailanysta/api/internal/pkg/useragent"
+)
+
+var linkClicks = metrics.NewCounterVec("link_clicks_total",
+	"Clicks on outbound post links through the /l/ redirect.", "counted")
+
+// ShortLinkService resolves the /l/{code} redirects that outbound links in
+// posts go through, and counts their clicks. A code is the post's base62
+// sequence number and a hash of the URL, e.g. "4c-1bXk9e", so nothing is
+// stored until someone clicks.
+type ShortLinkService struct {
+	db *pgxpool.Pool
+}
+
+func NewShortLinkService(db *pgxpool.Pool) *ShortLinkService {
+	return &ShortLinkService{db: db}
+}
+
+// RenderPostText renders a post's Markdown with outbound links pointed at
+// /l/{code}. Without a slug the links are left as written.
+func RenderPostText(slug, text string) string {
+	seq, _, _ := strings.Cut(slug, "-")
+	if seq == "" {
+		return markdown.Render(text)
+	}
+	return markdown.RenderLinks(text, func(href string) string {
+		return "/l/" + shortLinkCode(seq, href)
+	})
+}
+
+func shortLinkCode(seq, href string) string {
+	return seq + "-" + shortLinkHash(href)
+}
+
+func shortLinkHash(href string) string {
+	h := fnv.New32a()
+	h.Write([]byte(href))
+	return encodeBase62(int64(h.Sum32()))
+}
+
+// Resolve returns the post and URL code stands for. The URL must still be
+// in the post, so links removed by an edit stop redirecting.
+func (s *ShortLinkService) Resolve(ctx context.Context, code string) (uuid.UUID, string, error) {
+	seqPart, hash, ok := strings.Cut(code, "-")
+	seq, valid := decodeBase62(seqPart)
+	if !ok || !valid || hash == "" {
+		return uuid.Nil, "", fmt.Errorf("link not found")
+	}
+
+	var postID uuid.UUID
+	var text string
+	err := s.db.QueryRow(ctx, `SELECT id, text FROM posts WHERE seq = $1`, seq).Scan(&postID, &text)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, "", fmt.Errorf("link not found")
+	}
+	if err != nil {
+		return uuid.Nil, "", fmt.Errorf("failed to resolve link: %w", err)
+	}
+
+	for _, href := range markdown.Links(text) {
+		if shortLinkHash(href) == hash {
+			return postID, href, nil
+		}
+	}
+	return uuid.Nil, "", fmt.Errorf("link not found")
+}
+
+// RecordClick counts a click on url in postID for today, unless userAgent
+// looks like a bot or link unfurler
+func (s *ShortLinkService) RecordClick(ctx context.Context, postID uuid.UUID, url, userAgent string) error {
+	if isBotUserAgent(userAgent) {
+		linkClicks.Inc("false")
+		return nil
+	}
+
+	_, err := s.db.Exec(ctx, `
+		INSERT INTO link_clicks (post_id, url, clicks) VALUES ($1, $2, 1)
+		ON CONFLICT (post_id, url, clicked_on) DO UPDATE SET clicks = link_clicks.clicks + 1`,
+		postID, url)
+	if err != nil {
+		return fmt.Errorf("failed to record link click: %w", err)
+	}
+	linkClicks.Inc("true")
+	return nil
+}
+
+// isBotUserAgent reports whether a click should not count: crawlers, link
+// preview fetchers and clients that send no User-Agent at all
+func isBotUserAgent(userAgent string) bool {
+	if userAgent == "" || useragent.Parse(userAgent).Device == useragent.DeviceBot {
+		return true
+	}
+	ua := strings.ToLower(userAgent)
+	for _, token := range []string{"facebookexternalhit", "preview", "headless", "curl/", "wget/", "python-requests", "go-http-client"} {
+		if strings.Contains(ua, token) {
+			return true
+		}
+	}
+	return false
+}
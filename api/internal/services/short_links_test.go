@@ -0,0 +1,52 @@
+package services
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bailanysta/api/internal/pkg/markdown"
+)
+
+func TestRenderPostText(t *testing.T) {
+	text := "See https://go.dev/doc and [the tour](https://go.dev/tour), ask @alice about #go"
+
+	html := RenderPostText("4c-see-go", text)
+	codes := regexp.MustCompile(`href="/l/([^"]+)" rel="nofollow noopener noreferrer" target="_blank"`).FindAllStringSubmatch(html, -1)
+	require.Len(t, codes, 2, html)
+	assert.Contains(t, html, `href="/profile/alice"`)
+	assert.Contains(t, html, `href="/search?q=%23go"`)
+	assert.NotContains(t, html, "go.dev/doc\"")
+
+	links := markdown.Links(text)
+	require.Equal(t, []string{"https://go.dev/doc", "https://go.dev/tour"}, links)
+	for i, code := range codes {
+		assert.Equal(t, shortLinkCode("4c", links[i]), code[1])
+	}
+
+	t.Run("without a slug links are left alone", func(t *testing.T) {
+		assert.Equal(t, markdown.Render(text), RenderPostText("", text))
+	})
+}
+
+func TestIsBotUserAgent(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		bot       bool
+	}{
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0 Safari/537.36", false},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1", false},
+		{"", true},
+		{"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", true},
+		{"facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)", true},
+		{"Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)", true},
+		{"TelegramBot (like TwitterBot)", true},
+		{"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) HeadlessChrome/126.0 Safari/537.36", true},
+		{"curl/8.5.0", true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.bot, isBotUserAgent(tt.userAgent), tt.userAgent)
+	}
+}
@@ -2,120 +2,290 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
-	"github.com/jackc/pgx/v5/pgxpool"
+
+	"bailanysta/api/internal/pkg/database"
 )
 
 type SocialService struct {
-	db                   *pgxpool.Pool
+	db                   *database.Pool
 	notificationsService *NotificationService
+	rankingWeights       FeedRankingWeights
+	feedFanout           bool
+	abuse                *AbuseService
+}
+
+// FeedRankingWeights tunes the ranked feed score:
+// recency decay + engagement + author affinity + course match
+type FeedRankingWeights struct {
+	Recency    float64
+	Engagement float64
+	Affinity   float64
+	Course     float64
+	HalfLife   time.Duration // age at which the recency component halves
+	Window     time.Duration // only posts newer than this are ranked
+	AIPenalty  float64       // score multiplier for AI-generated posts the viewer down-ranks
 }
 
+// How a viewer's feeds treat other users' AI-generated posts. Down-ranking
+// only affects the ranked feed; the chronological feed shows them as usual.
+const (
+	AIContentShow     = "show"
+	AIContentDownrank = "downrank"
+	AIContentHide     = "hide"
+)
+
 type FollowStats struct {
-	FollowersCount int  `json:"followers_count"`
-	FollowingCount int  `json:"following_count"`
-	IsFollowing    bool `json:"is_following,omitempty"`
+	FollowersCount  int  `json:"followers_count"`
+	FollowingCount  int  `json:"following_count"`
+	IsFollowing     bool `json:"is_following,omitempty"`
+	FollowRequested bool `json:"follow_requested,omitempty"`
 }
 
 type FeedPost struct {
-	ID           uuid.UUID    `json:"id"`
-	AuthorID     uuid.UUID    `json:"author_id"`
-	Text         string       `json:"text"`
-	CourseID     *uuid.UUID   `json:"course_id,omitempty"`
-	ModuleID     *uuid.UUID   `json:"module_id,omitempty"`
-	CreatedAt    time.Time    `json:"created_at"`
-	UpdatedAt    time.Time    `json:"updated_at"`
-	LikeCount    int          `json:"like_count"`
-	CommentCount int          `json:"comment_count"`
-	Author       UserResponse `json:"author"`
-	IsLiked      bool         `json:"is_liked"`
+	ID            uuid.UUID      `json:"id"`
+	AuthorID      uuid.UUID      `json:"author_id"`
+	Text          string         `json:"text"`
+	TextHTML      string         `json:"text_html"`
+	CourseID      *uuid.UUID     `json:"course_id,omitempty"`
+	ModuleID      *uuid.UUID     `json:"module_id,omitempty"`
+	IsAIGenerated bool           `json:"is_ai_generated"`
+	Edited        bool           `json:"edited"`
+	Slug          string         `json:"slug"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	LikeCount     int            `json:"like_count"`
+	CommentCount  int            `json:"comment_count"`
+	Author        UserResponse   `json:"author"`
+	IsLiked       bool           `json:"is_liked"`
+	Score         *float64       `json:"score,omitempty"`
+	Poll          *Poll          `json:"poll,omitempty"`
+	LinkPreviews  []*LinkPreview `json:"link_previews,omitempty"`
+	Question      *Question      `json:"question,omitempty"`
+	GIF           *GIF           `json:"gif,omitempty"`
+}
+
+// FeedDigestItem is a compact view of a feed post used to build AI digests
+type FeedDigestItem struct {
+	PostID         uuid.UUID
+	AuthorUsername string
+	Text           string
+	CourseTitle    string
+	Hashtags       []string
+	CreatedAt      time.Time
 }
 
 type FollowRequest struct {
 	UserID uuid.UUID `json:"user_id" validate:"required"`
 }
 
-func NewSocialService(db *pgxpool.Pool, notificationsService *NotificationService) *SocialService {
+// NewSocialService creates the service; with feedFanout, follows keep
+// feed_items up to date and GetFeed reads from it once a user is backfilled
+func NewSocialService(db *database.Pool, notificationsService *NotificationService, rankingWeights FeedRankingWeights, feedFanout bool, abuse *AbuseService) *SocialService {
 	return &SocialService{
 		db:                   db,
 		notificationsService: notificationsService,
+		rankingWeights:       rankingWeights,
+		feedFanout:           feedFanout,
+		abuse:                abuse,
 	}
 }
 
-func (s *SocialService) FollowUser(ctx context.Context, followerID, followeeID uuid.UUID) error {
+// FollowUser follows followeeID, or sends a follow request if the account is
+// private. requested reports which of the two happened.
+func (s *SocialService) FollowUser(ctx context.Context, followerID, followeeID uuid.UUID) (requested bool, err error) {
 	if followerID == followeeID {
-		return fmt.Errorf("cannot follow yourself")
+		return false, fmt.Errorf("cannot follow yourself")
+	}
+	if err := s.abuse.Allow(ctx, followerID, AbuseActionFollow); err != nil {
+		return false, err
 	}
 
-	// Check if already following
-	var count int
-	err := s.db.QueryRow(ctx, `
-		SELECT COUNT(*) FROM follows
-		WHERE follower_id = $1 AND followee_id = $2`,
-		followerID, followeeID).Scan(&count)
+	blocked, err := s.IsBlockedEitherWay(ctx, followerID, followeeID)
 	if err != nil {
-		return fmt.Errorf("failed to check follow status: %w", err)
+		return false, err
 	}
-	if count > 0 {
-		return fmt.Errorf("already following this user")
+	if blocked {
+		return false, fmt.Errorf("cannot follow this user")
 	}
 
-	// Create follow relationship
-	_, err = s.db.Exec(ctx, `
-		INSERT INTO follows (follower_id, followee_id)
-		VALUES ($1, $2)`, followerID, followeeID)
+	// Users of other organizations can't be followed, so feeds stay within one
+	var isPrivate bool
+	err = s.db.QueryRow(ctx, `
+		SELECT is_private FROM users
+		WHERE id = $1 AND org_id = (SELECT org_id FROM users WHERE id = $2)`, followeeID, followerID).Scan(&isPrivate)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, fmt.Errorf("user not found")
+	}
 	if err != nil {
-		return fmt.Errorf("failed to follow user: %w", err)
+		return false, fmt.Errorf("failed to get user: %w", err)
+	}
+	if isPrivate {
+		return true, s.requestFollow(ctx, followerID, followeeID)
 	}
 
-	// Create notification
-	if s.notificationsService != nil {
-		err = s.notificationsService.NotifyFollow(ctx, followerID, followeeID)
+	// The primary key makes concurrent follows of the same user insert once
+	err = pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			INSERT INTO follows (follower_id, followee_id)
+			VALUES ($1, $2)
+			ON CONFLICT (follower_id, followee_id) DO NOTHING`, followerID, followeeID)
+		if err != nil {
+			return fmt.Errorf("failed to follow user: %w", err)
+		}
+		if result.RowsAffected() == 0 {
+			return fmt.Errorf("already following this user")
+		}
+
+		if s.feedFanout {
+			if err := addAuthorToFeed(ctx, tx, followerID, followeeID); err != nil {
+				return err
+			}
+		}
+
+		if s.notificationsService == nil {
+			return nil
+		}
+		return enqueueNotification(ctx, tx, OutboxEvent{
+			Type:     NotificationTypeFollow,
+			ActorID:  followerID,
+			TargetID: &followeeID,
+		})
+	})
+
+	return false, err
+}
+
+// UnfollowUser stops following followeeID or withdraws a pending follow request
+func (s *SocialService) UnfollowUser(ctx context.Context, followerID, followeeID uuid.UUID) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			DELETE FROM follows
+			WHERE follower_id = $1 AND followee_id = $2`,
+			followerID, followeeID)
+		if err != nil {
+			return fmt.Errorf("failed to unfollow user: %w", err)
+		}
+
+		if result.RowsAffected() > 0 {
+			if s.feedFanout {
+				return removeAuthorFromFeed(ctx, tx, followerID, followeeID)
+			}
+			return nil
+		}
+
+		result, err = tx.Exec(ctx, `
+			DELETE FROM follow_requests
+			WHERE requester_id = $1 AND target_id = $2`,
+			followerID, followeeID)
 		if err != nil {
-			// Log error but don't fail the operation
-			fmt.Printf("Failed to create follow notification: %v\n", err)
+			return fmt.Errorf("failed to cancel follow request: %w", err)
 		}
+		if result.RowsAffected() == 0 {
+			return fmt.Errorf("not following this user")
+		}
+		return nil
+	})
+}
+
+func (s *SocialService) BlockUser(ctx context.Context, blockerID, blockedID uuid.UUID) error {
+	if blockerID == blockedID {
+		return fmt.Errorf("cannot block yourself")
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO user_blocks (blocker_id, blocked_id)
+		VALUES ($1, $2)
+		ON CONFLICT (blocker_id, blocked_id) DO NOTHING`, blockerID, blockedID)
+	if err != nil {
+		return fmt.Errorf("failed to block user: %w", err)
+	}
+
+	// Blocking severs follows in both directions
+	_, err = tx.Exec(ctx, `
+		DELETE FROM follows
+		WHERE (follower_id = $1 AND followee_id = $2)
+		   OR (follower_id = $2 AND followee_id = $1)`, blockerID, blockedID)
+	if err != nil {
+		return fmt.Errorf("failed to remove follows: %w", err)
+	}
+
+	if s.feedFanout {
+		if err = removeAuthorFromFeed(ctx, tx, blockerID, blockedID); err != nil {
+			return err
+		}
+		if err = removeAuthorFromFeed(ctx, tx, blockedID, blockerID); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM follow_requests
+		WHERE (requester_id = $1 AND target_id = $2)
+		   OR (requester_id = $2 AND target_id = $1)`, blockerID, blockedID)
+	if err != nil {
+		return fmt.Errorf("failed to remove follow requests: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
 	return nil
 }
 
-func (s *SocialService) UnfollowUser(ctx context.Context, followerID, followeeID uuid.UUID) error {
+func (s *SocialService) UnblockUser(ctx context.Context, blockerID, blockedID uuid.UUID) error {
 	result, err := s.db.Exec(ctx, `
-		DELETE FROM follows
-		WHERE follower_id = $1 AND followee_id = $2`,
-		followerID, followeeID)
+		DELETE FROM user_blocks
+		WHERE blocker_id = $1 AND blocked_id = $2`, blockerID, blockedID)
 	if err != nil {
-		return fmt.Errorf("failed to unfollow user: %w", err)
+		return fmt.Errorf("failed to unblock user: %w", err)
 	}
 
-	rowsAffected := result.RowsAffected()
-	if rowsAffected == 0 {
-		return fmt.Errorf("not following this user")
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("user is not blocked")
 	}
 
 	return nil
 }
 
+func (s *SocialService) IsBlockedEitherWay(ctx context.Context, userID, otherID uuid.UUID) (bool, error) {
+	var blocked bool
+	err := s.db.QueryRow(ctx, `
+		SELECT EXISTS(
+		    SELECT 1 FROM user_blocks
+		    WHERE (blocker_id = $1 AND blocked_id = $2)
+		       OR (blocker_id = $2 AND blocked_id = $1)
+		)`, userID, otherID).Scan(&blocked)
+	if err != nil {
+		return false, fmt.Errorf("failed to check block status: %w", err)
+	}
+	return blocked, nil
+}
+
 func (s *SocialService) GetFollowStats(ctx context.Context, userID, currentUserID uuid.UUID) (*FollowStats, error) {
 	var stats FollowStats
 
-	// Get followers count
+	// The counts are kept on users by a trigger on follows
 	err := s.db.QueryRow(ctx, `
-		SELECT COUNT(*) FROM follows WHERE followee_id = $1`, userID).Scan(&stats.FollowersCount)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get followers count: %w", err)
+		SELECT followers_count, following_count FROM users WHERE id = $1`, userID).Scan(&stats.FollowersCount, &stats.FollowingCount)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("user not found")
 	}
-
-	// Get following count
-	err = s.db.QueryRow(ctx, `
-		SELECT COUNT(*) FROM follows WHERE follower_id = $1`, userID).Scan(&stats.FollowingCount)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get following count: %w", err)
+		return nil, fmt.Errorf("failed to get follow counts: %w", err)
 	}
 
 	// Check if current user is following this user
@@ -129,34 +299,204 @@ func (s *SocialService) GetFollowStats(ctx context.Context, userID, currentUserI
 			return nil, fmt.Errorf("failed to check follow status: %w", err)
 		}
 		stats.IsFollowing = followCount > 0
+
+		err = s.db.QueryRow(ctx, `
+			SELECT EXISTS(SELECT 1 FROM follow_requests WHERE requester_id = $1 AND target_id = $2)`,
+			currentUserID, userID).Scan(&stats.FollowRequested)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check follow request: %w", err)
+		}
 	}
 
 	return &stats, nil
 }
 
-func (s *SocialService) GetFeed(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*FeedPost, error) {
-	rows, err := s.db.Query(ctx, `
-		SELECT p.id, p.author_id, p.text, p.course_id, p.module_id, p.created_at, p.updated_at,
-		       COUNT(DISTINCT l.user_id) as like_count,
-		       COUNT(DISTINCT c.id) as comment_count,
-		       u.username, u.email, u.bio, u.avatar_url,
-		       CASE WHEN ul.user_id IS NOT NULL THEN true ELSE false END as is_liked
-		FROM posts p
-		JOIN users u ON p.author_id = u.id
-		LEFT JOIN likes l ON p.id = l.post_id
-		LEFT JOIN comments c ON p.id = c.post_id
-		LEFT JOIN likes ul ON p.id = ul.post_id AND ul.user_id = $1
-		WHERE p.author_id IN (
-		    SELECT followee_id FROM follows WHERE follower_id = $1
-		    UNION
-		    SELECT $1
-		)
-		GROUP BY p.id, u.username, u.email, u.bio, u.avatar_url, ul.user_id
-		ORDER BY p.created_at DESC
-		LIMIT $2 OFFSET $3`, userID, limit, offset)
+// computedFeedQuery builds a page of the feed from follows at read time
+const computedFeedQuery = `
+	SELECT p.id, p.author_id, p.text, p.course_id, p.module_id, p.is_ai_generated, p.edited_at IS NOT NULL, p.slug, p.created_at, p.updated_at,
+	       COUNT(DISTINCT l.user_id) as like_count,
+	       COUNT(DISTINCT c.id) as comment_count,
+	       u.username, u.email, u.bio, u.avatar_url,
+	       CASE WHEN ul.user_id IS NOT NULL THEN true ELSE false END as is_liked
+	FROM posts p
+	JOIN users u ON p.author_id = u.id
+	LEFT JOIN likes l ON p.id = l.post_id
+	LEFT JOIN comments c ON p.id = c.post_id
+	LEFT JOIN likes ul ON p.id = ul.post_id AND ul.user_id = $1
+	WHERE p.author_id IN (
+	    SELECT followee_id FROM follows WHERE follower_id = $1
+	    UNION
+	    SELECT $1
+	)
+	AND p.group_id IS NULL
+	AND (NOT u.shadow_banned OR u.id = $1)
+	AND (NOT p.is_ai_generated OR p.author_id = $1
+	     OR (SELECT ai_content FROM users WHERE id = $1) <> 'hide')
+	AND (p.language IS NULL OR p.author_id = $1
+	     OR (SELECT feed_languages = '{}' OR p.language = ANY(feed_languages) FROM users WHERE id = $1))
+	AND ($4 = '' OR p.post_type = 'question' AND ($4 = 'questions' OR p.accepted_comment_id IS NULL))
+	GROUP BY p.id, u.username, u.email, u.bio, u.avatar_url, ul.user_id
+	ORDER BY p.created_at DESC
+	LIMIT $2 OFFSET $3`
+
+// GetFeed lists posts by userID and the users they follow, newest first,
+// narrowed to questions by filter. With fan-out enabled it reads the
+// materialized feed once userID has been backfilled.
+func (s *SocialService) GetFeed(ctx context.Context, userID uuid.UUID, filter FeedFilter, limit, offset int) ([]*FeedPost, error) {
+	query := computedFeedQuery
+	materialized, err := s.hasMaterializedFeed(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if materialized {
+		query = materializedFeedQuery
+	}
+
+	rows, err := s.db.Reader().Query(ctx, query, userID, limit, offset, string(filter))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get feed: %w", err)
 	}
+	posts, err := scanFeedPosts(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.attachPostExtras(ctx, posts, userID); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// maxFeedUpdates bounds the new posts GetFeedUpdates returns; the count
+// covers all of them
+const maxFeedUpdates = 50
+
+// Feed update queries select feed posts created after $2. The FROM clauses
+// mirror computedFeedQuery and materializedFeedQuery; feedUpdatesFilter holds
+// the viewer preferences both apply.
+const (
+	feedUpdatesColumns = `
+	SELECT p.id, p.author_id, p.text, p.course_id, p.module_id, p.is_ai_generated, p.edited_at IS NOT NULL, p.slug, p.created_at, p.updated_at,
+	       p.like_count,
+	       (SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id) AS comment_count,
+	       u.username, u.email, u.bio, u.avatar_url,
+	       EXISTS(SELECT 1 FROM likes ul WHERE ul.post_id = p.id AND ul.user_id = $1) AS is_liked`
+
+	computedFeedUpdatesFrom = `
+	FROM posts p
+	JOIN users u ON p.author_id = u.id
+	WHERE p.author_id IN (
+	    SELECT followee_id FROM follows WHERE follower_id = $1
+	    UNION
+	    SELECT $1
+	)
+	AND p.group_id IS NULL
+	AND p.created_at > $2`
+
+	materializedFeedUpdatesFrom = `
+	FROM feed_items fi
+	JOIN posts p ON p.id = fi.post_id
+	JOIN users u ON p.author_id = u.id
+	WHERE fi.user_id = $1
+	AND fi.created_at > $2`
+
+	feedUpdatesFilter = `
+	AND (NOT u.shadow_banned OR u.id = $1)
+	AND (NOT p.is_ai_generated OR p.author_id = $1
+	     OR (SELECT ai_content FROM users WHERE id = $1) <> 'hide')
+	AND (p.language IS NULL OR p.author_id = $1
+	     OR (SELECT feed_languages = '{}' OR p.language = ANY(feed_languages) FROM users WHERE id = $1))`
+)
+
+// GetFeedUpdates returns up to limit feed posts newer than sinceID, the
+// newest post the client has, along with how many newer posts there are in
+// total. A limit of 0 only counts them.
+func (s *SocialService) GetFeedUpdates(ctx context.Context, userID, sinceID uuid.UUID, limit int) ([]*FeedPost, int, error) {
+	var since time.Time
+	err := s.db.Reader().QueryRow(ctx, `SELECT created_at FROM posts WHERE id = $1`, sinceID).Scan(&since)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, 0, fmt.Errorf("post not found")
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get feed updates: %w", err)
+	}
+
+	from := computedFeedUpdatesFrom
+	materialized, err := s.hasMaterializedFeed(ctx, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if materialized {
+		from = materializedFeedUpdatesFrom
+	}
+
+	var count int
+	err = s.db.Reader().QueryRow(ctx, "SELECT COUNT(*)"+from+feedUpdatesFilter, userID, since).Scan(&count)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count feed updates: %w", err)
+	}
+
+	posts := []*FeedPost{}
+	if count == 0 || limit <= 0 {
+		return posts, count, nil
+	}
+
+	rows, err := s.db.Reader().Query(ctx, feedUpdatesColumns+from+feedUpdatesFilter+`
+	ORDER BY p.created_at DESC
+	LIMIT $3`, userID, since, min(limit, maxFeedUpdates))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get feed updates: %w", err)
+	}
+	posts, err = scanFeedPosts(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := s.attachPostExtras(ctx, posts, userID); err != nil {
+		return nil, 0, err
+	}
+	return posts, count, nil
+}
+
+// FeedPostRef identifies a feed post without its content
+type FeedPostRef struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// GetFeedPostsSince lists up to maxFeedUpdates feed posts created after
+// since, newest first, for clients that only need to know what is new
+func (s *SocialService) GetFeedPostsSince(ctx context.Context, userID uuid.UUID, since time.Time) ([]FeedPostRef, error) {
+	from := computedFeedUpdatesFrom
+	materialized, err := s.hasMaterializedFeed(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if materialized {
+		from = materializedFeedUpdatesFrom
+	}
+
+	rows, err := s.db.Reader().Query(ctx, "SELECT p.id, p.created_at"+from+feedUpdatesFilter+`
+	ORDER BY p.created_at DESC
+	LIMIT $3`, userID, since, maxFeedUpdates)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get new feed posts: %w", err)
+	}
+	defer rows.Close()
+
+	var refs []FeedPostRef
+	for rows.Next() {
+		var ref FeedPostRef
+		if err := rows.Scan(&ref.ID, &ref.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan feed post: %w", err)
+		}
+		refs = append(refs, ref)
+	}
+	return refs, rows.Err()
+}
+
+// scanFeedPosts reads rows in the column order of computedFeedQuery
+func scanFeedPosts(rows pgx.Rows) ([]*FeedPost, error) {
 	defer rows.Close()
 
 	var posts []*FeedPost
@@ -166,7 +506,7 @@ func (s *SocialService) GetFeed(ctx context.Context, userID uuid.UUID, limit, of
 		var bio, avatarURL pgtype.Text
 
 		err := rows.Scan(
-			&post.ID, &post.AuthorID, &post.Text, &courseID, &moduleID,
+			&post.ID, &post.AuthorID, &post.Text, &courseID, &moduleID, &post.IsAIGenerated, &post.Edited, &post.Slug,
 			&post.CreatedAt, &post.UpdatedAt, &post.LikeCount, &post.CommentCount,
 			&post.Author.Username, &post.Author.Email, &bio, &avatarURL, &post.IsLiked)
 		if err != nil {
@@ -188,43 +528,273 @@ func (s *SocialService) GetFeed(ctx context.Context, userID uuid.UUID, limit, of
 		posts = append(posts, &post)
 	}
 
-	return posts, nil
+	return posts, rows.Err()
 }
 
-func (s *SocialService) GetFollowers(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*UserResponse, error) {
-	rows, err := s.db.Query(ctx, `
-		SELECT u.id, u.username, u.email, u.bio, u.avatar_url
-		FROM follows f
-		JOIN users u ON f.follower_id = u.id
-		WHERE f.followee_id = $1
-		ORDER BY f.created_at DESC
-		LIMIT $2 OFFSET $3`, userID, limit, offset)
+// attachPostExtras renders post text and adds poll results, link previews,
+// GIFs and question state to feed posts
+func (s *SocialService) attachPostExtras(ctx context.Context, posts []*FeedPost, viewerID uuid.UUID) error {
+	postIDs := make([]uuid.UUID, len(posts))
+	for i, post := range posts {
+		postIDs[i] = post.ID
+	}
+
+	polls, err := getPolls(ctx, s.db.Reader(), postIDs, viewerID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get followers: %w", err)
+		return err
+	}
+	previews, err := getLinkPreviews(ctx, s.db.Reader(), postIDs)
+	if err != nil {
+		return err
+	}
+	questions, err := getQuestions(ctx, s.db.Reader(), postIDs)
+	if err != nil {
+		return err
+	}
+	gifs, err := getPostGIFs(ctx, s.db.Reader(), postIDs)
+	if err != nil {
+		return err
+	}
+	for _, post := range posts {
+		post.TextHTML = RenderPostText(post.Slug, post.Text)
+		post.Poll = polls[post.ID]
+		post.LinkPreviews = previews[post.ID]
+		post.Question = questions[post.ID]
+		post.GIF = gifs[post.ID]
+	}
+	return nil
+}
+
+// GetRankedFeed orders recent feed posts by a personalized score instead of
+// strictly by time. Affinity counts the viewer's likes and comments on the
+// author's posts; course match uses courses the viewer has posted in.
+func (s *SocialService) GetRankedFeed(ctx context.Context, userID uuid.UUID, filter FeedFilter, limit, offset int) ([]*FeedPost, error) {
+	w := s.rankingWeights
+	rows, err := s.db.Reader().Query(ctx, `
+		WITH viewer AS (
+		    SELECT ai_content, feed_languages FROM users WHERE id = $1
+		),
+		feed AS (
+		    SELECT p.id, p.author_id, p.text, p.course_id, p.module_id, p.is_ai_generated,
+		           p.edited_at IS NOT NULL AS edited, p.slug, p.created_at, p.updated_at,
+		           (SELECT COUNT(*) FROM likes l WHERE l.post_id = p.id) AS like_count,
+		           (SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id) AS comment_count
+		    FROM posts p
+		    WHERE p.author_id IN (
+		        SELECT followee_id FROM follows WHERE follower_id = $1
+		        UNION
+		        SELECT $1
+		    )
+		    AND p.created_at > now() - make_interval(secs => $4::float8)
+		    AND p.group_id IS NULL
+		    AND (NOT p.is_ai_generated OR p.author_id = $1 OR (SELECT ai_content FROM viewer) <> 'hide')
+		    AND (p.language IS NULL OR p.author_id = $1
+		         OR (SELECT feed_languages = '{}' OR p.language = ANY(feed_languages) FROM viewer))
+		    AND ($11 = '' OR p.post_type = 'question' AND ($11 = 'questions' OR p.accepted_comment_id IS NULL))
+		),
+		affinity AS (
+		    SELECT p.author_id, COUNT(*) AS interactions
+		    FROM (
+		        SELECT post_id FROM likes WHERE user_id = $1
+		        UNION ALL
+		        SELECT post_id FROM comments WHERE author_id = $1
+		    ) i
+		    JOIN posts p ON p.id = i.post_id
+		    WHERE p.author_id <> $1
+		    GROUP BY p.author_id
+		),
+		my_courses AS (
+		    SELECT DISTINCT course_id FROM posts
+		    WHERE author_id = $1 AND course_id IS NOT NULL
+		),
+		scored AS (
+		    SELECT f.*,
+		           ($5::float8 * power(0.5, EXTRACT(EPOCH FROM (now() - f.created_at))::float8 / $9::float8) +
+		            $6::float8 * ln(1 + f.like_count + 2 * f.comment_count) +
+		            $7::float8 * ln(1 + COALESCE(a.interactions, 0)) +
+		            $8::float8 * CASE WHEN f.course_id IN (SELECT course_id FROM my_courses) THEN 1 ELSE 0 END) *
+		           CASE WHEN f.is_ai_generated AND f.author_id <> $1 AND (SELECT ai_content FROM viewer) = 'downrank'
+		                THEN $10::float8 ELSE 1 END AS score
+		    FROM feed f
+		    LEFT JOIN affinity a ON a.author_id = f.author_id
+		)
+		SELECT sc.id, sc.author_id, sc.text, sc.course_id, sc.module_id, sc.is_ai_generated, sc.edited, sc.slug, sc.created_at, sc.updated_at,
+		       sc.like_count, sc.comment_count,
+		       u.username, u.email, u.bio, u.avatar_url,
+		       EXISTS(SELECT 1 FROM likes ul WHERE ul.post_id = sc.id AND ul.user_id = $1) AS is_liked,
+		       sc.score
+		FROM scored sc
+		JOIN users u ON sc.author_id = u.id
+		WHERE NOT u.shadow_banned OR u.id = $1
+		ORDER BY sc.score DESC, sc.created_at DESC
+		LIMIT $2 OFFSET $3`,
+		userID, limit, offset, w.Window.Seconds(),
+		w.Recency, w.Engagement, w.Affinity, w.Course, w.HalfLife.Seconds(), w.AIPenalty, string(filter))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ranked feed: %w", err)
 	}
 	defer rows.Close()
 
-	var users []*UserResponse
+	var posts []*FeedPost
 	for rows.Next() {
-		var user UserResponse
+		var post FeedPost
+		var courseID, moduleID pgtype.UUID
 		var bio, avatarURL pgtype.Text
+		var score float64
 
-		err := rows.Scan(&user.ID, &user.Username, &user.Email, &bio, &avatarURL)
+		err := rows.Scan(
+			&post.ID, &post.AuthorID, &post.Text, &courseID, &moduleID, &post.IsAIGenerated, &post.Edited, &post.Slug,
+			&post.CreatedAt, &post.UpdatedAt, &post.LikeCount, &post.CommentCount,
+			&post.Author.Username, &post.Author.Email, &bio, &avatarURL, &post.IsLiked, &score)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan follower: %w", err)
+			return nil, fmt.Errorf("failed to scan ranked feed post: %w", err)
 		}
 
-		user.Bio = getPgtypeTextValue(bio)
-		user.AvatarURL = getPgtypeTextPtr(avatarURL)
-		users = append(users, &user)
+		if courseID.Valid {
+			courseUUID := uuid.UUID(courseID.Bytes)
+			post.CourseID = &courseUUID
+		}
+		if moduleID.Valid {
+			moduleUUID := uuid.UUID(moduleID.Bytes)
+			post.ModuleID = &moduleUUID
+		}
+		post.Author.Bio = getPgtypeTextValue(bio)
+		post.Author.AvatarURL = getPgtypeTextPtr(avatarURL)
+		post.Score = &score
+
+		posts = append(posts, &post)
+	}
+
+	if err := s.attachPostExtras(ctx, posts, userID); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// GetFeedLastSeen returns when the user last caught up with their feed
+func (s *SocialService) GetFeedLastSeen(ctx context.Context, userID uuid.UUID) (*time.Time, error) {
+	var lastSeen *time.Time
+	err := s.db.QueryRow(ctx, `
+		SELECT feed_last_seen_at FROM users WHERE id = $1`, userID).Scan(&lastSeen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feed last seen: %w", err)
+	}
+	return lastSeen, nil
+}
+
+// MarkFeedSeen moves the point the next feed digest starts from up to
+// upTo, the creation time of the newest post the user has been shown a
+// digest of. It never moves back.
+func (s *SocialService) MarkFeedSeen(ctx context.Context, userID uuid.UUID, upTo time.Time) error {
+	_, err := s.db.Exec(ctx, `
+		UPDATE users SET feed_last_seen_at = GREATEST(feed_last_seen_at, $2)
+		WHERE id = $1`, userID, upTo)
+	if err != nil {
+		return fmt.Errorf("failed to mark feed seen: %w", err)
+	}
+	return nil
+}
+
+// SetAIContentPreference sets how the user's feeds treat AI-generated posts
+func (s *SocialService) SetAIContentPreference(ctx context.Context, userID uuid.UUID, mode string) error {
+	switch mode {
+	case AIContentShow, AIContentDownrank, AIContentHide:
+	default:
+		return fmt.Errorf("invalid ai_content preference")
+	}
+
+	_, err := s.db.Exec(ctx, `UPDATE users SET ai_content = $2 WHERE id = $1`, userID, mode)
+	if err != nil {
+		return fmt.Errorf("failed to update feed preferences: %w", err)
+	}
+	return nil
+}
+
+// SetFeedLanguages limits the user's feeds to posts in languages; an empty
+// list shows every language. Their own posts and posts without a detected
+// language are always shown.
+func (s *SocialService) SetFeedLanguages(ctx context.Context, userID uuid.UUID, languages []string) ([]string, error) {
+	languages, err := normalizeFeedLanguages(languages)
+	if err != nil {
+		return nil, err
 	}
 
-	return users, nil
+	_, err = s.db.Exec(ctx, `UPDATE users SET feed_languages = $2 WHERE id = $1`, userID, languages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update feed preferences: %w", err)
+	}
+	return languages, nil
 }
 
-func (s *SocialService) GetFollowing(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*UserResponse, error) {
+// GetFeedDigestItems returns posts from followed users created after since,
+// oldest first, excluding the user's own posts and those their feed
+// preferences hide
+func (s *SocialService) GetFeedDigestItems(ctx context.Context, userID uuid.UUID, since time.Time, limit int) ([]*FeedDigestItem, error) {
 	rows, err := s.db.Query(ctx, `
-		SELECT u.id, u.username, u.email, u.bio, u.avatar_url
+		SELECT p.id, u.username, p.text, COALESCE(co.title, ''),
+		       COALESCE(array_agg(h.display ORDER BY h.tag) FILTER (WHERE h.tag IS NOT NULL), '{}'),
+		       p.created_at
+		FROM posts p
+		JOIN users u ON p.author_id = u.id
+		JOIN follows f ON f.followee_id = p.author_id AND f.follower_id = $1
+		LEFT JOIN courses co ON p.course_id = co.id
+		LEFT JOIN post_hashtags ph ON p.id = ph.post_id
+		LEFT JOIN hashtags h ON ph.hashtag_id = h.id
+		WHERE p.created_at > $2 AND p.group_id IS NULL AND NOT u.shadow_banned
+		  AND (NOT p.is_ai_generated OR (SELECT ai_content FROM users WHERE id = $1) <> 'hide')
+		  AND (p.language IS NULL
+		       OR (SELECT feed_languages = '{}' OR p.language = ANY(feed_languages) FROM users WHERE id = $1))
+		GROUP BY p.id, u.username, co.title
+		ORDER BY p.created_at ASC
+		LIMIT $3`, userID, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feed digest items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*FeedDigestItem
+	for rows.Next() {
+		var item FeedDigestItem
+		err := rows.Scan(&item.PostID, &item.AuthorUsername, &item.Text, &item.CourseTitle, &item.Hashtags, &item.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan feed digest item: %w", err)
+		}
+		items = append(items, &item)
+	}
+
+	return items, nil
+}
+
+// GetFollowers lists who follows userID, newest first. A private account's
+// connections are only listed to itself and its followers.
+func (s *SocialService) GetFollowers(ctx context.Context, userID, viewerID uuid.UUID, limit, offset int) ([]*UserResponse, error) {
+	if err := s.checkConnectionsVisible(ctx, userID, viewerID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Reader().Query(ctx, `
+		SELECT u.id, u.username, u.email, u.bio, u.avatar_url, u.last_active_at, u.show_presence
+		FROM follows f
+		JOIN users u ON f.follower_id = u.id
+		WHERE f.followee_id = $1
+		ORDER BY f.created_at DESC
+		LIMIT $2 OFFSET $3`, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get followers: %w", err)
+	}
+	return scanConnections(rows)
+}
+
+// GetFollowing lists whom userID follows, newest first, under the same rule
+// as GetFollowers
+func (s *SocialService) GetFollowing(ctx context.Context, userID, viewerID uuid.UUID, limit, offset int) ([]*UserResponse, error) {
+	if err := s.checkConnectionsVisible(ctx, userID, viewerID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Reader().Query(ctx, `
+		SELECT u.id, u.username, u.email, u.bio, u.avatar_url, u.last_active_at, u.show_presence
 		FROM follows f
 		JOIN users u ON f.followee_id = u.id
 		WHERE f.follower_id = $1
@@ -233,24 +803,55 @@ func (s *SocialService) GetFollowing(ctx context.Context, userID uuid.UUID, limi
 	if err != nil {
 		return nil, fmt.Errorf("failed to get following: %w", err)
 	}
+	return scanConnections(rows)
+}
+
+// checkConnectionsVisible fails unless viewerID may list userID's followers
+// and followees: userID must be in the viewer's organization and, if
+// private, be the viewer or followed by them
+func (s *SocialService) checkConnectionsVisible(ctx context.Context, userID, viewerID uuid.UUID) error {
+	var hidden bool
+	err := s.db.Reader().QueryRow(ctx, `
+		SELECT u.is_private AND u.id <> $2
+		       AND NOT EXISTS (SELECT 1 FROM follows WHERE follower_id = $2 AND followee_id = u.id)
+		FROM users u
+		WHERE u.id = $1 AND u.org_id = (SELECT org_id FROM users WHERE id = $2)`, userID, viewerID).Scan(&hidden)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("user not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check account privacy: %w", err)
+	}
+	if hidden {
+		return fmt.Errorf("account is private")
+	}
+	return nil
+}
+
+// scanConnections reads users with their presence
+func scanConnections(rows pgx.Rows) ([]*UserResponse, error) {
 	defer rows.Close()
 
-	var users []*UserResponse
+	now := time.Now()
+	users := []*UserResponse{}
 	for rows.Next() {
 		var user UserResponse
 		var bio, avatarURL pgtype.Text
+		var lastActive *time.Time
+		var showPresence bool
 
-		err := rows.Scan(&user.ID, &user.Username, &user.Email, &bio, &avatarURL)
+		err := rows.Scan(&user.ID, &user.Username, &user.Email, &bio, &avatarURL, &lastActive, &showPresence)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan following user: %w", err)
+			return nil, fmt.Errorf("failed to scan user: %w", err)
 		}
 
 		user.Bio = getPgtypeTextValue(bio)
 		user.AvatarURL = getPgtypeTextPtr(avatarURL)
+		user.Presence = PresenceOf(lastActive, showPresence, now)
 		users = append(users, &user)
 	}
 
-	return users, nil
+	return users, rows.Err()
 }
 
 func (s *SocialService) IsFollowing(ctx context.Context, followerID, followeeID uuid.UUID) (bool, error) {
@@ -265,11 +866,13 @@ func (s *SocialService) IsFollowing(ctx context.Context, followerID, followeeID
 	return count > 0, nil
 }
 
-func (s *SocialService) GetCourses(ctx context.Context) ([]*Course, error) {
+// GetCourses lists the courses of orgID
+func (s *SocialService) GetCourses(ctx context.Context, orgID uuid.UUID) ([]*Course, error) {
 	rows, err := s.db.Query(ctx, `
 		SELECT id, title, description
 		FROM courses
-		ORDER BY title`)
+		WHERE org_id = $1
+		ORDER BY title`, orgID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get courses: %w", err)
 	}
@@ -292,12 +895,15 @@ func (s *SocialService) GetCourses(ctx context.Context) ([]*Course, error) {
 	return courses, nil
 }
 
-func (s *SocialService) GetModulesByCourse(ctx context.Context, courseID uuid.UUID) ([]*Module, error) {
+// GetModulesByCourse lists a course's modules; courses of other
+// organizations have none
+func (s *SocialService) GetModulesByCourse(ctx context.Context, orgID, courseID uuid.UUID) ([]*Module, error) {
 	rows, err := s.db.Query(ctx, `
-		SELECT id, course_id, title, "order"
-		FROM modules
-		WHERE course_id = $1
-		ORDER BY "order"`, courseID)
+		SELECT m.id, m.course_id, m.title, m."order"
+		FROM modules m
+		JOIN courses c ON c.id = m.course_id
+		WHERE m.course_id = $1 AND c.org_id = $2
+		ORDER BY m."order"`, courseID, orgID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get modules: %w", err)
 	}
@@ -316,6 +922,36 @@ func (s *SocialService) GetModulesByCourse(ctx context.Context, courseID uuid.UU
 	return modules, nil
 }
 
+// CreateCourse adds a course to orgID
+func (s *SocialService) CreateCourse(ctx context.Context, orgID uuid.UUID, req CreateCourseRequest) (*Course, error) {
+	course := Course{Title: req.Title, Description: req.Description}
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO courses (title, description, org_id)
+		VALUES ($1, $2, $3)
+		RETURNING id`, req.Title, req.Description, orgID).Scan(&course.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create course: %w", err)
+	}
+	return &course, nil
+}
+
+// CreateModule adds a module to a course of orgID; it fails with "course not
+// found" for courses of other organizations
+func (s *SocialService) CreateModule(ctx context.Context, orgID, courseID uuid.UUID, req CreateModuleRequest) (*Module, error) {
+	module := Module{CourseID: courseID, Title: req.Title, Order: req.Order}
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO modules (course_id, title, "order")
+		SELECT id, $3, $4 FROM courses WHERE id = $1 AND org_id = $2
+		RETURNING id`, courseID, orgID, req.Title, req.Order).Scan(&module.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("course not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create module: %w", err)
+	}
+	return &module, nil
+}
+
 // Additional types
 type Course struct {
 	ID          uuid.UUID `json:"id"`
@@ -329,3 +965,13 @@ type Module struct {
 	Title    string    `json:"title"`
 	Order    int       `json:"order"`
 }
+
+type CreateCourseRequest struct {
+	Title       string `json:"title" validate:"required,max=200"`
+	Description string `json:"description" validate:"max=2000"`
+}
+
+type CreateModuleRequest struct {
+	Title string `json:"title" validate:"required,max=200"`
+	Order int    `json:"order" validate:"min=0"`
+}
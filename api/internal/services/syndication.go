@@ -0,0 +1,267 @@
+package services
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"bailanysta/api/internal/pkg/database"
+	"bailanysta/api/internal/pkg/markdown"
+)
+
+const (
+	syndicationFeedItems  = 50
+	syndicationTitleChars = 80
+	sitemapMaxURLs        = 50000 // sitemaps.org limit per file
+)
+
+// RSS is an RSS 2.0 document. The atom:link self reference is recommended by
+// the RSS Advisory Board and required by most validators.
+type RSS struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	Channel RSSChannel `xml:"channel"`
+}
+
+type RSSChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	AtomLink      AtomLink  `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []RSSItem `xml:"item"`
+}
+
+type AtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type RSSItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description"`
+	GUID        RSSGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type RSSGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+// Sitemap is a sitemaps.org urlset
+type Sitemap struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	URLs    []SitemapURL `xml:"url"`
+}
+
+type SitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// SyndicationService publishes public posts as RSS feeds and a sitemap, one
+// set per organization. Group posts and posts by private or shadow-banned
+// accounts are never included.
+type SyndicationService struct {
+	db     *database.Pool
+	appURL string
+	apiURL string
+}
+
+// NewSyndicationService creates the service; item links point at appURL and
+// feeds' self links at apiURL
+func NewSyndicationService(db *database.Pool, appURL, apiURL string) *SyndicationService {
+	return &SyndicationService{
+		db:     db,
+		appURL: strings.TrimRight(appURL, "/"),
+		apiURL: strings.TrimRight(apiURL, "/"),
+	}
+}
+
+type syndicatedPost struct {
+	id        uuid.UUID
+	slug      string
+	text      string
+	createdAt time.Time
+}
+
+// UserFeed returns the latest public posts by username in orgID
+func (s *SyndicationService) UserFeed(ctx context.Context, orgID uuid.UUID, username string) (*RSS, error) {
+	var userID uuid.UUID
+	var bio string
+	err := s.db.Reader().QueryRow(ctx, `
+		SELECT id, COALESCE(bio, '') FROM users
+		WHERE username = $1 AND org_id = $2 AND NOT is_private AND NOT shadow_banned`, username, orgID).Scan(&userID, &bio)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("user not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	posts, err := s.publicPosts(ctx, orgID, `AND p.author_id = $2`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	description := bio
+	if description == "" {
+		description = "Posts by @" + username + " on Bailanysta"
+	}
+	return s.rss("@"+username+" on Bailanysta", s.appURL+"/profile/"+url.PathEscape(username), description,
+		s.apiURL+"/api/v1/feeds/user/"+url.PathEscape(username)+".rss", posts), nil
+}
+
+// HashtagFeed returns the latest public posts in orgID tagged with tag
+func (s *SyndicationService) HashtagFeed(ctx context.Context, orgID uuid.UUID, tag string) (*RSS, error) {
+	posts, err := s.publicPosts(ctx, orgID, `
+		AND p.id IN (
+		    SELECT ph.post_id FROM post_hashtags ph
+		    JOIN hashtags h ON h.id = ph.hashtag_id
+		    WHERE h.tag = $2
+		)`, CanonicalHashtag(tag))
+	if err != nil {
+		return nil, err
+	}
+
+	return s.rss("#"+tag+" on Bailanysta", s.appURL+"/search?q="+url.QueryEscape("#"+tag), "Posts tagged #"+tag+" on Bailanysta",
+		s.apiURL+"/api/v1/feeds/hashtag/"+url.PathEscape(tag)+".rss", posts), nil
+}
+
+// publicPosts lists the latest public posts in orgID matching filter, which
+// may use $2
+func (s *SyndicationService) publicPosts(ctx context.Context, orgID uuid.UUID, filter string, arg interface{}) ([]syndicatedPost, error) {
+	rows, err := s.db.Reader().Query(ctx, `
+		SELECT p.id, p.slug, p.text, p.created_at
+		FROM posts p
+		JOIN users u ON p.author_id = u.id
+		WHERE p.org_id = $3 AND p.group_id IS NULL AND NOT u.is_private AND NOT u.shadow_banned
+		`+filter+`
+		ORDER BY p.created_at DESC
+		LIMIT $1`, syndicationFeedItems, arg, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get posts: %w", err)
+	}
+	defer rows.Close()
+
+	var posts []syndicatedPost
+	for rows.Next() {
+		var post syndicatedPost
+		if err := rows.Scan(&post.id, &post.slug, &post.text, &post.createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan post: %w", err)
+		}
+		posts = append(posts, post)
+	}
+	return posts, rows.Err()
+}
+
+func (s *SyndicationService) rss(title, link, description, self string, posts []syndicatedPost) *RSS {
+	feed := &RSS{
+		Version: "2.0",
+		AtomNS:  "http://www.w3.org/2005/Atom",
+		Channel: RSSChannel{
+			Title:       title,
+			Link:        link,
+			Description: description,
+			AtomLink:    AtomLink{Href: self, Rel: "self", Type: "application/rss+xml"},
+			Items:       []RSSItem{},
+		},
+	}
+	if len(posts) > 0 {
+		feed.Channel.LastBuildDate = posts[0].createdAt.UTC().Format(time.RFC1123Z)
+	}
+
+	for _, post := range posts {
+		feed.Channel.Items = append(feed.Channel.Items, RSSItem{
+			Title:       syndicationTitle(post.text),
+			Link:        s.appURL + "/post/" + url.PathEscape(post.slug),
+			Description: markdown.Render(post.text),
+			GUID:        RSSGUID{Value: "urn:uuid:" + post.id.String()},
+			PubDate:     post.createdAt.UTC().Format(time.RFC1123Z),
+		})
+	}
+	return feed
+}
+
+// Sitemap lists public profiles and the most recent public posts of orgID
+func (s *SyndicationService) Sitemap(ctx context.Context, orgID uuid.UUID) (*Sitemap, error) {
+	sitemap := &Sitemap{XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+
+	rows, err := s.db.Reader().Query(ctx, `
+		SELECT u.username, MAX(p.created_at)
+		FROM users u
+		JOIN posts p ON p.author_id = u.id
+		WHERE u.org_id = $2 AND NOT u.is_private AND NOT u.shadow_banned
+		GROUP BY u.id
+		ORDER BY MAX(p.created_at) DESC
+		LIMIT $1`, sitemapMaxURLs/10, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profiles: %w", err)
+	}
+	for rows.Next() {
+		var username string
+		var lastPost time.Time
+		if err := rows.Scan(&username, &lastPost); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan profile: %w", err)
+		}
+		sitemap.URLs = append(sitemap.URLs, SitemapURL{
+			Loc:     s.appURL + "/profile/" + url.PathEscape(username),
+			LastMod: lastPost.UTC().Format(time.RFC3339),
+		})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get profiles: %w", err)
+	}
+
+	rows, err = s.db.Reader().Query(ctx, `
+		SELECT p.slug, p.updated_at
+		FROM posts p
+		JOIN users u ON p.author_id = u.id
+		WHERE p.org_id = $2 AND p.group_id IS NULL AND NOT u.is_private AND NOT u.shadow_banned
+		ORDER BY p.created_at DESC
+		LIMIT $1`, sitemapMaxURLs-len(sitemap.URLs), orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get posts: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var slug string
+		var updatedAt time.Time
+		if err := rows.Scan(&slug, &updatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan post: %w", err)
+		}
+		sitemap.URLs = append(sitemap.URLs, SitemapURL{
+			Loc:     s.appURL + "/post/" + url.PathEscape(slug),
+			LastMod: updatedAt.UTC().Format(time.RFC3339),
+		})
+	}
+	return sitemap, rows.Err()
+}
+
+// syndicationTitle is the first line of text, cut to syndicationTitleChars
+// characters
+func syndicationTitle(text string) string {
+	title, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	title = strings.TrimSpace(title)
+	if utf8.RuneCountInString(title) <= syndicationTitleChars {
+		return title
+	}
+
+	runes := []rune(title)
+	return strings.TrimSpace(string(runes[:syndicationTitleChars-1])) + "…"
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyndicationTitle(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"short", "Hello world", "Hello world"},
+		{"first line", "  Title line\nrest of the post", "Title line"},
+		{"truncated", strings.Repeat("ә", 100), strings.Repeat("ә", 79) + "…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, syndicationTitle(tt.text))
+		})
+	}
+}
+
+func TestRSSMarshal(t *testing.T) {
+	s := NewSyndicationService(nil, "https://app.example/", "https://api.example")
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	feed := s.rss("#go on Bailanysta", "https://app.example/search", "Posts tagged #go",
+		"https://api.example/api/v1/feeds/hashtag/go.rss", []syndicatedPost{
+			{id: uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8"), slug: "1-hello", text: "Hello <b>", createdAt: created},
+		})
+
+	out, err := xml.Marshal(feed)
+	require.NoError(t, err)
+
+	body := string(out)
+	assert.Contains(t, body, `<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">`)
+	assert.Contains(t, body, `<atom:link href="https://api.example/api/v1/feeds/hashtag/go.rss" rel="self" type="application/rss+xml"></atom:link>`)
+	assert.Contains(t, body, `<link>https://app.example/post/1-hello</link>`)
+	assert.Contains(t, body, `<guid isPermaLink="false">urn:uuid:6ba7b810-9dad-11d1-80b4-00c04fd430c8</guid>`)
+	assert.Contains(t, body, `<pubDate>Fri, 01 Mar 2024 12:00:00 +0000</pubDate>`)
+	assert.NotContains(t, body, "<b>")
+}
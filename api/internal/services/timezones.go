@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultTimezone is the time zone of users who haven't picked one
+const DefaultTimezone = "UTC"
+
+type SetTimezoneRequest struct {
+	Timezone string `json:"timezone" validate:"required,max=64"`
+}
+
+// normalizeTimezone returns the canonical name of an IANA time zone such as
+// "Asia/Almaty". "Local" is rejected since it means the server's zone.
+func normalizeTimezone(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || name == "Local" {
+		return "", fmt.Errorf("invalid timezone")
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return "", fmt.Errorf("invalid timezone")
+	}
+	return location.String(), nil
+}
+
+// userLocation loads a stored time zone, falling back to UTC
+func userLocation(name string) *time.Location {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// localDate returns the calendar day of t in location, as midnight UTC so
+// days compare and subtract like those scanned from DATE columns
+func localDate(t time.Time, location *time.Location) time.Time {
+	year, month, day := t.In(location).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// SetTimezone sets the time zone used for the user's calendar days
+func (s *AuthService) SetTimezone(ctx context.Context, userID uuid.UUID, timezone string) (string, error) {
+	timezone, err := normalizeTimezone(timezone)
+	if err != nil {
+		return "", err
+	}
+
+	tag, err := s.db.Exec(ctx, `UPDATE users SET timezone = $2 WHERE id = $1`, userID, timezone)
+	if err != nil {
+		return "", fmt.Errorf("failed to update timezone: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return "", fmt.Errorf("user not found")
+	}
+	return timezone, nil
+}
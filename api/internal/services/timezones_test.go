@@ -0,0 +1,46 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeTimezone(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"Asia/Almaty", "Asia/Almaty", false},
+		{" Europe/Moscow ", "Europe/Moscow", false},
+		{"UTC", "UTC", false},
+		{"", "", true},
+		{"Local", "", true},
+		{"Mars/Olympus_Mons", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeTimezone(tt.name)
+			if tt.wantErr {
+				assert.EqualError(t, err, "invalid timezone")
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLocalDate(t *testing.T) {
+	almaty := userLocation("Asia/Almaty")
+	losAngeles := userLocation("America/Los_Angeles")
+	instant := time.Date(2026, 3, 10, 20, 30, 0, 0, time.UTC)
+
+	assert.Equal(t, time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC), localDate(instant, time.UTC))
+	assert.Equal(t, time.Date(2026, 3, 11, 0, 0, 0, 0, time.UTC), localDate(instant, almaty))
+	assert.Equal(t, time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC), localDate(instant, losAngeles))
+	assert.Equal(t, time.UTC, userLocation("not a zone"))
+}
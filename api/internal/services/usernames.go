@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// UsernameChangeCooldown is the minimum time between username changes
+const UsernameChangeCooldown = 30 * 24 * time.Hour
+
+// usernameRe matches usernames that @mentions can refer to
+var usernameRe = regexp.MustCompile(`^\w[\w.-]{2,49}$`)
+
+type ChangeUsernameRequest struct {
+	Username string `json:"username" validate:"required,min=3,max=50"`
+}
+
+// ValidUsername reports whether username can be chosen in a rename
+func ValidUsername(username string) bool {
+	return usernameRe.MatchString(username)
+}
+
+// UsernameChange is the result of a rename
+type UsernameChange struct {
+	Username     string    `json:"username"`
+	NextChangeAt time.Time `json:"next_change_at"`
+}
+
+// ChangeUsername renames userID. The old username is kept in
+// username_history: it still resolves to the user and can't be registered
+// by anyone else, though the user may switch back to it.
+func (s *AuthService) ChangeUsername(ctx context.Context, userID uuid.UUID, username string) (*UsernameChange, error) {
+	var change UsernameChange
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		var current string
+		var changedAt *time.Time
+		err := tx.QueryRow(ctx, `
+			SELECT username, username_changed_at FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&current, &changedAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("user not found")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+
+		if !ValidUsername(username) {
+			return fmt.Errorf("invalid username")
+		}
+		if username == current {
+			return fmt.Errorf("username is unchanged")
+		}
+		if changedAt != nil && time.Since(*changedAt) < UsernameChangeCooldown {
+			return fmt.Errorf("username was changed recently")
+		}
+
+		var taken bool
+		err = tx.QueryRow(ctx, `
+			SELECT EXISTS(SELECT 1 FROM users WHERE username = $1)
+			    OR EXISTS(SELECT 1 FROM username_history WHERE username = $1 AND user_id <> $2)`,
+			username, userID).Scan(&taken)
+		if err != nil {
+			return fmt.Errorf("failed to check username: %w", err)
+		}
+		if taken {
+			return fmt.Errorf("username is already taken")
+		}
+
+		// Switching back to an old name takes it out of the history
+		_, err = tx.Exec(ctx, `
+			DELETE FROM username_history WHERE username = $1 AND user_id = $2`, username, userID)
+		if err != nil {
+			return fmt.Errorf("failed to update username history: %w", err)
+		}
+		_, err = tx.Exec(ctx, `
+			INSERT INTO username_history (username, user_id) VALUES ($1, $2)`, current, userID)
+		if err != nil {
+			return fmt.Errorf("failed to update username history: %w", err)
+		}
+
+		var now time.Time
+		err = tx.QueryRow(ctx, `
+			UPDATE users SET username = $2, username_changed_at = now()
+			WHERE id = $1
+			RETURNING username_changed_at`, userID, username).Scan(&now)
+		if err != nil {
+			return fmt.Errorf("failed to change username: %w", err)
+		}
+
+		change = UsernameChange{Username: username, NextChangeAt: now.Add(UsernameChangeCooldown)}
+		return nil
+	})
+
+	// A concurrent registration or rename can still claim the name first
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return nil, fmt.Errorf("username is already taken")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
+
+// ResolveUsername returns the user currently or formerly known as username
+// and their current username
+func (s *AuthService) ResolveUsername(ctx context.Context, username string) (uuid.UUID, string, error) {
+	var userID uuid.UUID
+	var current string
+	err := s.db.Reader().QueryRow(ctx, `
+		SELECT id, username FROM users WHERE username = $1
+		UNION ALL
+		SELECT u.id, u.username FROM username_history h
+		JOIN users u ON u.id = h.user_id
+		WHERE h.username = $1
+		LIMIT 1`, username).Scan(&userID, &current)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, "", fmt.Errorf("user not found")
+	}
+	if err != nil {
+		return uuid.Nil, "", fmt.Errorf("failed to resolve username: %w", err)
+	}
+	return userID, current, nil
+}
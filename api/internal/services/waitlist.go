@@ -0,0 +1,254 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"bailanysta/api/internal/pkg/database"
+	"bailanysta/api/internal/pkg/mailer"
+)
+
+// Registration modes
+const (
+	RegistrationOpen     = "open"
+	RegistrationWaitlist = "waitlist"
+)
+
+// Waitlist entry states, for filtering
+const (
+	WaitlistPending    = "pending"
+	WaitlistApproved   = "approved"
+	WaitlistRegistered = "registered"
+)
+
+// WaitlistService runs invite-only registration: signups join the waitlist,
+// admins approve them in batches, and each approved email gets a one-time
+// registration link valid for inviteTTL.
+type WaitlistService struct {
+	db        *database.Pool
+	mailer    *mailer.Mailer
+	appURL    string
+	inviteTTL time.Duration
+}
+
+type WaitlistEntry struct {
+	ID              uuid.UUID  `json:"id"`
+	Email           string     `json:"email"`
+	CreatedAt       time.Time  `json:"created_at"`
+	ApprovedAt      *time.Time `json:"approved_at,omitempty"`
+	InviteExpiresAt *time.Time `json:"invite_expires_at,omitempty"`
+	RegisteredAt    *time.Time `json:"registered_at,omitempty"`
+	// Emailed reports whether the invite went out; only set by Approve
+	Emailed *bool `json:"emailed,omitempty"`
+}
+
+// ApproveWaitlistRequest approves either the listed entries or the Count
+// oldest pending ones, at most 100 at a time
+type ApproveWaitlistRequest struct {
+	IDs   []uuid.UUID `json:"ids" validate:"max=100"`
+	Count int         `json:"count" validate:"min=0,max=100"`
+}
+
+// NewWaitlistService creates the service; a nil service means registration
+// is open
+func NewWaitlistService(db *database.Pool, mailer *mailer.Mailer, appURL string, inviteTTL time.Duration) *WaitlistService {
+	return &WaitlistService{
+		db:        db,
+		mailer:    mailer,
+		appURL:    strings.TrimRight(appURL, "/"),
+		inviteTTL: inviteTTL,
+	}
+}
+
+// Enabled reports whether registration is invite-only
+func (s *WaitlistService) Enabled() bool {
+	return s != nil
+}
+
+// Join adds email to the waitlist. Joining twice is not an error, so the
+// response doesn't reveal who already signed up.
+func (s *WaitlistService) Join(ctx context.Context, email string) error {
+	_, err := s.db.Exec(ctx, `
+		INSERT INTO waitlist (email) VALUES ($1)
+		ON CONFLICT (lower(email)) DO NOTHING`, strings.TrimSpace(email))
+	if err != nil {
+		return fmt.Errorf("failed to join waitlist: %w", err)
+	}
+	return nil
+}
+
+const waitlistColumns = `id, email, created_at, approved_at, invite_expires_at, registered_at`
+
+// List returns up to limit entries in the given state ("" for all), oldest
+// first
+func (s *WaitlistService) List(ctx context.Context, status string, limit, offset int) ([]*WaitlistEntry, error) {
+	var condition string
+	switch status {
+	case "":
+		condition = "true"
+	case WaitlistPending:
+		condition = "approved_at IS NULL"
+	case WaitlistApproved:
+		condition = "approved_at IS NOT NULL AND registered_at IS NULL"
+	case WaitlistRegistered:
+		condition = "registered_at IS NOT NULL"
+	default:
+		return nil, fmt.Errorf("invalid waitlist status")
+	}
+
+	rows, err := s.db.Reader().Query(ctx, `
+		SELECT `+waitlistColumns+`
+		FROM waitlist
+		WHERE `+condition+`
+		ORDER BY created_at, id
+		LIMIT $1 OFFSET $2`, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get waitlist: %w", err)
+	}
+	return scanWaitlistEntries(rows)
+}
+
+// Approve issues invites to the requested entries and emails them. Entries
+// that already registered are skipped; approving an approved entry again
+// replaces its link. A failed email doesn't undo the approval, so the
+// entry can be approved again to resend.
+func (s *WaitlistService) Approve(ctx context.Context, adminID uuid.UUID, req ApproveWaitlistRequest) ([]*WaitlistEntry, error) {
+	if (len(req.IDs) == 0) == (req.Count == 0) {
+		return nil, fmt.Errorf("ids or count is required")
+	}
+
+	var rows pgx.Rows
+	var err error
+	if len(req.IDs) > 0 {
+		rows, err = s.db.Query(ctx, `
+			SELECT `+waitlistColumns+` FROM waitlist
+			WHERE id = ANY($1) AND registered_at IS NULL
+			ORDER BY created_at, id`, req.IDs)
+	} else {
+		rows, err = s.db.Query(ctx, `
+			SELECT `+waitlistColumns+` FROM waitlist
+			WHERE approved_at IS NULL
+			ORDER BY created_at, id
+			LIMIT $1`, req.Count)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get waitlist: %w", err)
+	}
+	entries, err := scanWaitlistEntries(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		token, err := generateInviteToken()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate invite: %w", err)
+		}
+		err = s.db.QueryRow(ctx, `
+			UPDATE waitlist
+			SET approved_at = now(), approved_by = $2, invite_token_hash = $3, invite_expires_at = $4
+			WHERE id = $1 AND registered_at IS NULL
+			RETURNING approved_at, invite_expires_at`,
+			entry.ID, adminID, hashToken(token), time.Now().Add(s.inviteTTL)).Scan(&entry.ApprovedAt, &entry.InviteExpiresAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue // registered meanwhile
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to approve waitlist entry: %w", err)
+		}
+
+		emailed := true
+		if err := s.sendInvite(ctx, entry.Email, token, *entry.InviteExpiresAt); err != nil {
+			fmt.Printf("Failed to send invite to waitlist entry %s: %v\n", entry.ID, err)
+			emailed = false
+		}
+		entry.Emailed = &emailed
+	}
+	return entries, nil
+}
+
+// InviteURL is the registration link an invite email carries
+func (s *WaitlistService) InviteURL(token string) string {
+	return s.appURL + "/register?invite=" + url.QueryEscape(token)
+}
+
+func (s *WaitlistService) sendInvite(ctx context.Context, email, token string, expiresAt time.Time) error {
+	if s.mailer == nil {
+		return fmt.Errorf("email is not configured")
+	}
+	link := s.InviteURL(token)
+	expires := expiresAt.UTC().Format("January 2, 2006")
+
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "You're invited to Bailanysta",
+		HTML: fmt.Sprintf(`<p>Your spot on the Bailanysta waitlist is ready.</p>`+
+			`<p><a href="%s">Create your account</a></p>`+
+			`<p>The link works once and expires on %s (UTC).</p>`, link, expires),
+		Text: fmt.Sprintf("Your spot on the Bailanysta waitlist is ready.\n\n"+
+			"Create your account: %s\n\nThe link works once and expires on %s (UTC).\n", link, expires),
+	})
+}
+
+// ClaimInvite uses up the invite token for email, returning the entry so a
+// failed registration can release it. Tokens are bound to the email they
+// were sent to.
+func (s *WaitlistService) ClaimInvite(ctx context.Context, token, email string) (uuid.UUID, error) {
+	var entryID uuid.UUID
+	err := s.db.QueryRow(ctx, `
+		UPDATE waitlist SET registered_at = now()
+		WHERE invite_token_hash = $1 AND lower(email) = lower($2)
+		  AND registered_at IS NULL AND invite_expires_at > now()
+		RETURNING id`, hashToken(token), strings.TrimSpace(email)).Scan(&entryID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, fmt.Errorf("invalid invite")
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to claim invite: %w", err)
+	}
+	return entryID, nil
+}
+
+// ReleaseInvite makes a claimed invite usable again
+func (s *WaitlistService) ReleaseInvite(ctx context.Context, entryID uuid.UUID) error {
+	_, err := s.db.Exec(ctx, `UPDATE waitlist SET registered_at = NULL WHERE id = $1`, entryID)
+	if err != nil {
+		return fmt.Errorf("failed to release invite: %w", err)
+	}
+	return nil
+}
+
+func generateInviteToken() (string, error) {
+	bytes := make([]byte, 24)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+func scanWaitlistEntries(rows pgx.Rows) ([]*WaitlistEntry, error) {
+	defer rows.Close()
+
+	entries := []*WaitlistEntry{}
+	for rows.Next() {
+		var entry WaitlistEntry
+		err := rows.Scan(&entry.ID, &entry.Email, &entry.CreatedAt, &entry.ApprovedAt, &entry.InviteExpiresAt, &entry.RegisteredAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan waitlist entry: %w", err)
+		}
+		entries = append(entries, &entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read waitlist: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitlistApproveRequiresIDsOrCount(t *testing.T) {
+	s := NewWaitlistService(nil, nil, "https://bailanysta.kz", time.Hour)
+
+	tests := []struct {
+		name string
+		req  ApproveWaitlistRequest
+	}{
+		{"neither", ApproveWaitlistRequest{}},
+		{"both", ApproveWaitlistRequest{IDs: []uuid.UUID{uuid.New()}, Count: 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Approve(context.Background(), uuid.New(), tt.req)
+			assert.EqualError(t, err, "ids or count is required")
+		})
+	}
+}
+
+func TestWaitlistInviteURL(t *testing.T) {
+	s := NewWaitlistService(nil, nil, "https://bailanysta.kz/", time.Hour)
+	assert.Equal(t, "https://bailanysta.kz/register?invite=abc123", s.InviteURL("abc123"))
+
+	var disabled *WaitlistService
+	assert.False(t, disabled.Enabled())
+	assert.True(t, s.Enabled())
+}
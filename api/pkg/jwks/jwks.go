@@ -0,0 +1,206 @@
+// Package jwks publishes and consumes JSON Web Key Sets (RFC 7517), so
+// services other than the API can verify its access tokens with public keys
+// instead of a shared secret. It lives outside internal/ to be importable by
+// those services.
+package jwks
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Key is a public JWK; RSA keys set N and E, Ed25519 keys Crv and X
+type Key struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// Set is a JWK Set document
+type Set struct {
+	Keys []Key `json:"keys"`
+}
+
+// FromPublicKey describes an RSA or Ed25519 public key as a JWK
+func FromPublicKey(kid string, key crypto.PublicKey) (Key, error) {
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		return Key{
+			Kty: "RSA",
+			Kid: kid,
+			Use: "sig",
+			Alg: "RS256",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}, nil
+	case ed25519.PublicKey:
+		return Key{
+			Kty: "OKP",
+			Kid: kid,
+			Use: "sig",
+			Alg: "EdDSA",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(key),
+		}, nil
+	}
+	return Key{}, fmt.Errorf("unsupported key type %T", key)
+}
+
+// PublicKey decodes the key and returns the signing method it verifies
+func (k Key) PublicKey() (crypto.PublicKey, jwt.SigningMethod, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid RSA modulus of key %q", k.Kid)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, nil, fmt.Errorf("invalid RSA exponent of key %q", k.Kid)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, jwt.SigningMethodRS256, nil
+	case "OKP":
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || k.Crv != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return nil, nil, fmt.Errorf("invalid Ed25519 key %q", k.Kid)
+		}
+		return ed25519.PublicKey(x), jwt.SigningMethodEdDSA, nil
+	}
+	return nil, nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// A key set is fetched again at most this often, however many tokens name
+// unknown keys
+const minRefreshInterval = time.Minute
+
+type verifierKey struct {
+	key    crypto.PublicKey
+	method jwt.SigningMethod
+}
+
+// Verifier validates tokens against the key set published at a URL, such as
+// https://api.example.com/.well-known/jwks.json. Keys are cached and the set
+// is fetched again when a token names a key it doesn't have, which is how
+// rotated keys are picked up.
+type Verifier struct {
+	url        string
+	issuer     string
+	audience   string
+	httpClient *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]verifierKey
+	fetchedAt time.Time
+}
+
+// NewVerifier creates a verifier for tokens with the given issuer and
+// audience, whose keys are published at url
+func NewVerifier(url, issuer, audience string) *Verifier {
+	return &Verifier{
+		url:        url,
+		issuer:     issuer,
+		audience:   audience,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		keys:       make(map[string]verifierKey),
+	}
+}
+
+// Verify checks tokenString's signature, expiry, issuer and audience and
+// decodes its claims into claims, e.g. a *jwt.MapClaims or a struct
+// embedding jwt.RegisteredClaims
+func (v *Verifier) Verify(ctx context.Context, tokenString string, claims jwt.Claims) error {
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		keyID, _ := token.Header["kid"].(string)
+		key, err := v.key(ctx, keyID)
+		if err != nil {
+			return nil, err
+		}
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return key.key, nil
+	},
+		jwt.WithValidMethods([]string{"RS256", "EdDSA"}),
+		jwt.WithIssuer(v.issuer),
+		jwt.WithAudience(v.audience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return fmt.Errorf("invalid token: %w", err)
+	}
+	return nil
+}
+
+func (v *Verifier) key(ctx context.Context, keyID string) (verifierKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if key, ok := v.keys[keyID]; ok {
+		return key, nil
+	}
+	if time.Since(v.fetchedAt) < minRefreshInterval {
+		return verifierKey{}, fmt.Errorf("unknown signing key %q", keyID)
+	}
+
+	keys, err := v.fetch(ctx)
+	v.fetchedAt = time.Now()
+	if err != nil {
+		return verifierKey{}, err
+	}
+	v.keys = keys
+
+	if key, ok := v.keys[keyID]; ok {
+		return key, nil
+	}
+	return verifierKey{}, fmt.Errorf("unknown signing key %q", keyID)
+}
+
+func (v *Verifier) fetch(ctx context.Context) (map[string]verifierKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JWKS request: %w", err)
+	}
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: %s", resp.Status)
+	}
+
+	var set Set
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]verifierKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, method, err := jwk.PublicKey()
+		if err != nil {
+			// One odd key shouldn't lock out the others
+			continue
+		}
+		keys[jwk.Kid] = verifierKey{key: key, method: method}
+	}
+	return keys, nil
+}